@@ -0,0 +1,109 @@
+package main
+
+import (
+	"flag"
+	"io"
+	"log"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"k3s-deploy-backend/internal/pkg/agent"
+)
+
+// k3s-deploy-agent 运行在无法接受入站 SSH 的节点上：
+// 主动连接后端控制通道，收到 dial 指令后建立隧道并转发到本机 sshd。
+func main() {
+	server := flag.String("server", "", "后端 WebSocket 地址，例如 ws://10.0.0.1:8080，后端配置了路径前缀时包含前缀（wss://deploy.example.com/k3s-deploy）")
+	agentID := flag.String("id", "", "Agent 标识（默认主机名），需与生成引导脚本时的 id 一致")
+	sshAddr := flag.String("ssh-addr", "127.0.0.1:22", "本机 sshd 地址")
+	flag.Parse()
+
+	// 连接密钥从环境变量读取（引导脚本写入仅 root 可读的环境文件），不出现在进程命令行中
+	token := os.Getenv("K3S_DEPLOY_AGENT_TOKEN")
+	if *server == "" || token == "" {
+		log.Fatal("必须指定 -server 并设置环境变量 K3S_DEPLOY_AGENT_TOKEN")
+	}
+
+	hostname, _ := os.Hostname()
+	if *agentID == "" {
+		*agentID = hostname
+	}
+
+	backoff := time.Second
+	for {
+		start := time.Now()
+		err := run(*server, token, *agentID, hostname, *sshAddr)
+		log.Printf("控制通道断开: %v", err)
+
+		// 连接维持较久时重置退避时间
+		if time.Since(start) > time.Minute {
+			backoff = time.Second
+		}
+		time.Sleep(backoff)
+		if backoff < 30*time.Second {
+			backoff *= 2
+		}
+	}
+}
+
+func run(server, token, agentID, hostname, sshAddr string) error {
+	controlURL := server + "/api/agent/connect?agent=" + url.QueryEscape(agentID)
+	ws, _, err := websocket.DefaultDialer.Dial(controlURL, authHeader(token))
+	if err != nil {
+		return err
+	}
+	defer ws.Close()
+
+	if err := ws.WriteJSON(agent.Message{Type: agent.MessageHello, AgentID: agentID, Hostname: hostname}); err != nil {
+		return err
+	}
+	log.Printf("已连接到 %s，Agent ID: %s", server, agentID)
+
+	for {
+		var msg agent.Message
+		if err := ws.ReadJSON(&msg); err != nil {
+			return err
+		}
+		if msg.Type == agent.MessageDial {
+			go tunnel(server, token, agentID, msg.TunnelID, sshAddr)
+		}
+	}
+}
+
+// authHeader 连接密钥放在请求头中，不写入后端和代理的访问日志
+func authHeader(token string) http.Header {
+	return http.Header{"X-Agent-Token": []string{token}}
+}
+
+func tunnel(server, token, agentID, tunnelID, sshAddr string) {
+	local, err := net.DialTimeout("tcp", sshAddr, 10*time.Second)
+	if err != nil {
+		log.Printf("连接本机 sshd 失败: %v", err)
+		return
+	}
+	defer local.Close()
+
+	tunnelURL := server + "/api/agent/tunnel?agent=" + url.QueryEscape(agentID) + "&id=" + url.QueryEscape(tunnelID)
+	ws, _, err := websocket.DefaultDialer.Dial(tunnelURL, authHeader(token))
+	if err != nil {
+		log.Printf("建立隧道 %s 失败: %v", tunnelID, err)
+		return
+	}
+	remote := agent.NewConn(ws)
+	defer remote.Close()
+
+	done := make(chan struct{}, 2)
+	go func() {
+		io.Copy(remote, local)
+		done <- struct{}{}
+	}()
+	go func() {
+		io.Copy(local, remote)
+		done <- struct{}{}
+	}()
+	<-done
+}
@@ -0,0 +1,197 @@
+package main
+
+import (
+	"bufio"
+	"flag"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/pkg/bundle"
+	"k3s-deploy-backend/internal/pkg/k3s"
+)
+
+// k3s-deploy-bundle 在可联网的机器上制作离线安装包：下载 k3s 二进制、离线镜像、
+// 安装脚本以及所选附加组件的镜像、Chart 和清单，写入签名清单后拷贝到后端的 bundles 目录使用。
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	cmd, args := os.Args[1], os.Args[2:]
+	var err error
+	switch cmd {
+	case "build":
+		err = build(args)
+	case "verify":
+		err = verify(args)
+	default:
+		usage()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `用法: k3s-deploy-bundle <命令> [参数]
+
+命令:
+  build   下载文件并生成签名安装包
+  verify  校验安装包签名和文件摘要
+
+附加组件（-addons）:
+  insuite   inSuite 组件镜像
+  longhorn  Longhorn 镜像与安装清单（版本由 -longhorn-version 指定）
+
+使用 "k3s-deploy-bundle <命令> -h" 查看命令参数`)
+	os.Exit(2)
+}
+
+// listFlag 可重复指定的参数
+type listFlag []string
+
+func (l *listFlag) String() string     { return strings.Join(*l, ",") }
+func (l *listFlag) Set(v string) error { *l = append(*l, v); return nil }
+
+func build(args []string) error {
+	fs := flag.NewFlagSet("build", flag.ExitOnError)
+	version := fs.String("k3s-version", "", "k3s 版本，如 v1.30.4+k3s1")
+	arch := fs.String("arch", "amd64", "节点架构：amd64、arm64 或 arm")
+	addons := fs.String("addons", "insuite", "附加组件，逗号分隔")
+	longhornVersion := fs.String("longhorn-version", "v1.7.2", "Longhorn 版本")
+	out := fs.String("out", "", "输出目录，默认 data/bundles/<版本>-<架构>")
+	keyFile := fs.String("key", "data/bundle.key", "签名私钥，不存在时生成（公钥写入 <key>.pub）")
+	var images, charts, packages, packageKeys listFlag
+	fs.Var(&images, "image", "额外打包的镜像，可重复指定")
+	fs.Var(&charts, "chart", "额外打包的 Chart，格式 name=url，可重复指定")
+	fs.Var(&packages, "package", "系统补丁软件包，格式 文件名=url（如 openssl_3.0.2_amd64.deb=https://...），可重复指定")
+	fs.Var(&packageKeys, "package-key", "补丁软件包的签名公钥，格式 文件名=url（rpm 为 GPG 公钥，apk 为 .rsa.pub 且文件名与签名中的密钥名一致），可重复指定")
+	fs.Parse(args)
+
+	if *version == "" {
+		return fmt.Errorf("必须指定 -k3s-version")
+	}
+	if *out == "" {
+		*out = filepath.Join("data/bundles", strings.ReplaceAll(*version, "+", "-")+"-"+*arch)
+	}
+
+	opts := bundle.Options{
+		K3sVersion:  *version,
+		Arch:        *arch,
+		Images:      images,
+		Charts:      make(map[string]string),
+		Manifests:   make(map[string]string),
+		Packages:    make(map[string]string),
+		PackageKeys: make(map[string]string),
+	}
+	for _, chart := range charts {
+		name, source, ok := strings.Cut(chart, "=")
+		if !ok || name == "" || source == "" {
+			return fmt.Errorf("无效的 -chart 参数: %s（格式 name=url）", chart)
+		}
+		opts.Charts[name] = source
+	}
+	for _, pkg := range packages {
+		name, source, ok := strings.Cut(pkg, "=")
+		if !ok || name == "" || source == "" {
+			return fmt.Errorf("无效的 -package 参数: %s（格式 文件名=url）", pkg)
+		}
+		opts.Packages[name] = source
+	}
+	for _, key := range packageKeys {
+		name, source, ok := strings.Cut(key, "=")
+		if !ok || name == "" || source == "" {
+			return fmt.Errorf("无效的 -package-key 参数: %s（格式 文件名=url）", key)
+		}
+		opts.PackageKeys[name] = source
+	}
+	for _, addon := range strings.Split(*addons, ",") {
+		addon = strings.TrimSpace(addon)
+		switch addon {
+		case "":
+			continue
+		case "insuite":
+			opts.Images = append(opts.Images, k3s.DatabaseImage, k3s.MiddlewareImage, k3s.AppImage)
+		case "longhorn":
+			base := "https://raw.githubusercontent.com/longhorn/longhorn/" + *longhornVersion + "/deploy/"
+			list, err := imageList(base + "longhorn-images.txt")
+			if err != nil {
+				return err
+			}
+			opts.Images = append(opts.Images, list...)
+			opts.Manifests["longhorn"] = base + "longhorn.yaml"
+		default:
+			return fmt.Errorf("未知的附加组件: %s", addon)
+		}
+		opts.Addons = append(opts.Addons, addon)
+	}
+
+	key, err := bundle.LoadOrCreateSigningKey(*keyFile)
+	if err != nil {
+		return err
+	}
+
+	start := time.Now()
+	manifest, err := bundle.NewBuilder(*out, log.Printf).Build(opts, key)
+	if err != nil {
+		return err
+	}
+
+	var total int64
+	for _, f := range manifest.Files {
+		total += f.Size
+	}
+	fmt.Printf("已生成安装包 %s：%d 个文件，共 %.1f MiB，耗时 %s\n", *out, len(manifest.Files), float64(total)/(1<<20), time.Since(start).Round(time.Second))
+	fmt.Printf("将目录拷贝到后端 bundles 目录，并将 %s.pub 配置为 bundles.public_key_file\n", *keyFile)
+	return nil
+}
+
+// imageList 下载每行一个镜像的列表文件
+func imageList(source string) ([]string, error) {
+	resp, err := http.Get(source)
+	if err != nil {
+		return nil, fmt.Errorf("下载镜像列表失败: %v", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载镜像列表 %s 失败: HTTP %d", source, resp.StatusCode)
+	}
+
+	var images []string
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		if line := strings.TrimSpace(scanner.Text()); line != "" && !strings.HasPrefix(line, "#") {
+			images = append(images, line)
+		}
+	}
+	return images, scanner.Err()
+}
+
+func verify(args []string) error {
+	fs := flag.NewFlagSet("verify", flag.ExitOnError)
+	dir := fs.String("dir", "", "安装包目录")
+	pubFile := fs.String("pub", "data/bundle.key.pub", "签名公钥")
+	fs.Parse(args)
+
+	if *dir == "" {
+		return fmt.Errorf("必须指定 -dir")
+	}
+	publicKey, err := bundle.LoadPublicKey(*pubFile)
+	if err != nil {
+		return err
+	}
+	b, err := bundle.Open(*dir, publicKey)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("安装包校验通过：k3s %s (%s)，附加组件 %s\n", b.Manifest.K3sVersion, b.Manifest.Arch, strings.Join(b.Manifest.Addons, ", "))
+	for _, f := range b.Manifest.Files {
+		fmt.Printf("  %-15s %s  %s\n", f.Kind, f.SHA256[:12], f.Path)
+	}
+	return nil
+}
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"crypto/x509"
+	"flag"
+	"fmt"
+	"log"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/pkg/pki"
+)
+
+// k3s-deploy-pki 管理 API 双向 TLS 使用的本地 CA：
+// 初始化 CA、签发服务端和客户端证书、吊销客户端证书。
+// 签发 Ingress 证书和集群中间 CA 的 Ingress CA 由 init-ingress 单独生成，与客户端证书 CA 相互独立
+func main() {
+	if len(os.Args) < 2 {
+		usage()
+	}
+
+	cmd, args := os.Args[1], os.Args[2:]
+	var err error
+	switch cmd {
+	case "init":
+		err = initCA(args)
+	case "init-ingress":
+		err = initIngressCA(args)
+	case "server":
+		err = issueServer(args)
+	case "client":
+		err = issueClient(args)
+	case "revoke":
+		err = revoke(args)
+	case "crl":
+		err = renewCRL(args)
+	case "list-revoked":
+		err = listRevoked(args)
+	default:
+		usage()
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+func usage() {
+	fmt.Fprintln(os.Stderr, `用法: k3s-deploy-pki <命令> [参数]
+
+命令:
+  init          生成 CA 证书（ca.crt、ca.key）和空的吊销列表（crl.pem）
+  init-ingress  生成 Ingress CA（ingress-ca.crt、ingress-ca.key），与 init 生成的 CA 相互独立
+  server        签发 API 服务端证书（server.crt、server.key）
+  client        签发客户端证书，-groups 中的 admin/operator/viewer 直接作为角色
+  revoke        吊销客户端证书并更新 crl.pem
+  crl           重新签发 crl.pem 以延长有效期，已吊销的证书保持不变
+  list-revoked  列出已吊销的证书序列号
+
+使用 "k3s-deploy-pki <命令> -h" 查看命令参数`)
+	os.Exit(2)
+}
+
+// caFiles CA 目录中的文件路径
+func caFiles(dir string) (certFile, keyFile, crlFile string) {
+	return filepath.Join(dir, "ca.crt"), filepath.Join(dir, "ca.key"), filepath.Join(dir, "crl.pem")
+}
+
+func initCA(args []string) error {
+	fs := flag.NewFlagSet("init", flag.ExitOnError)
+	dir := fs.String("dir", "data/pki", "CA 目录")
+	cn := fs.String("cn", "k3s-deploy-ca", "CA 名称")
+	years := fs.Int("years", 10, "CA 有效期（年）")
+	fs.Parse(args)
+
+	certFile, keyFile, crlFile := caFiles(*dir)
+	if err := generateCA(*cn, *years, certFile, keyFile); err != nil {
+		return err
+	}
+	// 后端在 CRL 不存在时拒绝客户端证书，初始化时一并生成空的吊销列表
+	ca, err := pki.LoadCA(certFile, keyFile)
+	if err != nil {
+		return err
+	}
+	if err := ca.Revoke(crlFile); err != nil {
+		return err
+	}
+	fmt.Printf("已生成吊销列表: %s\n", crlFile)
+	return nil
+}
+
+func initIngressCA(args []string) error {
+	fs := flag.NewFlagSet("init-ingress", flag.ExitOnError)
+	dir := fs.String("dir", "data/pki", "CA 目录")
+	cn := fs.String("cn", "k3s-deploy-ingress-ca", "CA 名称")
+	years := fs.Int("years", 10, "CA 有效期（年）")
+	fs.Parse(args)
+
+	return generateCA(*cn, *years, filepath.Join(*dir, "ingress-ca.crt"), filepath.Join(*dir, "ingress-ca.key"))
+}
+
+// generateCA 生成自签名 CA，私钥已存在时拒绝覆盖
+func generateCA(cn string, years int, certFile, keyFile string) error {
+	if _, err := os.Stat(keyFile); err == nil {
+		return fmt.Errorf("%s 已存在，拒绝覆盖现有 CA", keyFile)
+	}
+
+	ca, err := pki.GenerateCA(cn, time.Now().AddDate(years, 0, 0))
+	if err != nil {
+		return err
+	}
+	if err := pki.WriteCertificateAndKey(ca.Cert, ca.PrivateKey, certFile, keyFile); err != nil {
+		return err
+	}
+	fmt.Printf("已生成 CA: %s\n", certFile)
+	return nil
+}
+
+func issueServer(args []string) error {
+	fs := flag.NewFlagSet("server", flag.ExitOnError)
+	dir := fs.String("dir", "data/pki", "CA 目录")
+	hosts := fs.String("hosts", "127.0.0.1,localhost", "服务端地址，逗号分隔的 IP 或域名")
+	days := fs.Int("days", 825, "证书有效期（天）")
+	fs.Parse(args)
+
+	names := strings.Split(*hosts, ",")
+	template, err := pki.NewTemplate(strings.TrimSpace(names[0]), false,
+		[]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, time.Now().AddDate(0, 0, *days))
+	if err != nil {
+		return err
+	}
+	for _, name := range names {
+		name = strings.TrimSpace(name)
+		if ip := net.ParseIP(name); ip != nil {
+			template.IPAddresses = append(template.IPAddresses, ip)
+		} else if name != "" {
+			template.DNSNames = append(template.DNSNames, name)
+		}
+	}
+
+	return issue(*dir, template, filepath.Join(*dir, "server.crt"), filepath.Join(*dir, "server.key"))
+}
+
+func issueClient(args []string) error {
+	fs := flag.NewFlagSet("client", flag.ExitOnError)
+	dir := fs.String("dir", "data/pki", "CA 目录")
+	cn := fs.String("cn", "", "用户名（证书 CN）")
+	groups := fs.String("groups", "", "用户组，逗号分隔，写入证书 O 字段")
+	days := fs.Int("days", 365, "证书有效期（天）")
+	out := fs.String("out", ".", "证书输出目录")
+	fs.Parse(args)
+
+	if *cn == "" {
+		return fmt.Errorf("必须指定 -cn")
+	}
+	template, err := pki.NewTemplate(*cn, false,
+		[]x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}, time.Now().AddDate(0, 0, *days))
+	if err != nil {
+		return err
+	}
+	for _, group := range strings.Split(*groups, ",") {
+		if group = strings.TrimSpace(group); group != "" {
+			template.Subject.Organization = append(template.Subject.Organization, group)
+		}
+	}
+
+	return issue(*dir, template, filepath.Join(*out, *cn+".crt"), filepath.Join(*out, *cn+".key"))
+}
+
+func issue(dir string, template *x509.Certificate, certFile, keyFile string) error {
+	caCert, caKey, _ := caFiles(dir)
+	ca, err := pki.LoadCA(caCert, caKey)
+	if err != nil {
+		return err
+	}
+
+	cert, privateKey, err := ca.Issue(template)
+	if err != nil {
+		return err
+	}
+	if err := pki.WriteCertificateAndKey(cert, privateKey, certFile, keyFile); err != nil {
+		return err
+	}
+	fmt.Printf("已签发证书: %s（序列号 %s，有效期至 %s）\n", certFile, cert.SerialNumber.Text(16), cert.NotAfter.Format("2006-01-02"))
+	return nil
+}
+
+func revoke(args []string) error {
+	fs := flag.NewFlagSet("revoke", flag.ExitOnError)
+	dir := fs.String("dir", "data/pki", "CA 目录")
+	serial := fs.String("serial", "", "要吊销的证书序列号（十六进制）")
+	certPath := fs.String("cert", "", "要吊销的证书文件，与 -serial 二选一")
+	fs.Parse(args)
+
+	var number *big.Int
+	switch {
+	case *certPath != "":
+		cert, err := pki.LoadCertificate(*certPath)
+		if err != nil {
+			return err
+		}
+		number = cert.SerialNumber
+	case *serial != "":
+		var ok bool
+		number, ok = new(big.Int).SetString(strings.ReplaceAll(*serial, ":", ""), 16)
+		if !ok {
+			return fmt.Errorf("序列号格式无效: %s", *serial)
+		}
+	default:
+		return fmt.Errorf("必须指定 -serial 或 -cert")
+	}
+
+	caCert, caKey, crlFile := caFiles(*dir)
+	ca, err := pki.LoadCA(caCert, caKey)
+	if err != nil {
+		return err
+	}
+	if err := ca.Revoke(crlFile, number); err != nil {
+		return err
+	}
+	fmt.Printf("已吊销证书 %s，吊销列表: %s\n", number.Text(16), crlFile)
+	return nil
+}
+
+func renewCRL(args []string) error {
+	fs := flag.NewFlagSet("crl", flag.ExitOnError)
+	dir := fs.String("dir", "data/pki", "CA 目录")
+	fs.Parse(args)
+
+	caCert, caKey, crlFile := caFiles(*dir)
+	ca, err := pki.LoadCA(caCert, caKey)
+	if err != nil {
+		return err
+	}
+	if err := ca.Revoke(crlFile); err != nil {
+		return err
+	}
+	crl, err := pki.LoadRevocationList(crlFile, ca.Cert)
+	if err != nil {
+		return err
+	}
+	fmt.Printf("已重新签发吊销列表: %s（下次更新时间 %s）\n", crlFile, crl.NextUpdate.Format("2006-01-02"))
+	return nil
+}
+
+func listRevoked(args []string) error {
+	fs := flag.NewFlagSet("list-revoked", flag.ExitOnError)
+	dir := fs.String("dir", "data/pki", "CA 目录")
+	fs.Parse(args)
+
+	caCert, _, crlFile := caFiles(*dir)
+	cert, err := pki.LoadCertificate(caCert)
+	if err != nil {
+		return err
+	}
+	crl, err := pki.LoadRevocationList(crlFile, cert)
+	if err != nil {
+		return err
+	}
+	if crl == nil {
+		fmt.Println("没有已吊销的证书")
+		return nil
+	}
+	for _, entry := range crl.RevokedCertificateEntries {
+		fmt.Printf("%s\t%s\n", entry.SerialNumber.Text(16), entry.RevocationTime.Format(time.RFC3339))
+	}
+	return nil
+}
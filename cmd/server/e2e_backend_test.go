@@ -0,0 +1,89 @@
+//go:build e2e
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+)
+
+// backend 进程内后端任务接口的客户端
+type backend struct {
+	t   *testing.T
+	url string
+}
+
+// runTask 提交部署任务并等待结束，期间输出新增的任务日志
+func (b *backend) runTask(req *model.DeployRequest, timeout time.Duration) (*model.Task, error) {
+	var task model.Task
+	if err := b.call(http.MethodPost, "/api/tasks", req, &task); err != nil {
+		return nil, fmt.Errorf("提交步骤 %s 失败: %v", req.Step, err)
+	}
+	b.t.Logf("任务 %s 已提交: %s", task.ID, req.Step)
+
+	deadline := time.Now().Add(timeout)
+	printed := 0
+	for {
+		if err := b.call(http.MethodGet, "/api/tasks/"+task.ID, nil, &task); err != nil {
+			return nil, err
+		}
+		for ; printed < len(task.Logs); printed++ {
+			b.t.Logf("  | %s", task.Logs[printed])
+		}
+		switch task.Status {
+		case model.TaskSucceeded:
+			return &task, nil
+		case model.TaskFailed:
+			if task.Failure != nil {
+				return nil, fmt.Errorf("任务 %s 失败（%s）: %s，建议: %s", task.ID, task.Failure.Category, task.Message, task.Failure.Hint)
+			}
+			return nil, fmt.Errorf("任务 %s 失败: %s", task.ID, task.Message)
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("任务 %s 超时，当前步骤 %s", task.ID, task.CurrentStep)
+		}
+		time.Sleep(time.Second)
+	}
+}
+
+// call 发送 JSON 请求，非 2xx 响应返回其中的错误信息
+func (b *backend) call(method, path string, body, out interface{}) error {
+	var reader io.Reader
+	if body != nil {
+		data, err := json.Marshal(body)
+		if err != nil {
+			return err
+		}
+		reader = bytes.NewReader(data)
+	}
+	httpReq, err := http.NewRequest(method, b.url+path, reader)
+	if err != nil {
+		return err
+	}
+	httpReq.Header.Set("Content-Type", "application/json")
+
+	resp, err := http.DefaultClient.Do(httpReq)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	data, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return err
+	}
+	if resp.StatusCode/100 != 2 {
+		var errResp model.ErrorResponse
+		if json.Unmarshal(data, &errResp) == nil && errResp.Message != "" {
+			return fmt.Errorf("%s %s: %s %s", method, path, errResp.Message, errResp.Details)
+		}
+		return fmt.Errorf("%s %s: HTTP %d", method, path, resp.StatusCode)
+	}
+	return json.Unmarshal(data, out)
+}
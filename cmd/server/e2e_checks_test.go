@@ -0,0 +1,110 @@
+//go:build e2e
+
+package main
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+
+	"k3s-deploy-backend/internal/model"
+)
+
+// check 任务完成后对集群状态的一项检查
+type check struct {
+	name string
+	// step 执行了该步骤才进行检查
+	step string
+	run  func(c testCluster, req *model.DeployRequest, url string) error
+}
+
+var allChecks = []check{
+	{name: "Master 安装", step: "install-master", run: checkMaster},
+	{name: "Agent 加入", step: "configure-agent", run: checkAgents},
+	{name: "节点标签", step: "apply-labels", run: checkLabels},
+	{name: "verify 访问地址", step: "verify", run: checkAccessURL},
+}
+
+// checks 返回 steps 覆盖的检查
+func checks(steps string) []check {
+	submitted := strings.Split(steps, ",")
+	for i := range submitted {
+		submitted[i] = strings.TrimSpace(submitted[i])
+	}
+	var result []check
+	for _, c := range allChecks {
+		if slices.Contains(submitted, "all") || slices.Contains(submitted, c.step) {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+func checkMaster(c testCluster, req *model.DeployRequest, url string) error {
+	if err := c.serviceActive("k3s-master", "k3s"); err != nil {
+		return err
+	}
+	nodes, err := c.clusterNodes()
+	if err != nil {
+		return err
+	}
+	master, ok := nodes["k3s-master"]
+	if !ok {
+		return fmt.Errorf("集群中没有节点 k3s-master")
+	}
+	if !master.ready {
+		return fmt.Errorf("k3s-master 未就绪")
+	}
+	if !master.server {
+		return fmt.Errorf("k3s-master 不是 control-plane 节点")
+	}
+	return nil
+}
+
+func checkAgents(c testCluster, req *model.DeployRequest, url string) error {
+	nodes, err := c.clusterNodes()
+	if err != nil {
+		return err
+	}
+	members := c.nodes()
+	for _, n := range members[1:] {
+		if err := c.serviceActive(n.name, "k3s-agent"); err != nil {
+			return err
+		}
+		agent, ok := nodes[n.name]
+		if !ok {
+			return fmt.Errorf("集群中没有节点 %s", n.name)
+		}
+		if !agent.ready {
+			return fmt.Errorf("%s 未就绪", n.name)
+		}
+	}
+	if len(nodes) != len(members) {
+		return fmt.Errorf("集群有 %d 个节点，预期 %d 个", len(nodes), len(members))
+	}
+	return nil
+}
+
+func checkLabels(c testCluster, req *model.DeployRequest, url string) error {
+	nodes, err := c.clusterNodes()
+	if err != nil {
+		return err
+	}
+	for name, labels := range req.Labels {
+		n, ok := nodes[name]
+		if !ok {
+			return fmt.Errorf("集群中没有节点 %s", name)
+		}
+		for _, label := range labels {
+			key, value, _ := strings.Cut(label, "=")
+			if got, ok := n.labels[key]; !ok || got != value {
+				return fmt.Errorf("节点 %s 缺少标签 %s（当前值 %q）", name, label, got)
+			}
+		}
+	}
+	return nil
+}
+
+func checkAccessURL(c testCluster, req *model.DeployRequest, url string) error {
+	return c.reach(url)
+}
@@ -0,0 +1,245 @@
+//go:build e2e
+
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"k3s-deploy-backend/internal/pkg/sshsim"
+)
+
+// node 作为集群节点的容器或模拟主机
+type node struct {
+	name      string
+	container string
+	ip        string
+}
+
+// clusterNode 检查用到的节点状态
+type clusterNode struct {
+	ready  bool
+	server bool
+	labels map[string]string
+}
+
+// testCluster 部署目标：模拟后端或 Docker 容器
+type testCluster interface {
+	// nodes 返回全部节点，第一个为 Master
+	nodes() []node
+	// clusterNodes 返回已加入集群的节点，按节点名索引
+	clusterNodes() (map[string]clusterNode, error)
+	// serviceActive 检查节点上的 systemd 服务是否在运行
+	serviceActive(name, unit string) error
+	// reach 检查 verify 返回的访问地址
+	reach(url string) error
+}
+
+// simCluster 由模拟后端应答的节点，集群状态取自模拟后端登记的节点和标签
+type simCluster struct {
+	sim     *sshsim.Simulator
+	members []node
+}
+
+func (c *simCluster) nodes() []node { return c.members }
+
+func (c *simCluster) clusterNodes() (map[string]clusterNode, error) {
+	nodes := make(map[string]clusterNode)
+	for _, n := range c.sim.Nodes() {
+		nodes[n.Name] = clusterNode{ready: true, server: n.Server, labels: n.Labels}
+	}
+	return nodes, nil
+}
+
+// serviceActive 模拟节点执行过安装命令即视为服务在运行
+func (c *simCluster) serviceActive(name, unit string) error {
+	for _, n := range c.sim.Nodes() {
+		if n.Name == name && n.Server == (unit == "k3s") {
+			return nil
+		}
+	}
+	return fmt.Errorf("节点 %s 未安装 %s", name, unit)
+}
+
+// reach 模拟集群的访问地址不可访问，只检查已返回
+func (c *simCluster) reach(url string) error {
+	if url == "" {
+		return fmt.Errorf("任务没有返回访问地址")
+	}
+	return nil
+}
+
+// dockerCluster 一次测试使用的容器和网络，名称都以 prefix 开头
+type dockerCluster struct {
+	t       *testing.T
+	prefix  string
+	image   string
+	members []node
+}
+
+// buildImage 构建节点镜像
+func buildImage(t *testing.T, image, dockerfile, password string) error {
+	t.Logf("构建节点镜像 %s", image)
+	cmd := exec.Command("docker", "build", "-t", image, "-f", dockerfile,
+		"--build-arg", "ROOT_PASSWORD="+password, filepath.Dir(dockerfile))
+	cmd.Stdout = os.Stderr
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("构建节点镜像失败: %v", err)
+	}
+	return nil
+}
+
+// start 创建网络并启动节点容器，等待各节点的 sshd 可连接
+func (c *dockerCluster) start(names []string) error {
+	if _, err := docker("network", "create", c.prefix); err != nil {
+		return err
+	}
+	for _, name := range names {
+		container := c.prefix + "-" + name
+		// k3s 需要特权容器；数据目录使用镜像中声明的匿名卷，避免 overlay 嵌套
+		_, err := docker("run", "-d", "--name", container, "--hostname", name,
+			"--network", c.prefix, "--privileged", "--cgroupns=private",
+			"--tmpfs", "/run", "--tmpfs", "/run/lock",
+			"-v", "/lib/modules:/lib/modules:ro", c.image)
+		if err != nil {
+			return err
+		}
+		ip, err := docker("inspect", "-f", "{{range .NetworkSettings.Networks}}{{.IPAddress}}{{end}}", container)
+		if err != nil {
+			return err
+		}
+		c.members = append(c.members, node{name: name, container: container, ip: ip})
+		c.t.Logf("节点 %s 已启动: %s", name, ip)
+	}
+	for _, n := range c.members {
+		if err := waitSSH(n.ip, 2*time.Minute); err != nil {
+			return fmt.Errorf("节点 %s: %v", n.name, err)
+		}
+	}
+	return nil
+}
+
+// remove 删除容器（含匿名卷）和网络
+func (c *dockerCluster) remove() {
+	for _, n := range c.members {
+		if _, err := docker("rm", "-f", "-v", n.container); err != nil {
+			c.t.Logf("删除容器 %s 失败: %v", n.container, err)
+		}
+	}
+	if _, err := docker("network", "rm", c.prefix); err != nil {
+		c.t.Logf("删除网络 %s 失败: %v", c.prefix, err)
+	}
+}
+
+func (c *dockerCluster) nodes() []node { return c.members }
+
+// kubeNode kubectl get nodes -o json 中用到的字段
+type kubeNode struct {
+	Metadata struct {
+		Name   string            `json:"name"`
+		Labels map[string]string `json:"labels"`
+	} `json:"metadata"`
+	Status struct {
+		Conditions []struct {
+			Type   string `json:"type"`
+			Status string `json:"status"`
+		} `json:"conditions"`
+	} `json:"status"`
+}
+
+// clusterNodes 在 Master 容器内读取集群节点
+func (c *dockerCluster) clusterNodes() (map[string]clusterNode, error) {
+	out, err := c.exec("k3s-master", "k3s", "kubectl", "get", "nodes", "-o", "json")
+	if err != nil {
+		return nil, err
+	}
+	var list struct {
+		Items []kubeNode `json:"items"`
+	}
+	if err := json.Unmarshal([]byte(out), &list); err != nil {
+		return nil, fmt.Errorf("解析节点列表失败: %v", err)
+	}
+	nodes := make(map[string]clusterNode, len(list.Items))
+	for _, n := range list.Items {
+		state := clusterNode{labels: n.Metadata.Labels}
+		for _, cond := range n.Status.Conditions {
+			if cond.Type == "Ready" {
+				state.ready = cond.Status == "True"
+			}
+		}
+		_, state.server = n.Metadata.Labels["node-role.kubernetes.io/control-plane"]
+		nodes[n.Metadata.Name] = state
+	}
+	return nodes, nil
+}
+
+func (c *dockerCluster) serviceActive(name, unit string) error {
+	if out, err := c.exec(name, "systemctl", "is-active", unit); err != nil {
+		return fmt.Errorf("%s 的 %s 服务未运行: %s %v", name, unit, out, err)
+	}
+	return nil
+}
+
+func (c *dockerCluster) reach(url string) error {
+	if url == "" {
+		return fmt.Errorf("任务没有返回访问地址")
+	}
+	client := &http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(url)
+	if err != nil {
+		return fmt.Errorf("访问 %s 失败: %v", url, err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode >= 500 {
+		return fmt.Errorf("访问 %s 返回 HTTP %d", url, resp.StatusCode)
+	}
+	return nil
+}
+
+// exec 在节点容器内执行命令
+func (c *dockerCluster) exec(name string, args ...string) (string, error) {
+	return docker(append([]string{"exec", c.prefix + "-" + name}, args...)...)
+}
+
+// docker 执行 docker 命令并返回去掉首尾空白的标准输出
+func docker(args ...string) (string, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command("docker", args...)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("docker %s 失败: %v: %s", args[0], err, strings.TrimSpace(stderr.String()))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// waitSSH 等待 sshd 返回协议标识
+func waitSSH(ip string, timeout time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		conn, err := net.DialTimeout("tcp", net.JoinHostPort(ip, "22"), 3*time.Second)
+		if err == nil {
+			conn.SetReadDeadline(time.Now().Add(3 * time.Second))
+			banner := make([]byte, 4)
+			_, err = conn.Read(banner)
+			conn.Close()
+			if err == nil && string(banner) == "SSH-" {
+				return nil
+			}
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("等待 sshd 超时: %v", err)
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
@@ -0,0 +1,179 @@
+//go:build e2e
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k3s-deploy-backend/internal/config"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/ssh"
+	"k3s-deploy-backend/internal/pkg/sshsim"
+)
+
+// 端到端测试：在进程内启动后端（与 main 相同的 newServer），通过任务接口执行部署流水线，
+// 再检查 Master 安装、Agent 加入、节点标签和 verify 步骤的结果。使用 e2e 构建标签，常规构建和 go test ./... 不包含：
+//
+//	go test -tags e2e ./cmd/server -run TestEndToEnd -e2e.agents 2
+//
+// 默认节点命令由内置模拟后端应答；-e2e.docker 时用 Docker 启动带 systemd 和 sshd 的容器作为节点，
+// 检查在 Master 容器内执行
+var (
+	e2eAgents     = flag.Int("e2e.agents", 1, "Agent 节点数（0-2），决定部署模式 single/dual/triple")
+	e2eSteps      = flag.String("e2e.steps", "all", "依次提交的步骤，逗号分隔；all 执行完整流水线")
+	e2eDocker     = flag.Bool("e2e.docker", false, "使用 Docker 容器作为节点，测试进程需能直接访问容器网络（Docker Desktop 不适用）")
+	e2eImage      = flag.String("e2e.image", "k3s-deploy-e2e-node:latest", "节点镜像")
+	e2eDockerfile = flag.String("e2e.dockerfile", "../../scripts/e2e/node.Dockerfile", "节点镜像的 Dockerfile，为空时不构建、直接使用 -e2e.image")
+	e2ePassword   = flag.String("e2e.password", "k3s-e2e", "节点 root 密码，需与镜像构建参数 ROOT_PASSWORD 一致")
+	e2eTimeout    = flag.Duration("e2e.timeout", 30*time.Minute, "每个任务的超时时间")
+	e2eKeep       = flag.Bool("e2e.keep", false, "结束后保留容器，便于排查")
+)
+
+func TestEndToEnd(t *testing.T) {
+	if *e2eAgents < 0 || *e2eAgents > 2 {
+		t.Fatalf("-e2e.agents 只能为 0-2，当前 %d", *e2eAgents)
+	}
+	names := []string{"k3s-master"}
+	for i := 0; i < *e2eAgents; i++ {
+		names = append(names, agentName(i))
+	}
+
+	var cluster testCluster
+	if *e2eDocker {
+		cluster = startDockerCluster(t, names)
+	} else {
+		cluster = startSimCluster(t, names)
+	}
+	b := startBackend(t)
+
+	req := deployRequest(cluster.nodes(), *e2ePassword)
+	if !*e2eDocker {
+		simulate(req)
+	}
+	var url string
+	for _, step := range strings.Split(*e2eSteps, ",") {
+		req.Step = strings.TrimSpace(step)
+		task, err := b.runTask(req, *e2eTimeout)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if task.URL != "" {
+			url = task.URL
+		}
+	}
+
+	for _, check := range checks(*e2eSteps) {
+		if err := check.run(cluster, req, url); err != nil {
+			t.Errorf("%s: %v", check.name, err)
+			continue
+		}
+		t.Logf("ok   %s", check.name)
+	}
+}
+
+// startBackend 以单机模式的默认配置在临时目录中启动后端，不启用认证和前端
+func startBackend(t *testing.T) *backend {
+	cfg := config.Standalone(filepath.Join(t.TempDir(), "data"))
+	cfg.Server.Frontend.Enabled = false
+	if err := cfg.Validate(); err != nil {
+		t.Fatalf("配置验证失败: %v", err)
+	}
+	appLogger := logger.NewLogger()
+	appLogger.SetLevel(logrus.WarnLevel)
+
+	r, closeServer := newServer(cfg, "", appLogger)
+	server := httptest.NewServer(r)
+	t.Cleanup(func() {
+		server.Close()
+		closeServer()
+	})
+	return &backend{t: t, url: server.URL + cfg.Server.BasePath}
+}
+
+// startSimCluster 使用内置规则的模拟后端应答节点命令
+func startSimCluster(t *testing.T, names []string) *simCluster {
+	sim, err := sshsim.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ssh.SetBackend(sim)
+	t.Cleanup(func() { ssh.SetBackend(nil) })
+
+	c := &simCluster{sim: sim}
+	for i, name := range names {
+		c.members = append(c.members, node{name: name, ip: fmt.Sprintf("10.0.0.%d", i+1)})
+	}
+	return c
+}
+
+// startDockerCluster 构建节点镜像并启动容器，测试结束时删除（-e2e.keep 时保留）
+func startDockerCluster(t *testing.T, names []string) *dockerCluster {
+	if *e2eDockerfile != "" {
+		if err := buildImage(t, *e2eImage, *e2eDockerfile, *e2ePassword); err != nil {
+			t.Fatal(err)
+		}
+	}
+	runID := fmt.Sprintf("k3s-e2e-%d", time.Now().Unix())
+	c := &dockerCluster{t: t, prefix: runID, image: *e2eImage}
+	t.Cleanup(func() {
+		if *e2eKeep {
+			t.Logf("保留容器 %s-*，清理: docker rm -f $(docker ps -aq -f name=%s-) && docker network rm %s", runID, runID, runID)
+			return
+		}
+		c.remove()
+	})
+	if err := c.start(names); err != nil {
+		t.Fatal(err)
+	}
+	return c
+}
+
+// deployRequest 按节点数生成部署请求：组件依次分配到各节点，标签与角色分配一致
+func deployRequest(nodes []node, password string) *model.DeployRequest {
+	modes := []string{"single", "dual", "triple"}
+	req := &model.DeployRequest{
+		DeployMode:     modes[len(nodes)-1],
+		RoleAssignment: make(map[string]string),
+		Labels:         make(map[string][]string),
+	}
+	for _, n := range nodes {
+		req.Nodes = append(req.Nodes, model.NodeConfig{
+			Name:     n.name,
+			IP:       n.ip,
+			Port:     22,
+			Username: "root",
+			AuthType: "password",
+			Password: password,
+		})
+	}
+	for i, role := range []string{"app", "middleware", "database"} {
+		name := nodes[i*len(nodes)/3].name
+		req.RoleAssignment[role] = name
+		req.Labels[name] = append(req.Labels[name], "insuite."+role+"=true")
+	}
+	return req
+}
+
+// simulate 模拟节点无法下载安装脚本和校验 k3s 二进制，使用内联脚本并跳过网络检查
+func simulate(req *model.DeployRequest) {
+	req.InstallScript = &model.InstallScriptOptions{Content: "#!/bin/sh\nsetup_env() {\n}\n"}
+	req.AllowUnverifiedArtifacts = true
+	req.NetworkCheck = &model.NetworkCheckOptions{Disabled: true}
+	req.Wait = &model.WaitOptions{ServiceTimeout: 10, DeploymentTimeout: 10, PollInterval: 1}
+}
+
+// agentName 与后端生成的 Agent 节点名一致
+func agentName(index int) string {
+	if index == 0 {
+		return "k3s-agent"
+	}
+	return fmt.Sprintf("k3s-agent-%d", index+1)
+}
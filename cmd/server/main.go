@@ -1,22 +1,62 @@
 package main
 
 import (
+	"flag"
 	"fmt"
 	"k3s-deploy-backend/internal/config"
 	"log"
+	"net"
 	"net/http"
+	"os"
+	"strconv"
 
-	"github.com/gin-contrib/cors"
-	"github.com/gin-gonic/gin"
-	"k3s-deploy-backend/internal/handler"
+	"github.com/sirupsen/logrus"
+	"k3s-deploy-backend/internal/middleware"
 	"k3s-deploy-backend/internal/pkg/logger"
-	"k3s-deploy-backend/internal/router"
-	"k3s-deploy-backend/internal/service"
+	"k3s-deploy-backend/internal/pkg/pki"
+	"k3s-deploy-backend/internal/pkg/webui"
 )
 
 func main() {
-	// 加载配置
-	cfg := config.LoadConfig()
+	standalone := flag.Bool("standalone", false, "单机模式：无需配置文件，只监听本机地址，启动时输出带访问令牌的地址并打开浏览器")
+	dataDir := flag.String("data", defaultDataDir(), "单机模式的数据目录")
+	port := flag.Int("port", 0, "单机模式的监听端口，0 表示随机选择空闲端口")
+	noBrowser := flag.Bool("no-browser", false, "单机模式下不自动打开浏览器")
+	svc := registerServiceFlags()
+	flag.Parse()
+
+	// 安装或卸载 systemd 服务后直接退出
+	if *svc.install || *svc.uninstall {
+		run := installService
+		if *svc.uninstall {
+			run = uninstallService
+		}
+		if err := run(svc); err != nil {
+			log.Fatalf("%v", err)
+		}
+		return
+	}
+
+	// 加载配置；单机模式不读取配置文件，先占用端口再生成配置
+	var cfg *config.Config
+	var listener net.Listener
+	if *standalone {
+		if err := os.MkdirAll(*dataDir, 0700); err != nil {
+			log.Fatalf("创建数据目录失败: %v", err)
+		}
+		cfg = config.Standalone(*dataDir)
+		ln, err := net.Listen("tcp", net.JoinHostPort(cfg.Server.Host, strconv.Itoa(*port)))
+		if err != nil {
+			log.Fatalf("监听端口失败: %v", err)
+		}
+		listener = ln
+		cfg.Server.Port = ln.Addr().(*net.TCPAddr).Port
+		if !webui.Embedded() {
+			cfg.Server.Frontend.Enabled = false
+		}
+	} else {
+		cfg = config.LoadConfig()
+	}
 
 	// 验证配置
 	if err := cfg.Validate(); err != nil {
@@ -30,43 +70,58 @@ func main() {
 
 	// 初始化日志
 	appLogger := logger.NewLogger()
+	if level, err := logrus.ParseLevel(cfg.Logging.Level); err == nil {
+		appLogger.SetLevel(level)
+	}
 
-	// 初始化服务
-	sshService := service.NewSSHService(appLogger)
-	k3sService := service.NewK3sService(appLogger)
-	deployService := service.NewDeployService(sshService, k3sService, appLogger)
-
-	// 初始化处理器
-	sshHandler := handler.NewSSHHandler(sshService)
-	k3sHandler := handler.NewK3sHandler(deployService)
-
-	// 设置 Gin 模式
-	gin.SetMode(gin.ReleaseMode)
-
-	// 创建路由
-	r := gin.New()
-
-	// 中间件
-	r.Use(gin.Logger())
-	r.Use(gin.Recovery())
-
-	// CORS 配置（从配置文件读取）
-	corsConfig := cors.DefaultConfig()
-	corsConfig.AllowOrigins = cfg.Server.CORSOrigins
-	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
-	corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization"}
-	r.Use(cors.New(corsConfig))
+	// 单机模式使用启动时生成的访问令牌，代替用户认证
+	var localToken string
+	if *standalone {
+		token, err := middleware.NewLocalToken()
+		if err != nil {
+			log.Fatalf("%v", err)
+		}
+		localToken = token
+	}
 
-	// 注册路由
-	router.RegisterRoutes(r, sshHandler, k3sHandler)
+	r, closeServer := newServer(cfg, localToken, appLogger)
+	defer closeServer()
 
-	// 健康检查
-	r.GET("/health", func(c *gin.Context) {
-		c.JSON(http.StatusOK, gin.H{"status": "ok"})
-	})
+	if *standalone {
+		if !cfg.Server.Frontend.Enabled {
+			appLogger.Warn("本程序未内嵌前端，单机模式只提供 API，请求需携带 Authorization: Bearer <访问令牌>")
+		}
+		accessURL := fmt.Sprintf("http://%s%s/?token=%s", listener.Addr(), cfg.Server.BasePath, localToken)
+		printAccessURL(accessURL)
+		if cfg.Server.Frontend.Enabled && !*noBrowser {
+			if err := openBrowser(accessURL, *dataDir); err != nil {
+				appLogger.Warnf("打开浏览器失败: %v，请手动打开上述地址", err)
+			}
+		}
+		if err := http.Serve(listener, r); err != nil {
+			log.Fatal("Failed to start server:", err)
+		}
+		return
+	}
 
 	// 启动服务（使用配置文件中的地址和端口）
 	addr := fmt.Sprintf("%s:%d", cfg.Server.Host, cfg.Server.Port)
+	if tlsCfg := cfg.Server.TLS; tlsCfg.Enabled {
+		tlsConfig, err := pki.ServerTLSConfig(pki.ServerTLSOptions{
+			ClientAuth:   tlsCfg.ClientAuth,
+			ClientCAFile: tlsCfg.ClientCAFile,
+			CRLFile:      tlsCfg.CRLFile,
+		})
+		if err != nil {
+			log.Fatalf("初始化 TLS 失败: %v", err)
+		}
+		server := &http.Server{Addr: addr, Handler: r, TLSConfig: tlsConfig}
+		appLogger.Infof("Server starting on %s (TLS, client auth: %s)", addr, tlsCfg.ClientAuth)
+		if err := server.ListenAndServeTLS(tlsCfg.CertFile, tlsCfg.KeyFile); err != nil {
+			log.Fatal("Failed to start server:", err)
+		}
+		return
+	}
 	appLogger.Infof("Server starting on %s", addr)
 	if err := r.Run(addr); err != nil {
 		log.Fatal("Failed to start server:", err)
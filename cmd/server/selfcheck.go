@@ -0,0 +1,107 @@
+package main
+
+import (
+	"context"
+	"path/filepath"
+	"time"
+
+	"k3s-deploy-backend/internal/config"
+	"k3s-deploy-backend/internal/pkg/pki"
+	"k3s-deploy-backend/internal/pkg/selfcheck"
+	"k3s-deploy-backend/internal/pkg/store"
+	"k3s-deploy-backend/internal/service"
+)
+
+// readinessTimeout 就绪检查单项的超时时间，应小于探针的超时时间
+const readinessTimeout = 3 * time.Second
+
+// selfChecks 按配置生成启动自检项：只检查已启用功能使用的目录和证书
+func selfChecks(cfg *config.Config, stateStore store.Store) []selfcheck.Check {
+	var checks []selfcheck.Check
+	seen := make(map[string]bool)
+	addDir := func(name, dir string) {
+		if dir == "" || seen[dir] {
+			return
+		}
+		seen[dir] = true
+		checks = append(checks, selfcheck.Check{Name: name, Target: dir, Run: func(context.Context) (string, error) {
+			return selfcheck.WritableDir(dir)
+		}})
+	}
+
+	if cfg.Store.Backend == "sqlite" {
+		addDir("data-dir", filepath.Dir(cfg.Store.SQLite.Path))
+	}
+	addDir("data-dir", filepath.Dir(cfg.Vault.Path))
+	addDir("data-dir", filepath.Dir(cfg.Vault.KeyFile))
+	if cfg.Auth.Enabled {
+		addDir("data-dir", filepath.Dir(cfg.Auth.SessionKeyFile))
+	}
+	if cfg.SSHCA.Enabled {
+		addDir("data-dir", filepath.Dir(cfg.SSHCA.KeyFile))
+	}
+	if cfg.GitOps.Enabled {
+		addDir("data-dir", cfg.GitOps.WorkDir)
+	}
+	addDir("data-dir", filepath.Dir(cfg.JoinBundle.KeyFile))
+	if cfg.Transcripts.Record {
+		addDir("log-dir", cfg.Transcripts.Dir)
+	}
+	addDir("log-dir", cfg.Progress.LogDir)
+
+	if tlsCfg := cfg.Server.TLS; tlsCfg.Enabled {
+		checks = append(checks, selfcheck.Check{Name: "server-tls", Target: tlsCfg.CertFile, Run: func(context.Context) (string, error) {
+			return selfcheck.ServerCertificate(tlsCfg.CertFile, tlsCfg.KeyFile)
+		}})
+		if tlsCfg.ClientAuth != "" && tlsCfg.ClientAuth != pki.ClientAuthNone {
+			checks = append(checks, selfcheck.Check{Name: "client-ca", Target: tlsCfg.ClientCAFile, Run: func(context.Context) (string, error) {
+				return selfcheck.ClientCA(tlsCfg.ClientCAFile, tlsCfg.CRLFile)
+			}})
+		}
+	}
+	ingress := cfg.IngressTLS
+	checks = append(checks, selfcheck.Check{Name: "ingress-ca", Target: ingress.CACertFile, Run: func(context.Context) (string, error) {
+		return selfcheck.IssuingCA(ingress.CACertFile, ingress.CAKeyFile)
+	}})
+
+	checks = append(checks, selfcheck.Check{Name: "store", Target: cfg.Store.Backend, Run: func(context.Context) (string, error) {
+		return selfcheck.Store(stateStore)
+	}})
+
+	if cfg.SelfCheck.Mirrors {
+		registries := cfg.Registry.Mirrors
+		if cfg.Registry.SystemDefault != "" {
+			registries = append([]string{cfg.Registry.SystemDefault}, registries...)
+		}
+		for _, registry := range registries {
+			checks = append(checks, selfcheck.Check{Name: "mirror", Target: registry, Optional: true, Run: func(ctx context.Context) (string, error) {
+				return selfcheck.Registry(ctx, registry)
+			}})
+		}
+	}
+	return checks
+}
+
+// readinessChecks 就绪检查项：存储可用、任务调度正常、配置及其引用的服务端证书仍然有效
+func readinessChecks(cfg *config.Config, stateStore store.Store, taskService *service.TaskService) []selfcheck.Check {
+	return []selfcheck.Check{
+		{Name: "store", Target: cfg.Store.Backend, Run: func(context.Context) (string, error) {
+			return selfcheck.Store(stateStore)
+		}},
+		{Name: "task-engine", Run: func(context.Context) (string, error) {
+			if err := taskService.Ready(); err != nil {
+				return "", err
+			}
+			return "任务调度正常", nil
+		}},
+		{Name: "config", Run: func(context.Context) (string, error) {
+			if err := cfg.Validate(); err != nil {
+				return "", err
+			}
+			if tlsCfg := cfg.Server.TLS; tlsCfg.Enabled {
+				return selfcheck.ServerCertificate(tlsCfg.CertFile, tlsCfg.KeyFile)
+			}
+			return "配置有效", nil
+		}},
+	}
+}
@@ -0,0 +1,616 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"os"
+	"time"
+
+	"github.com/gin-contrib/cors"
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"k3s-deploy-backend/internal/config"
+	"k3s-deploy-backend/internal/handler"
+	"k3s-deploy-backend/internal/middleware"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/agent"
+	"k3s-deploy-backend/internal/pkg/auth"
+	"k3s-deploy-backend/internal/pkg/bundle"
+	"k3s-deploy-backend/internal/pkg/faults"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/notify"
+	"k3s-deploy-backend/internal/pkg/progress"
+	"k3s-deploy-backend/internal/pkg/selfcheck"
+	"k3s-deploy-backend/internal/pkg/ssh"
+	"k3s-deploy-backend/internal/pkg/sshsim"
+	"k3s-deploy-backend/internal/pkg/store"
+	"k3s-deploy-backend/internal/pkg/transcript"
+	"k3s-deploy-backend/internal/pkg/vault"
+	"k3s-deploy-backend/internal/pkg/webui"
+	"k3s-deploy-backend/internal/router"
+	"k3s-deploy-backend/internal/service"
+)
+
+// newServer 按配置初始化存储、服务和处理器并注册全部路由，返回的函数关闭状态存储。
+// localToken 非空时（单机模式）所有接口需携带该访问令牌。端到端测试在进程内以同一方式启动后端
+func newServer(cfg *config.Config, localToken string, appLogger *logger.Logger) (*gin.Engine, func()) {
+	// 节点命令录制，按请求 ID 保存命令与输出
+	var transcripts *transcript.Recorder
+	if cfg.Transcripts.Record {
+		recorder, err := transcript.NewRecorder(cfg.Transcripts.Dir)
+		if err != nil {
+			log.Fatalf("初始化节点命令录制失败: %v", err)
+		}
+		transcripts = recorder
+		appLogger.Infof("节点命令录制已启用，保存到 %s", cfg.Transcripts.Dir)
+	}
+
+	// 远程命令日志带上发起请求的 ID，便于端到端追踪
+	ssh.SetCommandLogger(func(entry ssh.CommandLog) {
+		if transcripts != nil {
+			if err := transcripts.Record(entry); err != nil {
+				appLogger.Warnf("录制节点命令失败: %v", err)
+			}
+		}
+		fields := logrus.Fields{
+			"type":      "ssh_command",
+			"requestId": entry.RequestID,
+			"host":      entry.Host,
+			"command":   entry.Command,
+			"exitCode":  entry.ExitCode,
+			"duration":  entry.Duration.String(),
+		}
+		switch {
+		case entry.Connect:
+			appLogger.WithFields(fields).WithError(entry.Err).Debug("连接节点失败")
+			return
+		case entry.Upload != "":
+			fields["upload"] = entry.Upload
+		}
+		if entry.Err != nil {
+			appLogger.WithFields(fields).WithError(entry.Err).Debug("远程命令执行失败")
+			return
+		}
+		appLogger.WithFields(fields).Debug("远程命令执行完成")
+	})
+
+	// 部署进度事件：安装器、预检和任务队列只发布事件，由以下消费者分别写日志、文件和推送 Webhook，
+	// 任务日志和 SSE 推送在 TaskService 和任务接口中订阅
+	progress.Default.Handle(func(e model.ProgressEvent) {
+		if e.Message == "" {
+			return
+		}
+		entry := appLogger.WithFields(logrus.Fields{
+			"type":      "progress",
+			"event":     e.Type,
+			"requestId": e.RequestID,
+			"step":      e.Step,
+			"node":      e.Node,
+		})
+		switch e.Level {
+		case model.LogError:
+			entry.Error(e.Message)
+		case model.LogWarn:
+			entry.Warn(e.Message)
+		default:
+			entry.Info(e.Message)
+		}
+	})
+	var progressLog *progress.FileWriter
+	if cfg.Progress.LogDir != "" {
+		w, err := progress.NewFileWriter(cfg.Progress.LogDir)
+		if err != nil {
+			log.Fatalf("初始化进度事件文件失败: %v", err)
+		}
+		progressLog = w
+		progress.Default.Handle(func(e model.ProgressEvent) {
+			if err := w.Write(e); err != nil {
+				appLogger.Warnf("写入进度事件失败: %v", err)
+			}
+		})
+		appLogger.Infof("进度事件已写入 %s", cfg.Progress.LogDir)
+	}
+	for _, hook := range cfg.Progress.Webhooks {
+		notifier := notify.NewWebhookNotifier(hook.URL)
+		// Webhook 较慢，异步推送，积压过多时丢弃事件而不阻塞部署
+		progress.Default.Subscribe(1024, progress.Types(hook.Types)).Run(func(e model.ProgressEvent) {
+			if err := notifier.Notify(notify.Event{
+				Type:    e.Type,
+				Title:   e.Type,
+				Message: e.Message,
+				Time:    e.Time,
+				Data:    e,
+			}); err != nil {
+				appLogger.Warnf("推送进度事件到 %s 失败: %v", hook.URL, err)
+			}
+		})
+	}
+
+	// 初始化 Agent 反向通道
+	var agentHub *agent.Hub
+	if cfg.Agent.Enabled {
+		agentHub = agent.NewHub(cfg.Agent.EnrollToken, appLogger)
+		ssh.RegisterTransport("agent", agentHub.Dial)
+		appLogger.Info("Agent 反向连接模式已启用")
+	}
+
+	// 模拟模式下节点命令由内存后端应答，不连接真实节点
+	if cfg.Simulation.Enabled {
+		sim, err := sshsim.Load(cfg.Simulation.Script)
+		if err != nil {
+			log.Fatalf("加载SSH模拟后端失败: %v", err)
+		}
+		ssh.SetBackend(sim)
+		appLogger.Warn("SSH 模拟后端已启用，所有节点操作均为模拟，不会连接真实节点")
+	}
+
+	// 回放模式下节点命令按录制文件应答，用于离线复现安装失败
+	if cfg.Transcripts.Replay != "" {
+		replayer, err := transcript.Load(cfg.Transcripts.Replay)
+		if err != nil {
+			log.Fatalf("加载命令回放失败: %v", err)
+		}
+		ssh.SetBackend(replayer)
+		appLogger.Warnf("命令回放已启用，节点操作按 %s 应答，不会连接真实节点", cfg.Transcripts.Replay)
+	}
+
+	// 故障注入用于验证重试、回滚和断点续跑，节点命令和部署步骤会按配置失败
+	var faultInjector *faults.Injector
+	if cfg.Faults.Enabled {
+		opts := faults.Options{DropAfter: cfg.Faults.DropAfter, FailSteps: make(map[string]int)}
+		if cfg.Faults.Delay != "" {
+			opts.Delay, _ = time.ParseDuration(cfg.Faults.Delay)
+		}
+		for _, spec := range cfg.Faults.FailSteps {
+			step, times, err := faults.ParseStep(spec)
+			if err != nil {
+				log.Fatalf("故障注入配置无效: %v", err)
+			}
+			if !service.KnownStep(step) {
+				log.Fatalf("故障注入配置无效: 未知的部署步骤 %s", step)
+			}
+			opts.FailSteps[step] = times
+		}
+		faultInjector = faults.New(opts)
+		ssh.SetFaultInjector(faultInjector)
+		appLogger.Warnf("故障注入已启用（每 %d 条命令断开连接，命令延迟 %s，失败步骤 %v），不要在生产环境使用",
+			cfg.Faults.DropAfter, opts.Delay, cfg.Faults.FailSteps)
+	}
+
+	// 打开共享状态存储
+	stateStore, err := store.Open(store.Options{
+		Backend:   cfg.Store.Backend,
+		Addr:      cfg.Store.Redis.Addr,
+		Password:  cfg.Store.Redis.Password,
+		DB:        cfg.Store.Redis.DB,
+		KeyPrefix: cfg.Store.KeyPrefix,
+		Path:      cfg.Store.SQLite.Path,
+	})
+	if err != nil {
+		log.Fatalf("打开状态存储失败: %v", err)
+	}
+
+	// 打开凭据库：memory 模式使用本地文件，其余模式凭据保存在状态存储中
+	var credentialVault *vault.Vault
+	if cfg.Store.Backend == "memory" {
+		credentialVault, err = vault.Open(cfg.Vault.Path, cfg.Vault.KeyFile)
+	} else {
+		if imported, err := vault.ImportFile(cfg.Vault.Path, stateStore); err != nil {
+			log.Fatalf("迁移凭据库文件失败: %v", err)
+		} else if imported > 0 {
+			appLogger.Infof("已将 %d 条凭据从 %s 迁移到状态存储", imported, cfg.Vault.Path)
+		}
+		credentialVault, err = vault.OpenStore(stateStore, cfg.Vault.KeyFile)
+	}
+	if err != nil {
+		log.Fatalf("打开凭据库失败: %v", err)
+	}
+
+	// 启动自检：目录、证书或存储有问题时立即退出，而不是在部署过程中才失败
+	selfCheckReport := model.SelfCheckReport{Status: model.SelfCheckDisabled, Checks: []model.SelfCheckResult{}}
+	if cfg.SelfCheck.Enabled {
+		timeout, _ := time.ParseDuration(cfg.SelfCheck.Timeout)
+		selfCheckReport = selfcheck.Run(selfChecks(cfg, stateStore), timeout)
+		for _, result := range selfCheckReport.Checks {
+			entry := appLogger.WithFields(logrus.Fields{
+				"type":       "self_check",
+				"check":      result.Name,
+				"target":     result.Target,
+				"status":     result.Status,
+				"durationMs": result.DurationMs,
+			})
+			switch result.Status {
+			case model.SelfCheckFail:
+				entry.Error(result.Message)
+			case model.SelfCheckWarn:
+				entry.Warn(result.Message)
+			default:
+				entry.Debug(result.Message)
+			}
+		}
+		if selfCheckReport.Status == model.SelfCheckFail {
+			report, _ := json.MarshalIndent(selfCheckReport, "", "  ")
+			fmt.Fprintln(os.Stderr, string(report))
+			log.Fatalf("启动自检失败，请根据以上报告修复后重新启动")
+		}
+		appLogger.Infof("启动自检完成（%s，%d 项，%dms）", selfCheckReport.Status, len(selfCheckReport.Checks), selfCheckReport.DurationMs)
+	}
+
+	// 初始化服务
+	// 请求中未设置 wait 参数时的等待时长（已由配置校验）
+	var waitDefaults k3s.WaitPolicy
+	waitDefaults.ServiceTimeout, _ = time.ParseDuration(cfg.Tasks.Wait.ServiceTimeout)
+	waitDefaults.DeploymentTimeout, _ = time.ParseDuration(cfg.Tasks.Wait.DeploymentTimeout)
+	waitDefaults.PollInterval, _ = time.ParseDuration(cfg.Tasks.Wait.PollInterval)
+	waitDefaults.InstallTimeout, _ = time.ParseDuration(cfg.Tasks.Wait.InstallTimeout)
+	waitDefaults.ScriptDownloadTimeout, _ = time.ParseDuration(cfg.Tasks.Wait.ScriptDownloadTimeout)
+	k3s.SetWaitDefaults(waitDefaults)
+
+	installer := k3s.NewInstaller(k3s.MirrorConfig{
+		SystemDefault: cfg.Registry.SystemDefault,
+		Mirrors:       cfg.Registry.Mirrors,
+		ProbeImages:   cfg.Registry.ProbeImages,
+		ReleaseURL:    cfg.Registry.ReleaseURL,
+	}, appLogger)
+	k3sService := service.NewK3sService(installer, service.NewChangeJournal(stateStore, appLogger), cfg.Tasks.PreflightConcurrency, appLogger)
+	// SSH CA：节点信任 CA 公钥后，连接时按次签发短期证书，不再保存节点密码或私钥
+	var userCA *ssh.UserCA
+	if cfg.SSHCA.Enabled {
+		certTTL, _ := time.ParseDuration(cfg.SSHCA.CertTTL)
+		userCA, err = ssh.LoadOrCreateUserCA(cfg.SSHCA.KeyFile, certTTL)
+		if err != nil {
+			log.Fatalf("加载SSH CA失败: %v", err)
+		}
+		ssh.SetUserCA(userCA)
+		appLogger.Infof("SSH CA 已启用，公钥指纹 %s", userCA.Fingerprint())
+	}
+	credentialService := service.NewCredentialService(credentialVault, userCA, appLogger)
+	sshService := service.NewSSHService(credentialService, appLogger)
+	clusterService := service.NewClusterService(stateStore, k3sService, credentialService, appLogger)
+	deployService := service.NewDeployService(k3sService, credentialService, clusterService, service.IngressCAConfig{
+		CertFile: cfg.IngressTLS.CACertFile,
+		KeyFile:  cfg.IngressTLS.CAKeyFile,
+		Validity: time.Duration(cfg.IngressTLS.ValidDays) * 24 * time.Hour,
+	}, bundle.NewCatalog(cfg.Bundles.Dir, cfg.Bundles.PublicKeyFile), appLogger)
+	if faultInjector != nil {
+		deployService.SetFaults(faultInjector)
+	}
+	// 全局部署设置，请求和集群默认值未设置的字段使用这里的值
+	waitDefaults = k3s.DefaultWaitPolicy()
+	deployService.SetDefaults(model.DeploySettings{
+		Wait: &model.WaitOptions{
+			ServiceTimeout:        int(waitDefaults.ServiceTimeout.Seconds()),
+			DeploymentTimeout:     int(waitDefaults.DeploymentTimeout.Seconds()),
+			PollInterval:          int(waitDefaults.PollInterval.Seconds()),
+			InstallTimeout:        int(waitDefaults.InstallTimeout.Seconds()),
+			ScriptDownloadTimeout: int(waitDefaults.ScriptDownloadTimeout.Seconds()),
+		},
+		Mirrors: &model.MirrorOptions{
+			SystemDefault: cfg.Registry.SystemDefault,
+			Mirrors:       cfg.Registry.Mirrors,
+		},
+		Proxy: &model.ProxyOptions{
+			HTTPProxy:  cfg.NodeProxy.HTTPProxy,
+			HTTPSProxy: cfg.NodeProxy.HTTPSProxy,
+			NoProxy:    cfg.NodeProxy.NoProxy,
+		},
+	})
+	// 通知渠道：邮件订阅任务和告警事件，Webhook 只推送告警
+	var emailNotifiers notify.Multi
+	if smtpCfg := cfg.Notifications.SMTP; smtpCfg.Enabled {
+		subscriptions := make([]notify.Subscription, 0, len(smtpCfg.Subscriptions))
+		for _, sub := range smtpCfg.Subscriptions {
+			subscriptions = append(subscriptions, notify.Subscription{To: sub.To, Events: sub.Events})
+		}
+		emailNotifiers = append(emailNotifiers, notify.NewSMTPNotifier(notify.SMTPOptions{
+			Host:          smtpCfg.Host,
+			Port:          smtpCfg.Port,
+			Username:      smtpCfg.Username,
+			Password:      smtpCfg.Password,
+			From:          smtpCfg.From,
+			TLS:           smtpCfg.TLS,
+			TemplateDir:   smtpCfg.TemplateDir,
+			Subscriptions: subscriptions,
+		}))
+	}
+
+	taskService := service.NewTaskService(deployService, credentialService, stateStore, cfg.Tasks.MaxConcurrent, emailNotifiers, transcripts, appLogger)
+	if sealed, err := taskService.SealLegacyRequests(); err != nil {
+		log.Fatalf("迁移任务请求中的敏感字段失败: %v", err)
+	} else if sealed > 0 {
+		appLogger.Infof("已将 %d 个任务请求中的敏感字段迁移到凭据库", sealed)
+	}
+	if progressLog != nil {
+		taskService.SetProgressLog(progressLog)
+	}
+	taskService.Start()
+	benchmarkService := service.NewBenchmarkService(stateStore, appLogger)
+	stateService := service.NewStateService(stateStore, credentialVault, appLogger)
+	webSSHService := service.NewWebSSHService(stateStore, clusterService, credentialService, appLogger)
+
+	if cfg.Vault.RotationInterval != "" {
+		interval, _ := time.ParseDuration(cfg.Vault.RotationInterval)
+		credentialService.StartRotationScheduler(interval)
+	}
+
+	if cfg.Drift.Interval != "" {
+		interval, _ := time.ParseDuration(cfg.Drift.Interval)
+		clusterService.StartDriftScheduler(interval, cfg.Drift.AutoReconcile)
+	}
+
+	if cfg.Retention.Interval != "" {
+		interval, _ := time.ParseDuration(cfg.Retention.Interval)
+		taskRetention, _ := time.ParseDuration(cfg.Retention.Tasks)
+		workspaceRetention, _ := time.ParseDuration(cfg.Retention.Workspaces)
+		webSSHTimeout, _ := time.ParseDuration(cfg.Retention.WebSSH)
+		service.NewJanitorService(service.JanitorOptions{
+			Interval:           interval,
+			TaskRetention:      taskRetention,
+			WorkspaceRetention: workspaceRetention,
+			WebSSHTimeout:      webSSHTimeout,
+		}, stateStore, taskService, clusterService, webSSHService, appLogger).Start()
+	}
+
+	alertNotifiers := append(notify.Multi{}, emailNotifiers...)
+	if cfg.Alerts.WebhookURL != "" {
+		alertNotifiers = append(alertNotifiers, notify.NewWebhookNotifier(cfg.Alerts.WebhookURL))
+	}
+	alertInterval, _ := time.ParseDuration(cfg.Alerts.Interval)
+	certExpiryWarning, _ := time.ParseDuration(cfg.Alerts.CertExpiryWarning)
+	alertService := service.NewAlertService(service.AlertOptions{
+		Interval:          alertInterval,
+		CertExpiryWarning: certExpiryWarning,
+	}, stateStore, clusterService, k3sService, alertNotifiers, appLogger)
+	if cfg.Alerts.Interval != "" {
+		alertService.Start()
+	}
+
+	eventInterval, _ := time.ParseDuration(cfg.Events.Interval)
+	eventRetention, _ := time.ParseDuration(cfg.Events.Retention)
+	eventService := service.NewEventService(service.EventOptions{
+		Interval:  eventInterval,
+		Retention: eventRetention,
+	}, stateStore, clusterService, k3sService, appLogger)
+	if cfg.Events.Interval != "" {
+		eventService.Start()
+	}
+
+	kubeAuditInterval, _ := time.ParseDuration(cfg.KubeAudit.Interval)
+	kubeAuditRetention, _ := time.ParseDuration(cfg.KubeAudit.Retention)
+	kubeAuditService := service.NewKubeAuditService(service.KubeAuditOptions{
+		Interval:  kubeAuditInterval,
+		Retention: kubeAuditRetention,
+	}, stateStore, clusterService, k3sService, appLogger)
+	if cfg.KubeAudit.Interval != "" {
+		kubeAuditService.Start()
+	}
+
+	scaleInterval, _ := time.ParseDuration(cfg.ScaleAdvisor.Interval)
+	scaleRetention, _ := time.ParseDuration(cfg.ScaleAdvisor.Retention)
+	scaleWindow, _ := time.ParseDuration(cfg.ScaleAdvisor.Window)
+	scaleAdvisorService := service.NewScaleAdvisorService(service.ScaleAdvisorOptions{
+		Interval:         scaleInterval,
+		Retention:        scaleRetention,
+		Window:           scaleWindow,
+		ScaleUpPercent:   cfg.ScaleAdvisor.ScaleUpPercent,
+		ScaleDownPercent: cfg.ScaleAdvisor.ScaleDownPercent,
+		TargetPercent:    cfg.ScaleAdvisor.TargetPercent,
+	}, stateStore, clusterService, k3sService, appLogger)
+	if cfg.ScaleAdvisor.Interval != "" {
+		scaleAdvisorService.Start()
+	}
+
+	auditService := service.NewAuditService(stateStore, appLogger)
+	maintenanceService := service.NewMaintenanceService(clusterService, k3sService, auditService, appLogger)
+	backupService := service.NewBackupService(clusterService, k3sService, auditService, appLogger)
+	secretsEncryptionService := service.NewSecretsEncryptionService(clusterService, k3sService, taskService, auditService, appLogger)
+	scanTimeout, _ := time.ParseDuration(cfg.SecurityScan.Timeout)
+	securityScanService := service.NewSecurityScanService(k3s.ScanConfig{
+		KubeBenchImage:   cfg.SecurityScan.KubeBenchImage,
+		Benchmark:        cfg.SecurityScan.Benchmark,
+		TrivyImage:       cfg.SecurityScan.TrivyImage,
+		DBRepository:     cfg.SecurityScan.DBRepository,
+		JavaDBRepository: cfg.SecurityScan.JavaDBRepository,
+		Timeout:          scanTimeout,
+	}, stateStore, clusterService, k3sService, auditService, appLogger)
+	// 加入信息包：导出时签名，导入和部署请求中的 edge.joinBundle 使用前校验
+	joinBundleKey, err := bundle.LoadOrCreateSigningKey(cfg.JoinBundle.KeyFile)
+	if err != nil {
+		log.Fatalf("加载加入信息包签名密钥失败: %v", err)
+	}
+	joinBundleTTL, _ := time.ParseDuration(cfg.JoinBundle.TTL)
+	joinBundleService := service.NewJoinBundleService(joinBundleKey, joinBundleTTL, clusterService, auditService, appLogger)
+	deployService.SetJoinBundles(joinBundleService)
+
+	var gitOpsService *service.GitOpsService
+	if cfg.GitOps.Enabled {
+		gitOpsService = service.NewGitOpsService(service.GitOpsOptions{
+			Repo:          cfg.GitOps.Repo,
+			Branch:        cfg.GitOps.Branch,
+			Username:      cfg.GitOps.Username,
+			Token:         cfg.GitOps.Token,
+			Path:          cfg.GitOps.Path,
+			WorkDir:       cfg.GitOps.WorkDir,
+			WebhookSecret: cfg.GitOps.WebhookSecret,
+		}, clusterService, appLogger)
+		if cfg.GitOps.Interval != "" {
+			interval, _ := time.ParseDuration(cfg.GitOps.Interval)
+			gitOpsService.StartSyncScheduler(interval)
+		}
+	}
+
+	// 用户认证：未启用时所有接口无需登录
+	var authService *service.AuthService
+	var verifier middleware.Verifier
+	if cfg.Auth.Enabled {
+		secret, err := auth.LoadOrCreateSecret(cfg.Auth.SessionKeyFile)
+		if err != nil {
+			log.Fatalf("加载会话密钥失败: %v", err)
+		}
+		localUsers := make([]auth.LocalUser, 0, len(cfg.Auth.LocalUsers))
+		for _, u := range cfg.Auth.LocalUsers {
+			localUsers = append(localUsers, auth.LocalUser{Username: u.Username, PasswordHash: u.PasswordHash, Roles: u.Roles})
+		}
+		var ldapProvider *auth.LDAPProvider
+		if ldapCfg := cfg.Auth.LDAP; ldapCfg.Enabled {
+			ldapProvider = auth.NewLDAPProvider(auth.LDAPOptions{
+				URL:                ldapCfg.URL,
+				BindDN:             ldapCfg.BindDN,
+				BindPassword:       ldapCfg.BindPassword,
+				BaseDN:             ldapCfg.BaseDN,
+				UserAttribute:      ldapCfg.UserAttribute,
+				GroupAttribute:     ldapCfg.GroupAttribute,
+				InsecureSkipVerify: ldapCfg.InsecureSkipVerify,
+			})
+		}
+		var oidcProvider *auth.OIDCProvider
+		if oidcCfg := cfg.Auth.OIDC; oidcCfg.Enabled {
+			oidcProvider = auth.NewOIDCProvider(auth.OIDCOptions{
+				Issuer:       oidcCfg.Issuer,
+				ClientID:     oidcCfg.ClientID,
+				ClientSecret: oidcCfg.ClientSecret,
+				RedirectURL:  oidcCfg.RedirectURL,
+				Scopes:       oidcCfg.Scopes,
+				GroupsClaim:  oidcCfg.GroupsClaim,
+			})
+		}
+		sessionTTL, _ := time.ParseDuration(cfg.Auth.SessionTTL)
+		authService = service.NewAuthService(service.AuthOptions{
+			SessionTTL:  sessionTTL,
+			GroupRoles:  cfg.Auth.GroupRoles,
+			DefaultRole: cfg.Auth.DefaultRole,
+		}, auth.NewSigner(secret), auth.NewLocalProvider(localUsers), ldapProvider, oidcProvider, appLogger)
+		verifier = authService
+		appLogger.Info("用户认证已启用")
+	}
+
+	// 初始化处理器
+	sshHandler := handler.NewSSHHandler(sshService)
+	k3sHandler := handler.NewK3sHandler(deployService, taskService)
+	credentialHandler := handler.NewCredentialHandler(credentialService)
+	taskHandler := handler.NewTaskHandler(taskService)
+	stateHandler := handler.NewStateHandler(stateService)
+	clusterHandler := handler.NewClusterHandler(clusterService)
+	gitOpsHandler := handler.NewGitOpsHandler(gitOpsService)
+	alertHandler := handler.NewAlertHandler(alertService)
+	eventHandler := handler.NewEventHandler(eventService)
+	kubeAuditHandler := handler.NewKubeAuditHandler(kubeAuditService)
+	maintenanceHandler := handler.NewMaintenanceHandler(maintenanceService)
+	backupHandler := handler.NewBackupHandler(backupService)
+	secretsEncryptionHandler := handler.NewSecretsEncryptionHandler(secretsEncryptionService)
+	securityScanHandler := handler.NewSecurityScanHandler(securityScanService)
+	joinBundleHandler := handler.NewJoinBundleHandler(joinBundleService)
+	scaleAdvisorHandler := handler.NewScaleAdvisorHandler(scaleAdvisorService)
+	benchmarkHandler := handler.NewBenchmarkHandler(benchmarkService)
+	auditHandler := handler.NewAuditHandler(auditService)
+	authHandler := handler.NewAuthHandler(authService, cfg.Auth.OIDC.FrontendRedirect)
+	webSSHHandler := handler.NewWebSSHHandler(webSSHService, verifier, cfg.Server.CORSOrigins)
+	agentHandler := handler.NewAgentHandler(agentHub, cfg.Agent.BinaryPath)
+
+	// 设置 Gin 模式
+	gin.SetMode(gin.ReleaseMode)
+
+	// 创建路由
+	r := gin.New()
+
+	// 只信任配置的反向代理传入的 X-Forwarded-For，未配置时客户端 IP 取直接连接的地址
+	if err := r.SetTrustedProxies(cfg.Server.TrustedProxies); err != nil {
+		log.Fatalf("可信代理配置无效: %v", err)
+	}
+
+	// 中间件
+	r.Use(middleware.Audit(appLogger))
+	r.Use(gin.Recovery())
+	r.Use(middleware.External(cfg.Server.BasePath, cfg.Server.TrustedProxies))
+
+	// 单机模式使用启动时生成的访问令牌，代替用户认证
+	if localToken != "" {
+		r.Use(middleware.LocalToken(localToken))
+	}
+
+	// CORS 配置（从配置文件读取）
+	corsConfig := cors.DefaultConfig()
+	corsConfig.AllowOrigins = cfg.Server.CORSOrigins
+	corsConfig.AllowMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	corsConfig.AllowHeaders = []string{"Origin", "Content-Length", "Content-Type", "Authorization", middleware.RequestIDHeader}
+	corsConfig.ExposeHeaders = []string{middleware.RequestIDHeader}
+	r.Use(cors.New(corsConfig))
+
+	// 注册路由，全部路由位于 base_path 之下，供 nginx/Ingress 按路径前缀转发
+	base := r.Group(cfg.Server.BasePath)
+	router.RegisterRoutes(base, router.Handlers{
+		SSH:               sshHandler,
+		K3s:               k3sHandler,
+		Agent:             agentHandler,
+		Credential:        credentialHandler,
+		Task:              taskHandler,
+		State:             stateHandler,
+		Cluster:           clusterHandler,
+		GitOps:            gitOpsHandler,
+		Alert:             alertHandler,
+		Event:             eventHandler,
+		KubeAudit:         kubeAuditHandler,
+		Maintenance:       maintenanceHandler,
+		Backup:            backupHandler,
+		SecretsEncryption: secretsEncryptionHandler,
+		SecurityScan:      securityScanHandler,
+		JoinBundle:        joinBundleHandler,
+		ScaleAdvisor:      scaleAdvisorHandler,
+		Benchmark:         benchmarkHandler,
+		Audit:             auditHandler,
+		Auth:              authHandler,
+		WebSSH:            webSSHHandler,
+	}, middleware.Authenticate(verifier))
+
+	// 健康检查
+	base.GET("/health", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+	// 启动自检报告，包含目录、证书和存储地址，需要登录
+	base.GET("/health/details", middleware.Authenticate(verifier), func(c *gin.Context) {
+		c.JSON(http.StatusOK, selfCheckReport)
+	})
+	// 存活检查：进程能够处理请求即返回 200，供 Kubernetes livenessProbe 和 systemd 看门狗使用
+	base.GET("/healthz", func(c *gin.Context) {
+		c.JSON(http.StatusOK, gin.H{"status": "ok"})
+	})
+	// 就绪检查：存储、任务调度或配置异常时返回 503，供 readinessProbe 和负载均衡摘除副本
+	readiness := readinessChecks(cfg, stateStore, taskService)
+	base.GET("/readyz", func(c *gin.Context) {
+		report := selfcheck.Run(readiness, readinessTimeout)
+		status := http.StatusOK
+		if report.Status == model.SelfCheckFail {
+			status = http.StatusServiceUnavailable
+			// 响应不含失败详情，记入日志供排查
+			for _, check := range report.Checks {
+				if check.Status == model.SelfCheckFail {
+					appLogger.Warnf("就绪检查 %s 失败（%s）: %s", check.Name, check.Target, check.Message)
+				}
+			}
+		}
+		c.JSON(status, selfcheck.Public(report))
+	})
+
+	// 前端页面：路径前缀下除 /api 外不存在的路径返回 index.html，由前端路由处理
+	if fe := cfg.Server.Frontend; fe.Enabled {
+		files, err := webui.Files(fe.Dir)
+		if err != nil {
+			log.Fatalf("加载前端文件失败: %v", err)
+		}
+		frontend, err := webui.NewHandler(files, cfg.Server.BasePath)
+		if err != nil {
+			log.Fatalf("加载前端文件失败: %v", err)
+		}
+		r.NoRoute(frontend.Serve)
+		if fe.Dir != "" {
+			appLogger.Infof("前端页面已启用，使用目录 %s", fe.Dir)
+		} else {
+			appLogger.Info("前端页面已启用，使用内嵌文件")
+		}
+	}
+
+	return r, func() { stateStore.Close() }
+}
@@ -0,0 +1,311 @@
+package main
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"os/user"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// serviceConfigFile 后端从工作目录读取的配置文件（见 config.LoadConfig）
+const serviceConfigFile = "config.yaml"
+
+// serviceOptions -install-service / -uninstall-service 的参数
+type serviceOptions struct {
+	install   *bool
+	uninstall *bool
+	name      *string
+	user      *string
+	dir       *string
+	binary    *string
+	envFile   *string
+}
+
+// registerServiceFlags 注册 systemd 服务安装相关的命令行参数
+func registerServiceFlags() *serviceOptions {
+	return &serviceOptions{
+		install:   flag.Bool("install-service", false, "安装为 systemd 服务并启动（需要 root），当前目录的 config.yaml 会复制到工作目录"),
+		uninstall: flag.Bool("uninstall-service", false, "停止并移除 systemd 服务和安装的程序，保留工作目录、环境变量文件和运行用户"),
+		name:      flag.String("service-name", "k3s-deploy", "systemd 服务名称"),
+		user:      flag.String("service-user", "k3s-deploy", "服务的运行用户，不存在时创建系统用户"),
+		dir:       flag.String("service-dir", "/var/lib/k3s-deploy", "服务的工作目录，存放 config.yaml 和 data 目录"),
+		binary:    flag.String("service-bin", "/usr/local/bin/k3s-deploy", "安装的程序路径"),
+		envFile:   flag.String("service-env", "", "环境变量文件，默认 /etc/<服务名称>/<服务名称>.env"),
+	}
+}
+
+func (o *serviceOptions) unitFile() string {
+	return filepath.Join("/etc/systemd/system", *o.name+".service")
+}
+
+func (o *serviceOptions) environmentFile() string {
+	if *o.envFile != "" {
+		return *o.envFile
+	}
+	return filepath.Join("/etc", *o.name, *o.name+".env")
+}
+
+// systemdUnit 后端的 systemd 单元：启动自检失败（配置、证书或数据目录有误）时 5 分钟内最多重启 5 次，
+// 之后停止重启等待运维处理
+const systemdUnit = `[Unit]
+Description=k3s-deploy backend
+After=network-online.target
+Wants=network-online.target
+StartLimitIntervalSec=300
+StartLimitBurst=5
+
+[Service]
+Type=simple
+User={{USER}}
+Group={{GROUP}}
+WorkingDirectory={{DIR}}
+EnvironmentFile=-{{ENV_FILE}}
+ExecStart={{BINARY}}
+Restart=on-failure
+RestartSec=5
+TimeoutStopSec=30
+LimitNOFILE=65536
+NoNewPrivileges=true
+PrivateTmp=true
+ProtectSystem=full
+ProtectHome=read-only
+
+[Install]
+WantedBy=multi-user.target
+`
+
+// environmentTemplate 首次安装时写入的环境变量文件，已存在时不覆盖
+const environmentTemplate = `# {{NAME}} 服务的环境变量，修改后执行 systemctl restart {{NAME}} 生效
+#
+# 后端下载 k3s 安装脚本和访问 Webhook 使用的代理
+#HTTP_PROXY=http://proxy.example.com:3128
+#HTTPS_PROXY=http://proxy.example.com:3128
+#NO_PROXY=127.0.0.1,localhost
+`
+
+// checkPaths 单元文件中的路径必须是绝对路径且不能换行
+func (o *serviceOptions) checkPaths() error {
+	for _, path := range []string{*o.dir, *o.binary, o.environmentFile()} {
+		if !filepath.IsAbs(path) || strings.ContainsAny(path, "\r\n") {
+			return fmt.Errorf("路径 %q 无效：需要不含换行的绝对路径", path)
+		}
+	}
+	return nil
+}
+
+// systemdPath 转义单元文件中的路径值：WorkingDirectory 和 EnvironmentFile 取整行作为路径，
+// 只需把 % 写成 %%，避免被当作说明符展开
+func systemdPath(path string) string {
+	return strings.ReplaceAll(path, "%", "%%")
+}
+
+// systemdExec 把程序路径写成 ExecStart 的一个参数：用双引号包住，路径中有空格时不会被拆成多个参数
+func systemdExec(path string) string {
+	path = strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(path)
+	return `"` + systemdPath(path) + `"`
+}
+
+// installService 安装并启动 systemd 服务，重复执行时更新程序和单元文件
+func installService(o *serviceOptions) error {
+	if err := checkServiceHost(); err != nil {
+		return err
+	}
+	if err := o.checkPaths(); err != nil {
+		return err
+	}
+
+	group, err := ensureServiceUser(*o.user, *o.dir)
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(*o.dir, 0750); err != nil {
+		return fmt.Errorf("创建工作目录失败: %w", err)
+	}
+	if err := chownByName(*o.dir, *o.user, group); err != nil {
+		return err
+	}
+	config := filepath.Join(*o.dir, serviceConfigFile)
+	if _, err := os.Stat(config); os.IsNotExist(err) {
+		if _, err := os.Stat(serviceConfigFile); err == nil {
+			if err := copyFile(serviceConfigFile, config, 0640); err != nil {
+				return fmt.Errorf("复制配置文件失败: %w", err)
+			}
+			if err := chownByName(config, *o.user, group); err != nil {
+				return err
+			}
+			fmt.Printf("已复制 %s 到 %s\n", serviceConfigFile, config)
+		}
+	}
+
+	if err := installBinary(*o.binary); err != nil {
+		return err
+	}
+
+	envFile := o.environmentFile()
+	if _, err := os.Stat(envFile); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(envFile), 0755); err != nil {
+			return fmt.Errorf("创建环境变量文件目录失败: %w", err)
+		}
+		content := strings.ReplaceAll(environmentTemplate, "{{NAME}}", *o.name)
+		if err := os.WriteFile(envFile, []byte(content), 0640); err != nil {
+			return fmt.Errorf("写入环境变量文件失败: %w", err)
+		}
+		if err := chownByName(envFile, "root", group); err != nil {
+			return err
+		}
+	}
+
+	unit := strings.NewReplacer(
+		"{{USER}}", *o.user,
+		"{{GROUP}}", group,
+		"{{DIR}}", systemdPath(*o.dir),
+		"{{ENV_FILE}}", systemdPath(envFile),
+		"{{BINARY}}", systemdExec(*o.binary),
+	).Replace(systemdUnit)
+	if err := os.WriteFile(o.unitFile(), []byte(unit), 0644); err != nil {
+		return fmt.Errorf("写入 systemd 单元失败: %w", err)
+	}
+
+	for _, args := range [][]string{{"daemon-reload"}, {"enable", *o.name}, {"restart", *o.name}} {
+		if err := systemctl(args...); err != nil {
+			return err
+		}
+	}
+
+	fmt.Printf("服务 %s 已安装并启动\n", *o.name)
+	fmt.Printf("  单元文件:     %s\n", o.unitFile())
+	fmt.Printf("  程序:         %s\n", *o.binary)
+	fmt.Printf("  工作目录:     %s（config.yaml 和 data 目录）\n", *o.dir)
+	fmt.Printf("  环境变量文件: %s\n", envFile)
+	fmt.Printf("  运行用户:     %s\n", *o.user)
+	fmt.Printf("查看状态: systemctl status %s，查看日志: journalctl -u %s -f\n", *o.name, *o.name)
+	return nil
+}
+
+// uninstallService 停止并移除服务和安装的程序，工作目录中的数据、环境变量文件和运行用户保留
+func uninstallService(o *serviceOptions) error {
+	if err := checkServiceHost(); err != nil {
+		return err
+	}
+
+	unitFile := o.unitFile()
+	if _, err := os.Stat(unitFile); os.IsNotExist(err) {
+		return fmt.Errorf("服务 %s 未安装（%s 不存在）", *o.name, unitFile)
+	}
+	if err := systemctl("disable", "--now", *o.name); err != nil {
+		return err
+	}
+	if err := os.Remove(unitFile); err != nil {
+		return fmt.Errorf("删除 systemd 单元失败: %w", err)
+	}
+	if err := systemctl("daemon-reload"); err != nil {
+		return err
+	}
+	if err := os.Remove(*o.binary); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("删除程序失败: %w", err)
+	}
+
+	fmt.Printf("服务 %s 已卸载\n", *o.name)
+	fmt.Printf("以下内容已保留，确认不再需要后手动删除: 工作目录 %s、环境变量文件 %s、运行用户 %s\n",
+		*o.dir, o.environmentFile(), *o.user)
+	return nil
+}
+
+func checkServiceHost() error {
+	if runtime.GOOS != "linux" {
+		return errors.New("只支持在 Linux 上安装 systemd 服务")
+	}
+	if os.Geteuid() != 0 {
+		return errors.New("安装或卸载服务需要 root 权限")
+	}
+	if _, err := os.Stat("/run/systemd/system"); err != nil {
+		return errors.New("当前系统未使用 systemd")
+	}
+	return nil
+}
+
+// ensureServiceUser 运行用户不存在时创建不可登录的系统用户，返回用户的主组名
+func ensureServiceUser(name, home string) (string, error) {
+	u, err := user.Lookup(name)
+	if errors.As(err, new(user.UnknownUserError)) {
+		cmd := exec.Command("useradd", "--system", "--user-group", "--home-dir", home, "--no-create-home", "--shell", "/usr/sbin/nologin", name)
+		if out, err := cmd.CombinedOutput(); err != nil {
+			return "", fmt.Errorf("创建用户 %s 失败: %v: %s", name, err, strings.TrimSpace(string(out)))
+		}
+		fmt.Printf("已创建系统用户 %s\n", name)
+		u, err = user.Lookup(name)
+	}
+	if err != nil {
+		return "", fmt.Errorf("查询用户 %s 失败: %w", name, err)
+	}
+	g, err := user.LookupGroupId(u.Gid)
+	if err != nil {
+		return "", fmt.Errorf("查询用户 %s 的主组失败: %w", name, err)
+	}
+	return g.Name, nil
+}
+
+func chownByName(path, userName, groupName string) error {
+	if out, err := exec.Command("chown", userName+":"+groupName, path).CombinedOutput(); err != nil {
+		return fmt.Errorf("设置 %s 的所有者失败: %v: %s", path, err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
+
+// installBinary 将当前程序复制到 target，先写临时文件再重命名，服务运行中也可以更新
+func installBinary(target string) error {
+	self, err := os.Executable()
+	if err != nil {
+		return fmt.Errorf("获取程序路径失败: %w", err)
+	}
+	if self, err = filepath.EvalSymlinks(self); err != nil {
+		return fmt.Errorf("获取程序路径失败: %w", err)
+	}
+	if resolved, err := filepath.EvalSymlinks(target); err == nil && resolved == self {
+		return nil
+	}
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return fmt.Errorf("创建程序目录失败: %w", err)
+	}
+	tmp := target + ".new"
+	if err := copyFile(self, tmp, 0755); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("安装程序失败: %w", err)
+	}
+	if err := os.Rename(tmp, target); err != nil {
+		os.Remove(tmp)
+		return fmt.Errorf("安装程序失败: %w", err)
+	}
+	return nil
+}
+
+func copyFile(src, dst string, perm os.FileMode) error {
+	in, err := os.Open(src)
+	if err != nil {
+		return err
+	}
+	defer in.Close()
+	out, err := os.OpenFile(dst, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, perm)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(out, in); err != nil {
+		out.Close()
+		return err
+	}
+	return out.Close()
+}
+
+func systemctl(args ...string) error {
+	if out, err := exec.Command("systemctl", args...).CombinedOutput(); err != nil {
+		return fmt.Errorf("systemctl %s 失败: %v: %s", strings.Join(args, " "), err, strings.TrimSpace(string(out)))
+	}
+	return nil
+}
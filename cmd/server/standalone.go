@@ -0,0 +1,77 @@
+package main
+
+import (
+	"fmt"
+	"html"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+)
+
+// defaultDataDir 单机模式默认的数据目录：~/.k3s-deploy
+func defaultDataDir() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return "data"
+	}
+	return filepath.Join(home, ".k3s-deploy")
+}
+
+// printAccessURL 以醒目的格式输出带访问令牌的地址，便于从终端复制
+func printAccessURL(url string) {
+	fmt.Println()
+	fmt.Println("    k3s-deploy 单机模式已启动，在浏览器中打开以下地址（包含访问令牌，请勿分享）：")
+	fmt.Println()
+	fmt.Printf("        %s\n", url)
+	fmt.Println()
+	fmt.Println("    按 Ctrl+C 退出")
+	fmt.Println()
+}
+
+// redirectPage 浏览器打开的本地跳转页，访问令牌只出现在文件内容中
+const redirectPage = `<!DOCTYPE html>
+<html>
+<head>
+<meta charset="utf-8">
+<meta http-equiv="refresh" content="0;url=%[1]s">
+<title>k3s-deploy</title>
+</head>
+<body>
+<p>正在打开 k3s-deploy，未自动跳转时请点击 <a href="%[1]s">此链接</a>。</p>
+</body>
+</html>
+`
+
+// openBrowser 使用系统默认浏览器打开地址。命令行参数对同一台机器上的其他用户可见，
+// 带令牌的地址写入数据目录中仅当前用户可读的跳转页，浏览器打开该文件后再跳转
+func openBrowser(url, dataDir string) error {
+	page := filepath.Join(dataDir, "open.html")
+	f, err := os.OpenFile(page, os.O_CREATE|os.O_WRONLY|os.O_TRUNC, 0600)
+	if err != nil {
+		return fmt.Errorf("写入跳转页失败: %v", err)
+	}
+	// 文件已存在时 OpenFile 不修改权限
+	if err := f.Chmod(0600); err != nil {
+		f.Close()
+		return fmt.Errorf("写入跳转页失败: %v", err)
+	}
+	if _, err := fmt.Fprintf(f, redirectPage, html.EscapeString(url)); err != nil {
+		f.Close()
+		return fmt.Errorf("写入跳转页失败: %v", err)
+	}
+	if err := f.Close(); err != nil {
+		return fmt.Errorf("写入跳转页失败: %v", err)
+	}
+
+	var cmd *exec.Cmd
+	switch runtime.GOOS {
+	case "darwin":
+		cmd = exec.Command("open", page)
+	case "windows":
+		cmd = exec.Command("rundll32", "url.dll,FileProtocolHandler", page)
+	default:
+		cmd = exec.Command("xdg-open", page)
+	}
+	return cmd.Start()
+}
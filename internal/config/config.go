@@ -2,21 +2,98 @@ package config
 
 import (
 	"fmt"
+	"net"
 	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
 	"strings"
+	"time"
 
 	"gopkg.in/yaml.v3"
+	"k3s-deploy-backend/internal/pkg/auth"
+	"k3s-deploy-backend/pkg/utils"
 )
 
 type Config struct {
-	Server  ServerConfig  `yaml:"server"`
-	Logging LoggingConfig `yaml:"logging"`
+	Server    ServerConfig    `yaml:"server"`
+	Logging   LoggingConfig   `yaml:"logging"`
+	Agent     AgentConfig     `yaml:"agent"`
+	Vault     VaultConfig     `yaml:"vault"`
+	Tasks     TasksConfig     `yaml:"tasks"`
+	Store     StoreConfig     `yaml:"store"`
+	Drift     DriftConfig     `yaml:"drift"`
+	GitOps    GitOpsConfig    `yaml:"gitops"`
+	Retention RetentionConfig `yaml:"retention"`
+	Alerts    AlertsConfig    `yaml:"alerts"`
+	Events    EventsConfig    `yaml:"events"`
+	// KubeAudit 受管集群 API Server 审计日志收集
+	KubeAudit KubeAuditConfig `yaml:"kube_audit"`
+	// SecurityScan 按需执行的 kube-bench 与 trivy 安全扫描
+	SecurityScan SecurityScanConfig `yaml:"security_scan"`
+	// ScaleAdvisor 资源用量采集与伸缩建议
+	ScaleAdvisor ScaleAdvisorConfig `yaml:"scale_advisor"`
+	// Notifications 通知渠道
+	Notifications NotificationsConfig `yaml:"notifications"`
+	// Auth 用户认证与权限
+	Auth AuthConfig `yaml:"auth"`
+	// SSHCA 使用 SSH 证书登录节点
+	SSHCA SSHCAConfig `yaml:"ssh_ca"`
+	// Registry 国内网络环境下安装 k3s 使用的镜像源
+	Registry RegistryConfig `yaml:"registry"`
+	// NodeProxy 节点下载安装文件和拉取镜像使用的代理
+	NodeProxy NodeProxyConfig `yaml:"node_proxy"`
+	// IngressTLS 为 inSuite Ingress 签发证书使用的内部 CA
+	IngressTLS IngressTLSConfig `yaml:"ingress_tls"`
+	// Bundles cmd/bundle 生成的离线安装包
+	Bundles BundleConfig `yaml:"bundles"`
+	// Simulation 模拟 SSH 后端，用于开发和 CI
+	Simulation SimulationConfig `yaml:"simulation"`
+	// Transcripts 部署任务的节点命令录制与回放
+	Transcripts TranscriptConfig `yaml:"transcripts"`
+	// Progress 部署进度事件的文件输出和 Webhook 推送
+	Progress ProgressConfig `yaml:"progress"`
+	// Faults 部署测试用的故障注入
+	Faults FaultConfig `yaml:"faults"`
+	// SelfCheck 启动自检
+	SelfCheck SelfCheckConfig `yaml:"self_check"`
+	// JoinBundle 集群加入信息包的签名与有效期
+	JoinBundle JoinBundleConfig `yaml:"join_bundle"`
 }
 
 type ServerConfig struct {
 	Host        string   `yaml:"host"`
 	Port        int      `yaml:"port"`
 	CORSOrigins []string `yaml:"cors_origins"`
+	// BasePath 全部路由的路径前缀（如 /k3s-deploy），反向代理按前缀转发且不剥离前缀时使用，为空表示挂在根路径
+	BasePath string `yaml:"base_path"`
+	// TrustedProxies 可信反向代理的 IP 或 CIDR，只有来自这些地址的请求才采用 X-Forwarded-For、
+	// X-Forwarded-Proto 和 X-Forwarded-Host；为空表示不信任任何代理
+	TrustedProxies []string `yaml:"trusted_proxies"`
+	// TLS 启用 HTTPS 和客户端证书认证
+	TLS TLSConfig `yaml:"tls"`
+	// Frontend 由后端直接提供前端页面
+	Frontend FrontendConfig `yaml:"frontend"`
+}
+
+// FrontendConfig 前端静态文件。默认使用编译时内嵌的 internal/pkg/webui/dist，
+// 前端路由（路径前缀下除 /api 外不存在的路径）返回 index.html
+type FrontendConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Dir 前端构建产物目录，配置后使用磁盘上的文件代替内嵌文件
+	Dir string `yaml:"dir"`
+}
+
+// TLSConfig API 服务端 TLS。证书可用 k3s-deploy-pki 工具基于本地 CA 签发
+type TLSConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	CertFile string `yaml:"cert_file"`
+	KeyFile  string `yaml:"key_file"`
+	// ClientAuth 客户端证书校验：none、optional（提供时校验）、require（强制双向 TLS）
+	ClientAuth   string `yaml:"client_auth"`
+	ClientCAFile string `yaml:"client_ca_file"`
+	// CRLFile 客户端证书吊销列表，更新后自动生效
+	CRLFile string `yaml:"crl_file"`
 }
 
 type LoggingConfig struct {
@@ -25,6 +102,346 @@ type LoggingConfig struct {
 	Output string `yaml:"output"`
 }
 
+// AgentConfig 节点 Agent 反向连接配置
+type AgentConfig struct {
+	Enabled     bool   `yaml:"enabled"`
+	EnrollToken string `yaml:"enroll_token"`
+	BinaryPath  string `yaml:"binary_path"`
+}
+
+// VaultConfig 凭据库配置
+type VaultConfig struct {
+	Path    string `yaml:"path"`
+	KeyFile string `yaml:"key_file"`
+	// RotationInterval 凭据定期轮换周期（如 720h），为空表示不自动轮换
+	RotationInterval string `yaml:"rotation_interval"`
+}
+
+// TasksConfig 异步部署任务队列配置
+type TasksConfig struct {
+	// MaxConcurrent 全局最大并发任务数，同一集群的任务始终串行执行
+	MaxConcurrent int `yaml:"max_concurrent"`
+	// PreflightConcurrency validate 步骤同时预检的节点数
+	PreflightConcurrency int `yaml:"preflight_concurrency"`
+	// Wait 请求中未设置 wait 参数时使用的等待时长
+	Wait WaitConfig `yaml:"wait"`
+}
+
+// WaitConfig 部署等待时长的默认值（Go duration 格式）
+type WaitConfig struct {
+	// ServiceTimeout 等待 k3s / k3s-agent 服务启动
+	ServiceTimeout string `yaml:"service_timeout"`
+	// DeploymentTimeout 等待每个 inSuite 组件就绪
+	DeploymentTimeout string `yaml:"deployment_timeout"`
+	// PollInterval 轮询间隔
+	PollInterval string `yaml:"poll_interval"`
+	// InstallTimeout 在节点上执行安装脚本
+	InstallTimeout string `yaml:"install_timeout"`
+	// ScriptDownloadTimeout 后端下载安装脚本
+	ScriptDownloadTimeout string `yaml:"script_download_timeout"`
+}
+
+// StoreConfig 状态存储配置
+// sqlite 为默认的单副本持久化存储；memory 不持久化；多副本部署时使用 redis，各副本共享任务状态和凭据
+type StoreConfig struct {
+	Backend   string       `yaml:"backend"`
+	KeyPrefix string       `yaml:"key_prefix"`
+	SQLite    SQLiteConfig `yaml:"sqlite"`
+	Redis     RedisConfig  `yaml:"redis"`
+}
+
+type SQLiteConfig struct {
+	Path string `yaml:"path"`
+}
+
+type RedisConfig struct {
+	Addr     string `yaml:"addr"`
+	Password string `yaml:"password"`
+	DB       int    `yaml:"db"`
+}
+
+// DriftConfig 配置漂移检测
+type DriftConfig struct {
+	// Interval 定期检测周期（如 30m），为空表示只在接口调用时检测
+	Interval string `yaml:"interval"`
+	// AutoReconcile 定期检测发现漂移时自动修复
+	AutoReconcile bool `yaml:"auto_reconcile"`
+}
+
+// GitOpsConfig 从 Git 仓库同步集群期望状态
+type GitOpsConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Repo 仓库地址，不能包含令牌，HTTPS 私有仓库使用 Username 和 Token
+	Repo   string `yaml:"repo"`
+	Branch string `yaml:"branch"`
+	// Username、Token HTTPS 私有仓库的访问令牌，经 GIT_ASKPASS 提供给 git，不写入仓库配置和命令行
+	Username string `yaml:"username"`
+	Token    string `yaml:"token"`
+	// Path 仓库中存放集群目录的路径
+	Path    string `yaml:"path"`
+	WorkDir string `yaml:"work_dir"`
+	// Interval 定期同步周期，为空表示只通过 Webhook 或手动触发
+	Interval      string `yaml:"interval"`
+	WebhookSecret string `yaml:"webhook_secret"`
+}
+
+// RetentionConfig 后台清理与数据保留策略
+type RetentionConfig struct {
+	// Interval 清理周期，为空表示不启用后台清理
+	Interval string `yaml:"interval"`
+	// Tasks 已结束任务（含日志）的保留时长，为空表示永久保留
+	Tasks string `yaml:"tasks"`
+	// Workspaces 节点上部署工作目录的保留时长，超过后视为异常中断的遗留文件
+	Workspaces string `yaml:"workspaces"`
+	// WebSSH 终端会话超过该时长未收到浏览器消息（含 ping 应答）时关闭，为空表示不检查
+	WebSSH string `yaml:"webssh"`
+}
+
+// AlertsConfig 受管集群健康告警
+type AlertsConfig struct {
+	// Interval 告警评估周期，为空表示只在接口调用时评估
+	Interval string `yaml:"interval"`
+	// CertExpiryWarning 证书剩余有效期低于该值时告警
+	CertExpiryWarning string `yaml:"cert_expiry_warning"`
+	// WebhookURL 告警通知地址，为空表示不推送
+	WebhookURL string `yaml:"webhook_url"`
+}
+
+// EventsConfig 受管集群 Kubernetes 事件收集
+type EventsConfig struct {
+	// Interval 收集周期，为空表示只在接口请求刷新时收集
+	Interval string `yaml:"interval"`
+	// Retention 事件保留时长，API Server 默认只保留 1 小时
+	Retention string `yaml:"retention"`
+}
+
+// KubeAuditConfig 从 Master 节点收集 API Server 审计日志（需在安装时设置 security.audit）
+type KubeAuditConfig struct {
+	// Interval 收集周期，为空表示只在接口请求刷新时收集
+	Interval string `yaml:"interval"`
+	// Retention 审计记录在后端的保留时长
+	Retention string `yaml:"retention"`
+}
+
+// ScaleAdvisorConfig 定期采集受管集群的资源用量（需要 metrics-server），据此给出节点与副本数伸缩建议
+type ScaleAdvisorConfig struct {
+	// Interval 采集周期，为空表示不采集
+	Interval string `yaml:"interval"`
+	// Retention 采样保留时长
+	Retention string `yaml:"retention"`
+	// Window 伸缩建议默认分析的时间窗口，不能超过 Retention
+	Window string `yaml:"window"`
+	// ScaleUpPercent、ScaleDownPercent 利用率在窗口内始终高于或低于该值时建议扩容或缩容，TargetPercent 为伸缩后的目标利用率
+	ScaleUpPercent   int `yaml:"scale_up_percent"`
+	ScaleDownPercent int `yaml:"scale_down_percent"`
+	TargetPercent    int `yaml:"target_percent"`
+}
+
+// SimulationConfig 启用后所有节点命令由内存中的模拟后端按脚本应答，不连接真实节点，
+// 前端联调和 CI 可在没有虚拟机的情况下走完整个部署流程
+type SimulationConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Script 命令脚本文件（YAML），为空时只使用内置规则
+	Script string `yaml:"script"`
+}
+
+// TranscriptConfig 节点命令录制与回放。录制时每个请求（含部署任务）执行的命令、输出和退出码
+// 脱敏后保存为 <dir>/<请求 ID>.jsonl；设置 Replay 后节点命令按录制文件应答，不连接真实节点，
+// 用于离线复现用户报告的安装失败
+type TranscriptConfig struct {
+	Record bool   `yaml:"record"`
+	Dir    string `yaml:"dir"`
+	// Replay 回放的录制文件，不能与模拟后端同时使用
+	Replay string `yaml:"replay"`
+}
+
+// ProgressConfig 部署进度事件（安装器、预检和任务队列发布的结构化事件）的输出
+type ProgressConfig struct {
+	// LogDir 每个请求的进度事件写入 <dir>/<请求 ID>.jsonl，为空表示不写文件
+	LogDir string `yaml:"log_dir"`
+	// Webhooks 推送进度事件的地址
+	Webhooks []ProgressWebhook `yaml:"webhooks"`
+}
+
+// ProgressWebhook 以 JSON POST 推送进度事件
+type ProgressWebhook struct {
+	URL string `yaml:"url"`
+	// Types 推送的事件类型（如 task.finished、step.finished），为空表示全部
+	Types []string `yaml:"types"`
+}
+
+// SelfCheckConfig 启动自检：检查数据和日志目录可写、TLS 证书有效、存储结构与程序一致，任一项失败时退出。
+// 结果通过 /health/details 查看
+type SelfCheckConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// Mirrors 同时从后端探测 registry 中的镜像源，不可达只告警
+	Mirrors bool `yaml:"mirrors"`
+	// Timeout 单项检查的超时时间
+	Timeout string `yaml:"timeout"`
+}
+
+// MaxJoinBundleTTL 加入信息包有效期的上限，加入信息包包含 join token，不宜长期有效
+const MaxJoinBundleTTL = 720 * time.Hour
+
+// JoinBundleConfig 集群加入信息包：导出时用 ed25519 私钥签名，导入时用对应公钥校验
+type JoinBundleConfig struct {
+	// KeyFile 签名私钥文件，不存在时自动生成（同时写出 .pub 公钥）；多副本部署时各副本必须使用同一份密钥
+	KeyFile string `yaml:"key_file"`
+	// TTL 导出时未指定有效期时使用的有效期
+	TTL string `yaml:"ttl"`
+}
+
+// FaultConfig 故障注入：每个节点执行一定数量的命令后断开连接、延迟命令执行、使指定步骤失败，
+// 用于确定性地验证重试、回滚和断点续跑逻辑，不要在生产环境启用。
+// 以 -tags faults 构建时环境变量 K3S_DEPLOY_FAULTS 可覆盖此配置，见 applyFaultsEnv
+type FaultConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// DropAfter 每个节点每执行 N 条命令后断开一次连接，0 表示不断开
+	DropAfter int `yaml:"drop_after"`
+	// Delay 每条命令执行前等待的时长，如 500ms
+	Delay string `yaml:"delay"`
+	// FailSteps 直接失败的部署步骤，"步骤:N" 表示只有前 N 次执行失败
+	FailSteps []string `yaml:"fail_steps"`
+}
+
+// faultsEnv 覆盖故障注入配置的环境变量
+const faultsEnv = "K3S_DEPLOY_FAULTS"
+
+// SecurityScanConfig 安全扫描使用的工具镜像与漏洞库
+type SecurityScanConfig struct {
+	KubeBenchImage string `yaml:"kube_bench_image"`
+	// Benchmark kube-bench 基准，需与集群的 k3s 版本对应
+	Benchmark  string `yaml:"benchmark"`
+	TrivyImage string `yaml:"trivy_image"`
+	// DBRepository trivy 漏洞库的 OCI 仓库，为空时使用 trivy 默认仓库（mirror.gcr.io、ghcr.io），国内或内网环境指向同步的镜像
+	DBRepository string `yaml:"db_repository"`
+	// JavaDBRepository trivy Java 漏洞库的 OCI 仓库
+	JavaDBRepository string `yaml:"java_db_repository"`
+	// Timeout kube-bench 与 trivy 各自的最长执行时间
+	Timeout string `yaml:"timeout"`
+}
+
+// NotificationsConfig 通知渠道配置
+type NotificationsConfig struct {
+	SMTP SMTPConfig `yaml:"smtp"`
+}
+
+// SMTPConfig 邮件通知
+type SMTPConfig struct {
+	Enabled  bool   `yaml:"enabled"`
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password"`
+	From     string `yaml:"from"`
+	// TLS 使用隐式 TLS（465 端口），否则在服务器支持时使用 STARTTLS
+	TLS bool `yaml:"tls"`
+	// TemplateDir 自定义 HTML 模板目录，文件名为 <事件类型>.html
+	TemplateDir   string             `yaml:"template_dir"`
+	Subscriptions []SMTPSubscription `yaml:"subscriptions"`
+}
+
+// SMTPSubscription 收件人组及订阅的事件：task.succeeded、task.failed、alert.firing、alert.resolved、cert.expiring，* 表示全部
+type SMTPSubscription struct {
+	To     []string `yaml:"to"`
+	Events []string `yaml:"events"`
+}
+
+// RegistryConfig 镜像源。安装前会在节点上检查各镜像源能否提供 ProbeImages，不可用的镜像源自动剔除
+type RegistryConfig struct {
+	// SystemDefault k3s 系统镜像的默认仓库（--system-default-registry），为空时系统镜像也通过 Mirrors 拉取
+	SystemDefault string `yaml:"system_default"`
+	// Mirrors docker.io 镜像加速地址
+	Mirrors []string `yaml:"mirrors"`
+	// ProbeImages 检查用的镜像（docker.io 路径，如 rancher/mirrored-pause:3.6）
+	ProbeImages []string `yaml:"probe_images"`
+	// ReleaseURL k3s 发布文件地址，安装后按其中的 sha256sum 校验 k3s 二进制；为空时经国内镜像安装先查镜像再查 GitHub，
+	// 均无法访问时可指向内网同步的副本
+	ReleaseURL string `yaml:"release_url"`
+}
+
+// NodeProxyConfig 节点使用的 HTTP 代理，作为部署请求和集群默认值未设置时的全局值
+type NodeProxyConfig struct {
+	HTTPProxy  string `yaml:"http_proxy"`
+	HTTPSProxy string `yaml:"https_proxy"`
+	NoProxy    string `yaml:"no_proxy"`
+}
+
+// IngressTLSConfig inSuite Ingress 证书签发。CA 由 cmd/pki init-ingress 生成，与签发 API 客户端证书的 CA 相互独立，
+// 只在部署请求开启 TLS 或安装 cert-manager 时加载
+type IngressTLSConfig struct {
+	CACertFile string `yaml:"ca_cert_file"`
+	CAKeyFile  string `yaml:"ca_key_file"`
+	// ValidDays 签发证书的有效天数
+	ValidDays int `yaml:"valid_days"`
+}
+
+// BundleConfig 离线安装包目录与签名公钥。部署请求按名称引用 Dir 下的子目录
+type BundleConfig struct {
+	Dir string `yaml:"dir"`
+	// PublicKeyFile cmd/bundle 签名私钥对应的公钥（生成私钥时一并写出的 .pub 文件）
+	PublicKeyFile string `yaml:"public_key_file"`
+}
+
+// SSHCAConfig SSH 用户证书签发
+type SSHCAConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// KeyFile CA 私钥文件，不存在时自动生成；多副本部署时各副本必须使用同一份密钥
+	KeyFile string `yaml:"key_file"`
+	// CertTTL 每次连接签发的证书有效期
+	CertTTL string `yaml:"cert_ttl"`
+}
+
+// AuthConfig 用户认证。启用后除健康检查、Agent 通道和 GitOps Webhook 外的接口都需要登录
+type AuthConfig struct {
+	Enabled bool `yaml:"enabled"`
+	// SessionKeyFile 会话令牌签名密钥文件，不存在时自动生成；多副本部署时各副本必须使用同一份密钥
+	SessionKeyFile string `yaml:"session_key_file"`
+	// SessionTTL 会话有效期
+	SessionTTL string            `yaml:"session_ttl"`
+	LocalUsers []LocalUserConfig `yaml:"local_users"`
+	OIDC       OIDCConfig        `yaml:"oidc"`
+	LDAP       LDAPConfig        `yaml:"ldap"`
+	// GroupRoles OIDC/LDAP 用户组 -> 角色（admin、operator、viewer）
+	GroupRoles map[string]string `yaml:"group_roles"`
+	// DefaultRole 未匹配任何组时的角色，为空表示拒绝登录
+	DefaultRole string `yaml:"default_role"`
+}
+
+// LocalUserConfig 本地用户，密码使用 bcrypt 哈希
+type LocalUserConfig struct {
+	Username     string   `yaml:"username"`
+	PasswordHash string   `yaml:"password_hash"`
+	Roles        []string `yaml:"roles"`
+}
+
+// OIDCConfig OIDC 单点登录
+type OIDCConfig struct {
+	Enabled      bool     `yaml:"enabled"`
+	Issuer       string   `yaml:"issuer"`
+	ClientID     string   `yaml:"client_id"`
+	ClientSecret string   `yaml:"client_secret"`
+	RedirectURL  string   `yaml:"redirect_url"`
+	Scopes       []string `yaml:"scopes"`
+	// GroupsClaim id_token 中携带用户组的字段
+	GroupsClaim string `yaml:"groups_claim"`
+	// FrontendRedirect 登录成功后跳转的前端地址，令牌放在 URL fragment 中；为空时直接返回 JSON
+	FrontendRedirect string `yaml:"frontend_redirect"`
+}
+
+// LDAPConfig LDAP 账号登录
+type LDAPConfig struct {
+	Enabled        bool   `yaml:"enabled"`
+	URL            string `yaml:"url"`
+	BindDN         string `yaml:"bind_dn"`
+	BindPassword   string `yaml:"bind_password"`
+	BaseDN         string `yaml:"base_dn"`
+	UserAttribute  string `yaml:"user_attribute"`
+	GroupAttribute string `yaml:"group_attribute"`
+	// InsecureSkipVerify ldaps 不校验服务端证书
+	InsecureSkipVerify bool `yaml:"insecure_skip_verify"`
+}
+
 const configFilePath = "config.yaml"
 
 // getDefaultConfig 返回默认配置
@@ -34,17 +451,171 @@ func getDefaultConfig() *Config {
 			Host:        "127.0.0.1",
 			Port:        8080,
 			CORSOrigins: []string{"http://localhost:3000"},
+			TLS: TLSConfig{
+				CertFile:     "data/pki/server.crt",
+				KeyFile:      "data/pki/server.key",
+				ClientAuth:   "none",
+				ClientCAFile: "data/pki/ca.crt",
+				CRLFile:      "data/pki/crl.pem",
+			},
 		},
 		Logging: LoggingConfig{
 			Level:  "debug",
 			Format: "text",
 			Output: "stdout",
 		},
+		Agent: AgentConfig{
+			Enabled:    false,
+			BinaryPath: "bin/k3s-deploy-agent",
+		},
+		Vault: VaultConfig{
+			Path:    "data/credentials.json",
+			KeyFile: "data/vault.key",
+		},
+		Tasks: TasksConfig{
+			MaxConcurrent:        2,
+			PreflightConcurrency: 10,
+			Wait: WaitConfig{
+				ServiceTimeout:        "3m",
+				DeploymentTimeout:     "5m",
+				PollInterval:          "10s",
+				InstallTimeout:        "15m",
+				ScriptDownloadTimeout: "1m",
+			},
+		},
+		SelfCheck: SelfCheckConfig{
+			Enabled: true,
+			Timeout: "10s",
+		},
+		JoinBundle: JoinBundleConfig{
+			KeyFile: "data/join-bundle.key",
+			TTL:     "24h",
+		},
+		GitOps: GitOpsConfig{
+			Enabled: false,
+			Branch:  "main",
+			Path:    "clusters",
+			WorkDir: "data/gitops",
+		},
+		Retention: RetentionConfig{
+			Interval:   "1h",
+			Tasks:      "720h",
+			Workspaces: "24h",
+			WebSSH:     "5m",
+		},
+		Alerts: AlertsConfig{
+			CertExpiryWarning: "720h",
+		},
+		Events: EventsConfig{
+			Retention: "24h",
+		},
+		KubeAudit: KubeAuditConfig{
+			Retention: "720h",
+		},
+		SecurityScan: SecurityScanConfig{
+			KubeBenchImage: "docker.io/aquasec/kube-bench:v0.9.4",
+			Benchmark:      "k3s-cis-1.8",
+			TrivyImage:     "docker.io/aquasec/trivy:0.57.1",
+			Timeout:        "30m",
+		},
+		ScaleAdvisor: ScaleAdvisorConfig{
+			Retention:        "24h",
+			Window:           "1h",
+			ScaleUpPercent:   75,
+			ScaleDownPercent: 30,
+			TargetPercent:    60,
+		},
+		Notifications: NotificationsConfig{
+			SMTP: SMTPConfig{
+				Port: 587,
+			},
+		},
+		Registry: RegistryConfig{
+			SystemDefault: "registry.cn-hangzhou.aliyuncs.com",
+			Mirrors:       []string{"https://registry.cn-hangzhou.aliyuncs.com", "https://mirror.ccs.tencentyun.com"},
+			ProbeImages:   []string{"rancher/mirrored-pause:3.6"},
+		},
+		IngressTLS: IngressTLSConfig{
+			CACertFile: "data/pki/ingress-ca.crt",
+			CAKeyFile:  "data/pki/ingress-ca.key",
+			ValidDays:  825,
+		},
+		Bundles: BundleConfig{
+			Dir:           "data/bundles",
+			PublicKeyFile: "data/bundle.key.pub",
+		},
+		Transcripts: TranscriptConfig{
+			Dir: "data/transcripts",
+		},
+		SSHCA: SSHCAConfig{
+			KeyFile: "data/ssh_ca.key",
+			CertTTL: "10m",
+		},
+		Auth: AuthConfig{
+			SessionKeyFile: "data/session.key",
+			SessionTTL:     "12h",
+			OIDC: OIDCConfig{
+				Scopes:      []string{"openid", "profile", "email", "groups"},
+				GroupsClaim: "groups",
+			},
+			LDAP: LDAPConfig{
+				UserAttribute:  "uid",
+				GroupAttribute: "memberOf",
+			},
+		},
+		Store: StoreConfig{
+			Backend:   "sqlite",
+			KeyPrefix: "k3s-deploy",
+			SQLite: SQLiteConfig{
+				Path: "data/k3s-deploy.db",
+			},
+		},
+	}
+}
+
+// Standalone 单机模式的配置：不读取也不生成配置文件，数据保存在 dataDir 下，只监听本机地址，
+// 由后端提供内嵌的前端页面，使用启动时生成的访问令牌代替用户认证
+func Standalone(dataDir string) *Config {
+	cfg := getDefaultConfig()
+	cfg.Server.Host = "127.0.0.1"
+	cfg.Server.Frontend.Enabled = true
+	cfg.Logging.Level = "info"
+	for _, path := range []*string{
+		&cfg.Server.TLS.CertFile, &cfg.Server.TLS.KeyFile, &cfg.Server.TLS.ClientCAFile, &cfg.Server.TLS.CRLFile,
+		&cfg.Vault.Path, &cfg.Vault.KeyFile, &cfg.GitOps.WorkDir,
+		&cfg.IngressTLS.CACertFile, &cfg.IngressTLS.CAKeyFile,
+		&cfg.Bundles.Dir, &cfg.Bundles.PublicKeyFile, &cfg.Transcripts.Dir,
+		&cfg.SSHCA.KeyFile, &cfg.Auth.SessionKeyFile, &cfg.Store.SQLite.Path,
+		&cfg.JoinBundle.KeyFile,
+	} {
+		*path = filepath.Join(dataDir, strings.TrimPrefix(*path, "data/"))
 	}
+	return cfg
 }
 
 // LoadConfig 加载配置
 func LoadConfig() *Config {
+	cfg := loadConfigFile()
+	applyFaultsEnv(cfg)
+	return cfg
+}
+
+// override 按环境变量的值开关或覆盖故障注入配置
+func (f *FaultConfig) override(spec string) error {
+	if enabled, err := strconv.ParseBool(strings.TrimSpace(spec)); err == nil {
+		f.Enabled = enabled
+		return nil
+	}
+	overridden := *f
+	if err := yaml.Unmarshal([]byte(spec), &overridden); err != nil {
+		return err
+	}
+	*f = overridden
+	f.Enabled = true
+	return nil
+}
+
+func loadConfigFile() *Config {
 	// 检查配置文件是否存在
 	if _, err := os.Stat(configFilePath); os.IsNotExist(err) {
 		// 配置文件不存在，生成默认配置文件
@@ -66,8 +637,8 @@ func LoadConfig() *Config {
 		return getDefaultConfig()
 	}
 
-	// 解析配置文件
-	cfg := &Config{}
+	// 解析配置文件（未配置的字段保留默认值）
+	cfg := getDefaultConfig()
 	if err := yaml.Unmarshal(data, cfg); err != nil {
 		fmt.Printf("⚠️  解析配置文件失败: %v，使用默认配置\n", err)
 		return getDefaultConfig()
@@ -98,6 +669,9 @@ func saveConfig(cfg *Config) error {
 	return nil
 }
 
+// validBasePath 路径前缀由一个或多个 /段 组成
+var validBasePath = regexp.MustCompile(`^(/[A-Za-z0-9._~-]+)+$`)
+
 // Validate 验证配置合法性
 func (c *Config) Validate() error {
 	// 验证端口范围
@@ -105,6 +679,44 @@ func (c *Config) Validate() error {
 		return ErrInvalidPort
 	}
 
+	// 路径前缀以 / 开头、不以 / 结尾，只包含 URL 路径中的安全字符
+	if c.Server.BasePath != "" && !validBasePath.MatchString(c.Server.BasePath) {
+		return ErrInvalidBasePath
+	}
+	for _, proxy := range c.Server.TrustedProxies {
+		if net.ParseIP(proxy) == nil {
+			if _, _, err := net.ParseCIDR(proxy); err != nil {
+				return ErrInvalidTrustedProxy
+			}
+		}
+	}
+
+	if fe := c.Server.Frontend; fe.Enabled && fe.Dir != "" {
+		if info, err := os.Stat(fe.Dir); err != nil || !info.IsDir() {
+			return ErrInvalidFrontendDir
+		}
+	}
+
+	// 启用 TLS 时必须配置证书，校验客户端证书时必须配置 CA
+	if tls := c.Server.TLS; tls.Enabled {
+		if tls.CertFile == "" || tls.KeyFile == "" {
+			return ErrMissingTLSCert
+		}
+		switch tls.ClientAuth {
+		case "", "none":
+		case "optional", "require":
+			if tls.ClientCAFile == "" {
+				return ErrMissingClientCA
+			}
+			// Ingress CA 及其签发给各集群的中间 CA 不能签发被后端接受的客户端证书
+			if filepath.Clean(tls.ClientCAFile) == filepath.Clean(c.IngressTLS.CACertFile) {
+				return ErrSharedClientCA
+			}
+		default:
+			return ErrInvalidClientAuth
+		}
+	}
+
 	// 验证日志级别
 	validLevels := map[string]bool{
 		"debug": true, "info": true, "warn": true, "error": true, "fatal": true, "panic": true,
@@ -113,6 +725,295 @@ func (c *Config) Validate() error {
 		return ErrInvalidLogLevel
 	}
 
+	// 凭据库路径不能为空
+	if c.Vault.Path == "" || c.Vault.KeyFile == "" {
+		return ErrInvalidVaultPath
+	}
+
+	// 验证凭据轮换周期
+	if c.Vault.RotationInterval != "" {
+		if d, err := time.ParseDuration(c.Vault.RotationInterval); err != nil || d < time.Hour {
+			return ErrInvalidRotation
+		}
+	}
+
+	// 验证漂移检测周期
+	if c.Drift.Interval != "" {
+		if d, err := time.ParseDuration(c.Drift.Interval); err != nil || d < time.Minute {
+			return ErrInvalidDrift
+		}
+	}
+
+	// 启用 GitOps 时必须配置仓库地址
+	if c.GitOps.Enabled {
+		if c.GitOps.Repo == "" || c.GitOps.Branch == "" || c.GitOps.WorkDir == "" {
+			return ErrInvalidGitOps
+		}
+		if utils.URLHasCredentials(c.GitOps.Repo) {
+			return ErrGitOpsRepoCredentials
+		}
+		if c.GitOps.Interval != "" {
+			if d, err := time.ParseDuration(c.GitOps.Interval); err != nil || d < time.Minute {
+				return ErrInvalidGitOpsInterval
+			}
+		}
+	}
+
+	// 验证数据保留策略
+	if c.Retention.Interval != "" {
+		if d, err := time.ParseDuration(c.Retention.Interval); err != nil || d < time.Minute {
+			return ErrInvalidRetention
+		}
+	}
+	for _, value := range []string{c.Retention.Tasks, c.Retention.Workspaces, c.Retention.WebSSH} {
+		if value == "" {
+			continue
+		}
+		if d, err := time.ParseDuration(value); err != nil || d <= 0 {
+			return ErrInvalidRetentionTTL
+		}
+	}
+
+	// 验证告警配置
+	if c.Alerts.Interval != "" {
+		if d, err := time.ParseDuration(c.Alerts.Interval); err != nil || d < time.Minute {
+			return ErrInvalidAlertInterval
+		}
+	}
+	if d, err := time.ParseDuration(c.Alerts.CertExpiryWarning); err != nil || d <= 0 {
+		return ErrInvalidCertWarning
+	}
+
+	// 验证事件收集配置
+	if c.Events.Interval != "" {
+		if d, err := time.ParseDuration(c.Events.Interval); err != nil || d < 10*time.Second {
+			return ErrInvalidEventInterval
+		}
+	}
+	if d, err := time.ParseDuration(c.Events.Retention); err != nil || d <= 0 {
+		return ErrInvalidEventRetention
+	}
+
+	// 验证审计日志收集配置
+	if c.KubeAudit.Interval != "" {
+		if d, err := time.ParseDuration(c.KubeAudit.Interval); err != nil || d < 10*time.Second {
+			return ErrInvalidKubeAuditInterval
+		}
+	}
+	if d, err := time.ParseDuration(c.KubeAudit.Retention); err != nil || d <= 0 {
+		return ErrInvalidKubeAuditRetention
+	}
+
+	// 验证安全扫描配置
+	if c.SecurityScan.KubeBenchImage == "" || c.SecurityScan.Benchmark == "" || c.SecurityScan.TrivyImage == "" {
+		return ErrInvalidSecurityScan
+	}
+	if d, err := time.ParseDuration(c.SecurityScan.Timeout); err != nil || d < time.Minute {
+		return ErrInvalidSecurityScanTimeout
+	}
+
+	// 验证伸缩建议配置
+	if c.ScaleAdvisor.Interval != "" {
+		if d, err := time.ParseDuration(c.ScaleAdvisor.Interval); err != nil || d < time.Minute {
+			return ErrInvalidScaleAdvisorInterval
+		}
+	}
+	retention, err := time.ParseDuration(c.ScaleAdvisor.Retention)
+	if err != nil || retention <= 0 {
+		return ErrInvalidScaleAdvisorWindow
+	}
+	if d, err := time.ParseDuration(c.ScaleAdvisor.Window); err != nil || d <= 0 || d > retention {
+		return ErrInvalidScaleAdvisorWindow
+	}
+	if sa := c.ScaleAdvisor; sa.ScaleDownPercent <= 0 || sa.ScaleDownPercent >= sa.TargetPercent || sa.TargetPercent >= sa.ScaleUpPercent || sa.ScaleUpPercent > 100 {
+		return ErrInvalidScaleAdvisorPercent
+	}
+
+	// 启用邮件通知时必须配置服务器、发件人和收件人
+	if smtp := c.Notifications.SMTP; smtp.Enabled {
+		if smtp.Host == "" || smtp.Port < 1 || smtp.Port > 65535 || smtp.From == "" {
+			return ErrInvalidSMTP
+		}
+		if len(smtp.Subscriptions) == 0 {
+			return ErrMissingSMTPRecipients
+		}
+		for _, sub := range smtp.Subscriptions {
+			if len(sub.To) == 0 {
+				return ErrMissingSMTPRecipients
+			}
+		}
+	}
+
+	// 验证 SSH CA 配置
+	if c.SSHCA.Enabled {
+		if c.SSHCA.KeyFile == "" {
+			return ErrInvalidSSHCA
+		}
+		if d, err := time.ParseDuration(c.SSHCA.CertTTL); err != nil || d < time.Minute || d > 24*time.Hour {
+			return ErrInvalidSSHCATTL
+		}
+	}
+
+	// 镜像源必须是 http(s) 地址，且至少配置一个镜像源和检查镜像
+	if c.Registry.SystemDefault == "" && len(c.Registry.Mirrors) == 0 {
+		return ErrInvalidRegistry
+	}
+	for _, mirror := range c.Registry.Mirrors {
+		if !strings.HasPrefix(mirror, "https://") && !strings.HasPrefix(mirror, "http://") {
+			return ErrInvalidRegistry
+		}
+	}
+	if len(c.Registry.ProbeImages) == 0 {
+		return ErrMissingProbeImages
+	}
+	if c.Registry.ReleaseURL != "" && !strings.HasPrefix(c.Registry.ReleaseURL, "https://") && !strings.HasPrefix(c.Registry.ReleaseURL, "http://") {
+		return ErrInvalidReleaseURL
+	}
+
+	if c.IngressTLS.CACertFile == "" || c.IngressTLS.CAKeyFile == "" || c.IngressTLS.ValidDays <= 0 {
+		return ErrInvalidIngressTLS
+	}
+	if c.Bundles.Dir == "" || c.Bundles.PublicKeyFile == "" {
+		return ErrInvalidBundles
+	}
+	if c.Transcripts.Record && c.Transcripts.Dir == "" {
+		return ErrInvalidTranscripts
+	}
+	if c.Transcripts.Replay != "" && c.Simulation.Enabled {
+		return ErrTranscriptReplayConflict
+	}
+	for _, proxy := range []string{c.NodeProxy.HTTPProxy, c.NodeProxy.HTTPSProxy} {
+		if proxy != "" && !strings.HasPrefix(proxy, "http://") && !strings.HasPrefix(proxy, "https://") {
+			return ErrInvalidNodeProxy
+		}
+	}
+	if d, err := time.ParseDuration(c.SelfCheck.Timeout); c.SelfCheck.Enabled && (err != nil || d <= 0) {
+		return ErrInvalidSelfCheckTimeout
+	}
+	if c.JoinBundle.KeyFile == "" {
+		return ErrInvalidJoinBundleKey
+	}
+	if d, err := time.ParseDuration(c.JoinBundle.TTL); err != nil || d < time.Minute || d > MaxJoinBundleTTL {
+		return ErrInvalidJoinBundleTTL
+	}
+	for _, hook := range c.Progress.Webhooks {
+		if !strings.HasPrefix(hook.URL, "https://") && !strings.HasPrefix(hook.URL, "http://") {
+			return ErrInvalidProgressWebhook
+		}
+	}
+	if err := c.Faults.validate(); err != nil {
+		return err
+	}
+
+	if err := c.Auth.validate(); err != nil {
+		return err
+	}
+
+	// 任务并发数至少为 1
+	if c.Tasks.MaxConcurrent < 1 {
+		return ErrInvalidMaxTasks
+	}
+	if c.Tasks.PreflightConcurrency < 1 {
+		return ErrInvalidPreflightConcurrency
+	}
+	wait := c.Tasks.Wait
+	for _, value := range []string{wait.ServiceTimeout, wait.DeploymentTimeout, wait.PollInterval, wait.InstallTimeout, wait.ScriptDownloadTimeout} {
+		if d, err := time.ParseDuration(value); err != nil || d < time.Second {
+			return ErrInvalidTaskWait
+		}
+	}
+
+	// 验证存储后端
+	switch c.Store.Backend {
+	case "memory":
+	case "sqlite":
+		if c.Store.SQLite.Path == "" {
+			return ErrMissingSQLitePath
+		}
+	case "redis":
+		if c.Store.Redis.Addr == "" {
+			return ErrMissingRedisAddr
+		}
+	default:
+		return ErrInvalidStore
+	}
+
+	// 启用 Agent 模式时必须配置注册令牌
+	if c.Agent.Enabled && c.Agent.EnrollToken == "" {
+		return ErrMissingEnrollToken
+	}
+
+	return nil
+}
+
+// validate 验证故障注入配置
+func (f FaultConfig) validate() error {
+	if !f.Enabled {
+		return nil
+	}
+	if f.DropAfter < 0 {
+		return ErrInvalidFaultDropAfter
+	}
+	if f.Delay != "" {
+		if d, err := time.ParseDuration(f.Delay); err != nil || d < 0 {
+			return ErrInvalidFaultDelay
+		}
+	}
+	for _, spec := range f.FailSteps {
+		step, times, ok := strings.Cut(spec, ":")
+		if step == "" {
+			return ErrInvalidFaultSteps
+		}
+		if n, err := strconv.Atoi(times); ok && (err != nil || n < 1) {
+			return ErrInvalidFaultSteps
+		}
+	}
+	return nil
+}
+
+// validate 验证认证配置：启用后至少要有一种登录方式，角色名必须有效
+func (a AuthConfig) validate() error {
+	if !a.Enabled {
+		return nil
+	}
+	if a.SessionKeyFile == "" {
+		return ErrInvalidSessionKey
+	}
+	if d, err := time.ParseDuration(a.SessionTTL); err != nil || d <= 0 {
+		return ErrInvalidSessionTTL
+	}
+	if len(a.LocalUsers) == 0 && !a.OIDC.Enabled && !a.LDAP.Enabled {
+		return ErrMissingAuthProvider
+	}
+	for _, user := range a.LocalUsers {
+		if user.Username == "" || !strings.HasPrefix(user.PasswordHash, "$2") {
+			return ErrInvalidLocalUser
+		}
+		for _, role := range user.Roles {
+			if !auth.ValidRole(role) {
+				return ErrInvalidRole
+			}
+		}
+	}
+	if a.OIDC.Enabled && (a.OIDC.Issuer == "" || a.OIDC.ClientID == "" || a.OIDC.RedirectURL == "") {
+		return ErrInvalidOIDC
+	}
+	if a.LDAP.Enabled {
+		if !strings.HasPrefix(a.LDAP.URL, "ldap://") && !strings.HasPrefix(a.LDAP.URL, "ldaps://") {
+			return ErrInvalidLDAP
+		}
+		if a.LDAP.BaseDN == "" {
+			return ErrInvalidLDAP
+		}
+	}
+	for _, role := range a.GroupRoles {
+		if !auth.ValidRole(role) {
+			return ErrInvalidRole
+		}
+	}
+	if a.DefaultRole != "" && !auth.ValidRole(a.DefaultRole) {
+		return ErrInvalidRole
+	}
 	return nil
 }
 
@@ -123,17 +1024,217 @@ func (c *Config) Print() {
 	fmt.Printf("  Host: %s\n", c.Server.Host)
 	fmt.Printf("  Port: %d\n", c.Server.Port)
 	fmt.Printf("  CORS Origins: %v\n", c.Server.CORSOrigins)
+	if c.Server.BasePath != "" {
+		fmt.Printf("  Base Path: %s\n", c.Server.BasePath)
+	}
+	fmt.Printf("  Trusted Proxies: %v\n", c.Server.TrustedProxies)
+	fmt.Printf("  TLS: %v\n", c.Server.TLS.Enabled)
+	if c.Server.TLS.Enabled {
+		fmt.Printf("  Client Auth: %s\n", c.Server.TLS.ClientAuth)
+	}
+	fmt.Printf("  Frontend: %v\n", c.Server.Frontend.Enabled)
+	if c.Server.Frontend.Enabled && c.Server.Frontend.Dir != "" {
+		fmt.Printf("  Frontend Dir: %s\n", c.Server.Frontend.Dir)
+	}
 	fmt.Printf("Logging:\n")
 	fmt.Printf("  Level: %s\n", c.Logging.Level)
 	fmt.Printf("  Format: %s\n", c.Logging.Format)
 	fmt.Printf("  Output: %s\n", c.Logging.Output)
+	fmt.Printf("Agent:\n")
+	fmt.Printf("  Enabled: %v\n", c.Agent.Enabled)
+	fmt.Printf("  Binary Path: %s\n", c.Agent.BinaryPath)
+	fmt.Printf("Vault:\n")
+	fmt.Printf("  Path: %s\n", c.Vault.Path)
+	fmt.Printf("  Key File: %s\n", c.Vault.KeyFile)
+	fmt.Printf("  Rotation Interval: %s\n", c.Vault.RotationInterval)
+	fmt.Printf("Tasks:\n")
+	fmt.Printf("  Max Concurrent: %d\n", c.Tasks.MaxConcurrent)
+	fmt.Printf("  Preflight Concurrency: %d\n", c.Tasks.PreflightConcurrency)
+	fmt.Printf("  Wait: service %s, deployment %s, poll %s, install %s, script download %s\n",
+		c.Tasks.Wait.ServiceTimeout, c.Tasks.Wait.DeploymentTimeout, c.Tasks.Wait.PollInterval,
+		c.Tasks.Wait.InstallTimeout, c.Tasks.Wait.ScriptDownloadTimeout)
+	fmt.Printf("Drift:\n")
+	fmt.Printf("  Interval: %s\n", c.Drift.Interval)
+	fmt.Printf("  Auto Reconcile: %v\n", c.Drift.AutoReconcile)
+	fmt.Printf("GitOps:\n")
+	fmt.Printf("  Enabled: %v\n", c.GitOps.Enabled)
+	if c.GitOps.Enabled {
+		fmt.Printf("  Repo: %s (%s:%s)\n", utils.RedactURL(c.GitOps.Repo), c.GitOps.Branch, c.GitOps.Path)
+		fmt.Printf("  Token: %v\n", c.GitOps.Token != "")
+		fmt.Printf("  Interval: %s\n", c.GitOps.Interval)
+	}
+	fmt.Printf("Retention:\n")
+	fmt.Printf("  Interval: %s\n", c.Retention.Interval)
+	fmt.Printf("  Tasks: %s\n", c.Retention.Tasks)
+	fmt.Printf("  Workspaces: %s\n", c.Retention.Workspaces)
+	fmt.Printf("  WebSSH: %s\n", c.Retention.WebSSH)
+	fmt.Printf("Alerts:\n")
+	fmt.Printf("  Interval: %s\n", c.Alerts.Interval)
+	fmt.Printf("  Cert Expiry Warning: %s\n", c.Alerts.CertExpiryWarning)
+	fmt.Printf("  Webhook: %v\n", c.Alerts.WebhookURL != "")
+	fmt.Printf("Events:\n")
+	fmt.Printf("  Interval: %s\n", c.Events.Interval)
+	fmt.Printf("  Retention: %s\n", c.Events.Retention)
+	fmt.Printf("Kube Audit:\n")
+	fmt.Printf("  Interval: %s\n", c.KubeAudit.Interval)
+	fmt.Printf("  Retention: %s\n", c.KubeAudit.Retention)
+	fmt.Printf("Security Scan:\n")
+	fmt.Printf("  kube-bench: %s (%s)\n", c.SecurityScan.KubeBenchImage, c.SecurityScan.Benchmark)
+	fmt.Printf("  trivy: %s\n", c.SecurityScan.TrivyImage)
+	fmt.Printf("  DB Repository: %s\n", c.SecurityScan.DBRepository)
+	fmt.Printf("  Timeout: %s\n", c.SecurityScan.Timeout)
+	fmt.Printf("Scale Advisor:\n")
+	fmt.Printf("  Interval: %s\n", c.ScaleAdvisor.Interval)
+	fmt.Printf("  Retention: %s\n", c.ScaleAdvisor.Retention)
+	fmt.Printf("  Window: %s\n", c.ScaleAdvisor.Window)
+	fmt.Printf("  Thresholds: up %d%%, down %d%%, target %d%%\n", c.ScaleAdvisor.ScaleUpPercent, c.ScaleAdvisor.ScaleDownPercent, c.ScaleAdvisor.TargetPercent)
+	fmt.Printf("Simulation:\n")
+	fmt.Printf("  Enabled: %v\n", c.Simulation.Enabled)
+	if c.Simulation.Enabled {
+		fmt.Printf("  Script: %s\n", c.Simulation.Script)
+	}
+	fmt.Printf("Transcripts:\n")
+	fmt.Printf("  Record: %v\n", c.Transcripts.Record)
+	fmt.Printf("  Dir: %s\n", c.Transcripts.Dir)
+	if c.Transcripts.Replay != "" {
+		fmt.Printf("  Replay: %s\n", c.Transcripts.Replay)
+	}
+	if c.NodeProxy.HTTPProxy != "" || c.NodeProxy.HTTPSProxy != "" {
+		fmt.Printf("Node Proxy:\n")
+		fmt.Printf("  HTTP Proxy: %s\n", c.NodeProxy.HTTPProxy)
+		fmt.Printf("  HTTPS Proxy: %s\n", c.NodeProxy.HTTPSProxy)
+		fmt.Printf("  No Proxy: %s\n", c.NodeProxy.NoProxy)
+	}
+	fmt.Printf("Self Check:\n")
+	fmt.Printf("  Enabled: %v\n", c.SelfCheck.Enabled)
+	fmt.Printf("  Mirrors: %v\n", c.SelfCheck.Mirrors)
+	fmt.Printf("  Timeout: %s\n", c.SelfCheck.Timeout)
+	fmt.Printf("Join Bundle:\n")
+	fmt.Printf("  Key File: %s\n", c.JoinBundle.KeyFile)
+	fmt.Printf("  TTL: %s\n", c.JoinBundle.TTL)
+	fmt.Printf("Progress:\n")
+	fmt.Printf("  Log Dir: %s\n", c.Progress.LogDir)
+	fmt.Printf("  Webhooks: %d\n", len(c.Progress.Webhooks))
+	if c.Faults.Enabled {
+		fmt.Printf("Faults:\n")
+		fmt.Printf("  DropAfter: %d\n", c.Faults.DropAfter)
+		fmt.Printf("  Delay: %s\n", c.Faults.Delay)
+		fmt.Printf("  FailSteps: %v\n", c.Faults.FailSteps)
+	}
+	fmt.Printf("Notifications:\n")
+	fmt.Printf("  SMTP: %v\n", c.Notifications.SMTP.Enabled)
+	if c.Notifications.SMTP.Enabled {
+		fmt.Printf("  SMTP Server: %s:%d\n", c.Notifications.SMTP.Host, c.Notifications.SMTP.Port)
+		fmt.Printf("  SMTP Subscriptions: %d\n", len(c.Notifications.SMTP.Subscriptions))
+	}
+	fmt.Printf("Registry:\n")
+	fmt.Printf("  System Default: %s\n", c.Registry.SystemDefault)
+	fmt.Printf("  Mirrors: %s\n", strings.Join(c.Registry.Mirrors, ", "))
+	if c.Registry.ReleaseURL != "" {
+		fmt.Printf("  Release URL: %s\n", c.Registry.ReleaseURL)
+	} else {
+		fmt.Printf("  Release URL: auto\n")
+	}
+	fmt.Printf("Ingress TLS:\n")
+	fmt.Printf("  CA Cert File: %s\n", c.IngressTLS.CACertFile)
+	fmt.Printf("  Valid Days: %d\n", c.IngressTLS.ValidDays)
+	fmt.Printf("Bundles:\n")
+	fmt.Printf("  Dir: %s\n", c.Bundles.Dir)
+	fmt.Printf("  Public Key File: %s\n", c.Bundles.PublicKeyFile)
+	fmt.Printf("SSH CA:\n")
+	fmt.Printf("  Enabled: %v\n", c.SSHCA.Enabled)
+	if c.SSHCA.Enabled {
+		fmt.Printf("  Key File: %s\n", c.SSHCA.KeyFile)
+		fmt.Printf("  Cert TTL: %s\n", c.SSHCA.CertTTL)
+	}
+	fmt.Printf("Auth:\n")
+	fmt.Printf("  Enabled: %v\n", c.Auth.Enabled)
+	if c.Auth.Enabled {
+		fmt.Printf("  Session TTL: %s\n", c.Auth.SessionTTL)
+		fmt.Printf("  Local Users: %d\n", len(c.Auth.LocalUsers))
+		fmt.Printf("  OIDC: %v\n", c.Auth.OIDC.Enabled)
+		if c.Auth.OIDC.Enabled {
+			fmt.Printf("  OIDC Issuer: %s\n", c.Auth.OIDC.Issuer)
+		}
+		fmt.Printf("  LDAP: %v\n", c.Auth.LDAP.Enabled)
+		if c.Auth.LDAP.Enabled {
+			fmt.Printf("  LDAP URL: %s\n", c.Auth.LDAP.URL)
+		}
+	}
+	fmt.Printf("Store:\n")
+	fmt.Printf("  Backend: %s\n", c.Store.Backend)
+	switch c.Store.Backend {
+	case "sqlite":
+		fmt.Printf("  SQLite: %s\n", c.Store.SQLite.Path)
+	case "redis":
+		fmt.Printf("  Redis: %s (db %d)\n", c.Store.Redis.Addr, c.Store.Redis.DB)
+	}
 	fmt.Println("================")
 }
 
 // 配置错误定义
 var (
-	ErrInvalidPort     = &ConfigError{Field: "Server.Port", Message: "端口必须在 1-65535 范围内"}
-	ErrInvalidLogLevel = &ConfigError{Field: "Logging.Level", Message: "无效的日志级别"}
+	ErrInvalidPort                 = &ConfigError{Field: "Server.Port", Message: "端口必须在 1-65535 范围内"}
+	ErrInvalidBasePath             = &ConfigError{Field: "Server.BasePath", Message: "路径前缀必须以 / 开头、不以 / 结尾，且只能包含字母、数字和 -._~/"}
+	ErrInvalidTrustedProxy         = &ConfigError{Field: "Server.TrustedProxies", Message: "可信代理必须是 IP 地址或 CIDR"}
+	ErrInvalidFrontendDir          = &ConfigError{Field: "Server.Frontend.Dir", Message: "前端目录不存在"}
+	ErrMissingTLSCert              = &ConfigError{Field: "Server.TLS", Message: "启用 TLS 时必须配置证书和私钥文件"}
+	ErrInvalidClientAuth           = &ConfigError{Field: "Server.TLS.ClientAuth", Message: "客户端证书校验模式必须是 none、optional 或 require"}
+	ErrMissingClientCA             = &ConfigError{Field: "Server.TLS.ClientCAFile", Message: "校验客户端证书时必须配置 CA 证书文件"}
+	ErrSharedClientCA              = &ConfigError{Field: "Server.TLS.ClientCAFile", Message: "客户端 CA 不能与 Ingress CA（ingress_tls.ca_cert_file）相同"}
+	ErrInvalidLogLevel             = &ConfigError{Field: "Logging.Level", Message: "无效的日志级别"}
+	ErrInvalidVaultPath            = &ConfigError{Field: "Vault.Path", Message: "凭据库路径和主密钥文件路径不能为空"}
+	ErrInvalidRotation             = &ConfigError{Field: "Vault.RotationInterval", Message: "轮换周期格式无效或小于 1h"}
+	ErrMissingEnrollToken          = &ConfigError{Field: "Agent.EnrollToken", Message: "启用 Agent 模式时必须配置注册令牌"}
+	ErrInvalidDrift                = &ConfigError{Field: "Drift.Interval", Message: "漂移检测周期格式无效或小于 1m"}
+	ErrInvalidGitOps               = &ConfigError{Field: "GitOps.Repo", Message: "启用 GitOps 时必须配置仓库地址、分支和工作目录"}
+	ErrInvalidGitOpsInterval       = &ConfigError{Field: "GitOps.Interval", Message: "同步周期格式无效或小于 1m"}
+	ErrGitOpsRepoCredentials       = &ConfigError{Field: "GitOps.Repo", Message: "仓库地址中不能包含用户名或令牌，请使用 gitops.username 和 gitops.token"}
+	ErrInvalidRetention            = &ConfigError{Field: "Retention.Interval", Message: "清理周期格式无效或小于 1m"}
+	ErrInvalidRetentionTTL         = &ConfigError{Field: "Retention", Message: "任务或工作目录保留时长格式无效"}
+	ErrInvalidAlertInterval        = &ConfigError{Field: "Alerts.Interval", Message: "告警评估周期格式无效或小于 1m"}
+	ErrInvalidCertWarning          = &ConfigError{Field: "Alerts.CertExpiryWarning", Message: "证书到期告警阈值格式无效"}
+	ErrInvalidEventInterval        = &ConfigError{Field: "Events.Interval", Message: "事件收集周期格式无效或小于 10s"}
+	ErrInvalidEventRetention       = &ConfigError{Field: "Events.Retention", Message: "事件保留时长格式无效"}
+	ErrInvalidKubeAuditInterval    = &ConfigError{Field: "KubeAudit.Interval", Message: "审计日志收集周期格式无效或小于 10s"}
+	ErrInvalidKubeAuditRetention   = &ConfigError{Field: "KubeAudit.Retention", Message: "审计日志保留时长格式无效"}
+	ErrInvalidSecurityScan         = &ConfigError{Field: "SecurityScan", Message: "kube-bench 镜像、基准和 trivy 镜像不能为空"}
+	ErrInvalidSecurityScanTimeout  = &ConfigError{Field: "SecurityScan.Timeout", Message: "安全扫描超时格式无效或小于 1m"}
+	ErrInvalidScaleAdvisorInterval = &ConfigError{Field: "ScaleAdvisor.Interval", Message: "用量采集周期格式无效或小于 1m"}
+	ErrInvalidScaleAdvisorWindow   = &ConfigError{Field: "ScaleAdvisor.Window", Message: "采样保留时长或分析窗口格式无效，窗口不能超过保留时长"}
+	ErrInvalidScaleAdvisorPercent  = &ConfigError{Field: "ScaleAdvisor", Message: "伸缩阈值必须满足 0 < scale_down_percent < target_percent < scale_up_percent <= 100"}
+	ErrInvalidSMTP                 = &ConfigError{Field: "Notifications.SMTP", Message: "启用邮件通知时必须配置服务器地址、端口和发件人"}
+	ErrMissingSMTPRecipients       = &ConfigError{Field: "Notifications.SMTP.Subscriptions", Message: "启用邮件通知时每个订阅都必须配置收件人"}
+	ErrInvalidRegistry             = &ConfigError{Field: "Registry.Mirrors", Message: "至少需要配置一个镜像源，镜像加速地址必须以 http:// 或 https:// 开头"}
+	ErrMissingProbeImages          = &ConfigError{Field: "Registry.ProbeImages", Message: "至少需要配置一个镜像源检查镜像"}
+	ErrInvalidReleaseURL           = &ConfigError{Field: "Registry.ReleaseURL", Message: "k3s 发布文件地址必须以 http:// 或 https:// 开头"}
+	ErrInvalidIngressTLS           = &ConfigError{Field: "IngressTLS", Message: "必须配置 CA 证书和私钥文件，证书有效天数必须大于 0"}
+	ErrInvalidBundles              = &ConfigError{Field: "Bundles", Message: "必须配置离线安装包目录和签名公钥文件"}
+	ErrInvalidTranscripts          = &ConfigError{Field: "Transcripts.Dir", Message: "启用命令录制时必须配置录制目录"}
+	ErrTranscriptReplayConflict    = &ConfigError{Field: "Transcripts.Replay", Message: "命令回放不能与模拟 SSH 后端同时启用"}
+	ErrInvalidNodeProxy            = &ConfigError{Field: "NodeProxy", Message: "节点代理地址必须以 http:// 或 https:// 开头"}
+	ErrInvalidSelfCheckTimeout     = &ConfigError{Field: "SelfCheck.Timeout", Message: "启动自检超时时间格式无效"}
+	ErrInvalidJoinBundleKey        = &ConfigError{Field: "JoinBundle.KeyFile", Message: "必须配置加入信息包签名私钥文件"}
+	ErrInvalidJoinBundleTTL        = &ConfigError{Field: "JoinBundle.TTL", Message: "加入信息包有效期格式无效或不在 1m-720h 范围内"}
+	ErrInvalidProgressWebhook      = &ConfigError{Field: "Progress.Webhooks", Message: "进度事件 Webhook 地址必须以 http:// 或 https:// 开头"}
+	ErrInvalidFaultDropAfter       = &ConfigError{Field: "Faults.DropAfter", Message: "断开连接的命令间隔不能为负数"}
+	ErrInvalidFaultDelay           = &ConfigError{Field: "Faults.Delay", Message: "命令延迟格式无效"}
+	ErrInvalidFaultSteps           = &ConfigError{Field: "Faults.FailSteps", Message: "故障步骤格式应为 步骤 或 步骤:失败次数（正整数）"}
+	ErrInvalidSSHCA                = &ConfigError{Field: "SSHCA.KeyFile", Message: "启用 SSH CA 时必须配置 CA 私钥文件"}
+	ErrInvalidSSHCATTL             = &ConfigError{Field: "SSHCA.CertTTL", Message: "SSH 证书有效期格式无效或不在 1m-24h 范围内"}
+	ErrInvalidSessionKey           = &ConfigError{Field: "Auth.SessionKeyFile", Message: "启用认证时必须配置会话密钥文件"}
+	ErrInvalidSessionTTL           = &ConfigError{Field: "Auth.SessionTTL", Message: "会话有效期格式无效"}
+	ErrMissingAuthProvider         = &ConfigError{Field: "Auth", Message: "启用认证时至少需要配置本地用户、OIDC 或 LDAP 之一"}
+	ErrInvalidLocalUser            = &ConfigError{Field: "Auth.LocalUsers", Message: "本地用户必须配置用户名和 bcrypt 密码哈希"}
+	ErrInvalidRole                 = &ConfigError{Field: "Auth", Message: "角色必须是 admin、operator 或 viewer"}
+	ErrInvalidOIDC                 = &ConfigError{Field: "Auth.OIDC", Message: "启用 OIDC 时必须配置 issuer、client_id 和 redirect_url"}
+	ErrInvalidLDAP                 = &ConfigError{Field: "Auth.LDAP", Message: "启用 LDAP 时必须配置 ldap:// 或 ldaps:// 地址和 base_dn"}
+	ErrInvalidMaxTasks             = &ConfigError{Field: "Tasks.MaxConcurrent", Message: "最大并发任务数必须大于 0"}
+	ErrInvalidPreflightConcurrency = &ConfigError{Field: "Tasks.PreflightConcurrency", Message: "节点预检并发数必须大于 0"}
+	ErrInvalidTaskWait             = &ConfigError{Field: "Tasks.Wait", Message: "部署等待时长格式无效或小于 1 秒"}
+	ErrInvalidStore                = &ConfigError{Field: "Store.Backend", Message: "存储后端必须是 memory、sqlite 或 redis"}
+	ErrMissingRedisAddr            = &ConfigError{Field: "Store.Redis.Addr", Message: "使用 redis 存储时必须配置地址"}
+	ErrMissingSQLitePath           = &ConfigError{Field: "Store.SQLite.Path", Message: "使用 sqlite 存储时必须配置数据库路径"}
 )
 
 type ConfigError struct {
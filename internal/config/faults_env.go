@@ -0,0 +1,21 @@
+//go:build faults
+
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// applyFaultsEnv 设置环境变量 K3S_DEPLOY_FAULTS 时覆盖故障注入配置（不写入配置文件）：true/false 开关配置文件中的故障注入，
+// 其他值按 YAML 解析后覆盖对应字段并启用，如 '{drop_after: 5, fail_steps: [install-master:1]}'。
+// 只在以 -tags faults 构建的测试版本中生效
+func applyFaultsEnv(cfg *Config) {
+	spec, ok := os.LookupEnv(faultsEnv)
+	if !ok {
+		return
+	}
+	if err := cfg.Faults.override(spec); err != nil {
+		fmt.Printf("⚠️  解析环境变量 %s 失败: %v，忽略\n", faultsEnv, err)
+	}
+}
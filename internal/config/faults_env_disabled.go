@@ -0,0 +1,16 @@
+//go:build !faults
+
+package config
+
+import (
+	"fmt"
+	"os"
+)
+
+// applyFaultsEnv 常规构建不读取 K3S_DEPLOY_FAULTS，避免通过环境变量在生产环境开启故障注入；
+// 故障注入只能通过配置文件的 faults.enabled 显式开启
+func applyFaultsEnv(cfg *Config) {
+	if _, ok := os.LookupEnv(faultsEnv); ok {
+		fmt.Printf("⚠️  环境变量 %s 只在以 -tags faults 构建时生效，已忽略\n", faultsEnv)
+	}
+}
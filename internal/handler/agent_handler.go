@@ -0,0 +1,164 @@
+package handler
+
+import (
+	"net/http"
+	"net/url"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/middleware"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/agent"
+)
+
+type AgentHandler struct {
+	hub        *agent.Hub
+	binaryPath string
+}
+
+func NewAgentHandler(hub *agent.Hub, binaryPath string) *AgentHandler {
+	return &AgentHandler{
+		hub:        hub,
+		binaryPath: binaryPath,
+	}
+}
+
+// enabled 检查 Agent 模式是否启用
+func (h *AgentHandler) enabled(c *gin.Context) bool {
+	if h.hub == nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Success: false,
+			Message: "Agent 模式未启用",
+		})
+		return false
+	}
+	return true
+}
+
+// agentToken 读取请求中的令牌，优先使用请求头
+func agentToken(c *gin.Context) string {
+	if token := c.GetHeader("X-Agent-Token"); token != "" {
+		return token
+	}
+	return c.Query("token")
+}
+
+// authorize 校验注册令牌，用于生成引导脚本
+func (h *AgentHandler) authorize(c *gin.Context) bool {
+	if !h.enabled(c) {
+		return false
+	}
+	if !h.hub.Authorize(agentToken(c)) {
+		c.JSON(http.StatusUnauthorized, model.ErrorResponse{
+			Success: false,
+			Message: "Agent 注册令牌无效",
+		})
+		return false
+	}
+	return true
+}
+
+// authorizeAgent 校验 Agent 的连接密钥，返回认证的 Agent ID
+func (h *AgentHandler) authorizeAgent(c *gin.Context) (string, bool) {
+	if !h.enabled(c) {
+		return "", false
+	}
+	agentID := c.Query("agent")
+	if !h.hub.AuthorizeAgent(agentID, agentToken(c)) {
+		c.JSON(http.StatusUnauthorized, model.ErrorResponse{
+			Success: false,
+			Message: "Agent 连接密钥无效",
+		})
+		return "", false
+	}
+	return agentID, true
+}
+
+// Connect Agent 控制通道
+func (h *AgentHandler) Connect(c *gin.Context) {
+	agentID, ok := h.authorizeAgent(c)
+	if !ok {
+		return
+	}
+	// 同一 ID 已在线时直接拒绝，避免持有密钥的另一台机器顶替在线的 Agent
+	if h.hub.Connected(agentID) {
+		c.JSON(http.StatusConflict, model.ErrorResponse{
+			Success: false,
+			Message: "连接失败",
+			Details: agent.ErrAgentConnected.Error(),
+		})
+		return
+	}
+	if err := h.hub.ServeControl(c.Writer, c.Request, agentID); err != nil {
+		c.Error(err)
+	}
+}
+
+// Tunnel Agent 隧道连接
+func (h *AgentHandler) Tunnel(c *gin.Context) {
+	agentID, ok := h.authorizeAgent(c)
+	if !ok {
+		return
+	}
+	if err := h.hub.ServeTunnel(c.Writer, c.Request, agentID, c.Query("id")); err != nil {
+		c.Error(err)
+	}
+}
+
+// List 返回在线 Agent 列表
+func (h *AgentHandler) List(c *gin.Context) {
+	if h.hub == nil {
+		respondList(c, []agent.AgentInfo{}, agentListSpec)
+		return
+	}
+	respondList(c, h.hub.Agents(), agentListSpec)
+}
+
+// agentListSpec Agent 列表支持按主机名过滤
+var agentListSpec = listSpec[agent.AgentInfo]{
+	filters: map[string]func(agent.AgentInfo, string) bool{
+		"hostname": func(a agent.AgentInfo, v string) bool { return containsFold(a.Hostname, v) },
+	},
+	sorts: map[string]func(a, b agent.AgentInfo) int{
+		"hostname":    func(a, b agent.AgentInfo) int { return strings.Compare(a.Hostname, b.Hostname) },
+		"connectedAt": func(a, b agent.AgentInfo) int { return strings.Compare(a.ConnectedAt, b.ConnectedAt) },
+	},
+}
+
+// Bootstrap 返回节点引导脚本，脚本中只包含 id 指定的 Agent 的连接密钥，不包含注册令牌
+func (h *AgentHandler) Bootstrap(c *gin.Context) {
+	if !h.authorize(c) {
+		return
+	}
+	agentID := c.Query("id")
+	if !agent.ValidAgentID(agentID) {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: "id 为必填项，只能包含字母、数字、点、下划线和连字符，不超过 63 个字符",
+		})
+		return
+	}
+
+	// 地址按客户端的访问方式生成，后端位于反向代理之后时包含代理的协议、主机和路径前缀
+	serverURL := middleware.ExternalURL(c, true, "")
+	binaryURL := middleware.ExternalURL(c, false, "/api/agent/binary?agent="+url.QueryEscape(agentID))
+
+	c.String(http.StatusOK, agent.BootstrapScript(serverURL, binaryURL, agentID, h.hub.AgentSecret(agentID)))
+}
+
+// Binary 下载 Agent 二进制，使用注册令牌或 Agent 的连接密钥
+func (h *AgentHandler) Binary(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+	token := agentToken(c)
+	if !h.hub.Authorize(token) && !h.hub.AuthorizeAgent(c.Query("agent"), token) {
+		c.JSON(http.StatusUnauthorized, model.ErrorResponse{
+			Success: false,
+			Message: "Agent 注册令牌无效",
+		})
+		return
+	}
+	c.FileAttachment(h.binaryPath, "k3s-deploy-agent")
+}
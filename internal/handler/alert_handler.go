@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/service"
+)
+
+type AlertHandler struct {
+	alertService *service.AlertService
+}
+
+func NewAlertHandler(alertService *service.AlertService) *AlertHandler {
+	return &AlertHandler{
+		alertService: alertService,
+	}
+}
+
+func (h *AlertHandler) List(c *gin.Context) {
+	alerts, err := h.alertService.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "读取告警列表失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	respondList(c, alerts, alertListSpec)
+}
+
+// Evaluate 立即评估所有受管集群的告警
+func (h *AlertHandler) Evaluate(c *gin.Context) {
+	if err := h.alertService.Evaluate(); err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "告警评估失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	h.List(c)
+}
+
+// alertListSpec 告警列表支持按状态、级别、规则和集群过滤
+var alertListSpec = listSpec[*model.Alert]{
+	filters: map[string]func(*model.Alert, string) bool{
+		"status":    func(a *model.Alert, v string) bool { return a.Status == v },
+		"severity":  func(a *model.Alert, v string) bool { return a.Severity == v },
+		"rule":      func(a *model.Alert, v string) bool { return a.Rule == v },
+		"clusterId": func(a *model.Alert, v string) bool { return a.ClusterID == v },
+	},
+	sorts: map[string]func(a, b *model.Alert) int{
+		"firedAt":    func(a, b *model.Alert) int { return a.FiredAt.Compare(b.FiredAt) },
+		"lastSeenAt": func(a, b *model.Alert) int { return a.LastSeenAt.Compare(b.LastSeenAt) },
+		"severity":   func(a, b *model.Alert) int { return strings.Compare(a.Severity, b.Severity) },
+	},
+	defaultSort: "-firedAt",
+}
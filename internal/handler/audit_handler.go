@@ -0,0 +1,47 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/service"
+)
+
+type AuditHandler struct {
+	auditService *service.AuditService
+}
+
+func NewAuditHandler(auditService *service.AuditService) *AuditHandler {
+	return &AuditHandler{
+		auditService: auditService,
+	}
+}
+
+// List 返回运维操作审计记录
+func (h *AuditHandler) List(c *gin.Context) {
+	events, err := h.auditService.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "读取审计记录失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	respondList(c, events, auditListSpec)
+}
+
+// auditListSpec 审计记录支持按动作、操作用户、集群和操作对象过滤
+var auditListSpec = listSpec[model.AuditEvent]{
+	filters: map[string]func(model.AuditEvent, string) bool{
+		"action":    func(e model.AuditEvent, v string) bool { return e.Action == v },
+		"actor":     func(e model.AuditEvent, v string) bool { return e.Actor == v },
+		"clusterId": func(e model.AuditEvent, v string) bool { return e.ClusterID == v },
+		"target":    func(e model.AuditEvent, v string) bool { return e.Target == v },
+	},
+	sorts: map[string]func(a, b model.AuditEvent) int{
+		"time": func(a, b model.AuditEvent) int { return a.Time.Compare(b.Time) },
+	},
+	defaultSort: "-time",
+}
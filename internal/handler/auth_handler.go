@@ -0,0 +1,184 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/middleware"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/auth"
+	"k3s-deploy-backend/internal/service"
+)
+
+// oidcStateCookie OIDC 登录发起时写入的 state、nonce 和 PKCE code_verifier，
+// 回调时比对 state 以防止 CSRF，nonce 和 code_verifier 用于校验授权码和 id_token 属于本次登录
+const oidcStateCookie = "oidc_state"
+
+type AuthHandler struct {
+	authService *service.AuthService
+	// frontendRedirect OIDC 登录成功后跳转的前端地址
+	frontendRedirect string
+}
+
+// NewAuthHandler authService 为 nil 表示未启用认证
+func NewAuthHandler(authService *service.AuthService, frontendRedirect string) *AuthHandler {
+	return &AuthHandler{
+		authService:      authService,
+		frontendRedirect: frontendRedirect,
+	}
+}
+
+// Login 用户名密码登录
+func (h *AuthHandler) Login(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+
+	var req model.LoginRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	resp, err := h.authService.Login(req.Username, req.Password)
+	if err != nil {
+		h.loginFailed(c, err)
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// OIDCLogin 跳转到 OIDC 身份提供方
+func (h *AuthHandler) OIDCLogin(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+
+	login, err := auth.NewOIDCLogin()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "生成登录状态失败",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	authURL, err := h.authService.OIDCAuthURL(login)
+	if err != nil {
+		h.loginFailed(c, err)
+		return
+	}
+
+	c.SetSameSite(http.SameSiteLaxMode)
+	c.SetCookie(oidcStateCookie, login.Encode(), 600, oidcCookiePath(c), "", middleware.ExternalSecure(c), true)
+	c.Redirect(http.StatusFound, authURL)
+}
+
+// OIDCCallback 身份提供方回调，登录成功后跳转到前端（令牌放在 URL fragment 中）或直接返回令牌
+func (h *AuthHandler) OIDCCallback(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+
+	if errMsg := c.Query("error"); errMsg != "" {
+		c.JSON(http.StatusUnauthorized, model.ErrorResponse{
+			Success: false,
+			Message: "OIDC 登录失败",
+			Details: errMsg + ": " + c.Query("error_description"),
+		})
+		return
+	}
+
+	var login *auth.OIDCLogin
+	cookie, err := c.Cookie(oidcStateCookie)
+	if err == nil {
+		login, err = auth.ParseOIDCLogin(cookie)
+	}
+	if err != nil || login.State != c.Query("state") {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "登录状态校验失败，请重新登录",
+		})
+		return
+	}
+	c.SetCookie(oidcStateCookie, "", -1, oidcCookiePath(c), "", middleware.ExternalSecure(c), true)
+
+	resp, err := h.authService.OIDCCallback(c.Query("code"), login)
+	if err != nil {
+		h.loginFailed(c, err)
+		return
+	}
+
+	if h.frontendRedirect != "" {
+		c.Redirect(http.StatusFound, h.frontendRedirect+"#token="+url.QueryEscape(resp.Token))
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Me 返回当前登录用户
+func (h *AuthHandler) Me(c *gin.Context) {
+	claims := middleware.GetClaims(c)
+	if claims == nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Success: false,
+			Message: "未启用认证",
+		})
+		return
+	}
+	c.JSON(http.StatusOK, model.UserInfo{
+		Subject:  claims.Subject,
+		Name:     claims.Name,
+		Provider: claims.Provider,
+		Roles:    claims.Roles,
+	})
+}
+
+func (h *AuthHandler) enabled(c *gin.Context) bool {
+	if h.authService == nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Success: false,
+			Message: "未启用认证",
+		})
+		return false
+	}
+	return true
+}
+
+func (h *AuthHandler) loginFailed(c *gin.Context, err error) {
+	switch {
+	case errors.Is(err, auth.ErrInvalidCredentials):
+		c.JSON(http.StatusUnauthorized, model.ErrorResponse{
+			Success: false,
+			Message: "用户名或密码错误",
+		})
+	case errors.Is(err, service.ErrNoRole):
+		c.JSON(http.StatusForbidden, model.ErrorResponse{
+			Success: false,
+			Message: "登录成功但没有访问权限，请联系管理员配置用户组映射",
+		})
+	case errors.Is(err, service.ErrOIDCDisabled):
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Success: false,
+			Message: err.Error(),
+		})
+	default:
+		c.JSON(http.StatusServiceUnavailable, model.ErrorResponse{
+			Success: false,
+			Message: "身份提供方认证失败",
+			Details: err.Error(),
+		})
+	}
+}
+
+// oidcCookiePath state Cookie 只在后端的路径前缀下发送，与同一域名下的其他应用隔离
+func oidcCookiePath(c *gin.Context) string {
+	return middleware.BasePath(c) + "/"
+}
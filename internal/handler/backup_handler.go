@@ -0,0 +1,214 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/middleware"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/service"
+)
+
+type BackupHandler struct {
+	backupService *service.BackupService
+}
+
+func NewBackupHandler(backupService *service.BackupService) *BackupHandler {
+	return &BackupHandler{
+		backupService: backupService,
+	}
+}
+
+// Backups 返回集群的 Velero 备份
+func (h *BackupHandler) Backups(c *gin.Context) {
+	backups, err := h.backupService.Backups(c.Param("clusterId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "读取备份失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	respondList(c, backups, backupListSpec)
+}
+
+// CreateBackup 立即备份命名空间，备份在集群中异步执行
+func (h *BackupHandler) CreateBackup(c *gin.Context) {
+	var req model.BackupRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	backup, err := h.backupService.CreateBackup(c.Param("clusterId"), &req, actor(c), middleware.GetRequestID(c))
+	if err != nil {
+		c.JSON(backupErrorStatus(err), model.ErrorResponse{
+			Success: false,
+			Message: "创建备份失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusAccepted, backup)
+}
+
+// Restore 从备份恢复；请求体可省略，此时恢复备份中的全部命名空间
+func (h *BackupHandler) Restore(c *gin.Context) {
+	var req model.RestoreRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	restore, err := h.backupService.CreateRestore(c.Param("clusterId"), c.Param("name"), &req, actor(c), middleware.GetRequestID(c))
+	if err != nil {
+		c.JSON(backupErrorStatus(err), model.ErrorResponse{
+			Success: false,
+			Message: "创建恢复失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusAccepted, restore)
+}
+
+// Restores 返回集群的 Velero 恢复
+func (h *BackupHandler) Restores(c *gin.Context) {
+	restores, err := h.backupService.Restores(c.Param("clusterId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "读取恢复记录失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	respondList(c, restores, restoreListSpec)
+}
+
+// Schedules 返回集群的定时备份
+func (h *BackupHandler) Schedules(c *gin.Context) {
+	schedules, err := h.backupService.Schedules(c.Param("clusterId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "读取定时备份失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	respondList(c, schedules, scheduleListSpec)
+}
+
+// ApplySchedule 创建或更新定时备份
+func (h *BackupHandler) ApplySchedule(c *gin.Context) {
+	var req model.BackupScheduleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	if err := h.backupService.ApplySchedule(c.Param("clusterId"), c.Param("name"), &req, actor(c), middleware.GetRequestID(c)); err != nil {
+		c.JSON(backupErrorStatus(err), model.ErrorResponse{
+			Success: false,
+			Message: "更新定时备份失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// DeleteSchedule 删除定时备份
+func (h *BackupHandler) DeleteSchedule(c *gin.Context) {
+	if err := h.backupService.DeleteSchedule(c.Param("clusterId"), c.Param("name"), actor(c), middleware.GetRequestID(c)); err != nil {
+		c.JSON(backupErrorStatus(err), model.ErrorResponse{
+			Success: false,
+			Message: "删除定时备份失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// backupErrorStatus 参数无效返回 400，集群不存在返回 404，其余为执行失败
+func backupErrorStatus(err error) int {
+	var notFound *service.ClusterNotFoundError
+	switch {
+	case errors.Is(err, service.ErrInvalidBackupRequest):
+		return http.StatusBadRequest
+	case errors.As(err, &notFound):
+		return http.StatusNotFound
+	}
+	return http.StatusInternalServerError
+}
+
+// compareStarted 按开始时间比较，尚未开始的排在最后
+func compareStarted(a, b *time.Time) int {
+	switch {
+	case a == nil && b == nil:
+		return 0
+	case a == nil:
+		return 1
+	case b == nil:
+		return -1
+	}
+	return a.Compare(*b)
+}
+
+// backupListSpec 备份支持按阶段、命名空间和定时备份过滤
+var backupListSpec = listSpec[model.Backup]{
+	filters: map[string]func(model.Backup, string) bool{
+		"phase":     func(b model.Backup, v string) bool { return strings.EqualFold(b.Phase, v) },
+		"namespace": func(b model.Backup, v string) bool { return slices.Contains(b.Namespaces, v) },
+		"schedule":  func(b model.Backup, v string) bool { return b.Schedule == v },
+	},
+	sorts: map[string]func(a, b model.Backup) int{
+		"name":      func(a, b model.Backup) int { return strings.Compare(a.Name, b.Name) },
+		"startedAt": func(a, b model.Backup) int { return compareStarted(a.StartedAt, b.StartedAt) },
+	},
+	defaultSort: "-startedAt",
+}
+
+// restoreListSpec 恢复支持按阶段和备份过滤
+var restoreListSpec = listSpec[model.Restore]{
+	filters: map[string]func(model.Restore, string) bool{
+		"phase":  func(r model.Restore, v string) bool { return strings.EqualFold(r.Phase, v) },
+		"backup": func(r model.Restore, v string) bool { return r.Backup == v },
+	},
+	sorts: map[string]func(a, b model.Restore) int{
+		"name":      func(a, b model.Restore) int { return strings.Compare(a.Name, b.Name) },
+		"startedAt": func(a, b model.Restore) int { return compareStarted(a.StartedAt, b.StartedAt) },
+	},
+	defaultSort: "-startedAt",
+}
+
+// scheduleListSpec 定时备份支持按命名空间过滤
+var scheduleListSpec = listSpec[model.BackupSchedule]{
+	filters: map[string]func(model.BackupSchedule, string) bool{
+		"namespace": func(s model.BackupSchedule, v string) bool { return slices.Contains(s.Namespaces, v) },
+	},
+	sorts: map[string]func(a, b model.BackupSchedule) int{
+		"name": func(a, b model.BackupSchedule) int { return strings.Compare(a.Name, b.Name) },
+	},
+	defaultSort: "name",
+}
@@ -0,0 +1,79 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/service"
+)
+
+type BenchmarkHandler struct {
+	benchmarkService *service.BenchmarkService
+}
+
+func NewBenchmarkHandler(benchmarkService *service.BenchmarkService) *BenchmarkHandler {
+	return &BenchmarkHandler{
+		benchmarkService: benchmarkService,
+	}
+}
+
+// List 返回全部基准记录
+func (h *BenchmarkHandler) List(c *gin.Context) {
+	runs, err := h.benchmarkService.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "读取基准记录失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, runs)
+}
+
+func (h *BenchmarkHandler) Get(c *gin.Context) {
+	run, err := h.benchmarkService.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Success: false,
+			Message: "基准记录不存在",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, run)
+}
+
+// Compare 对比 ids 参数（逗号分隔的任务 ID）指定的多次运行，第一个任务为基准
+func (h *BenchmarkHandler) Compare(c *gin.Context) {
+	var ids []string
+	for _, id := range strings.Split(c.Query("ids"), ",") {
+		if id = strings.TrimSpace(id); id != "" {
+			ids = append(ids, id)
+		}
+	}
+	report, err := h.benchmarkService.Compare(ids)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "生成对比报告失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+func (h *BenchmarkHandler) Delete(c *gin.Context) {
+	if err := h.benchmarkService.Delete(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Success: false,
+			Message: "删除基准记录失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,422 @@
+package handler
+
+import (
+	"bytes"
+	"encoding/csv"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/service"
+)
+
+type ClusterHandler struct {
+	clusterService *service.ClusterService
+}
+
+func NewClusterHandler(clusterService *service.ClusterService) *ClusterHandler {
+	return &ClusterHandler{
+		clusterService: clusterService,
+	}
+}
+
+// Adopt 纳管已有 k3s 集群
+func (h *ClusterHandler) Adopt(c *gin.Context) {
+	var req model.ClusterAdoptRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	cluster, err := h.clusterService.Adopt(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "纳管集群失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, cluster)
+}
+
+func (h *ClusterHandler) List(c *gin.Context) {
+	clusters, err := h.clusterService.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "读取集群列表失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	respondList(c, clusters, clusterListSpec)
+}
+
+// clusterListSpec 集群列表支持按来源、名称、版本和节点标签过滤
+var clusterListSpec = listSpec[*model.Cluster]{
+	filters: map[string]func(*model.Cluster, string) bool{
+		"source":  func(cl *model.Cluster, v string) bool { return cl.Source == v },
+		"name":    func(cl *model.Cluster, v string) bool { return containsFold(cl.Name, v) },
+		"version": func(cl *model.Cluster, v string) bool { return cl.Version == v },
+		"label": func(cl *model.Cluster, v string) bool {
+			for _, node := range cl.Nodes {
+				if matchLabel(node.Labels, v) {
+					return true
+				}
+			}
+			return false
+		},
+	},
+	sorts: map[string]func(a, b *model.Cluster) int{
+		"name":      func(a, b *model.Cluster) int { return strings.Compare(a.Name, b.Name) },
+		"version":   func(a, b *model.Cluster) int { return strings.Compare(a.Version, b.Version) },
+		"createdAt": func(a, b *model.Cluster) int { return a.CreatedAt.Compare(b.CreatedAt) },
+		"updatedAt": func(a, b *model.Cluster) int { return a.UpdatedAt.Compare(b.UpdatedAt) },
+	},
+	defaultSort: "name",
+}
+
+func (h *ClusterHandler) Get(c *gin.Context) {
+	cluster, err := h.clusterService.Get(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Success: false,
+			Message: "集群不存在",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, cluster)
+}
+
+func (h *ClusterHandler) Delete(c *gin.Context) {
+	if err := h.clusterService.Delete(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Success: false,
+			Message: "删除集群失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// Refresh 重新发现集群版本和节点，并读取实例命名空间的资源配额用量
+func (h *ClusterHandler) Refresh(c *gin.Context) {
+	cluster, err := h.clusterService.Refresh(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "刷新集群失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, cluster)
+}
+
+// Verify 验证受管集群的部署状态
+func (h *ClusterHandler) Verify(c *gin.Context) {
+	encryption, err := h.clusterService.Verify(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusOK, model.DeployResponse{
+			Success:           false,
+			Message:           err.Error(),
+			Step:              "verify",
+			SecretsEncryption: encryption,
+		})
+		return
+	}
+	c.JSON(http.StatusOK, model.DeployResponse{
+		Success:           true,
+		Message:           "集群验证通过",
+		Step:              "verify",
+		SecretsEncryption: encryption,
+	})
+}
+
+// SetDesired 设置集群期望状态
+func (h *ClusterHandler) SetDesired(c *gin.Context) {
+	var desired model.DesiredState
+	if err := c.ShouldBindJSON(&desired); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	cluster, err := h.clusterService.SetDesired(c.Param("id"), &desired)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "设置期望状态失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, cluster)
+}
+
+// SetDefaults 设置部署到该集群的请求未设置时使用的部署设置
+func (h *ClusterHandler) SetDefaults(c *gin.Context) {
+	var settings model.DeploySettings
+	if err := c.ShouldBindJSON(&settings); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	cluster, err := h.clusterService.SetDefaults(c.Param("id"), &settings)
+	if err != nil {
+		status := http.StatusBadRequest
+		var notFound *service.ClusterNotFoundError
+		if errors.As(err, &notFound) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, model.ErrorResponse{
+			Success: false,
+			Message: "设置集群默认值失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, cluster)
+}
+
+// Drift 检测配置漂移，reconcile=true 时同时修复
+func (h *ClusterHandler) Drift(c *gin.Context) {
+	report, err := h.clusterService.CheckDrift(c.Param("id"), c.Query("reconcile") == "true")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "漂移检测失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
+
+// Releases 列出集群上部署的 inSuite 实例
+func (h *ClusterHandler) Releases(c *gin.Context) {
+	releases, err := h.clusterService.Releases(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Success: false,
+			Message: "读取实例列表失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, releases)
+}
+
+// ObjectStore 返回集群内对象存储的地址和访问密钥
+func (h *ClusterHandler) ObjectStore(c *gin.Context) {
+	access, err := h.clusterService.ObjectStoreAccess(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Success: false,
+			Message: "读取对象存储失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, access)
+}
+
+// CISReport 检查 Master 节点并返回 CIS 合规报告，format=csv 时以 CSV 文件下载
+func (h *ClusterHandler) CISReport(c *gin.Context) {
+	report, err := h.clusterService.CISReport(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "生成 CIS 合规报告失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	if c.Query("format") != "csv" {
+		c.JSON(http.StatusOK, report)
+		return
+	}
+
+	var buf bytes.Buffer
+	w := csv.NewWriter(&buf)
+	w.Write([]string{"id", "title", "status", "actual", "remediation"})
+	for _, control := range report.Controls {
+		w.Write([]string{control.ID, control.Title, control.Status, control.Actual, control.Remediation})
+	}
+	w.Flush()
+
+	filename := fmt.Sprintf("cis-report-%s-%s.csv", report.ClusterID, report.GeneratedAt.Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "text/csv; charset=utf-8", buf.Bytes())
+}
+
+// DeleteRelease 删除集群上的 inSuite 实例及其命名空间
+func (h *ClusterHandler) DeleteRelease(c *gin.Context) {
+	if err := h.clusterService.DeleteRelease(c.Param("id"), c.Param("name")); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "删除实例失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// MergedKubeconfig 下载合并后的 kubeconfig，include、exclude 为逗号分隔的集群名称或 ID
+func (h *ClusterHandler) MergedKubeconfig(c *gin.Context) {
+	data, err := h.clusterService.MergedKubeconfig(queryList(c, "include"), queryList(c, "exclude"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "合并 kubeconfig 失败",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("kubeconfig-%s.yaml", time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/yaml", data)
+}
+
+// queryList 读取逗号分隔的查询参数，忽略空值
+func queryList(c *gin.Context, key string) []string {
+	var values []string
+	for _, value := range strings.Split(c.Query(key), ",") {
+		if value = strings.TrimSpace(value); value != "" {
+			values = append(values, value)
+		}
+	}
+	return values
+}
+
+// Workloads 返回集群工作负载清单，包含镜像版本、副本状态和所在节点
+func (h *ClusterHandler) Workloads(c *gin.Context) {
+	workloads, err := h.clusterService.Workloads(c.Param("clusterId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "读取工作负载失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	respondList(c, workloads, workloadListSpec)
+}
+
+// workloadListSpec 工作负载列表支持按命名空间、类型、就绪状态和节点过滤
+var workloadListSpec = listSpec[model.Workload]{
+	filters: map[string]func(model.Workload, string) bool{
+		"namespace": func(w model.Workload, v string) bool { return w.Namespace == v },
+		"kind":      func(w model.Workload, v string) bool { return strings.EqualFold(w.Kind, v) },
+		"ready":     func(w model.Workload, v string) bool { return fmt.Sprint(w.Ready) == v },
+		"node": func(w model.Workload, v string) bool {
+			for _, node := range w.Nodes {
+				if node == v {
+					return true
+				}
+			}
+			return false
+		},
+	},
+	sorts: map[string]func(a, b model.Workload) int{
+		"name": func(a, b model.Workload) int {
+			if c := strings.Compare(a.Namespace, b.Namespace); c != 0 {
+				return c
+			}
+			return strings.Compare(a.Name, b.Name)
+		},
+		"createdAt": func(a, b model.Workload) int { return a.CreatedAt.Compare(b.CreatedAt) },
+	},
+	defaultSort: "name",
+}
+
+// Metrics 返回节点和 Pod 的资源用量，namespace 参数只返回该命名空间的 Pod
+func (h *ClusterHandler) Metrics(c *gin.Context) {
+	metrics, err := h.clusterService.Metrics(c.Param("clusterId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "读取资源用量失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	if namespace := c.Query("namespace"); namespace != "" {
+		pods := metrics.Pods[:0]
+		for _, pod := range metrics.Pods {
+			if pod.Namespace == namespace {
+				pods = append(pods, pod)
+			}
+		}
+		metrics.Pods = pods
+	}
+	c.JSON(http.StatusOK, metrics)
+}
+
+// ReconcileLabels 将节点标签与污点对账为请求中的期望状态，dryRun=true 时只返回变更计划
+func (h *ClusterHandler) ReconcileLabels(c *gin.Context) {
+	var req model.NodeLabelsRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	changes, err := h.clusterService.ReconcileLabels(c.Param("clusterId"), &req, c.Query("dryRun") == "true")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "标签对账失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, changes)
+}
+
+// CheckNetwork 部署临时检查 Pod 验证集群 DNS 与服务网络，逐项返回结果；externalName 指定外部解析的域名
+func (h *ClusterHandler) CheckNetwork(c *gin.Context) {
+	externalName := c.Query("externalName")
+	if err := k3s.ValidateExternalName(externalName); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+	checks, err := h.clusterService.CheckNetwork(c.Param("clusterId"), externalName, c.Query("networkPolicy") == "true")
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "集群网络检查失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, checks)
+}
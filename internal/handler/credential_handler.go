@@ -0,0 +1,125 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/vault"
+	"k3s-deploy-backend/internal/service"
+)
+
+type CredentialHandler struct {
+	credentialService *service.CredentialService
+}
+
+func NewCredentialHandler(credentialService *service.CredentialService) *CredentialHandler {
+	return &CredentialHandler{
+		credentialService: credentialService,
+	}
+}
+
+func (h *CredentialHandler) DistributeKeys(c *gin.Context) {
+	var req model.KeyDistributionRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	results := h.credentialService.DistributeKeys(&req)
+	c.JSON(http.StatusOK, results)
+}
+
+func (h *CredentialHandler) Rotate(c *gin.Context) {
+	var req model.CredentialRotationRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	results := h.credentialService.RotateCredentials(&req)
+	c.JSON(http.StatusOK, results)
+}
+
+// SSHCA 返回 SSH CA 公钥，可用于手动配置节点的 TrustedUserCAKeys
+func (h *CredentialHandler) SSHCA(c *gin.Context) {
+	info, err := h.credentialService.SSHCAInfo()
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Success: false,
+			Message: "获取 SSH CA 失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, info)
+}
+
+// BootstrapSSHCA 让节点信任 SSH CA，并将对应凭据切换为证书认证
+func (h *CredentialHandler) BootstrapSSHCA(c *gin.Context) {
+	var req model.SSHCABootstrapRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	results, err := h.credentialService.BootstrapSSHCA(&req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrSSHCADisabled) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, model.ErrorResponse{
+			Success: false,
+			Message: "引导 SSH CA 失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+func (h *CredentialHandler) List(c *gin.Context) {
+	respondList(c, h.credentialService.List(), credentialListSpec)
+}
+
+// credentialListSpec 凭据列表支持按认证方式、主机和用户名过滤
+var credentialListSpec = listSpec[vault.Credential]{
+	filters: map[string]func(vault.Credential, string) bool{
+		"authType": func(cred vault.Credential, v string) bool { return cred.AuthType == v },
+		"host":     func(cred vault.Credential, v string) bool { return containsFold(cred.Host, v) },
+		"username": func(cred vault.Credential, v string) bool { return cred.Username == v },
+	},
+	sorts: map[string]func(a, b vault.Credential) int{
+		"name":      func(a, b vault.Credential) int { return strings.Compare(a.Name, b.Name) },
+		"host":      func(a, b vault.Credential) int { return strings.Compare(a.Host, b.Host) },
+		"createdAt": func(a, b vault.Credential) int { return a.CreatedAt.Compare(b.CreatedAt) },
+		"updatedAt": func(a, b vault.Credential) int { return a.UpdatedAt.Compare(b.UpdatedAt) },
+	},
+}
+
+func (h *CredentialHandler) Delete(c *gin.Context) {
+	if err := h.credentialService.Delete(c.Param("id")); err != nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Success: false,
+			Message: "删除凭据失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
@@ -0,0 +1,61 @@
+package handler
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/service"
+)
+
+type EventHandler struct {
+	eventService *service.EventService
+}
+
+func NewEventHandler(eventService *service.EventService) *EventHandler {
+	return &EventHandler{
+		eventService: eventService,
+	}
+}
+
+// List 返回集群最近的 Kubernetes 事件，refresh=true 时先从集群重新收集
+func (h *EventHandler) List(c *gin.Context) {
+	clusterID := c.Param("clusterId")
+
+	var (
+		record *model.ClusterEvents
+		err    error
+	)
+	if c.Query("refresh") == "true" {
+		record, err = h.eventService.CollectCluster(clusterID)
+	} else {
+		record, err = h.eventService.Events(clusterID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "读取集群事件失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	respondList(c, record.Events, eventListSpec)
+}
+
+// eventListSpec 事件列表支持按命名空间、类型、原因和关联对象过滤
+var eventListSpec = listSpec[model.ClusterEvent]{
+	filters: map[string]func(model.ClusterEvent, string) bool{
+		"namespace": func(e model.ClusterEvent, v string) bool { return e.Namespace == v },
+		"type":      func(e model.ClusterEvent, v string) bool { return strings.EqualFold(e.Type, v) },
+		"reason":    func(e model.ClusterEvent, v string) bool { return e.Reason == v },
+		"kind":      func(e model.ClusterEvent, v string) bool { return e.Kind == v },
+		"name":      func(e model.ClusterEvent, v string) bool { return e.Name == v },
+	},
+	sorts: map[string]func(a, b model.ClusterEvent) int{
+		"lastSeen":  func(a, b model.ClusterEvent) int { return a.LastSeen.Compare(b.LastSeen) },
+		"firstSeen": func(a, b model.ClusterEvent) int { return a.FirstSeen.Compare(b.FirstSeen) },
+		"count":     func(a, b model.ClusterEvent) int { return a.Count - b.Count },
+	},
+	defaultSort: "-lastSeen",
+}
@@ -0,0 +1,75 @@
+package handler
+
+import (
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/service"
+)
+
+type GitOpsHandler struct {
+	gitOpsService *service.GitOpsService
+}
+
+func NewGitOpsHandler(gitOpsService *service.GitOpsService) *GitOpsHandler {
+	return &GitOpsHandler{
+		gitOpsService: gitOpsService,
+	}
+}
+
+// enabled 未启用 GitOps 时返回 404
+func (h *GitOpsHandler) enabled(c *gin.Context) bool {
+	if h.gitOpsService == nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Success: false,
+			Message: "GitOps 模式未启用",
+		})
+		return false
+	}
+	return true
+}
+
+// Webhook 接收 Git 平台推送事件，校验签名后异步触发同步
+func (h *GitOpsHandler) Webhook(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+
+	body, err := io.ReadAll(c.Request.Body)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "读取请求失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	if !h.gitOpsService.VerifyWebhook(body, c.GetHeader("X-Hub-Signature-256"), c.GetHeader("X-Gitlab-Token")) {
+		c.JSON(http.StatusUnauthorized, model.ErrorResponse{
+			Success: false,
+			Message: "Webhook 签名无效",
+		})
+		return
+	}
+
+	go h.gitOpsService.Sync()
+	c.JSON(http.StatusAccepted, gin.H{"success": true, "message": "已触发同步"})
+}
+
+// Sync 手动同步并返回结果
+func (h *GitOpsHandler) Sync(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+	c.JSON(http.StatusOK, h.gitOpsService.Sync())
+}
+
+// Status 最近一次同步结果
+func (h *GitOpsHandler) Status(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+	c.JSON(http.StatusOK, h.gitOpsService.Status())
+}
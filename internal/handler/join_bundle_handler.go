@@ -0,0 +1,82 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/middleware"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/service"
+)
+
+type JoinBundleHandler struct {
+	joinBundleService *service.JoinBundleService
+}
+
+func NewJoinBundleHandler(joinBundleService *service.JoinBundleService) *JoinBundleHandler {
+	return &JoinBundleHandler{
+		joinBundleService: joinBundleService,
+	}
+}
+
+// Export 导出集群的加入信息包（包含 join token，仅管理员）
+func (h *JoinBundleHandler) Export(c *gin.Context) {
+	var req model.JoinBundleRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	export, err := h.joinBundleService.Export(&req, actor(c), middleware.GetRequestID(c))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "导出加入信息包失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, export)
+}
+
+// Import 校验加入信息包的签名、有效期和 server 的 CA，返回其中的加入信息
+func (h *JoinBundleHandler) Import(c *gin.Context) {
+	var req model.JoinBundleImportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	result, err := h.joinBundleService.Import(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "加入信息包校验失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// PublicKey 返回加入信息包的签名公钥，供其他工具校验
+func (h *JoinBundleHandler) PublicKey(c *gin.Context) {
+	pem, keyID, err := h.joinBundleService.PublicKey()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "读取签名公钥失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"keyId": keyID, "publicKey": pem})
+}
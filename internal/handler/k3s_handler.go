@@ -1,20 +1,24 @@
 package handler
 
 import (
+	"errors"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/middleware"
 	"k3s-deploy-backend/internal/model"
 	"k3s-deploy-backend/internal/service"
 )
 
 type K3sHandler struct {
 	deployService *service.DeployService
+	taskService   *service.TaskService
 }
 
-func NewK3sHandler(deployService *service.DeployService) *K3sHandler {
+func NewK3sHandler(deployService *service.DeployService, taskService *service.TaskService) *K3sHandler {
 	return &K3sHandler{
 		deployService: deployService,
+		taskService:   taskService,
 	}
 }
 
@@ -29,6 +33,196 @@ func (h *K3sHandler) Deploy(c *gin.Context) {
 		return
 	}
 
-	result := h.deployService.ExecuteStep(&req)
+	req.RequestID = middleware.GetRequestID(c)
+	result, err := h.taskService.ExecuteNow(&req)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrDeployBusy) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, model.ErrorResponse{
+			Success: false,
+			Message: "执行部署步骤失败",
+			Details: err.Error(),
+		})
+		return
+	}
 	c.JSON(http.StatusOK, result)
 }
+
+// PlanLabels 按部署模式生成节点标签方案，供用户确认后随部署请求提交
+func (h *K3sHandler) PlanLabels(c *gin.Context) {
+	var req model.LabelPlanRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	plan, err := h.deployService.PlanLabels(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "生成标签方案失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, plan)
+}
+
+// RoleTemplates 返回各部署模式的角色模板，部署请求未设置 roleAssignment 时按模板分配
+func (h *K3sHandler) RoleTemplates(c *gin.Context) {
+	c.JSON(http.StatusOK, h.deployService.RoleTemplates())
+}
+
+// SyncHosts 将 hosts 记录写入各节点 /etc/hosts 的受管区段，返回每个节点的结果
+func (h *K3sHandler) SyncHosts(c *gin.Context) {
+	h.hosts(c, func(req *model.HostsSyncRequest) ([]model.HostsSyncResult, error) {
+		return h.deployService.SyncHosts(req)
+	})
+}
+
+// RemoveHosts 删除各节点 /etc/hosts 中由部署维护的记录
+func (h *K3sHandler) RemoveHosts(c *gin.Context) {
+	h.hosts(c, func(req *model.HostsSyncRequest) ([]model.HostsSyncResult, error) {
+		return h.deployService.RemoveHosts(req.Nodes)
+	})
+}
+
+// Tune 在各节点应用内核参数与文件句柄限制，返回每个节点调优前的参数值
+func (h *K3sHandler) Tune(c *gin.Context) {
+	h.tuning(c, func(req *model.TuningRequest) ([]model.TuningResult, error) {
+		return h.deployService.TuneNodes(req)
+	})
+}
+
+// RevertTuning 恢复各节点调优前的内核参数并删除调优配置
+func (h *K3sHandler) RevertTuning(c *gin.Context) {
+	h.tuning(c, func(req *model.TuningRequest) ([]model.TuningResult, error) {
+		return h.deployService.RevertTuning(req.Nodes)
+	})
+}
+
+func (h *K3sHandler) tuning(c *gin.Context, apply func(*model.TuningRequest) ([]model.TuningResult, error)) {
+	var req model.TuningRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	for i := range req.Nodes {
+		req.Nodes[i].RequestID = middleware.GetRequestID(c)
+	}
+	results, err := apply(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "调优配置无效",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// RestartService 逐个重启各节点的 k3s / k3s-agent 服务，等待重新运行后再处理下一个节点
+func (h *K3sHandler) RestartService(c *gin.Context) {
+	h.nodeService(c, h.deployService.RestartServices)
+}
+
+// ApplyServiceConfig 逐个节点更新 config.yaml / registries.yaml 并重启服务，启动失败时恢复原配置
+func (h *K3sHandler) ApplyServiceConfig(c *gin.Context) {
+	h.nodeService(c, h.deployService.ApplyServiceConfig)
+}
+
+// ServiceLogs 查看各节点 k3s / k3s-agent 服务最近的日志
+func (h *K3sHandler) ServiceLogs(c *gin.Context) {
+	h.nodeService(c, h.deployService.ServiceLogs)
+}
+
+func (h *K3sHandler) nodeService(c *gin.Context, run func(*model.NodeServiceRequest) ([]model.NodeServiceResult, error)) {
+	var req model.NodeServiceRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	for i := range req.Nodes {
+		req.Nodes[i].RequestID = middleware.GetRequestID(c)
+	}
+	results, err := run(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "k3s 服务操作失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+// Uninstall 卸载各节点的 k3s，并按变更日志逆序撤销预检和节点准备对系统配置的修改
+func (h *K3sHandler) Uninstall(c *gin.Context) {
+	var req model.UninstallRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	for i := range req.Nodes {
+		req.Nodes[i].RequestID = middleware.GetRequestID(c)
+	}
+	results, err := h.deployService.Uninstall(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "卸载失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
+
+func (h *K3sHandler) hosts(c *gin.Context, sync func(*model.HostsSyncRequest) ([]model.HostsSyncResult, error)) {
+	var req model.HostsSyncRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	for i := range req.Nodes {
+		req.Nodes[i].RequestID = middleware.GetRequestID(c)
+	}
+	results, err := sync(&req)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "hosts 记录无效",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, results)
+}
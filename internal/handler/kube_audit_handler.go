@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/service"
+)
+
+type KubeAuditHandler struct {
+	kubeAuditService *service.KubeAuditService
+}
+
+func NewKubeAuditHandler(kubeAuditService *service.KubeAuditService) *KubeAuditHandler {
+	return &KubeAuditHandler{
+		kubeAuditService: kubeAuditService,
+	}
+}
+
+// List 返回集群最近的 API Server 审计记录，refresh=true 时先从 Master 重新收集
+func (h *KubeAuditHandler) List(c *gin.Context) {
+	clusterID := c.Param("clusterId")
+
+	var (
+		record *model.ClusterAuditLog
+		err    error
+	)
+	if c.Query("refresh") == "true" {
+		record, err = h.kubeAuditService.CollectCluster(clusterID)
+	} else {
+		record, err = h.kubeAuditService.Entries(clusterID)
+	}
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "读取审计日志失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	respondList(c, record.Entries, kubeAuditListSpec)
+}
+
+// kubeAuditListSpec 审计记录支持按用户、操作、资源、命名空间、对象名、状态码和授权结果过滤
+var kubeAuditListSpec = listSpec[model.KubeAuditEntry]{
+	filters: map[string]func(model.KubeAuditEntry, string) bool{
+		"user":      func(e model.KubeAuditEntry, v string) bool { return e.User == v },
+		"verb":      func(e model.KubeAuditEntry, v string) bool { return e.Verb == v },
+		"resource":  func(e model.KubeAuditEntry, v string) bool { return e.Resource == v },
+		"namespace": func(e model.KubeAuditEntry, v string) bool { return e.Namespace == v },
+		"name":      func(e model.KubeAuditEntry, v string) bool { return e.Name == v },
+		"code":      func(e model.KubeAuditEntry, v string) bool { return strconv.Itoa(e.Code) == v },
+		"decision":  func(e model.KubeAuditEntry, v string) bool { return strings.EqualFold(e.Decision, v) },
+	},
+	sorts: map[string]func(a, b model.KubeAuditEntry) int{
+		"time": func(a, b model.KubeAuditEntry) int { return a.Time.Compare(b.Time) },
+	},
+	defaultSort: "-time",
+}
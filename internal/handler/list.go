@@ -0,0 +1,141 @@
+package handler
+
+import (
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/model"
+)
+
+const (
+	defaultPageSize = 20
+	maxPageSize     = 200
+)
+
+// listSpec 列表接口支持的过滤与排序字段
+type listSpec[T any] struct {
+	// filters 查询参数名到匹配函数，多个值用逗号分隔，满足任一即匹配
+	filters map[string]func(item T, value string) bool
+	// sorts 排序字段到比较函数，sort 参数以 - 开头表示倒序
+	sorts map[string]func(a, b T) int
+	// defaultSort 未指定 sort 时使用的排序
+	defaultSort string
+}
+
+// respondList 按查询参数过滤、排序和分页后返回列表。
+// 响应头 X-Total-Count 为过滤后的总数；请求带 page 或 pageSize 时返回 model.PageResult，
+// 否则返回完整数组以兼容现有前端
+func respondList[T any](c *gin.Context, items []T, spec listSpec[T]) {
+	filtered := make([]T, 0, len(items))
+	for _, item := range items {
+		if spec.match(c, item) {
+			filtered = append(filtered, item)
+		}
+	}
+
+	sortBy := c.DefaultQuery("sort", spec.defaultSort)
+	if sortBy != "" {
+		desc := strings.HasPrefix(sortBy, "-")
+		compare, ok := spec.sorts[strings.TrimPrefix(sortBy, "-")]
+		if !ok {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Success: false,
+				Message: "请求参数无效",
+				Details: fmt.Sprintf("不支持的排序字段: %s（支持: %s）", sortBy, strings.Join(spec.sortKeys(), ", ")),
+			})
+			return
+		}
+		sort.SliceStable(filtered, func(i, j int) bool {
+			if desc {
+				return compare(filtered[j], filtered[i]) < 0
+			}
+			return compare(filtered[i], filtered[j]) < 0
+		})
+	}
+
+	total := len(filtered)
+	c.Header("X-Total-Count", strconv.Itoa(total))
+
+	_, hasPage := c.GetQuery("page")
+	_, hasPageSize := c.GetQuery("pageSize")
+	if !hasPage && !hasPageSize {
+		c.JSON(http.StatusOK, filtered)
+		return
+	}
+
+	page, err := strconv.Atoi(c.DefaultQuery("page", "1"))
+	if err != nil || page < 1 {
+		page = 1
+	}
+	pageSize, err := strconv.Atoi(c.DefaultQuery("pageSize", strconv.Itoa(defaultPageSize)))
+	if err != nil || pageSize < 1 {
+		pageSize = defaultPageSize
+	}
+	if pageSize > maxPageSize {
+		pageSize = maxPageSize
+	}
+
+	start := (page - 1) * pageSize
+	if start > total {
+		start = total
+	}
+	end := start + pageSize
+	if end > total {
+		end = total
+	}
+
+	c.JSON(http.StatusOK, model.PageResult{
+		Items:    filtered[start:end],
+		Total:    total,
+		Page:     page,
+		PageSize: pageSize,
+	})
+}
+
+func (s listSpec[T]) match(c *gin.Context, item T) bool {
+	for name, fn := range s.filters {
+		raw, ok := c.GetQuery(name)
+		if !ok || raw == "" {
+			continue
+		}
+		matched := false
+		for _, value := range strings.Split(raw, ",") {
+			if fn(item, strings.TrimSpace(value)) {
+				matched = true
+				break
+			}
+		}
+		if !matched {
+			return false
+		}
+	}
+	return true
+}
+
+func (s listSpec[T]) sortKeys() []string {
+	keys := make([]string, 0, len(s.sorts))
+	for key := range s.sorts {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// matchLabel 判断标签是否满足 key 或 key=value 形式的选择条件
+func matchLabel(labels map[string]string, selector string) bool {
+	key, value, hasValue := strings.Cut(selector, "=")
+	actual, ok := labels[key]
+	if !ok {
+		return false
+	}
+	return !hasValue || actual == value
+}
+
+// containsFold 不区分大小写的子串匹配
+func containsFold(s, substr string) bool {
+	return strings.Contains(strings.ToLower(s), strings.ToLower(substr))
+}
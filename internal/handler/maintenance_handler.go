@@ -0,0 +1,93 @@
+package handler
+
+import (
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/middleware"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/service"
+)
+
+type MaintenanceHandler struct {
+	maintenanceService *service.MaintenanceService
+}
+
+func NewMaintenanceHandler(maintenanceService *service.MaintenanceService) *MaintenanceHandler {
+	return &MaintenanceHandler{
+		maintenanceService: maintenanceService,
+	}
+}
+
+// Start cordon 节点并驱逐 Pod，返回驱逐结果；请求体可省略
+func (h *MaintenanceHandler) Start(c *gin.Context) {
+	var req model.MaintenanceRequest
+	if err := c.ShouldBindJSON(&req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	result, err := h.maintenanceService.Start(c.Param("clusterId"), c.Param("node"), &req, actor(c), middleware.GetRequestID(c))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "节点进入维护失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
+
+// End 结束节点维护，恢复调度
+func (h *MaintenanceHandler) End(c *gin.Context) {
+	if err := h.maintenanceService.End(c.Param("clusterId"), c.Param("node"), actor(c), middleware.GetRequestID(c)); err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "结束节点维护失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"success": true})
+}
+
+// Windows 返回集群的维护窗口
+func (h *MaintenanceHandler) Windows(c *gin.Context) {
+	windows, err := h.maintenanceService.Windows(c.Param("clusterId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "读取维护记录失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	respondList(c, windows, maintenanceListSpec)
+}
+
+// maintenanceListSpec 维护窗口支持按节点和是否结束过滤
+var maintenanceListSpec = listSpec[model.MaintenanceWindow]{
+	filters: map[string]func(model.MaintenanceWindow, string) bool{
+		"node":   func(w model.MaintenanceWindow, v string) bool { return w.Node == v },
+		"active": func(w model.MaintenanceWindow, v string) bool { return (w.EndedAt == nil) == (v == "true") },
+	},
+	sorts: map[string]func(a, b model.MaintenanceWindow) int{
+		"startedAt": func(a, b model.MaintenanceWindow) int { return a.StartedAt.Compare(b.StartedAt) },
+	},
+	defaultSort: "-startedAt",
+}
+
+// actor 当前登录用户，未启用认证时为空
+func actor(c *gin.Context) string {
+	if claims := middleware.GetClaims(c); claims != nil {
+		return claims.Subject
+	}
+	return ""
+}
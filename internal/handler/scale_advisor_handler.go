@@ -0,0 +1,53 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/service"
+)
+
+type ScaleAdvisorHandler struct {
+	scaleAdvisorService *service.ScaleAdvisorService
+}
+
+func NewScaleAdvisorHandler(scaleAdvisorService *service.ScaleAdvisorService) *ScaleAdvisorHandler {
+	return &ScaleAdvisorHandler{
+		scaleAdvisorService: scaleAdvisorService,
+	}
+}
+
+// Report 返回集群的节点与副本数伸缩建议，window 参数指定分析的时间窗口（如 30m、6h）
+func (h *ScaleAdvisorHandler) Report(c *gin.Context) {
+	var window time.Duration
+	if value := c.Query("window"); value != "" {
+		d, err := time.ParseDuration(value)
+		if err != nil || d <= 0 {
+			c.JSON(http.StatusBadRequest, model.ErrorResponse{
+				Success: false,
+				Message: "无效的时间窗口",
+				Details: value,
+			})
+			return
+		}
+		window = d
+	}
+
+	report, err := h.scaleAdvisorService.Report(c.Param("clusterId"), window)
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInsufficientSamples) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, model.ErrorResponse{
+			Success: false,
+			Message: "生成伸缩建议失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, report)
+}
@@ -0,0 +1,51 @@
+package handler
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/middleware"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/service"
+)
+
+type SecretsEncryptionHandler struct {
+	secretsEncryptionService *service.SecretsEncryptionService
+}
+
+func NewSecretsEncryptionHandler(secretsEncryptionService *service.SecretsEncryptionService) *SecretsEncryptionHandler {
+	return &SecretsEncryptionHandler{
+		secretsEncryptionService: secretsEncryptionService,
+	}
+}
+
+// Status 返回集群的 Secret 加密状态
+func (h *SecretsEncryptionHandler) Status(c *gin.Context) {
+	status, err := h.secretsEncryptionService.Status(c.Param("clusterId"))
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "读取 Secret 加密状态失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, status)
+}
+
+// Rotate 提交轮换 Secret 加密密钥的任务，返回 202 和任务
+func (h *SecretsEncryptionHandler) Rotate(c *gin.Context) {
+	task, err := h.secretsEncryptionService.Rotate(c.Param("clusterId"), actor(c), middleware.GetRequestID(c))
+	if err != nil {
+		if respondNodeConflict(c, "轮换 Secret 加密密钥失败", err) {
+			return
+		}
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "轮换 Secret 加密密钥失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusAccepted, task)
+}
@@ -0,0 +1,57 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/middleware"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/service"
+)
+
+type SecurityScanHandler struct {
+	securityScanService *service.SecurityScanService
+}
+
+func NewSecurityScanHandler(securityScanService *service.SecurityScanService) *SecurityScanHandler {
+	return &SecurityScanHandler{
+		securityScanService: securityScanService,
+	}
+}
+
+// Start 开始对集群运行 kube-bench 与 trivy 扫描，立即返回 202 和进行中的扫描记录
+func (h *SecurityScanHandler) Start(c *gin.Context) {
+	scan, err := h.securityScanService.Start(c.Param("clusterId"), actor(c), middleware.GetRequestID(c))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrSecurityScanRunning) {
+			status = http.StatusConflict
+		}
+		c.JSON(status, model.ErrorResponse{
+			Success: false,
+			Message: "启动安全扫描失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusAccepted, scan)
+}
+
+// Latest 返回集群最近一次安全扫描的结果
+func (h *SecurityScanHandler) Latest(c *gin.Context) {
+	scan, err := h.securityScanService.Latest(c.Param("clusterId"))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrNoSecurityScan) {
+			status = http.StatusNotFound
+		}
+		c.JSON(status, model.ErrorResponse{
+			Success: false,
+			Message: "读取安全扫描结果失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, scan)
+}
@@ -9,10 +9,10 @@ import (
 )
 
 type SSHHandler struct {
-	sshService *service.SSHService
+	sshService service.ConnectionTester
 }
 
-func NewSSHHandler(sshService *service.SSHService) *SSHHandler {
+func NewSSHHandler(sshService service.ConnectionTester) *SSHHandler {
 	return &SSHHandler{
 		sshService: sshService,
 	}
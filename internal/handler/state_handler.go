@@ -0,0 +1,96 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/service"
+)
+
+type StateHandler struct {
+	stateService *service.StateService
+}
+
+func NewStateHandler(stateService *service.StateService) *StateHandler {
+	return &StateHandler{
+		stateService: stateService,
+	}
+}
+
+// Export 下载后端状态导出包
+func (h *StateHandler) Export(c *gin.Context) {
+	var req model.StateExportRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	archive, err := h.stateService.Export(req.Passphrase)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "导出失败",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	filename := fmt.Sprintf("k3s-deploy-state-%s.tar.gz", time.Now().Format("20060102-150405"))
+	c.Header("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename))
+	c.Data(http.StatusOK, "application/gzip", archive)
+}
+
+// Import 上传导出包（multipart 字段 archive、passphrase、overwrite），请求体不超过 service.MaxStateArchiveSize
+func (h *StateHandler) Import(c *gin.Context) {
+	// 为 multipart 的其他字段和边界留出余量
+	c.Request.Body = http.MaxBytesReader(c.Writer, c.Request.Body, service.MaxStateArchiveSize+1<<20)
+	file, err := c.FormFile("archive")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) || err == nil && file.Size > service.MaxStateArchiveSize {
+		c.JSON(http.StatusRequestEntityTooLarge, model.ErrorResponse{
+			Success: false,
+			Message: "导出包过大",
+			Details: fmt.Sprintf("导出包不能超过 %d MiB", service.MaxStateArchiveSize>>20),
+		})
+		return
+	}
+	passphrase := c.PostForm("passphrase")
+	if err != nil || passphrase == "" {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: "需要上传 archive 文件并提供 passphrase",
+		})
+		return
+	}
+
+	archive, err := file.Open()
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "读取上传文件失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	defer archive.Close()
+
+	result, err := h.stateService.Import(archive, passphrase, c.PostForm("overwrite") == "true")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "导入失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, result)
+}
@@ -0,0 +1,281 @@
+package handler
+
+import (
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/middleware"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/progress"
+	"k3s-deploy-backend/internal/service"
+)
+
+type TaskHandler struct {
+	taskService *service.TaskService
+}
+
+func NewTaskHandler(taskService *service.TaskService) *TaskHandler {
+	return &TaskHandler{
+		taskService: taskService,
+	}
+}
+
+// Submit 提交异步部署任务，step 为 all 时按顺序执行全部步骤
+func (h *TaskHandler) Submit(c *gin.Context) {
+	var req model.DeployRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	req.RequestID = middleware.GetRequestID(c)
+	task, err := h.taskService.Submit(&req)
+	if err != nil {
+		if respondNodeConflict(c, "提交任务失败", err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "提交任务失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusAccepted, task)
+}
+
+// Rerun 以原任务的请求和生效设置提交新任务
+func (h *TaskHandler) Rerun(c *gin.Context) {
+	task, err := h.taskService.Rerun(c.Param("id"), middleware.GetRequestID(c))
+	if err != nil {
+		if respondNodeConflict(c, "重新执行任务失败", err) {
+			return
+		}
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "重新执行任务失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusAccepted, task)
+}
+
+// Takeover 强制提交任务（仅管理员）：节点正被其他集群的任务使用时接管这些节点，执行中的冲突任务在当前步骤结束后停止
+func (h *TaskHandler) Takeover(c *gin.Context) {
+	var req model.DeployRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	req.RequestID = middleware.GetRequestID(c)
+	task, err := h.taskService.Takeover(&req)
+	if err != nil {
+		status := http.StatusBadRequest
+		switch {
+		case errors.Is(err, service.ErrNodeLockHeld):
+			status = http.StatusConflict
+		case errors.Is(err, service.ErrTakeoverFailed):
+			status = http.StatusInternalServerError
+		}
+		c.JSON(status, model.ErrorResponse{
+			Success: false,
+			Message: "强制提交任务失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusAccepted, task)
+}
+
+// respondNodeConflict 节点冲突时返回 409 和冲突的任务列表
+func respondNodeConflict(c *gin.Context, message string, err error) bool {
+	var conflict *service.NodeConflictError
+	if !errors.As(err, &conflict) {
+		return false
+	}
+	c.JSON(http.StatusConflict, model.NodeConflictResponse{
+		ErrorResponse: model.ErrorResponse{
+			Success: false,
+			Message: message,
+			Details: err.Error() + "。确认冲突任务可以中止后，管理员可通过 POST /api/tasks/takeover 强制提交",
+		},
+		Conflicts: conflict.Conflicts,
+	})
+	return true
+}
+
+// Get 返回任务状态和执行日志。since 为上次收到的最大日志序号或 RFC3339 时间，level 为 info、warn 或 error，
+// 只返回不低于该级别的日志，用于前端增量轮询
+func (h *TaskHandler) Get(c *gin.Context) {
+	filter, err := logFilter(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数无效",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	task, err := h.taskService.Get(c.Param("id"), filter)
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Success: false,
+			Message: "任务不存在",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, task)
+}
+
+// Events 以 Server-Sent Events 推送任务的进度事件（任务、步骤、节点阶段、预检和安装进度），任务结束后关闭连接。
+// 任务已结束时只推送一条 task.finished；事件只在执行任务的副本上产生，连接到其他副本时只能收到任务结束通知
+func (h *TaskHandler) Events(c *gin.Context) {
+	id := c.Param("id")
+	// 先订阅再读取任务，避免读取后、订阅前发布的事件丢失。任务执行时以任务 ID 作为请求 ID
+	sub := progress.Default.Subscribe(256, func(e model.ProgressEvent) bool {
+		return e.TaskID == id || e.RequestID == id
+	})
+	defer sub.Close()
+
+	task, err := h.taskService.Get(id, service.LogFilter{Level: model.LogError})
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Success: false,
+			Message: "任务不存在",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	// nginx 默认缓冲响应，关闭后事件才能实时到达
+	c.Header("X-Accel-Buffering", "no")
+	if task.FinishedAt != nil {
+		c.SSEvent(model.ProgressTaskFinished, finishedEvent(task))
+		return
+	}
+	c.Writer.Flush()
+
+	keepalive := time.NewTicker(15 * time.Second)
+	defer keepalive.Stop()
+	for {
+		select {
+		case e := <-sub.Events():
+			c.SSEvent(e.Type, e)
+			c.Writer.Flush()
+			if e.Type == model.ProgressTaskFinished {
+				return
+			}
+		case <-keepalive.C:
+			// 任务可能由其他副本执行或已被清理，定期检查状态
+			task, err := h.taskService.Get(id, service.LogFilter{Level: model.LogError})
+			if err != nil {
+				return
+			}
+			if task.FinishedAt != nil {
+				c.SSEvent(model.ProgressTaskFinished, finishedEvent(task))
+				return
+			}
+			fmt.Fprint(c.Writer, ": keepalive\n\n")
+			c.Writer.Flush()
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
+
+// finishedEvent 按任务状态构造结束事件，用于连接时任务已经结束的情况
+func finishedEvent(task *model.Task) model.ProgressEvent {
+	e := model.ProgressEvent{
+		Type:      model.ProgressTaskFinished,
+		Time:      *task.FinishedAt,
+		RequestID: task.ID,
+		TaskID:    task.ID,
+		Level:     model.LogInfo,
+		Message:   task.Message,
+	}
+	if task.Status == model.TaskFailed {
+		e.Level = model.LogError
+	}
+	return e
+}
+
+func logFilter(c *gin.Context) (service.LogFilter, error) {
+	var filter service.LogFilter
+	if since := c.Query("since"); since != "" {
+		if seq, err := strconv.Atoi(since); err == nil && seq >= 0 {
+			filter.Since = seq
+		} else if t, err := time.Parse(time.RFC3339Nano, since); err == nil {
+			filter.SinceTime = t
+		} else {
+			return filter, fmt.Errorf("since 必须是日志序号或 RFC3339 时间: %s", since)
+		}
+	}
+	if level := c.Query("level"); level != "" {
+		if model.LogLevelRank(level) < 0 {
+			return filter, fmt.Errorf("level 必须是 info、warn 或 error: %s", level)
+		}
+		filter.Level = level
+	}
+	return filter, nil
+}
+
+// Transcript 返回任务执行期间录制的节点命令（已脱敏），可保存为文件用于回放
+func (h *TaskHandler) Transcript(c *gin.Context) {
+	entries, err := h.taskService.Transcript(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusNotFound, model.ErrorResponse{
+			Success: false,
+			Message: "读取任务命令录制失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, entries)
+}
+
+func (h *TaskHandler) List(c *gin.Context) {
+	tasks, err := h.taskService.List()
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, model.ErrorResponse{
+			Success: false,
+			Message: "读取任务列表失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	respondList(c, tasks, taskListSpec)
+}
+
+// taskListSpec 任务列表支持按状态、步骤、集群和执行副本过滤
+var taskListSpec = listSpec[*model.Task]{
+	filters: map[string]func(*model.Task, string) bool{
+		"status":     func(t *model.Task, v string) bool { return t.Status == v },
+		"step":       func(t *model.Task, v string) bool { return t.Step == v },
+		"clusterKey": func(t *model.Task, v string) bool { return t.ClusterKey == v },
+		"owner":      func(t *model.Task, v string) bool { return t.Owner == v },
+	},
+	sorts: map[string]func(a, b *model.Task) int{
+		"createdAt": func(a, b *model.Task) int { return a.CreatedAt.Compare(b.CreatedAt) },
+		"status":    func(a, b *model.Task) int { return strings.Compare(a.Status, b.Status) },
+		"step":      func(a, b *model.Task) int { return strings.Compare(a.Step, b.Step) },
+	},
+	defaultSort: "-createdAt",
+}
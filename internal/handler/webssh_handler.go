@@ -0,0 +1,271 @@
+package handler
+
+import (
+	"errors"
+	"net/http"
+	"net/url"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/gorilla/websocket"
+	"k3s-deploy-backend/internal/middleware"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/service"
+)
+
+// webSSHProtocol 终端 WebSocket 的子协议，浏览器同时以 bearer.<令牌> 子协议携带会话令牌时，服务端选择该协议应答
+const webSSHProtocol = "webssh"
+
+// webSSHPingInterval 向浏览器发送 ping 的周期，浏览器自动应答 pong，长时间没有应答的会话由后台清理关闭
+const webSSHPingInterval = 30 * time.Second
+
+type WebSSHHandler struct {
+	webSSHService *service.WebSSHService
+	// verifier 识别兑换票据的用户，nil 表示未启用认证，此时不提供终端
+	verifier middleware.Verifier
+	// allowedOrigins 除后端自身地址外允许发起终端连接的页面来源（cors_origins）
+	allowedOrigins []string
+}
+
+func NewWebSSHHandler(webSSHService *service.WebSSHService, verifier middleware.Verifier, allowedOrigins []string) *WebSSHHandler {
+	return &WebSSHHandler{
+		webSSHService:  webSSHService,
+		verifier:       verifier,
+		allowedOrigins: allowedOrigins,
+	}
+}
+
+// webSSHMessage 浏览器发送的终端消息：input 为键盘输入，resize 为窗口大小变化
+type webSSHMessage struct {
+	Type string `json:"type"`
+	Data string `json:"data"`
+	Cols int    `json:"cols"`
+	Rows int    `json:"rows"`
+}
+
+// enabled 未启用认证时终端可被任何能访问后端的人打开，直接拒绝
+func (h *WebSSHHandler) enabled(c *gin.Context) bool {
+	if h.verifier == nil {
+		c.JSON(http.StatusForbidden, model.ErrorResponse{
+			Success: false,
+			Message: "WebSSH 终端不可用",
+			Details: "未启用认证（auth.enabled）时不提供 WebSSH 终端",
+		})
+		return false
+	}
+	return true
+}
+
+// CreateTicket 签发一次性连接票据
+func (h *WebSSHHandler) CreateTicket(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+	var req model.WebSSHTicketRequest
+	if err := c.ShouldBindJSON(&req); err != nil {
+		c.JSON(http.StatusBadRequest, model.ErrorResponse{
+			Success: false,
+			Message: "请求参数错误",
+			Details: err.Error(),
+		})
+		return
+	}
+
+	subject := ""
+	if claims := middleware.GetClaims(c); claims != nil {
+		subject = claims.Subject
+	}
+
+	resp, err := h.webSSHService.IssueTicket(&req, subject, middleware.GetRequestID(c))
+	if err != nil {
+		status := http.StatusInternalServerError
+		if errors.Is(err, service.ErrInvalidTarget) {
+			status = http.StatusBadRequest
+		}
+		c.JSON(status, model.ErrorResponse{
+			Success: false,
+			Message: "签发连接票据失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+// Connect 使用票据建立 WebSocket 终端，浏览器发送 JSON 消息，服务端以二进制帧返回终端输出
+func (h *WebSSHHandler) Connect(c *gin.Context) {
+	if !h.enabled(c) {
+		return
+	}
+	// 先校验来源和用户再兑换票据，跨站页面或其他用户的请求不会消耗票据
+	if !h.originAllowed(c) {
+		c.JSON(http.StatusForbidden, model.ErrorResponse{
+			Success: false,
+			Message: "建立终端连接失败",
+			Details: "不允许从 " + c.GetHeader("Origin") + " 发起终端连接",
+		})
+		return
+	}
+	claims, err := middleware.Identify(h.verifier, c)
+	if err == nil && claims == nil {
+		err = errors.New("未登录")
+	}
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, model.ErrorResponse{
+			Success: false,
+			Message: "建立终端连接失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	subject := ""
+	if claims != nil {
+		subject = claims.Subject
+	}
+
+	client, ticket, err := h.webSSHService.Redeem(c.Query("ticket"), subject)
+	if err != nil {
+		status := http.StatusServiceUnavailable
+		switch {
+		case errors.Is(err, service.ErrInvalidTicket):
+			status = http.StatusUnauthorized
+		case errors.Is(err, service.ErrTicketSubject):
+			status = http.StatusForbidden
+		}
+		c.JSON(status, model.ErrorResponse{
+			Success: false,
+			Message: "建立终端连接失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	defer client.Close()
+
+	cols, _ := strconv.Atoi(c.DefaultQuery("cols", "120"))
+	rows, _ := strconv.Atoi(c.DefaultQuery("rows", "32"))
+	shell, err := client.OpenShell("xterm-256color", cols, rows)
+	if err != nil {
+		c.JSON(http.StatusServiceUnavailable, model.ErrorResponse{
+			Success: false,
+			Message: "启动终端失败",
+			Details: err.Error(),
+		})
+		return
+	}
+	defer shell.Close()
+
+	upgrader := websocket.Upgrader{
+		ReadBufferSize:  8 * 1024,
+		WriteBufferSize: 32 * 1024,
+		Subprotocols:    []string{webSSHProtocol},
+		CheckOrigin:     func(*http.Request) bool { return h.originAllowed(c) },
+	}
+	ws, err := upgrader.Upgrade(c.Writer, c.Request, nil)
+	if err != nil {
+		return
+	}
+	defer ws.Close()
+
+	session := h.webSSHService.Track(ticket, func() {
+		ws.Close()
+		shell.Close()
+		client.Close()
+	})
+	defer session.Close()
+	ws.SetPongHandler(func(string) error {
+		session.Touch()
+		return nil
+	})
+
+	var writeMu sync.Mutex
+	done := make(chan struct{})
+
+	// 定期 ping，浏览器仍在时以 pong 应答
+	go func() {
+		ticker := time.NewTicker(webSSHPingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-done:
+				return
+			case <-ticker.C:
+				writeMu.Lock()
+				err := ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second))
+				writeMu.Unlock()
+				if err != nil {
+					return
+				}
+			}
+		}
+	}()
+
+	// 终端输出 -> 浏览器
+	go func() {
+		defer close(done)
+		buf := make([]byte, 32*1024)
+		for {
+			n, err := shell.Stdout.Read(buf)
+			if n > 0 {
+				writeMu.Lock()
+				werr := ws.WriteMessage(websocket.BinaryMessage, buf[:n])
+				writeMu.Unlock()
+				if werr != nil {
+					return
+				}
+			}
+			if err != nil {
+				return
+			}
+		}
+	}()
+
+	// 浏览器输入 -> 终端
+	go func() {
+		for {
+			var msg webSSHMessage
+			if err := ws.ReadJSON(&msg); err != nil {
+				shell.Close()
+				return
+			}
+			session.Touch()
+			switch msg.Type {
+			case "input":
+				if _, err := shell.Stdin.Write([]byte(msg.Data)); err != nil {
+					shell.Close()
+					return
+				}
+			case "resize":
+				if msg.Cols > 0 && msg.Rows > 0 {
+					shell.Resize(msg.Cols, msg.Rows)
+				}
+			}
+		}
+	}()
+
+	<-done
+	writeMu.Lock()
+	ws.WriteMessage(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.CloseNormalClosure, "会话已结束"))
+	writeMu.Unlock()
+}
+
+// originAllowed 浏览器发起的连接需来自后端自身的外部地址或 cors_origins 中的页面；
+// 非浏览器客户端不发送 Origin，由票据和会话令牌授权
+func (h *WebSSHHandler) originAllowed(c *gin.Context) bool {
+	origin := c.GetHeader("Origin")
+	if origin == "" {
+		return true
+	}
+	if slices.Contains(h.allowedOrigins, origin) {
+		return true
+	}
+	u, err := url.Parse(origin)
+	if err != nil {
+		return false
+	}
+	self, err := url.Parse(middleware.ExternalURL(c, false, "/"))
+	return err == nil && strings.EqualFold(u.Scheme, self.Scheme) && strings.EqualFold(u.Host, self.Host)
+}
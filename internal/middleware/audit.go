@@ -0,0 +1,66 @@
+package middleware
+
+import (
+	"regexp"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sirupsen/logrus"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/pkg/utils"
+)
+
+// RequestIDHeader 请求 ID 的请求/响应头
+const RequestIDHeader = "X-Request-ID"
+
+const requestIDKey = "requestID"
+
+// validRequestID 调用方传入的请求 ID 会出现在日志和节点工作目录名中，只接受安全字符
+var validRequestID = regexp.MustCompile(`^[A-Za-z0-9._-]{1,64}$`)
+
+// Audit 为每个请求分配请求 ID（沿用调用方传入的 X-Request-ID），写入响应头，
+// 并在请求结束时记录带请求 ID 的访问日志
+func Audit(log *logger.Logger) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		requestID := c.GetHeader(RequestIDHeader)
+		if !validRequestID.MatchString(requestID) {
+			requestID, _ = utils.GenerateID("req")
+		}
+		c.Set(requestIDKey, requestID)
+		c.Header(RequestIDHeader, requestID)
+
+		start := time.Now()
+		c.Next()
+
+		status := c.Writer.Status()
+		entry := log.WithFields(logrus.Fields{
+			"type":      "access",
+			"requestId": requestID,
+			"method":    c.Request.Method,
+			"path":      c.Request.URL.Path,
+			"status":    status,
+			"latency":   time.Since(start).String(),
+			"clientIp":  c.ClientIP(),
+		})
+		if claims := GetClaims(c); claims != nil {
+			entry = entry.WithField("user", claims.Subject)
+		}
+		if len(c.Errors) > 0 {
+			entry = entry.WithField("errors", c.Errors.String())
+		}
+
+		switch {
+		case status >= 500:
+			entry.Error("请求处理失败")
+		case status >= 400:
+			entry.Warn("请求被拒绝")
+		default:
+			entry.Info("请求完成")
+		}
+	}
+}
+
+// GetRequestID 返回当前请求的请求 ID
+func GetRequestID(c *gin.Context) string {
+	return c.GetString(requestIDKey)
+}
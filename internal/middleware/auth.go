@@ -0,0 +1,107 @@
+package middleware
+
+import (
+	"crypto/x509"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/auth"
+)
+
+const claimsKey = "authClaims"
+
+// Verifier 校验会话令牌或客户端证书
+type Verifier interface {
+	Verify(token string) (*auth.Claims, error)
+	VerifyCertificate(cert *x509.Certificate) (*auth.Claims, error)
+}
+
+// Authenticate 要求请求携带有效的 Bearer 会话令牌或 TLS 客户端证书，并按角色检查接口权限。
+// verifier 为 nil 表示未启用认证，所有请求直接放行
+func Authenticate(verifier Verifier) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if verifier == nil {
+			c.Next()
+			return
+		}
+
+		var claims *auth.Claims
+		var err error
+		token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer ")
+		switch {
+		case ok && token != "":
+			claims, err = verifier.Verify(token)
+		case c.Request.TLS != nil && len(c.Request.TLS.VerifiedChains) > 0:
+			claims, err = verifier.VerifyCertificate(c.Request.TLS.VerifiedChains[0][0])
+		default:
+			c.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{
+				Success: false,
+				Message: "未登录",
+			})
+			return
+		}
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{
+				Success: false,
+				Message: "登录已失效，请重新登录",
+				Details: err.Error(),
+			})
+			return
+		}
+		c.Set(claimsKey, claims)
+
+		path := strings.TrimPrefix(c.Request.URL.Path, BasePath(c))
+		path = strings.TrimPrefix(strings.TrimPrefix(path, "/api"), "/v1")
+		method := c.Request.Method
+		// refresh=true 的查询会通过 SSH 到节点重新收集，与写操作一样需要 operator 角色
+		if c.Query("refresh") == "true" {
+			method = http.MethodPost
+		}
+		if !auth.Allowed(claims.Roles, method, path) {
+			c.AbortWithStatusJSON(http.StatusForbidden, model.ErrorResponse{
+				Success: false,
+				Message: "没有权限执行该操作",
+				Details: "当前角色: " + strings.Join(claims.Roles, ","),
+			})
+			return
+		}
+		c.Next()
+	}
+}
+
+// WebSocketTokenProtocol 浏览器无法为 WebSocket 设置 Authorization 头，
+// 以 Sec-WebSocket-Protocol 中 "bearer.<会话令牌>" 形式的子协议携带令牌
+const WebSocketTokenProtocol = "bearer."
+
+// Identify 识别公开接口（WebSocket 终端）请求的登录用户，依次使用 Authorization 头、
+// WebSocket 子协议中的令牌和 TLS 客户端证书，不检查接口权限。
+// verifier 为 nil（未启用认证）或请求未携带凭据时返回 nil
+func Identify(verifier Verifier, c *gin.Context) (*auth.Claims, error) {
+	if verifier == nil {
+		return nil, nil
+	}
+	if token, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && token != "" {
+		return verifier.Verify(token)
+	}
+	for _, header := range c.Request.Header.Values("Sec-WebSocket-Protocol") {
+		for _, protocol := range strings.Split(header, ",") {
+			if token, ok := strings.CutPrefix(strings.TrimSpace(protocol), WebSocketTokenProtocol); ok && token != "" {
+				return verifier.Verify(token)
+			}
+		}
+	}
+	if c.Request.TLS != nil && len(c.Request.TLS.VerifiedChains) > 0 {
+		return verifier.VerifyCertificate(c.Request.TLS.VerifiedChains[0][0])
+	}
+	return nil, nil
+}
+
+// GetClaims 返回当前登录用户，未启用认证时为 nil
+func GetClaims(c *gin.Context) *auth.Claims {
+	if v, ok := c.Get(claimsKey); ok {
+		return v.(*auth.Claims)
+	}
+	return nil
+}
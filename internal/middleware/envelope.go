@@ -0,0 +1,188 @@
+package middleware
+
+import (
+	"bytes"
+	"encoding/json"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/model"
+)
+
+// 统一错误码
+const (
+	CodeBadRequest      = "bad_request"
+	CodeUnauthorized    = "unauthorized"
+	CodeForbidden       = "forbidden"
+	CodeNotFound        = "not_found"
+	CodeConflict        = "conflict"
+	CodeOperationFailed = "operation_failed"
+	CodeUnavailable     = "unavailable"
+	CodeInternal        = "internal_error"
+)
+
+// Envelope 将处理器的 JSON 响应统一包装为 {success, data, error{code,message}}，
+// 非 JSON 响应（文件下载、脚本、WebSocket）原样透传。
+// 处理器本身保持旧的响应格式，/api 下的旧路由不经过该中间件，供现有前端继续使用
+func Envelope() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		w := &envelopeWriter{ResponseWriter: c.Writer, status: http.StatusOK}
+		c.Writer = w
+		c.Next()
+		c.Writer = w.ResponseWriter
+
+		if !w.buffering {
+			if !w.ResponseWriter.Written() && w.wroteHeader {
+				w.ResponseWriter.WriteHeader(w.status)
+				w.ResponseWriter.WriteHeaderNow()
+			}
+			return
+		}
+
+		body, err := json.Marshal(wrap(w.status, w.body.Bytes()))
+		if err != nil {
+			body = w.body.Bytes()
+		}
+		w.ResponseWriter.Header().Del("Content-Length")
+		w.ResponseWriter.WriteHeader(w.status)
+		w.ResponseWriter.Write(body)
+	}
+}
+
+// wrap 将旧格式响应转换为统一信封
+func wrap(status int, raw []byte) model.APIResponse {
+	var payload interface{}
+	if len(bytes.TrimSpace(raw)) > 0 {
+		if err := json.Unmarshal(raw, &payload); err != nil {
+			payload = string(raw)
+		}
+	}
+
+	obj, isObject := payload.(map[string]interface{})
+	failed := status >= http.StatusBadRequest
+	if isObject {
+		if success, ok := obj["success"].(bool); ok && !success {
+			failed = true
+		}
+	}
+
+	if failed {
+		apiErr := &model.APIError{Code: errorCode(status)}
+		if isObject {
+			apiErr.Message, _ = obj["message"].(string)
+			apiErr.Details, _ = obj["details"].(string)
+		}
+		if apiErr.Message == "" {
+			apiErr.Message = http.StatusText(status)
+		}
+		// 业务失败（如部署步骤失败）保留完整结果，便于查看失败步骤与分类
+		var data interface{}
+		if status < http.StatusBadRequest {
+			data = payload
+		}
+		// 冲突保留除错误信息外的字段（如节点冲突的任务列表）
+		if status == http.StatusConflict && isObject {
+			for _, key := range []string{"success", "message", "details"} {
+				delete(obj, key)
+			}
+			if len(obj) > 0 {
+				data = obj
+			}
+		}
+		return model.APIResponse{Success: false, Data: data, Error: apiErr}
+	}
+
+	if isObject {
+		delete(obj, "success")
+		if len(obj) == 0 {
+			return model.APIResponse{Success: true}
+		}
+	}
+	return model.APIResponse{Success: true, Data: payload}
+}
+
+func errorCode(status int) string {
+	switch {
+	case status < http.StatusBadRequest:
+		return CodeOperationFailed
+	case status == http.StatusUnauthorized:
+		return CodeUnauthorized
+	case status == http.StatusForbidden:
+		return CodeForbidden
+	case status == http.StatusNotFound:
+		return CodeNotFound
+	case status == http.StatusConflict:
+		return CodeConflict
+	case status == http.StatusServiceUnavailable:
+		return CodeUnavailable
+	case status >= http.StatusInternalServerError:
+		return CodeInternal
+	default:
+		return CodeBadRequest
+	}
+}
+
+// envelopeWriter 在首次写入时根据 Content-Type 决定缓冲 JSON 还是透传
+type envelopeWriter struct {
+	gin.ResponseWriter
+	body        bytes.Buffer
+	status      int
+	wroteHeader bool
+	decided     bool
+	buffering   bool
+}
+
+func (w *envelopeWriter) decide() {
+	if w.decided {
+		return
+	}
+	w.decided = true
+	w.buffering = strings.HasPrefix(w.Header().Get("Content-Type"), "application/json")
+	if !w.buffering {
+		w.ResponseWriter.WriteHeader(w.status)
+	}
+}
+
+func (w *envelopeWriter) WriteHeader(code int) {
+	if code > 0 {
+		w.status = code
+		w.wroteHeader = true
+	}
+	if w.decided && !w.buffering {
+		w.ResponseWriter.WriteHeader(code)
+	}
+}
+
+func (w *envelopeWriter) WriteHeaderNow() {
+	if w.decided && !w.buffering {
+		w.ResponseWriter.WriteHeaderNow()
+	}
+}
+
+func (w *envelopeWriter) Write(data []byte) (int, error) {
+	w.decide()
+	if w.buffering {
+		return w.body.Write(data)
+	}
+	return w.ResponseWriter.Write(data)
+}
+
+func (w *envelopeWriter) WriteString(s string) (int, error) {
+	return w.Write([]byte(s))
+}
+
+func (w *envelopeWriter) Status() int {
+	return w.status
+}
+
+func (w *envelopeWriter) Written() bool {
+	return w.decided || w.ResponseWriter.Written()
+}
+
+func (w *envelopeWriter) Flush() {
+	w.decide()
+	if !w.buffering {
+		w.ResponseWriter.Flush()
+	}
+}
@@ -0,0 +1,73 @@
+package middleware
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"net/http"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/model"
+)
+
+// localTokenCookie 浏览器通过带令牌的地址登录后保存令牌的 Cookie
+const localTokenCookie = "k3s_deploy_token"
+
+// NewLocalToken 生成单机模式的访问令牌
+func NewLocalToken() (string, error) {
+	buf := make([]byte, 24)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成访问令牌失败: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
+
+// probePaths 健康检查和探针路径，单机模式下无需令牌
+var probePaths = map[string]bool{"/health": true, "/healthz": true, "/readyz": true}
+
+// LocalToken 单机模式的访问控制：所有请求（健康检查和探针除外）必须携带启动时打印的令牌，
+// 可以是 ?token=（浏览器打开启动地址时使用，写入 Cookie 后跳转到不带令牌的地址）、
+// Cookie 或 Authorization: Bearer <令牌>。同一台机器上的其他用户和网页无法访问后端
+func LocalToken(token string) gin.HandlerFunc {
+	matches := func(value string) bool {
+		return value != "" && subtle.ConstantTimeCompare([]byte(value), []byte(token)) == 1
+	}
+
+	return func(c *gin.Context) {
+		if probePaths[strings.TrimPrefix(c.Request.URL.Path, BasePath(c))] {
+			c.Next()
+			return
+		}
+
+		if matches(c.Query("token")) {
+			c.SetSameSite(http.SameSiteStrictMode)
+			c.SetCookie(localTokenCookie, token, 0, BasePath(c)+"/", "", ExternalSecure(c), true)
+			if c.Request.Method == http.MethodGet && c.GetHeader("Upgrade") == "" {
+				query := c.Request.URL.Query()
+				query.Del("token")
+				target := *c.Request.URL
+				target.RawQuery = query.Encode()
+				c.Redirect(http.StatusFound, target.RequestURI())
+				c.Abort()
+				return
+			}
+			c.Next()
+			return
+		}
+		if cookie, err := c.Cookie(localTokenCookie); err == nil && matches(cookie) {
+			c.Next()
+			return
+		}
+		if bearer, ok := strings.CutPrefix(c.GetHeader("Authorization"), "Bearer "); ok && matches(bearer) {
+			c.Next()
+			return
+		}
+
+		c.AbortWithStatusJSON(http.StatusUnauthorized, model.ErrorResponse{
+			Success: false,
+			Message: "访问令牌无效，请使用启动时输出的地址打开",
+		})
+	}
+}
@@ -0,0 +1,103 @@
+package middleware
+
+import (
+	"net"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+const externalKey = "external"
+
+// external 客户端访问后端使用的协议、主机和路径前缀
+type external struct {
+	scheme   string
+	host     string
+	basePath string
+}
+
+// External 记录请求的外部访问地址，用于生成返回给客户端的 URL（如 Agent 引导脚本中的下载地址）。
+// 后端部署在 nginx/Ingress 之后时，只有直接连接来自 trustedProxies（IP 或 CIDR）才采用
+// X-Forwarded-Proto 和 X-Forwarded-Host，basePath 为全部路由的路径前缀
+func External(basePath string, trustedProxies []string) gin.HandlerFunc {
+	var trusted []*net.IPNet
+	for _, proxy := range trustedProxies {
+		if !strings.Contains(proxy, "/") {
+			if ip := net.ParseIP(proxy); ip != nil && ip.To4() != nil {
+				proxy += "/32"
+			} else {
+				proxy += "/128"
+			}
+		}
+		if _, network, err := net.ParseCIDR(proxy); err == nil {
+			trusted = append(trusted, network)
+		}
+	}
+
+	return func(c *gin.Context) {
+		ext := external{scheme: "http", host: c.Request.Host, basePath: basePath}
+		if c.Request.TLS != nil {
+			ext.scheme = "https"
+		}
+		if fromTrustedProxy(c.RemoteIP(), trusted) {
+			if proto := forwardedValue(c.GetHeader("X-Forwarded-Proto")); proto == "http" || proto == "https" {
+				ext.scheme = proto
+			}
+			if host := forwardedValue(c.GetHeader("X-Forwarded-Host")); host != "" {
+				ext.host = host
+			}
+		}
+		c.Set(externalKey, ext)
+		c.Next()
+	}
+}
+
+// ExternalURL 生成客户端可访问的地址，path 以 / 开头且不含路径前缀；websocket 为 true 时使用 ws/wss 协议
+func ExternalURL(c *gin.Context, websocket bool, path string) string {
+	ext := getExternal(c)
+	scheme := ext.scheme
+	if websocket {
+		scheme = strings.Replace(scheme, "http", "ws", 1)
+	}
+	return scheme + "://" + ext.host + ext.basePath + path
+}
+
+// ExternalSecure 客户端是否通过 HTTPS 访问（直接 TLS 或可信代理终止 TLS）
+func ExternalSecure(c *gin.Context) bool {
+	return getExternal(c).scheme == "https"
+}
+
+// BasePath 路由的路径前缀，未配置时为空
+func BasePath(c *gin.Context) string {
+	return getExternal(c).basePath
+}
+
+func getExternal(c *gin.Context) external {
+	if v, ok := c.Get(externalKey); ok {
+		return v.(external)
+	}
+	ext := external{scheme: "http", host: c.Request.Host}
+	if c.Request.TLS != nil {
+		ext.scheme = "https"
+	}
+	return ext
+}
+
+func fromTrustedProxy(remoteIP string, trusted []*net.IPNet) bool {
+	ip := net.ParseIP(remoteIP)
+	if ip == nil {
+		return false
+	}
+	for _, network := range trusted {
+		if network.Contains(ip) {
+			return true
+		}
+	}
+	return false
+}
+
+// forwardedValue 多级代理时 X-Forwarded-* 为逗号分隔的列表，第一项为最外层代理收到的值
+func forwardedValue(header string) string {
+	value, _, _ := strings.Cut(header, ",")
+	return strings.TrimSpace(value)
+}
@@ -0,0 +1,39 @@
+package model
+
+import "time"
+
+// 告警规则
+const (
+	AlertClusterUnreachable = "cluster-unreachable"
+	AlertNodeNotReady       = "node-not-ready"
+	AlertDiskPressure       = "disk-pressure"
+	AlertCertExpiring       = "cert-expiring"
+)
+
+// 告警级别
+const (
+	SeverityWarning  = "warning"
+	SeverityCritical = "critical"
+)
+
+// 告警状态
+const (
+	AlertFiring   = "firing"
+	AlertResolved = "resolved"
+)
+
+// Alert 受管集群的健康告警，同一集群、规则和对象只保留一条记录
+type Alert struct {
+	ID          string `json:"id"`
+	ClusterID   string `json:"clusterId"`
+	ClusterName string `json:"clusterName"`
+	Rule        string `json:"rule"`
+	Severity    string `json:"severity"`
+	// Target 告警对象，如节点名称
+	Target     string     `json:"target"`
+	Message    string     `json:"message"`
+	Status     string     `json:"status"`
+	FiredAt    time.Time  `json:"firedAt"`
+	LastSeenAt time.Time  `json:"lastSeenAt"`
+	ResolvedAt *time.Time `json:"resolvedAt,omitempty"`
+}
@@ -0,0 +1,34 @@
+package model
+
+import "time"
+
+// 审计动作
+const (
+	AuditMaintenanceStart = "maintenance.start"
+	AuditMaintenanceEnd   = "maintenance.end"
+	AuditBackupCreate     = "backup.create"
+	AuditRestoreCreate    = "restore.create"
+	AuditScheduleApply    = "backup-schedule.apply"
+	AuditScheduleDelete   = "backup-schedule.delete"
+	AuditSecretsRotate    = "secrets-encryption.rotate"
+	AuditSecurityScan     = "security-scan.start"
+	// AuditJoinBundleExport 导出的加入信息包包含 join token
+	AuditJoinBundleExport = "join-bundle.export"
+)
+
+// AuditEvent 运维操作的审计记录
+type AuditEvent struct {
+	ID     string    `json:"id"`
+	Time   time.Time `json:"time"`
+	Action string    `json:"action"`
+	// Actor 操作用户，未启用认证时为空
+	Actor     string `json:"actor,omitempty"`
+	RequestID string `json:"requestId,omitempty"`
+	ClusterID string `json:"clusterId,omitempty"`
+	// Target 操作对象，如节点名
+	Target  string `json:"target,omitempty"`
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+	// Details 失败原因等补充信息
+	Details string `json:"details,omitempty"`
+}
@@ -0,0 +1,24 @@
+package model
+
+import "time"
+
+// LoginRequest 用户名密码登录（本地用户或 LDAP）
+type LoginRequest struct {
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// LoginResponse 登录成功后签发的会话令牌，后续请求通过 Authorization: Bearer <token> 携带
+type LoginResponse struct {
+	Token     string    `json:"token"`
+	ExpiresAt time.Time `json:"expiresAt"`
+	User      UserInfo  `json:"user"`
+}
+
+// UserInfo 当前登录用户
+type UserInfo struct {
+	Subject  string   `json:"subject"`
+	Name     string   `json:"name"`
+	Provider string   `json:"provider"`
+	Roles    []string `json:"roles"`
+}
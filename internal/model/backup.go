@@ -0,0 +1,99 @@
+package model
+
+import "time"
+
+// VeleroOptions 由 install-velero 步骤安装的 Velero 及备份存储位置
+type VeleroOptions struct {
+	// ChartVersion Velero Helm chart 版本，默认 7.2.1（Velero v1.14）
+	ChartVersion string `json:"chartVersion"`
+	// ChartRepo Helm chart 仓库地址，用于内网镜像仓库，未设置时使用官方仓库
+	ChartRepo string `json:"chartRepo"`
+	// PluginVersion velero-plugin-for-aws 版本，需与 Velero 版本匹配，默认 v1.10.0
+	PluginVersion string `json:"pluginVersion"`
+	// Storage 备份写入的对象存储，未设置时使用 install-minio 安装的集群内 MinIO（存储桶 velero）
+	Storage *BackupStorageOptions `json:"storage"`
+}
+
+// BackupStorageOptions S3 兼容的对象存储（MinIO、AWS S3 等）
+type BackupStorageOptions struct {
+	// Endpoint S3 兼容服务地址，如 http://minio.example:9000，为空时使用 AWS S3
+	Endpoint string `json:"endpoint"`
+	// Bucket 存储桶，需预先创建
+	Bucket string `json:"bucket" binding:"required"`
+	// Prefix 桶内的路径前缀，多个集群共用一个桶时用于区分
+	Prefix string `json:"prefix"`
+	// Region 区域，未设置时为 us-east-1（MinIO 不校验区域）
+	Region    string `json:"region"`
+	AccessKey string `json:"accessKey" binding:"required"`
+	SecretKey string `json:"secretKey" binding:"required"`
+	// InsecureSkipTLSVerify 不校验对象存储的 HTTPS 证书，用于自签名证书的 MinIO
+	InsecureSkipTLSVerify bool `json:"insecureSkipTlsVerify"`
+}
+
+// BackupRequest 立即备份指定命名空间
+type BackupRequest struct {
+	Namespaces []string `json:"namespaces" binding:"required,min=1"`
+	// TTLHours 备份保留时间，默认 720 小时（30 天），到期后 Velero 自动删除
+	TTLHours int `json:"ttlHours" binding:"omitempty,min=1,max=87600"`
+	// SkipVolumes 不备份 Pod 挂载的卷数据，默认以文件系统方式（kopia）备份全部卷
+	SkipVolumes bool `json:"skipVolumes"`
+}
+
+// RestoreRequest 从备份恢复命名空间
+type RestoreRequest struct {
+	// Namespaces 只恢复备份中的这些命名空间，为空时恢复全部
+	Namespaces []string `json:"namespaces"`
+	// NamespaceMapping 恢复到其他命名空间（原命名空间 -> 新命名空间），用于在不影响原实例的情况下验证备份
+	NamespaceMapping map[string]string `json:"namespaceMapping"`
+}
+
+// BackupScheduleRequest 创建或更新定时备份
+type BackupScheduleRequest struct {
+	// Schedule 五段式 cron 表达式（UTC），如 0 2 * * *
+	Schedule   string   `json:"schedule" binding:"required"`
+	Namespaces []string `json:"namespaces" binding:"required,min=1"`
+	// TTLHours 每份备份的保留时间，默认 720 小时
+	TTLHours    int  `json:"ttlHours" binding:"omitempty,min=1,max=87600"`
+	SkipVolumes bool `json:"skipVolumes"`
+	// Paused 暂停定时备份
+	Paused bool `json:"paused"`
+}
+
+// Backup Velero 备份
+type Backup struct {
+	Name       string   `json:"name"`
+	Namespaces []string `json:"namespaces"`
+	// Phase Velero 的备份阶段，如 InProgress、Completed、PartiallyFailed、Failed
+	Phase string `json:"phase"`
+	// Schedule 由定时备份创建时为其名称
+	Schedule    string     `json:"schedule,omitempty"`
+	Errors      int        `json:"errors"`
+	Warnings    int        `json:"warnings"`
+	StartedAt   *time.Time `json:"startedAt,omitempty"`
+	CompletedAt *time.Time `json:"completedAt,omitempty"`
+	ExpiresAt   *time.Time `json:"expiresAt,omitempty"`
+}
+
+// Restore Velero 恢复
+type Restore struct {
+	Name        string            `json:"name"`
+	Backup      string            `json:"backup"`
+	Namespaces  []string          `json:"namespaces,omitempty"`
+	Mapping     map[string]string `json:"namespaceMapping,omitempty"`
+	Phase       string            `json:"phase"`
+	Errors      int               `json:"errors"`
+	Warnings    int               `json:"warnings"`
+	StartedAt   *time.Time        `json:"startedAt,omitempty"`
+	CompletedAt *time.Time        `json:"completedAt,omitempty"`
+}
+
+// BackupSchedule Velero 定时备份
+type BackupSchedule struct {
+	Name       string     `json:"name"`
+	Schedule   string     `json:"schedule"`
+	Namespaces []string   `json:"namespaces"`
+	TTL        string     `json:"ttl"`
+	Paused     bool       `json:"paused"`
+	Phase      string     `json:"phase"`
+	LastBackup *time.Time `json:"lastBackup,omitempty"`
+}
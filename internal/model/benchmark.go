@@ -0,0 +1,86 @@
+package model
+
+import "time"
+
+// 基准测试记录的部署阶段，按执行顺序排列
+const (
+	// PhasePreflight validate 步骤对节点的连接与系统检查
+	PhasePreflight = "preflight"
+	// PhaseDownload 获取安装脚本、探测镜像加速和上传离线安装包
+	PhaseDownload = "download"
+	// PhaseInstall 执行安装脚本、校验二进制并启动服务
+	PhaseInstall = "install"
+	// PhaseServiceWait 等待 k3s 服务就绪
+	PhaseServiceWait = "service-wait"
+	// PhaseVerify verify 步骤的集群检查
+	PhaseVerify = "verify"
+)
+
+var benchmarkPhases = []string{PhasePreflight, PhaseDownload, PhaseInstall, PhaseServiceWait, PhaseVerify}
+
+// PhaseOrder 阶段在部署流程中的顺序，未知阶段排在最后
+func PhaseOrder(phase string) int {
+	for i, p := range benchmarkPhases {
+		if p == phase {
+			return i
+		}
+	}
+	return len(benchmarkPhases)
+}
+
+// BenchmarkOptions 基准测试：任务执行时记录各阶段在每个节点上的耗时，结束后保存为基准记录，
+// 用于对比不同镜像源、并发度和超时设置下的部署耗时
+type BenchmarkOptions struct {
+	// Label 本次运行的标签（如 "mirror=aliyun"），在对比报告中区分各次运行
+	Label string `json:"label"`
+}
+
+// PhaseTiming 一个阶段在一个节点上的耗时，Node 为空表示集群级阶段
+type PhaseTiming struct {
+	Node       string `json:"node,omitempty"`
+	Phase      string `json:"phase"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// StepTiming 一个部署步骤的耗时
+type StepTiming struct {
+	Step       string `json:"step"`
+	DurationMs int64  `json:"durationMs"`
+	Success    bool   `json:"success"`
+}
+
+// BenchmarkRun 一次启用了基准测试的部署任务的耗时记录
+type BenchmarkRun struct {
+	TaskID     string `json:"taskId"`
+	Label      string `json:"label,omitempty"`
+	ClusterKey string `json:"clusterKey"`
+	// Status 任务的最终状态
+	Status string `json:"status"`
+	// Settings 影响耗时的运行设置，如安装源、镜像加速地址和等待超时
+	Settings  map[string]string `json:"settings,omitempty"`
+	Phases    []PhaseTiming     `json:"phases"`
+	Steps     []StepTiming      `json:"steps"`
+	TotalMs   int64             `json:"totalMs"`
+	CreatedAt time.Time         `json:"createdAt"`
+}
+
+// BenchmarkComparison 多次运行的耗时对比，第一次运行作为基准
+type BenchmarkComparison struct {
+	Runs []BenchmarkRun `json:"runs"`
+	// Phases 各节点各阶段的耗时，Steps 各步骤的耗时，Total 任务总耗时
+	Phases []BenchmarkRow `json:"phases"`
+	Steps  []BenchmarkRow `json:"steps"`
+	Total  BenchmarkRow   `json:"total"`
+}
+
+// BenchmarkRow 对比报告中的一行，各切片与 Runs 顺序一致，运行中没有该项时为 null
+type BenchmarkRow struct {
+	Phase       string   `json:"phase,omitempty"`
+	Step        string   `json:"step,omitempty"`
+	Node        string   `json:"node,omitempty"`
+	DurationsMs []*int64 `json:"durationsMs"`
+	// DeltaPercent 相对第一次运行的耗时变化百分比，负数表示更快
+	DeltaPercent []*float64 `json:"deltaPercent"`
+	// Fastest 耗时最短的运行在 Runs 中的序号
+	Fastest int `json:"fastest"`
+}
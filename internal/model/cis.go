@@ -0,0 +1,35 @@
+package model
+
+import "time"
+
+// CIS 检查结果
+const (
+	CISPass = "pass"
+	CISFail = "fail"
+	// CISSkip 检查对象不存在（如未使用嵌入式 etcd）或无法读取
+	CISSkip = "skip"
+)
+
+// CISReport Master 节点的 CIS 合规报告，每项检查对应基准中的一条控制项
+type CISReport struct {
+	ClusterID string `json:"clusterId"`
+	// Benchmark 检查依据的基准版本
+	Benchmark   string       `json:"benchmark"`
+	Node        string       `json:"node"`
+	GeneratedAt time.Time    `json:"generatedAt"`
+	Passed      int          `json:"passed"`
+	Failed      int          `json:"failed"`
+	Skipped     int          `json:"skipped"`
+	Controls    []CISControl `json:"controls"`
+}
+
+// CISControl 一条控制项的检查结果
+type CISControl struct {
+	ID     string `json:"id"`
+	Title  string `json:"title"`
+	Status string `json:"status"`
+	// Actual 节点上的实际值
+	Actual string `json:"actual"`
+	// Remediation 未通过时的修复方法
+	Remediation string `json:"remediation,omitempty"`
+}
@@ -0,0 +1,305 @@
+package model
+
+import "time"
+
+// 集群来源
+const (
+	ClusterSourceDeployed = "deployed"
+	ClusterSourceAdopted  = "adopted"
+)
+
+// Cluster 受管集群记录
+type Cluster struct {
+	ID      string `json:"id"`
+	Name    string `json:"name"`
+	Source  string `json:"source"`
+	Version string `json:"version"`
+	// Master 连接方式，认证信息通过 CredentialID 引用凭据库，不含明文
+	Master NodeConfig `json:"master"`
+	// Agents configure-agent 成功的 Agent 节点的连接方式，认证信息同样引用凭据库，
+	// 供需要登录各节点的运维操作使用（如修复 journald 配置漂移）；纳管的集群为空
+	Agents []NodeConfig  `json:"agents,omitempty"`
+	Nodes  []ClusterNode `json:"nodes"`
+	// KubeconfigID 凭据库中集群管理员 kubeconfig 的记录 ID
+	KubeconfigID string `json:"kubeconfigId,omitempty"`
+	// Defaults 部署到该集群（按 Master 地址匹配）的请求未设置时使用的部署设置
+	Defaults *DeploySettings `json:"defaults,omitempty"`
+	// Desired 期望状态，用于配置漂移检测
+	Desired *DesiredState `json:"desired,omitempty"`
+	// Drift 最近一次漂移检测结果
+	Drift *DriftReport `json:"drift,omitempty"`
+	// Releases 集群上部署的 inSuite 实例
+	Releases []Release `json:"releases,omitempty"`
+	// ObjectStore install-minio 安装的对象存储
+	ObjectStore *ObjectStore `json:"objectStore,omitempty"`
+	// Quotas 最近一次刷新时各实例命名空间的资源配额用量
+	Quotas []NamespaceQuota `json:"quotas,omitempty"`
+	// Degraded 部分成功的部署中未能加入集群的节点，重新配置成功后移除
+	Degraded  []DegradedNode `json:"degraded,omitempty"`
+	CreatedAt time.Time      `json:"createdAt"`
+	UpdatedAt time.Time      `json:"updatedAt"`
+}
+
+// Release 部署到集群的 inSuite 实例，每次执行 deploy-insuite 递增版本
+type Release struct {
+	Name      string `json:"name"`
+	Namespace string `json:"namespace"`
+	Revision  int    `json:"revision"`
+	// Images 本次部署使用的组件镜像
+	Images []string `json:"images"`
+	// Exposure 访问方式：nodeport、ingress 或 loadbalancer
+	Exposure string        `json:"exposure"`
+	URL      string        `json:"url,omitempty"`
+	Quota    *QuotaOptions `json:"quota,omitempty"`
+	// LimitRange 本次部署设置的容器资源默认值与上限
+	LimitRange *LimitRangeOptions `json:"limitRange,omitempty"`
+	// DeployID 执行部署的任务 ID 或请求 ID
+	DeployID   string    `json:"deployId"`
+	DeployedAt time.Time `json:"deployedAt"`
+}
+
+// DegradedNode 设置 continueOnError 的部署中配置失败的节点
+type DegradedNode struct {
+	Name string `json:"name"`
+	IP   string `json:"ip"`
+	// Step 失败的部署步骤
+	Step    string    `json:"step"`
+	Message string    `json:"message"`
+	Since   time.Time `json:"since"`
+}
+
+// NamespaceQuota 实例命名空间资源配额的上限与用量，键为配额项（如 limits.cpu、pods）
+type NamespaceQuota struct {
+	Namespace string            `json:"namespace"`
+	Hard      map[string]string `json:"hard"`
+	Used      map[string]string `json:"used"`
+	// Percent 各项用量占上限的百分比
+	Percent map[string]int `json:"percent"`
+}
+
+// ClusterNode 从集群中发现的节点
+type ClusterNode struct {
+	Name       string            `json:"name"`
+	InternalIP string            `json:"internalIp"`
+	Roles      []string          `json:"roles"`
+	Version    string            `json:"version"`
+	Ready      bool              `json:"ready"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	// Taints 格式为 key=value:Effect
+	Taints []string `json:"taints,omitempty"`
+	// Conditions 处于异常状态的节点条件，如 DiskPressure、MemoryPressure
+	Conditions []string `json:"conditions,omitempty"`
+}
+
+// 工作负载类型
+const (
+	WorkloadDeployment  = "Deployment"
+	WorkloadStatefulSet = "StatefulSet"
+	WorkloadDaemonSet   = "DaemonSet"
+)
+
+// Workload 集群中的 Deployment、StatefulSet 或 DaemonSet
+type Workload struct {
+	Namespace string `json:"namespace"`
+	Kind      string `json:"kind"`
+	Name      string `json:"name"`
+	// Images 容器镜像（含版本标签），不含 init 容器
+	Images []string `json:"images"`
+	// Replicas 期望副本数，DaemonSet 为应调度的节点数
+	Replicas          int `json:"replicas"`
+	ReadyReplicas     int `json:"readyReplicas"`
+	UpdatedReplicas   int `json:"updatedReplicas"`
+	AvailableReplicas int `json:"availableReplicas"`
+	// Ready 全部副本就绪
+	Ready bool `json:"ready"`
+	// Nodes 运行中 Pod 所在的节点
+	Nodes     []string  `json:"nodes"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+// DesiredState 集群期望状态。未设置的项不参与漂移检测
+type DesiredState struct {
+	// Labels 节点名 -> key=value 标签列表
+	Labels map[string][]string `json:"labels,omitempty" yaml:"labels"`
+	// Taints 节点名 -> key=value:Effect 污点列表
+	Taints map[string][]string `json:"taints,omitempty" yaml:"taints"`
+	// Registries Master 节点 /etc/rancher/k3s/registries.yaml 的完整内容
+	Registries string `json:"registries,omitempty" yaml:"registries"`
+	// K3sArgs Master 节点 k3s 服务应包含的启动参数
+	K3sArgs []string `json:"k3sArgs,omitempty" yaml:"k3sArgs"`
+	// Manifests 清单名称 -> YAML，通过 kubectl diff 比对
+	Manifests map[string]string `json:"manifests,omitempty" yaml:"manifests"`
+	// LogRotation 各节点 kubelet 容器日志轮转与 Master 的 journald 上限
+	LogRotation *LogRotationOptions `json:"logRotation,omitempty" yaml:"logRotation"`
+}
+
+// 漂移类型
+const (
+	DriftLabel       = "label"
+	DriftTaint       = "taint"
+	DriftRegistries  = "registries"
+	DriftK3sArgs     = "k3s-args"
+	DriftManifest    = "manifest"
+	DriftLogRotation = "log-rotation"
+)
+
+// DriftItem 单项漂移
+type DriftItem struct {
+	Kind       string `json:"kind"`
+	Target     string `json:"target"`
+	Expected   string `json:"expected"`
+	Actual     string `json:"actual"`
+	Reconciled bool   `json:"reconciled"`
+	Message    string `json:"message,omitempty"`
+}
+
+// 标签与污点对账的变更动作
+const (
+	LabelAdd    = "add"
+	LabelUpdate = "update"
+	LabelRemove = "remove"
+)
+
+// NodeLabelsRequest 节点标签与污点的期望状态，只调整其中列出的节点
+type NodeLabelsRequest struct {
+	// Labels 节点名 -> key=value 标签列表
+	Labels map[string][]string `json:"labels"`
+	// Taints 节点名 -> key=value:Effect 污点列表
+	Taints map[string][]string `json:"taints"`
+}
+
+// LabelChange 标签或污点对账的一项变更
+type LabelChange struct {
+	Node string `json:"node"`
+	// Kind 为 label 或 taint
+	Kind   string `json:"kind"`
+	Action string `json:"action"`
+	Value  string `json:"value"`
+	// Previous 更新前的值
+	Previous string `json:"previous,omitempty"`
+	Applied  bool   `json:"applied"`
+	Message  string `json:"message,omitempty"`
+}
+
+// DriftReport 漂移检测结果
+type DriftReport struct {
+	CheckedAt time.Time   `json:"checkedAt"`
+	InSync    bool        `json:"inSync"`
+	Items     []DriftItem `json:"items"`
+}
+
+// GitOpsStatus 最近一次 GitOps 同步结果
+type GitOpsStatus struct {
+	Success  bool                  `json:"success"`
+	Commit   string                `json:"commit"`
+	SyncedAt time.Time             `json:"syncedAt"`
+	Message  string                `json:"message,omitempty"`
+	Clusters []GitOpsClusterResult `json:"clusters"`
+}
+
+// GitOpsClusterResult 单个集群的同步结果
+type GitOpsClusterResult struct {
+	Cluster string       `json:"cluster"`
+	Success bool         `json:"success"`
+	Message string       `json:"message,omitempty"`
+	Drift   *DriftReport `json:"drift,omitempty"`
+}
+
+// ClusterMetrics metrics-server 提供的节点和 Pod 资源用量快照
+type ClusterMetrics struct {
+	CollectedAt time.Time     `json:"collectedAt"`
+	Nodes       []NodeMetrics `json:"nodes"`
+	Pods        []PodMetrics  `json:"pods"`
+}
+
+// NodeMetrics 节点资源用量。CPU 单位为 millicore，内存单位为字节
+type NodeMetrics struct {
+	Name              string `json:"name"`
+	CPUUsage          int64  `json:"cpuUsage"`
+	CPUAllocatable    int64  `json:"cpuAllocatable"`
+	MemoryUsage       int64  `json:"memoryUsage"`
+	MemoryAllocatable int64  `json:"memoryAllocatable"`
+	// CPUPercent、MemoryPercent 用量占可分配资源的百分比
+	CPUPercent    float64 `json:"cpuPercent"`
+	MemoryPercent float64 `json:"memoryPercent"`
+}
+
+// PodMetrics Pod 资源用量（全部容器之和）。CPU 单位为 millicore，内存单位为字节
+type PodMetrics struct {
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	CPUUsage    int64  `json:"cpuUsage"`
+	MemoryUsage int64  `json:"memoryUsage"`
+}
+
+// CapacityReport deploy-insuite 前的节点容量检查结果。CPU 单位为 millicore，内存和磁盘单位为字节
+type CapacityReport struct {
+	Fits  bool           `json:"fits"`
+	Nodes []NodeCapacity `json:"nodes"`
+	// Problems 无法容纳的组件副本、磁盘不足及未参与规划的节点
+	Problems []string `json:"problems"`
+}
+
+// NodeCapacity 带 inSuite 角色标签节点的容量。Free 为可分配资源减去其他 Pod 的资源请求，
+// Planned 为计划调度到该节点的组件资源请求
+type NodeCapacity struct {
+	Name              string   `json:"name"`
+	Roles             []string `json:"roles"`
+	CPUAllocatable    int64    `json:"cpuAllocatable"`
+	CPUFree           int64    `json:"cpuFree"`
+	CPUPlanned        int64    `json:"cpuPlanned"`
+	MemoryAllocatable int64    `json:"memoryAllocatable"`
+	MemoryFree        int64    `json:"memoryFree"`
+	MemoryPlanned     int64    `json:"memoryPlanned"`
+	// CPUUsage、MemoryUsage metrics-server 报告的实际用量，不可用时为 0
+	CPUUsage      int64 `json:"cpuUsage"`
+	MemoryUsage   int64 `json:"memoryUsage"`
+	DiskAvailable int64 `json:"diskAvailable"`
+	DiskRequired  int64 `json:"diskRequired"`
+	// Replicas 计划调度到该节点的组件副本数，角色 -> 副本数
+	Replicas map[string]int `json:"replicas"`
+}
+
+// 网络检查项
+const (
+	NetworkCheckDNSInternal = "dns-internal"
+	NetworkCheckDNSExternal = "dns-external"
+	NetworkCheckClusterIP   = "service-clusterip"
+	NetworkCheckPodNetwork  = "pod-network"
+	NetworkCheckNodePort    = "nodeport"
+	NetworkCheckPolicy      = "network-policy"
+)
+
+// NetworkCheck 一项集群网络检查的结果
+type NetworkCheck struct {
+	Name string `json:"name"`
+	// From 发起检查的节点，NodePort 检查由后端发起时为 backend
+	From    string `json:"from"`
+	Target  string `json:"target"`
+	Passed  bool   `json:"passed"`
+	Skipped bool   `json:"skipped,omitempty"`
+	Message string `json:"message,omitempty"`
+}
+
+// DrainResult 节点驱逐结果
+type DrainResult struct {
+	Node string `json:"node"`
+	// Evicted 已驱逐的 Pod（命名空间/名称）
+	Evicted []string `json:"evicted"`
+	// BlockingPDBs 驱逐前 disruptionsAllowed 为 0、覆盖节点上 Pod 的 PodDisruptionBudget
+	BlockingPDBs []string `json:"blockingPdbs,omitempty"`
+	Duration     string   `json:"duration"`
+}
+
+// MaintenanceWindow 由审计日志中成对的维护开始与结束记录组成的维护窗口，未结束时 EndedAt 为空
+type MaintenanceWindow struct {
+	ClusterID string    `json:"clusterId"`
+	Node      string    `json:"node"`
+	Reason    string    `json:"reason,omitempty"`
+	StartedBy string    `json:"startedBy,omitempty"`
+	StartedAt time.Time `json:"startedAt"`
+	// Drained 开始维护时驱逐是否完成，未完成时节点仍保持 cordon
+	Drained bool       `json:"drained"`
+	EndedBy string     `json:"endedBy,omitempty"`
+	EndedAt *time.Time `json:"endedAt,omitempty"`
+}
@@ -0,0 +1,37 @@
+package model
+
+// inSuite 数据库引擎
+const (
+	DatabaseEngineDeployment = "deployment"
+	DatabaseEngineCNPG       = "cloudnative-pg"
+)
+
+// DatabaseOptions inSuite 数据库的部署方式
+type DatabaseOptions struct {
+	// Engine deployment（默认，单副本 postgres:13 Deployment，无持久卷）或 cloudnative-pg（由 CloudNativePG operator 管理的主备集群，主库故障时自动切换）
+	Engine string `json:"engine" binding:"omitempty,oneof=deployment cloudnative-pg"`
+	// Instances cloudnative-pg 的实例数（1 个主库加流复制备库），默认 3
+	Instances int `json:"instances" binding:"omitempty,min=1,max=9"`
+	// Image cloudnative-pg 使用的 PostgreSQL 镜像，默认 ghcr.io/cloudnative-pg/postgresql:16.4
+	Image string `json:"image"`
+	// Size 每个实例的数据卷大小，默认 10Gi
+	Size string `json:"size"`
+	// StorageClass 数据卷的 StorageClass，为空时使用集群默认
+	StorageClass string `json:"storageClass"`
+	// OperatorVersion CloudNativePG operator 版本，默认 1.24.1
+	OperatorVersion string `json:"operatorVersion"`
+	// OperatorManifestURL operator 安装清单地址，用于内网镜像，设置后忽略 operatorVersion
+	OperatorManifestURL string `json:"operatorManifestUrl"`
+	// Backup cloudnative-pg 的自动备份（定时基础备份加持续 WAL 归档），未设置时不备份
+	Backup *DatabaseBackupOptions `json:"backup"`
+}
+
+// DatabaseBackupOptions 数据库自动备份
+type DatabaseBackupOptions struct {
+	// Schedule 基础备份的五段式 cron 表达式（UTC），默认 0 2 * * *
+	Schedule string `json:"schedule"`
+	// RetentionDays 备份与 WAL 的保留天数，默认 7
+	RetentionDays int `json:"retentionDays" binding:"omitempty,min=1,max=3650"`
+	// Storage 备份写入的对象存储，未设置时使用 install-minio 安装的集群内 MinIO（存储桶 insuite）
+	Storage *BackupStorageOptions `json:"storage"`
+}
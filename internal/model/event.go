@@ -0,0 +1,33 @@
+package model
+
+import "time"
+
+// 事件类型
+const (
+	EventNormal  = "Normal"
+	EventWarning = "Warning"
+)
+
+// ClusterEvent 从受管集群收集的 Kubernetes 事件
+type ClusterEvent struct {
+	UID       string `json:"uid"`
+	Namespace string `json:"namespace"`
+	// Kind、Name 事件关联的对象，如 Pod insuite-app-7d9c
+	Kind    string `json:"kind"`
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Reason  string `json:"reason"`
+	Message string `json:"message"`
+	// Source 产生事件的组件，如 kubelet、default-scheduler
+	Source    string    `json:"source"`
+	Count     int       `json:"count"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+}
+
+// ClusterEvents 一个集群最近收集的事件
+type ClusterEvents struct {
+	ClusterID   string         `json:"clusterId"`
+	CollectedAt time.Time      `json:"collectedAt"`
+	Events      []ClusterEvent `json:"events"`
+}
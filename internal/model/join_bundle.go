@@ -0,0 +1,57 @@
+package model
+
+import "time"
+
+// JoinBundle 集群加入信息，签名后编码为一行文本的加入信息包，用于之后通过其他工具或手动加入节点
+type JoinBundle struct {
+	// Version 格式版本
+	Version     int    `json:"version"`
+	ClusterID   string `json:"clusterId"`
+	ClusterName string `json:"clusterName"`
+	// ServerURL k3s server 地址，如 https://192.168.1.10:6443
+	ServerURL string `json:"serverUrl"`
+	Token     string `json:"token"`
+	// CAHash 集群 CA（server /cacerts 的内容）的 SHA256，加入前据此确认连接的是同一个集群
+	CAHash string `json:"caHash"`
+	// K3sVersion 集群的 k3s 版本，加入的节点应使用相同版本
+	K3sVersion string `json:"k3sVersion,omitempty"`
+	// Flags 建议的 Agent 启动参数，取自集群期望状态中同样适用于 Agent 的参数
+	Flags []string `json:"flags,omitempty"`
+	// KeyID 签名公钥的指纹
+	KeyID     string    `json:"keyId"`
+	IssuedAt  time.Time `json:"issuedAt"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
+
+// JoinBundleRequest 导出集群加入信息包
+type JoinBundleRequest struct {
+	ClusterID string `json:"clusterId" binding:"required"`
+	// TTL 有效期，如 2h，未设置时使用配置 join_bundle.ttl
+	TTL string `json:"ttl"`
+}
+
+// JoinBundleExport 导出的加入信息包
+type JoinBundleExport struct {
+	// Bundle 签名后的加入信息包，导入时原样提交
+	Bundle  string     `json:"bundle"`
+	Content JoinBundle `json:"content"`
+	// Command 在新节点上执行的安装命令，<节点名> 需替换为新节点的名称
+	Command string `json:"command"`
+}
+
+// JoinBundleImportRequest 校验加入信息包
+type JoinBundleImportRequest struct {
+	Bundle string `json:"bundle" binding:"required"`
+	// SkipProbe 不从后端访问 server 核对 CA（后端与集群网络不通时使用）
+	SkipProbe bool `json:"skipProbe"`
+}
+
+// JoinBundleImport 校验通过的加入信息包
+type JoinBundleImport struct {
+	Content JoinBundle `json:"content"`
+	Command string     `json:"command"`
+	// Verified 已从后端访问 server，确认其 CA 与加入信息包一致
+	Verified bool `json:"verified"`
+	// Warnings 不影响使用的问题，如后端无法访问 server
+	Warnings []string `json:"warnings"`
+}
@@ -0,0 +1,36 @@
+package model
+
+import "time"
+
+// KubeAuditEntry 从 Master 审计日志收集的 API Server 审计记录，只保留元数据，不保存请求和响应体
+type KubeAuditEntry struct {
+	AuditID string `json:"auditId"`
+	// Stage 记录阶段，如 ResponseComplete、Panic
+	Stage string    `json:"stage"`
+	Level string    `json:"level"`
+	Time  time.Time `json:"time"`
+	User  string    `json:"user"`
+	// Groups 用户所属的组，如 system:masters
+	Groups    []string `json:"groups,omitempty"`
+	SourceIPs []string `json:"sourceIps,omitempty"`
+	UserAgent string   `json:"userAgent,omitempty"`
+	Verb      string   `json:"verb"`
+	// Namespace、Resource、Subresource、Name 请求访问的对象，非资源请求（如 /healthz）为空
+	Namespace   string `json:"namespace,omitempty"`
+	Resource    string `json:"resource,omitempty"`
+	Subresource string `json:"subresource,omitempty"`
+	Name        string `json:"name,omitempty"`
+	RequestURI  string `json:"requestUri"`
+	Code        int    `json:"code"`
+	// Decision 鉴权结果 allow 或 forbid
+	Decision string `json:"decision,omitempty"`
+}
+
+// ClusterAuditLog 一个集群最近收集的审计记录
+type ClusterAuditLog struct {
+	ClusterID   string    `json:"clusterId"`
+	CollectedAt time.Time `json:"collectedAt"`
+	// Cursor 已收集的最新记录时间（审计日志中的原始字符串），下次只读取不早于该时间的记录
+	Cursor  string           `json:"cursor,omitempty"`
+	Entries []KubeAuditEntry `json:"entries"`
+}
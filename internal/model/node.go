@@ -1,5 +1,7 @@
 package model
 
+import "time"
+
 type Node struct {
 	ID        int    `json:"id"`
 	Name      string `json:"name"`
@@ -16,3 +18,34 @@ type ClusterInfo struct {
 	Labels     map[string]string `json:"labels"`
 	Token      string            `json:"token"`
 }
+
+// 节点变更类型
+const (
+	ChangeResolvConf   = "resolv-conf"
+	ChangeDNS          = "dns"
+	ChangeSwap         = "swap"
+	ChangeNMCloudSetup = "nm-cloud-setup"
+	ChangeFirewall     = "firewall"
+	ChangeDataDir      = "data-dir"
+	ChangeSysctl       = "sysctl"
+	ChangeFstab        = "fstab"
+	ChangeCIS          = "cis"
+)
+
+// NodeChange 部署对节点系统配置的一次修改，Undo 为撤销该修改的命令
+type NodeChange struct {
+	Kind        string    `json:"kind"`
+	Description string    `json:"description"`
+	Undo        string    `json:"undo"`
+	At          time.Time `json:"at"`
+}
+
+// NodeJournal 节点的系统变更日志，按修改顺序记录，卸载或回滚时逆序撤销
+type NodeJournal struct {
+	// ID 节点 machine-id，为空表示旧版本以 IP 为键的日志
+	ID        string       `json:"id,omitempty"`
+	IP        string       `json:"ip"`
+	Node      string       `json:"node"`
+	Changes   []NodeChange `json:"changes"`
+	UpdatedAt time.Time    `json:"updatedAt"`
+}
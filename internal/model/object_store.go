@@ -0,0 +1,43 @@
+package model
+
+import "time"
+
+// MinIO 部署模式
+const (
+	MinIOStandalone  = "standalone"
+	MinIODistributed = "distributed"
+)
+
+// MinIOOptions 由 install-minio 步骤安装的集群内 MinIO，访问密钥自动生成并保存在凭据库
+type MinIOOptions struct {
+	// Mode standalone（默认，单副本）或 distributed（在带 insuite.minio=true 标签的节点上各运行一个副本，至少 4 个节点）
+	Mode string `json:"mode" binding:"omitempty,oneof=standalone distributed"`
+	// Image MinIO 镜像，默认 minio/minio:RELEASE.2024-10-13T13-34-11Z
+	Image string `json:"image"`
+	// Size 每个副本的数据卷大小，默认 20Gi
+	Size string `json:"size"`
+	// StorageClass 数据卷的 StorageClass，为空时使用集群默认
+	StorageClass string `json:"storageClass"`
+	// Buckets 额外创建的存储桶，velero 与 insuite 总是创建
+	Buckets []string `json:"buckets"`
+}
+
+// ObjectStore 集群内的对象存储，供 Velero 和 inSuite 实例使用
+type ObjectStore struct {
+	// Endpoint 集群内访问地址
+	Endpoint string   `json:"endpoint"`
+	Mode     string   `json:"mode"`
+	Replicas int      `json:"replicas"`
+	Buckets  []string `json:"buckets"`
+	// CredentialID 凭据库中访问密钥的记录 ID
+	CredentialID string    `json:"credentialId"`
+	InstalledAt  time.Time `json:"installedAt"`
+}
+
+// ObjectStoreAccess 对象存储的地址与访问密钥
+type ObjectStoreAccess struct {
+	Endpoint  string   `json:"endpoint"`
+	Buckets   []string `json:"buckets"`
+	AccessKey string   `json:"accessKey"`
+	SecretKey string   `json:"secretKey"`
+}
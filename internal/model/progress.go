@@ -0,0 +1,41 @@
+package model
+
+import "time"
+
+// 部署进度事件类型
+const (
+	ProgressTaskQueued   = "task.queued"
+	ProgressTaskStarted  = "task.started"
+	ProgressTaskFinished = "task.finished"
+	// ProgressTaskTakeover 节点被管理员强制提交的任务接管，新任务和被接管的任务各发布一条
+	ProgressTaskTakeover = "task.takeover"
+	ProgressStepStarted  = "step.started"
+	ProgressStepFinished = "step.finished"
+	// ProgressPhaseStarted、ProgressPhaseFinished 节点上的部署阶段（见基准测试的阶段），结束事件带耗时
+	ProgressPhaseStarted  = "phase.started"
+	ProgressPhaseFinished = "phase.finished"
+	// ProgressPreflight 单个节点的预检结果
+	ProgressPreflight = "preflight.node"
+	// ProgressNode 节点上的安装、部署进度
+	ProgressNode = "node.progress"
+	// ProgressDetail 步骤或任务的补充信息，如摘要校验结果和访问地址
+	ProgressDetail = "detail"
+)
+
+// ProgressEvent 安装器、Manager、预检和任务队列发布的部署进度事件
+type ProgressEvent struct {
+	Type string    `json:"type"`
+	Time time.Time `json:"time"`
+	// RequestID 发起操作的 API 请求 ID，任务的全部事件使用任务 ID
+	RequestID string `json:"requestId,omitempty"`
+	// TaskID 任务队列发布的事件带任务 ID；安装器等只知道请求 ID
+	TaskID string `json:"taskId,omitempty"`
+	Step   string `json:"step,omitempty"`
+	Node   string `json:"node,omitempty"`
+	Phase  string `json:"phase,omitempty"`
+	// Level info、warn 或 error，同任务日志级别
+	Level   string `json:"level"`
+	Message string `json:"message,omitempty"`
+	// DurationMs 阶段、步骤的耗时
+	DurationMs int64 `json:"durationMs,omitempty"`
+}
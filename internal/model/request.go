@@ -4,10 +4,15 @@ type SSHTestRequest struct {
 	IP         string `json:"ip" binding:"required"`
 	Port       int    `json:"port" binding:"required"`
 	Username   string `json:"username" binding:"required"`
-	AuthType   string `json:"authType" binding:"required,oneof=password key"`
+	AuthType   string `json:"authType" binding:"required,oneof=password key ca"`
 	Password   string `json:"password"`
 	PrivateKey string `json:"privateKey"`
 	Passphrase string `json:"passphrase"`
+	Transport  string `json:"transport" binding:"omitempty,oneof=ssh agent"`
+	AgentID    string `json:"agentId"`
+	// JumpHosts 按顺序经过的跳板机链路
+	JumpHosts   []JumpHost   `json:"jumpHosts"`
+	DialOptions *DialOptions `json:"dialOptions"`
 }
 
 type BatchSSHTestRequest struct {
@@ -24,14 +29,543 @@ type BatchNodeRequest struct {
 	Password   string `json:"password"`
 	PrivateKey string `json:"privateKey"`
 	Passphrase string `json:"passphrase"`
+	Transport  string `json:"transport" binding:"omitempty,oneof=ssh agent"`
+	AgentID    string `json:"agentId"`
+	// JumpHosts 按顺序经过的跳板机链路
+	JumpHosts   []JumpHost   `json:"jumpHosts"`
+	DialOptions *DialOptions `json:"dialOptions"`
 }
 
 type DeployRequest struct {
-	DeployMode     string              `json:"deployMode" binding:"required,oneof=single dual triple"`
-	Step           string              `json:"step" binding:"required"`
-	Nodes          []NodeConfig        `json:"nodes" binding:"required"`
-	RoleAssignment map[string]string   `json:"roleAssignment" binding:"required"`
-	Labels         map[string][]string `json:"labels"`
+	DeployMode string       `json:"deployMode" binding:"required,oneof=single dual triple"`
+	Step       string       `json:"step" binding:"required"`
+	Nodes      []NodeConfig `json:"nodes" binding:"required"`
+	// RoleAssignment 组件角色所在的节点（角色 -> 节点名），未设置时按 deployMode 的角色模板分配
+	RoleAssignment map[string]string `json:"roleAssignment"`
+	// Labels 节点标签，与 roleAssignment 同时未设置时按角色模板生成 insuite.* 标签
+	Labels map[string][]string `json:"labels"`
+	// Profile 部署配置档：standard（默认）或 edge（资源受限的小型 ARM 设备）
+	Profile string `json:"profile" binding:"omitempty,oneof=standard edge"`
+	// Edge edge 配置档的附加选项，仅在 profile 为 edge 时生效
+	Edge *EdgeOptions `json:"edge"`
+	// DeploySettings 等待超时、镜像源、预检建议值和代理，未设置的字段继承集群默认值和全局配置
+	DeploySettings
+	// Runtime 容器运行时选项，未设置时使用 k3s 内置的 containerd
+	Runtime *RuntimeOptions `json:"runtime"`
+	// NodePrep prepare-nodes 步骤的主机名、时区和 locale 设置，未设置时跳过该步骤
+	NodePrep *NodePrepOptions `json:"nodePrep"`
+	// DiskPrep prepare-disks 步骤格式化并挂载的数据盘，未设置时跳过该步骤
+	DiskPrep *DiskPrepOptions `json:"diskPrep"`
+	// Tuning tune-nodes 步骤应用的内核参数与文件句柄限制，未设置时跳过该步骤
+	Tuning *TuningOptions `json:"tuning"`
+	// InstallScript 自定义 k3s 安装脚本（fork 或内网镜像），未设置时按节点网络环境选择官方或国内镜像脚本
+	InstallScript *InstallScriptOptions `json:"installScript"`
+	// Airgap 使用离线安装包安装，节点无需访问外网；设置后忽略 installScript 并跳过 check-mirrors 与 prepull-images
+	Airgap *AirgapOptions `json:"airgap"`
+	// AllowUnverifiedArtifacts 在线安装时 k3s 二进制与官方校验和不一致或无法获取官方校验和仍继续安装，默认拒绝
+	AllowUnverifiedArtifacts bool `json:"allowUnverifiedArtifacts"`
+	// DNS 节点与集群 DNS 配置，未设置时沿用节点现有解析（解析失败时追加公共 DNS）
+	DNS *DNSOptions `json:"dns"`
+	// Firewall validate 步骤对节点防火墙的处理方式，未设置时关闭 ufw/firewalld
+	Firewall *FirewallOptions `json:"firewall"`
+	// CNI 以 Calico 或 Cilium 替换 k3s 内置的 flannel，未设置时使用 flannel
+	CNI *CNIOptions `json:"cni"`
+	// Hosts 由 prepare-nodes 步骤写入各节点 /etc/hosts 的记录，未设置时不修改
+	Hosts *HostsOptions `json:"hosts"`
+	// Storage 由 configure-storage 步骤配置的集群存储，未设置时保留 k3s 默认的 local-path
+	Storage *StorageOptions `json:"storage"`
+	// CertManager 由 install-cert-manager 步骤安装的 cert-manager 与 ClusterIssuer，设置后 Ingress TLS 证书由其签发
+	CertManager *CertManagerOptions `json:"certManager"`
+	// MinIO 由 install-minio 步骤安装的集群内对象存储，未设置时跳过该步骤
+	MinIO *MinIOOptions `json:"minio"`
+	// Velero 由 install-velero 步骤安装的 Velero 及备份存储，未设置时跳过该步骤
+	Velero *VeleroOptions `json:"velero"`
+	// Instance deploy-insuite 部署的应用实例（团队或环境），未设置时部署到命名空间 insuite 中的默认实例
+	Instance *InstanceOptions `json:"instance"`
+	// Exposure inSuite 应用的访问方式，未设置时使用随机分配的 NodePort
+	Exposure *ExposureOptions `json:"exposure"`
+	// Components 按角色（database、middleware、app）设置 inSuite 组件的副本、资源与探针，未设置的字段使用默认值
+	Components map[string]*ComponentOptions `json:"components"`
+	// Database inSuite 数据库的部署方式，未设置时使用单副本 Deployment
+	Database *DatabaseOptions `json:"database"`
+	// SkipCapacityCheck deploy-insuite 前不检查节点剩余 CPU、内存和磁盘是否足够
+	SkipCapacityCheck bool `json:"skipCapacityCheck"`
+	// NetworkPolicy deploy-insuite 在实例命名空间应用的默认拒绝网络策略，未设置时不限制（并删除之前应用的策略）
+	NetworkPolicy *NetworkPolicyOptions `json:"networkPolicy"`
+	// NetworkCheck verify 步骤的集群网络检查，未设置时执行默认检查
+	NetworkCheck *NetworkCheckOptions `json:"networkCheck"`
+	// Security 安装 Master 时写入的 PodSecurity 准入默认级别与 API Server 审计策略
+	Security *SecurityOptions `json:"security"`
+	// ComponentArgs 透传给 kubelet、kube-proxy 和 kube-apiserver 的参数，参数名需在允许列表中
+	ComponentArgs *ComponentArgsOptions `json:"componentArgs"`
+	// Patch patch-os 步骤逐个节点升级系统软件包的方式，未设置时在线升级并按需重启
+	Patch *PatchOptions `json:"patch"`
+	// Benchmark 记录各阶段在每个节点上的耗时并保存为基准记录，仅对异步任务生效
+	Benchmark *BenchmarkOptions `json:"benchmark"`
+	// ContinueOnError 逐节点执行的步骤某个节点失败时继续处理其余节点，至少一个节点成功时步骤部分成功；
+	// Master 或承担组件角色的节点失败时步骤仍失败。异步任务的后续步骤不再包含失败的节点
+	ContinueOnError bool `json:"continueOnError"`
+	// RequestID 发起部署的 API 请求 ID，由服务端填充
+	RequestID string `json:"-"`
+	// SettingsResolved 部署设置已在提交任务时解析，执行步骤时直接使用记录的值，由服务端填充
+	SettingsResolved bool `json:"-"`
+	// WorkspaceID 节点上临时工作目录的名称，由服务端填充（任务 ID 或请求 ID）
+	WorkspaceID string `json:"-"`
+	// Artifacts 执行过程中上传到节点的文件路径，由服务端填充
+	Artifacts []string `json:"-"`
+	// Digests 执行过程中校验的安装文件摘要，由服务端填充
+	Digests []ArtifactDigest `json:"-"`
+	// AccessURL deploy-insuite 和 verify 步骤得到的 inSuite 访问地址，由服务端填充
+	AccessURL string `json:"-"`
+	// SecretsEncryption verify 步骤读取的 Secret 加密状态，由服务端填充
+	SecretsEncryption *SecretsEncryptionStatus `json:"-"`
+	// Preflight validate 步骤各节点的预检结果，由服务端填充
+	Preflight []PreflightResult `json:"-"`
+	// NodeResults 逐节点执行的步骤各节点的结果，由服务端填充
+	NodeResults []NodeResult `json:"-"`
+	// Readiness wait-nodes 步骤各节点的就绪状态，由服务端填充
+	Readiness []NodeReadiness `json:"-"`
+}
+
+// 默认拒绝网络策略
+const (
+	DefaultDenyIngress = "ingress"
+	DefaultDenyAll     = "all"
+)
+
+// NetworkPolicyOptions 实例命名空间的默认拒绝网络策略，由 k3s 内置的网络策略控制器或 cni 指定的插件执行
+type NetworkPolicyOptions struct {
+	// DefaultDeny ingress 只允许同命名空间和应用端口的入站流量；all 同时限制出站，只允许同命名空间、集群 DNS 和 egressCidrs
+	DefaultDeny string `json:"defaultDeny" binding:"required,oneof=ingress all"`
+	// EgressCIDRs all 模式下允许访问的外部网段，如外部数据库或对象存储
+	EgressCIDRs []string `json:"egressCidrs"`
+}
+
+// NetworkCheckOptions verify 步骤的集群网络检查
+type NetworkCheckOptions struct {
+	// Disabled 跳过网络检查
+	Disabled bool `json:"disabled"`
+	// ExternalName 检查外部域名解析使用的域名，默认 registry.cn-hangzhou.aliyuncs.com
+	ExternalName string `json:"externalName"`
+}
+
+// PodSecurity 准入级别
+const (
+	PodSecurityPrivileged = "privileged"
+	PodSecurityBaseline   = "baseline"
+	PodSecurityRestricted = "restricted"
+)
+
+// SecurityOptions 集群初始化时的 PodSecurity 准入、API Server 审计与 Secret 加密配置，未设置的项不配置
+type SecurityOptions struct {
+	PodSecurity *PodSecurityOptions `json:"podSecurity"`
+	Audit       *AuditPolicyOptions `json:"audit"`
+	// SecretsEncryption 以 --secrets-encryption 安装 Master，Secret 在 datastore 中以 AES-CBC 加密存储；只能在首次安装时启用
+	SecretsEncryption bool `json:"secretsEncryption"`
+	// CIS 按 k3s CIS 加固指南安装：harden-nodes 步骤设置内核参数并启用 protect-kernel-defaults，
+	// 未设置的 podSecurity、audit 使用默认值，并启用 secretsEncryption，安装后收紧证书与 kubeconfig 的文件权限
+	CIS bool `json:"cis"`
+}
+
+// PodSecurityOptions 集群默认的 PodSecurity 准入级别及按命名空间覆盖的级别
+type PodSecurityOptions struct {
+	// Enforce 默认强制级别：privileged、baseline（默认）或 restricted
+	Enforce string `json:"enforce" binding:"omitempty,oneof=privileged baseline restricted"`
+	// Audit 默认审计级别，违反时记入审计日志，默认 restricted
+	Audit string `json:"audit" binding:"omitempty,oneof=privileged baseline restricted"`
+	// Warn 默认告警级别，违反时向客户端返回警告，默认 restricted
+	Warn string `json:"warn" binding:"omitempty,oneof=privileged baseline restricted"`
+	// Exemptions 豁免检查的命名空间，kube-system 始终豁免
+	Exemptions []string `json:"exemptions"`
+	// Namespaces 命名空间 -> 级别，安装后为命名空间设置 enforce/audit/warn 标签覆盖默认级别，命名空间不存在时创建
+	Namespaces map[string]string `json:"namespaces"`
+}
+
+// AuditPolicyOptions API Server 审计日志配置
+type AuditPolicyOptions struct {
+	// Policy 审计策略 YAML（audit.k8s.io/v1 Policy），为空时使用内置策略
+	Policy string `json:"policy"`
+	// MaxAgeDays 审计日志保留天数，默认 30
+	MaxAgeDays int `json:"maxAgeDays" binding:"omitempty,min=1,max=365"`
+	// MaxBackups 保留的历史日志文件数，默认 10
+	MaxBackups int `json:"maxBackups" binding:"omitempty,min=1,max=100"`
+	// MaxSizeMB 单个日志文件大小上限（MB），默认 100
+	MaxSizeMB int `json:"maxSizeMb" binding:"omitempty,min=1,max=1024"`
+}
+
+// ComponentArgsOptions Kubernetes 组件参数，每项为不带前导 -- 的 name=value
+type ComponentArgsOptions struct {
+	// Kubelet 所有节点的 kubelet 参数，如 max-pods=200、eviction-hard=memory.available<500Mi
+	Kubelet []string `json:"kubelet"`
+	// KubeProxy 所有节点的 kube-proxy 参数，如 proxy-mode=ipvs
+	KubeProxy []string `json:"kubeProxy"`
+	// APIServer Master 的 kube-apiserver 参数，如 feature-gates=...
+	APIServer []string `json:"apiServer"`
+}
+
+// 补丁后的重启策略
+const (
+	RebootAuto   = "auto"
+	RebootAlways = "always"
+	RebootNever  = "never"
+)
+
+// PatchOptions patch-os 步骤的系统补丁选项
+type PatchOptions struct {
+	// Bundle 离线安装包名称，设置后安装其中的系统补丁软件包，不访问软件源
+	Bundle string `json:"bundle"`
+	// Reboot 重启策略：auto（默认，系统提示需要时重启）、always 或 never
+	Reboot string `json:"reboot" binding:"omitempty,oneof=auto always never"`
+	// DrainTimeoutSeconds 驱逐节点 Pod 的超时，默认 300
+	DrainTimeoutSeconds int `json:"drainTimeoutSeconds" binding:"omitempty,min=10,max=3600"`
+	// ReadyTimeoutSeconds 重启后等待节点重新连接并 Ready 的超时，默认 600
+	ReadyTimeoutSeconds int `json:"readyTimeoutSeconds" binding:"omitempty,min=60,max=3600"`
+	// Force 驱逐时同时删除不受控制器管理的 Pod
+	Force bool `json:"force"`
+}
+
+// WaitOptions 部署等待参数（秒）
+type WaitOptions struct {
+	// ServiceTimeout 等待 k3s 服务启动的最长时间，默认 180
+	ServiceTimeout int `json:"serviceTimeout" binding:"omitempty,min=10,max=3600"`
+	// DeploymentTimeout 等待每个 inSuite 组件就绪的最长时间，默认 300
+	DeploymentTimeout int `json:"deploymentTimeout" binding:"omitempty,min=10,max=7200"`
+	// PollInterval 轮询间隔，默认 10
+	PollInterval int `json:"pollInterval" binding:"omitempty,min=1,max=300"`
+	// InstallTimeout 在节点上执行安装脚本的最长时间，默认 900
+	InstallTimeout int `json:"installTimeout" binding:"omitempty,min=60,max=7200"`
+	// ScriptDownloadTimeout 后端下载安装脚本的最长时间，默认 60
+	ScriptDownloadTimeout int `json:"scriptDownloadTimeout" binding:"omitempty,min=5,max=600"`
+}
+
+// 部署配置档
+const (
+	ProfileStandard = "standard"
+	ProfileEdge     = "edge"
+)
+
+// EdgeOptions edge 配置档选项
+type EdgeOptions struct {
+	// Disable 禁用的内置组件，未设置时禁用 traefik、servicelb 和 metrics-server
+	Disable []string `json:"disable"`
+	// ServerURL 中心云端 k3s server 地址，如 https://cloud.example.com:6443。
+	// 设置后所有节点以 Agent 身份加入该集群，不安装 Master，节点名称使用请求中的节点名
+	ServerURL string `json:"serverUrl"`
+	// Token 加入中心集群的 token，设置 serverUrl 时必填
+	Token string `json:"token"`
+	// JoinBundle 导出的加入信息包，校验通过后代替 serverUrl 和 token
+	JoinBundle string `json:"joinBundle"`
+	// AutoRestart 为 k3s 服务配置不限次数的自动重启，断电恢复后存储或网络未就绪时持续重试
+	AutoRestart bool `json:"autoRestart"`
+}
+
+// RuntimeOptions 节点已有容器运行时和 Kubernetes 残留的处理方式
+type RuntimeOptions struct {
+	// Docker 使用节点上已安装的 Docker 作为容器运行时（k3s --docker），未安装 Docker 的节点预检失败
+	Docker bool `json:"docker"`
+	// CleanupKubernetes 预检时清理 kubeadm/kubelet 等旧 Kubernetes 安装的残留
+	CleanupKubernetes bool `json:"cleanupKubernetes"`
+}
+
+// CNI 插件
+const (
+	CNICalico = "calico"
+	CNICilium = "cilium"
+)
+
+// CNIOptions 替换 flannel 的 CNI 插件，以 k3s HelmChart 在安装 Master 时部署
+type CNIOptions struct {
+	// Plugin calico 或 cilium
+	Plugin string `json:"plugin" binding:"required,oneof=calico cilium"`
+	// Version Helm chart 版本，未设置时使用内置的默认版本
+	Version string `json:"version"`
+	// ChartRepo Helm chart 仓库地址，用于内网镜像仓库，未设置时使用官方仓库
+	ChartRepo string `json:"chartRepo"`
+	// Registry 替换插件镜像所在的仓库（如同步了 quay.io 镜像的内网仓库），未设置时使用 chart 默认仓库
+	Registry string `json:"registry"`
+}
+
+// 防火墙处理方式
+const (
+	FirewallDisable  = "disable"
+	FirewallPreserve = "preserve"
+)
+
+// FirewallOptions 节点防火墙处理方式
+type FirewallOptions struct {
+	// Mode disable（默认）关闭 ufw/firewalld；preserve 保持防火墙启用，只放行 k3s 所需端口和集群网段
+	Mode string `json:"mode" binding:"omitempty,oneof=disable preserve"`
+	// ClusterCIDR、ServiceCIDR Pod 与 Service 网段，默认为 k3s 的 10.42.0.0/16 和 10.43.0.0/16
+	ClusterCIDR string `json:"clusterCidr"`
+	ServiceCIDR string `json:"serviceCidr"`
+	// ExtraPorts 额外放行的端口，格式为 端口/协议 或 起始-结束/协议，如 80/tcp、9100-9200/tcp
+	ExtraPorts []string `json:"extraPorts"`
+}
+
+// NodePrepOptions 节点系统设置的统一化
+type NodePrepOptions struct {
+	// Hostname 将主机名设置为节点名称
+	Hostname bool `json:"hostname"`
+	// Timezone 时区，如 Asia/Shanghai，为空时不修改
+	Timezone string `json:"timezone"`
+	// Locale 系统默认 locale，必须为 UTF-8 编码，如 C.UTF-8，为空时不修改
+	Locale string `json:"locale"`
+	// TimeSync 以 Master 为 chrony 服务端同步各节点时间，适用于无法访问外部 NTP 的离线环境，未设置时不修改
+	TimeSync *TimeSyncOptions `json:"timeSync"`
+	// LogRotation journald 占用上限与容器日志轮转，避免长期运行的边缘节点磁盘写满，未设置时不修改
+	LogRotation *LogRotationOptions `json:"logRotation"`
+}
+
+// LogRotationOptions 节点日志轮转，未设置的字段使用默认值
+type LogRotationOptions struct {
+	// JournaldMaxUse journald 持久日志占用上限，如 500M、1G，默认 1G
+	JournaldMaxUse string `json:"journaldMaxUse" yaml:"journaldMaxUse"`
+	// ContainerLogMaxSize 单个容器日志文件上限（kubelet container-log-max-size），如 10Mi，默认 10Mi
+	ContainerLogMaxSize string `json:"containerLogMaxSize" yaml:"containerLogMaxSize"`
+	// ContainerLogMaxFiles 每个容器保留的日志文件数（kubelet container-log-max-files），默认 5
+	ContainerLogMaxFiles int `json:"containerLogMaxFiles" yaml:"containerLogMaxFiles" binding:"omitempty,min=2"`
+}
+
+// DiskPrepOptions 数据盘准备。只格式化没有分区和文件系统签名的磁盘，已有数据的磁盘会被拒绝
+type DiskPrepOptions struct {
+	// Disks 节点名 -> 数据盘设备，如 /dev/vdb；未列出的节点不处理
+	Disks map[string]string `json:"disks" binding:"required"`
+	// MountPoint 挂载点，默认为 k3s 数据目录 /var/lib/rancher/k3s
+	MountPoint string `json:"mountPoint"`
+	// Filesystem 文件系统类型，ext4（默认）或 xfs
+	Filesystem string `json:"filesystem" binding:"omitempty,oneof=ext4 xfs"`
+	// ConfirmFormat 确认格式化，必须为 true
+	ConfirmFormat bool `json:"confirmFormat"`
+}
+
+// TuningOptions 节点内核参数与文件句柄限制
+type TuningOptions struct {
+	// Profile 内置调优配置：default（默认）或 large（节点与 Pod 较多的集群）
+	Profile string `json:"profile" binding:"omitempty,oneof=default large"`
+	// Sysctl 追加或覆盖的内核参数
+	Sysctl map[string]string `json:"sysctl"`
+	// NoFile 登录会话的 nofile 限制，未设置时使用配置中的值
+	NoFile int `json:"noFile" binding:"omitempty,min=1024"`
+}
+
+// TimeSyncOptions 节点时间同步
+type TimeSyncOptions struct {
+	// Upstreams Master 的上游 NTP 服务器，为空时以 Master 本地时钟为准
+	Upstreams []string `json:"upstreams"`
+	// MaxOffsetMs 同步后各节点与 Master 允许的最大时钟偏差（毫秒），默认 100
+	MaxOffsetMs int `json:"maxOffsetMs" binding:"omitempty,min=1,max=60000"`
+}
+
+// InstallScriptOptions 自定义安装脚本，url 与 content 二选一
+type InstallScriptOptions struct {
+	// URL 脚本下载地址，由后端下载后传到节点执行
+	URL string `json:"url"`
+	// Content 脚本内容
+	Content string `json:"content"`
+	// SHA256 脚本校验和（十六进制），设置后与下载或提供的脚本不一致时中止安装
+	SHA256 string `json:"sha256"`
+	// ApplyRegistryPatch 注入国内镜像脚本的 setup_registry 调用，脚本需定义 setup_registry 函数
+	ApplyRegistryPatch bool `json:"applyRegistryPatch"`
+	// ApplyCertPatch 写入客户端证书有效期配置
+	ApplyCertPatch bool `json:"applyCertPatch"`
+}
+
+// AirgapOptions 离线安装
+type AirgapOptions struct {
+	// Bundle 离线安装包名称，即 bundles.dir 下由 cmd/bundle 生成的目录名
+	Bundle string `json:"bundle" binding:"required"`
+}
+
+// DNSOptions 节点与集群 DNS 配置
+type DNSOptions struct {
+	// Servers 站点 DNS 服务器 IP，设置后 validate 步骤将节点解析指向这些服务器，不再追加公共 DNS
+	Servers []string `json:"servers"`
+	// SearchDomains 节点的搜索域
+	SearchDomains []string `json:"searchDomains"`
+	// Forwarder 节点解析方式：auto（默认，systemd-resolved 运行时使用它，否则直接写 resolv.conf）、systemd-resolved、dnsmasq、none
+	Forwarder string `json:"forwarder" binding:"omitempty,oneof=auto systemd-resolved dnsmasq none"`
+	// CoreDNS 集群 DNS 自定义，由 configure-dns 步骤在集群安装后应用
+	CoreDNS *CoreDNSOptions `json:"coredns"`
+}
+
+// CoreDNSOptions CoreDNS 自定义
+type CoreDNSOptions struct {
+	// Upstreams 集群外域名的上游 DNS，默认使用 Servers，在安装 k3s 时生效
+	Upstreams []string `json:"upstreams"`
+	// StubDomains 域名 -> DNS 服务器，该域名下的查询转发到指定服务器
+	StubDomains map[string][]string `json:"stubDomains"`
+	// Hosts 域名 -> IP 静态解析记录
+	Hosts map[string]string `json:"hosts"`
+}
+
+// HostsOptions 集群各节点 /etc/hosts 中统一维护的记录
+type HostsOptions struct {
+	// Entries 私有镜像仓库、API VIP、内部服务等记录
+	Entries []HostsEntry `json:"entries"`
+	// IncludeNodes 同时写入所有节点的主机名与 IP
+	IncludeNodes bool `json:"includeNodes"`
+}
+
+// HostsEntry /etc/hosts 记录
+type HostsEntry struct {
+	IP    string   `json:"ip"`
+	Names []string `json:"names"`
+}
+
+// 存储方式
+const (
+	StorageLocalPath = "local-path"
+	StorageLonghorn  = "longhorn"
+)
+
+// StorageOptions 集群存储配置，所选存储会被设为默认 StorageClass
+type StorageOptions struct {
+	// Provider local-path（k3s 内置，数据目录放在各节点可用空间最大的分区）或 longhorn
+	Provider string `json:"provider" binding:"required,oneof=local-path longhorn"`
+	// Longhorn Provider 为 longhorn 时的安装参数
+	Longhorn *LonghornOptions `json:"longhorn"`
+}
+
+// LonghornOptions Longhorn 安装参数
+type LonghornOptions struct {
+	// Version Longhorn 版本，默认 v1.7.2
+	Version string `json:"version"`
+	// ManifestURL 安装清单地址（内网镜像），设置后忽略 Version
+	ManifestURL string `json:"manifestUrl"`
+	// Replicas 卷的默认副本数，默认为节点数与 3 的较小值，不能超过节点数
+	Replicas int `json:"replicas" binding:"omitempty,min=1"`
+}
+
+// ClusterIssuer 类型
+const (
+	IssuerCA   = "ca"
+	IssuerACME = "acme"
+)
+
+// ACME 质询方式
+const (
+	SolverHTTP01 = "http01"
+	SolverDNS01  = "dns01"
+)
+
+// CertManagerOptions cert-manager 安装参数与签发 Ingress 证书的 ClusterIssuer
+type CertManagerOptions struct {
+	// Version cert-manager 版本，默认 v1.16.1
+	Version string `json:"version"`
+	// ManifestURL 安装清单地址（内网镜像），设置后忽略 Version
+	ManifestURL string `json:"manifestUrl"`
+	// Issuer ca（默认，使用后端的内部 CA 签发）或 acme
+	Issuer string `json:"issuer" binding:"omitempty,oneof=ca acme"`
+	// DNSNames Issuer 为 ca 时集群中间 CA 允许签发的域名（含子域名），未设置时使用 exposure.hosts
+	DNSNames []string `json:"dnsNames"`
+	// ACME Issuer 为 acme 时的参数
+	ACME *ACMEOptions `json:"acme"`
+}
+
+// ACMEOptions ACME 签发参数
+type ACMEOptions struct {
+	// Email 注册账号的邮箱，用于接收证书到期提醒
+	Email string `json:"email" binding:"required,email"`
+	// Server ACME 目录地址，默认 Let's Encrypt 生产环境
+	Server string `json:"server"`
+	// Solver http01（默认，通过 Traefik Ingress 应答）或 dns01
+	Solver string `json:"solver" binding:"omitempty,oneof=http01 dns01"`
+	// DNS Solver 为 dns01 时的 DNS 服务商
+	DNS *DNSSolverOptions `json:"dns"`
+}
+
+// DNSSolverOptions DNS-01 质询使用的 DNS 服务商，cloudflare 与 rfc2136 二选一
+type DNSSolverOptions struct {
+	// Provider cloudflare 或 rfc2136（支持动态更新的权威 DNS，如 BIND）
+	Provider string `json:"provider" binding:"required,oneof=cloudflare rfc2136"`
+	// APIToken Cloudflare API 令牌，需要 Zone.DNS 编辑权限
+	APIToken string `json:"apiToken"`
+	// Nameserver rfc2136 的权威 DNS 地址，如 10.0.0.53:53
+	Nameserver string `json:"nameserver"`
+	// TSIGKeyName、TSIGAlgorithm、TSIGSecret rfc2136 的 TSIG 密钥，算法默认 HMACSHA256
+	TSIGKeyName   string `json:"tsigKeyName"`
+	TSIGAlgorithm string `json:"tsigAlgorithm"`
+	TSIGSecret    string `json:"tsigSecret"`
+}
+
+// InstanceOptions inSuite 应用实例。同一集群可部署多个相互独立的实例，每个实例独占一个命名空间
+type InstanceOptions struct {
+	// Name 实例名（小写字母、数字和 -），默认 insuite
+	Name string `json:"name"`
+	// Namespace 实例的命名空间，默认与实例名相同
+	Namespace string `json:"namespace"`
+	// Quota 命名空间资源配额，未设置时不限制（并删除之前设置的配额）
+	Quota *QuotaOptions `json:"quota"`
+	// LimitRange 命名空间内容器的默认资源与上限，未设置时不限制（并删除之前设置的 LimitRange）
+	LimitRange *LimitRangeOptions `json:"limitRange"`
+}
+
+// QuotaOptions 命名空间资源配额（Kubernetes 数量格式），未设置的项不限制
+type QuotaOptions struct {
+	RequestsCPU    string `json:"requestsCpu,omitempty"`
+	RequestsMemory string `json:"requestsMemory,omitempty"`
+	LimitsCPU      string `json:"limitsCpu,omitempty"`
+	LimitsMemory   string `json:"limitsMemory,omitempty"`
+	Pods           int    `json:"pods,omitempty" binding:"omitempty,min=1"`
+}
+
+// LimitRangeOptions 命名空间内每个容器的资源默认值与上限（Kubernetes 数量格式），未设置的项不限制。
+// 默认值用于未声明 resources 的容器，设置了 requests/limits 配额时这类容器依赖默认值才能创建
+type LimitRangeOptions struct {
+	DefaultRequestCPU    string `json:"defaultRequestCpu,omitempty"`
+	DefaultRequestMemory string `json:"defaultRequestMemory,omitempty"`
+	DefaultLimitCPU      string `json:"defaultLimitCpu,omitempty"`
+	DefaultLimitMemory   string `json:"defaultLimitMemory,omitempty"`
+	MaxCPU               string `json:"maxCpu,omitempty"`
+	MaxMemory            string `json:"maxMemory,omitempty"`
+}
+
+// ExposureOptions inSuite 应用的访问方式
+type ExposureOptions struct {
+	// Type nodeport（默认）、ingress（ClusterIP + k3s 内置 Traefik）或 loadbalancer（k3s ServiceLB）
+	Type string `json:"type" binding:"omitempty,oneof=nodeport ingress loadbalancer"`
+	// NodePort 固定的 NodePort，未设置时随机分配
+	NodePort int `json:"nodePort" binding:"omitempty,min=30000,max=32767"`
+	// Hosts ingress 方式的主机名，访问地址使用第一个主机名
+	Hosts []string `json:"hosts"`
+	// Port loadbalancer 方式的对外端口，默认 8080（80/443 已被 Traefik 占用）
+	Port int `json:"port" binding:"omitempty,min=1,max=65535"`
+	// TLS ingress 方式使用内部 CA 签发证书并启用 HTTPS
+	TLS bool `json:"tls"`
+}
+
+// ComponentOptions inSuite 组件的副本、资源与探针设置
+type ComponentOptions struct {
+	// Replicas 副本数，默认 1；数据库和中间件为单实例镜像，只能为 1（cloudnative-pg 数据库的实例数由 database.instances 设置）
+	Replicas int `json:"replicas" binding:"omitempty,min=1,max=50"`
+	// AntiAffinity 多副本时的分散方式：preferred（默认，尽量分散到不同节点）或 required（每个节点最多一个副本）
+	AntiAffinity string           `json:"antiAffinity" binding:"omitempty,oneof=preferred required"`
+	Resources    *ResourceOptions `json:"resources"`
+	Probes       *ProbeOptions    `json:"probes"`
+}
+
+// ResourceOptions 容器资源请求与限制（Kubernetes 数量格式，如 500m、256Mi）
+type ResourceOptions struct {
+	RequestsCPU    string `json:"requestsCpu"`
+	RequestsMemory string `json:"requestsMemory"`
+	LimitsCPU      string `json:"limitsCpu"`
+	LimitsMemory   string `json:"limitsMemory"`
+}
+
+// ProbeOptions 存活与就绪探针参数（秒）
+type ProbeOptions struct {
+	// Disabled 不生成探针
+	Disabled bool `json:"disabled"`
+	// Path 应用组件 HTTP 检查的路径，默认 /
+	Path string `json:"path"`
+	// ReadinessDelaySeconds 就绪探针的初始延迟，默认 5
+	ReadinessDelaySeconds int `json:"readinessDelaySeconds" binding:"omitempty,min=0,max=3600"`
+	// LivenessDelaySeconds 存活探针的初始延迟，默认 30
+	LivenessDelaySeconds int `json:"livenessDelaySeconds" binding:"omitempty,min=0,max=3600"`
+	// PeriodSeconds 检查间隔，默认 10
+	PeriodSeconds int `json:"periodSeconds" binding:"omitempty,min=1,max=300"`
+	// FailureThreshold 连续失败多少次判定为失败，默认 3
+	FailureThreshold int `json:"failureThreshold" binding:"omitempty,min=1,max=30"`
 }
 
 type NodeConfig struct {
@@ -43,4 +577,150 @@ type NodeConfig struct {
 	Password   string `json:"password"`
 	PrivateKey string `json:"privateKey"`
 	Passphrase string `json:"passphrase"`
+	Transport  string `json:"transport" binding:"omitempty,oneof=ssh agent"`
+	AgentID    string `json:"agentId"`
+	// JumpHosts 按顺序经过的跳板机链路
+	JumpHosts   []JumpHost   `json:"jumpHosts"`
+	DialOptions *DialOptions `json:"dialOptions"`
+	// CredentialID 引用凭据库中的凭据，设置后忽略请求中的认证字段
+	CredentialID string `json:"credentialId"`
+	// RequestID 发起操作的 API 请求 ID，由服务端填充，用于关联 SSH 命令日志
+	RequestID string `json:"-"`
+	// ClusterName 节点在集群中的名称，由服务端填充；为空时按节点在请求中的顺序生成
+	ClusterName string `json:"-"`
+}
+
+// JumpHost 跳板机
+type JumpHost struct {
+	IP           string `json:"ip"`
+	Port         int    `json:"port"`
+	Username     string `json:"username"`
+	AuthType     string `json:"authType"`
+	Password     string `json:"password"`
+	PrivateKey   string `json:"privateKey"`
+	Passphrase   string `json:"passphrase"`
+	CredentialID string `json:"credentialId"`
+}
+
+// DialOptions 节点级连接参数
+type DialOptions struct {
+	// ConnectTimeout 连接超时（秒）
+	ConnectTimeout int      `json:"connectTimeout"`
+	Ciphers        []string `json:"ciphers"`
+	KeyExchanges   []string `json:"keyExchanges"`
+	MACs           []string `json:"macs"`
+}
+
+type KeyDistributionRequest struct {
+	KeyType string                `json:"keyType" binding:"omitempty,oneof=ed25519 rsa"`
+	Nodes   []KeyDistributionNode `json:"nodes" binding:"required,min=1,dive"`
+}
+
+// KeyDistributionNode 使用一次性密码登录节点以安装公钥，密码不会被保存
+type KeyDistributionNode struct {
+	Name     string `json:"name"`
+	IP       string `json:"ip" binding:"required"`
+	Port     int    `json:"port" binding:"required"`
+	Username string `json:"username" binding:"required"`
+	Password string `json:"password" binding:"required"`
+}
+
+// SSHCABootstrapRequest 在节点上信任 SSH CA 并将凭据切换为证书认证。
+// CredentialIDs 使用凭据库中的现有凭据登录；Nodes 使用一次性密码登录，密码不会被保存
+type SSHCABootstrapRequest struct {
+	CredentialIDs []string              `json:"credentialIds"`
+	Nodes         []KeyDistributionNode `json:"nodes" binding:"dive"`
+}
+
+// CredentialRotationRequest 轮换凭据，CredentialIDs 为空时轮换全部
+type CredentialRotationRequest struct {
+	CredentialIDs []string `json:"credentialIds"`
+	Mode          string   `json:"mode" binding:"omitempty,oneof=key password"`
+}
+
+// StateExportRequest 后端状态导出请求，口令用于加密导出包中的凭据
+type StateExportRequest struct {
+	Passphrase string `json:"passphrase" binding:"required,min=8"`
+}
+
+// HostsSyncRequest 同步或删除节点 /etc/hosts 中的受管记录
+type HostsSyncRequest struct {
+	Nodes []NodeConfig `json:"nodes" binding:"required,min=1"`
+	Hosts HostsOptions `json:"hosts"`
+}
+
+// TuningRequest 应用或撤销节点调优，撤销时忽略 Tuning
+type TuningRequest struct {
+	Nodes  []NodeConfig  `json:"nodes" binding:"required,min=1"`
+	Tuning TuningOptions `json:"tuning"`
+}
+
+// MaintenanceRequest 将节点置于维护状态：cordon 后驱逐 Pod
+type MaintenanceRequest struct {
+	// Reason 维护原因，记入审计日志
+	Reason string `json:"reason"`
+	// TimeoutSeconds 驱逐超时，默认 300 秒；PodDisruptionBudget 不允许驱逐时会一直重试到超时
+	TimeoutSeconds int `json:"timeoutSeconds" binding:"omitempty,min=10,max=3600"`
+	// Force 同时删除不受控制器管理的 Pod（删除后不会重建）
+	Force bool `json:"force"`
+	// KeepEmptyDirData 为 true 时遇到使用 emptyDir 的 Pod 停止驱逐；默认删除 emptyDir 数据（k3s 的 metrics-server 即使用 emptyDir）
+	KeepEmptyDirData bool `json:"keepEmptyDirData"`
+}
+
+// UninstallRequest 卸载节点上的 k3s 并撤销部署对系统配置的修改
+type UninstallRequest struct {
+	Nodes []NodeConfig `json:"nodes" binding:"required,min=1"`
+	// RollbackOnly 只撤销变更日志中的修改、不卸载 k3s，用于安装前失败的部署回滚；节点已安装 k3s 时拒绝执行
+	RollbackOnly bool `json:"rollbackOnly"`
+}
+
+// NodeServiceRequest 节点 k3s / k3s-agent 服务操作：重启、更新配置后重启或查看日志
+type NodeServiceRequest struct {
+	Nodes []NodeConfig `json:"nodes" binding:"required,min=1"`
+	// Config /etc/rancher/k3s/config.yaml 的完整内容，仅更新配置时使用，为空表示不修改
+	Config string `json:"config"`
+	// Registries /etc/rancher/k3s/registries.yaml 的完整内容，仅更新配置时使用，为空表示不修改
+	Registries string `json:"registries"`
+	// Lines 查看日志时返回的行数，默认 200
+	Lines int `json:"lines" binding:"omitempty,min=1,max=5000"`
+	// Wait 等待服务重新运行的超时与轮询间隔
+	Wait *WaitOptions `json:"wait"`
+}
+
+// LabelPlanRequest 按部署模式生成节点标签方案
+type LabelPlanRequest struct {
+	DeployMode string `json:"deployMode" binding:"required,oneof=single dual triple"`
+	// Nodes 参与部署的节点名，Master 固定为 k3s-master，其余按顺序视为 Agent
+	Nodes []string `json:"nodes" binding:"required,min=1"`
+	// RoleAssignment 可选，指定角色的主节点（角色 -> 节点名），未指定的角色按部署模式分配
+	RoleAssignment map[string]string `json:"roleAssignment"`
+	// Components 与部署请求相同，用于按应用副本数和分散方式扩展 insuite.app 标签
+	Components map[string]*ComponentOptions `json:"components"`
+}
+
+// LabelPlan 生成的标签方案，确认后作为部署请求的 labels 和 roleAssignment 提交
+type LabelPlan struct {
+	DeployMode     string              `json:"deployMode"`
+	RoleAssignment map[string]string   `json:"roleAssignment"`
+	Labels         map[string][]string `json:"labels"`
+	// Notes 分配依据及需要确认的事项
+	Notes []string `json:"notes"`
+}
+
+// RoleTemplate 部署模式的角色模板：Master（k3s-master）是唯一的 server 节点，其余节点按请求中的顺序作为 Agent
+type RoleTemplate struct {
+	DeployMode string `json:"deployMode"`
+	// Nodes 该模式至少需要的节点数，多出的节点只作为集群计算资源
+	Nodes int `json:"nodes"`
+	// Server 运行 k3s server 的节点
+	Server string `json:"server"`
+	// Roles 角色 -> 节点位置：master，或 agent-1、agent-2（请求中第几个 Agent）
+	Roles       map[string]string `json:"roles"`
+	Description string            `json:"description"`
+}
+
+// ClusterAdoptRequest 纳管已有 k3s 集群，Master 为集群 server 节点的 SSH 连接信息
+type ClusterAdoptRequest struct {
+	Name   string     `json:"name"`
+	Master NodeConfig `json:"master" binding:"required"`
 }
@@ -5,12 +5,49 @@ type SSHTestResponse struct {
 	Message string   `json:"message,omitempty"`
 	Details []string `json:"details,omitempty"`
 	ID      int      `json:"id,omitempty"`
+	// KeyType 密钥认证时解析出的私钥算法
+	KeyType           string   `json:"keyType,omitempty"`
+	SupportedKeyTypes []string `json:"supportedKeyTypes,omitempty"`
 }
 
 type DeployResponse struct {
 	Success bool   `json:"success"`
 	Message string `json:"message,omitempty"`
 	Step    string `json:"step,omitempty"`
+	// Failure 失败时的分类与处理建议
+	Failure *FailureInfo `json:"failure,omitempty"`
+	// Artifacts 本步骤上传到节点工作目录的文件
+	Artifacts []string `json:"artifacts,omitempty"`
+	// URL inSuite 应用的访问地址，仅 deploy-insuite 和 verify 步骤返回
+	URL string `json:"url,omitempty"`
+	// Digests 本步骤安装的 k3s 二进制、安装脚本和离线文件的摘要，仅 install-master 和 configure-agent 步骤返回
+	Digests []ArtifactDigest `json:"digests,omitempty"`
+	// SecretsEncryption Secret 加密状态，仅 verify 步骤和集群验证返回
+	SecretsEncryption *SecretsEncryptionStatus `json:"secretsEncryption,omitempty"`
+	// Preflight 各节点的预检结果，仅 validate 步骤返回（有节点失败时同样返回全部节点的结果）
+	Preflight []PreflightResult `json:"preflight,omitempty"`
+	// Partial 设置 continueOnError 后部分节点失败，其余节点已配置成功
+	Partial bool `json:"partial,omitempty"`
+	// Nodes 各节点的结果，仅 configure-agent 步骤返回
+	Nodes []NodeResult `json:"nodes,omitempty"`
+	// Readiness 各节点在集群中的就绪状态，仅 wait-nodes 步骤返回
+	Readiness []NodeReadiness `json:"readiness,omitempty"`
+}
+
+// ArtifactDigest 安装文件的 SHA256 及校验结果
+type ArtifactDigest struct {
+	Node   string `json:"node"`
+	Name   string `json:"name"`
+	SHA256 string `json:"sha256"`
+	// Source 期望摘要的来源，未校验时为空
+	Source   string `json:"source,omitempty"`
+	Verified bool   `json:"verified"`
+}
+
+// FailureInfo 失败分类及建议的处理方式
+type FailureInfo struct {
+	Category string `json:"category"`
+	Hint     string `json:"hint"`
 }
 
 type ErrorResponse struct {
@@ -18,3 +55,148 @@ type ErrorResponse struct {
 	Message string `json:"message"`
 	Details string `json:"details,omitempty"`
 }
+
+type KeyDistributionResult struct {
+	Name         string `json:"name"`
+	IP           string `json:"ip"`
+	Success      bool   `json:"success"`
+	Message      string `json:"message,omitempty"`
+	CredentialID string `json:"credentialId,omitempty"`
+	PublicKey    string `json:"publicKey,omitempty"`
+	Fingerprint  string `json:"fingerprint,omitempty"`
+}
+
+// SSHCAInfo SSH CA 公钥，可手动写入节点的 TrustedUserCAKeys
+type SSHCAInfo struct {
+	PublicKey   string `json:"publicKey"`
+	Fingerprint string `json:"fingerprint"`
+}
+
+// SSHCABootstrapResult 单个节点的 SSH CA 引导结果
+type SSHCABootstrapResult struct {
+	Name         string `json:"name"`
+	IP           string `json:"ip"`
+	Success      bool   `json:"success"`
+	Message      string `json:"message,omitempty"`
+	CredentialID string `json:"credentialId,omitempty"`
+}
+
+// NodeResult 多节点步骤中单个节点的执行结果
+type NodeResult struct {
+	Name    string `json:"name"`
+	IP      string `json:"ip"`
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+}
+
+// 节点就绪门控中节点的状态
+const (
+	NodeReady    = "ready"
+	NodeNotReady = "not-ready"
+	// NodeMissing 集群中既没有期望名称也没有该 IP 的节点
+	NodeMissing = "missing"
+	// NodeMisnamed 节点 IP 已注册，但名称与期望不一致
+	NodeMisnamed = "misnamed"
+	// NodeUnexpected 集群中存在、但不在请求中的节点
+	NodeUnexpected = "unexpected"
+)
+
+// NodeReadiness wait-nodes 步骤中单个节点的就绪状态
+type NodeReadiness struct {
+	// Name 请求中的节点名称，集群中多出的节点为空
+	Name string `json:"name,omitempty"`
+	IP   string `json:"ip"`
+	// ClusterName 期望的集群节点名称
+	ClusterName string `json:"clusterName,omitempty"`
+	// RegisteredAs 节点在集群中实际注册的名称
+	RegisteredAs string `json:"registeredAs,omitempty"`
+	Status       string `json:"status"`
+}
+
+// PreflightResult 单个节点的预检结果
+type PreflightResult struct {
+	Name    string `json:"name"`
+	IP      string `json:"ip"`
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+	// DurationMs 连接与检查该节点的耗时
+	DurationMs int64 `json:"durationMs"`
+}
+
+// HostsSyncResult 单个节点的 /etc/hosts 同步结果
+type HostsSyncResult struct {
+	NodeResult
+	// Entries 写入的记录数，删除时为 0
+	Entries int `json:"entries"`
+}
+
+// TuningResult 单个节点的调优结果
+type TuningResult struct {
+	NodeResult
+	// Previous 首次调优前的内核参数值，撤销时据此恢复
+	Previous map[string]string `json:"previous,omitempty"`
+}
+
+// UninstallResult 单个节点的卸载结果
+type UninstallResult struct {
+	NodeResult
+	// Uninstalled 是否执行了 k3s 卸载脚本
+	Uninstalled bool `json:"uninstalled"`
+	// Restored 已撤销的修改，按撤销顺序排列
+	Restored []NodeChange `json:"restored,omitempty"`
+	// Remaining 撤销失败、仍保留在变更日志中的修改
+	Remaining []NodeChange `json:"remaining,omitempty"`
+}
+
+// NodeServiceResult 单个节点的 k3s 服务操作结果
+type NodeServiceResult struct {
+	Name    string `json:"name"`
+	IP      string `json:"ip"`
+	Success bool   `json:"success"`
+	Message string `json:"message,omitempty"`
+	// Unit 节点上的服务：Server 为 k3s，Agent 为 k3s-agent
+	Unit string `json:"unit,omitempty"`
+	// Changed 内容有变化并已写入的配置文件
+	Changed []string `json:"changed,omitempty"`
+	// Restarted 是否重启了服务
+	Restarted bool `json:"restarted"`
+	// Logs 服务最近的日志；重启失败时为失败时的日志
+	Logs string `json:"logs,omitempty"`
+}
+
+type CredentialRotationResult struct {
+	CredentialID string `json:"credentialId"`
+	Host         string `json:"host"`
+	Mode         string `json:"mode"`
+	Success      bool   `json:"success"`
+	Message      string `json:"message,omitempty"`
+	PublicKey    string `json:"publicKey,omitempty"`
+}
+
+// StateImportResult 状态导入结果，按集合统计条数
+type StateImportResult struct {
+	Imported map[string]int `json:"imported"`
+	Skipped  map[string]int `json:"skipped"`
+}
+
+// APIResponse /api/v1 统一响应信封
+type APIResponse struct {
+	Success bool        `json:"success"`
+	Data    interface{} `json:"data,omitempty"`
+	Error   *APIError   `json:"error,omitempty"`
+}
+
+// APIError 统一错误信息
+type APIError struct {
+	Code    string `json:"code"`
+	Message string `json:"message"`
+	Details string `json:"details,omitempty"`
+}
+
+// PageResult 分页列表
+type PageResult struct {
+	Items    interface{} `json:"items"`
+	Total    int         `json:"total"`
+	Page     int         `json:"page"`
+	PageSize int         `json:"pageSize"`
+}
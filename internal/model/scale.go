@@ -0,0 +1,93 @@
+package model
+
+import "time"
+
+// 节点伸缩建议
+const (
+	ScaleNone   = "none"
+	ScaleAdd    = "add"
+	ScaleRemove = "remove"
+)
+
+// UtilizationSample 一次采集的节点与 Deployment 资源用量。CPU 单位为 millicore，内存单位为字节
+type UtilizationSample struct {
+	Time      time.Time             `json:"time"`
+	Nodes     []NodeUtilization     `json:"nodes"`
+	Workloads []WorkloadUtilization `json:"workloads"`
+}
+
+// NodeUtilization 节点用量与可分配资源
+type NodeUtilization struct {
+	Name string `json:"name"`
+	// Server 控制面节点，伸缩建议只增减 Agent 节点
+	Server            bool  `json:"server"`
+	CPUUsage          int64 `json:"cpuUsage"`
+	CPUAllocatable    int64 `json:"cpuAllocatable"`
+	MemoryUsage       int64 `json:"memoryUsage"`
+	MemoryAllocatable int64 `json:"memoryAllocatable"`
+}
+
+// WorkloadUtilization Deployment 全部运行中 Pod 的用量之和，CPURequest、MemoryRequest 为每个副本的资源请求
+type WorkloadUtilization struct {
+	Namespace     string `json:"namespace"`
+	Name          string `json:"name"`
+	Replicas      int    `json:"replicas"`
+	CPUUsage      int64  `json:"cpuUsage"`
+	MemoryUsage   int64  `json:"memoryUsage"`
+	CPURequest    int64  `json:"cpuRequest"`
+	MemoryRequest int64  `json:"memoryRequest"`
+}
+
+// ClusterUtilization 一个集群保留的用量采样，按时间先后排列
+type ClusterUtilization struct {
+	ClusterID string              `json:"clusterId"`
+	Samples   []UtilizationSample `json:"samples"`
+}
+
+// ScaleReport 根据时间窗口内持续的资源利用率给出的伸缩建议，只作参考，不会自动执行
+type ScaleReport struct {
+	ClusterID   string    `json:"clusterId"`
+	GeneratedAt time.Time `json:"generatedAt"`
+	// Window 参与分析的时间窗口，Samples 为窗口内的采样数
+	Window  string `json:"window"`
+	Samples int    `json:"samples"`
+	// ScaleUpPercent、ScaleDownPercent 持续高于或低于该利用率时建议扩容或缩容，TargetPercent 为伸缩后的目标利用率
+	ScaleUpPercent   int                     `json:"scaleUpPercent"`
+	ScaleDownPercent int                     `json:"scaleDownPercent"`
+	TargetPercent    int                     `json:"targetPercent"`
+	Nodes            NodeRecommendation      `json:"nodes"`
+	Workloads        []ReplicaRecommendation `json:"workloads"`
+}
+
+// NodeRecommendation 集群 Agent 节点数建议。利用率为全部节点用量之和占可分配资源之和的百分比
+type NodeRecommendation struct {
+	Action string `json:"action"`
+	Agents int    `json:"agents"`
+	// Count 建议增加或减少的 Agent 节点数
+	Count int `json:"count"`
+	// Candidates 建议移除时利用率最低的 Agent 节点
+	Candidates []string `json:"candidates,omitempty"`
+	// CPUPercent、MemoryPercent 窗口内利用率的最小值、平均值和最大值
+	CPUPercent    UtilizationRange `json:"cpuPercent"`
+	MemoryPercent UtilizationRange `json:"memoryPercent"`
+	Reason        string           `json:"reason"`
+}
+
+// ReplicaRecommendation Deployment 副本数建议。利用率为用量占全部副本资源请求之和的百分比
+type ReplicaRecommendation struct {
+	Namespace   string `json:"namespace"`
+	Name        string `json:"name"`
+	Replicas    int    `json:"replicas"`
+	Recommended int    `json:"recommended"`
+	// CPUPercent、MemoryPercent 未设置对应资源请求时为 nil
+	CPUPercent    *UtilizationRange `json:"cpuPercent,omitempty"`
+	MemoryPercent *UtilizationRange `json:"memoryPercent,omitempty"`
+	Reason        string            `json:"reason"`
+}
+
+// UtilizationRange 窗口内利用率（百分比）的最小值、平均值和最大值
+type UtilizationRange struct {
+	Min float64 `json:"min"`
+	Avg float64 `json:"avg"`
+	Max float64 `json:"max"`
+}
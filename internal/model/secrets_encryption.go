@@ -0,0 +1,20 @@
+package model
+
+// SecretsEncryptionStatus k3s secrets-encrypt status 报告的 Secret 静态加密状态
+type SecretsEncryptionStatus struct {
+	Enabled bool `json:"enabled"`
+	// Stage 密钥轮换阶段，未轮换过为 start，轮换完成为 reencrypt_finished
+	Stage string `json:"stage,omitempty"`
+	// HashesMatch 各 Server 节点的加密配置一致
+	HashesMatch bool `json:"hashesMatch"`
+	// Hashes 配置不一致时 k3s 报告的说明
+	Hashes string          `json:"hashes,omitempty"`
+	Keys   []EncryptionKey `json:"keys,omitempty"`
+}
+
+// EncryptionKey 加密配置中的密钥，Active 的密钥用于加密新写入的 Secret，其余只用于解密
+type EncryptionKey struct {
+	Active bool   `json:"active"`
+	Type   string `json:"type"`
+	Name   string `json:"name"`
+}
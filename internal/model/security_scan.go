@@ -0,0 +1,75 @@
+package model
+
+import "time"
+
+// 安全扫描状态
+const (
+	SecurityScanRunning   = "running"
+	SecurityScanCompleted = "completed"
+	SecurityScanFailed    = "failed"
+)
+
+// SecurityScan 集群最近一次安全扫描：各节点的 kube-bench 结果与工作负载镜像的 trivy 漏洞统计
+type SecurityScan struct {
+	ID        string `json:"id"`
+	ClusterID string `json:"clusterId"`
+	Status    string `json:"status"`
+	// Error 扫描整体失败的原因，单个节点或镜像的失败记录在对应结果中
+	Error      string            `json:"error,omitempty"`
+	StartedBy  string            `json:"startedBy,omitempty"`
+	StartedAt  time.Time         `json:"startedAt"`
+	FinishedAt *time.Time        `json:"finishedAt,omitempty"`
+	Summary    ScanSummary       `json:"summary"`
+	Nodes      []BenchNodeResult `json:"nodes,omitempty"`
+	Images     []ImageScanResult `json:"images,omitempty"`
+}
+
+// ScanSummary 扫描结果汇总
+type ScanSummary struct {
+	BenchFail int `json:"benchFail"`
+	BenchWarn int `json:"benchWarn"`
+	Critical  int `json:"critical"`
+	High      int `json:"high"`
+	// FailedTargets 未能完成扫描的节点和镜像数
+	FailedTargets int `json:"failedTargets"`
+}
+
+// BenchNodeResult 一个节点的 kube-bench 结果，Findings 只包含 FAIL 和 WARN 项
+type BenchNodeResult struct {
+	Node     string         `json:"node"`
+	Targets  string         `json:"targets"`
+	Pass     int            `json:"pass"`
+	Fail     int            `json:"fail"`
+	Warn     int            `json:"warn"`
+	Info     int            `json:"info"`
+	Error    string         `json:"error,omitempty"`
+	Findings []BenchFinding `json:"findings,omitempty"`
+}
+
+// BenchFinding kube-bench 中未通过或需人工确认的检查项
+type BenchFinding struct {
+	ID          string `json:"id"`
+	Description string `json:"description"`
+	Status      string `json:"status"`
+	Remediation string `json:"remediation,omitempty"`
+}
+
+// ImageScanResult 一个镜像的 trivy 漏洞扫描结果，Counts 按严重级别统计，Vulnerabilities 只包含 CRITICAL 和 HIGH
+type ImageScanResult struct {
+	Image string `json:"image"`
+	// Workloads 使用该镜像的工作负载，格式为 namespace/Kind/name
+	Workloads       []string             `json:"workloads"`
+	Counts          map[string]int       `json:"counts,omitempty"`
+	Error           string               `json:"error,omitempty"`
+	Vulnerabilities []ImageVulnerability `json:"vulnerabilities,omitempty"`
+}
+
+// ImageVulnerability 镜像中的一个漏洞
+type ImageVulnerability struct {
+	ID               string `json:"id"`
+	Package          string `json:"package"`
+	InstalledVersion string `json:"installedVersion"`
+	FixedVersion     string `json:"fixedVersion,omitempty"`
+	Severity         string `json:"severity"`
+	Title            string `json:"title,omitempty"`
+}
@@ -0,0 +1,32 @@
+package model
+
+import "time"
+
+// 启动自检和就绪检查的结果状态
+const (
+	SelfCheckOK   = "ok"
+	SelfCheckWarn = "warn"
+	SelfCheckFail = "fail"
+	// SelfCheckDisabled 配置关闭了启动自检
+	SelfCheckDisabled = "disabled"
+)
+
+// SelfCheckResult 一项启动自检的结果
+type SelfCheckResult struct {
+	// Name 检查项，如 data-dir、server-tls、store、mirror
+	Name string `json:"name"`
+	// Target 检查的目录、文件或地址
+	Target     string `json:"target,omitempty"`
+	Status     string `json:"status"`
+	Message    string `json:"message,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// SelfCheckReport 启动自检或就绪检查的报告：任一项失败时 Status 为 fail（启动自检失败时服务不会启动），
+// 只有警告时为 warn
+type SelfCheckReport struct {
+	Status     string            `json:"status"`
+	CheckedAt  time.Time         `json:"checkedAt"`
+	DurationMs int64             `json:"durationMs"`
+	Checks     []SelfCheckResult `json:"checks"`
+}
@@ -0,0 +1,52 @@
+package model
+
+// DeploySettings 可继承的部署设置。生效值按 全局配置 → 集群默认值 → 请求 逐级覆盖，
+// 每个字段未设置（零值或 nil）时继承上一级；任务提交时解析为生效值并随任务保存
+type DeploySettings struct {
+	// Wait 等待服务与组件就绪的超时设置
+	Wait *WaitOptions `json:"wait,omitempty"`
+	// Mirrors 国内网络环境下安装使用的镜像源
+	Mirrors *MirrorOptions `json:"mirrors,omitempty"`
+	// PreflightThresholds 预检的资源建议值，低于建议值时只告警
+	PreflightThresholds *PreflightThresholds `json:"preflightThresholds,omitempty"`
+	// Proxy 节点下载安装文件和拉取镜像使用的代理，写入 k3s 服务的环境变量
+	Proxy *ProxyOptions `json:"proxy,omitempty"`
+}
+
+// MirrorOptions 镜像源，Mirrors 为空数组表示不使用镜像加速
+type MirrorOptions struct {
+	// SystemDefault k3s 系统镜像的默认仓库（--system-default-registry）
+	SystemDefault string `json:"systemDefault,omitempty"`
+	// Mirrors docker.io 镜像加速地址
+	Mirrors []string `json:"mirrors"`
+}
+
+// PreflightThresholds 预检的资源建议值，未设置时按配置档（standard 或 edge）取内置值
+type PreflightThresholds struct {
+	CPUCores int     `json:"cpuCores,omitempty" binding:"omitempty,min=1"`
+	MemoryMB int     `json:"memoryMb,omitempty" binding:"omitempty,min=128"`
+	DiskGB   float64 `json:"diskGb,omitempty" binding:"omitempty,min=1"`
+}
+
+// ProxyOptions 节点使用的 HTTP 代理
+type ProxyOptions struct {
+	HTTPProxy  string `json:"httpProxy,omitempty"`
+	HTTPSProxy string `json:"httpsProxy,omitempty"`
+	// NoProxy 不经过代理的地址，逗号分隔（k3s 自动追加集群 Pod、Service 网段和集群域名）
+	NoProxy string `json:"noProxy,omitempty"`
+}
+
+// Env 代理的环境变量（HTTP_PROXY、HTTPS_PROXY、NO_PROXY），未设置的项不输出
+func (p *ProxyOptions) Env() []string {
+	var env []string
+	if p.HTTPProxy != "" {
+		env = append(env, "HTTP_PROXY="+p.HTTPProxy)
+	}
+	if p.HTTPSProxy != "" {
+		env = append(env, "HTTPS_PROXY="+p.HTTPSProxy)
+	}
+	if p.NoProxy != "" {
+		env = append(env, "NO_PROXY="+p.NoProxy)
+	}
+	return env
+}
@@ -0,0 +1,96 @@
+package model
+
+import "time"
+
+// 任务状态
+const (
+	TaskQueued    = "queued"
+	TaskRunning   = "running"
+	TaskSucceeded = "succeeded"
+	TaskFailed    = "failed"
+)
+
+// Task 异步部署任务
+type Task struct {
+	ID         string `json:"id"`
+	ClusterKey string `json:"clusterKey"`
+	// Nodes 任务涉及的节点地址，执行期间持有这些节点的锁
+	Nodes []string `json:"nodes,omitempty"`
+	Step  string   `json:"step"`
+	// CurrentStep 流水线任务当前执行到的步骤
+	CurrentStep string `json:"currentStep,omitempty"`
+	Status      string `json:"status"`
+	// RequestID 提交任务的 API 请求 ID
+	RequestID string `json:"requestId,omitempty"`
+	// Owner 执行该任务的后端副本
+	Owner string `json:"owner,omitempty"`
+	// QueuePosition 排队位置，从 1 开始；非排队状态为 0
+	QueuePosition int    `json:"queuePosition"`
+	Message       string `json:"message,omitempty"`
+	// Failure 任务失败时的分类与处理建议
+	Failure *FailureInfo `json:"failure,omitempty"`
+	// Artifacts 任务执行期间上传到节点工作目录的文件
+	Artifacts []string `json:"artifacts,omitempty"`
+	// URL inSuite 应用的访问地址
+	URL string `json:"url,omitempty"`
+	// Settings 提交时解析的生效部署设置，重新执行任务时沿用
+	Settings *DeploySettings `json:"settings,omitempty"`
+	// FailedNodes 设置 continueOnError 时配置失败、已从后续步骤中排除的节点
+	FailedNodes []NodeResult `json:"failedNodes,omitempty"`
+	// Logs 执行日志，仅在查询单个任务时返回
+	Logs []string `json:"logs,omitempty"`
+	// Timeline 带时间、级别、步骤和节点的执行日志，与 Logs 一一对应，仅在查询单个任务时返回
+	Timeline   []TaskLogEntry `json:"timeline,omitempty"`
+	CreatedAt  time.Time      `json:"createdAt"`
+	StartedAt  *time.Time     `json:"startedAt,omitempty"`
+	FinishedAt *time.Time     `json:"finishedAt,omitempty"`
+}
+
+// NodeConflict 提交的任务与其他集群尚未结束的任务使用了同一节点
+type NodeConflict struct {
+	IP         string `json:"ip"`
+	TaskID     string `json:"taskId"`
+	ClusterKey string `json:"clusterKey"`
+	Status     string `json:"status"`
+	// Step 冲突任务当前执行的步骤，排队中的任务为提交的步骤
+	Step string `json:"step,omitempty"`
+}
+
+// NodeConflictResponse 提交任务时节点冲突的响应（HTTP 409）
+type NodeConflictResponse struct {
+	ErrorResponse
+	Conflicts []NodeConflict `json:"conflicts"`
+}
+
+// 任务日志级别
+const (
+	LogInfo  = "info"
+	LogWarn  = "warn"
+	LogError = "error"
+)
+
+// LogLevelRank 日志级别的严重程度，未知级别返回 -1
+func LogLevelRank(level string) int {
+	switch level {
+	case LogInfo:
+		return 0
+	case LogWarn:
+		return 1
+	case LogError:
+		return 2
+	}
+	return -1
+}
+
+// TaskLogEntry 一条任务日志
+type TaskLogEntry struct {
+	// Seq 日志序号，从 1 开始；轮询时以上次收到的最大序号作为 since 增量获取
+	Seq       int       `json:"seq"`
+	Timestamp time.Time `json:"timestamp"`
+	Level     string    `json:"level"`
+	// Step 日志所属的部署步骤，任务级日志为空
+	Step string `json:"step,omitempty"`
+	// Node 日志涉及的节点名称
+	Node    string `json:"node,omitempty"`
+	Message string `json:"message"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// WebSSHTicketRequest 申请 WebSSH 连接票据，指定受管集群（连接 Master）或凭据库中的节点凭据之一
+type WebSSHTicketRequest struct {
+	ClusterID    string `json:"clusterId"`
+	CredentialID string `json:"credentialId"`
+}
+
+// WebSSHTicket 短期一次性连接票据，只保存连接目标的引用，不含节点认证信息
+type WebSSHTicket struct {
+	ID           string    `json:"id"`
+	Subject      string    `json:"subject"`
+	ClusterID    string    `json:"clusterId,omitempty"`
+	CredentialID string    `json:"credentialId,omitempty"`
+	Host         string    `json:"host"`
+	RequestID    string    `json:"requestId,omitempty"`
+	ExpiresAt    time.Time `json:"expiresAt"`
+}
+
+// WebSSHTicketResponse 返回给浏览器的票据，用于 GET /api/webssh/connect?ticket=...
+type WebSSHTicketResponse struct {
+	Ticket    string    `json:"ticket"`
+	Host      string    `json:"host"`
+	ExpiresAt time.Time `json:"expiresAt"`
+}
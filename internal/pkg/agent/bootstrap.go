@@ -0,0 +1,85 @@
+package agent
+
+import "strings"
+
+const bootstrapTemplate = `#!/bin/sh
+# k3s-deploy Agent 引导脚本：下载 Agent 并注册为 systemd 或 OpenRC 服务
+set -e
+
+AGENT_ID="{{AGENT_ID}}"
+BIN=/usr/local/bin/k3s-deploy-agent
+ENV_DIR=/etc/k3s-deploy-agent
+ENV_FILE=$ENV_DIR/agent.env
+
+# 连接密钥只写入仅 root 可读的环境文件，不出现在进程命令行和服务定义中
+mkdir -p "$ENV_DIR"
+chmod 700 "$ENV_DIR"
+(umask 077 && printf 'K3S_DEPLOY_AGENT_TOKEN=%s\n' '{{AGENT_SECRET}}' > "$ENV_FILE")
+chmod 600 "$ENV_FILE"
+
+printf 'X-Agent-Token: %s\n' '{{AGENT_SECRET}}' | curl -sfL -H @- "{{BINARY_URL}}" -o "$BIN"
+chmod 755 "$BIN"
+
+if [ ! -d /run/systemd/system ] && command -v rc-service >/dev/null 2>&1; then
+  cat > /etc/init.d/k3s-deploy-agent <<EOF
+#!/sbin/openrc-run
+description="k3s-deploy enrollment agent"
+command="$BIN"
+command_args="-server {{SERVER_URL}} -id $AGENT_ID"
+pidfile="/run/k3s-deploy-agent.pid"
+output_log="/var/log/k3s-deploy-agent.log"
+error_log="/var/log/k3s-deploy-agent.log"
+supervisor=supervise-daemon
+respawn_delay=5
+
+depend() {
+  need net
+}
+
+start_pre() {
+  set -a
+  . $ENV_FILE
+  set +a
+}
+EOF
+  chmod 755 /etc/init.d/k3s-deploy-agent
+  rc-update add k3s-deploy-agent default
+  rc-service k3s-deploy-agent restart
+  echo "k3s-deploy-agent 已启动，Agent ID: $AGENT_ID"
+  exit 0
+fi
+
+cat > /etc/systemd/system/k3s-deploy-agent.service <<EOF
+[Unit]
+Description=k3s-deploy enrollment agent
+After=network-online.target
+Wants=network-online.target
+
+[Service]
+EnvironmentFile=$ENV_FILE
+ExecStart=$BIN -server {{SERVER_URL}} -id $AGENT_ID
+Restart=always
+RestartSec=5
+
+[Install]
+WantedBy=multi-user.target
+EOF
+
+systemctl daemon-reload
+systemctl enable k3s-deploy-agent
+systemctl restart k3s-deploy-agent
+echo "k3s-deploy-agent 已启动，Agent ID: $AGENT_ID"
+`
+
+// BootstrapScript 生成节点一键引导脚本
+// serverURL 为后端 WebSocket 基地址（ws:// 或 wss://），binaryURL 为 Agent 二进制下载地址，
+// agentID 需经 ValidAgentID 校验，secret 为该 Agent 的连接密钥（见 Hub.AgentSecret）
+func BootstrapScript(serverURL, binaryURL, agentID, secret string) string {
+	r := strings.NewReplacer(
+		"{{SERVER_URL}}", serverURL,
+		"{{BINARY_URL}}", binaryURL,
+		"{{AGENT_ID}}", agentID,
+		"{{AGENT_SECRET}}", secret,
+	)
+	return r.Replace(bootstrapTemplate)
+}
@@ -0,0 +1,282 @@
+package agent
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"regexp"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+	"k3s-deploy-backend/internal/pkg/logger"
+)
+
+const (
+	// pingInterval 控制通道的心跳间隔，pongWait 内没有收到响应时断开，
+	// 失联的连接及时释放 Agent ID，Agent 重连时不会被当作重复连接拒绝
+	pingInterval = 30 * time.Second
+	pongWait     = 75 * time.Second
+)
+
+// ErrAgentConnected 同一 Agent ID 已有在线的控制通道
+var ErrAgentConnected = errors.New("该 Agent ID 已连接")
+
+// agentIDPattern Agent ID 会写入引导脚本和服务定义，只允许安全字符
+var agentIDPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._-]{0,62}$`)
+
+// ValidAgentID 检查 Agent ID 格式
+func ValidAgentID(id string) bool {
+	return agentIDPattern.MatchString(id)
+}
+
+// Hub 管理节点 Agent 发起的反向连接。
+// 每个 Agent 维持一条控制通道；后端需要访问节点时通过控制通道下发 dial 指令，
+// Agent 再主动建立一条隧道连接并转发到本机 sshd，从而替代入站的 ssh.Dial。
+// 注册令牌只用于生成引导脚本，每个 Agent 使用由注册令牌和 Agent ID 派生的独立密钥连接，
+// 持有某个节点密钥的人不能以其他 Agent ID 连接
+type Hub struct {
+	token    string
+	logger   *logger.Logger
+	upgrader websocket.Upgrader
+
+	mu      sync.Mutex
+	agents  map[string]*session
+	pending map[string]*pendingTunnel
+}
+
+// pendingTunnel 已下发 dial 指令、等待 Agent 建立的隧道，只接受该 Agent 的连接
+type pendingTunnel struct {
+	agentID string
+	ch      chan net.Conn
+}
+
+type session struct {
+	info    AgentInfo
+	ws      *websocket.Conn
+	writeMu sync.Mutex
+}
+
+func (s *session) send(msg Message) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+	return s.ws.WriteJSON(msg)
+}
+
+func NewHub(token string, logger *logger.Logger) *Hub {
+	return &Hub{
+		token:  token,
+		logger: logger,
+		upgrader: websocket.Upgrader{
+			ReadBufferSize:  32 * 1024,
+			WriteBufferSize: 32 * 1024,
+			// Agent 不是浏览器，不需要校验 Origin
+			CheckOrigin: func(r *http.Request) bool { return true },
+		},
+		agents:  make(map[string]*session),
+		pending: make(map[string]*pendingTunnel),
+	}
+}
+
+// Authorize 校验注册令牌，用于生成引导脚本
+func (h *Hub) Authorize(token string) bool {
+	if h.token == "" || token == "" {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(h.token), []byte(token)) == 1
+}
+
+// AgentSecret 返回 Agent 的连接密钥，由注册令牌和 Agent ID 派生，更换注册令牌后全部失效
+func (h *Hub) AgentSecret(agentID string) string {
+	mac := hmac.New(sha256.New, []byte(h.token))
+	mac.Write([]byte("k3s-deploy-agent:" + agentID))
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// AuthorizeAgent 校验 Agent 的连接密钥与其 ID 是否匹配
+func (h *Hub) AuthorizeAgent(agentID, secret string) bool {
+	if h.token == "" || secret == "" || !ValidAgentID(agentID) {
+		return false
+	}
+	return subtle.ConstantTimeCompare([]byte(h.AgentSecret(agentID)), []byte(secret)) == 1
+}
+
+// Connected 返回 Agent ID 是否已有在线的控制通道
+func (h *Hub) Connected(agentID string) bool {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	_, exists := h.agents[agentID]
+	return exists
+}
+
+// ServeControl 处理已认证为 agentID 的 Agent 的控制通道连接。
+// 握手中的 Agent ID 必须与认证的 ID 一致；该 ID 已有在线连接时拒绝，返回 ErrAgentConnected
+func (h *Hub) ServeControl(w http.ResponseWriter, r *http.Request, agentID string) error {
+	ws, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return fmt.Errorf("升级WebSocket失败: %v", err)
+	}
+
+	var hello Message
+	ws.SetReadDeadline(time.Now().Add(10 * time.Second))
+	if err := ws.ReadJSON(&hello); err != nil || hello.Type != MessageHello || hello.AgentID == "" {
+		ws.Close()
+		return fmt.Errorf("Agent握手失败: %v", err)
+	}
+	if hello.AgentID != agentID {
+		ws.Close()
+		return fmt.Errorf("Agent握手失败: 握手中的 ID %q 与认证的 ID %q 不一致", hello.AgentID, agentID)
+	}
+
+	s := &session{
+		info: AgentInfo{
+			ID:          hello.AgentID,
+			Hostname:    hello.Hostname,
+			RemoteAddr:  r.RemoteAddr,
+			ConnectedAt: time.Now().Format(time.RFC3339),
+		},
+		ws: ws,
+	}
+
+	h.mu.Lock()
+	if _, exists := h.agents[hello.AgentID]; exists {
+		h.mu.Unlock()
+		ws.WriteControl(websocket.CloseMessage, websocket.FormatCloseMessage(websocket.ClosePolicyViolation, "agent id already connected"), time.Now().Add(time.Second))
+		ws.Close()
+		return fmt.Errorf("Agent %s 来自 %s 的连接被拒绝: %w", hello.AgentID, r.RemoteAddr, ErrAgentConnected)
+	}
+	h.agents[hello.AgentID] = s
+	h.mu.Unlock()
+
+	h.logger.Infof("Agent %s (%s) 已连接，来源 %s", hello.AgentID, hello.Hostname, r.RemoteAddr)
+
+	ws.SetReadDeadline(time.Now().Add(pongWait))
+	ws.SetPongHandler(func(string) error {
+		return ws.SetReadDeadline(time.Now().Add(pongWait))
+	})
+	stopPing := make(chan struct{})
+	go func() {
+		ticker := time.NewTicker(pingInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-ticker.C:
+				if err := ws.WriteControl(websocket.PingMessage, nil, time.Now().Add(10*time.Second)); err != nil {
+					return
+				}
+			case <-stopPing:
+				return
+			}
+		}
+	}()
+
+	// 读循环：仅用于感知断开和接收心跳
+	for {
+		var msg Message
+		if err := ws.ReadJSON(&msg); err != nil {
+			break
+		}
+		ws.SetReadDeadline(time.Now().Add(pongWait))
+	}
+	close(stopPing)
+
+	h.mu.Lock()
+	if h.agents[hello.AgentID] == s {
+		delete(h.agents, hello.AgentID)
+	}
+	h.mu.Unlock()
+	ws.Close()
+
+	h.logger.Warnf("Agent %s 已断开", hello.AgentID)
+	return nil
+}
+
+// ServeTunnel 处理 Agent 响应 dial 指令建立的隧道连接，隧道只能由收到指令的 Agent 建立
+func (h *Hub) ServeTunnel(w http.ResponseWriter, r *http.Request, agentID, tunnelID string) error {
+	h.mu.Lock()
+	pending, exists := h.pending[tunnelID]
+	if exists && pending.agentID == agentID {
+		delete(h.pending, tunnelID)
+	} else {
+		exists = false
+	}
+	h.mu.Unlock()
+
+	if !exists {
+		http.Error(w, "unknown tunnel", http.StatusNotFound)
+		return fmt.Errorf("未知的隧道: %s", tunnelID)
+	}
+
+	ws, err := h.upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return fmt.Errorf("升级WebSocket失败: %v", err)
+	}
+
+	pending.ch <- NewConn(ws)
+	return nil
+}
+
+// Dial 通过指定 Agent 建立到节点 sshd 的连接，可作为 ssh 包的自定义传输层
+func (h *Hub) Dial(agentID string, timeout time.Duration) (net.Conn, error) {
+	h.mu.Lock()
+	s, exists := h.agents[agentID]
+	h.mu.Unlock()
+	if !exists {
+		return nil, fmt.Errorf("Agent %s 未连接", agentID)
+	}
+
+	tunnelID, err := newTunnelID()
+	if err != nil {
+		return nil, err
+	}
+
+	ch := make(chan net.Conn, 1)
+	h.mu.Lock()
+	h.pending[tunnelID] = &pendingTunnel{agentID: agentID, ch: ch}
+	h.mu.Unlock()
+
+	if err := s.send(Message{Type: MessageDial, TunnelID: tunnelID}); err != nil {
+		h.mu.Lock()
+		delete(h.pending, tunnelID)
+		h.mu.Unlock()
+		return nil, fmt.Errorf("向Agent %s 下发连接指令失败: %v", agentID, err)
+	}
+
+	select {
+	case conn := <-ch:
+		return conn, nil
+	case <-time.After(timeout):
+		h.mu.Lock()
+		delete(h.pending, tunnelID)
+		h.mu.Unlock()
+		return nil, fmt.Errorf("等待Agent %s 建立隧道超时", agentID)
+	}
+}
+
+// Agents 返回当前在线的 Agent 列表
+func (h *Hub) Agents() []AgentInfo {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	agents := make([]AgentInfo, 0, len(h.agents))
+	for _, s := range h.agents {
+		agents = append(agents, s.info)
+	}
+	sort.Slice(agents, func(i, j int) bool { return agents[i].ID < agents[j].ID })
+	return agents
+}
+
+func newTunnelID() (string, error) {
+	buf := make([]byte, 16)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成隧道ID失败: %v", err)
+	}
+	return hex.EncodeToString(buf), nil
+}
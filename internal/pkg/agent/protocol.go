@@ -0,0 +1,24 @@
+package agent
+
+// 控制通道消息类型
+const (
+	MessageHello = "hello"
+	MessageDial  = "dial"
+	MessagePing  = "ping"
+)
+
+// Message 控制通道上传输的 JSON 消息
+type Message struct {
+	Type     string `json:"type"`
+	AgentID  string `json:"agentId,omitempty"`
+	Hostname string `json:"hostname,omitempty"`
+	TunnelID string `json:"tunnelId,omitempty"`
+}
+
+// AgentInfo 已注册 Agent 的概要信息
+type AgentInfo struct {
+	ID          string `json:"id"`
+	Hostname    string `json:"hostname"`
+	RemoteAddr  string `json:"remoteAddr"`
+	ConnectedAt string `json:"connectedAt"`
+}
@@ -0,0 +1,76 @@
+package auth
+
+import (
+	"crypto/x509"
+	"errors"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+// ErrInvalidCredentials 用户名或密码错误
+var ErrInvalidCredentials = errors.New("用户名或密码错误")
+
+// Identity 身份提供方认证通过的用户
+type Identity struct {
+	Subject  string
+	Name     string
+	Provider string
+	// Groups 用户所属组，用于映射角色
+	Groups []string
+	// Roles 直接指定的角色（本地用户）
+	Roles []string
+}
+
+// CertificateIdentity 从已校验的客户端证书中提取用户：CN 为用户名，
+// O 字段为用户组，其中 admin/operator/viewer 直接作为角色
+func CertificateIdentity(cert *x509.Certificate) *Identity {
+	identity := &Identity{
+		Subject:  "cert:" + cert.Subject.CommonName,
+		Name:     cert.Subject.CommonName,
+		Provider: "mtls",
+		Groups:   cert.Subject.Organization,
+	}
+	for _, group := range cert.Subject.Organization {
+		if ValidRole(group) {
+			identity.Roles = append(identity.Roles, group)
+		}
+	}
+	return identity
+}
+
+// LocalUser 配置文件中的本地用户，密码为 bcrypt 哈希
+type LocalUser struct {
+	Username     string
+	PasswordHash string
+	Roles        []string
+}
+
+// LocalProvider 本地用户认证
+type LocalProvider struct {
+	users map[string]LocalUser
+}
+
+func NewLocalProvider(users []LocalUser) *LocalProvider {
+	p := &LocalProvider{users: make(map[string]LocalUser, len(users))}
+	for _, u := range users {
+		p.users[u.Username] = u
+	}
+	return p
+}
+
+// Authenticate 校验本地用户密码，用户不存在时返回 ErrInvalidCredentials
+func (p *LocalProvider) Authenticate(username, password string) (*Identity, error) {
+	user, ok := p.users[username]
+	if !ok {
+		return nil, ErrInvalidCredentials
+	}
+	if err := bcrypt.CompareHashAndPassword([]byte(user.PasswordHash), []byte(password)); err != nil {
+		return nil, ErrInvalidCredentials
+	}
+	return &Identity{
+		Subject:  "local:" + username,
+		Name:     username,
+		Provider: "local",
+		Roles:    user.Roles,
+	}, nil
+}
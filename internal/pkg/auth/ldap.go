@@ -0,0 +1,115 @@
+package auth
+
+import (
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"time"
+
+	"github.com/go-ldap/ldap/v3"
+)
+
+// LDAPOptions LDAP 认证配置。登录时先用服务账号查找用户 DN，再以用户身份绑定校验密码
+type LDAPOptions struct {
+	// URL ldap://host:389 或 ldaps://host:636
+	URL          string
+	BindDN       string
+	BindPassword string
+	BaseDN       string
+	// UserAttribute 登录名对应的属性，如 uid、sAMAccountName
+	UserAttribute string
+	// GroupAttribute 用户所属组的属性，如 memberOf
+	GroupAttribute string
+	// InsecureSkipVerify ldaps 不校验服务端证书，仅用于测试环境
+	InsecureSkipVerify bool
+}
+
+// LDAPProvider 基于简单绑定的 LDAP 认证
+type LDAPProvider struct {
+	opts LDAPOptions
+}
+
+func NewLDAPProvider(opts LDAPOptions) *LDAPProvider {
+	if opts.UserAttribute == "" {
+		opts.UserAttribute = "uid"
+	}
+	if opts.GroupAttribute == "" {
+		opts.GroupAttribute = "memberOf"
+	}
+	return &LDAPProvider{opts: opts}
+}
+
+// Authenticate 校验用户名和密码，返回用户 DN 和所属组
+func (p *LDAPProvider) Authenticate(username, password string) (*Identity, error) {
+	// 空密码在 LDAP 中是匿名绑定，会被服务端直接接受
+	if username == "" || password == "" {
+		return nil, ErrInvalidCredentials
+	}
+
+	conn, err := p.dial()
+	if err != nil {
+		return nil, fmt.Errorf("连接 LDAP 服务器失败: %w", err)
+	}
+	defer conn.Close()
+
+	if err := conn.Bind(p.opts.BindDN, p.opts.BindPassword); err != nil {
+		return nil, fmt.Errorf("LDAP 服务账号绑定失败: %w", err)
+	}
+
+	// sizeLimit 为 2：多于一个结果即视为不唯一
+	result, err := conn.Search(ldap.NewSearchRequest(
+		p.opts.BaseDN, ldap.ScopeWholeSubtree, ldap.NeverDerefAliases, 2, 10, false,
+		fmt.Sprintf("(%s=%s)", p.opts.UserAttribute, ldap.EscapeFilter(username)),
+		[]string{p.opts.GroupAttribute}, nil,
+	))
+	if ldap.IsErrorWithCode(err, ldap.LDAPResultSizeLimitExceeded) {
+		return nil, ErrInvalidCredentials
+	}
+	if err != nil {
+		return nil, fmt.Errorf("查找 LDAP 用户失败: %w", err)
+	}
+	if len(result.Entries) != 1 {
+		return nil, ErrInvalidCredentials
+	}
+	entry := result.Entries[0]
+
+	if err := conn.Bind(entry.DN, password); err != nil {
+		// 服务端返回的结果码（密码错误、账号锁定等）均视为认证失败，200 以上为客户端错误
+		var ldapErr *ldap.Error
+		if errors.As(err, &ldapErr) && ldapErr.ResultCode < ldap.ErrorNetwork {
+			return nil, ErrInvalidCredentials
+		}
+		return nil, err
+	}
+
+	return &Identity{
+		Subject:  "ldap:" + entry.DN,
+		Name:     username,
+		Provider: "ldap",
+		Groups:   entry.GetAttributeValues(p.opts.GroupAttribute),
+	}, nil
+}
+
+func (p *LDAPProvider) dial() (*ldap.Conn, error) {
+	u, err := url.Parse(p.opts.URL)
+	if err != nil {
+		return nil, err
+	}
+	if u.Scheme != "ldap" && u.Scheme != "ldaps" {
+		return nil, fmt.Errorf("不支持的 LDAP 协议: %s", u.Scheme)
+	}
+
+	conn, err := ldap.DialURL(p.opts.URL,
+		ldap.DialWithDialer(&net.Dialer{Timeout: 10 * time.Second}),
+		ldap.DialWithTLSConfig(&tls.Config{
+			ServerName:         u.Hostname(),
+			InsecureSkipVerify: p.opts.InsecureSkipVerify,
+		}))
+	if err != nil {
+		return nil, err
+	}
+	conn.SetTimeout(30 * time.Second)
+	return conn, nil
+}
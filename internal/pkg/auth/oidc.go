@@ -0,0 +1,177 @@
+package auth
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/coreos/go-oidc/v3/oidc"
+	"golang.org/x/oauth2"
+)
+
+// OIDCOptions OIDC 身份提供方配置
+type OIDCOptions struct {
+	Issuer       string
+	ClientID     string
+	ClientSecret string
+	RedirectURL  string
+	Scopes       []string
+	// GroupsClaim id_token 中携带用户组的字段名
+	GroupsClaim string
+}
+
+// OIDCProvider OIDC 授权码模式登录，使用 PKCE（S256）并校验 id_token 的签名、签发方、受众、有效期和 nonce
+type OIDCProvider struct {
+	opts   OIDCOptions
+	client *http.Client
+
+	mu       sync.Mutex
+	oauth    *oauth2.Config
+	verifier *oidc.IDTokenVerifier
+}
+
+// OIDCLogin 一次 OIDC 登录的 state、nonce 和 PKCE code_verifier，发起登录时生成，回调时用于校验
+type OIDCLogin struct {
+	State    string
+	Nonce    string
+	Verifier string
+}
+
+// NewOIDCLogin 生成一次登录使用的随机值
+func NewOIDCLogin() (*OIDCLogin, error) {
+	var values [2]string
+	for i := range values {
+		buf := make([]byte, 16)
+		if _, err := rand.Read(buf); err != nil {
+			return nil, fmt.Errorf("生成登录状态失败: %v", err)
+		}
+		values[i] = hex.EncodeToString(buf)
+	}
+	return &OIDCLogin{State: values[0], Nonce: values[1], Verifier: oauth2.GenerateVerifier()}, nil
+}
+
+// Encode 编码为 Cookie 值，各字段均不含 "."
+func (l *OIDCLogin) Encode() string {
+	return l.State + "." + l.Nonce + "." + l.Verifier
+}
+
+// ParseOIDCLogin 解析 Encode 的结果
+func ParseOIDCLogin(value string) (*OIDCLogin, error) {
+	parts := strings.Split(value, ".")
+	if len(parts) != 3 || parts[0] == "" || parts[1] == "" || parts[2] == "" {
+		return nil, errors.New("登录状态格式无效")
+	}
+	return &OIDCLogin{State: parts[0], Nonce: parts[1], Verifier: parts[2]}, nil
+}
+
+func NewOIDCProvider(opts OIDCOptions) *OIDCProvider {
+	if len(opts.Scopes) == 0 {
+		opts.Scopes = []string{"openid", "profile", "email", "groups"}
+	}
+	if opts.GroupsClaim == "" {
+		opts.GroupsClaim = "groups"
+	}
+	return &OIDCProvider{
+		opts:   opts,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+// AuthURL 返回跳转到身份提供方的登录地址
+func (p *OIDCProvider) AuthURL(login *OIDCLogin) (string, error) {
+	ctx, cancel := p.context()
+	defer cancel()
+	cfg, _, err := p.discover(ctx)
+	if err != nil {
+		return "", err
+	}
+	return cfg.AuthCodeURL(login.State, oidc.Nonce(login.Nonce), oauth2.S256ChallengeOption(login.Verifier)), nil
+}
+
+// Exchange 用授权码和 PKCE code_verifier 换取 id_token 并校验，返回登录用户
+func (p *OIDCProvider) Exchange(code string, login *OIDCLogin) (*Identity, error) {
+	ctx, cancel := p.context()
+	defer cancel()
+	cfg, verifier, err := p.discover(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	token, err := cfg.Exchange(ctx, code, oauth2.VerifierOption(login.Verifier))
+	if err != nil {
+		return nil, fmt.Errorf("请求令牌接口失败: %w", err)
+	}
+	rawIDToken, _ := token.Extra("id_token").(string)
+	if rawIDToken == "" {
+		return nil, errors.New("令牌响应中缺少 id_token")
+	}
+	idToken, err := verifier.Verify(ctx, rawIDToken)
+	if err != nil {
+		return nil, fmt.Errorf("id_token 校验失败: %w", err)
+	}
+	if idToken.Nonce != login.Nonce {
+		return nil, errors.New("id_token nonce 不匹配")
+	}
+
+	var claims map[string]interface{}
+	if err := idToken.Claims(&claims); err != nil {
+		return nil, fmt.Errorf("解析 id_token 失败: %w", err)
+	}
+	if idToken.Subject == "" {
+		return nil, errors.New("id_token 缺少 sub")
+	}
+	identity := &Identity{Subject: "oidc:" + idToken.Subject, Name: idToken.Subject, Provider: "oidc"}
+	for _, key := range []string{"preferred_username", "email", "name"} {
+		if name, ok := claims[key].(string); ok && name != "" {
+			identity.Name = name
+			break
+		}
+	}
+	switch groups := claims[p.opts.GroupsClaim].(type) {
+	case []interface{}:
+		for _, g := range groups {
+			if s, ok := g.(string); ok {
+				identity.Groups = append(identity.Groups, s)
+			}
+		}
+	case string:
+		identity.Groups = []string{groups}
+	}
+	return identity, nil
+}
+
+// context 身份提供方请求使用带超时的 HTTP 客户端
+func (p *OIDCProvider) context() (context.Context, context.CancelFunc) {
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	return oidc.ClientContext(ctx, p.client), cancel
+}
+
+// discover 读取并缓存 .well-known/openid-configuration，失败时下次登录重试
+func (p *OIDCProvider) discover(ctx context.Context) (*oauth2.Config, *oidc.IDTokenVerifier, error) {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	if p.oauth != nil {
+		return p.oauth, p.verifier, nil
+	}
+
+	// JWKS 在 provider 生命周期内按需刷新，不能绑定单次请求的 context
+	provider, err := oidc.NewProvider(oidc.ClientContext(context.Background(), p.client), p.opts.Issuer)
+	if err != nil {
+		return nil, nil, fmt.Errorf("读取 OIDC 发现文档失败: %w", err)
+	}
+	p.oauth = &oauth2.Config{
+		ClientID:     p.opts.ClientID,
+		ClientSecret: p.opts.ClientSecret,
+		RedirectURL:  p.opts.RedirectURL,
+		Endpoint:     provider.Endpoint(),
+		Scopes:       p.opts.Scopes,
+	}
+	p.verifier = provider.Verifier(&oidc.Config{ClientID: p.opts.ClientID})
+	return p.oauth, p.verifier, nil
+}
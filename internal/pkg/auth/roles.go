@@ -0,0 +1,109 @@
+package auth
+
+import (
+	"net/http"
+	"slices"
+	"strings"
+)
+
+// 角色
+const (
+	// RoleAdmin 全部权限，包括凭据和后端状态管理
+	RoleAdmin = "admin"
+	// RoleOperator 可执行部署和集群运维操作
+	RoleOperator = "operator"
+	// RoleViewer 只读
+	RoleViewer = "viewer"
+)
+
+// ValidRole 判断角色名是否有效
+func ValidRole(role string) bool {
+	return role == RoleAdmin || role == RoleOperator || role == RoleViewer
+}
+
+// adminPaths 只有管理员可以修改的资源，/tasks/takeover 会中止其他任务
+var adminPaths = []string{"/credentials", "/state", "/tasks/takeover"}
+
+// adminReadPaths 包含集群管理员凭据、对象存储根密钥、join token 或节点命令原文，只有管理员可以访问的资源。
+// * 匹配任意一段路径，如集群 ID
+var adminReadPaths = []string{"/kubeconfig", "/join-bundles", "/clusters/*/object-store", "/tasks/*/transcript"}
+
+// publicReadPaths 位于 adminReadPaths 之下、任何已登录用户都可以读取的资源
+var publicReadPaths = []string{"/join-bundles/public-key"}
+
+// Allowed 判断角色是否可以访问指定接口。path 为去掉 /api 或 /api/v1 前缀后的路径
+func Allowed(roles []string, method, path string) bool {
+	required := RoleViewer
+	for _, prefix := range adminReadPaths {
+		if matchPrefix(prefix, path) && !slices.Contains(publicReadPaths, path) {
+			required = RoleAdmin
+		}
+	}
+	if required != RoleAdmin && method != http.MethodGet && method != http.MethodHead && method != http.MethodOptions {
+		required = RoleOperator
+		for _, prefix := range adminPaths {
+			if strings.HasPrefix(path, prefix) {
+				required = RoleAdmin
+			}
+		}
+	}
+
+	for _, role := range roles {
+		switch {
+		case role == RoleAdmin:
+			return true
+		case role == RoleOperator && required != RoleAdmin:
+			return true
+		case role == RoleViewer && required == RoleViewer:
+			return true
+		}
+	}
+	return false
+}
+
+// matchPrefix 判断 path 是否以 pattern 开头，pattern 中的 * 匹配任意一段非空路径
+func matchPrefix(pattern, path string) bool {
+	if !strings.Contains(pattern, "*") {
+		return strings.HasPrefix(path, pattern)
+	}
+	want, got := strings.Split(pattern, "/"), strings.Split(path, "/")
+	if len(got) < len(want) {
+		return false
+	}
+	for i, segment := range want {
+		if segment == "*" && got[i] != "" || segment == got[i] {
+			continue
+		}
+		return false
+	}
+	return true
+}
+
+// MapGroups 按组与角色的映射计算用户角色。组名既可以按完整值匹配，
+// 也可以按 LDAP DN 的第一个 RDN 值匹配（cn=k3s-admins,ou=groups,... 匹配 k3s-admins）；
+// 没有任何组命中时使用 defaultRole，defaultRole 为空表示拒绝登录
+func MapGroups(groups []string, mapping map[string]string, defaultRole string) []string {
+	seen := make(map[string]bool)
+	var roles []string
+	add := func(role string) {
+		if role != "" && !seen[role] {
+			seen[role] = true
+			roles = append(roles, role)
+		}
+	}
+
+	for _, group := range groups {
+		if role, ok := mapping[group]; ok {
+			add(role)
+			continue
+		}
+		if rdn, _, _ := strings.Cut(group, ","); strings.Contains(rdn, "=") {
+			_, value, _ := strings.Cut(rdn, "=")
+			add(mapping[value])
+		}
+	}
+	if len(roles) == 0 {
+		add(defaultRole)
+	}
+	return roles
+}
@@ -0,0 +1,100 @@
+package auth
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// ErrInvalidToken 会话令牌无效或已过期
+var ErrInvalidToken = errors.New("会话令牌无效或已过期")
+
+// Claims 会话令牌中的用户信息
+type Claims struct {
+	Subject   string   `json:"sub"`
+	Name      string   `json:"name"`
+	Provider  string   `json:"provider"`
+	Roles     []string `json:"roles"`
+	ExpiresAt int64    `json:"exp"`
+}
+
+// Signer 使用 HMAC-SHA256 签发和校验会话令牌，格式为 base64(claims).base64(signature)
+type Signer struct {
+	secret []byte
+}
+
+func NewSigner(secret []byte) *Signer {
+	return &Signer{secret: secret}
+}
+
+// Sign 签发令牌
+func (s *Signer) Sign(claims Claims) (string, error) {
+	payload, err := json.Marshal(claims)
+	if err != nil {
+		return "", err
+	}
+	encoded := base64.RawURLEncoding.EncodeToString(payload)
+	return encoded + "." + s.signature(encoded), nil
+}
+
+// Verify 校验签名和有效期
+func (s *Signer) Verify(token string) (*Claims, error) {
+	encoded, signature, ok := strings.Cut(token, ".")
+	if !ok || !hmac.Equal([]byte(signature), []byte(s.signature(encoded))) {
+		return nil, ErrInvalidToken
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, ErrInvalidToken
+	}
+	var claims Claims
+	if err := json.Unmarshal(payload, &claims); err != nil {
+		return nil, ErrInvalidToken
+	}
+	if time.Now().Unix() >= claims.ExpiresAt {
+		return nil, ErrInvalidToken
+	}
+	return &claims, nil
+}
+
+func (s *Signer) signature(data string) string {
+	mac := hmac.New(sha256.New, s.secret)
+	mac.Write([]byte(data))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
+}
+
+// LoadOrCreateSecret 读取会话签名密钥文件，不存在时生成。多副本部署应在配置中使用相同的密钥
+func LoadOrCreateSecret(path string) ([]byte, error) {
+	data, err := os.ReadFile(path)
+	if err == nil {
+		secret, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(secret) < 32 {
+			return nil, fmt.Errorf("会话密钥文件 %s 格式无效", path)
+		}
+		return secret, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取会话密钥文件失败: %w", err)
+	}
+
+	secret := make([]byte, 32)
+	if _, err := rand.Read(secret); err != nil {
+		return nil, fmt.Errorf("生成会话密钥失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("创建密钥目录失败: %w", err)
+	}
+	if err := os.WriteFile(path, []byte(base64.StdEncoding.EncodeToString(secret)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("写入会话密钥文件失败: %w", err)
+	}
+	return secret, nil
+}
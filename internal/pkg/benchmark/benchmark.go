@@ -0,0 +1,96 @@
+// Package benchmark 按请求记录部署各阶段（预检、下载、安装、等待服务、验证）在每个节点上的耗时。
+// 只有调用 Begin 开始记录的请求才会计时，其他请求的 Track 不做任何事
+package benchmark
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+)
+
+type timingKey struct {
+	node  string
+	phase string
+}
+
+// run 一个请求记录中的计时与运行设置
+type run struct {
+	timings  map[timingKey]time.Duration
+	settings map[string]string
+}
+
+var (
+	mu   sync.Mutex
+	runs = map[string]*run{}
+)
+
+// Begin 开始记录请求的阶段耗时
+func Begin(requestID string) {
+	if requestID == "" {
+		return
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	runs[requestID] = &run{
+		timings:  make(map[timingKey]time.Duration),
+		settings: make(map[string]string),
+	}
+}
+
+// Track 开始计时，调用返回的函数结束计时。同一节点同一阶段多次计时（如重试）时累加，
+// node 为空表示集群级阶段
+func Track(requestID, node, phase string) func() {
+	if !recording(requestID) {
+		return func() {}
+	}
+	start := time.Now()
+	return func() {
+		elapsed := time.Since(start)
+		mu.Lock()
+		defer mu.Unlock()
+		if r, ok := runs[requestID]; ok {
+			r.timings[timingKey{node, phase}] += elapsed
+		}
+	}
+}
+
+// Annotate 记录影响耗时的运行设置，如实际使用的安装源和镜像加速地址
+func Annotate(requestID, key, value string) {
+	mu.Lock()
+	defer mu.Unlock()
+	if r, ok := runs[requestID]; ok {
+		r.settings[key] = value
+	}
+}
+
+// End 结束记录，返回按阶段、节点排序的耗时和记录的运行设置
+func End(requestID string) ([]model.PhaseTiming, map[string]string) {
+	mu.Lock()
+	r, ok := runs[requestID]
+	delete(runs, requestID)
+	mu.Unlock()
+	if !ok {
+		return nil, nil
+	}
+
+	timings := make([]model.PhaseTiming, 0, len(r.timings))
+	for key, d := range r.timings {
+		timings = append(timings, model.PhaseTiming{Node: key.node, Phase: key.phase, DurationMs: d.Milliseconds()})
+	}
+	sort.Slice(timings, func(i, j int) bool {
+		if pi, pj := model.PhaseOrder(timings[i].Phase), model.PhaseOrder(timings[j].Phase); pi != pj {
+			return pi < pj
+		}
+		return timings[i].Node < timings[j].Node
+	})
+	return timings, r.settings
+}
+
+func recording(requestID string) bool {
+	mu.Lock()
+	defer mu.Unlock()
+	_, ok := runs[requestID]
+	return ok
+}
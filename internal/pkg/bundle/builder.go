@@ -0,0 +1,226 @@
+package bundle
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"time"
+)
+
+// k3sVersionPattern k3s 发布版本，如 v1.30.4+k3s1
+var k3sVersionPattern = regexp.MustCompile(`^v\d+\.\d+\.\d+\+k3s\d+$`)
+
+// Options 安装包内容
+type Options struct {
+	K3sVersion string
+	Arch       string
+	// Addons 附加组件名称，仅记录在清单中
+	Addons []string
+	// Images 需要打包的镜像引用
+	Images []string
+	// Charts Chart 名称 -> .tgz 下载地址
+	Charts map[string]string
+	// Manifests 清单名称 -> YAML 下载地址
+	Manifests map[string]string
+	// Packages 系统补丁软件包文件名 -> 下载地址
+	Packages map[string]string
+	// PackageKeys 软件包签名公钥文件名 -> 下载地址
+	PackageKeys map[string]string
+}
+
+// Builder 在可联网的机器上下载文件并生成安装包
+type Builder struct {
+	dir    string
+	client *http.Client
+	logf   func(format string, args ...any)
+}
+
+func NewBuilder(dir string, logf func(format string, args ...any)) *Builder {
+	return &Builder{
+		dir:    dir,
+		client: &http.Client{Timeout: 30 * time.Minute},
+		logf:   logf,
+	}
+}
+
+// Build 下载全部文件到安装包目录，k3s 二进制和离线镜像按官方 sha256sum 文件校验，最后写入签名清单
+func (b *Builder) Build(opts Options, key ed25519.PrivateKey) (*Manifest, error) {
+	if !k3sVersionPattern.MatchString(opts.K3sVersion) {
+		return nil, fmt.Errorf("无效的 k3s 版本: %s（如 v1.30.4+k3s1）", opts.K3sVersion)
+	}
+	binary, ok := BinaryName(opts.Arch)
+	if !ok {
+		return nil, fmt.Errorf("不支持的架构: %s（可选 amd64、arm64、arm）", opts.Arch)
+	}
+	if err := os.MkdirAll(b.dir, 0755); err != nil {
+		return nil, err
+	}
+
+	manifest := &Manifest{
+		FormatVersion: formatVersion,
+		K3sVersion:    opts.K3sVersion,
+		Arch:          opts.Arch,
+		Addons:        opts.Addons,
+		CreatedAt:     time.Now().UTC(),
+	}
+
+	release := releaseBase("", opts.K3sVersion)
+	checksums, err := fetchChecksums(b.client, release+"sha256sum-"+opts.Arch+".txt")
+	if err != nil {
+		return nil, err
+	}
+
+	imagesName := "k3s-airgap-images-" + opts.Arch + ".tar.zst"
+	for _, item := range []struct{ kind, name, rel string }{
+		{KindBinary, binary, "bin/k3s"},
+		{KindAirgapImages, imagesName, "images/" + imagesName},
+	} {
+		f, err := b.download(release+item.name, item.rel, item.kind, "")
+		if err != nil {
+			return nil, err
+		}
+		if expected := checksums[item.name]; expected != f.SHA256 {
+			return nil, fmt.Errorf("%s 与官方校验和不一致: 期望 %q，实际 %s", item.name, expected, f.SHA256)
+		}
+		manifest.Files = append(manifest.Files, f)
+	}
+
+	// 安装脚本取与 k3s 版本对应的标签，保证安装包可复现
+	scriptURL := "https://raw.githubusercontent.com/k3s-io/k3s/" + url.PathEscape(opts.K3sVersion) + "/install.sh"
+	script, err := b.download(scriptURL, "install.sh", KindScript, "")
+	if err != nil {
+		return nil, err
+	}
+	manifest.Files = append(manifest.Files, script)
+
+	for _, ref := range opts.Images {
+		f, err := b.saveImage(ref, opts.Arch)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, f)
+	}
+	for _, name := range sortedKeys(opts.Charts) {
+		f, err := b.download(opts.Charts[name], "charts/"+name+".tgz", KindChart, name)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, f)
+	}
+	for _, name := range sortedKeys(opts.Manifests) {
+		f, err := b.download(opts.Manifests[name], "manifests/"+name+".yaml", KindManifest, name)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, f)
+	}
+
+	for _, name := range sortedKeys(opts.Packages) {
+		if !namePattern.MatchString(name) {
+			return nil, fmt.Errorf("无效的软件包文件名: %s", name)
+		}
+		f, err := b.download(opts.Packages[name], "packages/"+name, KindOSPackage, name)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, f)
+	}
+	for _, name := range sortedKeys(opts.PackageKeys) {
+		if !namePattern.MatchString(name) {
+			return nil, fmt.Errorf("无效的签名公钥文件名: %s", name)
+		}
+		f, err := b.download(opts.PackageKeys[name], "package-keys/"+name, KindPackageKey, name)
+		if err != nil {
+			return nil, err
+		}
+		manifest.Files = append(manifest.Files, f)
+	}
+
+	if err := manifest.write(b.dir, key); err != nil {
+		return nil, fmt.Errorf("写入安装包清单失败: %v", err)
+	}
+	return manifest, nil
+}
+
+// download 下载文件到安装包目录并计算摘要，先写临时文件，完成后重命名
+func (b *Builder) download(source, rel, kind, name string) (File, error) {
+	b.logf("下载 %s", source)
+	resp, err := b.get(source, nil)
+	if err != nil {
+		return File{}, err
+	}
+	defer resp.Body.Close()
+
+	return b.writeFile(rel, kind, name, source, resp.Body)
+}
+
+func (b *Builder) writeFile(rel, kind, name, source string, r io.Reader) (File, error) {
+	target := filepath.Join(b.dir, filepath.FromSlash(rel))
+	if err := os.MkdirAll(filepath.Dir(target), 0755); err != nil {
+		return File{}, err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(target), ".download-*")
+	if err != nil {
+		return File{}, err
+	}
+	defer os.Remove(tmp.Name())
+
+	h := sha256.New()
+	size, err := io.Copy(io.MultiWriter(tmp, h), r)
+	if cerr := tmp.Close(); err == nil {
+		err = cerr
+	}
+	if err != nil {
+		return File{}, fmt.Errorf("下载 %s 失败: %v", source, err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return File{}, err
+	}
+
+	return File{
+		Path:   path.Clean(rel),
+		Kind:   kind,
+		Name:   name,
+		Source: source,
+		SHA256: hex.EncodeToString(h.Sum(nil)),
+		Size:   size,
+	}, nil
+}
+
+// get 发起 GET 请求，非 2xx 状态码视为失败
+func (b *Builder) get(source string, header http.Header) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, source, nil)
+	if err != nil {
+		return nil, err
+	}
+	for k, v := range header {
+		req.Header[k] = v
+	}
+	resp, err := b.client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("下载 %s 失败: %v", source, err)
+	}
+	if resp.StatusCode/100 != 2 {
+		resp.Body.Close()
+		return nil, fmt.Errorf("下载 %s 失败: HTTP %d", source, resp.StatusCode)
+	}
+	return resp, nil
+}
+
+func sortedKeys(m map[string]string) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,85 @@
+package bundle
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// LoadOrCreateSigningKey 读取签名私钥（PKCS#8 PEM），文件不存在时生成新密钥，
+// 并在同目录写入公钥 <path>.pub 供部署后端校验
+func LoadOrCreateSigningKey(path string) (ed25519.PrivateKey, error) {
+	data, err := os.ReadFile(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return createSigningKey(path)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取签名密钥失败: %v", err)
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("签名密钥 %s 不是 PEM 格式", path)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析签名密钥失败: %v", err)
+	}
+	ed, ok := key.(ed25519.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("签名密钥 %s 不是 ed25519 密钥", path)
+	}
+	return ed, nil
+}
+
+func createSigningKey(path string) (ed25519.PrivateKey, error) {
+	publicKey, privateKey, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, err
+	}
+	der, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: der}), 0600); err != nil {
+		return nil, fmt.Errorf("保存签名密钥失败: %v", err)
+	}
+
+	pub, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.WriteFile(path+".pub", pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: pub}), 0644); err != nil {
+		return nil, fmt.Errorf("保存签名公钥失败: %v", err)
+	}
+	return privateKey, nil
+}
+
+// LoadPublicKey 读取签名公钥（PKIX PEM）
+func LoadPublicKey(path string) (ed25519.PublicKey, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取安装包签名公钥失败: %v", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("签名公钥 %s 不是 PEM 格式", path)
+	}
+	key, err := x509.ParsePKIXPublicKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析签名公钥失败: %v", err)
+	}
+	ed, ok := key.(ed25519.PublicKey)
+	if !ok {
+		return nil, fmt.Errorf("签名公钥 %s 不是 ed25519 公钥", path)
+	}
+	return ed, nil
+}
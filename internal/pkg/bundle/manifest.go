@@ -0,0 +1,183 @@
+// Package bundle 离线安装包：在可联网的机器上下载 k3s 二进制、安装脚本、镜像和 Chart，
+// 写入带签名的清单，部署时校验签名与文件摘要后用于离线安装
+package bundle
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+)
+
+// 文件类型
+const (
+	KindBinary       = "k3s-binary"
+	KindAirgapImages = "airgap-images"
+	KindScript       = "install-script"
+	// KindImages 附加组件镜像（OCI 归档）
+	KindImages   = "images"
+	KindChart    = "chart"
+	KindManifest = "manifest"
+	// KindOSPackage 系统补丁软件包（.deb、.rpm 或 .apk），由 patch-os 步骤离线安装
+	KindOSPackage = "os-package"
+	// KindPackageKey 系统补丁软件包的签名公钥（rpm 的 GPG 公钥或 apk 的 .rsa.pub），安装前导入节点
+	KindPackageKey = "package-key"
+)
+
+const (
+	ManifestFile  = "manifest.json"
+	SignatureFile = "manifest.json.sig"
+	formatVersion = 1
+)
+
+// File 安装包中的文件
+type File struct {
+	// Path 相对安装包目录的路径
+	Path string `json:"path"`
+	Kind string `json:"kind"`
+	// Name 镜像引用、Chart 或清单名称
+	Name   string `json:"name,omitempty"`
+	Source string `json:"source"`
+	SHA256 string `json:"sha256"`
+	Size   int64  `json:"size"`
+}
+
+// Manifest 安装包清单
+type Manifest struct {
+	FormatVersion int       `json:"formatVersion"`
+	K3sVersion    string    `json:"k3sVersion"`
+	Arch          string    `json:"arch"`
+	Addons        []string  `json:"addons,omitempty"`
+	Files         []File    `json:"files"`
+	CreatedAt     time.Time `json:"createdAt"`
+}
+
+// Find 返回指定类型的文件
+func (m *Manifest) Find(kind string) []File {
+	var files []File
+	for _, f := range m.Files {
+		if f.Kind == kind {
+			files = append(files, f)
+		}
+	}
+	return files
+}
+
+// Bundle 已校验的安装包
+type Bundle struct {
+	Dir      string
+	Manifest *Manifest
+}
+
+// Path 文件在本地的完整路径
+func (b *Bundle) Path(f File) string {
+	return filepath.Join(b.Dir, filepath.FromSlash(f.Path))
+}
+
+// File 返回指定类型的唯一文件
+func (b *Bundle) File(kind string) (File, error) {
+	files := b.Manifest.Find(kind)
+	if len(files) != 1 {
+		return File{}, fmt.Errorf("安装包 %s 中 %s 文件数量为 %d，应为 1", b.Dir, kind, len(files))
+	}
+	return files[0], nil
+}
+
+// Open 校验清单签名和每个文件的大小与 SHA256 后打开安装包
+func Open(dir string, publicKey ed25519.PublicKey) (*Bundle, error) {
+	data, err := os.ReadFile(filepath.Join(dir, ManifestFile))
+	if err != nil {
+		return nil, fmt.Errorf("读取安装包清单失败: %v", err)
+	}
+	sig, err := os.ReadFile(filepath.Join(dir, SignatureFile))
+	if err != nil {
+		return nil, fmt.Errorf("读取安装包签名失败: %v", err)
+	}
+	signature, err := hex.DecodeString(strings.TrimSpace(string(sig)))
+	if err != nil || !ed25519.Verify(publicKey, data, signature) {
+		return nil, fmt.Errorf("安装包 %s 签名校验失败", dir)
+	}
+
+	var manifest Manifest
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return nil, fmt.Errorf("解析安装包清单失败: %v", err)
+	}
+	if manifest.FormatVersion != formatVersion {
+		return nil, fmt.Errorf("不支持的安装包格式版本: %d", manifest.FormatVersion)
+	}
+
+	b := &Bundle{Dir: dir, Manifest: &manifest}
+	for _, f := range manifest.Files {
+		if !filepath.IsLocal(filepath.FromSlash(f.Path)) {
+			return nil, fmt.Errorf("安装包文件路径无效: %s", f.Path)
+		}
+		sum, size, err := fileDigest(b.Path(f))
+		if err != nil {
+			return nil, err
+		}
+		if size != f.Size || sum != f.SHA256 {
+			return nil, fmt.Errorf("安装包文件 %s 校验失败: 期望 %s (%d 字节)，实际 %s (%d 字节)", f.Path, f.SHA256, f.Size, sum, size)
+		}
+	}
+	return b, nil
+}
+
+func fileDigest(path string) (string, int64, error) {
+	file, err := os.Open(path)
+	if err != nil {
+		return "", 0, fmt.Errorf("读取安装包文件失败: %v", err)
+	}
+	defer file.Close()
+
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return "", 0, fmt.Errorf("读取安装包文件 %s 失败: %v", path, err)
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+// write 写入清单及其签名
+func (m *Manifest) write(dir string, key ed25519.PrivateKey) error {
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(dir, ManifestFile), data, 0644); err != nil {
+		return err
+	}
+	signature := hex.EncodeToString(ed25519.Sign(key, data))
+	return os.WriteFile(filepath.Join(dir, SignatureFile), []byte(signature+"\n"), 0644)
+}
+
+// namePattern 安装包名称（bundles 目录下的子目录名）
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._+-]*$`)
+
+// Catalog 后端可用的安装包目录，每个子目录为一个安装包
+type Catalog struct {
+	dir           string
+	publicKeyFile string
+}
+
+func NewCatalog(dir, publicKeyFile string) *Catalog {
+	return &Catalog{dir: dir, publicKeyFile: publicKeyFile}
+}
+
+// Open 按名称打开并校验安装包
+func (c *Catalog) Open(name string) (*Bundle, error) {
+	if !namePattern.MatchString(name) {
+		return nil, fmt.Errorf("无效的安装包名称: %s", name)
+	}
+	publicKey, err := LoadPublicKey(c.publicKeyFile)
+	if err != nil {
+		return nil, err
+	}
+	return Open(filepath.Join(c.dir, name), publicKey)
+}
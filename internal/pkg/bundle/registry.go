@@ -0,0 +1,326 @@
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"regexp"
+	"strings"
+)
+
+// 清单媒体类型
+const (
+	mediaOCIIndex      = "application/vnd.oci.image.index.v1+json"
+	mediaOCIManifest   = "application/vnd.oci.image.manifest.v1+json"
+	mediaDockerList    = "application/vnd.docker.distribution.manifest.list.v2+json"
+	mediaDockerSchema2 = "application/vnd.docker.distribution.manifest.v2+json"
+)
+
+// imageRef 解析后的镜像引用
+type imageRef struct {
+	// name 镜像在 containerd 中的完整名称（保留用户指定的仓库地址）
+	name     string
+	registry string
+	repo     string
+	// reference 标签或摘要
+	reference string
+}
+
+func parseImageRef(ref string) (imageRef, error) {
+	r := imageRef{}
+	rest := ref
+	if i := strings.Index(rest, "/"); i > 0 && strings.ContainsAny(rest[:i], ".:") || strings.HasPrefix(rest, "localhost/") {
+		r.registry, rest = rest[:i], rest[i+1:]
+	} else {
+		r.registry = "docker.io"
+	}
+
+	if i := strings.Index(rest, "@"); i >= 0 {
+		r.repo, r.reference = rest[:i], rest[i+1:]
+	} else if i := strings.LastIndex(rest, ":"); i >= 0 && !strings.Contains(rest[i:], "/") {
+		r.repo, r.reference = rest[:i], rest[i+1:]
+	} else {
+		r.repo, r.reference = rest, "latest"
+	}
+	if r.registry == "docker.io" && !strings.Contains(r.repo, "/") {
+		r.repo = "library/" + r.repo
+	}
+	if r.repo == "" || r.reference == "" {
+		return r, fmt.Errorf("无效的镜像引用: %s", ref)
+	}
+
+	separator := ":"
+	if strings.HasPrefix(r.reference, "sha256:") {
+		separator = "@"
+	}
+	r.name = r.registry + "/" + r.repo + separator + r.reference
+	return r, nil
+}
+
+func (r imageRef) endpoint() string {
+	if r.registry == "docker.io" {
+		return "https://registry-1.docker.io"
+	}
+	return "https://" + r.registry
+}
+
+// descriptor OCI 内容描述符
+type descriptor struct {
+	MediaType   string            `json:"mediaType"`
+	Digest      string            `json:"digest"`
+	Size        int64             `json:"size"`
+	Platform    *platform         `json:"platform,omitempty"`
+	Annotations map[string]string `json:"annotations,omitempty"`
+}
+
+type platform struct {
+	OS           string `json:"os"`
+	Architecture string `json:"architecture"`
+	Variant      string `json:"variant,omitempty"`
+}
+
+type imageManifest struct {
+	MediaType string       `json:"mediaType"`
+	Manifests []descriptor `json:"manifests"`
+	Config    descriptor   `json:"config"`
+	Layers    []descriptor `json:"layers"`
+}
+
+// registryClient 匿名访问镜像仓库，按 WWW-Authenticate 申请拉取令牌
+type registryClient struct {
+	builder *Builder
+	ref     imageRef
+	token   string
+}
+
+var challengeParam = regexp.MustCompile(`(\w+)="([^"]*)"`)
+
+func (c *registryClient) fetch(path, accept string) (*http.Response, error) {
+	source := c.ref.endpoint() + "/v2/" + c.ref.repo + path
+	for attempt := 0; ; attempt++ {
+		req, err := http.NewRequest(http.MethodGet, source, nil)
+		if err != nil {
+			return nil, err
+		}
+		if accept != "" {
+			req.Header.Set("Accept", accept)
+		}
+		if c.token != "" {
+			req.Header.Set("Authorization", "Bearer "+c.token)
+		}
+		resp, err := c.builder.client.Do(req)
+		if err != nil {
+			return nil, fmt.Errorf("访问 %s 失败: %v", source, err)
+		}
+		if resp.StatusCode == http.StatusUnauthorized && attempt == 0 {
+			challenge := resp.Header.Get("WWW-Authenticate")
+			resp.Body.Close()
+			if err := c.authenticate(challenge); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		if resp.StatusCode/100 != 2 {
+			resp.Body.Close()
+			return nil, fmt.Errorf("访问 %s 失败: HTTP %d", source, resp.StatusCode)
+		}
+		return resp, nil
+	}
+}
+
+func (c *registryClient) authenticate(challenge string) error {
+	if !strings.HasPrefix(strings.ToLower(challenge), "bearer ") {
+		return fmt.Errorf("镜像仓库 %s 要求不支持的认证方式: %s", c.ref.registry, challenge)
+	}
+	params := make(map[string]string)
+	for _, m := range challengeParam.FindAllStringSubmatch(challenge, -1) {
+		params[m[1]] = m[2]
+	}
+	if params["realm"] == "" {
+		return fmt.Errorf("镜像仓库 %s 认证信息缺少 realm", c.ref.registry)
+	}
+
+	source := params["realm"] + "?service=" + params["service"] + "&scope=repository:" + c.ref.repo + ":pull"
+	resp, err := c.builder.get(source, nil)
+	if err != nil {
+		return fmt.Errorf("申请镜像拉取令牌失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	var token struct {
+		Token       string `json:"token"`
+		AccessToken string `json:"access_token"`
+	}
+	if err := json.NewDecoder(resp.Body).Decode(&token); err != nil {
+		return fmt.Errorf("解析镜像拉取令牌失败: %v", err)
+	}
+	c.token = token.Token
+	if c.token == "" {
+		c.token = token.AccessToken
+	}
+	return nil
+}
+
+// manifest 获取清单原文，校验摘要
+func (c *registryClient) manifest(reference string) ([]byte, string, error) {
+	accept := strings.Join([]string{mediaOCIIndex, mediaDockerList, mediaOCIManifest, mediaDockerSchema2}, ", ")
+	resp, err := c.fetch("/manifests/"+reference, accept)
+	if err != nil {
+		return nil, "", err
+	}
+	defer resp.Body.Close()
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 4<<20))
+	if err != nil {
+		return nil, "", err
+	}
+	if strings.HasPrefix(reference, "sha256:") && digestOf(data) != reference {
+		return nil, "", fmt.Errorf("镜像清单 %s 摘要不一致", reference)
+	}
+	return data, strings.TrimSpace(strings.Split(resp.Header.Get("Content-Type"), ";")[0]), nil
+}
+
+// saveImage 拉取镜像指定架构的平台清单，写成 containerd 可直接导入的 OCI 归档（images/ 目录下）
+func (b *Builder) saveImage(ref, arch string) (File, error) {
+	parsed, err := parseImageRef(ref)
+	if err != nil {
+		return File{}, err
+	}
+	b.logf("拉取镜像 %s (%s)", parsed.name, arch)
+	c := &registryClient{builder: b, ref: parsed}
+
+	data, mediaType, err := c.manifest(parsed.reference)
+	if err != nil {
+		return File{}, err
+	}
+	var m imageManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return File{}, fmt.Errorf("解析镜像清单失败: %v", err)
+	}
+	if m.MediaType != "" {
+		mediaType = m.MediaType
+	}
+
+	if mediaType == mediaOCIIndex || mediaType == mediaDockerList {
+		var chosen *descriptor
+		for i, d := range m.Manifests {
+			if d.Platform != nil && d.Platform.OS == "linux" && d.Platform.Architecture == arch &&
+				(arch != "arm" || d.Platform.Variant == "" || d.Platform.Variant == "v7") {
+				chosen = &m.Manifests[i]
+				break
+			}
+		}
+		if chosen == nil {
+			return File{}, fmt.Errorf("镜像 %s 没有 linux/%s 平台", parsed.name, arch)
+		}
+		if data, _, err = c.manifest(chosen.Digest); err != nil {
+			return File{}, err
+		}
+		mediaType = chosen.MediaType
+		m = imageManifest{}
+		if err := json.Unmarshal(data, &m); err != nil {
+			return File{}, fmt.Errorf("解析镜像清单失败: %v", err)
+		}
+	}
+	if mediaType != mediaOCIManifest && mediaType != mediaDockerSchema2 {
+		return File{}, fmt.Errorf("镜像 %s 清单类型 %s 不受支持", parsed.name, mediaType)
+	}
+
+	pr, pw := io.Pipe()
+	go func() {
+		pw.CloseWithError(c.writeArchive(pw, parsed, data, mediaType, m))
+	}()
+	rel := "images/" + archiveName(parsed.name)
+	f, err := b.writeFile(rel, KindImages, parsed.name, parsed.name, pr)
+	pr.Close()
+	return f, err
+}
+
+// writeArchive 按 OCI 镜像布局写出 oci-layout、index.json 和全部 blob，
+// index.json 中的 io.containerd.image.name 注解决定导入后的镜像名
+func (c *registryClient) writeArchive(w io.Writer, ref imageRef, manifest []byte, mediaType string, m imageManifest) error {
+	tw := tar.NewWriter(w)
+
+	manifestDigest := digestOf(manifest)
+	index := map[string]any{
+		"schemaVersion": 2,
+		"manifests": []descriptor{{
+			MediaType: mediaType,
+			Digest:    manifestDigest,
+			Size:      int64(len(manifest)),
+			Annotations: map[string]string{
+				"io.containerd.image.name":          ref.name,
+				"org.opencontainers.image.ref.name": ref.reference,
+			},
+		}},
+	}
+	indexData, err := json.Marshal(index)
+	if err != nil {
+		return err
+	}
+
+	for _, entry := range []struct {
+		name string
+		data []byte
+	}{
+		{"oci-layout", []byte(`{"imageLayoutVersion":"1.0.0"}`)},
+		{"index.json", indexData},
+		{blobPath(manifestDigest), manifest},
+	} {
+		if err := writeTarEntry(tw, entry.name, int64(len(entry.data)), bytes.NewReader(entry.data)); err != nil {
+			return err
+		}
+	}
+
+	for _, blob := range append([]descriptor{m.Config}, m.Layers...) {
+		if err := c.copyBlob(tw, blob); err != nil {
+			return err
+		}
+	}
+	return tw.Close()
+}
+
+// copyBlob 下载 blob 写入归档并校验摘要
+func (c *registryClient) copyBlob(tw *tar.Writer, blob descriptor) error {
+	resp, err := c.fetch("/blobs/"+blob.Digest, "")
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	h := sha256.New()
+	if err := writeTarEntry(tw, blobPath(blob.Digest), blob.Size, io.TeeReader(resp.Body, h)); err != nil {
+		return fmt.Errorf("下载镜像层 %s 失败: %v", blob.Digest, err)
+	}
+	if actual := "sha256:" + hex.EncodeToString(h.Sum(nil)); actual != blob.Digest {
+		return fmt.Errorf("镜像层摘要不一致: 期望 %s，实际 %s", blob.Digest, actual)
+	}
+	return nil
+}
+
+func writeTarEntry(tw *tar.Writer, name string, size int64, r io.Reader) error {
+	if err := tw.WriteHeader(&tar.Header{Name: name, Mode: 0644, Size: size, Typeflag: tar.TypeReg}); err != nil {
+		return err
+	}
+	_, err := io.CopyN(tw, r, size)
+	return err
+}
+
+func blobPath(digest string) string {
+	return "blobs/sha256/" + strings.TrimPrefix(digest, "sha256:")
+}
+
+func digestOf(data []byte) string {
+	sum := sha256.Sum256(data)
+	return "sha256:" + hex.EncodeToString(sum[:])
+}
+
+// archiveName 镜像归档文件名，如 docker.io/library/redis:6 -> docker.io_library_redis_6.tar
+func archiveName(name string) string {
+	return strings.NewReplacer("/", "_", ":", "_", "@", "_").Replace(name) + ".tar"
+}
@@ -0,0 +1,70 @@
+package bundle
+
+import (
+	"bufio"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+// ReleaseURL k3s 官方发布文件地址
+const ReleaseURL = "https://github.com/k3s-io/k3s/releases/download"
+
+// MirrorReleaseURL Rancher 国内镜像的 k3s 发布文件地址，版本目录中的 "+" 替换为 "-"
+const MirrorReleaseURL = "https://rancher-mirror.rancher.cn/k3s"
+
+// binaryNames 架构 -> k3s 发布中的二进制文件名
+var binaryNames = map[string]string{
+	"amd64": "k3s",
+	"arm64": "k3s-arm64",
+	"arm":   "k3s-armhf",
+}
+
+// BinaryName 架构对应的 k3s 发布二进制文件名
+func BinaryName(arch string) (string, bool) {
+	name, ok := binaryNames[arch]
+	return name, ok
+}
+
+// releaseBase 版本 version 的发布文件目录，base 为空时使用官方地址
+func releaseBase(base, version string) string {
+	if base == "" {
+		base = ReleaseURL
+	}
+	if strings.TrimSuffix(base, "/") == MirrorReleaseURL {
+		version = strings.ReplaceAll(version, "+", "-")
+	}
+	return strings.TrimSuffix(base, "/") + "/" + url.PathEscape(version) + "/"
+}
+
+// OfficialChecksums 下载并解析 k3s 发布中架构 arch 的 sha256sum 文件（文件名 -> SHA256），base 为空时使用官方地址
+func OfficialChecksums(base, version, arch string) (map[string]string, error) {
+	client := &http.Client{Timeout: 30 * time.Second}
+	return fetchChecksums(client, releaseBase(base, version)+"sha256sum-"+arch+".txt")
+}
+
+func fetchChecksums(client *http.Client, source string) (map[string]string, error) {
+	resp, err := client.Get(source)
+	if err != nil {
+		return nil, fmt.Errorf("下载 %s 失败: %v", source, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return nil, fmt.Errorf("下载 %s 失败: HTTP %d", source, resp.StatusCode)
+	}
+
+	sums := make(map[string]string)
+	scanner := bufio.NewScanner(resp.Body)
+	for scanner.Scan() {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 2 {
+			sums[strings.TrimPrefix(fields[1], "*")] = fields[0]
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %v", source, err)
+	}
+	return sums, nil
+}
@@ -0,0 +1,117 @@
+package diagnose
+
+import (
+	"errors"
+	"strings"
+)
+
+// 失败分类
+const (
+	CategoryNetworkUnreachable = "network-unreachable"
+	CategoryAuthFailure        = "auth-failure"
+	CategoryMirrorUnreachable  = "mirror-unreachable"
+	CategoryDiskFull           = "disk-full"
+	CategoryCgroupMissing      = "cgroup-missing"
+	CategoryImagePull          = "image-pull-failure"
+	CategoryUnschedulable      = "unschedulable"
+	CategoryServiceCrash       = "service-crash"
+	CategoryInstallTimeout     = "install-timeout"
+	CategoryUnknown            = "unknown"
+)
+
+// Diagnosis 失败分类及建议的处理方式
+type Diagnosis struct {
+	Category string
+	Hint     string
+}
+
+// Diagnostics 可由错误实现，提供错误信息之外的诊断文本（如 journal 日志），参与分类
+type Diagnostics interface {
+	Diagnostics() string
+}
+
+type rule struct {
+	category string
+	hint     string
+	patterns []string
+}
+
+// rules 按优先级排列：更具体的原因排在前面，服务崩溃作为兜底放在最后，
+// 因为 journal 日志中往往同时包含导致崩溃的根因
+var rules = []rule{
+	{
+		category: CategoryAuthFailure,
+		hint:     "SSH 认证失败：检查用户名、密码或私钥是否正确，私钥口令是否匹配，目标主机是否允许该认证方式（sshd_config 中的 PasswordAuthentication / PubkeyAuthentication）",
+		patterns: []string{"unable to authenticate", "permission denied (publickey", "permission denied (password", "no supported methods remain", "解析私钥失败", "passphrase protected"},
+	},
+	{
+		category: CategoryDiskFull,
+		hint:     "磁盘空间不足：清理 /var/lib/rancher 与 /var/log 下的无用数据或扩容磁盘，k3s 建议至少保留 10GB 可用空间",
+		patterns: []string{"no space left on device", "diskpressure", "disk pressure", "evicted"},
+	},
+	{
+		category: CategoryCgroupMissing,
+		hint:     "内核未启用所需的 cgroup：在内核启动参数中添加 cgroup_memory=1 cgroup_enable=memory（树莓派为 /boot/cmdline.txt）后重启节点",
+		patterns: []string{"failed to find memory cgroup", "cgroup_memory", "cgroups v2", "failed to find cpuset cgroup", "cgroup controller", "no cgroup mount"},
+	},
+	{
+		category: CategoryImagePull,
+		hint:     "镜像拉取失败：确认镜像名称和标签正确、节点可以访问镜像仓库；私有仓库需配置 registries.yaml，慢速链路可先执行 prepull-images 步骤",
+		patterns: []string{"imagepullbackoff", "errimagepull", "invalidimagename", "failed to pull image", "pull access denied", "manifest unknown", "failed to resolve reference"},
+	},
+	{
+		category: CategoryUnschedulable,
+		hint:     "Pod 无法调度：根据诊断结果降低组件资源请求、扩容节点，或检查节点的 insuite.<角色> 标签、污点以及 PVC 是否已绑定",
+		patterns: []string{"insufficient memory", "insufficient cpu", "unbound immediate persistentvolumeclaims", "didn't match pod's node affinity/selector", "untolerated taint", "didn't match pod anti-affinity rules", "调度失败"},
+	},
+	{
+		category: CategoryInstallTimeout,
+		hint:     "安装脚本执行超时：节点下载 k3s 二进制和镜像较慢时调大 wait.installTimeout（或配置 tasks.wait.install_timeout），也可改用离线安装包",
+		patterns: []string{"安装超时"},
+	},
+	{
+		category: CategoryMirrorUnreachable,
+		hint:     "无法访问 k3s 安装源：检查节点 DNS 与外网访问，国内环境可切换 INSTALL_K3S_MIRROR=cn 或配置可用的镜像站",
+		patterns: []string{"get.k3s.io", "rancher-mirror", "github.com/k3s-io", "curl: (6)", "curl: (7)", "curl: (28)", "curl: (35)", "could not resolve host", "failed to download"},
+	},
+	{
+		category: CategoryNetworkUnreachable,
+		hint:     "网络不可达：确认节点 IP 和端口正确、节点已开机，检查防火墙和安全组是否放行 SSH（22）及 k3s（6443、8472/udp、10250）端口",
+		patterns: []string{"connection refused", "no route to host", "network is unreachable", "i/o timeout", "connection timed out", "connection reset by peer", "host is unreachable", "temporary failure in name resolution"},
+	},
+	{
+		category: CategoryServiceCrash,
+		hint:     "服务启动后异常退出：在节点上执行 journalctl -u k3s -n 200（Agent 为 k3s-agent；OpenRC 节点查看 /var/log/k3s.log）查看完整日志，确认端口未被占用、配置参数正确",
+		patterns: []string{"crashloopbackoff", "未正常运行", "main process exited", "failed with result", "start request repeated too quickly", "activating (auto-restart)"},
+	},
+}
+
+// Classify 根据错误信息及其携带的诊断文本对失败进行分类
+func Classify(err error) *Diagnosis {
+	if err == nil {
+		return nil
+	}
+
+	text := err.Error()
+	var d Diagnostics
+	if errors.As(err, &d) {
+		text += "\n" + d.Diagnostics()
+	}
+	return ClassifyText(text)
+}
+
+// ClassifyText 对命令输出或日志文本进行分类，无法识别时返回 unknown
+func ClassifyText(text string) *Diagnosis {
+	lower := strings.ToLower(text)
+	for _, r := range rules {
+		for _, pattern := range r.patterns {
+			if strings.Contains(lower, strings.ToLower(pattern)) {
+				return &Diagnosis{Category: r.category, Hint: r.hint}
+			}
+		}
+	}
+	return &Diagnosis{
+		Category: CategoryUnknown,
+		Hint:     "未能识别失败原因，请查看任务日志和节点上的系统日志",
+	}
+}
@@ -0,0 +1,91 @@
+// Package faults 部署测试用的故障注入：每个节点执行一定数量的命令后断开连接、延迟命令执行、
+// 使指定的部署步骤失败，用于确定性地验证重连重试、回滚和断点续跑逻辑，不要在生产环境启用
+package faults
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// Options 故障注入选项
+type Options struct {
+	// DropAfter 每个节点每执行 DropAfter 条命令后，下一条命令执行前断开连接，0 表示不断开
+	DropAfter int
+	// Delay 每条命令执行前等待的时长
+	Delay time.Duration
+	// FailSteps 直接失败的部署步骤及失败次数，0 表示每次都失败
+	FailSteps map[string]int
+}
+
+// Injector 实现 ssh.FaultInjector 和 service.StepFaults
+type Injector struct {
+	opts Options
+
+	mu       sync.Mutex
+	commands map[string]int
+	steps    map[string]int
+}
+
+var _ ssh.FaultInjector = (*Injector)(nil)
+
+// New 创建故障注入
+func New(opts Options) *Injector {
+	return &Injector{
+		opts:     opts,
+		commands: make(map[string]int),
+		steps:    make(map[string]int),
+	}
+}
+
+// ParseStep 解析 "步骤" 或 "步骤:N"（只有前 N 次执行失败）形式的步骤故障
+func ParseStep(spec string) (step string, times int, err error) {
+	step, count, ok := strings.Cut(spec, ":")
+	if step == "" {
+		return "", 0, fmt.Errorf("故障步骤为空")
+	}
+	if !ok {
+		return step, 0, nil
+	}
+	times, err = strconv.Atoi(count)
+	if err != nil || times < 1 {
+		return "", 0, fmt.Errorf("故障步骤 %s 的失败次数无效: %s", step, count)
+	}
+	return step, times, nil
+}
+
+// BeforeCommand 按节点计数，每执行 DropAfter 条命令后断开一次连接，重试的命令重新计数
+func (i *Injector) BeforeCommand(host, cmd string) (time.Duration, bool) {
+	if i.opts.DropAfter <= 0 {
+		return i.opts.Delay, false
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	i.commands[host]++
+	if i.commands[host] <= i.opts.DropAfter {
+		return i.opts.Delay, false
+	}
+	i.commands[host] = 0
+	return i.opts.Delay, true
+}
+
+// StepFault 步骤配置为失败且未达到失败次数时返回错误
+func (i *Injector) StepFault(step string) error {
+	times, ok := i.opts.FailSteps[step]
+	if !ok {
+		return nil
+	}
+
+	i.mu.Lock()
+	defer i.mu.Unlock()
+	if times > 0 && i.steps[step] >= times {
+		return nil
+	}
+	i.steps[step]++
+	return fmt.Errorf("步骤 %s 被故障注入设为失败（第 %d 次）", step, i.steps[step])
+}
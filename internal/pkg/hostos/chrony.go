@@ -0,0 +1,40 @@
+package hostos
+
+import "fmt"
+
+// chronyConflicts 与 chronyd 争用系统时钟的时间同步服务
+var chronyConflicts = []string{"systemd-timesyncd", "ntp", "ntpd", "openntpd"}
+
+// ChronyConfigPath chrony 配置文件路径，apt 系发行版放在 /etc/chrony 目录下
+func (i *Info) ChronyConfigPath() string {
+	if i.PackageManager == PackageManagerApt {
+		return "/etc/chrony/chrony.conf"
+	}
+	return "/etc/chrony.conf"
+}
+
+// ChronyUnit chrony 的服务名，apt 系发行版为 chrony，其余为 chronyd
+func (i *Info) ChronyUnit() string {
+	if i.PackageManager == PackageManagerApt {
+		return "chrony"
+	}
+	return "chronyd"
+}
+
+// InstallChronyCommand 缺少 chronyd 时安装 chrony。离线环境需要预先通过本地仓库或离线包安装
+func (i *Info) InstallChronyCommand() (string, error) {
+	install, err := i.InstallCommand("chrony")
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("command -v chronyd >/dev/null 2>&1 || [ -x /usr/sbin/chronyd ] || { %s; }", install), nil
+}
+
+// DisableTimeSyncConflictsCommand 停用其他时间同步服务，避免与 chronyd 同时调整时钟
+func (i *Info) DisableTimeSyncConflictsCommand() string {
+	cmd := ""
+	for _, unit := range chronyConflicts {
+		cmd += fmt.Sprintf("if %s >/dev/null 2>&1; then %s; fi; ", i.ServiceExistsCommand(unit), i.ServiceStopDisableCommand(unit))
+	}
+	return cmd + "true"
+}
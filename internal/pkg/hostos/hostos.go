@@ -0,0 +1,175 @@
+// Package hostos 识别节点的发行版、包管理器和 init 系统，并生成与之匹配的包安装和服务管理命令
+package hostos
+
+import (
+	"fmt"
+	"strings"
+
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// 发行版家族
+const (
+	FamilyDebian = "debian"
+	FamilyRHEL   = "rhel"
+	FamilySUSE   = "suse"
+	FamilyAlpine = "alpine"
+	// FamilyDomestic 国产发行版，多数兼容 RHEL 或 Debian，但 ID_LIKE 不一定声明
+	FamilyDomestic = "domestic"
+)
+
+// 包管理器
+const (
+	PackageManagerApt    = "apt"
+	PackageManagerDnf    = "dnf"
+	PackageManagerYum    = "yum"
+	PackageManagerZypper = "zypper"
+	PackageManagerApk    = "apk"
+)
+
+// init 系统
+const (
+	InitSystemd = "systemd"
+	InitOpenRC  = "openrc"
+	InitSysV    = "sysvinit"
+)
+
+// familyIDs 各家族在 os-release ID / ID_LIKE 中可能出现的取值
+var familyIDs = map[string][]string{
+	FamilyDebian:   {"debian", "ubuntu", "raspbian"},
+	FamilyRHEL:     {"rhel", "centos", "fedora", "rocky", "almalinux", "ol"},
+	FamilySUSE:     {"suse", "opensuse", "opensuse-leap", "opensuse-tumbleweed", "sles", "sle-micro", "sled"},
+	FamilyAlpine:   {"alpine"},
+	FamilyDomestic: {"uos", "uoss", "kylin", "deepin", "openeuler", "anolis"},
+}
+
+// Info 节点操作系统信息
+type Info struct {
+	ID             string   `json:"id"`
+	IDLike         []string `json:"idLike,omitempty"`
+	VersionID      string   `json:"versionId,omitempty"`
+	PrettyName     string   `json:"prettyName,omitempty"`
+	Family         string   `json:"family"`
+	PackageManager string   `json:"packageManager"`
+	InitSystem     string   `json:"initSystem"`
+}
+
+// detectScript 一次性输出 os-release、可用的包管理器和 init 系统，减少往返
+const detectScript = `cat /etc/os-release 2>/dev/null
+echo '--- k3s-deploy ---'
+for pm in apt-get dnf yum zypper apk; do
+  if command -v $pm >/dev/null 2>&1; then echo "pm=$pm"; break; fi
+done
+if [ -d /run/systemd/system ]; then
+  echo init=systemd
+elif command -v rc-service >/dev/null 2>&1 || [ -x /sbin/openrc-run ]; then
+  echo init=openrc
+else
+  echo init=sysvinit
+fi`
+
+// Detect 识别节点的操作系统、包管理器和 init 系统
+func Detect(client *ssh.Client) (*Info, error) {
+	result, err := client.ExecuteIdempotentCommand(detectScript)
+	if err != nil {
+		return nil, fmt.Errorf("识别操作系统失败: %v", err)
+	}
+	osRelease, probe, _ := strings.Cut(result.Stdout, "--- k3s-deploy ---")
+	info := ParseOSRelease(osRelease)
+	if info.ID == "" {
+		return nil, fmt.Errorf("无法解析 /etc/os-release")
+	}
+
+	for _, line := range strings.Split(probe, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		switch key {
+		case "pm":
+			info.PackageManager = strings.TrimSuffix(value, "-get")
+		case "init":
+			info.InitSystem = value
+		}
+	}
+	if info.InitSystem == "" {
+		info.InitSystem = InitSysV
+	}
+	return info, nil
+}
+
+// ParseOSRelease 解析 /etc/os-release 内容并归类发行版家族
+func ParseOSRelease(content string) *Info {
+	info := &Info{}
+	for _, line := range strings.Split(content, "\n") {
+		key, value, ok := strings.Cut(strings.TrimSpace(line), "=")
+		if !ok {
+			continue
+		}
+		value = strings.Trim(value, `"'`)
+		switch key {
+		case "ID":
+			info.ID = strings.ToLower(value)
+		case "ID_LIKE":
+			info.IDLike = strings.Fields(strings.ToLower(value))
+		case "VERSION_ID":
+			info.VersionID = value
+		case "PRETTY_NAME":
+			info.PrettyName = value
+		}
+	}
+	info.Family = family(info.ID, info.IDLike)
+	return info
+}
+
+// family 优先按 ID 归类，再按 ID_LIKE 归类（如 rocky 的 ID_LIKE 为 "rhel centos fedora"）
+func family(id string, idLike []string) string {
+	for _, candidate := range append([]string{id}, idLike...) {
+		for name, ids := range familyIDs {
+			for _, v := range ids {
+				if candidate == v {
+					return name
+				}
+			}
+		}
+	}
+	return ""
+}
+
+// Supported 是否为支持的发行版
+func (i *Info) Supported() bool {
+	return i.Family != ""
+}
+
+// CheckServiceManager k3s 安装脚本只支持 systemd 和 OpenRC，其他 init 系统（SysV 等）返回错误
+func (i *Info) CheckServiceManager() error {
+	if i.InitSystem != InitSystemd && i.InitSystem != InitOpenRC {
+		return fmt.Errorf("init 系统为 %s，k3s 需要 systemd 或 OpenRC 管理服务", i.InitSystem)
+	}
+	return nil
+}
+
+// String 返回便于日志展示的描述
+func (i *Info) String() string {
+	name := i.PrettyName
+	if name == "" {
+		name = i.ID
+	}
+	return fmt.Sprintf("%s（%s/%s）", name, valueOr(i.PackageManager, "无包管理器"), i.InitSystem)
+}
+
+// SupportedFamilies 列出支持的发行版 ID，用于错误提示
+func SupportedFamilies() []string {
+	var ids []string
+	for _, name := range []string{FamilyDebian, FamilyRHEL, FamilySUSE, FamilyAlpine, FamilyDomestic} {
+		ids = append(ids, familyIDs[name]...)
+	}
+	return ids
+}
+
+func valueOr(v, fallback string) string {
+	if v == "" {
+		return fallback
+	}
+	return v
+}
@@ -0,0 +1,60 @@
+package hostos
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+)
+
+// /etc/hosts 中由部署维护的记录位于以下两行标记之间，更新时整段替换，其余内容保持不变
+const (
+	hostsBlockBegin = "# BEGIN k3s-deploy-backend"
+	hostsBlockEnd   = "# END k3s-deploy-backend"
+)
+
+var hostsNamePattern = regexp.MustCompile(`^([A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?\.)*[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?$`)
+
+// HostsEntry /etc/hosts 中的一行：IP 与对应的主机名
+type HostsEntry struct {
+	IP    string
+	Names []string
+}
+
+// Validate 校验 IP 与主机名，写入命令时直接拼接，只允许安全字符
+func (e HostsEntry) Validate() error {
+	if net.ParseIP(e.IP) == nil {
+		return fmt.Errorf("无效的 hosts 记录 IP: %s", e.IP)
+	}
+	if len(e.Names) == 0 {
+		return fmt.Errorf("hosts 记录 %s 未指定主机名", e.IP)
+	}
+	for _, name := range e.Names {
+		if !hostsNamePattern.MatchString(name) {
+			return fmt.Errorf("无效的 hosts 记录主机名: %s", name)
+		}
+	}
+	return nil
+}
+
+// UpdateHostsCommand 将 entries 写入 /etc/hosts 的受管区段，entries 为空时删除该区段。
+// 通过 cat 覆盖写回而不是重命名，容器或 bind mount 的 /etc/hosts 无法被替换
+func UpdateHostsCommand(entries []HostsEntry) string {
+	lines := []string{}
+	if len(entries) > 0 {
+		lines = append(lines, hostsBlockBegin)
+		for _, e := range entries {
+			lines = append(lines, e.IP+" "+strings.Join(e.Names, " "))
+		}
+		lines = append(lines, hostsBlockEnd)
+	}
+
+	var b strings.Builder
+	fmt.Fprintf(&b, `tmp=$(mktemp) && awk -v b='%s' -v e='%s' '$0 == b { skip = 1; next } $0 == e { skip = 0; next } !skip' /etc/hosts > "$tmp"`,
+		hostsBlockBegin, hostsBlockEnd)
+	if len(lines) > 0 {
+		fmt.Fprintf(&b, ` && printf '%%s\n' '%s' >> "$tmp"`, strings.Join(lines, "' '"))
+	}
+	b.WriteString(` && cat "$tmp" > /etc/hosts; rc=$?; rm -f "$tmp"; exit $rc`)
+	return b.String()
+}
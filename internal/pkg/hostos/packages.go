@@ -0,0 +1,170 @@
+package hostos
+
+import (
+	"fmt"
+	"strings"
+)
+
+// Prerequisite 预检和安装依赖的命令，Check 成功即视为已满足
+type Prerequisite struct {
+	Name  string
+	Check string
+	// Packages 各包管理器下提供该命令的软件包
+	Packages map[string]string
+}
+
+// Prerequisites 预检和 k3s 安装脚本依赖的命令。
+// Alpine 的 busybox df 不支持 --output，需要 coreutils；nslookup 在各发行版中分属不同的软件包
+var Prerequisites = []Prerequisite{
+	{
+		Name:  "curl",
+		Check: "command -v curl",
+		Packages: map[string]string{
+			PackageManagerApt: "curl", PackageManagerDnf: "curl", PackageManagerYum: "curl",
+			PackageManagerZypper: "curl", PackageManagerApk: "curl",
+		},
+	},
+	{
+		Name:  "nslookup",
+		Check: "command -v nslookup",
+		Packages: map[string]string{
+			PackageManagerApt: "dnsutils", PackageManagerDnf: "bind-utils", PackageManagerYum: "bind-utils",
+			PackageManagerZypper: "bind-utils", PackageManagerApk: "bind-tools",
+		},
+	},
+	{
+		Name:  "free",
+		Check: "command -v free",
+		Packages: map[string]string{
+			PackageManagerApt: "procps", PackageManagerDnf: "procps-ng", PackageManagerYum: "procps-ng",
+			PackageManagerZypper: "procps", PackageManagerApk: "procps",
+		},
+	},
+	{
+		Name:  "ping",
+		Check: "command -v ping",
+		Packages: map[string]string{
+			PackageManagerApt: "iputils-ping", PackageManagerDnf: "iputils", PackageManagerYum: "iputils",
+			PackageManagerZypper: "iputils", PackageManagerApk: "iputils",
+		},
+	},
+	{
+		Name:  "df --output",
+		Check: "df --output=target / >/dev/null 2>&1",
+		Packages: map[string]string{
+			PackageManagerApt: "coreutils", PackageManagerDnf: "coreutils", PackageManagerYum: "coreutils",
+			PackageManagerZypper: "coreutils", PackageManagerApk: "coreutils",
+		},
+	},
+	{
+		Name:  "iptables",
+		Check: "command -v iptables",
+		Packages: map[string]string{
+			PackageManagerApt: "iptables", PackageManagerDnf: "iptables", PackageManagerYum: "iptables",
+			PackageManagerZypper: "iptables", PackageManagerApk: "iptables",
+		},
+	},
+}
+
+// InstallCommand 生成非交互安装软件包的命令
+func (i *Info) InstallCommand(packages ...string) (string, error) {
+	list := strings.Join(packages, " ")
+	switch i.PackageManager {
+	case PackageManagerApt:
+		return "DEBIAN_FRONTEND=noninteractive apt-get update -q && DEBIAN_FRONTEND=noninteractive apt-get install -y -q " + list, nil
+	case PackageManagerDnf:
+		return "dnf install -y -q " + list, nil
+	case PackageManagerYum:
+		return "yum install -y -q " + list, nil
+	case PackageManagerZypper:
+		// 新装系统的仓库签名密钥尚未导入，--gpg-auto-import-keys 避免交互确认
+		return "zypper --non-interactive --gpg-auto-import-keys install --no-recommends " + list, nil
+	case PackageManagerApk:
+		return "apk add --no-cache " + list, nil
+	default:
+		return "", fmt.Errorf("未识别的包管理器，请手动安装: %s", list)
+	}
+}
+
+// UpgradeCommand 生成非交互升级全部软件包的命令，apt 保留本地修改过的配置文件
+func (i *Info) UpgradeCommand() (string, error) {
+	switch i.PackageManager {
+	case PackageManagerApt:
+		return "DEBIAN_FRONTEND=noninteractive apt-get update -q && DEBIAN_FRONTEND=noninteractive apt-get upgrade -y -q -o Dpkg::Options::=--force-confdef -o Dpkg::Options::=--force-confold", nil
+	case PackageManagerDnf:
+		return "dnf upgrade -y -q", nil
+	case PackageManagerYum:
+		return "yum update -y -q", nil
+	case PackageManagerZypper:
+		return "zypper --non-interactive --gpg-auto-import-keys update", nil
+	case PackageManagerApk:
+		return "apk update && apk upgrade", nil
+	default:
+		return "", fmt.Errorf("未识别的包管理器，无法升级软件包")
+	}
+}
+
+// InstallLocalPackagesCommand 生成安装目录中全部本地软件包（离线补丁）的命令，已安装的较新版本不降级。
+// rpm 和 apk 先导入 dir/keys 中的签名公钥，再按节点信任的密钥校验软件包签名，未签名或密钥不受信任时安装失败；
+// .deb 没有包级签名，完整性由安装包清单的签名和摘要保证
+func (i *Info) InstallLocalPackagesCommand(dir string) (string, error) {
+	keys := dir + "/keys"
+	importRPMKeys := "if [ -d " + keys + " ]; then for k in " + keys + "/*; do rpm --import \"$k\" || exit 1; done; fi && "
+	switch i.PackageManager {
+	case PackageManagerApt:
+		return "DEBIAN_FRONTEND=noninteractive dpkg -i --skip-same-version " + dir + "/*.deb", nil
+	case PackageManagerDnf, PackageManagerYum:
+		return importRPMKeys + i.PackageManager + " install -y -q --disablerepo='*' --setopt=localpkg_gpgcheck=1 " + dir + "/*.rpm", nil
+	case PackageManagerZypper:
+		return importRPMKeys + "zypper --non-interactive --no-refresh install " + dir + "/*.rpm", nil
+	case PackageManagerApk:
+		return "if [ -d " + keys + " ]; then cp -f " + keys + "/* /etc/apk/keys/ || exit 1; fi && apk add --no-network " + dir + "/*.apk", nil
+	default:
+		return "", fmt.Errorf("未识别的包管理器，无法安装离线补丁")
+	}
+}
+
+// RebootRequiredScript 判断升级后是否需要重启，需要时输出 reboot：
+// apt 检查 /var/run/reboot-required，dnf/yum 使用 needs-restarting -r，zypper 使用 needs-rebooting，
+// 都不可用时以正在运行的内核模块目录是否已被删除（内核已升级）判断
+const RebootRequiredScript = `if [ -f /var/run/reboot-required ]; then echo reboot
+elif command -v needs-restarting >/dev/null 2>&1; then needs-restarting -r >/dev/null 2>&1 || echo reboot
+elif command -v zypper >/dev/null 2>&1; then zypper needs-rebooting >/dev/null 2>&1 || echo reboot
+elif [ ! -d /lib/modules/$(uname -r) ]; then echo reboot
+fi`
+
+// ISCSIPrerequisite Longhorn 通过 iSCSI 挂载卷，各节点需要 iscsiadm 和 iscsid 服务
+var ISCSIPrerequisite = Prerequisite{
+	Name:  "iscsiadm",
+	Check: "command -v iscsiadm",
+	Packages: map[string]string{
+		PackageManagerApt: "open-iscsi", PackageManagerDnf: "iscsi-initiator-utils", PackageManagerYum: "iscsi-initiator-utils",
+		PackageManagerZypper: "open-iscsi", PackageManagerApk: "open-iscsi",
+	},
+}
+
+// FilesystemPrerequisites 文件系统类型 -> 格式化数据盘所需的 mkfs 工具
+var FilesystemPrerequisites = map[string]Prerequisite{
+	"ext4": {
+		Name:  "mkfs.ext4",
+		Check: "command -v mkfs.ext4",
+		Packages: map[string]string{
+			PackageManagerApt: "e2fsprogs", PackageManagerDnf: "e2fsprogs", PackageManagerYum: "e2fsprogs",
+			PackageManagerZypper: "e2fsprogs", PackageManagerApk: "e2fsprogs",
+		},
+	},
+	"xfs": {
+		Name:  "mkfs.xfs",
+		Check: "command -v mkfs.xfs",
+		Packages: map[string]string{
+			PackageManagerApt: "xfsprogs", PackageManagerDnf: "xfsprogs", PackageManagerYum: "xfsprogs",
+			PackageManagerZypper: "xfsprogs", PackageManagerApk: "xfsprogs",
+		},
+	},
+}
+
+// PackageFor 返回提供某项依赖的软件包
+func (i *Info) PackageFor(p Prerequisite) (string, bool) {
+	pkg, ok := p.Packages[i.PackageManager]
+	return pkg, ok
+}
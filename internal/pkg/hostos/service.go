@@ -0,0 +1,81 @@
+package hostos
+
+import "fmt"
+
+// 以下命令与 systemctl 保持相同语义：状态查询输出 active/inactive，未运行时返回非零退出码
+
+// ServiceActiveCommand 查询服务是否运行
+func (i *Info) ServiceActiveCommand(unit string) string {
+	switch i.InitSystem {
+	case InitSystemd:
+		return "systemctl is-active " + unit
+	case InitOpenRC:
+		return fmt.Sprintf("if rc-service %s status >/dev/null 2>&1; then echo active; else echo inactive; false; fi", unit)
+	default:
+		return fmt.Sprintf("if service %s status >/dev/null 2>&1; then echo active; else echo inactive; false; fi", unit)
+	}
+}
+
+// ServiceExistsCommand 服务已安装时退出码为 0
+func (i *Info) ServiceExistsCommand(unit string) string {
+	if i.InitSystem == InitSystemd {
+		return fmt.Sprintf("systemctl list-unit-files %s.service --no-legend 2>/dev/null | grep -q %s", unit, unit)
+	}
+	return "test -x /etc/init.d/" + unit
+}
+
+// ServiceStopDisableCommand 停止服务并取消开机启动
+func (i *Info) ServiceStopDisableCommand(unit string) string {
+	switch i.InitSystem {
+	case InitSystemd:
+		return fmt.Sprintf("systemctl stop %[1]s && systemctl disable %[1]s", unit)
+	case InitOpenRC:
+		return fmt.Sprintf("rc-service %[1]s stop; rc-update del %[1]s default 2>/dev/null; true", unit)
+	default:
+		return fmt.Sprintf("service %[1]s stop; (chkconfig %[1]s off || update-rc.d %[1]s disable) 2>/dev/null; true", unit)
+	}
+}
+
+// ServiceEnableStartCommand 设置开机启动并立即启动服务
+func (i *Info) ServiceEnableStartCommand(unit string) string {
+	switch i.InitSystem {
+	case InitSystemd:
+		return "systemctl enable --now " + unit
+	case InitOpenRC:
+		return fmt.Sprintf("rc-update add %[1]s default && rc-service %[1]s start", unit)
+	default:
+		return fmt.Sprintf("(chkconfig %[1]s on || update-rc.d %[1]s defaults) 2>/dev/null; service %[1]s start", unit)
+	}
+}
+
+// ServiceRestartCommand 重启服务
+func (i *Info) ServiceRestartCommand(unit string) string {
+	switch i.InitSystem {
+	case InitSystemd:
+		return "systemctl restart " + unit
+	case InitOpenRC:
+		return "rc-service " + unit + " restart"
+	default:
+		return "service " + unit + " restart"
+	}
+}
+
+// ServiceReloadCommand 重新加载服务配置
+func (i *Info) ServiceReloadCommand(unit string) string {
+	switch i.InitSystem {
+	case InitSystemd:
+		return "systemctl reload " + unit
+	case InitOpenRC:
+		return "rc-service " + unit + " reload"
+	default:
+		return "service " + unit + " reload"
+	}
+}
+
+// ServiceLogsCommand 读取服务最近的日志。OpenRC 和 SysV 下 k3s 等服务写入 /var/log/<unit>.log
+func (i *Info) ServiceLogsCommand(unit string, lines int) string {
+	if i.InitSystem == InitSystemd {
+		return fmt.Sprintf("journalctl -u %s.service -n %d --no-pager", unit, lines)
+	}
+	return fmt.Sprintf("tail -n %d /var/log/%s.log 2>/dev/null || tail -n %d /var/log/messages", lines, unit, lines)
+}
@@ -0,0 +1,104 @@
+package hostos
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+var (
+	timezonePattern = regexp.MustCompile(`^[A-Za-z0-9_+\-]+(/[A-Za-z0-9_+\-]+)*$`)
+	localePattern   = regexp.MustCompile(`^[A-Za-z]+(_[A-Za-z]+)?\.(UTF-8|utf8|UTF8|utf-8)$`)
+	hostnameInvalid = regexp.MustCompile(`[^a-z0-9-]+`)
+)
+
+// tzdataPackages 提供 /usr/share/zoneinfo 的软件包，精简镜像和 Alpine 默认不安装
+var tzdataPackages = map[string]string{
+	PackageManagerApt: "tzdata", PackageManagerDnf: "tzdata", PackageManagerYum: "tzdata",
+	PackageManagerZypper: "timezone", PackageManagerApk: "tzdata",
+}
+
+// NormalizeHostname 将节点名称转换为合法的主机名（RFC 1123 标签：小写字母、数字和连字符，最长 63 个字符）
+func NormalizeHostname(name string) (string, error) {
+	hostname := hostnameInvalid.ReplaceAllString(strings.ToLower(name), "-")
+	if len(hostname) > 63 {
+		hostname = hostname[:63]
+	}
+	hostname = strings.Trim(hostname, "-")
+	if hostname == "" {
+		return "", fmt.Errorf("节点名称 %q 无法转换为合法的主机名", name)
+	}
+	return hostname, nil
+}
+
+// ValidateTimezone 校验时区名称格式（如 Asia/Shanghai），是否存在由节点上的 zoneinfo 决定
+func ValidateTimezone(tz string) error {
+	if !timezonePattern.MatchString(tz) || strings.Contains(tz, "..") {
+		return fmt.Errorf("无效的时区: %s", tz)
+	}
+	return nil
+}
+
+// ValidateLocale 校验 locale 名称，只接受 UTF-8 编码（如 C.UTF-8、en_US.UTF-8、zh_CN.UTF-8）
+func ValidateLocale(locale string) error {
+	if !localePattern.MatchString(locale) {
+		return fmt.Errorf("无效的 locale: %s（需要 UTF-8 编码，如 C.UTF-8）", locale)
+	}
+	return nil
+}
+
+// SetHostnameCommand 设置主机名并保证其可在本机解析：主机名无法解析时 sudo、证书生成等操作会等待 DNS 超时
+func (i *Info) SetHostnameCommand(hostname string) string {
+	set := fmt.Sprintf("echo %[1]s > /etc/hostname && hostname %[1]s", hostname)
+	if i.InitSystem == InitSystemd {
+		set = "hostnamectl set-hostname " + hostname
+	}
+	return set + fmt.Sprintf(` && if grep -qE '^127\.0\.1\.1[[:space:]]' /etc/hosts; then sed -i 's/^127\.0\.1\.1[[:space:]].*/127.0.1.1 %[1]s/' /etc/hosts; else echo '127.0.1.1 %[1]s' >> /etc/hosts; fi`, hostname)
+}
+
+// SetTimezoneCommand 设置时区，缺少 zoneinfo 时先安装时区数据
+func (i *Info) SetTimezoneCommand(tz string) string {
+	zoneinfo := "/usr/share/zoneinfo/" + tz
+	var b strings.Builder
+	if pkg, ok := tzdataPackages[i.PackageManager]; ok {
+		if install, err := i.InstallCommand(pkg); err == nil {
+			fmt.Fprintf(&b, "[ -f %s ] || { %s; }; ", zoneinfo, install)
+		}
+	}
+	fmt.Fprintf(&b, "[ -f %[1]s ] || { echo '时区 %[2]s 不存在' >&2; exit 1; }; ", zoneinfo, tz)
+	if i.InitSystem == InitSystemd {
+		b.WriteString("timedatectl set-timezone " + tz)
+	} else {
+		fmt.Fprintf(&b, "ln -sf %s /etc/localtime && echo %s > /etc/timezone", zoneinfo, tz)
+	}
+	return b.String()
+}
+
+// SetLocaleCommand 生成（如缺失）并设置系统默认 locale。
+// musl（Alpine）没有 locale 数据库，UTF-8 为内置行为，只需设置 LANG
+func (i *Info) SetLocaleCommand(locale string) string {
+	lang, _, _ := strings.Cut(locale, ".")
+
+	var generate string
+	switch i.PackageManager {
+	case PackageManagerApk:
+	case PackageManagerApt:
+		generate = fmt.Sprintf("command -v locale-gen >/dev/null 2>&1 || DEBIAN_FRONTEND=noninteractive apt-get install -y -q locales; "+
+			"if [ -f /etc/locale.gen ]; then sed -i 's/^# *\\(%[1]s.UTF-8\\)/\\1/' /etc/locale.gen; grep -q '^%[1]s.UTF-8' /etc/locale.gen || echo '%[1]s.UTF-8 UTF-8' >> /etc/locale.gen; locale-gen; "+
+			"else localedef -i %[1]s -f UTF-8 %[1]s.UTF-8; fi", lang)
+	default:
+		generate = fmt.Sprintf("localedef -i %[1]s -f UTF-8 %[1]s.UTF-8", lang)
+	}
+
+	var b strings.Builder
+	if generate != "" && !strings.EqualFold(lang, "C") {
+		// locale -a 输出的名称形如 en_US.utf8
+		fmt.Fprintf(&b, "locale -a 2>/dev/null | tr 'A-Z' 'a-z' | grep -qx '%s.utf8' || { %s; }; ", strings.ToLower(lang), generate)
+	}
+	if i.InitSystem == InitSystemd {
+		fmt.Fprintf(&b, "localectl set-locale LANG=%s", locale)
+	} else {
+		fmt.Fprintf(&b, "echo 'export LANG=%[1]s' > /etc/profile.d/k3s-deploy-locale.sh && sed -i '/^LANG=/d' /etc/locale.conf 2>/dev/null; echo 'LANG=%[1]s' >> /etc/locale.conf", locale)
+	}
+	return b.String()
+}
@@ -0,0 +1,141 @@
+package hostos
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// 内核参数与文件句柄限制写入独立的配置文件；首次应用前各参数的运行值保存在 TuningBackupPath，撤销时据此恢复
+const (
+	SysctlConfPath   = "/etc/sysctl.d/90-k3s-deploy.conf"
+	LimitsConfPath   = "/etc/security/limits.d/90-k3s-deploy.conf"
+	TuningBackupPath = "/etc/k3s-deploy/sysctl.backup"
+)
+
+// defaultNrOpen 内核 fs.nr_open 默认值，nofile 不能超过它
+const defaultNrOpen = 1048576
+
+var (
+	sysctlKeyPattern   = regexp.MustCompile(`^[a-z0-9_]+(\.[A-Za-z0-9_-]+)+$`)
+	sysctlValuePattern = regexp.MustCompile(`^[A-Za-z0-9._-]+( [A-Za-z0-9._-]+)*$`)
+)
+
+// TuningProfile 节点内核参数与文件句柄限制
+type TuningProfile struct {
+	Sysctl map[string]string
+	// NoFile 登录会话的 nofile 软硬限制，0 表示不写 limits.d
+	NoFile int
+}
+
+// TuningProfiles 经过验证的调优配置：default 适用于一般集群，large 面向节点数与 Pod 数较多的集群，
+// 额外放大 ARP 邻居表，避免 Pod 数量多时出现 neighbour table overflow
+var TuningProfiles = map[string]TuningProfile{
+	"default": {
+		Sysctl: map[string]string{
+			"fs.inotify.max_user_instances": "8192",
+			"fs.inotify.max_user_watches":   "524288",
+			"fs.file-max":                   "2097152",
+			"vm.max_map_count":              "262144",
+			"net.core.somaxconn":            "32768",
+		},
+		NoFile: 1048576,
+	},
+	"large": {
+		Sysctl: map[string]string{
+			"fs.inotify.max_user_instances":     "16384",
+			"fs.inotify.max_user_watches":       "1048576",
+			"fs.file-max":                       "4194304",
+			"vm.max_map_count":                  "524288",
+			"net.core.somaxconn":                "65535",
+			"net.ipv4.neigh.default.gc_thresh1": "4096",
+			"net.ipv4.neigh.default.gc_thresh2": "8192",
+			"net.ipv4.neigh.default.gc_thresh3": "16384",
+		},
+		NoFile: 1048576,
+	},
+}
+
+// Validate 校验参数名与取值，写入命令时直接拼接，只允许安全字符
+func (p TuningProfile) Validate() error {
+	if len(p.Sysctl) == 0 && p.NoFile == 0 {
+		return fmt.Errorf("调优配置为空")
+	}
+	for key, value := range p.Sysctl {
+		if !sysctlKeyPattern.MatchString(key) {
+			return fmt.Errorf("无效的内核参数名: %s", key)
+		}
+		if !sysctlValuePattern.MatchString(value) {
+			return fmt.Errorf("内核参数 %s 的取值无效: %q", key, value)
+		}
+	}
+	if p.NoFile < 0 {
+		return fmt.Errorf("nofile 不能为负数")
+	}
+	nrOpen := defaultNrOpen
+	if v, ok := p.Sysctl["fs.nr_open"]; ok {
+		nrOpen, _ = strconv.Atoi(v)
+	}
+	if p.NoFile > nrOpen {
+		return fmt.Errorf("nofile %d 超过 fs.nr_open（%d），需同时调大 fs.nr_open", p.NoFile, nrOpen)
+	}
+	return nil
+}
+
+// Keys 按名称排序的内核参数
+func (p TuningProfile) Keys() []string {
+	keys := make([]string, 0, len(p.Sysctl))
+	for key := range p.Sysctl {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// ApplyTuningCommand 记录尚未备份的参数的当前值，然后写入 sysctl.d 与 limits.d 并立即加载内核参数。
+// 重复执行不会覆盖已备份的原始值
+func (p TuningProfile) ApplyTuningCommand() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "mkdir -p $(dirname %[1]s) && touch %[1]s", TuningBackupPath)
+	for _, key := range p.Keys() {
+		fmt.Fprintf(&b, ` && { grep -q '^%[1]s=' %[2]s || echo "%[1]s=$(sysctl -n %[1]s | tr '\t' ' ')" >> %[2]s; }`, key, TuningBackupPath)
+	}
+
+	lines := []string{"# managed by k3s-deploy-backend"}
+	for _, key := range p.Keys() {
+		lines = append(lines, key+" = "+p.Sysctl[key])
+	}
+	fmt.Fprintf(&b, ` && printf '%%s\n' '%s' > %s && sysctl -p %s`, strings.Join(lines, "' '"), SysctlConfPath, SysctlConfPath)
+
+	if p.NoFile > 0 {
+		limits := []string{"# managed by k3s-deploy-backend"}
+		for _, domain := range []string{"*", "root"} {
+			for _, kind := range []string{"soft", "hard"} {
+				limits = append(limits, fmt.Sprintf("%s %s nofile %d", domain, kind, p.NoFile))
+			}
+		}
+		fmt.Fprintf(&b, ` && mkdir -p $(dirname %[2]s) && printf '%%s\n' '%[1]s' > %[2]s`, strings.Join(limits, "' '"), LimitsConfPath)
+	} else {
+		fmt.Fprintf(&b, " && rm -f %s", LimitsConfPath)
+	}
+	return b.String()
+}
+
+// RevertTuningCommand 按备份恢复内核参数的原始值并删除调优配置文件
+func RevertTuningCommand() string {
+	return fmt.Sprintf(`if [ -f %[1]s ]; then while IFS='=' read -r k v; do if [ -n "$k" ] && [ -n "$v" ]; then sysctl -w "$k=$v" >/dev/null || echo "恢复 $k 失败" >&2; fi; done < %[1]s; fi; rm -f %[2]s %[3]s %[1]s`,
+		TuningBackupPath, SysctlConfPath, LimitsConfPath)
+}
+
+// ParseTuningBackup 解析备份文件内容（key=value）
+func ParseTuningBackup(content string) map[string]string {
+	values := make(map[string]string)
+	for _, line := range strings.Split(content, "\n") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			values[key] = value
+		}
+	}
+	return values
+}
@@ -0,0 +1,140 @@
+// Package joinbundle 集群加入信息包：将 server 地址、join token、集群 CA 摘要和建议的 Agent 参数
+// 用 ed25519 签名后编码为一行文本（长度适合生成二维码），导入时校验签名、有效期和 CA 摘要后再使用
+package joinbundle
+
+import (
+	"context"
+	"crypto/ed25519"
+	"crypto/sha256"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+)
+
+const (
+	// Prefix 加入信息包的前缀，带格式版本
+	Prefix        = "K3SJOIN1."
+	formatVersion = 1
+)
+
+// ErrExpired 加入信息包已过期
+var ErrExpired = errors.New("加入信息包已过期")
+
+// KeyID 返回公钥指纹（SHA256 的前 16 位十六进制）
+func KeyID(publicKey ed25519.PublicKey) string {
+	sum := sha256.Sum256(publicKey)
+	return hex.EncodeToString(sum[:])[:16]
+}
+
+// PublicKeyPEM 返回 PKIX PEM 格式的公钥，供其他工具校验签名
+func PublicKeyPEM(publicKey ed25519.PublicKey) (string, error) {
+	der, err := x509.MarshalPKIXPublicKey(publicKey)
+	if err != nil {
+		return "", err
+	}
+	return string(pem.EncodeToMemory(&pem.Block{Type: "PUBLIC KEY", Bytes: der})), nil
+}
+
+// Encode 填写版本和密钥指纹后签名，格式为 K3SJOIN1.<base64url(JSON)>.<base64url(签名)>，签名覆盖签名前的全部内容
+func Encode(bundle *model.JoinBundle, key ed25519.PrivateKey) (string, error) {
+	bundle.Version = formatVersion
+	bundle.KeyID = KeyID(key.Public().(ed25519.PublicKey))
+	payload, err := json.Marshal(bundle)
+	if err != nil {
+		return "", err
+	}
+	signed := Prefix + base64.RawURLEncoding.EncodeToString(payload)
+	return signed + "." + base64.RawURLEncoding.EncodeToString(ed25519.Sign(key, []byte(signed))), nil
+}
+
+// Decode 校验签名、格式版本、有效期和 token 中的 CA 摘要后返回内容
+func Decode(text string, publicKey ed25519.PublicKey) (*model.JoinBundle, error) {
+	text = strings.TrimSpace(text)
+	if !strings.HasPrefix(text, Prefix) {
+		return nil, fmt.Errorf("不是加入信息包（应以 %s 开头）", Prefix)
+	}
+	dot := strings.LastIndex(text, ".")
+	signed, encodedSig := text[:dot], text[dot+1:]
+	signature, err := base64.RawURLEncoding.DecodeString(encodedSig)
+	if err != nil || !ed25519.Verify(publicKey, []byte(signed), signature) {
+		return nil, fmt.Errorf("加入信息包签名校验失败（不是由本后端的密钥 %s 签发，或内容被修改）", KeyID(publicKey))
+	}
+
+	payload, err := base64.RawURLEncoding.DecodeString(strings.TrimPrefix(signed, Prefix))
+	if err != nil {
+		return nil, fmt.Errorf("解析加入信息包失败: %v", err)
+	}
+	var bundle model.JoinBundle
+	if err := json.Unmarshal(payload, &bundle); err != nil {
+		return nil, fmt.Errorf("解析加入信息包失败: %v", err)
+	}
+	if bundle.Version != formatVersion {
+		return nil, fmt.Errorf("不支持的加入信息包格式版本: %d", bundle.Version)
+	}
+	if time.Now().After(bundle.ExpiresAt) {
+		return nil, fmt.Errorf("%w（%s）", ErrExpired, bundle.ExpiresAt.Format(time.RFC3339))
+	}
+	if err := k3s.ValidateServerURL(bundle.ServerURL); err != nil {
+		return nil, err
+	}
+	if bundle.Token == "" || bundle.CAHash == "" {
+		return nil, fmt.Errorf("加入信息包缺少 token 或 CA 摘要")
+	}
+	if hash := k3s.TokenCAHash(bundle.Token); hash != "" && hash != bundle.CAHash {
+		return nil, fmt.Errorf("加入信息包中 token 的 CA 摘要与 caHash 不一致")
+	}
+	return &bundle, nil
+}
+
+// FetchCAHash 与 k3s Agent 相同，不校验证书读取 server 的 /cacerts 并返回其 SHA256
+func FetchCAHash(ctx context.Context, serverURL string) (string, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, strings.TrimSuffix(serverURL, "/")+"/cacerts", nil)
+	if err != nil {
+		return "", err
+	}
+	client := &http.Client{Transport: &http.Transport{TLSClientConfig: &tls.Config{InsecureSkipVerify: true}}}
+	resp, err := client.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("无法访问 server %s: %w", serverURL, err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("server %s 的 /cacerts 返回 HTTP %d", serverURL, resp.StatusCode)
+	}
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return "", fmt.Errorf("读取 server %s 的 CA 失败: %w", serverURL, err)
+	}
+	sum := sha256.Sum256(body)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// Command 返回在新节点上以 Agent 身份加入集群的安装命令
+func Command(bundle *model.JoinBundle) string {
+	env := []string{}
+	if bundle.K3sVersion != "" {
+		env = append(env, "INSTALL_K3S_VERSION="+quote(bundle.K3sVersion))
+	}
+	env = append(env, "K3S_URL="+quote(bundle.ServerURL), "K3S_TOKEN="+quote(bundle.Token), "K3S_NODE_NAME='<节点名>'")
+	args := []string{"agent"}
+	for _, flag := range bundle.Flags {
+		args = append(args, quote(flag))
+	}
+	return fmt.Sprintf("curl -sfL https://get.k3s.io | %s sh -s - %s", strings.Join(env, " "), strings.Join(args, " "))
+}
+
+func quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,179 @@
+package k3s
+
+import (
+	"fmt"
+	"os"
+	"path"
+	"strings"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/bundle"
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// k3s 离线安装使用的节点目录
+const (
+	airgapImagesDir = "/var/lib/rancher/k3s/agent/images"
+	staticChartsDir = "/var/lib/rancher/k3s/server/static/charts"
+	k3sBinaryPath   = "/usr/local/bin/k3s"
+)
+
+// unameArch uname -m 输出 -> 安装包架构
+var unameArch = map[string]string{
+	"x86_64":  "amd64",
+	"aarch64": "arm64",
+	"arm64":   "arm64",
+	"armv7l":  "arm",
+}
+
+// bundleUpload 安装包文件及其在节点上的路径
+type bundleUpload struct {
+	file   bundle.File
+	remote string
+}
+
+// installAirgap 使用离线安装包安装 k3s：上传二进制、离线镜像和附加组件镜像（Server 节点还上传 Chart），
+// 然后以 INSTALL_K3S_SKIP_DOWNLOAD 执行安装包中的安装脚本，全程不访问外网。返回上传文件在节点上校验的摘要
+func (i *Installer) installAirgap(client *ssh.Client, nodeName string, osInfo *hostos.Info, policy WaitPolicy, opts InstallOptions, envArgs, cmdArgs []string) ([]Digest, error) {
+	b := opts.Airgap
+	i.logger.Infof("使用离线安装包 %s（k3s %s，%s）", b.Dir, b.Manifest.K3sVersion, b.Manifest.Arch)
+
+	result, err := client.ExecuteIdempotentCommand("uname -m")
+	if err != nil {
+		return nil, fmt.Errorf("检测节点架构失败: %v", err)
+	}
+	if arch := unameArch[strings.TrimSpace(result.Stdout)]; arch != b.Manifest.Arch {
+		return nil, fmt.Errorf("节点 %s 架构 %s 与离线安装包架构 %s 不一致", nodeName, strings.TrimSpace(result.Stdout), b.Manifest.Arch)
+	}
+
+	binary, err := b.File(bundle.KindBinary)
+	if err != nil {
+		return nil, err
+	}
+	uploads := []bundleUpload{{binary, k3sBinaryPath}}
+	for _, f := range append(b.Manifest.Find(bundle.KindAirgapImages), b.Manifest.Find(bundle.KindImages)...) {
+		uploads = append(uploads, bundleUpload{f, path.Join(airgapImagesDir, path.Base(f.Path))})
+	}
+	if !isAgentInstall(envArgs) {
+		for _, f := range b.Manifest.Find(bundle.KindChart) {
+			uploads = append(uploads, bundleUpload{f, path.Join(staticChartsDir, path.Base(f.Path))})
+		}
+	}
+
+	digests, err := i.uploadBundle(client, nodeName, b, uploads)
+	if err != nil {
+		return digests, err
+	}
+	if _, err := client.ExecuteCommand("chmod 755 " + k3sBinaryPath); err != nil {
+		return digests, fmt.Errorf("设置 k3s 可执行权限失败: %v", err)
+	}
+
+	scriptFile, err := b.File(bundle.KindScript)
+	if err != nil {
+		return digests, err
+	}
+	script, err := os.ReadFile(b.Path(scriptFile))
+	if err != nil {
+		return digests, fmt.Errorf("读取离线安装脚本失败: %v", err)
+	}
+	opts.Script = &ScriptSource{Content: string(script), SHA256: scriptFile.SHA256, PatchCertConfig: true}
+
+	// 离线环境无法访问 rpm 仓库安装 SELinux 策略包
+	envArgs = append(envArgs, "INSTALL_K3S_SKIP_DOWNLOAD=true", "INSTALL_K3S_SKIP_SELINUX_RPM=true")
+	installed, err := i.executeInstall(client, nodeName, osInfo, policy, opts, officialInstallURL, envArgs, cmdArgs)
+	return append(digests, installed...), err
+}
+
+// uploadBundle 依次上传安装包文件，返回已上传文件的摘要
+func (i *Installer) uploadBundle(client *ssh.Client, nodeName string, b *bundle.Bundle, uploads []bundleUpload) ([]Digest, error) {
+	defer trackPhase(client, nodeName, model.PhaseDownload)()
+
+	var digests []Digest
+	for _, u := range uploads {
+		if _, err := client.ExecuteCommand("mkdir -p " + path.Dir(u.remote)); err != nil {
+			return digests, fmt.Errorf("创建目录 %s 失败: %v", path.Dir(u.remote), err)
+		}
+		if err := i.uploadBundleFile(client, b, u.file, u.remote); err != nil {
+			return digests, err
+		}
+		digests = append(digests, Digest{Node: nodeName, Name: u.file.Path, SHA256: u.file.SHA256, Source: "离线安装包清单", Verified: true})
+	}
+	return digests, nil
+}
+
+// uploadBundleFile 上传安装包文件并在节点上校验 SHA256，已存在且摘要一致的文件跳过
+func (i *Installer) uploadBundleFile(client *ssh.Client, b *bundle.Bundle, f bundle.File, remotePath string) error {
+	if remoteSHA256(client, remotePath) == f.SHA256 {
+		i.logger.Infof("%s 已存在且校验一致，跳过上传", remotePath)
+		return nil
+	}
+
+	i.logger.Infof("上传 %s -> %s（%.1f MiB）", f.Path, remotePath, float64(f.Size)/(1<<20))
+	tmp := remotePath + ".k3s-deploy.tmp"
+	if err := client.UploadLocalFile(b.Path(f), tmp); err != nil {
+		return fmt.Errorf("上传 %s 失败: %v", f.Path, err)
+	}
+	if actual := remoteSHA256(client, tmp); actual != f.SHA256 {
+		client.ExecuteCommand("rm -f " + tmp)
+		return fmt.Errorf("%s 上传后校验失败: 期望 %s，实际 %q", f.Path, f.SHA256, actual)
+	}
+	if _, err := client.ExecuteCommand(fmt.Sprintf("mv -f %s %s", tmp, remotePath)); err != nil {
+		return fmt.Errorf("安装 %s 失败: %v", remotePath, err)
+	}
+	i.logger.Infof("%s 校验通过: sha256 %s", remotePath, f.SHA256)
+	return nil
+}
+
+// UploadOSPackages 将安装包中的系统补丁软件包上传到节点的 dir 目录、签名公钥上传到 dir/keys 目录并逐个校验，
+// 返回上传的软件包数量
+func (i *Installer) UploadOSPackages(client *ssh.Client, b *bundle.Bundle, dir string) (int, error) {
+	packages := b.Manifest.Find(bundle.KindOSPackage)
+	if len(packages) == 0 {
+		return 0, fmt.Errorf("安装包 %s 中没有系统补丁软件包", b.Dir)
+	}
+	if err := i.uploadBundleFiles(client, b, packages, dir); err != nil {
+		return 0, err
+	}
+	if keys := b.Manifest.Find(bundle.KindPackageKey); len(keys) > 0 {
+		if err := i.uploadBundleFiles(client, b, keys, path.Join(dir, "keys")); err != nil {
+			return 0, err
+		}
+	}
+	return len(packages), nil
+}
+
+// uploadBundleFiles 将安装包中的文件按原文件名上传到节点的 dir 目录
+func (i *Installer) uploadBundleFiles(client *ssh.Client, b *bundle.Bundle, files []bundle.File, dir string) error {
+	if _, err := client.ExecuteIdempotentCommand("mkdir -p " + dir); err != nil {
+		return fmt.Errorf("创建目录 %s 失败: %v", dir, err)
+	}
+	for _, f := range files {
+		if err := i.uploadBundleFile(client, b, f, path.Join(dir, path.Base(f.Path))); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// remoteSHA256 节点上文件的 SHA256，文件不存在或无法计算时返回空字符串
+func remoteSHA256(client *ssh.Client, remotePath string) string {
+	result, err := client.ExecuteIdempotentCommand(fmt.Sprintf("sha256sum %s 2>/dev/null", remotePath))
+	if err != nil {
+		return ""
+	}
+	if fields := strings.Fields(result.Stdout); len(fields) > 0 {
+		return fields[0]
+	}
+	return ""
+}
+
+// isAgentInstall 安装环境变量中包含 K3S_URL 时为 Agent 安装
+func isAgentInstall(envArgs []string) bool {
+	for _, env := range envArgs {
+		if strings.HasPrefix(env, "K3S_URL=") {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,253 @@
+package k3s
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// componentDisk 各组件每个副本预计占用的节点磁盘（镜像与容器可写层）。
+// 组件未挂载持久卷，数据库数据写在容器可写层，因此预留较多
+var componentDisk = map[string]int64{
+	RoleDatabase:   4 << 30,
+	RoleMiddleware: 1 << 30,
+	RoleApp:        1 << 30,
+}
+
+// evictionReserve kubelet 默认在 nodefs.available < 10% 时驱逐 Pod，规划磁盘时保留该比例
+const evictionReserve = 0.1
+
+// capacityNodeList kubectl get nodes -o json 中容量检查用到的字段
+type capacityNodeList struct {
+	Items []struct {
+		Metadata struct {
+			Name   string            `json:"name"`
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Spec struct {
+			Unschedulable bool `json:"unschedulable"`
+		} `json:"spec"`
+		Status struct {
+			Allocatable map[string]string `json:"allocatable"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// requestPodList 运行中 Pod 的节点和资源请求
+type requestPodList struct {
+	Items []struct {
+		Metadata struct {
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Spec struct {
+			NodeName   string `json:"nodeName"`
+			Containers []struct {
+				Resources struct {
+					Requests map[string]string `json:"requests"`
+					Limits   map[string]string `json:"limits"`
+				} `json:"resources"`
+			} `json:"containers"`
+		} `json:"spec"`
+	} `json:"items"`
+}
+
+// statsSummary kubelet /stats/summary 中节点文件系统的可用空间
+type statsSummary struct {
+	Node struct {
+		Fs struct {
+			AvailableBytes int64 `json:"availableBytes"`
+			CapacityBytes  int64 `json:"capacityBytes"`
+		} `json:"fs"`
+	} `json:"node"`
+}
+
+// capacityNode 容量规划过程中的节点状态
+type capacityNode struct {
+	report   *model.NodeCapacity
+	reserve  int64
+	occupied map[string]bool
+}
+
+func (n *capacityNode) fits(cpu, memory int64) bool {
+	return n.report.CPUFree-n.report.CPUPlanned >= cpu && n.report.MemoryFree-n.report.MemoryPlanned >= memory
+}
+
+// CheckCapacity 检查带 insuite.<角色>=true 标签的节点能否容纳 spec 中各组件的副本：
+// 按调度器的方式以资源请求计算空闲 CPU/内存（忽略目标命名空间中已有的 Pod，重复部署时会被替换），
+// 逐个副本放到剩余资源最多的节点上；同时检查 kubelet 报告的磁盘可用空间
+func (m *Manager) CheckCapacity(client *ssh.Client, spec AppSpec) (*model.CapacityReport, error) {
+	result, err := runKubectlIdempotent(client, "kubectl get nodes -o json")
+	if err != nil {
+		return nil, fmt.Errorf("获取集群节点失败: %v", err)
+	}
+	var nodeList capacityNodeList
+	if err := json.Unmarshal([]byte(result.Stdout), &nodeList); err != nil {
+		return nil, fmt.Errorf("解析集群节点失败: %v", err)
+	}
+
+	result, err = runKubectlIdempotent(client, "kubectl get pods -A --field-selector=status.phase!=Succeeded,status.phase!=Failed -o json")
+	if err != nil {
+		return nil, fmt.Errorf("读取 Pod 列表失败: %v", err)
+	}
+	var pods requestPodList
+	if err := json.Unmarshal([]byte(result.Stdout), &pods); err != nil {
+		return nil, fmt.Errorf("解析 Pod 列表失败: %v", err)
+	}
+	requestedCPU := make(map[string]int64)
+	requestedMemory := make(map[string]int64)
+	for _, pod := range pods.Items {
+		if pod.Spec.NodeName == "" || pod.Metadata.Namespace == spec.namespace() {
+			continue
+		}
+		for _, c := range pod.Spec.Containers {
+			requestedCPU[pod.Spec.NodeName] += CPUMillis(request(c.Resources.Requests, c.Resources.Limits, "cpu"))
+			requestedMemory[pod.Spec.NodeName] += MemoryBytes(request(c.Resources.Requests, c.Resources.Limits, "memory"))
+		}
+	}
+
+	roles := []string{RoleDatabase, RoleMiddleware, RoleApp}
+	report := &model.CapacityReport{Fits: true, Nodes: []model.NodeCapacity{}, Problems: []string{}}
+	nodes := make(map[string]*capacityNode)
+	candidates := make(map[string][]*capacityNode)
+	for _, item := range nodeList.Items {
+		var nodeRoles []string
+		for _, role := range roles {
+			if item.Metadata.Labels["insuite."+role] == "true" {
+				nodeRoles = append(nodeRoles, role)
+			}
+		}
+		if len(nodeRoles) == 0 {
+			continue
+		}
+		name := item.Metadata.Name
+		if item.Spec.Unschedulable {
+			report.Problems = append(report.Problems, fmt.Sprintf("节点 %s 已禁止调度（cordon），不参与容量规划", name))
+			continue
+		}
+
+		allocCPU := CPUMillis(item.Status.Allocatable["cpu"])
+		allocMemory := MemoryBytes(item.Status.Allocatable["memory"])
+		node := &capacityNode{
+			report: &model.NodeCapacity{
+				Name:              name,
+				Roles:             nodeRoles,
+				CPUAllocatable:    allocCPU,
+				CPUFree:           allocCPU - requestedCPU[name],
+				MemoryAllocatable: allocMemory,
+				MemoryFree:        allocMemory - requestedMemory[name],
+				Replicas:          make(map[string]int),
+			},
+			occupied: make(map[string]bool),
+		}
+		if result, err := runKubectlIdempotent(client, fmt.Sprintf("kubectl get --raw /api/v1/nodes/%s/proxy/stats/summary", name)); err == nil {
+			var summary statsSummary
+			if json.Unmarshal([]byte(result.Stdout), &summary) == nil {
+				node.report.DiskAvailable = summary.Node.Fs.AvailableBytes
+				node.reserve = int64(float64(summary.Node.Fs.CapacityBytes) * evictionReserve)
+			}
+		} else {
+			m.logger.Warnf("读取节点 %s 磁盘用量失败: %v", name, err)
+		}
+		nodes[name] = node
+		for _, role := range nodeRoles {
+			candidates[role] = append(candidates[role], node)
+		}
+	}
+
+	// metrics-server 可能被禁用，实际用量只作参考
+	if result, err := runKubectlIdempotent(client, "kubectl get --raw /apis/metrics.k8s.io/v1beta1/nodes"); err == nil {
+		var usage metricsList
+		if json.Unmarshal([]byte(result.Stdout), &usage) == nil {
+			for _, item := range usage.Items {
+				if node, ok := nodes[item.Metadata.Name]; ok {
+					node.report.CPUUsage = CPUMillis(item.Usage["cpu"])
+					node.report.MemoryUsage = MemoryBytes(item.Usage["memory"])
+				}
+			}
+		}
+	}
+
+	for _, role := range roles {
+		component := spec.component(role)
+		cpu := CPUMillis(requestOrLimit(component.Resources.RequestsCPU, component.Resources.LimitsCPU))
+		memory := MemoryBytes(requestOrLimit(component.Resources.RequestsMemory, component.Resources.LimitsMemory))
+		if len(candidates[role]) == 0 {
+			report.Fits = false
+			report.Problems = append(report.Problems, fmt.Sprintf("没有可调度的带 insuite.%s=true 标签的节点", role))
+			continue
+		}
+
+		for replica := 1; replica <= component.Replicas; replica++ {
+			var best *capacityNode
+			for _, node := range candidates[role] {
+				if component.AntiAffinity == AntiAffinityRequired && node.occupied[role] {
+					continue
+				}
+				if !node.fits(cpu, memory) {
+					continue
+				}
+				if best == nil || node.report.MemoryFree-node.report.MemoryPlanned > best.report.MemoryFree-best.report.MemoryPlanned {
+					best = node
+				}
+			}
+			if best == nil {
+				report.Fits = false
+				report.Problems = append(report.Problems, fmt.Sprintf("组件 %s 第 %d/%d 个副本（请求 CPU %dm、内存 %dMi）没有节点能容纳",
+					role, replica, component.Replicas, cpu, memory>>20))
+				break
+			}
+			best.report.CPUPlanned += cpu
+			best.report.MemoryPlanned += memory
+			best.report.DiskRequired += componentDisk[role]
+			best.report.Replicas[role]++
+			best.occupied[role] = true
+		}
+	}
+
+	for _, name := range sortedKeys(nodes) {
+		node := nodes[name]
+		if node.report.DiskAvailable > 0 && node.report.DiskRequired > 0 &&
+			node.report.DiskAvailable-node.report.DiskRequired < node.reserve {
+			report.Fits = false
+			report.Problems = append(report.Problems, fmt.Sprintf("节点 %s 磁盘可用 %dMi，部署后预计低于 kubelet 驱逐阈值（需要 %dMi，并保留 %dMi）",
+				name, node.report.DiskAvailable>>20, node.report.DiskRequired>>20, node.reserve>>20))
+		}
+		report.Nodes = append(report.Nodes, *node.report)
+	}
+	sort.Slice(report.Nodes, func(i, j int) bool { return report.Nodes[i].Name < report.Nodes[j].Name })
+	return report, nil
+}
+
+// FormatCapacityReport 容量不足时附在错误信息中的节点容量摘要
+func FormatCapacityReport(report *model.CapacityReport) string {
+	var b strings.Builder
+	for _, problem := range report.Problems {
+		fmt.Fprintf(&b, "\n- %s", problem)
+	}
+	for _, node := range report.Nodes {
+		fmt.Fprintf(&b, "\n节点 %s（%s）: CPU 空闲 %dm/可分配 %dm，计划 %dm；内存空闲 %dMi/可分配 %dMi，计划 %dMi；磁盘可用 %dMi，计划 %dMi",
+			node.Name, strings.Join(node.Roles, ","), node.CPUFree, node.CPUAllocatable, node.CPUPlanned,
+			node.MemoryFree>>20, node.MemoryAllocatable>>20, node.MemoryPlanned>>20,
+			node.DiskAvailable>>20, node.DiskRequired>>20)
+		if node.CPUUsage > 0 || node.MemoryUsage > 0 {
+			fmt.Fprintf(&b, "；实际用量 CPU %dm、内存 %dMi", node.CPUUsage, node.MemoryUsage>>20)
+		}
+	}
+	return b.String()
+}
+
+// request 容器未设置请求而设置了限制时，Kubernetes 以限制作为请求
+func request(requests, limits map[string]string, name string) string {
+	return requestOrLimit(requests[name], limits[name])
+}
+
+func requestOrLimit(request, limit string) string {
+	if request != "" {
+		return request
+	}
+	return limit
+}
@@ -0,0 +1,180 @@
+package k3s
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+const (
+	// ClusterIssuerName 部署创建的 ClusterIssuer，inSuite Ingress 通过注解引用
+	ClusterIssuerName = "k3s-deploy"
+	// certManagerNamespace cert-manager 所在命名空间，ClusterIssuer 引用的 Secret 需放在这里
+	certManagerNamespace = "cert-manager"
+	// issuerSecret CA 证书或 ACME DNS 服务商凭据
+	issuerSecret = "k3s-deploy-issuer"
+)
+
+// certManagerDeployments cert-manager 清单中的组件，webhook 就绪后才能创建 ClusterIssuer
+var certManagerDeployments = []string{"cert-manager", "cert-manager-cainjector", "cert-manager-webhook"}
+
+// CertManagerConfig cert-manager 安装参数
+type CertManagerConfig struct {
+	// ManifestURL 安装清单地址，在 Master 节点上下载
+	ManifestURL string
+	// CACert、CAKey 内部 CA 为该集群签发的中间 CA（PEM，CACert 含到根 CA 的证书链），
+	// 设置时创建 CA 类型的 ClusterIssuer
+	CACert []byte
+	CAKey  []byte
+	// ACME 设置时创建 ACME 类型的 ClusterIssuer
+	ACME *model.ACMEOptions
+}
+
+// InstallCertManager 安装 cert-manager 并创建（或更新）ClusterIssuer k3s-deploy
+func (m *Manager) InstallCertManager(client *ssh.Client, ws *Workspace, cfg CertManagerConfig, policy WaitPolicy) error {
+	m.logger.Infof("开始安装 cert-manager: %s", cfg.ManifestURL)
+	policy = policy.WithDefaults()
+
+	if _, err := runKubectl(client, "kubectl apply -f "+cfg.ManifestURL); err != nil {
+		return fmt.Errorf("应用 cert-manager 清单失败: %v", err)
+	}
+	for _, deployment := range certManagerDeployments {
+		if err := m.waitForRollout(client, certManagerNamespace, deployment, policy); err != nil {
+			return err
+		}
+	}
+
+	if err := m.applyIssuerSecret(client, ws, cfg); err != nil {
+		return err
+	}
+	issuer, err := clusterIssuer(cfg)
+	if err != nil {
+		return fmt.Errorf("生成 ClusterIssuer 失败: %v", err)
+	}
+	file, err := ws.Upload("cluster-issuer.yaml", issuer)
+	if err != nil {
+		return fmt.Errorf("上传 ClusterIssuer 失败: %v", err)
+	}
+	// webhook 的 Pod 就绪后证书注入仍需片刻，期间创建会被拒绝
+	deadline := time.Now().Add(policy.DeploymentTimeout)
+	for {
+		_, err := runKubectl(client, "kubectl apply -f "+file)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("创建 ClusterIssuer 失败: %v", err)
+		}
+		time.Sleep(policy.PollInterval)
+	}
+
+	cmd := fmt.Sprintf("kubectl wait clusterissuer/%s --for=condition=Ready --timeout=%ds", ClusterIssuerName, int(policy.DeploymentTimeout.Seconds()))
+	if _, err := runKubectlIdempotent(client, cmd); err != nil {
+		reason, _ := runKubectlIdempotent(client, fmt.Sprintf(`kubectl get clusterissuer %s -o jsonpath='{.status.conditions[?(@.type=="Ready")].message}'`, ClusterIssuerName))
+		message := ""
+		if reason != nil {
+			message = strings.TrimSpace(reason.Stdout)
+		}
+		return fmt.Errorf("ClusterIssuer %s 未就绪: %s", ClusterIssuerName, message)
+	}
+	m.logger.Infof("cert-manager 安装完成，ClusterIssuer %s 已就绪", ClusterIssuerName)
+	return nil
+}
+
+// applyIssuerSecret 创建 ClusterIssuer 引用的 Secret：CA 的证书与私钥，或 DNS 服务商凭据。
+// 内容先上传到工作目录再由 kubectl 读取，不出现在命令行中
+func (m *Manager) applyIssuerSecret(client *ssh.Client, ws *Workspace, cfg CertManagerConfig) error {
+	var args []string
+	switch {
+	case cfg.ACME != nil && cfg.ACME.DNS != nil:
+		dns := cfg.ACME.DNS
+		name, value := "api-token", dns.APIToken
+		if dns.Provider == "rfc2136" {
+			name, value = "tsig-secret", dns.TSIGSecret
+		}
+		file, err := ws.Upload("issuer-"+name, value)
+		if err != nil {
+			return fmt.Errorf("上传 DNS 服务商凭据失败: %v", err)
+		}
+		args = []string{"generic", issuerSecret, "--from-file=" + name + "=" + file}
+	case cfg.ACME == nil:
+		certFile, err := ws.Upload("issuer-ca.crt", string(cfg.CACert))
+		if err != nil {
+			return fmt.Errorf("上传 CA 证书失败: %v", err)
+		}
+		keyFile, err := ws.Upload("issuer-ca.key", string(cfg.CAKey))
+		if err != nil {
+			return fmt.Errorf("上传 CA 私钥失败: %v", err)
+		}
+		defer ws.Remove(keyFile)
+		args = []string{"tls", issuerSecret, "--cert=" + certFile, "--key=" + keyFile}
+	default:
+		return nil
+	}
+	cmd := fmt.Sprintf("kubectl -n %s create secret %s --dry-run=client -o yaml | kubectl apply -f -", certManagerNamespace, strings.Join(args, " "))
+	if _, err := runKubectl(client, cmd); err != nil {
+		return fmt.Errorf("创建 ClusterIssuer 凭据失败: %v", err)
+	}
+	return nil
+}
+
+// clusterIssuer 生成 ClusterIssuer 清单
+func clusterIssuer(cfg CertManagerConfig) (string, error) {
+	var spec map[string]any
+	if acme := cfg.ACME; acme != nil {
+		var solver map[string]any
+		if acme.Solver == model.SolverDNS01 {
+			solver = map[string]any{"dns01": dnsSolver(acme.DNS)}
+		} else {
+			// k3s 内置的 Traefik 应答 HTTP-01 质询，域名需解析到集群节点并能从公网访问 80 端口
+			solver = map[string]any{"http01": map[string]any{"ingress": map[string]any{"ingressClassName": "traefik"}}}
+		}
+		spec = map[string]any{"acme": map[string]any{
+			"email":               acme.Email,
+			"server":              acme.Server,
+			"privateKeySecretRef": map[string]any{"name": ClusterIssuerName + "-acme-account"},
+			"solvers":             []map[string]any{solver},
+		}}
+	} else {
+		spec = map[string]any{"ca": map[string]any{"secretName": issuerSecret}}
+	}
+
+	manifest, err := yaml.Marshal(map[string]any{
+		"apiVersion": "cert-manager.io/v1",
+		"kind":       "ClusterIssuer",
+		"metadata": map[string]any{
+			"name":   ClusterIssuerName,
+			"labels": map[string]string{ManagedByLabel: ManagedBy},
+		},
+		"spec": spec,
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(manifest), nil
+}
+
+func dnsSolver(dns *model.DNSSolverOptions) map[string]any {
+	if dns.Provider == "rfc2136" {
+		return map[string]any{"rfc2136": map[string]any{
+			"nameserver":    dns.Nameserver,
+			"tsigKeyName":   dns.TSIGKeyName,
+			"tsigAlgorithm": dns.TSIGAlgorithm,
+			"tsigSecretSecretRef": map[string]any{
+				"name": issuerSecret,
+				"key":  "tsig-secret",
+			},
+		}}
+	}
+	return map[string]any{"cloudflare": map[string]any{
+		"apiTokenSecretRef": map[string]any{
+			"name": issuerSecret,
+			"key":  "api-token",
+		},
+	}}
+}
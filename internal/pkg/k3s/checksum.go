@@ -0,0 +1,175 @@
+package k3s
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k3s-deploy-backend/internal/pkg/bundle"
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// Digest 安装过程中记录的文件摘要
+type Digest struct {
+	Node   string
+	Name   string
+	SHA256 string
+	// Source 期望摘要的来源（官方 sha256sum、离线安装包清单或请求固定的校验和）
+	Source string
+	// Verified 为 false 表示没有可比对的期望摘要，或按 AllowUnverified 跳过了不一致
+	Verified bool
+}
+
+// releaseLinePattern 安装脚本输出中选定的 k3s 版本
+var releaseLinePattern = regexp.MustCompile(`Using (v\S+) as release`)
+
+// scriptDigest 记录实际执行的安装脚本摘要（修改前）。脚本校验和已由 loadScript 比对，官方渠道不发布脚本校验和
+func (i *Installer) scriptDigest(nodeName string, opts InstallOptions, script []byte) Digest {
+	sum := sha256.Sum256(script)
+	d := Digest{Node: nodeName, Name: "install.sh", SHA256: hex.EncodeToString(sum[:])}
+	switch {
+	case opts.Airgap != nil:
+		d.Source, d.Verified = "离线安装包清单", true
+	case opts.Script != nil && opts.Script.SHA256 != "":
+		d.Source, d.Verified = "installScript.sha256", true
+	default:
+		i.logger.Warnf("安装脚本未固定校验和，SHA256: %s（可通过 installScript.sha256 或离线安装包固定）", d.SHA256)
+	}
+	return d
+}
+
+// binaryPathCommand 输出节点上已安装的 k3s 二进制路径
+const binaryPathCommand = "for p in /usr/local/bin/k3s /opt/bin/k3s; do [ -x $p ] && echo $p && break; done"
+
+// verifyBinary 按官方 sha256sum 校验安装脚本下载的 k3s 二进制。校验失败或无法获取官方校验和时删除二进制并返回错误，
+// allowUnverified 为 true 时只记录警告
+func (i *Installer) verifyBinary(client *ssh.Client, nodeName, installURL, installOutput string, allowUnverified bool) (Digest, error) {
+	d := Digest{Node: nodeName, Name: "k3s"}
+
+	result, err := client.ExecuteIdempotentCommand(binaryPathCommand)
+	if err != nil || strings.TrimSpace(result.Stdout) == "" {
+		return d, fmt.Errorf("未找到安装的 k3s 二进制")
+	}
+	binaryPath := strings.TrimSpace(result.Stdout)
+	d.SHA256 = remoteSHA256(client, binaryPath)
+
+	expected, source, err := i.officialBinaryChecksum(client, installURL, installOutput)
+	if err == nil && expected != d.SHA256 {
+		err = fmt.Errorf("k3s 二进制与官方校验和不一致: 期望 %s，实际 %q", expected, d.SHA256)
+	}
+	if err != nil {
+		if !allowUnverified {
+			client.ExecuteCommand("rm -f " + binaryPath)
+			return d, fmt.Errorf("%v，已删除未通过校验的 k3s 二进制（确认来源可信时可设置 allowUnverifiedArtifacts 继续）", err)
+		}
+		i.logger.Warnf("%v，已按 allowUnverifiedArtifacts 继续安装", err)
+		return d, nil
+	}
+
+	d.Source, d.Verified = source, true
+	i.logger.Infof("k3s 二进制校验通过: sha256 %s（%s）", d.SHA256, source)
+	return d, nil
+}
+
+// checksumSources 获取官方 sha256sum 的地址：配置了 registry.release_url 时只使用该地址，
+// 否则经国内镜像安装时先查镜像再查 GitHub，其余情况相反
+func (i *Installer) checksumSources(installURL string) []string {
+	if i.mirrors.ReleaseURL != "" {
+		return []string{i.mirrors.ReleaseURL}
+	}
+	if installURL == officialCNInstallURL {
+		return []string{bundle.MirrorReleaseURL, bundle.ReleaseURL}
+	}
+	return []string{bundle.ReleaseURL, bundle.MirrorReleaseURL}
+}
+
+// officialBinaryChecksum 根据安装脚本选定的版本和节点架构获取官方 sha256sum 中的二进制摘要
+func (i *Installer) officialBinaryChecksum(client *ssh.Client, installURL, installOutput string) (string, string, error) {
+	match := releaseLinePattern.FindStringSubmatch(installOutput)
+	if match == nil {
+		return "", "", fmt.Errorf("无法从安装脚本输出确定 k3s 版本")
+	}
+	version := match[1]
+
+	result, err := client.ExecuteIdempotentCommand("uname -m")
+	if err != nil {
+		return "", "", fmt.Errorf("检测节点架构失败: %v", err)
+	}
+	arch := unameArch[strings.TrimSpace(result.Stdout)]
+	binary, ok := bundle.BinaryName(arch)
+	if !ok {
+		return "", "", fmt.Errorf("不支持的节点架构: %s", strings.TrimSpace(result.Stdout))
+	}
+
+	var sums map[string]string
+	var failures []string
+	for _, base := range i.checksumSources(installURL) {
+		if sums, err = bundle.OfficialChecksums(base, version, arch); err == nil {
+			break
+		}
+		i.logger.Warnf("获取官方校验和失败: %v", err)
+		failures = append(failures, err.Error())
+	}
+	if sums == nil {
+		return "", "", fmt.Errorf("获取官方校验和失败: %s", strings.Join(failures, "; "))
+	}
+	expected, ok := sums[binary]
+	if !ok {
+		return "", "", fmt.Errorf("官方校验和中没有 %s", binary)
+	}
+	return expected, fmt.Sprintf("%s sha256sum-%s.txt", version, arch), nil
+}
+
+// previousBinary 重新安装前节点上已有的 k3s 二进制：备份路径和服务是否在运行，首次安装时均为空
+type previousBinary struct {
+	path   string
+	backup string
+	active bool
+}
+
+// backupBinary 重新安装前备份已有的 k3s 二进制并记录服务状态，新二进制未通过校验或安装失败时据此恢复
+func (i *Installer) backupBinary(client *ssh.Client, osInfo *hostos.Info, unit string) previousBinary {
+	var prev previousBinary
+	result, err := client.ExecuteIdempotentCommand(binaryPathCommand)
+	if err != nil || strings.TrimSpace(result.Stdout) == "" {
+		return prev
+	}
+	prev.path = strings.TrimSpace(result.Stdout)
+	result, err = client.ExecuteIdempotentCommand(osInfo.ServiceActiveCommand(unit))
+	prev.active = err == nil && strings.TrimSpace(result.Stdout) == "active"
+
+	backup := prev.path + ".k3s-deploy.bak"
+	if _, err := client.ExecuteCommand(fmt.Sprintf("cp -p %s %s", ssh.Quote(prev.path), ssh.Quote(backup))); err != nil {
+		i.logger.Warnf("备份已有的 k3s 二进制失败，校验失败时无法恢复: %v", err)
+		return prev
+	}
+	prev.backup = backup
+	i.logger.Infof("重新安装: 已备份 %s（%s 服务运行中: %v）", prev.path, unit, prev.active)
+	return prev
+}
+
+// restore 恢复备份的 k3s 二进制，服务原本在运行时重新启动
+func (p previousBinary) restore(client *ssh.Client, osInfo *hostos.Info, unit string) error {
+	if p.backup == "" {
+		return nil
+	}
+	if _, err := client.ExecuteCommand(fmt.Sprintf("mv -f %s %s", ssh.Quote(p.backup), ssh.Quote(p.path))); err != nil {
+		return fmt.Errorf("恢复原 k3s 二进制失败: %v", err)
+	}
+	if p.active {
+		if _, err := client.ExecuteCommand(osInfo.ServiceRestartCommand(unit)); err != nil {
+			return fmt.Errorf("以原 k3s 二进制重启 %s 服务失败: %v", unit, err)
+		}
+	}
+	return nil
+}
+
+// cleanup 删除备份
+func (p previousBinary) cleanup(client *ssh.Client) {
+	if p.backup != "" {
+		client.ExecuteCommand("rm -f " + ssh.Quote(p.backup))
+	}
+}
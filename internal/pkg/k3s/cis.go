@@ -0,0 +1,361 @@
+package k3s
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// CIS 加固写入的文件，内核参数须在 k3s 启动前生效，否则启用 protect-kernel-defaults 的 kubelet 会退出
+const (
+	CISSysctlPath = "/etc/sysctl.d/90-k3s-deploy-cis.conf"
+	CISDropInPath = "/etc/rancher/k3s/config.yaml.d/70-k3s-deploy-cis.yaml"
+	CISBenchmark  = "CIS Kubernetes Benchmark v1.8（k3s 自评估指南）"
+)
+
+// cisKernelParams kubelet 在 protect-kernel-defaults 下要求的内核参数
+var cisKernelParams = [][2]string{
+	{"vm.panic_on_oom", "0"},
+	{"vm.overcommit_memory", "1"},
+	{"kernel.panic", "10"},
+	{"kernel.panic_on_oops", "1"},
+	{"kernel.keys.root_maxkeys", "1000000"},
+	{"kernel.keys.root_maxbytes", "25000000"},
+}
+
+// cisFilePermissions 收紧 k3s 默认以 644 创建的证书和 kubeconfig，etcd 数据目录只允许 root 访问。
+// k3s 轮换证书后需重新执行
+const cisFilePermissions = `d=/var/lib/rancher/k3s
+if [ -d $d/server/tls ]; then find $d/server/tls -type f \( -name '*.crt' -o -name '*.key' \) -exec chmod 600 {} +; chown -R root:root $d/server/tls; fi
+if [ -d $d/server/cred ]; then chmod 600 $d/server/cred/*.kubeconfig 2>/dev/null; fi
+if [ -d $d/server/db/etcd ]; then chmod 700 $d/server/db/etcd; fi
+chmod 600 $d/agent/*.kubeconfig $d/agent/client-ca.crt 2>/dev/null
+true`
+
+// CISSecurity 返回 CIS 加固要求的安全选项：未设置的 PodSecurity 准入和审计日志使用默认值，并启用 Secret 加密。
+// 未设置 cis 时原样返回
+func CISSecurity(opts *model.SecurityOptions) *model.SecurityOptions {
+	if opts == nil || !opts.CIS {
+		return opts
+	}
+	hardened := *opts
+	if hardened.PodSecurity == nil {
+		hardened.PodSecurity = &model.PodSecurityOptions{}
+	}
+	if hardened.Audit == nil {
+		hardened.Audit = &model.AuditPolicyOptions{}
+	}
+	hardened.SecretsEncryption = true
+	return &hardened
+}
+
+// CISUndoCommand 删除 CIS 加固写入的内核参数和 k3s 配置，已生效的内核参数保持到重启
+const CISUndoCommand = "rm -f " + CISSysctlPath + " " + CISDropInPath
+
+// WriteCISConfig 在安装 k3s 前写入 CIS 要求的内核参数并立即加载，同时写入启用 protect-kernel-defaults 的 k3s 配置。
+// Server 节点额外限制已终止 Pod 的保留数量
+func (i *Installer) WriteCISConfig(client *ssh.Client, server bool) error {
+	var sysctl strings.Builder
+	for _, param := range cisKernelParams {
+		fmt.Fprintf(&sysctl, "%s = %s\n", param[0], param[1])
+	}
+	dropIn := "protect-kernel-defaults: true\nkubelet-arg+:\n  - streaming-connection-idle-timeout=5m\n"
+	if server {
+		dropIn += "kube-controller-manager-arg+:\n  - terminated-pod-gc-threshold=10\n"
+	}
+
+	if _, err := client.ExecuteCommand("mkdir -p /etc/sysctl.d /etc/rancher/k3s/config.yaml.d"); err != nil {
+		return fmt.Errorf("创建配置目录失败: %v", err)
+	}
+	if err := client.UploadFile(sysctl.String(), CISSysctlPath); err != nil {
+		return fmt.Errorf("上传 %s 失败: %v", CISSysctlPath, err)
+	}
+	if _, err := client.ExecuteCommand("sysctl -p " + CISSysctlPath); err != nil {
+		return fmt.Errorf("加载 CIS 内核参数失败: %v", err)
+	}
+	if err := client.UploadFile(dropIn, CISDropInPath); err != nil {
+		return fmt.Errorf("上传 %s 失败: %v", CISDropInPath, err)
+	}
+	if _, err := client.ExecuteCommand("chmod 600 " + CISDropInPath); err != nil {
+		return fmt.Errorf("设置 %s 权限失败: %v", CISDropInPath, err)
+	}
+	i.logger.Infof("已写入 %s 和 %s", CISSysctlPath, CISDropInPath)
+	return nil
+}
+
+// hardenCISFiles 安装完成后收紧证书、kubeconfig 和 etcd 数据目录的权限
+func (i *Installer) hardenCISFiles(client *ssh.Client) error {
+	if _, err := client.ExecuteCommand(cisFilePermissions); err != nil {
+		return fmt.Errorf("设置 CIS 文件权限失败: %v", err)
+	}
+	i.logger.Info("已按 CIS 要求收紧证书与 kubeconfig 的文件权限")
+	return nil
+}
+
+// cisScanScript 收集检查所需的节点信息，每行一项：
+// file <名称> <权限> <属主:属组>|missing、count <名称> <数量>、
+// run <k3s 启动组件时记录的参数行>、config <k3s 配置行>、encrypt <secrets-encrypt status 输出行>
+const cisScanScript = `d=/var/lib/rancher/k3s
+f() { if [ -e "$2" ]; then echo "file $1 $(stat -c '%a %U:%G' "$2")"; else echo "file $1 missing"; fi; }
+f etcd $d/server/db/etcd
+f admin $d/server/cred/admin.kubeconfig
+f scheduler $d/server/cred/scheduler.kubeconfig
+f controller $d/server/cred/controller.kubeconfig
+f kubelet $d/agent/kubelet.kubeconfig
+f client-ca $d/agent/client-ca.crt
+echo "count pki-owner $(find $d/server/tls ! -user root 2>/dev/null | wc -l)"
+echo "count pki-crt $(find $d/server/tls -type f -name '*.crt' -perm /177 2>/dev/null | wc -l)"
+echo "count pki-key $(find $d/server/tls -type f -name '*.key' -perm /177 2>/dev/null | wc -l)"
+{ journalctl -u k3s -o cat --no-pager 2>/dev/null || cat /var/log/k3s.log 2>/dev/null; } | grep -E 'Running (kube-apiserver|kube-controller-manager|kubelet) ' | tail -n 20 | sed 's/^/run /'
+cat /etc/rancher/k3s/config.yaml /etc/rancher/k3s/config.yaml.d/*.yaml 2>/dev/null | sed 's/^/config /'
+k3s secrets-encrypt status 2>/dev/null | sed 's/^/encrypt /'
+true`
+
+// cisFacts 从节点收集的检查依据
+type cisFacts struct {
+	files  map[string]string
+	counts map[string]int
+	// args 组件 -> 参数 -> 值，取自 k3s 最近一次启动组件时的日志；日志已轮转时为空
+	args map[string]map[string]string
+	// config 参数 -> 值，取自 k3s 配置文件中显式设置的参数，启动日志不可用时使用
+	config     map[string]string
+	encryption *model.SecretsEncryptionStatus
+}
+
+var (
+	// runArgPattern 启动日志中的 --name=value 参数
+	runArgPattern = regexp.MustCompile(`--([a-z0-9-]+)=([^\s"]*)`)
+	// configArgPattern 配置文件中的 name=value（如 kubelet-arg 列表项）或 name: value
+	configArgPattern = regexp.MustCompile(`(?m)(?:^|[\s'"-])([A-Za-z0-9][A-Za-z0-9._-]*)(?:=|:[ \t]*)["']?([^\s"']*)`)
+)
+
+func parseCISFacts(output string) *cisFacts {
+	facts := &cisFacts{
+		files:  map[string]string{},
+		counts: map[string]int{},
+		args:   map[string]map[string]string{},
+		config: map[string]string{},
+	}
+	var config, encrypt []string
+	for _, line := range strings.Split(output, "\n") {
+		kind, rest, _ := strings.Cut(line, " ")
+		switch kind {
+		case "file":
+			name, value, _ := strings.Cut(rest, " ")
+			facts.files[name] = value
+		case "count":
+			name, value, _ := strings.Cut(rest, " ")
+			n, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				n = -1
+			}
+			facts.counts[name] = n
+		case "run":
+			for _, component := range []string{"kube-apiserver", "kube-controller-manager", "kubelet"} {
+				_, after, found := strings.Cut(rest, "Running "+component+" ")
+				if !found {
+					continue
+				}
+				// 同一组件只保留最近一次启动的参数
+				args := map[string]string{}
+				for _, m := range runArgPattern.FindAllStringSubmatch(after, -1) {
+					args[m[1]] = m[2]
+				}
+				facts.args[component] = args
+			}
+		case "config":
+			config = append(config, rest)
+		case "encrypt":
+			encrypt = append(encrypt, rest)
+		}
+	}
+	// 同一参数出现多次时以最后一次为准
+	for _, m := range configArgPattern.FindAllStringSubmatch(strings.Join(config, "\n"), -1) {
+		facts.config[m[1]] = m[2]
+	}
+	if len(encrypt) > 0 {
+		facts.encryption = parseSecretsEncryptionStatus(strings.Join(encrypt, "\n"))
+	}
+	return facts
+}
+
+// arg 返回组件参数的值。启动日志可用时以其为准（未出现的参数为组件默认值），
+// 否则从配置文件中查找显式设置的值，找不到时返回 fallback
+func (f *cisFacts) arg(component, name, fallback string) string {
+	if args, ok := f.args[component]; ok {
+		if value, ok := args[name]; ok {
+			return value
+		}
+		return fallback
+	}
+	if value, ok := f.config[name]; ok {
+		return value
+	}
+	return fallback
+}
+
+// cisCheck 一条控制项及其判定方法，返回是否通过（skip 时为 nil）和实际值
+type cisCheck struct {
+	id, title, remediation string
+	check                  func(f *cisFacts) (*bool, string)
+}
+
+// fileCheck 检查文件权限不宽于 mode，文件不存在时跳过
+func fileCheck(name string, mode int) func(f *cisFacts) (*bool, string) {
+	return func(f *cisFacts) (*bool, string) {
+		value := f.files[name]
+		if value == "" || value == "missing" {
+			return nil, "文件不存在"
+		}
+		perm, _, _ := strings.Cut(value, " ")
+		actual, err := strconv.ParseInt(perm, 8, 32)
+		ok := err == nil && int(actual)&^mode == 0
+		return &ok, perm
+	}
+}
+
+// ownerCheck 检查文件属主为 root:root，文件不存在时跳过
+func ownerCheck(name string) func(f *cisFacts) (*bool, string) {
+	return func(f *cisFacts) (*bool, string) {
+		value := f.files[name]
+		if value == "" || value == "missing" {
+			return nil, "文件不存在"
+		}
+		_, owner, _ := strings.Cut(value, " ")
+		ok := owner == "root:root"
+		return &ok, owner
+	}
+}
+
+// countCheck 检查不合规的文件数为 0
+func countCheck(name string) func(f *cisFacts) (*bool, string) {
+	return func(f *cisFacts) (*bool, string) {
+		n, found := f.counts[name]
+		if !found || n < 0 {
+			return nil, "无法读取"
+		}
+		ok := n == 0
+		return &ok, fmt.Sprintf("%d 个文件不合规", n)
+	}
+}
+
+// argCheck 按组件参数值判定，fallback 为 k3s 未设置时的默认值
+func argCheck(component, name, fallback string, pass func(string) bool) func(f *cisFacts) (*bool, string) {
+	return func(f *cisFacts) (*bool, string) {
+		value := f.arg(component, name, fallback)
+		ok := pass(value)
+		if value == "" {
+			value = "未设置"
+		}
+		return &ok, fmt.Sprintf("%s=%s", name, value)
+	}
+}
+
+func notEmpty(value string) bool { return value != "" }
+
+// atLeast 参数值为不小于 min 的整数
+func atLeast(min int) func(string) bool {
+	return func(value string) bool {
+		n, err := strconv.Atoi(value)
+		return err == nil && n >= min
+	}
+}
+
+// cisChecks 可通过 SSH 在 Master 上自动判定的控制项，编号与 k3s CIS 1.8 自评估指南一致
+var cisChecks = []cisCheck{
+	{"1.1.11", "etcd 数据目录权限为 700 或更严格", "chmod 700 /var/lib/rancher/k3s/server/db/etcd", fileCheck("etcd", 0o700)},
+	{"1.1.13", "admin.kubeconfig 权限为 600 或更严格", "chmod 600 /var/lib/rancher/k3s/server/cred/admin.kubeconfig", fileCheck("admin", 0o600)},
+	{"1.1.14", "admin.kubeconfig 属主为 root:root", "chown root:root /var/lib/rancher/k3s/server/cred/admin.kubeconfig", ownerCheck("admin")},
+	{"1.1.15", "scheduler.kubeconfig 权限为 600 或更严格", "chmod 600 /var/lib/rancher/k3s/server/cred/scheduler.kubeconfig", fileCheck("scheduler", 0o600)},
+	{"1.1.16", "scheduler.kubeconfig 属主为 root:root", "chown root:root /var/lib/rancher/k3s/server/cred/scheduler.kubeconfig", ownerCheck("scheduler")},
+	{"1.1.17", "controller.kubeconfig 权限为 600 或更严格", "chmod 600 /var/lib/rancher/k3s/server/cred/controller.kubeconfig", fileCheck("controller", 0o600)},
+	{"1.1.18", "controller.kubeconfig 属主为 root:root", "chown root:root /var/lib/rancher/k3s/server/cred/controller.kubeconfig", ownerCheck("controller")},
+	{"1.1.19", "PKI 目录及文件属主为 root:root", "chown -R root:root /var/lib/rancher/k3s/server/tls", countCheck("pki-owner")},
+	{"1.1.20", "PKI 证书文件权限为 600 或更严格", "chmod 600 /var/lib/rancher/k3s/server/tls/*.crt", countCheck("pki-crt")},
+	{"1.1.21", "PKI 私钥文件权限为 600", "chmod 600 /var/lib/rancher/k3s/server/tls/*.key", countCheck("pki-key")},
+	{"1.2.16", "API Server --profiling 为 false", "在 kube-apiserver-arg 中设置 profiling=false",
+		argCheck("kube-apiserver", "profiling", "false", func(v string) bool { return v == "false" })},
+	{"1.2.17", "API Server 设置 --audit-log-path", "部署时设置 security.audit 或 security.cis",
+		argCheck("kube-apiserver", "audit-log-path", "", notEmpty)},
+	{"1.2.18", "API Server --audit-log-maxage 不小于 30", "security.audit.maxAgeDays 设为 30 以上",
+		argCheck("kube-apiserver", "audit-log-maxage", "", atLeast(30))},
+	{"1.2.19", "API Server --audit-log-maxbackup 不小于 10", "security.audit.maxBackups 设为 10 以上",
+		argCheck("kube-apiserver", "audit-log-maxbackup", "", atLeast(10))},
+	{"1.2.20", "API Server --audit-log-maxsize 不小于 100", "security.audit.maxSizeMb 设为 100 以上",
+		argCheck("kube-apiserver", "audit-log-maxsize", "", atLeast(100))},
+	{"1.2.27", "启用 Secret 静态加密（--encryption-provider-config）", "部署时设置 security.secretsEncryption 或 security.cis（只能在首次安装时启用）",
+		func(f *cisFacts) (*bool, string) {
+			if f.encryption == nil {
+				return nil, "无法读取 k3s secrets-encrypt status"
+			}
+			ok := f.encryption.Enabled
+			if ok {
+				return &ok, "Enabled"
+			}
+			return &ok, "Disabled"
+		}},
+	{"1.3.1", "Controller Manager 设置 --terminated-pod-gc-threshold", "在 kube-controller-manager-arg 中设置 terminated-pod-gc-threshold=10",
+		argCheck("kube-controller-manager", "terminated-pod-gc-threshold", "", notEmpty)},
+	{"4.1.5", "kubelet.kubeconfig 权限为 600 或更严格", "chmod 600 /var/lib/rancher/k3s/agent/kubelet.kubeconfig", fileCheck("kubelet", 0o600)},
+	{"4.1.6", "kubelet.kubeconfig 属主为 root:root", "chown root:root /var/lib/rancher/k3s/agent/kubelet.kubeconfig", ownerCheck("kubelet")},
+	{"4.1.7", "kubelet 客户端 CA 证书权限为 600 或更严格", "chmod 600 /var/lib/rancher/k3s/agent/client-ca.crt", fileCheck("client-ca", 0o600)},
+	{"4.1.8", "kubelet 客户端 CA 证书属主为 root:root", "chown root:root /var/lib/rancher/k3s/agent/client-ca.crt", ownerCheck("client-ca")},
+	{"4.2.5", "kubelet --streaming-connection-idle-timeout 不为 0", "在 kubelet-arg 中设置 streaming-connection-idle-timeout=5m",
+		argCheck("kubelet", "streaming-connection-idle-timeout", "4h0m0s", func(v string) bool { return v != "0" && v != "0s" })},
+	{"4.2.6", "kubelet --protect-kernel-defaults 为 true", "部署时设置 security.cis（harden-nodes 先设置内核参数再启用该参数）",
+		argCheck("kubelet", "protect-kernel-defaults", "false", func(v string) bool { return v == "true" })},
+	{"5.2.1", "配置集群级 PodSecurity 准入（admission-control-config-file）", "部署时设置 security.podSecurity 或 security.cis",
+		argCheck("kube-apiserver", "admission-control-config-file", "", notEmpty)},
+}
+
+// evaluateCIS 逐项判定控制项
+func evaluateCIS(facts *cisFacts) []model.CISControl {
+	controls := make([]model.CISControl, 0, len(cisChecks))
+	for _, c := range cisChecks {
+		ok, actual := c.check(facts)
+		control := model.CISControl{ID: c.id, Title: c.title, Actual: actual}
+		switch {
+		case ok == nil:
+			control.Status = model.CISSkip
+		case *ok:
+			control.Status = model.CISPass
+		default:
+			control.Status = model.CISFail
+			control.Remediation = c.remediation
+		}
+		controls = append(controls, control)
+	}
+	return controls
+}
+
+// ScanCIS 在 Master 节点上检查可自动判定的 CIS 控制项。组件参数取自 k3s 启动日志，
+// 日志已轮转时只能识别配置文件中显式设置的参数
+func (m *Manager) ScanCIS(client *ssh.Client) (*model.CISReport, error) {
+	result, err := client.ExecuteIdempotentCommand(cisScanScript)
+	if err != nil {
+		return nil, fmt.Errorf("收集 CIS 检查信息失败: %v", err)
+	}
+
+	facts := parseCISFacts(result.Stdout)
+	report := &model.CISReport{
+		Benchmark:   CISBenchmark,
+		GeneratedAt: time.Now(),
+		Controls:    evaluateCIS(facts),
+	}
+	for _, control := range report.Controls {
+		switch control.Status {
+		case model.CISPass:
+			report.Passed++
+		case model.CISFail:
+			report.Failed++
+		default:
+			report.Skipped++
+		}
+	}
+	return report, nil
+}
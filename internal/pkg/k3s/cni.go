@@ -0,0 +1,272 @@
+package k3s
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+const (
+	// CNIManifestPath k3s 启动时自动应用 server/manifests 下的清单，CNI 的 HelmChart 在安装前写入
+	CNIManifestPath = "/var/lib/rancher/k3s/server/manifests/k3s-deploy-cni.yaml"
+	// defaultClusterCIDR k3s 默认的 Pod 网段，CNI 的地址池与之保持一致
+	defaultClusterCIDR = "10.42.0.0/16"
+)
+
+var (
+	chartVersionPattern = regexp.MustCompile(`^v?\d+\.\d+\.\d+$`)
+	chartRepoPattern    = regexp.MustCompile(`^https?://[A-Za-z0-9.:/_-]+$`)
+	registryPattern     = regexp.MustCompile(`^[A-Za-z0-9.-]+(:\d+)?(/[a-z0-9._-]+)*$`)
+)
+
+// cniChart CNI 插件的 Helm chart 及安装后需要等待的 DaemonSet
+type cniChart struct {
+	repo            string
+	chart           string
+	version         string
+	targetNamespace string
+	// daemonSet 每个节点上运行的 CNI Pod，格式为 命名空间/名称
+	daemonSet string
+	// ports 节点间需要放行的端口（flannel 的 8472/udp 已在基础端口中）
+	ports []string
+	// modules 节点需要的内核模块
+	modules []string
+}
+
+var cniCharts = map[string]cniChart{
+	// Calico 由 tigera-operator 管理，使用 VXLAN 封装，不依赖节点间 BGP
+	model.CNICalico: {
+		repo:            "https://docs.tigera.io/calico/charts",
+		chart:           "tigera-operator",
+		version:         "v3.28.2",
+		targetNamespace: "tigera-operator",
+		daemonSet:       "calico-system/calico-node",
+		ports:           []string{"4789/udp", "5473/tcp"},
+		modules:         []string{"vxlan", "ip_set", "xt_set"},
+	},
+	// Cilium 保留 kube-proxy，使用默认的 VXLAN 隧道（8472/udp）
+	model.CNICilium: {
+		repo:            "https://helm.cilium.io/",
+		chart:           "cilium",
+		version:         "1.16.3",
+		targetNamespace: "kube-system",
+		daemonSet:       "kube-system/cilium",
+		ports:           []string{"4240/tcp"},
+		modules:         []string{"vxlan"},
+	},
+}
+
+// ValidateCNI 校验 CNI 选项
+func ValidateCNI(opts *model.CNIOptions) error {
+	if opts == nil {
+		return nil
+	}
+	if _, ok := cniCharts[opts.Plugin]; !ok {
+		return fmt.Errorf("不支持的 CNI 插件: %s（支持 calico、cilium）", opts.Plugin)
+	}
+	if opts.Version != "" && !chartVersionPattern.MatchString(opts.Version) {
+		return fmt.Errorf("无效的 %s chart 版本: %s", opts.Plugin, opts.Version)
+	}
+	if opts.ChartRepo != "" && !chartRepoPattern.MatchString(opts.ChartRepo) {
+		return fmt.Errorf("无效的 chart 仓库地址: %s", opts.ChartRepo)
+	}
+	if opts.Registry != "" && !registryPattern.MatchString(opts.Registry) {
+		return fmt.Errorf("无效的镜像仓库: %s", opts.Registry)
+	}
+	return nil
+}
+
+// CNIServerArgs 替换 CNI 时 Server 的安装参数：不启动 flannel，网络策略由 CNI 插件执行
+func CNIServerArgs(opts *model.CNIOptions) []string {
+	if opts == nil {
+		return nil
+	}
+	return []string{"--flannel-backend=none", "--disable-network-policy"}
+}
+
+// CNIPorts 防火墙保持启用时 CNI 插件额外需要放行的端口
+func CNIPorts(opts *model.CNIOptions) []string {
+	if opts == nil {
+		return nil
+	}
+	return cniCharts[opts.Plugin].ports
+}
+
+// cniKernelModulesScript 输出既未内置也无法加载的内核模块
+const cniKernelModulesScript = `for m in %s; do
+  grep -q "/$m.ko" /lib/modules/$(uname -r)/modules.builtin 2>/dev/null || modprobe -q "$m" 2>/dev/null || echo "$m"
+done`
+
+// CheckCNIKernel 检查节点内核是否满足 CNI 插件的要求：Calico 需要 vxlan 与 ipset 模块；
+// Cilium 需要 5.4 以上内核（RHEL 8 的 4.18 已回合所需特性）并支持 BPF 文件系统
+func CheckCNIKernel(client *ssh.Client, osInfo *hostos.Info, opts *model.CNIOptions) error {
+	chart := cniCharts[opts.Plugin]
+
+	result, err := client.ExecuteIdempotentCommand("uname -r")
+	if err != nil {
+		return fmt.Errorf("获取内核版本失败: %v", err)
+	}
+	release := strings.TrimSpace(result.Stdout)
+
+	if opts.Plugin == model.CNICilium {
+		var major, minor int
+		if _, err := fmt.Sscanf(release, "%d.%d", &major, &minor); err != nil {
+			return fmt.Errorf("无法解析内核版本 %q: %v", release, err)
+		}
+		rhel8 := osInfo.Family == hostos.FamilyRHEL && major == 4 && minor == 18
+		if major < 5 || major == 5 && minor < 4 {
+			if !rhel8 {
+				return fmt.Errorf("内核 %s 低于 Cilium 要求的 5.4", release)
+			}
+		}
+		if _, err := client.ExecuteIdempotentCommand("grep -qw bpf /proc/filesystems"); err != nil {
+			return fmt.Errorf("内核 %s 不支持 BPF 文件系统，无法运行 Cilium", release)
+		}
+	}
+
+	result, err = client.ExecuteIdempotentCommand(fmt.Sprintf(cniKernelModulesScript, strings.Join(chart.modules, " ")))
+	if err != nil {
+		return fmt.Errorf("检查内核模块失败: %v", err)
+	}
+	if missing := strings.Fields(result.Stdout); len(missing) > 0 {
+		return fmt.Errorf("内核 %s 缺少 %s 需要的模块: %s", release, opts.Plugin, strings.Join(missing, ", "))
+	}
+	return nil
+}
+
+// cniValues 生成 chart 的 values：地址池使用 k3s 默认 Pod 网段，设置了 Registry 时替换全部镜像的仓库
+func cniValues(opts *model.CNIOptions) map[string]any {
+	registry := strings.TrimSuffix(opts.Registry, "/")
+	if opts.Plugin == model.CNICalico {
+		installation := map[string]any{
+			"cni": map[string]any{"type": "Calico"},
+			"calicoNetwork": map[string]any{
+				"bgp": "Disabled",
+				// k3s 的 Pod 需要在容器内转发流量（如 servicelb）
+				"containerIPForwarding": "Enabled",
+				"ipPools": []map[string]any{{
+					"cidr":          defaultClusterCIDR,
+					"encapsulation": "VXLAN",
+					"natOutgoing":   "Enabled",
+					"nodeSelector":  "all()",
+				}},
+			},
+		}
+		values := map[string]any{"installation": installation}
+		if registry != "" {
+			installation["registry"] = registry + "/"
+			values["tigeraOperator"] = map[string]any{"registry": registry}
+		}
+		return values
+	}
+
+	values := map[string]any{
+		"ipam": map[string]any{
+			"operator": map[string]any{"clusterPoolIPv4PodCIDRList": []string{defaultClusterCIDR}},
+		},
+		"operator": map[string]any{"replicas": 1},
+	}
+	if registry != "" {
+		// 镜像同步到其他仓库后摘要不一定保留，改为按标签拉取
+		image := func(name string) map[string]any {
+			return map[string]any{"repository": registry + "/cilium/" + name, "useDigest": false}
+		}
+		values["image"] = image("cilium")
+		values["operator"].(map[string]any)["image"] = image("operator")
+		values["envoy"] = map[string]any{"image": image("cilium-envoy")}
+	}
+	return values
+}
+
+// cniManifest 生成部署 CNI 的 HelmChart。bootstrap 使安装 Job 在节点尚无 Pod 网络时以主机网络运行
+func cniManifest(opts *model.CNIOptions) (string, error) {
+	chart := cniCharts[opts.Plugin]
+	if opts.Version != "" {
+		chart.version = opts.Version
+	}
+	if opts.ChartRepo != "" {
+		chart.repo = opts.ChartRepo
+	}
+	values, err := yaml.Marshal(cniValues(opts))
+	if err != nil {
+		return "", err
+	}
+	manifest, err := yaml.Marshal(map[string]any{
+		"apiVersion": "helm.cattle.io/v1",
+		"kind":       "HelmChart",
+		"metadata": map[string]any{
+			"name":      opts.Plugin,
+			"namespace": "kube-system",
+			"labels":    map[string]string{ManagedByLabel: ManagedBy},
+		},
+		"spec": map[string]any{
+			"repo":            chart.repo,
+			"chart":           chart.chart,
+			"version":         chart.version,
+			"targetNamespace": chart.targetNamespace,
+			"createNamespace": true,
+			"bootstrap":       true,
+			"valuesContent":   string(values),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(manifest), nil
+}
+
+// WriteCNIManifest 在安装 k3s 前写入 CNI 的 HelmChart，k3s 启动后由内置的 Helm 控制器安装
+func (i *Installer) WriteCNIManifest(client *ssh.Client, opts *model.CNIOptions) error {
+	manifest, err := cniManifest(opts)
+	if err != nil {
+		return fmt.Errorf("生成 %s 清单失败: %v", opts.Plugin, err)
+	}
+	if _, err := client.ExecuteCommand("mkdir -p /var/lib/rancher/k3s/server/manifests"); err != nil {
+		return fmt.Errorf("创建 k3s 清单目录失败: %v", err)
+	}
+	if err := client.UploadFile(manifest, CNIManifestPath); err != nil {
+		return fmt.Errorf("上传 %s 失败: %v", CNIManifestPath, err)
+	}
+	i.logger.Infof("已写入 %s 的 HelmChart: %s", opts.Plugin, CNIManifestPath)
+	return nil
+}
+
+// WaitCNI 等待 CNI 插件的 DaemonSet 在全部节点就绪，nodes 中的节点随后应变为 Ready
+func (m *Manager) WaitCNI(client *ssh.Client, opts *model.CNIOptions, nodes []string, policy WaitPolicy) error {
+	policy = policy.WithDefaults()
+	namespace, name, _ := strings.Cut(cniCharts[opts.Plugin].daemonSet, "/")
+	m.logger.Infof("等待 %s 就绪（daemonset/%s）", opts.Plugin, name)
+
+	// DaemonSet 由 Helm 安装 Job（Calico 还需经 operator）创建，先等待其出现
+	deadline := time.Now().Add(policy.DeploymentTimeout)
+	for {
+		if _, err := runKubectlIdempotent(client, fmt.Sprintf("kubectl -n %s get daemonset %s", namespace, name)); err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("等待 %s 创建 daemonset/%s 超时（%s），请检查 kube-system 中 helm-install-%s 的日志",
+				opts.Plugin, name, policy.DeploymentTimeout, opts.Plugin)
+		}
+		time.Sleep(policy.PollInterval)
+	}
+
+	remaining := max(time.Until(deadline), time.Second)
+	cmd := fmt.Sprintf("kubectl -n %s rollout status daemonset/%s --timeout=%ds", namespace, name, int(remaining.Seconds()))
+	if _, err := runKubectlIdempotent(client, cmd); err != nil {
+		return fmt.Errorf("等待 %s 启动失败（请确认节点满足内核要求且镜像可拉取）: %v", opts.Plugin, err)
+	}
+	for _, node := range nodes {
+		if err := m.WaitNodeReady(client, node, policy.DeploymentTimeout, policy.PollInterval); err != nil {
+			return err
+		}
+	}
+	m.logger.Infof("%s 已就绪", opts.Plugin)
+	return nil
+}
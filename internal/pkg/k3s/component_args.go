@@ -0,0 +1,128 @@
+package k3s
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k3s-deploy-backend/internal/model"
+)
+
+// 组件参数在 k3s 命令行和 config.yaml 中的名称
+const (
+	kubeletArg   = "kubelet-arg"
+	kubeProxyArg = "kube-proxy-arg"
+	apiServerArg = "kube-apiserver-arg"
+)
+
+// componentArgAllowlist 允许透传的组件参数。只开放资源、驱逐、特性开关等调优参数，
+// 不开放证书、端口、数据目录等由 k3s 管理的参数，避免覆盖后集群无法启动
+var componentArgAllowlist = map[string]map[string]bool{
+	kubeletArg: setOf(
+		"max-pods", "pod-max-pids", "feature-gates",
+		"eviction-hard", "eviction-soft", "eviction-soft-grace-period", "eviction-minimum-reclaim",
+		"eviction-max-pod-grace-period", "eviction-pressure-transition-period",
+		"system-reserved", "kube-reserved", "reserved-cpus", "enforce-node-allocatable",
+		"image-gc-high-threshold", "image-gc-low-threshold", "image-maximum-gc-age",
+		"container-log-max-size", "container-log-max-files",
+		"serialize-image-pulls", "max-parallel-image-pulls", "registry-qps", "registry-burst",
+		"kube-api-qps", "kube-api-burst", "node-status-update-frequency",
+		"cpu-manager-policy", "cpu-manager-policy-options", "memory-manager-policy", "topology-manager-policy", "topology-manager-scope",
+		"shutdown-grace-period", "shutdown-grace-period-critical-pods", "allowed-unsafe-sysctls",
+	),
+	kubeProxyArg: setOf(
+		"proxy-mode", "feature-gates", "ipvs-scheduler", "ipvs-strict-arp", "ipvs-sync-period", "iptables-sync-period",
+		"conntrack-max-per-core", "conntrack-min", "conntrack-tcp-timeout-established", "conntrack-tcp-timeout-close-wait",
+		"nodeport-addresses", "metrics-bind-address",
+	),
+	apiServerArg: setOf(
+		"feature-gates", "runtime-config", "max-requests-inflight", "max-mutating-requests-inflight", "request-timeout",
+		"event-ttl", "service-node-port-range", "default-not-ready-toleration-seconds", "default-unreachable-toleration-seconds",
+		"enable-admission-plugins", "disable-admission-plugins",
+		"oidc-issuer-url", "oidc-client-id", "oidc-username-claim", "oidc-username-prefix", "oidc-groups-claim", "oidc-groups-prefix",
+	),
+}
+
+// componentArgValue 参数值允许的字符：不含空白和引号，拼接到安装命令时以单引号包裹
+var componentArgValue = regexp.MustCompile(`^[A-Za-z0-9._:,/=%<>+@*-]+$`)
+
+func setOf(items ...string) map[string]bool {
+	set := make(map[string]bool, len(items))
+	for _, item := range items {
+		set[item] = true
+	}
+	return set
+}
+
+// ComponentArgs 校验组件参数并转换为 k3s 安装参数：kube-apiserver 参数只用于 Server，kubelet 与 kube-proxy 参数 Server 和 Agent 共用
+func ComponentArgs(opts *model.ComponentArgsOptions) (serverArgs, extraArgs []string, err error) {
+	if opts == nil {
+		return nil, nil, nil
+	}
+	for _, group := range []struct {
+		flag string
+		args []string
+		dest *[]string
+	}{
+		{kubeletArg, opts.Kubelet, &extraArgs},
+		{kubeProxyArg, opts.KubeProxy, &extraArgs},
+		{apiServerArg, opts.APIServer, &serverArgs},
+	} {
+		for _, arg := range group.args {
+			arg, err := validateComponentArg(group.flag, arg)
+			if err != nil {
+				return nil, nil, err
+			}
+			*group.dest = append(*group.dest, fmt.Sprintf("'--%s=%s'", group.flag, arg))
+		}
+	}
+	return serverArgs, extraArgs, nil
+}
+
+// ValidateConfigArgs 校验 config.yaml 中的 kubelet-arg、kube-proxy-arg、kube-apiserver-arg（含追加形式 kubelet-arg+），
+// 与部署请求中的 componentArgs 使用相同的允许列表
+func ValidateConfigArgs(config map[string]interface{}) error {
+	for key, value := range config {
+		flag := strings.TrimSuffix(key, "+")
+		if _, ok := componentArgAllowlist[flag]; !ok {
+			continue
+		}
+		var args []string
+		switch v := value.(type) {
+		case string:
+			args = []string{v}
+		case []interface{}:
+			for _, item := range v {
+				s, ok := item.(string)
+				if !ok {
+					return fmt.Errorf("%s 的每一项应为字符串", key)
+				}
+				args = append(args, s)
+			}
+		default:
+			return fmt.Errorf("%s 应为字符串或字符串列表", key)
+		}
+		for _, arg := range args {
+			if _, err := validateComponentArg(flag, arg); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// validateComponentArg 校验 name=value 形式的组件参数，返回去掉前导 -- 的参数
+func validateComponentArg(flag, arg string) (string, error) {
+	arg = strings.TrimPrefix(arg, "--")
+	name, value, ok := strings.Cut(arg, "=")
+	if !ok || name == "" {
+		return "", fmt.Errorf("%s 参数 %q 应为 name=value 形式", flag, arg)
+	}
+	if !componentArgAllowlist[flag][name] {
+		return "", fmt.Errorf("%s 不允许设置 %s", flag, name)
+	}
+	if !componentArgValue.MatchString(value) {
+		return "", fmt.Errorf("%s 参数 %s 的值 %q 包含不允许的字符", flag, name, value)
+	}
+	return arg, nil
+}
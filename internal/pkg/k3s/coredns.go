@@ -0,0 +1,88 @@
+package k3s
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// ResolvConfPath k3s 专用的 resolv.conf，安装时通过 --resolv-conf 传给 kubelet，CoreDNS 以其中的服务器作为集群外域名的上游。
+// 节点使用 127.0.0.1 上的本地转发器时，kubelet 不能直接使用 /etc/resolv.conf
+const ResolvConfPath = "/etc/rancher/k3s/resolv.conf"
+
+// CoreDNSConfig CoreDNS 自定义配置，写入 k3s 预留的 kube-system/coredns-custom ConfigMap，
+// 不修改 k3s 管理的 coredns ConfigMap，k3s 重启后不会被覆盖
+type CoreDNSConfig struct {
+	// StubDomains 域名 -> DNS 服务器，该域名下的查询转发到指定服务器
+	StubDomains map[string][]string
+	// Hosts 域名 -> IP 静态解析记录
+	Hosts map[string]string
+}
+
+// manifest 生成 coredns-custom ConfigMap。k3s 的 Corefile 会导入其中的 *.server 作为额外的 server 块；
+// 静态记录所在的域名单独成块，未列出的子域名继续转发到上游
+func (c CoreDNSConfig) manifest() string {
+	var b strings.Builder
+	b.WriteString(`apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: coredns-custom
+  namespace: kube-system
+  labels:
+    app.kubernetes.io/managed-by: k3s-deploy-backend
+data:
+`)
+
+	domains := make([]string, 0, len(c.StubDomains))
+	for domain := range c.StubDomains {
+		domains = append(domains, domain)
+	}
+	sort.Strings(domains)
+	for i, domain := range domains {
+		fmt.Fprintf(&b, "  stub-%d.server: |\n    %s:53 {\n        errors\n        cache 30\n        forward . %s\n    }\n",
+			i, domain, strings.Join(c.StubDomains[domain], " "))
+	}
+
+	if len(c.Hosts) > 0 {
+		names := make([]string, 0, len(c.Hosts))
+		for name := range c.Hosts {
+			names = append(names, name)
+		}
+		sort.Strings(names)
+		zones := make([]string, len(names))
+		for i, name := range names {
+			zones[i] = name + ":53"
+		}
+		fmt.Fprintf(&b, "  hosts.server: |\n    %s {\n        errors\n        cache 30\n        hosts {\n", strings.Join(zones, " "))
+		for _, name := range names {
+			fmt.Fprintf(&b, "            %s %s\n", c.Hosts[name], name)
+		}
+		b.WriteString("            fallthrough\n        }\n        forward . /etc/resolv.conf\n    }\n")
+	}
+	return b.String()
+}
+
+// ApplyCoreDNS 写入 coredns-custom 并重启 CoreDNS 使配置立即生效（ConfigMap 卷的更新传播可能需要一分钟以上），
+// 配置有误时 CoreDNS 无法启动，rollout 失败即返回错误
+func (m *Manager) ApplyCoreDNS(client *ssh.Client, ws *Workspace, cfg CoreDNSConfig, policy WaitPolicy) error {
+	m.logger.Info("开始应用 CoreDNS 自定义配置")
+
+	file, err := ws.Upload("coredns-custom.yaml", cfg.manifest())
+	if err != nil {
+		return fmt.Errorf("上传 CoreDNS 自定义配置失败: %v", err)
+	}
+	if _, err := runKubectl(client, "kubectl apply -f "+file); err != nil {
+		return fmt.Errorf("应用 CoreDNS 自定义配置失败: %v", err)
+	}
+	if _, err := runKubectl(client, "kubectl -n kube-system rollout restart deployment/coredns"); err != nil {
+		return fmt.Errorf("重启 CoreDNS 失败: %v", err)
+	}
+	if err := m.waitForRollout(client, "kube-system", "coredns", policy.WithDefaults()); err != nil {
+		return fmt.Errorf("CoreDNS 自定义配置未生效: %v", err)
+	}
+
+	m.logger.Info("CoreDNS 自定义配置已生效")
+	return nil
+}
@@ -0,0 +1,369 @@
+package k3s
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+const (
+	// DatabaseOperatorNamespace CloudNativePG operator 所在的命名空间
+	DatabaseOperatorNamespace = "cnpg-system"
+	// databaseCluster 实例命名空间中 CloudNativePG 集群的名称，operator 为其创建 -rw、-ro、-r 三个 Service
+	databaseCluster = "insuite-database"
+	// databaseAppSecret 应用连接数据库的用户名和密码，与 Deployment 方式相同
+	databaseAppSecret = "insuite-database-app"
+	// databaseBackupSecret 数据库备份写入对象存储的访问密钥
+	databaseBackupSecret = "insuite-database-backup"
+
+	defaultCNPGVersion            = "1.24.1"
+	defaultPostgresImage          = "ghcr.io/cloudnative-pg/postgresql:16.4"
+	defaultDatabaseSize           = "10Gi"
+	defaultDatabaseInstances      = 3
+	defaultDatabaseBackupSchedule = "0 2 * * *"
+	defaultDatabaseRetentionDays  = 7
+)
+
+// ManagedDatabase 由 CloudNativePG 管理的 inSuite 数据库，资源与分散方式沿用 database 组件的设置
+type ManagedDatabase struct {
+	Instances    int
+	Image        string
+	Size         string
+	StorageClass string
+	// OperatorManifest operator 安装清单地址
+	OperatorManifest string
+	// Backup 为 nil 时不备份（并删除之前的定时备份）
+	Backup *DatabaseBackup
+}
+
+// DatabaseBackup 数据库定时基础备份与 WAL 归档，Endpoint 等存储参数由调用方填入
+type DatabaseBackup struct {
+	Schedule      string
+	RetentionDays int
+	Endpoint      string
+	Bucket        string
+	Prefix        string
+	Region        string
+	AccessKey     string
+	SecretKey     string
+}
+
+// ValidateDatabase 校验数据库参数
+func ValidateDatabase(opts *model.DatabaseOptions) error {
+	if opts == nil || opts.Engine != model.DatabaseEngineCNPG {
+		return nil
+	}
+	if opts.Image != "" && !imagePattern.MatchString(opts.Image) {
+		return fmt.Errorf("无效的 PostgreSQL 镜像: %s", opts.Image)
+	}
+	if opts.Size != "" && !quantityPattern.MatchString(opts.Size) {
+		return fmt.Errorf("无效的数据卷大小: %s", opts.Size)
+	}
+	if opts.StorageClass != "" && !namespacePattern.MatchString(opts.StorageClass) {
+		return fmt.Errorf("无效的 StorageClass: %s", opts.StorageClass)
+	}
+	if opts.OperatorVersion != "" && !chartVersionPattern.MatchString(opts.OperatorVersion) {
+		return fmt.Errorf("无效的 CloudNativePG 版本: %s", opts.OperatorVersion)
+	}
+	if opts.OperatorManifestURL != "" && !chartRepoPattern.MatchString(opts.OperatorManifestURL) {
+		return fmt.Errorf("无效的 operator 清单地址: %s", opts.OperatorManifestURL)
+	}
+	backup := opts.Backup
+	if backup == nil {
+		return nil
+	}
+	// CloudNativePG 的定时备份不支持 @daily 等预定义表达式
+	if backup.Schedule != "" && (!cronPattern.MatchString(backup.Schedule) || strings.HasPrefix(backup.Schedule, "@")) {
+		return fmt.Errorf("无效的 cron 表达式: %q（五段式，如 0 2 * * *）", backup.Schedule)
+	}
+	if storage := backup.Storage; storage != nil {
+		if !bucketPattern.MatchString(storage.Bucket) {
+			return fmt.Errorf("无效的存储桶名: %s", storage.Bucket)
+		}
+		if storage.Prefix != "" && !prefixPattern.MatchString(storage.Prefix) {
+			return fmt.Errorf("无效的路径前缀: %s", storage.Prefix)
+		}
+		if storage.Endpoint != "" {
+			u, err := url.Parse(storage.Endpoint)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+				return fmt.Errorf("无效的对象存储地址: %s", storage.Endpoint)
+			}
+		}
+		if strings.ContainsAny(storage.AccessKey+storage.SecretKey, "\r\n") {
+			return fmt.Errorf("对象存储密钥不能包含换行")
+		}
+	}
+	return nil
+}
+
+// NewManagedDatabase 按请求参数生成 CloudNativePG 数据库设置，未设置的字段使用默认值；备份存储由调用方填入
+func NewManagedDatabase(opts *model.DatabaseOptions) *ManagedDatabase {
+	db := &ManagedDatabase{
+		Instances:        opts.Instances,
+		Image:            opts.Image,
+		Size:             opts.Size,
+		StorageClass:     opts.StorageClass,
+		OperatorManifest: opts.OperatorManifestURL,
+	}
+	if db.Instances == 0 {
+		db.Instances = defaultDatabaseInstances
+	}
+	if db.Image == "" {
+		db.Image = defaultPostgresImage
+	}
+	if db.Size == "" {
+		db.Size = defaultDatabaseSize
+	}
+	if db.OperatorManifest == "" {
+		version := strings.TrimPrefix(opts.OperatorVersion, "v")
+		if version == "" {
+			version = defaultCNPGVersion
+		}
+		db.OperatorManifest = fmt.Sprintf("https://github.com/cloudnative-pg/cloudnative-pg/releases/download/v%s/cnpg-%s.yaml", version, version)
+	}
+	if b := opts.Backup; b != nil {
+		db.Backup = &DatabaseBackup{Schedule: b.Schedule, RetentionDays: b.RetentionDays}
+		if db.Backup.Schedule == "" {
+			db.Backup.Schedule = defaultDatabaseBackupSchedule
+		}
+		if db.Backup.RetentionDays == 0 {
+			db.Backup.RetentionDays = defaultDatabaseRetentionDays
+		}
+		if s := b.Storage; s != nil {
+			db.Backup.Endpoint, db.Backup.Bucket, db.Backup.Prefix = s.Endpoint, s.Bucket, s.Prefix
+			db.Backup.Region, db.Backup.AccessKey, db.Backup.SecretKey = s.Region, s.AccessKey, s.SecretKey
+		}
+	}
+	return db
+}
+
+// databaseHost 应用连接数据库使用的 Service：CloudNativePG 的 -rw Service 始终指向当前主库
+func (s AppSpec) databaseHost() string {
+	if s.Database != nil {
+		return databaseCluster + "-rw"
+	}
+	return "insuite-database"
+}
+
+// databaseManifest 生成 CloudNativePG 的 Cluster 与（设置备份时）ScheduledBackup。
+// 实例调度到带 insuite.database 标签的节点并按 database 组件的分散方式互斥，
+// 备份以实例命名空间区分，barman 再以集群名作为下一级目录
+func databaseManifest(spec AppSpec) (string, error) {
+	db := spec.Database
+	component := spec.component(RoleDatabase)
+	namespace := spec.namespace()
+
+	storage := map[string]any{"size": db.Size}
+	if db.StorageClass != "" {
+		storage["storageClass"] = db.StorageClass
+	}
+	antiAffinity := component.AntiAffinity
+	if antiAffinity == "" {
+		antiAffinity = AntiAffinityPreferred
+	}
+	clusterSpec := map[string]any{
+		"instances": db.Instances,
+		"imageName": db.Image,
+		// 主库所在节点更新时由 operator 自动切换到备库
+		"primaryUpdateStrategy": "unsupervised",
+		"bootstrap": map[string]any{"initdb": map[string]any{
+			"database": "insuite",
+			"owner":    "insuite",
+			"secret":   map[string]string{"name": databaseAppSecret},
+		}},
+		"storage": storage,
+		"affinity": map[string]any{
+			"nodeSelector":          map[string]string{"insuite.database": "true"},
+			"enablePodAntiAffinity": true,
+			"podAntiAffinityType":   antiAffinity,
+			"topologyKey":           "kubernetes.io/hostname",
+		},
+	}
+	resources := map[string]any{}
+	r := component.Resources
+	if requests := quantityMap(r.RequestsCPU, r.RequestsMemory); len(requests) > 0 {
+		resources["requests"] = requests
+	}
+	if limits := quantityMap(r.LimitsCPU, r.LimitsMemory); len(limits) > 0 {
+		resources["limits"] = limits
+	}
+	if len(resources) > 0 {
+		clusterSpec["resources"] = resources
+	}
+
+	if b := db.Backup; b != nil {
+		destination := "s3://" + b.Bucket + "/"
+		if b.Prefix != "" {
+			destination += strings.Trim(b.Prefix, "/") + "/"
+		}
+		store := map[string]any{
+			"destinationPath": destination + "cnpg/" + namespace,
+			"s3Credentials": map[string]any{
+				"accessKeyId":     map[string]string{"name": databaseBackupSecret, "key": "ACCESS_KEY_ID"},
+				"secretAccessKey": map[string]string{"name": databaseBackupSecret, "key": "ACCESS_SECRET_KEY"},
+				"region":          map[string]string{"name": databaseBackupSecret, "key": "ACCESS_REGION"},
+			},
+			"wal":  map[string]string{"compression": "gzip"},
+			"data": map[string]string{"compression": "gzip"},
+		}
+		if b.Endpoint != "" {
+			store["endpointURL"] = b.Endpoint
+		}
+		clusterSpec["backup"] = map[string]any{
+			"retentionPolicy":   fmt.Sprintf("%dd", b.RetentionDays),
+			"barmanObjectStore": store,
+		}
+	}
+
+	labels := map[string]string{ManagedByLabel: ManagedBy}
+	objects := []map[string]any{{
+		"apiVersion": "postgresql.cnpg.io/v1",
+		"kind":       "Cluster",
+		"metadata":   map[string]any{"name": databaseCluster, "namespace": namespace, "labels": labels},
+		"spec":       clusterSpec,
+	}}
+	if b := db.Backup; b != nil {
+		objects = append(objects, map[string]any{
+			"apiVersion": "postgresql.cnpg.io/v1",
+			"kind":       "ScheduledBackup",
+			"metadata":   map[string]any{"name": databaseCluster, "namespace": namespace, "labels": labels},
+			"spec": map[string]any{
+				// CloudNativePG 的 cron 表达式带秒字段
+				"schedule":             "0 " + b.Schedule,
+				"immediate":            true,
+				"backupOwnerReference": "self",
+				"cluster":              map[string]string{"name": databaseCluster},
+			},
+		})
+	}
+
+	docs := make([]string, 0, len(objects))
+	for _, object := range objects {
+		doc, err := yaml.Marshal(object)
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, string(doc))
+	}
+	return strings.Join(docs, "---\n"), nil
+}
+
+// quantityMap 由非空的 CPU、内存数量组成资源字段
+func quantityMap(cpu, memory string) map[string]string {
+	values := map[string]string{}
+	if cpu != "" {
+		values["cpu"] = cpu
+	}
+	if memory != "" {
+		values["memory"] = memory
+	}
+	return values
+}
+
+// installDatabaseOperator 安装（或升级）CloudNativePG operator 并等待其就绪
+func (m *Manager) installDatabaseOperator(client *ssh.Client, manifest string, policy WaitPolicy) error {
+	m.logger.Infof("安装 CloudNativePG operator: %s", manifest)
+	// CRD 较大，客户端 apply 会超出 last-applied 注解的长度限制
+	if _, err := runKubectl(client, fmt.Sprintf("kubectl apply --server-side --force-conflicts -f %q", manifest)); err != nil {
+		return fmt.Errorf("安装 CloudNativePG operator 失败: %v", err)
+	}
+	if err := m.waitForRollout(client, DatabaseOperatorNamespace, "cnpg-controller-manager", policy); err != nil {
+		return fmt.Errorf("等待 CloudNativePG operator 就绪失败: %v", err)
+	}
+	return nil
+}
+
+// hasDatabaseCluster 判断命名空间中是否已有 CloudNativePG 集群，未安装 operator 时返回 false
+func (m *Manager) hasDatabaseCluster(client *ssh.Client, namespace string) bool {
+	cmd := fmt.Sprintf("kubectl -n %s get clusters.postgresql.cnpg.io %s -o name", namespace, databaseCluster)
+	_, err := runKubectlIdempotent(client, cmd)
+	return err == nil
+}
+
+// deployManagedDatabase 部署由 CloudNativePG 管理的数据库：安装 operator，创建应用用户与备份密钥，
+// 删除之前以 Deployment 方式部署的数据库（其数据不在持久卷中），再应用 Cluster 清单
+func (m *Manager) deployManagedDatabase(client *ssh.Client, ws *Workspace, spec AppSpec, policy WaitPolicy) error {
+	db := spec.Database
+	namespace := spec.namespace()
+	if err := m.installDatabaseOperator(client, db.OperatorManifest, policy); err != nil {
+		return err
+	}
+
+	file, err := ws.Upload("insuite-database-app.env", "username=insuite\npassword=insuite123\n")
+	if err != nil {
+		return fmt.Errorf("上传数据库用户配置失败: %v", err)
+	}
+	cmd := fmt.Sprintf("kubectl -n %s create secret generic %s --type=kubernetes.io/basic-auth --from-env-file=%s --dry-run=client -o yaml | kubectl apply -f -", namespace, databaseAppSecret, file)
+	if _, err := runKubectl(client, cmd); err != nil {
+		return fmt.Errorf("创建数据库用户 Secret 失败: %v", err)
+	}
+
+	if b := db.Backup; b != nil {
+		region := b.Region
+		if region == "" {
+			region = "us-east-1"
+		}
+		file, err := ws.Upload("insuite-database-backup.env", fmt.Sprintf("ACCESS_KEY_ID=%s\nACCESS_SECRET_KEY=%s\nACCESS_REGION=%s\n", b.AccessKey, b.SecretKey, region))
+		if err != nil {
+			return fmt.Errorf("上传数据库备份密钥失败: %v", err)
+		}
+		cmd := fmt.Sprintf("kubectl -n %s create secret generic %s --from-env-file=%s --dry-run=client -o yaml | kubectl apply -f -", namespace, databaseBackupSecret, file)
+		if _, err := runKubectl(client, cmd); err != nil {
+			return fmt.Errorf("创建数据库备份密钥失败: %v", err)
+		}
+	} else {
+		cmd := fmt.Sprintf("kubectl -n %s delete scheduledbackups.postgresql.cnpg.io %s --ignore-not-found && kubectl -n %s delete secret %s --ignore-not-found",
+			namespace, databaseCluster, namespace, databaseBackupSecret)
+		if _, err := runKubectl(client, cmd); err != nil {
+			return fmt.Errorf("删除数据库定时备份失败: %v", err)
+		}
+	}
+
+	cmd = fmt.Sprintf("kubectl -n %s delete deployment,service insuite-database --ignore-not-found", namespace)
+	if _, err := runKubectl(client, cmd); err != nil {
+		return fmt.Errorf("删除 Deployment 方式的数据库失败: %v", err)
+	}
+
+	manifest, err := databaseManifest(spec)
+	if err != nil {
+		return fmt.Errorf("生成数据库清单失败: %v", err)
+	}
+	file, err = ws.Upload("insuite-database.yaml", manifest)
+	if err != nil {
+		return fmt.Errorf("上传数据库配置失败: %v", err)
+	}
+	// operator 刚启动时准入 webhook 可能尚未就绪
+	deadline := time.Now().Add(policy.DeploymentTimeout)
+	for {
+		_, err := runKubectl(client, "kubectl apply -f "+file)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("部署数据库集群失败: %v", err)
+		}
+		time.Sleep(policy.PollInterval)
+	}
+	m.logger.Infof("已提交 CloudNativePG 数据库集群（%d 个实例，备份: %v）", db.Instances, db.Backup != nil)
+	return nil
+}
+
+// waitForDatabaseCluster 等待 CloudNativePG 集群全部实例就绪
+func (m *Manager) waitForDatabaseCluster(client *ssh.Client, namespace string, policy WaitPolicy) error {
+	cmd := fmt.Sprintf("kubectl -n %s wait clusters.postgresql.cnpg.io/%s --for=condition=Ready --timeout=%ds", namespace, databaseCluster, int(policy.DeploymentTimeout.Seconds()))
+	if _, err := runKubectlIdempotent(client, cmd); err != nil {
+		phase := "未知"
+		result, perr := runKubectlIdempotent(client, fmt.Sprintf("kubectl -n %s get clusters.postgresql.cnpg.io %s -o jsonpath='{.status.phase}'", namespace, databaseCluster))
+		if perr == nil && strings.TrimSpace(result.Stdout) != "" {
+			phase = strings.TrimSpace(result.Stdout)
+		}
+		return fmt.Errorf("等待数据库集群就绪超时（%s），当前状态: %s，请检查数据卷能否创建: %v", policy.DeploymentTimeout, phase, err)
+	}
+	return nil
+}
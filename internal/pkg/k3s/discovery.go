@@ -0,0 +1,145 @@
+package k3s
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// Discovery 从已安装集群中发现的信息
+type Discovery struct {
+	Version string
+	Token   string
+	Nodes   []model.ClusterNode
+}
+
+// nodeList kubectl get nodes -o json 输出中用到的字段
+type nodeList struct {
+	Items []struct {
+		Metadata struct {
+			Name   string            `json:"name"`
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Spec struct {
+			Taints []struct {
+				Key    string `json:"key"`
+				Value  string `json:"value"`
+				Effect string `json:"effect"`
+			} `json:"taints"`
+		} `json:"spec"`
+		Status struct {
+			Addresses []struct {
+				Type    string `json:"type"`
+				Address string `json:"address"`
+			} `json:"addresses"`
+			Conditions []struct {
+				Type   string `json:"type"`
+				Status string `json:"status"`
+			} `json:"conditions"`
+			NodeInfo struct {
+				KubeletVersion string `json:"kubeletVersion"`
+			} `json:"nodeInfo"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// DiscoverCluster 通过 server 节点读取 k3s 版本、join token 和节点列表
+func (m *Manager) DiscoverCluster(client *ssh.Client) (*Discovery, error) {
+	m.logger.Info("开始发现已有K3s集群")
+
+	result, err := client.ExecuteIdempotentCommand("k3s --version")
+	if err != nil {
+		return nil, fmt.Errorf("节点未安装k3s: %v", err)
+	}
+	version := parseK3sVersion(result.Stdout)
+	if version == "" {
+		return nil, fmt.Errorf("无法解析k3s版本: %s", strings.TrimSpace(result.Stdout))
+	}
+
+	token, err := m.GetNodeToken(client)
+	if err != nil {
+		return nil, fmt.Errorf("该节点不是k3s server节点或无权读取token: %v", err)
+	}
+
+	nodes, err := m.ListNodes(client)
+	if err != nil {
+		return nil, err
+	}
+
+	m.logger.Infof("发现k3s集群: 版本 %s，%d 个节点", version, len(nodes))
+	return &Discovery{Version: version, Token: token, Nodes: nodes}, nil
+}
+
+// parseK3sVersion 解析 "k3s version v1.28.5+k3s1 (5b2d1271)" 格式的输出
+func parseK3sVersion(output string) string {
+	for _, line := range strings.Split(output, "\n") {
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && fields[0] == "k3s" && fields[1] == "version" {
+			return fields[2]
+		}
+	}
+	return ""
+}
+
+func parseNodeList(output string) ([]model.ClusterNode, error) {
+	var list nodeList
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("解析节点列表失败: %v", err)
+	}
+
+	nodes := make([]model.ClusterNode, 0, len(list.Items))
+	for _, item := range list.Items {
+		node := model.ClusterNode{
+			Name:    item.Metadata.Name,
+			Version: item.Status.NodeInfo.KubeletVersion,
+			Labels:  item.Metadata.Labels,
+			Roles:   []string{},
+		}
+		for _, addr := range item.Status.Addresses {
+			if addr.Type == "InternalIP" {
+				node.InternalIP = addr.Address
+			}
+		}
+		for _, cond := range item.Status.Conditions {
+			switch {
+			case cond.Type == "Ready":
+				node.Ready = cond.Status == "True"
+			case cond.Status == "True":
+				// 除 Ready 外的节点条件为 True 均表示异常
+				node.Conditions = append(node.Conditions, cond.Type)
+			}
+		}
+		for label := range item.Metadata.Labels {
+			if role, ok := strings.CutPrefix(label, "node-role.kubernetes.io/"); ok {
+				node.Roles = append(node.Roles, role)
+			}
+		}
+		sort.Strings(node.Roles)
+		for _, taint := range item.Spec.Taints {
+			node.Taints = append(node.Taints, formatTaint(taint.Key, taint.Value, taint.Effect))
+		}
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+// formatTaint 与 kubectl taint 参数格式一致：key=value:Effect 或 key:Effect
+func formatTaint(key, value, effect string) string {
+	if value == "" {
+		return key + ":" + effect
+	}
+	return key + "=" + value + ":" + effect
+}
+
+// ListNodes 获取集群节点列表
+func (m *Manager) ListNodes(client *ssh.Client) ([]model.ClusterNode, error) {
+	result, err := runKubectlIdempotent(client, "kubectl get nodes -o json")
+	if err != nil {
+		return nil, fmt.Errorf("获取集群节点失败: %v", err)
+	}
+	return parseNodeList(result.Stdout)
+}
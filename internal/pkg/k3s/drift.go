@@ -0,0 +1,347 @@
+package k3s
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+const registriesPath = "/etc/rancher/k3s/registries.yaml"
+
+// DetectDrift 比对期望状态与集群实际状态，返回所有不一致项；清单比对文件上传到 ws 工作目录
+func (m *Manager) DetectDrift(client *ssh.Client, ws *Workspace, desired *model.DesiredState) ([]model.DriftItem, error) {
+	items := []model.DriftItem{}
+
+	if len(desired.Labels) > 0 || len(desired.Taints) > 0 {
+		nodes, err := m.ListNodes(client)
+		if err != nil {
+			return nil, err
+		}
+		actual := make(map[string]model.ClusterNode, len(nodes))
+		for _, node := range nodes {
+			actual[node.Name] = node
+		}
+		items = append(items, labelDrift(desired.Labels, actual)...)
+		items = append(items, taintDrift(desired.Taints, actual)...)
+	}
+
+	if desired.Registries != "" {
+		result, _ := client.ExecuteIdempotentCommand("cat " + registriesPath)
+		current := ""
+		if result != nil {
+			current = result.Stdout
+		}
+		if strings.TrimSpace(current) != strings.TrimSpace(desired.Registries) {
+			items = append(items, model.DriftItem{
+				Kind:     model.DriftRegistries,
+				Target:   registriesPath,
+				Expected: desired.Registries,
+				Actual:   current,
+			})
+		}
+	}
+
+	if len(desired.K3sArgs) > 0 {
+		osInfo, err := detectServiceManager(client)
+		if err != nil {
+			return nil, err
+		}
+		k3sServiceUnit := serviceFile(osInfo, "k3s")
+		result, err := client.ExecuteIdempotentCommand("cat " + k3sServiceUnit)
+		if err != nil {
+			return nil, fmt.Errorf("读取k3s服务配置失败: %v", err)
+		}
+		actual := make(map[string]bool)
+		for _, arg := range serviceArgs(result.Stdout, osInfo.InitSystem == hostos.InitOpenRC) {
+			actual[arg] = true
+		}
+		for _, arg := range desired.K3sArgs {
+			if !containsArgs(actual, normalizeArgs(strings.Fields(arg))) {
+				items = append(items, model.DriftItem{
+					Kind:     model.DriftK3sArgs,
+					Target:   k3sServiceUnit,
+					Expected: arg,
+				})
+			}
+		}
+	}
+
+	if desired.LogRotation != nil {
+		drifted, err := m.logRotationDrift(client, desired.LogRotation)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, drifted...)
+	}
+
+	if len(desired.Manifests) > 0 {
+		drifted, err := m.manifestDrift(client, ws, desired.Manifests)
+		if err != nil {
+			return nil, err
+		}
+		items = append(items, drifted...)
+	}
+
+	return items, nil
+}
+
+func labelDrift(desired map[string][]string, actual map[string]model.ClusterNode) []model.DriftItem {
+	items := []model.DriftItem{}
+	for _, nodeName := range sortedKeys(desired) {
+		node, exists := actual[nodeName]
+		for _, label := range desired[nodeName] {
+			key, value, _ := strings.Cut(label, "=")
+			current, found := node.Labels[key]
+			if exists && found && current == value {
+				continue
+			}
+			item := model.DriftItem{Kind: model.DriftLabel, Target: nodeName, Expected: label}
+			if found {
+				item.Actual = key + "=" + current
+			}
+			if !exists {
+				item.Message = "节点不存在"
+			}
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+func taintDrift(desired map[string][]string, actual map[string]model.ClusterNode) []model.DriftItem {
+	items := []model.DriftItem{}
+	for _, nodeName := range sortedKeys(desired) {
+		node, exists := actual[nodeName]
+		present := make(map[string]bool, len(node.Taints))
+		for _, taint := range node.Taints {
+			present[taint] = true
+		}
+		for _, taint := range desired[nodeName] {
+			if exists && present[taint] {
+				continue
+			}
+			item := model.DriftItem{Kind: model.DriftTaint, Target: nodeName, Expected: taint, Actual: strings.Join(node.Taints, ",")}
+			if !exists {
+				item.Message = "节点不存在"
+			}
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// manifestDrift 使用 kubectl diff 比对清单：退出码 0 表示一致，1 表示存在差异
+func (m *Manager) manifestDrift(client *ssh.Client, ws *Workspace, manifests map[string]string) ([]model.DriftItem, error) {
+	items := []model.DriftItem{}
+	for _, name := range sortedKeys(manifests) {
+		file, err := ws.Upload(name+".yaml", manifests[name])
+		if err != nil {
+			return nil, fmt.Errorf("上传清单 %s 失败: %v", name, err)
+		}
+
+		result, err := runKubectlIdempotent(client, "kubectl diff -f "+file)
+		if err == nil {
+			continue
+		}
+		if result == nil || result.ExitCode != 1 {
+			return nil, fmt.Errorf("比对清单 %s 失败: %v", name, err)
+		}
+		items = append(items, model.DriftItem{
+			Kind:   model.DriftManifest,
+			Target: name,
+			Actual: result.Stdout,
+		})
+	}
+	return items, nil
+}
+
+// ReconcileDrift 将漂移项恢复为期望状态，逐项记录结果。k3s 启动参数需重新安装，仅报告不修复
+func (m *Manager) ReconcileDrift(client *ssh.Client, ws *Workspace, desired *model.DesiredState, items []model.DriftItem) {
+	var osInfo *hostos.Info
+	for i := range items {
+		item := &items[i]
+		var err error
+
+		switch item.Kind {
+		case model.DriftLabel:
+			if item.Message != "" {
+				continue
+			}
+			if err = validateDriftTarget(item.Target, item.Expected, ValidateLabel); err == nil {
+				_, err = runKubectl(client, fmt.Sprintf("kubectl label nodes %s %s --overwrite", ssh.Quote(item.Target), ssh.Quote(item.Expected)))
+			}
+		case model.DriftTaint:
+			if item.Message != "" {
+				continue
+			}
+			if err = validateDriftTarget(item.Target, item.Expected, ValidateTaint); err == nil {
+				_, err = runKubectl(client, fmt.Sprintf("kubectl taint nodes %s %s --overwrite", ssh.Quote(item.Target), ssh.Quote(item.Expected)))
+			}
+		case model.DriftRegistries:
+			if osInfo == nil {
+				if osInfo, err = detectServiceManager(client); err != nil {
+					break
+				}
+			}
+			var file string
+			if file, err = ws.Upload("registries.yaml", desired.Registries); err == nil {
+				_, err = client.ExecuteCommand("mkdir -p /etc/rancher/k3s && cp " + file + " " + registriesPath + " && " + osInfo.ServiceRestartCommand("k3s"))
+			}
+		case model.DriftManifest:
+			_, err = runKubectl(client, "kubectl apply -f "+ws.Path(item.Target+".yaml"))
+		case model.DriftLogRotation:
+			// kubelet 参数写在各节点的配置文件中，修改后需重启 k3s，由 prepare-nodes 统一处理；
+			// journald 配置由调用方逐节点检查和修复（JournaldDrift、ReconcileJournald）
+			item.Message = "需要对该节点重新执行 prepare-nodes"
+			continue
+		default:
+			item.Message = "需要重新安装k3s或手动修改服务配置"
+			continue
+		}
+
+		if err != nil {
+			item.Message = fmt.Sprintf("修复失败: %v", err)
+			m.logger.Errorf("修复漂移 %s %s 失败: %v", item.Kind, item.Target, err)
+			continue
+		}
+		item.Reconciled = true
+		m.logger.Infof("已修复漂移 %s %s", item.Kind, item.Target)
+	}
+}
+
+// validateDriftTarget 修复前再次校验节点名和标签/污点，期望状态可能来自未经校验的旧记录
+func validateDriftTarget(node, value string, validate func(string) error) error {
+	if !nodeNamePattern.MatchString(node) {
+		return fmt.Errorf("无效的节点名: %q", node)
+	}
+	return validate(value)
+}
+
+// serviceArgs 解析 k3s 服务配置中的启动参数：systemd 取 ExecStart，OpenRC 取 command_args。
+// 按 shell 规则拆分引号与续行，跳过可执行文件、子命令和输出重定向，并统一为 --flag=value 形式
+func serviceArgs(unit string, openrc bool) []string {
+	var command string
+	if openrc {
+		start := strings.Index(unit, `command_args="`)
+		if start < 0 {
+			return nil
+		}
+		rest := unit[start+len(`command_args="`):]
+		end := 0
+		for end < len(rest) && rest[end] != '"' {
+			if rest[end] == '\\' {
+				end++
+			}
+			end++
+		}
+		command = strings.ReplaceAll(rest[:min(end, len(rest))], `\"`, `"`)
+	} else {
+		start := strings.Index(unit, "ExecStart=")
+		if start < 0 {
+			return nil
+		}
+		lines := strings.Split(unit[start+len("ExecStart="):], "\n")
+		for i, line := range lines {
+			line = strings.TrimSpace(line)
+			command += strings.TrimSuffix(line, "\\") + " "
+			if !strings.HasSuffix(line, "\\") || i == len(lines)-1 {
+				break
+			}
+		}
+	}
+
+	var args []string
+	for _, word := range splitShellWords(command) {
+		if strings.HasPrefix(word, ">") || strings.HasPrefix(word, "2>") {
+			break
+		}
+		if len(args) > 0 || strings.HasPrefix(word, "-") {
+			args = append(args, word)
+		}
+	}
+	return normalizeArgs(args)
+}
+
+// splitShellWords 按空白拆分，处理单引号、双引号和反斜杠转义
+func splitShellWords(s string) []string {
+	var words []string
+	var word strings.Builder
+	inWord := false
+	var quote rune
+	escaped := false
+	for _, r := range s {
+		switch {
+		case escaped:
+			word.WriteRune(r)
+			escaped = false
+		case quote == '\'':
+			if r == '\'' {
+				quote = 0
+			} else {
+				word.WriteRune(r)
+			}
+		case quote == '"':
+			switch r {
+			case '"':
+				quote = 0
+			case '\\':
+				escaped = true
+			default:
+				word.WriteRune(r)
+			}
+		case r == '\'' || r == '"':
+			quote, inWord = r, true
+		case r == '\\':
+			escaped, inWord = true, true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inWord {
+				words = append(words, word.String())
+				word.Reset()
+				inWord = false
+			}
+		default:
+			word.WriteRune(r)
+			inWord = true
+		}
+	}
+	if inWord {
+		words = append(words, word.String())
+	}
+	return words
+}
+
+// normalizeArgs 将 --flag value 合并为 --flag=value
+func normalizeArgs(words []string) []string {
+	var args []string
+	for i := 0; i < len(words); i++ {
+		arg := words[i]
+		if strings.HasPrefix(arg, "-") && !strings.Contains(arg, "=") && i+1 < len(words) && !strings.HasPrefix(words[i+1], "-") {
+			arg += "=" + words[i+1]
+			i++
+		}
+		args = append(args, arg)
+	}
+	return args
+}
+
+func containsArgs(actual map[string]bool, args []string) bool {
+	for _, arg := range args {
+		if !actual[arg] {
+			return false
+		}
+	}
+	return len(args) > 0
+}
+
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for key := range m {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
@@ -0,0 +1,107 @@
+package k3s
+
+import (
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// EdgeDisabledComponents edge 配置档默认禁用的内置组件，小型 ARM 设备上合计可节省约 200MB 内存
+var EdgeDisabledComponents = []string{"traefik", "servicelb", "metrics-server"}
+
+// edgeKubeletArgs 镜像回收提前触发，避免小容量存储被镜像占满
+var edgeKubeletArgs = []string{
+	"--kubelet-arg=image-gc-high-threshold=70",
+	"--kubelet-arg=image-gc-low-threshold=50",
+}
+
+// edgeServerArgs 缩短事件保留时间并限制并发请求，减小 sqlite 数据库体积与 API Server 内存占用
+var edgeServerArgs = []string{
+	"--kube-apiserver-arg=event-ttl=15m",
+	"--kube-apiserver-arg=max-requests-inflight=100",
+	"--kube-apiserver-arg=max-mutating-requests-inflight=50",
+}
+
+// EdgeArgs 返回 edge 配置档的 Server 参数与 Server、Agent 共用参数，disable 为空时使用 EdgeDisabledComponents
+func EdgeArgs(disable []string) (serverArgs, extraArgs []string) {
+	if len(disable) == 0 {
+		disable = EdgeDisabledComponents
+	}
+	for _, component := range disable {
+		serverArgs = append(serverArgs, "--disable="+component)
+	}
+	serverArgs = append(serverArgs, edgeServerArgs...)
+	return serverArgs, slices.Clone(edgeKubeletArgs)
+}
+
+// serverHostPattern server 地址中的 DNS 主机名
+var serverHostPattern = regexp.MustCompile(`^[a-zA-Z0-9]([-a-zA-Z0-9]{0,61}[a-zA-Z0-9])?(\.[a-zA-Z0-9]([-a-zA-Z0-9]{0,61}[a-zA-Z0-9])?)*$`)
+
+// ValidateServerURL 校验 Agent 加入的中心 server 地址：https://<IP 或域名>:<端口>，不含路径、查询参数和用户信息
+func ValidateServerURL(serverURL string) error {
+	invalid := fmt.Errorf("server 地址 %q 无效，应为 https://host:6443 形式", serverURL)
+	u, err := url.Parse(serverURL)
+	if err != nil || u.Scheme != "https" || u.User != nil || u.RawQuery != "" || u.Fragment != "" || u.Path != "" && u.Path != "/" {
+		return invalid
+	}
+	host, port := u.Hostname(), u.Port()
+	if net.ParseIP(host) == nil && (len(host) > 253 || !serverHostPattern.MatchString(host)) {
+		return invalid
+	}
+	if n, err := strconv.Atoi(port); err != nil || n < 1 || n > 65535 {
+		return invalid
+	}
+	return nil
+}
+
+// CheckServerReachable 检查节点能否访问 k3s server 的 /ping 接口，Agent 安装前提前发现网络不通
+func CheckServerReachable(client *ssh.Client, serverURL string) error {
+	if err := ValidateServerURL(serverURL); err != nil {
+		return err
+	}
+	result, err := client.ExecuteIdempotentCommand("curl -ks --max-time 10 " + ssh.Quote(strings.TrimSuffix(serverURL, "/")+"/ping"))
+	if err != nil || strings.TrimSpace(result.Stdout) != "pong" {
+		return fmt.Errorf("无法访问 server %s: %v", serverURL, err)
+	}
+	return nil
+}
+
+// 服务加固配置：取消启动次数限制，k3s 反复崩溃（如边缘设备断电后存储未就绪）时持续重试而不是停在 failed 状态
+const (
+	hardeningDropIn = "90-k3s-deploy-restart.conf"
+	hardeningUnit   = `[Unit]
+StartLimitIntervalSec=0
+
+[Service]
+Restart=always
+RestartSec=10s
+OOMScoreAdjust=-900
+`
+	// OpenRC 由 supervise-daemon 守护进程，respawn_max=0 表示不限重启次数
+	hardeningOpenRC = `respawn_delay=10
+respawn_max=0
+`
+)
+
+// hardenService 为 k3s 服务配置不限次数的自动重启
+func (i *Installer) hardenService(client *ssh.Client, osInfo *hostos.Info, unit string) error {
+	var cmd string
+	if osInfo.InitSystem == hostos.InitOpenRC {
+		cmd = fmt.Sprintf("grep -q '^respawn_max=' /etc/conf.d/%[1]s 2>/dev/null || printf '%%s' '%[2]s' >> /etc/conf.d/%[1]s", unit, hardeningOpenRC)
+	} else {
+		dir := fmt.Sprintf("/etc/systemd/system/%s.service.d", unit)
+		cmd = fmt.Sprintf("mkdir -p %[1]s && printf '%%s' '%[2]s' > %[1]s/%[3]s && systemctl daemon-reload", dir, hardeningUnit, hardeningDropIn)
+	}
+	if _, err := client.ExecuteCommand(cmd); err != nil {
+		return fmt.Errorf("配置 %s 服务自动重启失败: %v", unit, err)
+	}
+	i.logger.Infof("已为 %s 服务配置不限次数的自动重启", unit)
+	return nil
+}
@@ -0,0 +1,94 @@
+package k3s
+
+import (
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// eventList kubectl get events -o json 输出中用到的字段
+type eventList struct {
+	Items []struct {
+		Metadata struct {
+			UID               string    `json:"uid"`
+			Namespace         string    `json:"namespace"`
+			CreationTimestamp time.Time `json:"creationTimestamp"`
+		} `json:"metadata"`
+		InvolvedObject struct {
+			Kind string `json:"kind"`
+			Name string `json:"name"`
+		} `json:"involvedObject"`
+		Type    string `json:"type"`
+		Reason  string `json:"reason"`
+		Message string `json:"message"`
+		Source  struct {
+			Component string `json:"component"`
+		} `json:"source"`
+		ReportingComponent string    `json:"reportingComponent"`
+		Count              int       `json:"count"`
+		FirstTimestamp     time.Time `json:"firstTimestamp"`
+		LastTimestamp      time.Time `json:"lastTimestamp"`
+		EventTime          time.Time `json:"eventTime"`
+		Series             *struct {
+			Count            int       `json:"count"`
+			LastObservedTime time.Time `json:"lastObservedTime"`
+		} `json:"series"`
+	} `json:"items"`
+}
+
+// ListEvents 读取集群中 API Server 仍保留的全部事件（默认保留 1 小时）
+func (m *Manager) ListEvents(client *ssh.Client) ([]model.ClusterEvent, error) {
+	result, err := runKubectlIdempotent(client, "kubectl get events -A -o json")
+	if err != nil {
+		return nil, fmt.Errorf("读取集群事件失败: %v", err)
+	}
+	var list eventList
+	if err := json.Unmarshal([]byte(result.Stdout), &list); err != nil {
+		return nil, fmt.Errorf("解析集群事件失败: %v", err)
+	}
+
+	events := make([]model.ClusterEvent, 0, len(list.Items))
+	for _, item := range list.Items {
+		// events.k8s.io/v1 产生的事件只有 eventTime 和 series，旧式事件使用 first/lastTimestamp 和 count
+		event := model.ClusterEvent{
+			UID:       item.Metadata.UID,
+			Namespace: item.Metadata.Namespace,
+			Kind:      item.InvolvedObject.Kind,
+			Name:      item.InvolvedObject.Name,
+			Type:      item.Type,
+			Reason:    item.Reason,
+			Message:   item.Message,
+			Source:    item.Source.Component,
+			Count:     item.Count,
+			FirstSeen: firstTime(item.FirstTimestamp, item.EventTime, item.Metadata.CreationTimestamp),
+			LastSeen:  firstTime(item.LastTimestamp, item.EventTime, item.Metadata.CreationTimestamp),
+		}
+		if event.Source == "" {
+			event.Source = item.ReportingComponent
+		}
+		if item.Series != nil {
+			event.Count = item.Series.Count
+			if !item.Series.LastObservedTime.IsZero() {
+				event.LastSeen = item.Series.LastObservedTime
+			}
+		}
+		if event.Count == 0 {
+			event.Count = 1
+		}
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// firstTime 返回第一个非零时间
+func firstTime(times ...time.Time) time.Time {
+	for _, t := range times {
+		if !t.IsZero() {
+			return t
+		}
+	}
+	return time.Time{}
+}
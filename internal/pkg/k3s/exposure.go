@@ -0,0 +1,205 @@
+package k3s
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// inSuite 应用的访问方式
+const (
+	ExposeNodePort     = "nodeport"
+	ExposeIngress      = "ingress"
+	ExposeLoadBalancer = "loadbalancer"
+)
+
+// defaultLoadBalancerPort k3s 自带的 Traefik 已通过 ServiceLB 占用各节点的 80/443
+const defaultLoadBalancerPort = 8080
+
+// Exposure inSuite 应用的访问方式
+type Exposure struct {
+	Type string
+	// NodePort 固定的 NodePort，为 0 时随机分配
+	NodePort int
+	// Hosts Ingress 的主机名规则
+	Hosts []string
+	// Port LoadBalancer 对外端口，为 0 时使用 8080
+	Port int
+	// TLS Ingress 启用 HTTPS，证书与私钥（PEM）由 TLSCert、TLSKey 提供
+	TLS     bool
+	TLSCert []byte
+	TLSKey  []byte
+	// Issuer 设置时 TLS 证书改由 cert-manager 的该 ClusterIssuer 签发，忽略 TLSCert、TLSKey
+	Issuer string
+}
+
+func (e Exposure) lbPort() int {
+	if e.Port == 0 {
+		return defaultLoadBalancerPort
+	}
+	return e.Port
+}
+
+// appService insuite-app 的 Service 清单
+func (e Exposure) appService(namespace string) string {
+	var spec string
+	switch e.Type {
+	case ExposeIngress:
+		spec = "  type: ClusterIP\n  ports:\n  - port: 80\n    targetPort: 80\n"
+	case ExposeLoadBalancer:
+		spec = fmt.Sprintf("  type: LoadBalancer\n  ports:\n  - port: %d\n    targetPort: 80\n", e.lbPort())
+	default:
+		spec = "  type: NodePort\n  ports:\n  - port: 80\n    targetPort: 80\n"
+		if e.NodePort > 0 {
+			spec += fmt.Sprintf("    nodePort: %d\n", e.NodePort)
+		}
+	}
+	return `apiVersion: v1
+kind: Service
+metadata:
+  name: insuite-app
+  namespace: ` + namespace + `
+spec:
+  selector:
+    app: insuite-app
+` + spec
+}
+
+// ingress insuite-app 的 Ingress 清单，使用集群默认的 IngressClass（k3s 为 Traefik）
+func (e Exposure) ingress(namespace string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `apiVersion: networking.k8s.io/v1
+kind: Ingress
+metadata:
+  name: insuite-app
+  namespace: %s
+`, namespace)
+	if e.TLS && e.Issuer != "" {
+		fmt.Fprintf(&b, "  annotations:\n    cert-manager.io/cluster-issuer: %s\n", e.Issuer)
+	}
+	b.WriteString("spec:\n")
+	if e.TLS {
+		b.WriteString("  tls:\n  - secretName: insuite-app-tls\n    hosts:\n")
+		for _, host := range e.Hosts {
+			fmt.Fprintf(&b, "    - %s\n", host)
+		}
+	}
+	b.WriteString("  rules:\n")
+	for _, host := range e.Hosts {
+		fmt.Fprintf(&b, `  - host: %s
+    http:
+      paths:
+      - path: /
+        pathType: Prefix
+        backend:
+          service:
+            name: insuite-app
+            port:
+              number: 80
+`, host)
+	}
+	return b.String()
+}
+
+// applyIngress 按访问方式创建或删除 insuite-app 的 Ingress 与 TLS Secret
+func (m *Manager) applyIngress(client *ssh.Client, ws *Workspace, namespace string, e Exposure) error {
+	if e.Type != ExposeIngress {
+		if _, err := runKubectl(client, "kubectl -n "+namespace+" delete ingress insuite-app --ignore-not-found"); err != nil {
+			return fmt.Errorf("删除 inSuite Ingress 失败: %v", err)
+		}
+		return nil
+	}
+
+	if e.TLS && e.Issuer == "" {
+		if len(e.TLSCert) == 0 || len(e.TLSKey) == 0 {
+			return fmt.Errorf("启用 Ingress TLS 时必须提供证书和私钥")
+		}
+		certFile, err := ws.Upload("insuite-app-tls.crt", string(e.TLSCert))
+		if err != nil {
+			return fmt.Errorf("上传 Ingress 证书失败: %v", err)
+		}
+		keyFile, err := ws.Upload("insuite-app-tls.key", string(e.TLSKey))
+		if err != nil {
+			return fmt.Errorf("上传 Ingress 私钥失败: %v", err)
+		}
+		defer ws.Remove(keyFile)
+		cmd := fmt.Sprintf("kubectl -n %s create secret tls insuite-app-tls --cert=%s --key=%s --dry-run=client -o yaml | kubectl apply -f -", namespace, certFile, keyFile)
+		if _, err := runKubectl(client, cmd); err != nil {
+			return fmt.Errorf("创建 Ingress TLS Secret 失败: %v", err)
+		}
+	}
+
+	file, err := ws.Upload("insuite-ingress.yaml", e.ingress(namespace))
+	if err != nil {
+		return fmt.Errorf("上传 Ingress 配置失败: %v", err)
+	}
+	if _, err := runKubectl(client, "kubectl apply -f "+file); err != nil {
+		return fmt.Errorf("创建 inSuite Ingress 失败: %v", err)
+	}
+	m.logger.Infof("inSuite Ingress 已创建: %v", e.Hosts)
+	return nil
+}
+
+// AccessURL 返回 inSuite 应用的访问地址。nodeIP 为 NodePort 方式使用的节点地址；
+// LoadBalancer 方式等待 ServiceLB 分配地址，最长等待 DeploymentTimeout
+func (m *Manager) AccessURL(client *ssh.Client, spec AppSpec, nodeIP string, policy WaitPolicy) (string, error) {
+	e := spec.Exposure
+	namespace := spec.namespace()
+	switch e.Type {
+	case ExposeIngress:
+		scheme := "http"
+		if e.TLS {
+			scheme = "https"
+		}
+		if e.TLS && e.Issuer != "" {
+			if err := m.waitForCertificate(client, namespace, policy.WithDefaults()); err != nil {
+				return "", err
+			}
+		}
+		return fmt.Sprintf("%s://%s/", scheme, e.Hosts[0]), nil
+
+	case ExposeLoadBalancer:
+		policy = policy.WithDefaults()
+		deadline := time.Now().Add(policy.DeploymentTimeout)
+		for {
+			result, err := runKubectlIdempotent(client, "kubectl get service insuite-app -n "+namespace+" -o jsonpath='{.status.loadBalancer.ingress[0].ip}'")
+			if err == nil && strings.TrimSpace(result.Stdout) != "" {
+				return fmt.Sprintf("http://%s:%d/", strings.TrimSpace(result.Stdout), e.lbPort()), nil
+			}
+			if time.Now().After(deadline) {
+				return "", fmt.Errorf("等待 ServiceLB 分配地址超时（%s），请确认端口 %d 未被占用且未禁用 servicelb", policy.DeploymentTimeout, e.lbPort())
+			}
+			time.Sleep(policy.PollInterval)
+		}
+
+	default:
+		result, err := runKubectlIdempotent(client, "kubectl get service insuite-app -n "+namespace+" -o jsonpath='{.spec.ports[0].nodePort}'")
+		if err != nil || strings.TrimSpace(result.Stdout) == "" {
+			return "", fmt.Errorf("获取 inSuite NodePort 失败: %v", err)
+		}
+		return fmt.Sprintf("http://%s:%s/", nodeIP, strings.TrimSpace(result.Stdout)), nil
+	}
+}
+
+// waitForCertificate 等待 cert-manager 为 Ingress 签发的证书就绪，最长等待 DeploymentTimeout
+func (m *Manager) waitForCertificate(client *ssh.Client, namespace string, policy WaitPolicy) error {
+	cmd := fmt.Sprintf(`kubectl -n %s get certificate insuite-app-tls -o jsonpath='{.status.conditions[?(@.type=="Ready")].status}{"\t"}{.status.conditions[?(@.type=="Ready")].message}'`, namespace)
+	deadline := time.Now().Add(policy.DeploymentTimeout)
+	for {
+		result, err := runKubectlIdempotent(client, cmd)
+		status, message := "", "Certificate 尚未创建"
+		if err == nil {
+			status, message, _ = strings.Cut(strings.TrimSpace(result.Stdout), "\t")
+		}
+		if status == "True" {
+			m.logger.Infof("命名空间 %s 的 Ingress 证书已签发", namespace)
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("等待 cert-manager 签发 Ingress 证书超时（%s）: %s", policy.DeploymentTimeout, message)
+		}
+		time.Sleep(policy.PollInterval)
+	}
+}
@@ -0,0 +1,55 @@
+package k3s
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// apiServerCert k3s 自动签发的 API Server 证书，默认有效期一年
+const apiServerCert = "/var/lib/rancher/k3s/server/tls/serving-kube-apiserver.crt"
+
+// Health 集群健康状态
+type Health struct {
+	Nodes []model.ClusterNode
+	// CertNotAfter API Server 证书到期时间，无法读取时为零值
+	CertNotAfter time.Time
+}
+
+// CheckHealth 读取节点状态和证书有效期
+func (m *Manager) CheckHealth(client *ssh.Client) (*Health, error) {
+	nodes, err := m.ListNodes(client)
+	if err != nil {
+		return nil, err
+	}
+	health := &Health{Nodes: nodes}
+
+	result, err := client.ExecuteIdempotentCommand("openssl x509 -enddate -noout -in " + apiServerCert)
+	if err != nil {
+		m.logger.Warnf("读取证书有效期失败: %v", err)
+		return health, nil
+	}
+	notAfter, err := parseCertEndDate(result.Stdout)
+	if err != nil {
+		m.logger.Warnf("%v", err)
+		return health, nil
+	}
+	health.CertNotAfter = notAfter
+	return health, nil
+}
+
+// parseCertEndDate 解析 "notAfter=Jan  2 15:04:05 2026 GMT" 格式的输出
+func parseCertEndDate(output string) (time.Time, error) {
+	value, ok := strings.CutPrefix(strings.TrimSpace(output), "notAfter=")
+	if !ok {
+		return time.Time{}, fmt.Errorf("无法解析证书有效期: %s", output)
+	}
+	notAfter, err := time.Parse("Jan _2 15:04:05 2006 MST", strings.TrimSpace(value))
+	if err != nil {
+		return time.Time{}, fmt.Errorf("无法解析证书有效期: %v", err)
+	}
+	return notAfter, nil
+}
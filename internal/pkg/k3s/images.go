@@ -0,0 +1,38 @@
+package k3s
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// PrePullImages 通过 k3s 内置 containerd 在节点上预先拉取镜像，已存在的镜像跳过
+func (m *Manager) PrePullImages(client *ssh.Client, nodeName string, images []string) error {
+	for i, image := range images {
+		progress := fmt.Sprintf("[%s %d/%d]", nodeName, i+1, len(images))
+
+		if result, err := client.ExecuteIdempotentCommand("k3s ctr images ls -q"); err == nil && containsLine(result.Stdout, image) {
+			m.logger.Infof("%s 镜像 %s 已存在，跳过", progress, image)
+			continue
+		}
+
+		m.logger.Infof("%s 开始拉取镜像 %s", progress, image)
+		start := time.Now()
+		if _, err := client.ExecuteIdempotentCommand("k3s ctr images pull " + image); err != nil {
+			return fmt.Errorf("节点 %s 拉取镜像 %s 失败: %v", nodeName, image, err)
+		}
+		m.logger.Infof("%s 镜像 %s 拉取完成，耗时 %s", progress, image, time.Since(start).Round(time.Second))
+	}
+	return nil
+}
+
+func containsLine(output, line string) bool {
+	for _, l := range strings.Split(output, "\n") {
+		if strings.TrimSpace(l) == line {
+			return true
+		}
+	}
+	return false
+}
@@ -3,37 +3,36 @@ package k3s
 import (
 	"bufio"
 	"bytes"
-	"crypto/rand"
+	"context"
 	"crypto/rsa"
 	"crypto/x509"
-	"crypto/x509/pkix"
-	"encoding/pem"
+	"errors"
 	"fmt"
-	"io"
-	"math/big"
 	"net"
-	"net/http"
 	"path"
 	"strings"
 	"time"
 
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/benchmark"
+	"k3s-deploy-backend/internal/pkg/bundle"
+	"k3s-deploy-backend/internal/pkg/hostos"
 	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/pki"
 	"k3s-deploy-backend/internal/pkg/ssh"
 )
 
 const (
-	officialInstallURL       = "https://get.k3s.io"
-	officialCNInstallURL     = "https://rancher-mirror.rancher.cn/k3s/k3s-install.sh"
-	defaultSystemRegistryURL = "registry.cn-hangzhou.aliyuncs.com"
-	additionalRegistryURLs   = "https://registry.cn-hangzhou.aliyuncs.com,https://mirror.ccs.tencentyun.com"
-	caExpirationYears        = 1000 // CA 证书有效期 100 年
-	clientExpirationYears    = 100  // 客户端证书有效期 10 年
-	daysInYear               = 365  // 每年近似天数，用于证书有效期计算
-	keyBits                  = 2048
+	officialInstallURL    = "https://get.k3s.io"
+	officialCNInstallURL  = "https://rancher-mirror.rancher.cn/k3s/k3s-install.sh"
+	caExpirationYears     = 1000 // CA 证书有效期 100 年
+	clientExpirationYears = 100  // 客户端证书有效期 10 年
+	daysInYear            = 365  // 每年近似天数，用于证书有效期计算
 )
 
 type Installer struct {
-	logger *logger.Logger
+	mirrors MirrorConfig
+	logger  *logger.Logger
 }
 
 type ModifyOptions struct {
@@ -43,10 +42,32 @@ type ModifyOptions struct {
 	DaysInYear            int
 }
 
-// CertificateAuthority 表示一个 CA
-type CertificateAuthority struct {
-	Cert       *x509.Certificate
-	PrivateKey *rsa.PrivateKey
+// InstallOptions 影响 k3s 安装参数的部署选项
+type InstallOptions struct {
+	// Docker 使用节点上已有的 Docker 作为容器运行时
+	Docker bool
+	// Script 自定义安装脚本，为空时使用官方或国内镜像脚本
+	Script *ScriptSource
+	// ResolvConf 使用节点 DNS 配置写入的 ResolvConfPath 作为 kubelet 的 resolv.conf
+	ResolvConf bool
+	// Airgap 离线安装包，设置后不访问外网，忽略 Script
+	Airgap *bundle.Bundle
+	// AllowUnverified k3s 二进制与官方校验和不一致或无法获取官方校验和时仍继续安装
+	AllowUnverified bool
+	// ServerArgs 仅用于 Server 节点的附加参数，如 --disable
+	ServerArgs []string
+	// ExtraArgs Server 与 Agent 共用的附加参数
+	ExtraArgs []string
+	// Hardened 安装完成后为 k3s 服务配置不限次数的自动重启
+	Hardened bool
+	// Security Server 安装前写入的 PodSecurity 准入配置与审计策略；设置 CIS 时安装后收紧各节点的文件权限
+	Security *model.SecurityOptions
+	// CNI 替换 flannel 的 CNI 插件，Server 安装前写入其 HelmChart
+	CNI *model.CNIOptions
+	// Mirrors 替换配置中的镜像源（探测镜像和发布地址仍使用配置），为 nil 时使用配置
+	Mirrors *model.MirrorOptions
+	// Proxy 节点使用的代理，以 HTTP_PROXY 等环境变量传给安装脚本，安装脚本将其写入 k3s 服务的环境文件
+	Proxy *model.ProxyOptions
 }
 
 // CertConfig 证书配置
@@ -59,75 +80,144 @@ type CertConfig struct {
 	Usage    []x509.ExtKeyUsage
 }
 
-func NewInstaller(logger *logger.Logger) *Installer {
+func NewInstaller(mirrors MirrorConfig, logger *logger.Logger) *Installer {
 	return &Installer{
-		logger: logger,
+		mirrors: mirrors,
+		logger:  logger,
 	}
 }
 
-func (i *Installer) InstallMaster(client *ssh.Client, nodeName string) error {
-	i.logger.Infof("开始在节点 %s 上安装K3s Master", nodeName)
+// InstallMaster 安装 Master 节点，返回安装过程中校验的文件摘要
+func (i *Installer) InstallMaster(client *ssh.Client, nodeName string, policy WaitPolicy, opts InstallOptions) ([]Digest, error) {
+	policy = policy.WithDefaults()
+	report(client, nodeName, model.LogInfo, "开始在节点 %s 上安装K3s Master", nodeName)
 
 	// 检查是否已经安装K3s
 	if result, err := client.ExecuteCommand("which k3s"); err == nil && result.Stdout != "" {
-		i.logger.Warnf("节点 %s 已经安装了K3s，跳过安装步骤", nodeName)
-		return nil
+		report(client, nodeName, model.LogWarn, "节点 %s 已经安装了K3s，跳过安装步骤", nodeName)
+		return nil, nil
+	}
+
+	osInfo, err := detectServiceManager(client)
+	if err != nil {
+		return nil, err
 	}
 
 	// 设置环境变量，仅包含节点名称
 	envArgs := []string{
 		"K3S_NODE_NAME=k3s-master",
 	}
-	cmdArgs := []string{}
+	cmdArgs := append(opts.cmdArgs(), opts.ServerArgs...)
+
+	if opts.Security != nil {
+		if err := i.WriteSecurityConfig(client, opts.Security); err != nil {
+			return nil, err
+		}
+	}
+	if opts.CNI != nil {
+		if err := i.WriteCNIManifest(client, opts.CNI); err != nil {
+			return nil, err
+		}
+	}
 
-	if err := i.autoInstallK3sByLocation(client, envArgs, cmdArgs); err != nil {
-		return fmt.Errorf("K3s Master安装失败: %v", err)
+	digests, err := i.autoInstallK3sByLocation(client, nodeName, osInfo, policy, opts, envArgs, cmdArgs)
+	if err != nil {
+		return digests, fmt.Errorf("K3s Master安装失败: %v", err)
 	}
 
 	// 验证安装
-	if err := i.verifyMasterInstallation(client); err != nil {
-		return fmt.Errorf("验证Master安装失败: %v", err)
+	stopWait := trackPhase(client, nodeName, model.PhaseServiceWait)
+	err = i.verifyMasterInstallation(client, osInfo, policy)
+	stopWait()
+	if err != nil {
+		return digests, fmt.Errorf("验证Master安装失败: %w", err)
+	}
+	if opts.Security != nil && opts.Security.CIS {
+		if err := i.hardenCISFiles(client); err != nil {
+			return digests, err
+		}
+	}
+	if opts.Hardened {
+		if err := i.hardenService(client, osInfo, "k3s"); err != nil {
+			return digests, err
+		}
 	}
 
-	i.logger.Infof("节点 %s K3s Master安装成功", nodeName)
-	return nil
+	report(client, nodeName, model.LogInfo, "节点 %s K3s Master安装成功", nodeName)
+	return digests, nil
 }
 
-func (i *Installer) InstallAgent(client *ssh.Client, masterClient *ssh.Client, nodeName string, token string) error {
-	i.logger.Infof("开始在节点 %s 上安装K3s Agent", nodeName)
+// ServerURL 返回 Agent 加入 Master 使用的地址
+func (i *Installer) ServerURL(masterClient *ssh.Client) (string, error) {
+	masterIP, err := i.getInternalIP(masterClient)
+	if err != nil {
+		return "", fmt.Errorf("获取Master内部IP失败: %v", err)
+	}
+	i.logger.Infof("从Master节点自动获取的内部IP: %s", masterIP)
+	return fmt.Sprintf("https://%s:6443", masterIP), nil
+}
+
+// InstallAgent 安装 Agent 节点并加入 serverURL 所在的集群，返回安装过程中校验的文件摘要
+func (i *Installer) InstallAgent(client *ssh.Client, serverURL string, nodeName string, token string, policy WaitPolicy, opts InstallOptions) ([]Digest, error) {
+	policy = policy.WithDefaults()
+	report(client, nodeName, model.LogInfo, "开始在节点 %s 上安装K3s Agent", nodeName)
 
 	// 检查是否已经安装K3s
 	if result, err := client.ExecuteCommand("which k3s"); err == nil && result.Stdout != "" {
-		i.logger.Warnf("节点 %s 已经安装了K3s，跳过安装步骤", nodeName)
-		return nil
+		report(client, nodeName, model.LogWarn, "节点 %s 已经安装了K3s，跳过安装步骤", nodeName)
+		return nil, nil
 	}
 
-	// 获取Master内部IP
-	masterIP, err := i.getInternalIP(masterClient)
+	osInfo, err := detectServiceManager(client)
 	if err != nil {
-		return fmt.Errorf("获取Master内部IP失败: %v", err)
+		return nil, err
 	}
-	i.logger.Infof("从Master节点自动获取的内部IP: %s", masterIP)
 
 	// 设置环境变量，包含节点名称
 	envArgs := []string{
-		fmt.Sprintf("K3S_URL=https://%s:6443", masterIP),
+		fmt.Sprintf("K3S_URL=%s", serverURL),
 		fmt.Sprintf("K3S_TOKEN=%s", token),
 		fmt.Sprintf("K3S_NODE_NAME=%s", nodeName),
 	}
-	cmdArgs := []string{}
+	cmdArgs := opts.cmdArgs()
 
-	if err := i.autoInstallK3sByLocation(client, envArgs, cmdArgs); err != nil {
-		return fmt.Errorf("K3s Agent安装失败: %v", err)
+	digests, err := i.autoInstallK3sByLocation(client, nodeName, osInfo, policy, opts, envArgs, cmdArgs)
+	if err != nil {
+		return digests, fmt.Errorf("K3s Agent安装失败: %v", err)
 	}
 
 	// 验证 Agent 安装
-	if err := i.verifyAgentInstallation(client); err != nil {
-		return fmt.Errorf("验证Agent安装失败: %v", err)
+	stopWait := trackPhase(client, nodeName, model.PhaseServiceWait)
+	err = i.verifyAgentInstallation(client, osInfo, policy)
+	stopWait()
+	if err != nil {
+		return digests, fmt.Errorf("验证Agent安装失败: %w", err)
+	}
+	if opts.Security != nil && opts.Security.CIS {
+		if err := i.hardenCISFiles(client); err != nil {
+			return digests, err
+		}
+	}
+	if opts.Hardened {
+		if err := i.hardenService(client, osInfo, "k3s-agent"); err != nil {
+			return digests, err
+		}
 	}
 
-	i.logger.Infof("节点 %s K3s Agent安装成功", nodeName)
-	return nil
+	report(client, nodeName, model.LogInfo, "节点 %s K3s Agent安装成功", nodeName)
+	return digests, nil
+}
+
+// cmdArgs 将安装选项转换为 k3s 命令参数
+func (o InstallOptions) cmdArgs() []string {
+	args := []string{}
+	if o.Docker {
+		args = append(args, "--docker")
+	}
+	if o.ResolvConf {
+		args = append(args, "--resolv-conf", ResolvConfPath)
+	}
+	return append(args, o.ExtraArgs...)
 }
 
 func (i *Installer) getInternalIP(client *ssh.Client) (string, error) {
@@ -160,14 +250,21 @@ func (i *Installer) getInternalIP(client *ssh.Client) (string, error) {
 	return "", fmt.Errorf("无法获取内网IP地址")
 }
 
-func (i *Installer) autoInstallK3sByLocation(client *ssh.Client, envArgs, cmdArgs []string) error {
+func (i *Installer) autoInstallK3sByLocation(client *ssh.Client, nodeName string, osInfo *hostos.Info, policy WaitPolicy, opts InstallOptions, envArgs, cmdArgs []string) ([]Digest, error) {
+	if opts.Airgap != nil {
+		return i.installAirgap(client, nodeName, osInfo, policy, opts, envArgs, cmdArgs)
+	}
+
+	stopDownload := trackPhase(client, nodeName, model.PhaseDownload)
 	installURL, err := i.getInstallURL(client)
+	stopDownload()
 	if err != nil {
-		return err
+		return nil, err
 	}
 
-	i.logger.Infof("使用安装URL: %s", installURL)
-	return i.executeInstall(client, installURL, envArgs, cmdArgs)
+	report(client, nodeName, model.LogInfo, "使用安装脚本 %s", installURL)
+	benchmark.Annotate(client.RequestID(), "installURL", installURL)
+	return i.executeInstall(client, nodeName, osInfo, policy, opts, installURL, envArgs, cmdArgs)
 }
 
 func (i *Installer) getInstallURL(client *ssh.Client) (string, error) {
@@ -210,7 +307,8 @@ func (i *Installer) isInternetReachable(client *ssh.Client, host string) (bool,
 	return result.ExitCode == 0, nil
 }
 
-func (i *Installer) executeInstall(client *ssh.Client, installURL string, envArgs, cmdArgs []string) error {
+// executeInstall 执行安装脚本。在线安装时先以 INSTALL_K3S_SKIP_START 安装，按官方校验和校验二进制后再启动服务
+func (i *Installer) executeInstall(client *ssh.Client, nodeName string, osInfo *hostos.Info, policy WaitPolicy, opts InstallOptions, installURL string, envArgs, cmdArgs []string) ([]Digest, error) {
 	i.logger.Infof("=== K3s 安装调试信息 ===")
 	i.logger.Infof("安装URL: %s", installURL)
 	i.logger.Warnf("脚本在后端下载，确保 %s 适合目标节点网络环境", installURL)
@@ -232,50 +330,22 @@ func (i *Installer) executeInstall(client *ssh.Client, installURL string, envArg
 	}
 
 	i.logger.Info("Step 1: 下载K3s安装脚本")
-	resp, err := http.Get(installURL)
-	if err != nil {
-		return fmt.Errorf("下载安装脚本失败: %v", err)
-	}
-	defer resp.Body.Close()
-
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("下载脚本失败: HTTP %d", resp.StatusCode)
-	}
-
-	script, err := io.ReadAll(resp.Body)
+	stopDownload := trackPhase(client, nodeName, model.PhaseDownload)
+	script, err := i.loadScript(installURL, opts.Script, policy.ScriptDownloadTimeout)
+	stopDownload()
 	if err != nil {
-		return fmt.Errorf("读取脚本内容失败: %v", err)
+		return nil, err
 	}
+	digests := []Digest{i.scriptDigest(nodeName, opts, script)}
 
 	i.logger.Infof("脚本下载成功，大小: %d bytes", len(script))
 
 	i.logger.Info("Step 2: 修改安装脚本")
-	var modifiedScript []byte
-
-	switch installURL {
-	case officialInstallURL:
-		i.logger.Info("使用官方安装URL - 仅应用证书配置")
-		modifiedScript, err = i.modifyScriptSelective(script, ModifyOptions{
-			EnableRegistry:        false,
-			EnableCertConfig:      true,
-			ClientExpirationYears: clientExpirationYears,
-			DaysInYear:            daysInYear,
-		})
-	case officialCNInstallURL:
-		i.logger.Info("使用国内镜像URL - 应用注册表设置和证书配置")
-		modifiedScript, err = i.modifyScriptSelective(script, ModifyOptions{
-			EnableRegistry:        true,
-			EnableCertConfig:      true,
-			ClientExpirationYears: clientExpirationYears,
-			DaysInYear:            daysInYear,
-		})
-	default:
-		i.logger.Infof("使用未知/自定义URL (%s) - 不应用修改", installURL)
-		modifiedScript = script
-	}
-
+	patches := scriptPatches(installURL, opts.Script)
+	i.logger.Infof("应用修改 - 注册表设置: %v，证书配置: %v", patches.EnableRegistry, patches.EnableCertConfig)
+	modifiedScript, err := i.modifyScriptSelective(script, patches)
 	if err != nil {
-		return fmt.Errorf("修改脚本失败: %v", err)
+		return digests, fmt.Errorf("修改脚本失败: %v", err)
 	}
 
 	i.logger.Infof("脚本修改完成，最终大小: %d bytes", len(modifiedScript))
@@ -334,14 +404,26 @@ func (i *Installer) executeInstall(client *ssh.Client, installURL string, envArg
 		i.logger.Info("已添加SELinux绕过配置")
 	}
 
+	if osInfo.InitSystem == hostos.InitOpenRC {
+		// 安装脚本会自动生成 /etc/init.d 服务脚本；OpenRC 发行版（Alpine）没有 rpm 与 SELinux 策略包
+		i.logger.Infof("--- OpenRC 配置（%s）---", osInfo.ID)
+		finalEnvArgs = append(finalEnvArgs, "INSTALL_K3S_SKIP_SELINUX_RPM=true")
+	}
+
 	if installURL == officialCNInstallURL {
 		i.logger.Info("--- 国内镜像配置 ---")
 
-		additionalEnvs := []string{
-			"INSTALL_K3S_MIRROR=cn",
-			fmt.Sprintf("INSTALL_K3S_REGISTRIES=%s", additionalRegistryURLs),
+		stopDownload := trackPhase(client, nodeName, model.PhaseDownload)
+		plan, err := i.planMirrors(client, nodeName, i.mirrorsFor(opts.Mirrors))
+		stopDownload()
+		if err != nil {
+			return digests, err
+		}
+		benchmark.Annotate(client.RequestID(), "mirrors", strings.Join(plan.mirrors, ","))
+		finalEnvArgs = append(finalEnvArgs, "INSTALL_K3S_MIRROR=cn")
+		if len(plan.mirrors) > 0 {
+			finalEnvArgs = append(finalEnvArgs, fmt.Sprintf("INSTALL_K3S_REGISTRIES=%s", strings.Join(plan.mirrors, ",")))
 		}
-		finalEnvArgs = append(finalEnvArgs, additionalEnvs...)
 
 		isAgentMode := false
 		for _, env := range finalEnvArgs {
@@ -352,18 +434,29 @@ func (i *Installer) executeInstall(client *ssh.Client, installURL string, envArg
 		}
 
 		additionalArgs := []string{}
-		if !isAgentMode {
+		if !isAgentMode && plan.systemDefault != "" {
 			additionalArgs = []string{
-				fmt.Sprintf("--system-default-registry=%s", defaultSystemRegistryURL),
+				fmt.Sprintf("--system-default-registry=%s", plan.systemDefault),
 				"--disable-default-registry-endpoint",
 			}
 			i.logger.Info("已添加国内镜像命令参数（仅 Server 模式）")
+		} else if !isAgentMode {
+			i.logger.Info("系统镜像仓库不可用，系统镜像通过镜像加速拉取")
 		} else {
 			i.logger.Info("跳过国内镜像命令参数（Agent 模式）")
 		}
 		finalCmdArgs = append(finalCmdArgs, additionalArgs...)
 	}
 
+	if opts.Proxy != nil {
+		finalEnvArgs = append(finalEnvArgs, opts.Proxy.Env()...)
+	}
+
+	if opts.Airgap == nil {
+		// 二进制校验通过前不启动服务
+		finalEnvArgs = append(finalEnvArgs, "INSTALL_K3S_SKIP_START=true")
+	}
+
 	i.logger.Infof("最终环境变量: %d 总计", len(finalEnvArgs))
 	for idx, env := range finalEnvArgs {
 		if strings.Contains(strings.ToUpper(env), "TOKEN") || strings.Contains(strings.ToUpper(env), "PASSWORD") {
@@ -406,10 +499,31 @@ func (i *Installer) executeInstall(client *ssh.Client, installURL string, envArg
 		}
 	}
 
-	i.logger.Info("Step 6: 开始执行安装")
+	unit := "k3s"
+	if isAgentMode {
+		unit = "k3s-agent"
+	}
+	var prev previousBinary
+	if opts.Airgap == nil {
+		prev = i.backupBinary(client, osInfo, unit)
+		defer prev.cleanup(client)
+	}
+
+	i.logger.Infof("Step 6: 开始执行安装（最长 %s）", policy.InstallTimeout)
+	defer trackPhase(client, nodeName, model.PhaseInstall)()
 	i.logger.Infof("等效官方安装命令：")
 	i.logger.Infof("  curl -sfL %s | %s sh -s - %s", installURL, strings.Join(finalEnvArgs, " "), strings.Join(finalCmdArgs, " "))
-	result, err := client.ExecuteCommandWithStdin(modifiedScript, cmd, finalEnvArgs)
+	ctx, cancel := context.WithTimeout(context.Background(), policy.InstallTimeout)
+	defer cancel()
+	result, err := client.ExecuteCommandWithStdinContext(ctx, modifiedScript, cmd, finalEnvArgs)
+	if err != nil {
+		if restoreErr := prev.restore(client, osInfo, unit); restoreErr != nil {
+			i.logger.Errorf("%v", restoreErr)
+		}
+	}
+	if errors.Is(err, ssh.ErrCommandTimeout) {
+		return digests, fmt.Errorf("K3s安装超时: 安装脚本 %s 内未执行完成，已终止（可通过 wait.installTimeout 调整）", policy.InstallTimeout)
+	}
 	if err != nil {
 		i.logger.Errorf("K3s安装失败: %v", err)
 		if result != nil {
@@ -419,18 +533,43 @@ func (i *Installer) executeInstall(client *ssh.Client, installURL string, envArg
 			i.logger.Errorf("无标准输出或错误输出（result is nil）")
 		}
 		if isDomestic {
-			i.logger.Info("💡 注意：已为国产操作系统启用SELinux绕过 (%s)", osName)
+			i.logger.Infof("💡 注意：已为国产操作系统启用SELinux绕过 (%s)", osName)
 			i.logger.Info("💡 如果问题持续，问题可能与SELinux无关")
 		}
-		return fmt.Errorf("K3s安装失败: %v", err)
+		return digests, fmt.Errorf("K3s安装失败: %v", err)
 	}
 
 	i.logger.Infof("安装脚本输出: %s", result.Stdout)
+
+	if opts.Airgap == nil {
+		i.logger.Info("Step 7: 校验 k3s 二进制并启动服务")
+		digest, err := i.verifyBinary(client, nodeName, installURL, result.Stdout, opts.AllowUnverified)
+		digests = append(digests, digest)
+		if err != nil {
+			if restoreErr := prev.restore(client, osInfo, unit); restoreErr != nil {
+				return digests, fmt.Errorf("%v；%v", err, restoreErr)
+			}
+			if prev.backup != "" {
+				return digests, fmt.Errorf("%v，已恢复原 k3s 二进制", err)
+			}
+			return digests, err
+		}
+		if _, err := client.ExecuteCommand(osInfo.ServiceEnableStartCommand(unit)); err != nil {
+			return digests, fmt.Errorf("启动 %s 服务失败: %v", unit, err)
+		}
+		// 重新安装时服务仍以旧二进制运行（INSTALL_K3S_SKIP_START 不会重启），重启后使用新二进制和配置
+		if prev.active {
+			if _, err := client.ExecuteCommand(osInfo.ServiceRestartCommand(unit)); err != nil {
+				return digests, fmt.Errorf("重启 %s 服务失败: %v", unit, err)
+			}
+		}
+	}
+
 	i.logger.Info("K3s安装完成!")
 	if isDomestic {
 		i.logger.Infof("国产操作系统 (%s) 兼容模式已使用", osName)
 	}
-	return nil
+	return digests, nil
 }
 
 func (i *Installer) isDomesticOS(client *ssh.Client) (bool, string, error) {
@@ -574,30 +713,40 @@ func (i *Installer) modifyScriptSelective(script []byte, options ModifyOptions)
 	return result, nil
 }
 
-func (i *Installer) verifyMasterInstallation(client *ssh.Client) error {
-	i.logger.Info("等待K3s服务启动...")
-	// 增加重试机制，最多等待3分钟
-	for attempt := 0; attempt < 18; attempt++ {
-		result, err := client.ExecuteCommand("systemctl is-active k3s")
-		if err == nil && strings.Contains(result.Stdout, "active") {
-			i.logger.Info("K3s服务已启动")
-			break
-		}
-		i.logger.Warnf("K3s服务未就绪（尝试 %d/%d）: %v, Stdout: %s, Stderr: %s", attempt+1, 18, err, result.Stdout, result.Stderr)
-		time.Sleep(10 * time.Second)
+func (i *Installer) verifyMasterInstallation(client *ssh.Client, osInfo *hostos.Info, policy WaitPolicy) error {
+	i.logger.Infof("等待K3s服务启动（最长 %s）...", policy.ServiceTimeout)
+	if waitForService(client, osInfo, "k3s", policy, i.logger.Warnf) {
+		i.logger.Info("K3s服务已启动")
 	}
 
-	result, err := client.ExecuteCommand("systemctl is-active k3s")
-	if err != nil || !strings.Contains(result.Stdout, "active") {
+	result, err := client.ExecuteIdempotentCommand(osInfo.ServiceActiveCommand("k3s"))
+	if err != nil || strings.TrimSpace(result.Stdout) != "active" {
 		// 获取更多服务状态信息
-		logResult, logErr := client.ExecuteCommand("journalctl -u k3s.service -n 50")
+		journal := ""
+		logResult, logErr := client.ExecuteIdempotentCommand(osInfo.ServiceLogsCommand("k3s", 50))
 		if logErr == nil {
-			i.logger.Errorf("K3s服务日志: %s", logResult.Stdout)
+			journal = logResult.Stdout
+			i.logger.Errorf("K3s服务日志: %s", journal)
+		}
+		stderr := ""
+		if result != nil {
+			stderr = result.Stderr
+		}
+		return &ServiceError{
+			Unit:    "k3s",
+			Err:     fmt.Errorf("K3s服务未正常运行（等待 %s 后）: %v, Stderr: %s", policy.ServiceTimeout, err, stderr),
+			Journal: journal,
 		}
-		return fmt.Errorf("K3s服务未正常运行: %v, Stderr: %s", err, result.Stderr)
 	}
 
-	result, err = client.ExecuteCommand("kubectl get nodes")
+	// 重新检测 kubectl，重装到其他目录或修改了 write-kubeconfig 后不沿用旧的缓存
+	kubectl, err := DetectKubectl(client)
+	if err != nil {
+		return err
+	}
+	i.logger.Infof("kubectl: %s", kubectl.Command())
+
+	result, err = runKubectlIdempotent(client, "kubectl get nodes")
 	if err != nil {
 		return fmt.Errorf("kubectl命令执行失败: %v", err)
 	}
@@ -609,146 +758,59 @@ func (i *Installer) verifyMasterInstallation(client *ssh.Client) error {
 	return nil
 }
 
-func (i *Installer) verifyAgentInstallation(client *ssh.Client) error {
-	i.logger.Info("等待K3s Agent服务启动...")
-	// 增加重试机制，最多等待3分钟
-	for attempt := 0; attempt < 18; attempt++ {
-		result, err := client.ExecuteCommand("systemctl is-active k3s-agent")
-		if err == nil && strings.Contains(result.Stdout, "active") {
-			i.logger.Info("K3s Agent服务已启动")
-			break
-		}
-		i.logger.Warnf("K3s Agent服务未就绪（尝试 %d/%d）: %v, Stdout: %s, Stderr: %s", attempt+1, 18, err, result.Stdout, result.Stderr)
-		time.Sleep(10 * time.Second)
+func (i *Installer) verifyAgentInstallation(client *ssh.Client, osInfo *hostos.Info, policy WaitPolicy) error {
+	i.logger.Infof("等待K3s Agent服务启动（最长 %s）...", policy.ServiceTimeout)
+	if waitForService(client, osInfo, "k3s-agent", policy, i.logger.Warnf) {
+		i.logger.Info("K3s Agent服务已启动")
 	}
 
-	result, err := client.ExecuteCommand("systemctl is-active k3s-agent")
-	if err != nil || !strings.Contains(result.Stdout, "active") {
+	result, err := client.ExecuteIdempotentCommand(osInfo.ServiceActiveCommand("k3s-agent"))
+	if err != nil || strings.TrimSpace(result.Stdout) != "active" {
 		// 获取更多服务状态信息
-		logResult, logErr := client.ExecuteCommand("journalctl -u k3s-agent.service -n 50")
+		journal := ""
+		logResult, logErr := client.ExecuteIdempotentCommand(osInfo.ServiceLogsCommand("k3s-agent", 50))
 		if logErr == nil {
-			i.logger.Errorf("K3s Agent服务日志: %s", logResult.Stdout)
+			journal = logResult.Stdout
+			i.logger.Errorf("K3s Agent服务日志: %s", journal)
+		}
+		stderr := ""
+		if result != nil {
+			stderr = result.Stderr
+		}
+		return &ServiceError{
+			Unit:    "k3s-agent",
+			Err:     fmt.Errorf("K3s Agent服务未正常运行（等待 %s 后）: %v, Stderr: %s", policy.ServiceTimeout, err, stderr),
+			Journal: journal,
 		}
-		return fmt.Errorf("K3s Agent服务未正常运行: %v, Stderr: %s", err, result.Stderr)
 	}
 
 	return nil
 }
 
-// generatePrivateKey 生成 RSA 私钥
-func generatePrivateKey() (*rsa.PrivateKey, error) {
-	return rsa.GenerateKey(rand.Reader, keyBits)
-}
-
-// createCertificateTemplate 创建证书模板
-func createCertificateTemplate(cn string, isCA bool, usage []x509.ExtKeyUsage) (*x509.Certificate, error) {
-	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate serial number: %v", err)
-	}
-
-	now := time.Now()
-	var notAfter time.Time
-	if isCA {
-		// CA 证书有效期 10 年
-		notAfter = now.AddDate(caExpirationYears, 0, 0)
-	} else {
-		// 客户端证书有效期 10 年
-		notAfter = now.AddDate(clientExpirationYears, 0, 0)
-	}
-
-	template := &x509.Certificate{
-		SerialNumber: serialNumber,
-		Subject: pkix.Name{
-			CommonName: cn,
-		},
-		NotBefore:             now,
-		NotAfter:              notAfter,
-		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
-		ExtKeyUsage:           usage,
-		BasicConstraintsValid: true,
-		IsCA:                  isCA,
-	}
-
-	if isCA {
-		template.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
-	}
-
-	return template, nil
-}
-
-// generateCA 生成 CA 证书
-func generateCA(cn string) (*CertificateAuthority, error) {
-	// 生成私钥
-	privateKey, err := generatePrivateKey()
-	if err != nil {
-		return nil, fmt.Errorf("failed to generate private key: %v", err)
-	}
-
-	// 创建证书模板
-	template, err := createCertificateTemplate(cn, true, nil)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create certificate template: %v", err)
-	}
-
-	// 生成自签名证书
-	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
-	if err != nil {
-		return nil, fmt.Errorf("failed to create certificate: %v", err)
-	}
-
-	// 解析证书
-	cert, err := x509.ParseCertificate(certDER)
-	if err != nil {
-		return nil, fmt.Errorf("failed to parse certificate: %v", err)
-	}
-
-	return &CertificateAuthority{
-		Cert:       cert,
-		PrivateKey: privateKey,
-	}, nil
+// generateCA 生成 k3s 使用的 CA 证书
+func generateCA(cn string) (*pki.CertificateAuthority, error) {
+	// CA 证书有效期 10 年
+	return pki.GenerateCA(cn, time.Now().AddDate(caExpirationYears, 0, 0))
 }
 
 // generateClientCert 生成客户端证书
-func generateClientCert(cn string, ca *CertificateAuthority, usage []x509.ExtKeyUsage) (*x509.Certificate, *rsa.PrivateKey, error) {
-	// 生成私钥
-	privateKey, err := generatePrivateKey()
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to generate private key: %v", err)
-	}
-
-	// 创建证书模板
-	template, err := createCertificateTemplate(cn, false, usage)
+func generateClientCert(cn string, ca *pki.CertificateAuthority, usage []x509.ExtKeyUsage) (*x509.Certificate, *rsa.PrivateKey, error) {
+	// 客户端证书有效期 10 年
+	template, err := pki.NewTemplate(cn, false, usage, time.Now().AddDate(clientExpirationYears, 0, 0))
 	if err != nil {
 		return nil, nil, fmt.Errorf("failed to create certificate template: %v", err)
 	}
-
-	// 使用 CA 签名
-	certDER, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, &privateKey.PublicKey, ca.PrivateKey)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to create certificate: %v", err)
-	}
-
-	// 解析证书
-	cert, err := x509.ParseCertificate(certDER)
-	if err != nil {
-		return nil, nil, fmt.Errorf("failed to parse certificate: %v", err)
-	}
-
-	return cert, privateKey, nil
+	return ca.Issue(template)
 }
 
 // saveCertificateAndKey 保存证书和私钥到远程节点
 func saveCertificateAndKey(cert *x509.Certificate, privateKey *rsa.PrivateKey, certPath, keyPath string, client *ssh.Client) error {
-	// 编码证书
-	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
-
-	// 编码私钥
-	privKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	// 编码证书和私钥
+	certPEM := pki.EncodeCertificate(cert)
+	keyPEM, err := pki.EncodePrivateKey(privateKey)
 	if err != nil {
-		return fmt.Errorf("failed to marshal private key: %v", err)
+		return err
 	}
-	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privKeyBytes})
 
 	// 上传证书文件
 	if err := client.UploadFile(string(certPEM), certPath); err != nil {
@@ -808,7 +870,7 @@ func (i *Installer) generateCustomCACerts(client *ssh.Client) error {
 	}
 
 	// 存储生成的 CA
-	cas := make(map[string]*CertificateAuthority)
+	cas := make(map[string]*pki.CertificateAuthority)
 
 	// 生成 CA 证书
 	for _, config := range caConfigs {
@@ -844,7 +906,7 @@ func (i *Installer) generateCustomCACerts(client *ssh.Client) error {
 		CN       string
 		KeyFile  string
 		CertFile string
-		CA       *CertificateAuthority
+		CA       *pki.CertificateAuthority
 		Usage    []x509.ExtKeyUsage
 	}{
 		{
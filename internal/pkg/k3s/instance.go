@@ -0,0 +1,271 @@
+package k3s
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"sort"
+	"strings"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// DefaultInstance 未指定实例时使用的实例名和命名空间
+const DefaultInstance = "insuite"
+
+// 命名空间标签：标记由本服务部署的实例，删除实例前据此确认命名空间归属
+const (
+	ManagedByLabel = "app.kubernetes.io/managed-by"
+	ManagedBy      = "k3s-deploy-backend"
+	InstanceLabel  = "insuite.instance"
+)
+
+// Quota 实例命名空间的资源配额，为空的字段不限制
+type Quota struct {
+	RequestsCPU    string
+	RequestsMemory string
+	LimitsCPU      string
+	LimitsMemory   string
+	Pods           int
+}
+
+// Validate 检查资源数量格式
+func (q Quota) Validate() error {
+	return Resources{
+		RequestsCPU:    q.RequestsCPU,
+		RequestsMemory: q.RequestsMemory,
+		LimitsCPU:      q.LimitsCPU,
+		LimitsMemory:   q.LimitsMemory,
+	}.Validate()
+}
+
+func (q Quota) manifest(namespace string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `apiVersion: v1
+kind: ResourceQuota
+metadata:
+  name: insuite-quota
+  namespace: %s
+spec:
+  hard:
+`, namespace)
+	for _, item := range []struct{ name, value string }{
+		{"requests.cpu", q.RequestsCPU},
+		{"requests.memory", q.RequestsMemory},
+		{"limits.cpu", q.LimitsCPU},
+		{"limits.memory", q.LimitsMemory},
+	} {
+		if item.value != "" {
+			fmt.Fprintf(&b, "    %s: %q\n", item.name, item.value)
+		}
+	}
+	if q.Pods > 0 {
+		fmt.Fprintf(&b, "    pods: \"%d\"\n", q.Pods)
+	}
+	return b.String()
+}
+
+// applyQuota 设置实例命名空间的资源配额，quota 为 nil 时删除已有配额
+func (m *Manager) applyQuota(client *ssh.Client, ws *Workspace, namespace string, quota *Quota) error {
+	if quota == nil {
+		if _, err := runKubectl(client, "kubectl -n "+namespace+" delete resourcequota insuite-quota --ignore-not-found"); err != nil {
+			return fmt.Errorf("删除资源配额失败: %v", err)
+		}
+		return nil
+	}
+
+	file, err := ws.Upload("insuite-quota.yaml", quota.manifest(namespace))
+	if err != nil {
+		return fmt.Errorf("上传资源配额失败: %v", err)
+	}
+	if _, err := runKubectl(client, "kubectl apply -f "+file); err != nil {
+		return fmt.Errorf("设置资源配额失败: %v", err)
+	}
+	m.logger.Infof("命名空间 %s 资源配额已更新", namespace)
+	return nil
+}
+
+// LimitRange 实例命名空间中每个容器的资源默认值与上限，为空的字段不限制
+type LimitRange struct {
+	DefaultRequestCPU    string
+	DefaultRequestMemory string
+	DefaultLimitCPU      string
+	DefaultLimitMemory   string
+	MaxCPU               string
+	MaxMemory            string
+}
+
+// Validate 检查资源数量格式，以及默认请求不超过默认限制、默认值不超过上限
+func (l LimitRange) Validate() error {
+	for _, q := range []string{l.DefaultRequestCPU, l.DefaultRequestMemory, l.DefaultLimitCPU, l.DefaultLimitMemory, l.MaxCPU, l.MaxMemory} {
+		if q != "" && !quantityPattern.MatchString(q) {
+			return fmt.Errorf("无效的资源数量: %s", q)
+		}
+	}
+	for _, pair := range []struct{ low, high, desc string }{
+		{l.DefaultRequestCPU, l.DefaultLimitCPU, "CPU 默认请求大于默认限制"},
+		{l.DefaultRequestMemory, l.DefaultLimitMemory, "内存默认请求大于默认限制"},
+		{l.DefaultRequestCPU, l.MaxCPU, "CPU 默认请求大于上限"},
+		{l.DefaultRequestMemory, l.MaxMemory, "内存默认请求大于上限"},
+		{l.DefaultLimitCPU, l.MaxCPU, "CPU 默认限制大于上限"},
+		{l.DefaultLimitMemory, l.MaxMemory, "内存默认限制大于上限"},
+	} {
+		if pair.low != "" && pair.high != "" && parseQuantity(pair.low) > parseQuantity(pair.high) {
+			return fmt.Errorf("%s（%s > %s）", pair.desc, pair.low, pair.high)
+		}
+	}
+	return nil
+}
+
+// Admits 检查容器资源不超过上限，超过上限的 Pod 会被准入控制拒绝
+func (l LimitRange) Admits(r Resources) error {
+	for _, pair := range []struct{ value, max, name string }{
+		{r.RequestsCPU, l.MaxCPU, "CPU 请求"},
+		{r.LimitsCPU, l.MaxCPU, "CPU 限制"},
+		{r.RequestsMemory, l.MaxMemory, "内存请求"},
+		{r.LimitsMemory, l.MaxMemory, "内存限制"},
+	} {
+		if pair.value != "" && pair.max != "" && parseQuantity(pair.value) > parseQuantity(pair.max) {
+			return fmt.Errorf("%s %s 超过 LimitRange 上限 %s", pair.name, pair.value, pair.max)
+		}
+	}
+	return nil
+}
+
+func (l LimitRange) manifest(namespace string) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, `apiVersion: v1
+kind: LimitRange
+metadata:
+  name: insuite-limits
+  namespace: %s
+spec:
+  limits:
+  - type: Container
+`, namespace)
+	for _, item := range []struct{ field, cpu, memory string }{
+		{"defaultRequest", l.DefaultRequestCPU, l.DefaultRequestMemory},
+		{"default", l.DefaultLimitCPU, l.DefaultLimitMemory},
+		{"max", l.MaxCPU, l.MaxMemory},
+	} {
+		if item.cpu == "" && item.memory == "" {
+			continue
+		}
+		fmt.Fprintf(&b, "    %s:\n", item.field)
+		if item.cpu != "" {
+			fmt.Fprintf(&b, "      cpu: %q\n", item.cpu)
+		}
+		if item.memory != "" {
+			fmt.Fprintf(&b, "      memory: %q\n", item.memory)
+		}
+	}
+	return b.String()
+}
+
+// applyLimitRange 设置实例命名空间的 LimitRange，limits 为 nil 时删除已有的 LimitRange
+func (m *Manager) applyLimitRange(client *ssh.Client, ws *Workspace, namespace string, limits *LimitRange) error {
+	if limits == nil {
+		if _, err := runKubectl(client, "kubectl -n "+namespace+" delete limitrange insuite-limits --ignore-not-found"); err != nil {
+			return fmt.Errorf("删除 LimitRange 失败: %v", err)
+		}
+		return nil
+	}
+
+	file, err := ws.Upload("insuite-limits.yaml", limits.manifest(namespace))
+	if err != nil {
+		return fmt.Errorf("上传 LimitRange 失败: %v", err)
+	}
+	if _, err := runKubectl(client, "kubectl apply -f "+file); err != nil {
+		return fmt.Errorf("设置 LimitRange 失败: %v", err)
+	}
+	m.logger.Infof("命名空间 %s LimitRange 已更新", namespace)
+	return nil
+}
+
+// quotaList kubectl get resourcequota -o json 输出中用到的字段
+type quotaList struct {
+	Items []struct {
+		Metadata struct {
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Status struct {
+			Hard map[string]string `json:"hard"`
+			Used map[string]string `json:"used"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// parseQuotaUsage 解析资源配额列表，计算各项用量占上限的百分比，按命名空间排序
+func parseQuotaUsage(output string) ([]model.NamespaceQuota, error) {
+	var list quotaList
+	if err := json.Unmarshal([]byte(output), &list); err != nil {
+		return nil, fmt.Errorf("解析资源配额失败: %v", err)
+	}
+	quotas := make([]model.NamespaceQuota, 0, len(list.Items))
+	for _, item := range list.Items {
+		quota := model.NamespaceQuota{
+			Namespace: item.Metadata.Namespace,
+			Hard:      item.Status.Hard,
+			Used:      item.Status.Used,
+			Percent:   make(map[string]int, len(item.Status.Hard)),
+		}
+		for name, hard := range item.Status.Hard {
+			if limit := parseQuantity(hard); limit > 0 {
+				quota.Percent[name] = int(math.Round(parseQuantity(item.Status.Used[name]) / limit * 100))
+			}
+		}
+		quotas = append(quotas, quota)
+	}
+	sort.Slice(quotas, func(i, j int) bool { return quotas[i].Namespace < quotas[j].Namespace })
+	return quotas, nil
+}
+
+// QuotaUsage 读取全部实例命名空间中由 deploy-insuite 创建的资源配额及其用量
+func (m *Manager) QuotaUsage(client *ssh.Client) ([]model.NamespaceQuota, error) {
+	result, err := runKubectlIdempotent(client, "kubectl get resourcequota -A --field-selector metadata.name=insuite-quota -o json")
+	if err != nil {
+		return nil, fmt.Errorf("读取资源配额失败: %v", err)
+	}
+	return parseQuotaUsage(result.Stdout)
+}
+
+// namespaceInstance 返回命名空间所属的实例名，命名空间不存在或没有实例标签时返回空字符串
+func (m *Manager) namespaceInstance(client *ssh.Client, namespace string) (string, error) {
+	result, err := runKubectlIdempotent(client, fmt.Sprintf(
+		"kubectl get namespace %s --ignore-not-found -o jsonpath='{.metadata.labels.insuite\\.instance}'", namespace))
+	if err != nil {
+		return "", fmt.Errorf("查询命名空间 %s 失败: %v", namespace, err)
+	}
+	return strings.TrimSpace(result.Stdout), nil
+}
+
+// DeleteInstance 删除实例的命名空间及其中全部资源。命名空间必须带有该实例的标签，
+// 避免误删不是由本服务部署的命名空间；命名空间已不存在时视为成功
+func (m *Manager) DeleteInstance(client *ssh.Client, instance, namespace string, policy WaitPolicy) error {
+	policy = policy.WithDefaults()
+
+	result, err := runKubectlIdempotent(client, "kubectl get namespace "+namespace+" --ignore-not-found -o name")
+	if err != nil {
+		return fmt.Errorf("查询命名空间 %s 失败: %v", namespace, err)
+	}
+	if strings.TrimSpace(result.Stdout) == "" {
+		m.logger.Infof("命名空间 %s 不存在，无需删除", namespace)
+		return nil
+	}
+
+	owner, err := m.namespaceInstance(client, namespace)
+	if err != nil {
+		return err
+	}
+	if owner != instance {
+		return fmt.Errorf("命名空间 %s 不属于实例 %s（实例标签为 %q），拒绝删除", namespace, instance, owner)
+	}
+
+	cmd := fmt.Sprintf("kubectl delete namespace %s --wait --timeout=%ds", namespace, int(policy.DeploymentTimeout.Seconds()))
+	if _, err := runKubectl(client, cmd); err != nil {
+		return fmt.Errorf("删除命名空间 %s 失败: %v", namespace, err)
+	}
+	m.logger.Infof("实例 %s 已删除（命名空间 %s）", instance, namespace)
+	return nil
+}
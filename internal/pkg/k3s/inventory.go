@@ -0,0 +1,147 @@
+package k3s
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// workloadList kubectl get deployments,statefulsets,daemonsets -o json 输出中用到的字段
+type workloadList struct {
+	Items []struct {
+		Kind     string `json:"kind"`
+		Metadata struct {
+			Name              string    `json:"name"`
+			Namespace         string    `json:"namespace"`
+			CreationTimestamp time.Time `json:"creationTimestamp"`
+		} `json:"metadata"`
+		Spec struct {
+			Replicas *int `json:"replicas"`
+			Selector struct {
+				MatchLabels map[string]string `json:"matchLabels"`
+			} `json:"selector"`
+			Template struct {
+				Spec struct {
+					Containers []struct {
+						Image     string `json:"image"`
+						Resources struct {
+							Requests map[string]string `json:"requests"`
+						} `json:"resources"`
+					} `json:"containers"`
+				} `json:"spec"`
+			} `json:"template"`
+		} `json:"spec"`
+		Status struct {
+			ReadyReplicas     int `json:"readyReplicas"`
+			UpdatedReplicas   int `json:"updatedReplicas"`
+			AvailableReplicas int `json:"availableReplicas"`
+			// DaemonSet 使用的状态字段
+			DesiredNumberScheduled int `json:"desiredNumberScheduled"`
+			NumberReady            int `json:"numberReady"`
+			UpdatedNumberScheduled int `json:"updatedNumberScheduled"`
+			NumberAvailable        int `json:"numberAvailable"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// podList kubectl get pods -o json 输出中用于确定工作负载所在节点及用量的字段
+type podList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string            `json:"name"`
+			Namespace string            `json:"namespace"`
+			Labels    map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Spec struct {
+			NodeName string `json:"nodeName"`
+		} `json:"spec"`
+		Status struct {
+			Phase string `json:"phase"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// ListWorkloads 列出全部命名空间的 Deployment、StatefulSet 和 DaemonSet，
+// 并按 selector 匹配运行中的 Pod 确定所在节点
+func (m *Manager) ListWorkloads(client *ssh.Client) ([]model.Workload, error) {
+	result, err := runKubectlIdempotent(client, "kubectl get deployments,statefulsets,daemonsets -A -o json")
+	if err != nil {
+		return nil, fmt.Errorf("读取工作负载失败: %v", err)
+	}
+	var workloads workloadList
+	if err := json.Unmarshal([]byte(result.Stdout), &workloads); err != nil {
+		return nil, fmt.Errorf("解析工作负载失败: %v", err)
+	}
+
+	result, err = runKubectlIdempotent(client, "kubectl get pods -A --field-selector=status.phase=Running -o json")
+	if err != nil {
+		return nil, fmt.Errorf("读取 Pod 列表失败: %v", err)
+	}
+	var pods podList
+	if err := json.Unmarshal([]byte(result.Stdout), &pods); err != nil {
+		return nil, fmt.Errorf("解析 Pod 列表失败: %v", err)
+	}
+
+	inventory := make([]model.Workload, 0, len(workloads.Items))
+	for _, item := range workloads.Items {
+		w := model.Workload{
+			Namespace:         item.Metadata.Namespace,
+			Kind:              item.Kind,
+			Name:              item.Metadata.Name,
+			Images:            []string{},
+			ReadyReplicas:     item.Status.ReadyReplicas,
+			UpdatedReplicas:   item.Status.UpdatedReplicas,
+			AvailableReplicas: item.Status.AvailableReplicas,
+			CreatedAt:         item.Metadata.CreationTimestamp,
+		}
+		switch {
+		case item.Kind == model.WorkloadDaemonSet:
+			w.Replicas = item.Status.DesiredNumberScheduled
+			w.ReadyReplicas = item.Status.NumberReady
+			w.UpdatedReplicas = item.Status.UpdatedNumberScheduled
+			w.AvailableReplicas = item.Status.NumberAvailable
+		case item.Spec.Replicas != nil:
+			w.Replicas = *item.Spec.Replicas
+		default:
+			// 未设置 replicas 时 Kubernetes 默认为 1
+			w.Replicas = 1
+		}
+		w.Ready = w.ReadyReplicas >= w.Replicas
+		for _, c := range item.Spec.Template.Spec.Containers {
+			w.Images = append(w.Images, c.Image)
+		}
+
+		nodes := make(map[string]bool)
+		for _, pod := range pods.Items {
+			if pod.Metadata.Namespace == w.Namespace && pod.Spec.NodeName != "" &&
+				matchLabels(item.Spec.Selector.MatchLabels, pod.Metadata.Labels) {
+				nodes[pod.Spec.NodeName] = true
+			}
+		}
+		w.Nodes = make([]string, 0, len(nodes))
+		for node := range nodes {
+			w.Nodes = append(w.Nodes, node)
+		}
+		sort.Strings(w.Nodes)
+
+		inventory = append(inventory, w)
+	}
+	return inventory, nil
+}
+
+// matchLabels selector 为空时不匹配任何 Pod，避免把命名空间内全部 Pod 算到一个工作负载上
+func matchLabels(selector, labels map[string]string) bool {
+	if len(selector) == 0 {
+		return false
+	}
+	for k, v := range selector {
+		if labels[k] != v {
+			return false
+		}
+	}
+	return true
+}
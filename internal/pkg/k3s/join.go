@@ -0,0 +1,87 @@
+package k3s
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// bootstrapTokenPattern k3s token create 输出的 token：可带 K10<CA 摘要>:: 前缀的 <id>.<secret> 引导 token
+var bootstrapTokenPattern = regexp.MustCompile(`^(K10[0-9a-f]{64}::)?[a-z0-9]{6}\.[a-z0-9]{16}$`)
+
+// agentArgPrefixes Server 启动参数中同样适用于 Agent 的参数，加入节点时建议保持一致
+var agentArgPrefixes = []string{
+	"--docker",
+	"--container-runtime-endpoint",
+	"--resolv-conf",
+	"--protect-kernel-defaults",
+	"--kubelet-arg",
+	"--kube-proxy-arg",
+	"--selinux",
+	"--snapshotter",
+	"--pause-image",
+	"--prefer-bundled-bin",
+}
+
+// AgentArgs 从 Server 的启动参数中筛选加入的 Agent 也应使用的参数
+func AgentArgs(serverArgs []string) []string {
+	var args []string
+	for _, arg := range serverArgs {
+		name, _, _ := strings.Cut(arg, "=")
+		for _, prefix := range agentArgPrefixes {
+			if name == prefix {
+				args = append(args, arg)
+				break
+			}
+		}
+	}
+	return args
+}
+
+// TokenCAHash 返回 k3s 安全格式 token（K10<CA 摘要>::<凭据>）中的集群 CA 摘要，旧格式 token 返回空字符串
+func TokenCAHash(token string) string {
+	hash, _, ok := strings.Cut(strings.TrimPrefix(token, "K10"), "::")
+	if !ok || !strings.HasPrefix(token, "K10") || len(hash) != sha256.Size*2 {
+		return ""
+	}
+	return hash
+}
+
+// KubeconfigCAHash 返回 kubeconfig 中集群 CA 的 SHA256（十六进制），与 k3s 对 server /cacerts 内容取摘要的结果相同
+func KubeconfigCAHash(content string) (string, error) {
+	config, err := parseKubeconfig(content)
+	if err != nil {
+		return "", err
+	}
+	caData, _ := config.Clusters[0].Cluster["certificate-authority-data"].(string)
+	raw, err := base64.StdEncoding.DecodeString(caData)
+	if err != nil || len(raw) == 0 {
+		return "", fmt.Errorf("kubeconfig 集群 CA 无效")
+	}
+	sum := sha256.Sum256(raw)
+	return hex.EncodeToString(sum[:]), nil
+}
+
+// CreateJoinToken 在 server 上创建 ttl 后过期的引导 token，供加入信息包使用，避免导出永久的 server token。
+// caHash 非空且输出不带 CA 摘要时补全为安全格式
+func (m *Manager) CreateJoinToken(client *ssh.Client, ttl time.Duration, caHash string) (string, error) {
+	result, err := client.ExecuteCommand(fmt.Sprintf("k3s token create --ttl %s --description 'k3s-deploy join bundle'", ttl))
+	if err != nil {
+		return "", fmt.Errorf("创建引导 token 失败: %v", err)
+	}
+	lines := strings.Fields(result.Stdout)
+	if len(lines) == 0 || !bootstrapTokenPattern.MatchString(lines[len(lines)-1]) {
+		return "", fmt.Errorf("k3s token create 输出无法识别")
+	}
+	token := lines[len(lines)-1]
+	if caHash != "" && TokenCAHash(token) == "" {
+		token = "K10" + caHash + "::" + token
+	}
+	return token, nil
+}
@@ -0,0 +1,108 @@
+package k3s
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// auditTailLines 每次最多读取审计日志末尾的行数，收集周期内写入更多记录时较早的记录会被遗漏
+const auditTailLines = 5000
+
+// ErrAuditLogMissing Master 节点上没有审计日志，集群安装时未设置 security.audit
+var ErrAuditLogMissing = errors.New("Master 节点上没有 API Server 审计日志（安装时未设置 security.audit）")
+
+// auditCursorPattern 审计日志中的 stageTimestamp，作为 awk 参数前校验
+var auditCursorPattern = regexp.MustCompile(`^[0-9T:.Z+-]+$`)
+
+// auditEvent audit.k8s.io/v1 Event 中需要的字段
+type auditEvent struct {
+	AuditID    string `json:"auditID"`
+	Stage      string `json:"stage"`
+	Level      string `json:"level"`
+	RequestURI string `json:"requestURI"`
+	Verb       string `json:"verb"`
+	User       struct {
+		Username string   `json:"username"`
+		Groups   []string `json:"groups"`
+	} `json:"user"`
+	SourceIPs []string `json:"sourceIPs"`
+	UserAgent string   `json:"userAgent"`
+	ObjectRef *struct {
+		Resource    string `json:"resource"`
+		Namespace   string `json:"namespace"`
+		Name        string `json:"name"`
+		Subresource string `json:"subresource"`
+	} `json:"objectRef"`
+	ResponseStatus *struct {
+		Code int `json:"code"`
+	} `json:"responseStatus"`
+	StageTimestamp string            `json:"stageTimestamp"`
+	Annotations    map[string]string `json:"annotations"`
+}
+
+// ReadAuditLog 读取 Master 审计日志末尾时间不早于 since 的记录（since 为空时读取全部），
+// 返回记录和其中最新的时间。时间格式固定为微秒精度的 UTC，可以按字符串比较，在节点上用 awk 过滤，
+// 只传回新记录；请求与响应体不保存
+func (m *Manager) ReadAuditLog(client *ssh.Client, since string) ([]model.KubeAuditEntry, string, error) {
+	if since != "" && !auditCursorPattern.MatchString(since) {
+		return nil, "", fmt.Errorf("无效的审计日志游标: %q", since)
+	}
+	if _, err := client.ExecuteIdempotentCommand("test -f " + AuditLogPath); err != nil {
+		return nil, "", ErrAuditLogMissing
+	}
+
+	cmd := fmt.Sprintf(`tail -n %d %s | awk -v since='%s' 'match($0, /"stageTimestamp":"[^"]*"/) { ts = substr($0, RSTART + 18, RLENGTH - 19); if (ts >= since) print }'`,
+		auditTailLines, AuditLogPath, since)
+	result, err := client.ExecuteIdempotentCommand(cmd)
+	if err != nil {
+		return nil, "", fmt.Errorf("读取审计日志失败: %v", err)
+	}
+
+	cursor := since
+	var entries []model.KubeAuditEntry
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		var event auditEvent
+		// 日志正在写入时最后一行可能不完整
+		if err := json.Unmarshal([]byte(line), &event); err != nil {
+			continue
+		}
+		stageTime, err := time.Parse(time.RFC3339Nano, event.StageTimestamp)
+		if err != nil {
+			continue
+		}
+		entry := model.KubeAuditEntry{
+			AuditID:    event.AuditID,
+			Stage:      event.Stage,
+			Level:      event.Level,
+			Time:       stageTime,
+			User:       event.User.Username,
+			Groups:     event.User.Groups,
+			SourceIPs:  event.SourceIPs,
+			UserAgent:  event.UserAgent,
+			Verb:       event.Verb,
+			RequestURI: event.RequestURI,
+			Decision:   event.Annotations["authorization.k8s.io/decision"],
+		}
+		if ref := event.ObjectRef; ref != nil {
+			entry.Namespace, entry.Resource, entry.Subresource, entry.Name = ref.Namespace, ref.Resource, ref.Subresource, ref.Name
+		}
+		if event.ResponseStatus != nil {
+			entry.Code = event.ResponseStatus.Code
+		}
+		entries = append(entries, entry)
+		if event.StageTimestamp > cursor {
+			cursor = event.StageTimestamp
+		}
+	}
+	return entries, cursor, nil
+}
@@ -0,0 +1,248 @@
+package k3s
+
+import (
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"fmt"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// KubeconfigPath k3s server 生成的管理员 kubeconfig
+const KubeconfigPath = "/etc/rancher/k3s/k3s.yaml"
+
+// FetchKubeconfig 读取 server 节点的管理员 kubeconfig，并将其中的本地地址替换为 serverIP 以便从外部访问
+func (m *Manager) FetchKubeconfig(client *ssh.Client, serverIP string) (string, error) {
+	result, err := client.ExecuteIdempotentCommand("cat " + KubeconfigPath)
+	if err != nil {
+		return "", fmt.Errorf("读取 kubeconfig 失败: %v", err)
+	}
+	if _, err := parseKubeconfig(result.Stdout); err != nil {
+		return "", err
+	}
+	return strings.ReplaceAll(result.Stdout, "https://127.0.0.1:6443", fmt.Sprintf("https://%s:6443", serverIP)), nil
+}
+
+// EnsureAccess 检查保存的 kubeconfig 和 join token，失效或未保存时从 server 重新读取。
+// 返回有效的 kubeconfig、token，以及是否重新读取
+func (m *Manager) EnsureAccess(client *ssh.Client, serverIP, content, token string) (string, string, bool, error) {
+	if content != "" {
+		err := m.CheckAccess(client, content, token)
+		if err == nil {
+			return content, token, false, nil
+		}
+		m.logger.Warnf("保存的集群访问凭据已失效（%v），重新读取", err)
+	}
+
+	content, err := m.FetchKubeconfig(client, serverIP)
+	if err != nil {
+		return "", "", false, err
+	}
+	token, err = m.GetNodeToken(client)
+	if err != nil {
+		return "", "", false, err
+	}
+	return content, token, true, nil
+}
+
+// kubeconfig 合并时只重命名条目，其余字段原样保留
+type kubeconfig struct {
+	APIVersion     string         `yaml:"apiVersion"`
+	Kind           string         `yaml:"kind"`
+	Clusters       []namedEntry   `yaml:"clusters"`
+	Users          []namedEntry   `yaml:"users"`
+	Contexts       []namedContext `yaml:"contexts"`
+	CurrentContext string         `yaml:"current-context"`
+}
+
+type namedEntry struct {
+	Name    string                 `yaml:"name"`
+	Cluster map[string]interface{} `yaml:"cluster,omitempty"`
+	User    map[string]interface{} `yaml:"user,omitempty"`
+}
+
+type namedContext struct {
+	Name    string `yaml:"name"`
+	Context struct {
+		Cluster   string `yaml:"cluster"`
+		User      string `yaml:"user"`
+		Namespace string `yaml:"namespace,omitempty"`
+	} `yaml:"context"`
+}
+
+func parseKubeconfig(content string) (*kubeconfig, error) {
+	var config kubeconfig
+	if err := yaml.Unmarshal([]byte(content), &config); err != nil {
+		return nil, fmt.Errorf("解析 kubeconfig 失败: %v", err)
+	}
+	if len(config.Clusters) == 0 || len(config.Contexts) == 0 {
+		return nil, fmt.Errorf("kubeconfig 中没有集群或上下文")
+	}
+	return &config, nil
+}
+
+// NamedKubeconfig 待合并的 kubeconfig，Name 作为条目名称前缀
+type NamedKubeconfig struct {
+	Name    string
+	Content string
+}
+
+// MergeKubeconfigs 合并多个 kubeconfig。k3s 生成的条目都叫 default，
+// 合并后集群、用户和上下文改名为 <Name>-<原名称>（原名称为 default 时直接使用 Name），当前上下文为第一个集群的当前上下文
+func MergeKubeconfigs(configs []NamedKubeconfig) ([]byte, error) {
+	merged := kubeconfig{APIVersion: "v1", Kind: "Config"}
+	for _, named := range configs {
+		config, err := parseKubeconfig(named.Content)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %v", named.Name, err)
+		}
+		rename := func(name string) string {
+			if name == "default" {
+				return named.Name
+			}
+			return named.Name + "-" + name
+		}
+
+		for _, entry := range config.Clusters {
+			entry.Name = rename(entry.Name)
+			merged.Clusters = append(merged.Clusters, entry)
+		}
+		for _, entry := range config.Users {
+			entry.Name = rename(entry.Name)
+			merged.Users = append(merged.Users, entry)
+		}
+		for _, ctx := range config.Contexts {
+			current := ctx.Name == config.CurrentContext
+			ctx.Name = rename(ctx.Name)
+			ctx.Context.Cluster = rename(ctx.Context.Cluster)
+			ctx.Context.User = rename(ctx.Context.User)
+			merged.Contexts = append(merged.Contexts, ctx)
+			if current && merged.CurrentContext == "" {
+				merged.CurrentContext = ctx.Name
+			}
+		}
+	}
+	if len(merged.Contexts) == 0 {
+		return nil, fmt.Errorf("没有可合并的 kubeconfig")
+	}
+	if merged.CurrentContext == "" {
+		merged.CurrentContext = merged.Contexts[0].Name
+	}
+	return yaml.Marshal(&merged)
+}
+
+// k3s server 当前使用的 CA 证书
+const (
+	serverCAPath = "/var/lib/rancher/k3s/server/tls/server-ca.crt"
+	clientCAPath = "/var/lib/rancher/k3s/server/tls/client-ca.crt"
+)
+
+// CheckAccess 检查保存的 kubeconfig 和 join token 是否仍然有效：集群 CA 与 server 当前的 CA 一致，
+// 客户端证书未过期且由当前 client CA 签发，token 与 server 上的一致。手动轮换证书或重新生成 token 后返回错误
+func (m *Manager) CheckAccess(client *ssh.Client, content, token string) error {
+	config, err := parseKubeconfig(content)
+	if err != nil {
+		return err
+	}
+	caData, _ := config.Clusters[0].Cluster["certificate-authority-data"].(string)
+	certData := ""
+	if len(config.Users) > 0 {
+		certData, _ = config.Users[0].User["client-certificate-data"].(string)
+	}
+	savedCA, err := decodeCertData(caData)
+	if err != nil {
+		return fmt.Errorf("kubeconfig 集群 CA 无效: %v", err)
+	}
+	chain, err := decodeCertData(certData)
+	if err != nil {
+		return fmt.Errorf("kubeconfig 客户端证书无效: %v", err)
+	}
+
+	serverCA, err := m.readCerts(client, serverCAPath)
+	if err != nil {
+		return err
+	}
+	if !savedCA[0].Equal(serverCA[0]) {
+		return fmt.Errorf("集群 CA 已变更")
+	}
+
+	if time.Now().After(chain[0].NotAfter) {
+		return fmt.Errorf("客户端证书已于 %s 过期", chain[0].NotAfter.Format(time.RFC3339))
+	}
+	clientCA, err := m.readCerts(client, clientCAPath)
+	if err != nil {
+		return err
+	}
+	roots, intermediates := x509.NewCertPool(), x509.NewCertPool()
+	for _, cert := range clientCA {
+		roots.AddCert(cert)
+	}
+	for _, cert := range chain[1:] {
+		intermediates.AddCert(cert)
+	}
+	if _, err := chain[0].Verify(x509.VerifyOptions{
+		Roots:         roots,
+		Intermediates: intermediates,
+		KeyUsages:     []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth},
+	}); err != nil {
+		return fmt.Errorf("客户端证书不是由当前 client CA 签发: %v", err)
+	}
+
+	current, err := m.GetNodeToken(client)
+	if err != nil {
+		return err
+	}
+	if token != current {
+		return fmt.Errorf("join token 已变更")
+	}
+	return nil
+}
+
+func (m *Manager) readCerts(client *ssh.Client, path string) ([]*x509.Certificate, error) {
+	result, err := client.ExecuteIdempotentCommand("cat " + path)
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %v", path, err)
+	}
+	certs, err := parseCerts([]byte(result.Stdout))
+	if err != nil {
+		return nil, fmt.Errorf("%s: %v", path, err)
+	}
+	return certs, nil
+}
+
+// decodeCertData 解析 kubeconfig 中 base64 编码的 PEM 证书
+func decodeCertData(data string) ([]*x509.Certificate, error) {
+	raw, err := base64.StdEncoding.DecodeString(data)
+	if err != nil {
+		return nil, err
+	}
+	return parseCerts(raw)
+}
+
+func parseCerts(data []byte) ([]*x509.Certificate, error) {
+	var certs []*x509.Certificate
+	for {
+		var block *pem.Block
+		block, data = pem.Decode(data)
+		if block == nil {
+			break
+		}
+		if block.Type != "CERTIFICATE" {
+			continue
+		}
+		cert, err := x509.ParseCertificate(block.Bytes)
+		if err != nil {
+			return nil, err
+		}
+		certs = append(certs, cert)
+	}
+	if len(certs) == 0 {
+		return nil, fmt.Errorf("没有找到证书")
+	}
+	return certs, nil
+}
@@ -0,0 +1,105 @@
+package k3s
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// Kubectl Master 节点上 kubectl 的调用方式。部分加固过的主机 root 的 PATH 中没有 kubectl，
+// 或设置了其他 KUBECONFIG，因此所有 kubectl 命令都使用绝对路径和 --kubeconfig 调用，不依赖 shell 的默认值
+type Kubectl struct {
+	// Binary kubectl 的绝对路径；节点上没有 kubectl 时为 k3s 二进制，以 k3s kubectl 调用
+	Binary string
+	// Kubeconfig 管理员 kubeconfig 的路径，k3s 配置了 write-kubeconfig 时为该路径
+	Kubeconfig string
+}
+
+// Command 命令行中替换 kubectl 的调用方式，路径经过引用
+func (k Kubectl) Command() string {
+	if path.Base(k.Binary) == "k3s" {
+		return fmt.Sprintf("%s kubectl --kubeconfig %s", ssh.Quote(k.Binary), ssh.Quote(k.Kubeconfig))
+	}
+	return fmt.Sprintf("%s --kubeconfig %s", ssh.Quote(k.Binary), ssh.Quote(k.Kubeconfig))
+}
+
+// detectKubectlScript 优先使用 k3s 二进制所在目录中的 kubectl（与 server 版本一致），
+// 其次是 PATH 中的 kubectl，都没有时使用 k3s kubectl；kubeconfig 取 config.yaml 中的 write-kubeconfig
+const detectKubectlScript = `k=$(command -v k3s 2>/dev/null)
+for d in /usr/local/bin /opt/bin /usr/bin; do [ -n "$k" ] && break; [ -x $d/k3s ] && k=$d/k3s; done
+[ -n "$k" ] || { echo "未找到 k3s 二进制" >&2; exit 1; }
+b=$(dirname "$k")/kubectl
+[ -x "$b" ] || b=$(command -v kubectl 2>/dev/null)
+[ -n "$b" ] || b=$k
+c=$(sed -n 's/^write-kubeconfig:[[:space:]]*//p' /etc/rancher/k3s/config.yaml 2>/dev/null | tr -d "\"'" | head -n 1)
+c=${c:-` + KubeconfigPath + `}
+[ -r "$c" ] || { echo "kubeconfig $c 不存在或不可读" >&2; exit 1; }
+echo "$b"
+echo "$c"`
+
+// kubectlMemoKey kubectl 检测结果在 ssh.Client 上的缓存键
+const kubectlMemoKey = "k3s.kubectl"
+
+// kubectlToken 命令中处于命令位置的 kubectl：行首、管道、&&、||、;、$( 和 do 之后
+var kubectlToken = regexp.MustCompile(`(?m)(^|[|;&(]\s*|\bdo\s+)kubectl(\s)`)
+
+// DetectKubectl 重新检测 server 节点上 kubectl 的路径和管理员 kubeconfig，替换该连接上缓存的结果。
+// 安装 Master 后调用，已有集群在连接上第一次执行 kubectl 命令时检测
+func DetectKubectl(client *ssh.Client) (Kubectl, error) {
+	client.Forget(kubectlMemoKey)
+	return kubectlFor(client)
+}
+
+// kubectlFor 返回节点上 kubectl 的调用方式，每个连接只检测一次，检测失败的结果同样缓存
+func kubectlFor(client *ssh.Client) (Kubectl, error) {
+	value, err := client.Memo(kubectlMemoKey, func() (any, error) {
+		return detectKubectl(client)
+	})
+	if err != nil {
+		return Kubectl{}, err
+	}
+	return value.(Kubectl), nil
+}
+
+func detectKubectl(client *ssh.Client) (Kubectl, error) {
+	result, err := client.ExecuteIdempotentCommand(detectKubectlScript)
+	if err != nil {
+		return Kubectl{}, fmt.Errorf("检测 kubectl 失败: %v", err)
+	}
+	lines := strings.Split(strings.TrimSpace(result.Stdout), "\n")
+	if len(lines) != 2 || !strings.HasPrefix(lines[0], "/") || !strings.HasPrefix(lines[1], "/") {
+		return Kubectl{}, fmt.Errorf("无法解析 kubectl 检测结果: %q", result.Stdout)
+	}
+	return Kubectl{Binary: strings.TrimSpace(lines[0]), Kubeconfig: strings.TrimSpace(lines[1])}, nil
+}
+
+// kubectlCommand 将命令中的 kubectl 替换为节点上 kubectl 的绝对路径和 --kubeconfig
+func kubectlCommand(client *ssh.Client, cmd string) (string, error) {
+	kubectl, err := kubectlFor(client)
+	if err != nil {
+		return "", err
+	}
+	invocation := strings.ReplaceAll(kubectl.Command(), "$", "$$")
+	return kubectlToken.ReplaceAllString(cmd, "${1}"+invocation+"${2}"), nil
+}
+
+// runKubectl 执行包含 kubectl 的命令
+func runKubectl(client *ssh.Client, cmd string) (*ssh.CommandResult, error) {
+	cmd, err := kubectlCommand(client, cmd)
+	if err != nil {
+		return nil, err
+	}
+	return client.ExecuteCommand(cmd)
+}
+
+// runKubectlIdempotent 执行包含 kubectl 的只读命令，连接中断时重连重试
+func runKubectlIdempotent(client *ssh.Client, cmd string) (*ssh.CommandResult, error) {
+	cmd, err := kubectlCommand(client, cmd)
+	if err != nil {
+		return nil, err
+	}
+	return client.ExecuteIdempotentCommand(cmd)
+}
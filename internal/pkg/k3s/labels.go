@@ -0,0 +1,188 @@
+package k3s
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// ManagedLabelPrefix 由部署工具维护的标签前缀，对账时不在期望状态中的此类标签会被移除
+const ManagedLabelPrefix = "insuite."
+
+var (
+	// labelPattern key=value，key 可带 DNS 前缀（如 insuite.app、node.example.com/zone）
+	labelPattern = regexp.MustCompile(`^([a-z0-9]([-a-z0-9.]*[a-z0-9])?/)?[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?=([A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)?$`)
+	// taintPattern key=value:Effect 或 key:Effect
+	taintPattern = regexp.MustCompile(`^([a-z0-9]([-a-z0-9.]*[a-z0-9])?/)?[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?(=[A-Za-z0-9]([-A-Za-z0-9_.]*[A-Za-z0-9])?)?:(NoSchedule|PreferNoSchedule|NoExecute)$`)
+)
+
+// ValidateLabel 检查标签格式 key=value
+func ValidateLabel(label string) error {
+	if !labelPattern.MatchString(label) {
+		return fmt.Errorf("无效的标签 %q（格式 key=value）", label)
+	}
+	return nil
+}
+
+// ValidateTaint 检查污点格式 key=value:Effect，Effect 为 NoSchedule、PreferNoSchedule 或 NoExecute
+func ValidateTaint(taint string) error {
+	if !taintPattern.MatchString(taint) {
+		return fmt.Errorf("无效的污点 %q（格式 key=value:Effect）", taint)
+	}
+	return nil
+}
+
+// ValidateNodeLabels 检查节点名 -> 标签、节点名 -> 污点列表中的节点名、标签和污点格式
+func ValidateNodeLabels(labels, taints map[string][]string) error {
+	for _, node := range sortedKeys(labels) {
+		if !nodeNamePattern.MatchString(node) {
+			return fmt.Errorf("无效的节点名: %q", node)
+		}
+		for _, label := range labels[node] {
+			if err := ValidateLabel(label); err != nil {
+				return fmt.Errorf("节点 %s: %v", node, err)
+			}
+		}
+	}
+	for _, node := range sortedKeys(taints) {
+		if !nodeNamePattern.MatchString(node) {
+			return fmt.Errorf("无效的节点名: %q", node)
+		}
+		for _, taint := range taints[node] {
+			if err := ValidateTaint(taint); err != nil {
+				return fmt.Errorf("节点 %s: %v", node, err)
+			}
+		}
+	}
+	return nil
+}
+
+// taintIdentity 污点以 key 和 Effect 区分，kubectl taint 删除时使用 key:Effect-
+func taintIdentity(taint string) string {
+	keyValue, effect, _ := strings.Cut(taint, ":")
+	key, _, _ := strings.Cut(keyValue, "=")
+	return key + ":" + effect
+}
+
+// ReconcileNodeLabels 将 desired 中列出节点的标签和污点调整为期望值：补齐缺失项、更新取值不同的项，
+// 并移除由工具维护（带 insuite. 前缀或记录在 previous 中）但不再期望的项。未列出的节点和其他标签不受影响。
+// dryRun 为 true 时只返回变更计划
+func (m *Manager) ReconcileNodeLabels(client *ssh.Client, desired, previous *model.DesiredState, dryRun bool) ([]model.LabelChange, error) {
+	nodes, err := m.ListNodes(client)
+	if err != nil {
+		return nil, err
+	}
+	actual := make(map[string]model.ClusterNode, len(nodes))
+	for _, node := range nodes {
+		actual[node.Name] = node
+	}
+	for _, name := range append(sortedKeys(desired.Labels), sortedKeys(desired.Taints)...) {
+		if _, ok := actual[name]; !ok {
+			return nil, fmt.Errorf("节点 %s 不存在", name)
+		}
+	}
+
+	changes := []model.LabelChange{}
+	for _, name := range sortedKeys(desired.Labels) {
+		changes = append(changes, labelChanges(name, actual[name].Labels, desired.Labels[name], previous.Labels[name])...)
+	}
+	for _, name := range sortedKeys(desired.Taints) {
+		changes = append(changes, taintChanges(name, actual[name].Taints, desired.Taints[name], previous.Taints[name])...)
+	}
+	if dryRun {
+		return changes, nil
+	}
+
+	for i := range changes {
+		change := &changes[i]
+		if _, err := runKubectl(client, changeCommand(*change)); err != nil {
+			change.Message = fmt.Sprintf("执行失败: %v", err)
+			m.logger.Errorf("节点 %s %s %s 失败: %v", change.Node, change.Action, change.Value, err)
+			continue
+		}
+		change.Applied = true
+		m.logger.Infof("节点 %s %s %s %s", change.Node, change.Kind, change.Action, change.Value)
+	}
+	return changes, nil
+}
+
+// changeCommand 执行变更的 kubectl 命令，删除时标签使用 key-，污点使用 key:Effect-
+func changeCommand(c model.LabelChange) string {
+	verb := "label"
+	if c.Kind == model.DriftTaint {
+		verb = "taint"
+	}
+	if c.Action != model.LabelRemove {
+		return fmt.Sprintf("kubectl %s nodes %s %s --overwrite", verb, ssh.Quote(c.Node), ssh.Quote(c.Value))
+	}
+	target, _, _ := strings.Cut(c.Value, "=")
+	if c.Kind == model.DriftTaint {
+		target = taintIdentity(c.Value)
+	}
+	return fmt.Sprintf("kubectl %s nodes %s %s", verb, ssh.Quote(c.Node), ssh.Quote(target+"-"))
+}
+
+func labelChanges(node string, current map[string]string, desired, previous []string) []model.LabelChange {
+	var changes []model.LabelChange
+	wanted := make(map[string]bool, len(desired))
+	for _, label := range desired {
+		key, value, _ := strings.Cut(label, "=")
+		wanted[key] = true
+		existing, found := current[key]
+		switch {
+		case !found:
+			changes = append(changes, model.LabelChange{Node: node, Kind: model.DriftLabel, Action: model.LabelAdd, Value: label})
+		case existing != value:
+			changes = append(changes, model.LabelChange{Node: node, Kind: model.DriftLabel, Action: model.LabelUpdate, Value: label, Previous: key + "=" + existing})
+		}
+	}
+
+	managed := make(map[string]bool)
+	for _, label := range previous {
+		key, _, _ := strings.Cut(label, "=")
+		managed[key] = true
+	}
+	for _, key := range sortedKeys(current) {
+		if wanted[key] || !(managed[key] || strings.HasPrefix(key, ManagedLabelPrefix)) {
+			continue
+		}
+		changes = append(changes, model.LabelChange{Node: node, Kind: model.DriftLabel, Action: model.LabelRemove, Value: key + "=" + current[key]})
+	}
+	return changes
+}
+
+func taintChanges(node string, current, desired, previous []string) []model.LabelChange {
+	var changes []model.LabelChange
+	existing := make(map[string]string, len(current))
+	for _, taint := range current {
+		existing[taintIdentity(taint)] = taint
+	}
+
+	wanted := make(map[string]bool, len(desired))
+	for _, taint := range desired {
+		id := taintIdentity(taint)
+		wanted[id] = true
+		switch old, found := existing[id]; {
+		case !found:
+			changes = append(changes, model.LabelChange{Node: node, Kind: model.DriftTaint, Action: model.LabelAdd, Value: taint})
+		case old != taint:
+			changes = append(changes, model.LabelChange{Node: node, Kind: model.DriftTaint, Action: model.LabelUpdate, Value: taint, Previous: old})
+		}
+	}
+
+	managed := make(map[string]bool)
+	for _, taint := range previous {
+		managed[taintIdentity(taint)] = true
+	}
+	for _, taint := range current {
+		id := taintIdentity(taint)
+		if wanted[id] || !(managed[id] || strings.HasPrefix(id, ManagedLabelPrefix)) {
+			continue
+		}
+		changes = append(changes, model.LabelChange{Node: node, Kind: model.DriftTaint, Action: model.LabelRemove, Value: taint})
+	}
+	return changes
+}
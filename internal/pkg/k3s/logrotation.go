@@ -0,0 +1,140 @@
+package k3s
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// 日志轮转配置写入独立的 drop-in 文件。k3s 合并 config.yaml.d 下的文件，kubelet-arg+ 追加而不覆盖其他文件中的参数
+const (
+	JournaldDropInPath   = "/etc/systemd/journald.conf.d/90-k3s-deploy.conf"
+	KubeletLogDropInPath = "/etc/rancher/k3s/config.yaml.d/90-k3s-deploy-logs.yaml"
+)
+
+func journaldConfig(opts *model.LogRotationOptions) string {
+	return fmt.Sprintf("[Journal]\nSystemMaxUse=%s\n", opts.JournaldMaxUse)
+}
+
+func kubeletLogConfig(opts *model.LogRotationOptions) string {
+	return fmt.Sprintf("kubelet-arg+:\n  - container-log-max-size=%s\n  - container-log-max-files=%d\n", opts.ContainerLogMaxSize, opts.ContainerLogMaxFiles)
+}
+
+// writeIfChangedCommand 内容变化时写入文件并输出 changed
+func writeIfChangedCommand(path, content string) string {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	return fmt.Sprintf(`mkdir -p $(dirname %[1]s) && printf '%%s\n' '%[2]s' > %[1]s.tmp && if cmp -s %[1]s.tmp %[1]s; then rm -f %[1]s.tmp; else mv -f %[1]s.tmp %[1]s && echo changed; fi`,
+		path, strings.Join(lines, "' '"))
+}
+
+// ConfigureLogRotation 设置 journald 占用上限（仅 systemd 节点）和 kubelet 容器日志轮转。
+// kubelet 参数在 k3s 启动时读取，节点已安装 k3s 且配置有变化时重启 k3s 服务
+func (m *Manager) ConfigureLogRotation(client *ssh.Client, nodeName string, osInfo *hostos.Info, opts *model.LogRotationOptions) error {
+	if osInfo.InitSystem == hostos.InitSystemd {
+		result, err := client.ExecuteCommand(writeIfChangedCommand(JournaldDropInPath, journaldConfig(opts)))
+		if err != nil {
+			return fmt.Errorf("写入 journald 配置失败: %v", err)
+		}
+		if strings.Contains(result.Stdout, "changed") {
+			if _, err := client.ExecuteCommand("systemctl restart systemd-journald"); err != nil {
+				return fmt.Errorf("重启 systemd-journald 失败: %v", err)
+			}
+		}
+		m.logger.Infof("节点 %s journald 占用上限: %s", nodeName, opts.JournaldMaxUse)
+	} else {
+		m.logger.Warnf("节点 %s 未使用 systemd，跳过 journald 配置", nodeName)
+	}
+
+	result, err := client.ExecuteCommand(writeIfChangedCommand(KubeletLogDropInPath, kubeletLogConfig(opts)))
+	if err != nil {
+		return fmt.Errorf("写入 kubelet 日志轮转配置失败: %v", err)
+	}
+	if strings.Contains(result.Stdout, "changed") {
+		for _, unit := range []string{"k3s", "k3s-agent"} {
+			if _, err := client.ExecuteIdempotentCommand(osInfo.ServiceExistsCommand(unit)); err != nil {
+				continue
+			}
+			if _, err := client.ExecuteCommand(osInfo.ServiceRestartCommand(unit)); err != nil {
+				return fmt.Errorf("重启 %s 使日志轮转配置生效失败: %v", unit, err)
+			}
+			m.logger.Infof("节点 %s 已重启 %s 使日志轮转配置生效", nodeName, unit)
+		}
+	}
+	m.logger.Infof("节点 %s 容器日志轮转: 单文件 %s，保留 %d 个", nodeName, opts.ContainerLogMaxSize, opts.ContainerLogMaxFiles)
+	return nil
+}
+
+// kubeletConfigz kubelet /configz 接口中与日志轮转相关的字段
+type kubeletConfigz struct {
+	KubeletConfig struct {
+		ContainerLogMaxSize  string `json:"containerLogMaxSize"`
+		ContainerLogMaxFiles int    `json:"containerLogMaxFiles"`
+	} `json:"kubeletconfig"`
+}
+
+// logRotationDrift 通过 API Server 代理读取各节点 kubelet 的生效配置，journald 配置由 JournaldDrift 逐节点检查
+func (m *Manager) logRotationDrift(client *ssh.Client, opts *model.LogRotationOptions) ([]model.DriftItem, error) {
+	items := []model.DriftItem{}
+
+	nodes, err := m.ListNodes(client)
+	if err != nil {
+		return nil, err
+	}
+	expected := fmt.Sprintf("container-log-max-size=%s,container-log-max-files=%d", opts.ContainerLogMaxSize, opts.ContainerLogMaxFiles)
+	for _, node := range nodes {
+		item := model.DriftItem{Kind: model.DriftLogRotation, Target: node.Name, Expected: expected}
+		result, err := runKubectlIdempotent(client, fmt.Sprintf("kubectl get --raw /api/v1/nodes/%s/proxy/configz", node.Name))
+		if err != nil {
+			item.Message = fmt.Sprintf("读取 kubelet 配置失败: %v", err)
+			items = append(items, item)
+			continue
+		}
+		var configz kubeletConfigz
+		if err := json.Unmarshal([]byte(result.Stdout), &configz); err != nil {
+			item.Message = fmt.Sprintf("解析 kubelet 配置失败: %v", err)
+			items = append(items, item)
+			continue
+		}
+		kc := configz.KubeletConfig
+		if kc.ContainerLogMaxSize != opts.ContainerLogMaxSize || kc.ContainerLogMaxFiles != opts.ContainerLogMaxFiles {
+			item.Actual = fmt.Sprintf("container-log-max-size=%s,container-log-max-files=%d", kc.ContainerLogMaxSize, kc.ContainerLogMaxFiles)
+			items = append(items, item)
+		}
+	}
+	return items, nil
+}
+
+// JournaldDrift 检查节点的 journald 配置，一致或节点未使用 systemd 时返回 nil。Target 为 <节点名>:<配置文件>
+func (m *Manager) JournaldDrift(client *ssh.Client, nodeName string, opts *model.LogRotationOptions) (*model.DriftItem, error) {
+	osInfo, err := hostos.Detect(client)
+	if err != nil {
+		return nil, err
+	}
+	if osInfo.InitSystem != hostos.InitSystemd {
+		return nil, nil
+	}
+	result, _ := client.ExecuteIdempotentCommand("cat " + JournaldDropInPath)
+	current := ""
+	if result != nil {
+		current = result.Stdout
+	}
+	if strings.TrimSpace(current) == strings.TrimSpace(journaldConfig(opts)) {
+		return nil, nil
+	}
+	return &model.DriftItem{
+		Kind:     model.DriftLogRotation,
+		Target:   nodeName + ":" + JournaldDropInPath,
+		Expected: journaldConfig(opts),
+		Actual:   current,
+	}, nil
+}
+
+// ReconcileJournald 重写节点的 journald 配置并重启 journald
+func (m *Manager) ReconcileJournald(client *ssh.Client, opts *model.LogRotationOptions) error {
+	_, err := client.ExecuteCommand(writeIfChangedCommand(JournaldDropInPath, journaldConfig(opts)) + " && systemctl restart systemd-journald")
+	return err
+}
@@ -0,0 +1,207 @@
+package k3s
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// nodeNamePattern Kubernetes 节点名（DNS 子域名）
+var nodeNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9.]*[a-z0-9])?$`)
+
+// DefaultDrainTimeout 未指定时的驱逐超时
+const DefaultDrainTimeout = 5 * time.Minute
+
+// DrainOptions kubectl drain 参数
+type DrainOptions struct {
+	Timeout time.Duration
+	// Force 删除不受控制器管理的 Pod
+	Force bool
+	// KeepEmptyDirData 不删除使用 emptyDir 的 Pod
+	KeepEmptyDirData bool
+}
+
+// pdbList kubectl get pdb -o json 输出中用到的字段
+type pdbList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Spec struct {
+			Selector struct {
+				MatchLabels map[string]string `json:"matchLabels"`
+			} `json:"selector"`
+		} `json:"spec"`
+		Status struct {
+			DisruptionsAllowed int `json:"disruptionsAllowed"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// nodePodList kubectl get pods -o json 输出中用到的字段
+type nodePodList struct {
+	Items []struct {
+		Metadata struct {
+			Name            string            `json:"name"`
+			Namespace       string            `json:"namespace"`
+			Labels          map[string]string `json:"labels"`
+			OwnerReferences []struct {
+				Kind string `json:"kind"`
+			} `json:"ownerReferences"`
+		} `json:"metadata"`
+	} `json:"items"`
+}
+
+// ValidateNodeName 校验节点名并确认节点存在于集群中
+func (m *Manager) ValidateNodeName(client *ssh.Client, node string) error {
+	if !nodeNamePattern.MatchString(node) {
+		return fmt.Errorf("无效的节点名: %q", node)
+	}
+	if _, err := runKubectlIdempotent(client, "kubectl get node "+node+" -o name"); err != nil {
+		return fmt.Errorf("集群中不存在节点 %s", node)
+	}
+	return nil
+}
+
+// Cordon 将节点标记为不可调度
+func (m *Manager) Cordon(client *ssh.Client, node string) error {
+	if _, err := runKubectl(client, "kubectl cordon "+node); err != nil {
+		return fmt.Errorf("cordon 节点 %s 失败: %v", node, err)
+	}
+	return nil
+}
+
+// Uncordon 恢复节点调度
+func (m *Manager) Uncordon(client *ssh.Client, node string) error {
+	if _, err := runKubectl(client, "kubectl uncordon "+node); err != nil {
+		return fmt.Errorf("uncordon 节点 %s 失败: %v", node, err)
+	}
+	return nil
+}
+
+// Drain 通过 Eviction API 驱逐节点上的 Pod（DaemonSet 管理的 Pod 除外），遵守 PodDisruptionBudget。
+// 驱逐前列出当前不允许中断的 PDB；超时失败时错误中附带这些 PDB，便于判断是否需要先扩容
+func (m *Manager) Drain(client *ssh.Client, node string, opts DrainOptions) (*model.DrainResult, error) {
+	if opts.Timeout <= 0 {
+		opts.Timeout = DefaultDrainTimeout
+	}
+	start := time.Now()
+	result := &model.DrainResult{Node: node, Evicted: []string{}}
+
+	blocking, err := m.blockingPDBs(client, node)
+	if err != nil {
+		m.logger.Warnf("节点 %s 读取 PodDisruptionBudget 失败: %v", node, err)
+	}
+	result.BlockingPDBs = blocking
+	if len(blocking) > 0 {
+		m.logger.Warnf("节点 %s 上的 Pod 受 PodDisruptionBudget %v 保护，当前不允许中断，驱逐将等待副本就绪", node, blocking)
+	}
+
+	cmd := fmt.Sprintf("kubectl drain %s --ignore-daemonsets --timeout=%ds", node, int(opts.Timeout.Seconds()))
+	if !opts.KeepEmptyDirData {
+		cmd += " --delete-emptydir-data"
+	}
+	if opts.Force {
+		cmd += " --force"
+	}
+	out, err := runKubectl(client, cmd)
+	if out != nil {
+		result.Evicted = evictedPods(out.Stdout + "\n" + out.Stderr)
+	}
+	result.Duration = time.Since(start).Round(time.Second).String()
+	if err != nil {
+		detail := err.Error()
+		if out != nil && out.Stderr != "" {
+			detail = out.Stderr
+		}
+		if len(blocking) > 0 {
+			return result, fmt.Errorf("驱逐节点 %s 失败（PodDisruptionBudget %s 不允许中断）: %s", node, strings.Join(blocking, ", "), detail)
+		}
+		return result, fmt.Errorf("驱逐节点 %s 失败: %s", node, detail)
+	}
+	m.logger.Infof("节点 %s 已驱逐 %d 个 Pod，耗时 %s", node, len(result.Evicted), result.Duration)
+	return result, nil
+}
+
+// WaitNodeReady 轮询节点的 Ready 条件直到为 True 或超时；apiserver 暂不可用（如 Master 重启中）时继续等待
+func (m *Manager) WaitNodeReady(client *ssh.Client, node string, timeout, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	cmd := fmt.Sprintf(`kubectl get node %s -o jsonpath='{.status.conditions[?(@.type=="Ready")].status}'`, node)
+	for {
+		out, err := runKubectlIdempotent(client, cmd)
+		if err == nil && strings.TrimSpace(out.Stdout) == "True" {
+			m.logger.Infof("节点 %s 已 Ready", node)
+			return nil
+		}
+		if time.Now().Add(interval).After(deadline) {
+			status := "未知"
+			if err == nil {
+				status = strings.TrimSpace(out.Stdout)
+			}
+			return fmt.Errorf("等待节点 %s Ready 超时（%s），当前状态: %s", node, timeout, status)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// blockingPDBs 返回覆盖节点上 Pod、且当前 disruptionsAllowed 为 0 的 PDB（命名空间/名称）。
+// 只按 matchLabels 匹配
+func (m *Manager) blockingPDBs(client *ssh.Client, node string) ([]string, error) {
+	out, err := runKubectlIdempotent(client, "kubectl get pdb -A -o json")
+	if err != nil {
+		return nil, err
+	}
+	var pdbs pdbList
+	if err := json.Unmarshal([]byte(out.Stdout), &pdbs); err != nil {
+		return nil, fmt.Errorf("解析 PodDisruptionBudget 失败: %v", err)
+	}
+	if len(pdbs.Items) == 0 {
+		return nil, nil
+	}
+
+	out, err = runKubectlIdempotent(client, "kubectl get pods -A --field-selector spec.nodeName="+node+" -o json")
+	if err != nil {
+		return nil, err
+	}
+	var pods nodePodList
+	if err := json.Unmarshal([]byte(out.Stdout), &pods); err != nil {
+		return nil, fmt.Errorf("解析节点 Pod 失败: %v", err)
+	}
+
+	var blocking []string
+	for _, pdb := range pdbs.Items {
+		if pdb.Status.DisruptionsAllowed > 0 {
+			continue
+		}
+		for _, pod := range pods.Items {
+			if len(pod.Metadata.OwnerReferences) > 0 && pod.Metadata.OwnerReferences[0].Kind == "DaemonSet" {
+				continue
+			}
+			if pod.Metadata.Namespace == pdb.Metadata.Namespace && matchLabels(pdb.Spec.Selector.MatchLabels, pod.Metadata.Labels) {
+				blocking = append(blocking, pdb.Metadata.Namespace+"/"+pdb.Metadata.Name)
+				break
+			}
+		}
+	}
+	return blocking, nil
+}
+
+// evictedPods 从 kubectl drain 输出的 "evicting pod <命名空间>/<名称>" 行中提取 Pod
+func evictedPods(output string) []string {
+	pods := []string{}
+	seen := make(map[string]bool)
+	for _, line := range strings.Split(output, "\n") {
+		pod, ok := strings.CutPrefix(strings.TrimSpace(line), "evicting pod ")
+		if ok && !seen[pod] {
+			seen[pod] = true
+			pods = append(pods, pod)
+		}
+	}
+	return pods
+}
@@ -0,0 +1,157 @@
+package k3s
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// metricsList metrics.k8s.io/v1beta1 的 NodeMetrics 与 PodMetrics 列表
+type metricsList struct {
+	Items []struct {
+		Metadata struct {
+			Name      string `json:"name"`
+			Namespace string `json:"namespace"`
+		} `json:"metadata"`
+		Usage      map[string]string `json:"usage"`
+		Containers []struct {
+			Usage map[string]string `json:"usage"`
+		} `json:"containers"`
+	} `json:"items"`
+}
+
+// nodeAllocatableList kubectl get nodes -o json 中的可分配资源
+type nodeAllocatableList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Status struct {
+			Allocatable map[string]string `json:"allocatable"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// Metrics 通过 metrics-server 读取节点和 Pod 的 CPU、内存用量。
+// metrics-server 被禁用（如边缘配置档）或尚未就绪时返回错误
+func (m *Manager) Metrics(client *ssh.Client) (*model.ClusterMetrics, error) {
+	result, err := runKubectlIdempotent(client, "kubectl get --raw /apis/metrics.k8s.io/v1beta1/nodes")
+	if err != nil {
+		return nil, fmt.Errorf("metrics-server 不可用（是否已禁用或尚未就绪）: %v", err)
+	}
+	var nodeUsage metricsList
+	if err := json.Unmarshal([]byte(result.Stdout), &nodeUsage); err != nil {
+		return nil, fmt.Errorf("解析节点用量失败: %v", err)
+	}
+
+	result, err = runKubectlIdempotent(client, "kubectl get --raw /apis/metrics.k8s.io/v1beta1/pods")
+	if err != nil {
+		return nil, fmt.Errorf("读取 Pod 用量失败: %v", err)
+	}
+	var podUsage metricsList
+	if err := json.Unmarshal([]byte(result.Stdout), &podUsage); err != nil {
+		return nil, fmt.Errorf("解析 Pod 用量失败: %v", err)
+	}
+
+	result, err = runKubectlIdempotent(client, "kubectl get nodes -o json")
+	if err != nil {
+		return nil, fmt.Errorf("读取节点可分配资源失败: %v", err)
+	}
+	var nodes nodeAllocatableList
+	if err := json.Unmarshal([]byte(result.Stdout), &nodes); err != nil {
+		return nil, fmt.Errorf("解析节点可分配资源失败: %v", err)
+	}
+	allocatable := make(map[string]map[string]string, len(nodes.Items))
+	for _, node := range nodes.Items {
+		allocatable[node.Metadata.Name] = node.Status.Allocatable
+	}
+
+	metrics := &model.ClusterMetrics{
+		CollectedAt: time.Now(),
+		Nodes:       make([]model.NodeMetrics, 0, len(nodeUsage.Items)),
+		Pods:        make([]model.PodMetrics, 0, len(podUsage.Items)),
+	}
+	for _, item := range nodeUsage.Items {
+		node := model.NodeMetrics{
+			Name:              item.Metadata.Name,
+			CPUUsage:          CPUMillis(item.Usage["cpu"]),
+			MemoryUsage:       MemoryBytes(item.Usage["memory"]),
+			CPUAllocatable:    CPUMillis(allocatable[item.Metadata.Name]["cpu"]),
+			MemoryAllocatable: MemoryBytes(allocatable[item.Metadata.Name]["memory"]),
+		}
+		node.CPUPercent = percent(node.CPUUsage, node.CPUAllocatable)
+		node.MemoryPercent = percent(node.MemoryUsage, node.MemoryAllocatable)
+		metrics.Nodes = append(metrics.Nodes, node)
+	}
+	for _, item := range podUsage.Items {
+		pod := model.PodMetrics{Namespace: item.Metadata.Namespace, Name: item.Metadata.Name}
+		for _, c := range item.Containers {
+			pod.CPUUsage += CPUMillis(c.Usage["cpu"])
+			pod.MemoryUsage += MemoryBytes(c.Usage["memory"])
+		}
+		metrics.Pods = append(metrics.Pods, pod)
+	}
+	sort.Slice(metrics.Nodes, func(i, j int) bool { return metrics.Nodes[i].Name < metrics.Nodes[j].Name })
+	return metrics, nil
+}
+
+// quantityParts 拆分 Kubernetes 资源数量的数值和单位后缀
+var quantityParts = regexp.MustCompile(`^([0-9.]+)([a-zA-Z]*)$`)
+
+// quantityScale 单位后缀到倍数（以基本单位计）
+var quantityScale = map[string]float64{
+	"":   1,
+	"n":  1e-9,
+	"u":  1e-6,
+	"m":  1e-3,
+	"k":  1e3,
+	"M":  1e6,
+	"G":  1e9,
+	"T":  1e12,
+	"Ki": 1 << 10,
+	"Mi": 1 << 20,
+	"Gi": 1 << 30,
+	"Ti": 1 << 40,
+}
+
+// parseQuantity 解析资源数量为基本单位的数值，无法解析时返回 0
+func parseQuantity(q string) float64 {
+	parts := quantityParts.FindStringSubmatch(strings.TrimSpace(q))
+	if parts == nil {
+		return 0
+	}
+	scale, ok := quantityScale[parts[2]]
+	if !ok {
+		return 0
+	}
+	value, err := strconv.ParseFloat(parts[1], 64)
+	if err != nil {
+		return 0
+	}
+	return value * scale
+}
+
+// CPUMillis 将 CPU 数量（如 250m、1、123456789n）转换为 millicore
+func CPUMillis(q string) int64 {
+	return int64(math.Round(parseQuantity(q) * 1000))
+}
+
+// MemoryBytes 将内存数量（如 512Mi、1Gi、123456Ki）转换为字节
+func MemoryBytes(q string) int64 {
+	return int64(parseQuantity(q))
+}
+
+func percent(used, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return math.Round(float64(used)*1000/float64(total)) / 10
+}
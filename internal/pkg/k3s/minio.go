@@ -0,0 +1,402 @@
+package k3s
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+const (
+	// MinIONamespace 集群内对象存储所在的命名空间
+	MinIONamespace = "minio"
+	// MinIOLabel distributed 模式下运行 MinIO 副本的节点标签（值为 true）
+	MinIOLabel = "insuite.minio"
+	// MinIOEndpoint 集群内访问 MinIO 的地址
+	MinIOEndpoint = "http://minio.minio.svc.cluster.local:9000"
+	// VeleroBucket、AppBucket 安装时总是创建的存储桶，分别供 Velero 和 inSuite 实例使用
+	VeleroBucket = "velero"
+	AppBucket    = "insuite"
+	// minioSecret MinIO 根用户的访问密钥
+	minioSecret = "k3s-deploy-minio"
+	// MinIODistributedNodes distributed 模式的最少节点数，MinIO 纠删码至少需要 4 个驱动器
+	MinIODistributedNodes = 4
+
+	defaultMinIOImage = "minio/minio:RELEASE.2024-10-13T13-34-11Z"
+	defaultMinIOSize  = "20Gi"
+)
+
+// imagePattern 镜像引用，如 registry:5000/minio/minio:tag
+var imagePattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9.:/_@-]*$`)
+
+// MinIOConfig MinIO 安装参数，访问密钥由调用方生成或沿用
+type MinIOConfig struct {
+	Mode         string
+	Image        string
+	Size         string
+	StorageClass string
+	Buckets      []string
+	AccessKey    string
+	SecretKey    string
+}
+
+// ValidateMinIO 校验 MinIO 安装参数
+func ValidateMinIO(opts *model.MinIOOptions) error {
+	if opts == nil {
+		return nil
+	}
+	if opts.Mode != "" && opts.Mode != model.MinIOStandalone && opts.Mode != model.MinIODistributed {
+		return fmt.Errorf("不支持的 MinIO 模式: %s（支持 standalone、distributed）", opts.Mode)
+	}
+	if opts.Image != "" && !imagePattern.MatchString(opts.Image) {
+		return fmt.Errorf("无效的 MinIO 镜像: %s", opts.Image)
+	}
+	if opts.Size != "" && !quantityPattern.MatchString(opts.Size) {
+		return fmt.Errorf("无效的数据卷大小: %s", opts.Size)
+	}
+	if opts.StorageClass != "" && !namespacePattern.MatchString(opts.StorageClass) {
+		return fmt.Errorf("无效的 StorageClass: %s", opts.StorageClass)
+	}
+	for _, bucket := range opts.Buckets {
+		if !bucketPattern.MatchString(bucket) {
+			return fmt.Errorf("无效的存储桶名: %s", bucket)
+		}
+	}
+	return nil
+}
+
+// MinIOBuckets 安装时创建的存储桶：固定的 velero、insuite 加上请求中的存储桶
+func MinIOBuckets(extra []string) []string {
+	buckets := []string{VeleroBucket, AppBucket}
+	for _, bucket := range extra {
+		if !slices.Contains(buckets, bucket) {
+			buckets = append(buckets, bucket)
+		}
+	}
+	return buckets
+}
+
+// minioManifest 生成 MinIO 的 Service 与 StatefulSet。distributed 模式每个带标签的节点运行一个副本，
+// 通过 headless Service 的固定 Pod 域名组成纠删码集群；standalone 模式有带标签的节点时调度到其中
+func minioManifest(cfg MinIOConfig, replicas int, labeled bool) (string, error) {
+	image, size := cfg.Image, cfg.Size
+	if image == "" {
+		image = defaultMinIOImage
+	}
+	if size == "" {
+		size = defaultMinIOSize
+	}
+	args := []string{"server", "/data", "--console-address", ":9001"}
+	if cfg.Mode == model.MinIODistributed {
+		args[1] = fmt.Sprintf("http://minio-{0...%d}.minio-hl.%s.svc.cluster.local/data", replicas-1, MinIONamespace)
+	}
+	secretEnv := func(name, key string) map[string]any {
+		return map[string]any{"name": name, "valueFrom": map[string]any{
+			"secretKeyRef": map[string]string{"name": minioSecret, "key": key},
+		}}
+	}
+	probe := func(path string, delay int) map[string]any {
+		return map[string]any{
+			"httpGet":             map[string]any{"path": path, "port": 9000},
+			"initialDelaySeconds": delay,
+			"periodSeconds":       10,
+		}
+	}
+
+	pod := map[string]any{
+		"securityContext": map[string]any{
+			"runAsNonRoot":   true,
+			"runAsUser":      1000,
+			"runAsGroup":     1000,
+			"fsGroup":        1000,
+			"seccompProfile": map[string]string{"type": "RuntimeDefault"},
+		},
+		"containers": []map[string]any{{
+			"name":  "minio",
+			"image": image,
+			"args":  args,
+			"env": []map[string]any{
+				secretEnv("MINIO_ROOT_USER", "access-key"),
+				secretEnv("MINIO_ROOT_PASSWORD", "secret-key"),
+			},
+			"ports": []map[string]any{
+				{"name": "api", "containerPort": 9000},
+				{"name": "console", "containerPort": 9001},
+			},
+			"readinessProbe": probe("/minio/health/ready", 10),
+			"livenessProbe":  probe("/minio/health/live", 30),
+			"volumeMounts":   []map[string]string{{"name": "data", "mountPath": "/data"}},
+			"securityContext": map[string]any{
+				"allowPrivilegeEscalation": false,
+				"capabilities":             map[string]any{"drop": []string{"ALL"}},
+			},
+		}},
+	}
+	if labeled {
+		pod["nodeSelector"] = map[string]string{MinIOLabel: "true"}
+	}
+	if cfg.Mode == model.MinIODistributed {
+		pod["affinity"] = map[string]any{"podAntiAffinity": map[string]any{
+			"requiredDuringSchedulingIgnoredDuringExecution": []map[string]any{{
+				"labelSelector": map[string]any{"matchLabels": map[string]string{"app": "minio"}},
+				"topologyKey":   "kubernetes.io/hostname",
+			}},
+		}}
+	}
+
+	claim := map[string]any{
+		"accessModes": []string{"ReadWriteOnce"},
+		"resources":   map[string]any{"requests": map[string]string{"storage": size}},
+	}
+	if cfg.StorageClass != "" {
+		claim["storageClassName"] = cfg.StorageClass
+	}
+	labels := map[string]string{"app": "minio", ManagedByLabel: ManagedBy}
+	objects := []map[string]any{
+		{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   map[string]any{"name": "minio", "namespace": MinIONamespace, "labels": labels},
+			"spec": map[string]any{
+				"selector": map[string]string{"app": "minio"},
+				"ports": []map[string]any{
+					{"name": "api", "port": 9000, "targetPort": 9000},
+					{"name": "console", "port": 9001, "targetPort": 9001},
+				},
+			},
+		},
+		{
+			"apiVersion": "v1",
+			"kind":       "Service",
+			"metadata":   map[string]any{"name": "minio-hl", "namespace": MinIONamespace, "labels": labels},
+			"spec": map[string]any{
+				"clusterIP": "None",
+				// 副本互相发现时对方尚未就绪
+				"publishNotReadyAddresses": true,
+				"selector":                 map[string]string{"app": "minio"},
+				"ports":                    []map[string]any{{"name": "api", "port": 9000}},
+			},
+		},
+		{
+			"apiVersion": "apps/v1",
+			"kind":       "StatefulSet",
+			"metadata":   map[string]any{"name": "minio", "namespace": MinIONamespace, "labels": labels},
+			"spec": map[string]any{
+				"serviceName":         "minio-hl",
+				"replicas":            replicas,
+				"podManagementPolicy": "Parallel",
+				"selector":            map[string]any{"matchLabels": map[string]string{"app": "minio"}},
+				"template": map[string]any{
+					"metadata": map[string]any{"labels": map[string]string{"app": "minio"}},
+					"spec":     pod,
+				},
+				"volumeClaimTemplates": []map[string]any{{
+					"metadata": map[string]any{"name": "data"},
+					"spec":     claim,
+				}},
+			},
+		},
+	}
+
+	docs := make([]string, 0, len(objects))
+	for _, object := range objects {
+		doc, err := yaml.Marshal(object)
+		if err != nil {
+			return "", err
+		}
+		docs = append(docs, string(doc))
+	}
+	return strings.Join(docs, "---\n"), nil
+}
+
+// labeledMinIONodes 返回带 MinIO 标签的节点数
+func (m *Manager) labeledMinIONodes(client *ssh.Client) (int, error) {
+	result, err := runKubectlIdempotent(client, fmt.Sprintf("kubectl get nodes -l %s=true -o name", MinIOLabel))
+	if err != nil {
+		return 0, fmt.Errorf("查询 MinIO 节点失败: %v", err)
+	}
+	return len(strings.Fields(result.Stdout)), nil
+}
+
+// InstallMinIO 安装（或更新）MinIO，等待全部副本就绪并创建存储桶，返回副本数。
+// distributed 模式的副本数由带标签的节点数决定，已安装后不能变更
+func (m *Manager) InstallMinIO(client *ssh.Client, ws *Workspace, cfg MinIOConfig, policy WaitPolicy) (int, error) {
+	policy = policy.WithDefaults()
+	labeled, err := m.labeledMinIONodes(client)
+	if err != nil {
+		return 0, err
+	}
+	replicas := 1
+	if cfg.Mode == model.MinIODistributed {
+		if labeled < MinIODistributedNodes {
+			return 0, fmt.Errorf("distributed 模式需要至少 %d 个带 %s=true 标签的节点，当前 %d 个", MinIODistributedNodes, MinIOLabel, labeled)
+		}
+		replicas = labeled
+	}
+	result, err := runKubectlIdempotent(client, fmt.Sprintf("kubectl -n %s get statefulset minio -o jsonpath='{.spec.replicas}' 2>/dev/null || true", MinIONamespace))
+	if err != nil {
+		return 0, fmt.Errorf("查询已安装的 MinIO 失败: %v", err)
+	}
+	if current, err := strconv.Atoi(strings.TrimSpace(result.Stdout)); err == nil && current != replicas {
+		return 0, fmt.Errorf("已安装的 MinIO 有 %d 个副本，不能变更为 %d 个（扩容需新增服务器池，请手动操作）", current, replicas)
+	}
+	m.logger.Infof("开始安装 MinIO（%s，%d 个副本）", cfg.Mode, replicas)
+
+	cmd := fmt.Sprintf("kubectl create namespace %s --dry-run=client -o yaml | kubectl apply -f -", MinIONamespace)
+	if _, err := runKubectl(client, cmd); err != nil {
+		return 0, fmt.Errorf("创建命名空间 %s 失败: %v", MinIONamespace, err)
+	}
+	keys, err := ws.Upload("minio-credentials", fmt.Sprintf("access-key=%s\nsecret-key=%s\n", cfg.AccessKey, cfg.SecretKey))
+	if err != nil {
+		return 0, fmt.Errorf("上传 MinIO 访问密钥失败: %v", err)
+	}
+	cmd = fmt.Sprintf("kubectl -n %s create secret generic %s --from-env-file=%s --dry-run=client -o yaml | kubectl apply -f -", MinIONamespace, minioSecret, keys)
+	if _, err := runKubectl(client, cmd); err != nil {
+		return 0, fmt.Errorf("创建 MinIO 访问密钥失败: %v", err)
+	}
+
+	manifest, err := minioManifest(cfg, replicas, labeled > 0)
+	if err != nil {
+		return 0, fmt.Errorf("生成 MinIO 清单失败: %v", err)
+	}
+	file, err := ws.Upload("minio.yaml", manifest)
+	if err != nil {
+		return 0, fmt.Errorf("上传 MinIO 清单失败: %v", err)
+	}
+	if _, err := runKubectl(client, "kubectl apply -f "+file); err != nil {
+		return 0, fmt.Errorf("应用 MinIO 清单失败: %v", err)
+	}
+
+	cmd = fmt.Sprintf("kubectl -n %s rollout status statefulset/minio --timeout=%ds", MinIONamespace, int(policy.DeploymentTimeout.Seconds()))
+	if _, err := runKubectlIdempotent(client, cmd); err != nil {
+		if reason := m.podFailure(client, MinIONamespace, "minio"); reason != "" {
+			return 0, fmt.Errorf("MinIO 启动失败: %s", reason)
+		}
+		return 0, fmt.Errorf("等待 MinIO 就绪超时（%s），请检查数据卷能否创建: %v", policy.DeploymentTimeout, err)
+	}
+
+	// 使用镜像自带的 mc 创建存储桶，访问密钥从容器环境变量读取，不出现在命令行中；
+	// 容器以非 root 用户运行，mc 配置写入 /tmp
+	targets := make([]string, 0, len(cfg.Buckets))
+	for _, bucket := range cfg.Buckets {
+		targets = append(targets, "local/"+bucket)
+	}
+	cmd = fmt.Sprintf(`kubectl -n %s exec minio-0 -- sh -c 'mc -C /tmp/.mc alias set local http://127.0.0.1:9000 "$MINIO_ROOT_USER" "$MINIO_ROOT_PASSWORD" >/dev/null && mc -C /tmp/.mc mb --ignore-existing %s'`,
+		MinIONamespace, strings.Join(targets, " "))
+	deadline := time.Now().Add(policy.DeploymentTimeout)
+	for {
+		// distributed 模式在多数副本互相连通前不接受写入
+		_, err := runKubectl(client, cmd)
+		if err == nil {
+			break
+		}
+		if time.Now().After(deadline) {
+			return 0, fmt.Errorf("创建存储桶失败: %v", err)
+		}
+		time.Sleep(policy.PollInterval)
+	}
+	m.logger.Infof("MinIO 安装完成，存储桶: %s", strings.Join(cfg.Buckets, ", "))
+	return replicas, nil
+}
+
+// appObjectStorageSecret 实例命名空间中的对象存储访问信息，应用组件以环境变量读取
+const appObjectStorageSecret = "insuite-object-storage"
+
+// ObjectStorage 提供给 inSuite 实例的对象存储，AccessKey/SecretKey 为实例专用的 MinIO 用户，只能访问 Bucket 中 Prefix 下的对象
+type ObjectStorage struct {
+	Endpoint  string
+	Bucket    string
+	Prefix    string
+	AccessKey string
+	SecretKey string
+}
+
+// applyObjectStorage 在实例命名空间中创建（或删除）对象存储 Secret，实例以自己的名称作为桶内前缀
+func (m *Manager) applyObjectStorage(client *ssh.Client, ws *Workspace, spec AppSpec) error {
+	namespace := spec.namespace()
+	store := spec.ObjectStorage
+	if store == nil {
+		if _, err := runKubectl(client, fmt.Sprintf("kubectl -n %s delete secret %s --ignore-not-found", namespace, appObjectStorageSecret)); err != nil {
+			return fmt.Errorf("删除对象存储 Secret 失败: %v", err)
+		}
+		return nil
+	}
+	if err := m.ensureMinIOUser(client, ws, store); err != nil {
+		return err
+	}
+
+	env := fmt.Sprintf("S3_ENDPOINT=%s\nS3_BUCKET=%s\nS3_PREFIX=%s\nS3_ACCESS_KEY=%s\nS3_SECRET_KEY=%s\n",
+		store.Endpoint, store.Bucket, store.Prefix, store.AccessKey, store.SecretKey)
+	file, err := ws.Upload("insuite-object-storage.env", env)
+	if err != nil {
+		return fmt.Errorf("上传对象存储配置失败: %v", err)
+	}
+	cmd := fmt.Sprintf("kubectl -n %s create secret generic %s --from-env-file=%s --dry-run=client -o yaml | kubectl apply -f -", namespace, appObjectStorageSecret, file)
+	if _, err := runKubectl(client, cmd); err != nil {
+		return fmt.Errorf("创建对象存储 Secret 失败: %v", err)
+	}
+	m.logger.Infof("实例 %s 使用对象存储 %s/%s/%s", spec.instance(), store.Endpoint, store.Bucket, store.Prefix)
+	return nil
+}
+
+// minioPrefixPolicy 只允许访问存储桶中 prefix 下对象的 MinIO 策略
+func minioPrefixPolicy(bucket, prefix string) (string, error) {
+	policy := map[string]any{
+		"Version": "2012-10-17",
+		"Statement": []map[string]any{
+			{
+				"Effect":   "Allow",
+				"Action":   []string{"s3:GetBucketLocation"},
+				"Resource": []string{"arn:aws:s3:::" + bucket},
+			},
+			{
+				"Effect":    "Allow",
+				"Action":    []string{"s3:ListBucket"},
+				"Resource":  []string{"arn:aws:s3:::" + bucket},
+				"Condition": map[string]any{"StringLike": map[string][]string{"s3:prefix": {prefix + "/*"}}},
+			},
+			{
+				"Effect":   "Allow",
+				"Action":   []string{"s3:GetObject", "s3:PutObject", "s3:DeleteObject", "s3:ListMultipartUploadParts", "s3:AbortMultipartUpload"},
+				"Resource": []string{"arn:aws:s3:::" + bucket + "/" + prefix + "/*"},
+			},
+		},
+	}
+	data, err := json.Marshal(policy)
+	return string(data), err
+}
+
+// ensureMinIOUser 在集群内 MinIO 中创建（或更新）实例专用用户并绑定前缀策略。
+// 用户密钥和策略经 stdin 传入 minio-0 容器，不出现在命令行中；根用户密钥从容器环境变量读取
+func (m *Manager) ensureMinIOUser(client *ssh.Client, ws *Workspace, store *ObjectStorage) error {
+	policy, err := minioPrefixPolicy(store.Bucket, store.Prefix)
+	if err != nil {
+		return fmt.Errorf("生成对象存储策略失败: %v", err)
+	}
+	input, err := ws.Upload("minio-user", store.AccessKey+"\n"+store.SecretKey+"\n"+policy+"\n")
+	if err != nil {
+		return fmt.Errorf("上传对象存储用户失败: %v", err)
+	}
+	name := AppBucket + "-" + store.Prefix
+	script := strings.Join([]string{
+		`read -r ak`, `read -r sk`, `cat > /tmp/.mc-policy.json`,
+		`mc -C /tmp/.mc alias set local http://127.0.0.1:9000 "$MINIO_ROOT_USER" "$MINIO_ROOT_PASSWORD" >/dev/null`,
+		`mc -C /tmp/.mc admin user add local "$ak" "$sk" >/dev/null`,
+		`mc -C /tmp/.mc admin policy create local ` + name + ` /tmp/.mc-policy.json >/dev/null`,
+		// 策略已绑定时 attach 返回错误，以用户信息确认
+		`{ mc -C /tmp/.mc admin policy attach local ` + name + ` --user "$ak" >/dev/null 2>&1 || mc -C /tmp/.mc admin user info local "$ak" | grep -qw ` + name + `; }`,
+	}, " && ")
+	cmd := fmt.Sprintf("kubectl -n %s exec -i minio-0 -- sh -c '%s; status=$?; rm -f /tmp/.mc-policy.json; exit $status' < %s", MinIONamespace, script, input)
+	if _, err := runKubectl(client, cmd); err != nil {
+		return fmt.Errorf("创建对象存储用户 %s 失败: %v", name, err)
+	}
+	return nil
+}
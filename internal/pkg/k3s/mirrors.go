@@ -0,0 +1,146 @@
+package k3s
+
+import (
+	"fmt"
+	"strings"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// MirrorConfig 国内网络环境下安装使用的镜像源
+type MirrorConfig struct {
+	// SystemDefault k3s 系统镜像（pause、coredns 等）的默认仓库，对应 --system-default-registry
+	SystemDefault string
+	// Mirrors docker.io 镜像加速地址，通过 INSTALL_K3S_REGISTRIES 写入 registries.yaml
+	Mirrors []string
+	// ProbeImages 安装前检查的镜像（docker.io 路径），镜像源需能提供全部镜像才视为可用
+	ProbeImages []string
+	// ReleaseURL k3s 发布文件地址，用于获取官方 sha256sum 校验安装的二进制，为空时使用 GitHub Releases
+	ReleaseURL string
+}
+
+// mirrorPlan 经过健康检查后实际使用的镜像源
+type mirrorPlan struct {
+	// systemDefault 为空表示系统镜像也通过 docker.io 镜像加速拉取
+	systemDefault string
+	mirrors       []string
+}
+
+// manifestAccept 同时接受多架构索引与单架构清单，避免仓库因 Accept 不匹配返回 404
+const manifestAccept = "application/vnd.oci.image.index.v1+json, application/vnd.docker.distribution.manifest.list.v2+json, " +
+	"application/vnd.docker.distribution.manifest.v2+json, application/vnd.oci.image.manifest.v1+json"
+
+// probeManifestScript 在节点上对镜像清单发起 HEAD 请求并输出 HTTP 状态码。
+// 遇到 401 时按 WWW-Authenticate 申请匿名拉取令牌后重试，公共仓库普遍要求这一步
+const probeManifestScript = `url="$1/v2/$2/manifests/$3"
+hdr=$(curl -sSI -m 10 -H "Accept: $4" "$url" 2>/dev/null | tr -d '\r')
+code=$(echo "$hdr" | awk 'NR==1 { print $2 }')
+if [ "$code" = "401" ]; then
+  auth=$(echo "$hdr" | grep -i '^www-authenticate:')
+  realm=$(echo "$auth" | sed -n 's/.*realm="\([^"]*\)".*/\1/p')
+  service=$(echo "$auth" | sed -n 's/.*service="\([^"]*\)".*/\1/p')
+  token=$(curl -sS -m 10 "$realm?service=$service&scope=repository:$2:pull" 2>/dev/null | sed -n 's/.*"token" *: *"\([^"]*\)".*/\1/p')
+  code=$(curl -sSI -o /dev/null -m 10 -w '%{http_code}' -H "Accept: $4" -H "Authorization: Bearer $token" "$url" 2>/dev/null)
+fi
+echo "${code:-000}"`
+
+// CheckMirrors 在节点上检查镜像源能否提供所需镜像。节点不在国内网络环境时不使用镜像源，直接跳过
+func (i *Installer) CheckMirrors(client *ssh.Client, nodeName string, mirrors *model.MirrorOptions) error {
+	installURL, err := i.getInstallURL(client)
+	if err != nil {
+		return err
+	}
+	if installURL != officialCNInstallURL {
+		i.logger.Infof("节点 %s 使用官方源安装，跳过镜像源检查", nodeName)
+		return nil
+	}
+	_, err = i.planMirrors(client, nodeName, i.mirrorsFor(mirrors))
+	return err
+}
+
+// mirrorsFor 请求设置了镜像源时替换配置中的镜像源
+func (i *Installer) mirrorsFor(override *model.MirrorOptions) MirrorConfig {
+	mirrors := i.mirrors
+	if override != nil {
+		mirrors.SystemDefault = override.SystemDefault
+		mirrors.Mirrors = override.Mirrors
+	}
+	return mirrors
+}
+
+// planMirrors 逐个检查镜像源，剔除不可用的 docker.io 镜像加速；系统默认仓库不可用时回退为通过镜像加速拉取系统镜像。
+// 没有任何可用镜像源时提前失败，而不是等到 k3s 启动后镜像拉取超时
+func (i *Installer) planMirrors(client *ssh.Client, nodeName string, mirrors MirrorConfig) (*mirrorPlan, error) {
+	plan := &mirrorPlan{}
+	var failures []string
+
+	for _, mirror := range mirrors.Mirrors {
+		if err := i.probeRegistry(client, mirror); err != nil {
+			i.logger.Warnf("节点 %s 镜像加速 %s 不可用，已剔除: %v", nodeName, mirror, err)
+			failures = append(failures, fmt.Sprintf("%s: %v", mirror, err))
+			continue
+		}
+		i.logger.Infof("节点 %s 镜像加速 %s 可用", nodeName, mirror)
+		plan.mirrors = append(plan.mirrors, mirror)
+	}
+
+	if mirrors.SystemDefault != "" {
+		if err := i.probeRegistry(client, mirrors.SystemDefault); err != nil {
+			failures = append(failures, fmt.Sprintf("%s: %v", mirrors.SystemDefault, err))
+			if len(plan.mirrors) > 0 {
+				i.logger.Warnf("节点 %s 系统镜像仓库 %s 不可用，回退为通过镜像加速拉取系统镜像: %v", nodeName, mirrors.SystemDefault, err)
+			}
+		} else {
+			i.logger.Infof("节点 %s 系统镜像仓库 %s 可用", nodeName, mirrors.SystemDefault)
+			plan.systemDefault = mirrors.SystemDefault
+		}
+	}
+
+	if plan.systemDefault == "" && len(plan.mirrors) == 0 {
+		return nil, fmt.Errorf("节点 %s 没有可用的镜像源（%s），请检查网络或在配置 registry 中更换镜像源",
+			nodeName, strings.Join(failures, "; "))
+	}
+	return plan, nil
+}
+
+// probeRegistry 检查仓库能否提供全部 ProbeImages
+func (i *Installer) probeRegistry(client *ssh.Client, registry string) error {
+	base := registry
+	if !strings.Contains(base, "://") {
+		base = "https://" + base
+	}
+	base = strings.TrimSuffix(base, "/")
+
+	for _, image := range i.mirrors.ProbeImages {
+		repo, ref := splitImageRef(image)
+		cmd := fmt.Sprintf("/bin/sh -s -- '%s' '%s' '%s' '%s'", base, repo, ref, manifestAccept)
+		result, err := client.ExecuteCommandWithStdin([]byte(probeManifestScript), cmd, nil)
+		if err != nil {
+			return fmt.Errorf("检查镜像 %s 失败: %v", image, err)
+		}
+		switch code := strings.TrimSpace(result.Stdout); code {
+		case "200":
+		case "000":
+			return fmt.Errorf("无法连接")
+		case "404":
+			return fmt.Errorf("不提供镜像 %s", image)
+		case "401", "403":
+			return fmt.Errorf("拉取镜像 %s 需要认证（HTTP %s）", image, code)
+		default:
+			return fmt.Errorf("检查镜像 %s 返回 HTTP %s", image, code)
+		}
+	}
+	return nil
+}
+
+// splitImageRef 拆分 repo:tag 或 repo@digest，未指定标签时为 latest
+func splitImageRef(image string) (repo, ref string) {
+	if repo, digest, ok := strings.Cut(image, "@"); ok {
+		return repo, digest
+	}
+	if idx := strings.LastIndex(image, ":"); idx > strings.LastIndex(image, "/") {
+		return image[:idx], image[idx+1:]
+	}
+	return image, "latest"
+}
@@ -0,0 +1,230 @@
+package k3s
+
+import (
+	"fmt"
+	"net/http"
+	"regexp"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+const (
+	// canaryNamespace 网络检查使用的临时命名空间，检查结束后删除
+	canaryNamespace = "k3s-deploy-canary"
+	// DefaultCanaryExternalName 未指定时用于检查外部域名解析的域名
+	DefaultCanaryExternalName = "registry.cn-hangzhou.aliyuncs.com"
+)
+
+// externalNamePattern RFC 1123 域名，每段不超过 63 个字符
+var externalNamePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?(\.[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?)*$`)
+
+// ValidateExternalName 检查外部解析使用的域名，空值使用默认域名
+func ValidateExternalName(name string) error {
+	if name == "" {
+		return nil
+	}
+	if len(name) > 253 || !externalNamePattern.MatchString(strings.ToLower(name)) {
+		return fmt.Errorf("无效的外部域名: %q", name)
+	}
+	return nil
+}
+
+// canaryManifest 在每个节点上运行一个 nginx Pod（复用 inSuite 应用镜像，离线和预拉取场景下无需额外镜像），
+// 通过 NodePort Service 同时提供 ClusterIP 和 NodePort
+func canaryManifest() string {
+	return fmt.Sprintf(`
+apiVersion: v1
+kind: Namespace
+metadata:
+  name: %[1]s
+  labels:
+    %[2]s: %[3]s
+---
+apiVersion: apps/v1
+kind: DaemonSet
+metadata:
+  name: canary
+  namespace: %[1]s
+spec:
+  selector:
+    matchLabels:
+      app: canary
+  template:
+    metadata:
+      labels:
+        app: canary
+    spec:
+      tolerations:
+      - operator: Exists
+      containers:
+      - name: nginx
+        image: %[4]s
+        ports:
+        - containerPort: 80
+        readinessProbe:
+          httpGet:
+            path: /
+            port: 80
+          periodSeconds: 2
+---
+apiVersion: v1
+kind: Service
+metadata:
+  name: canary
+  namespace: %[1]s
+spec:
+  type: NodePort
+  selector:
+    app: canary
+  ports:
+  - port: 80
+    targetPort: 80
+`, canaryNamespace, ManagedByLabel, ManagedBy, AppImage)
+}
+
+// canaryPod 检查 Pod 及其所在节点
+type canaryPod struct {
+	name string
+	node string
+	ip   string
+}
+
+// CheckNetwork 部署临时检查 Pod 验证集群网络，逐项返回结果：
+// Pod 内解析 kubernetes.default 和外部域名，通过 ClusterIP 访问 Service，跨节点访问其他节点上的 Pod，
+// 并由后端直接访问各节点的 NodePort；checkPolicy 时再验证网络策略能阻断跨节点访问。检查 Pod 无法就绪时返回错误
+func (m *Manager) CheckNetwork(client *ssh.Client, ws *Workspace, externalName string, checkPolicy bool, policy WaitPolicy) ([]model.NetworkCheck, error) {
+	policy = policy.WithDefaults()
+	if externalName == "" {
+		externalName = DefaultCanaryExternalName
+	}
+	if err := ValidateExternalName(externalName); err != nil {
+		return nil, err
+	}
+
+	file, err := ws.Upload("network-canary.yaml", canaryManifest())
+	if err != nil {
+		return nil, fmt.Errorf("上传网络检查清单失败: %v", err)
+	}
+	if _, err := runKubectl(client, "kubectl apply -f "+file); err != nil {
+		return nil, fmt.Errorf("部署网络检查 Pod 失败: %v", err)
+	}
+	defer func() {
+		if _, err := runKubectl(client, "kubectl delete namespace "+canaryNamespace+" --wait=false"); err != nil {
+			m.logger.Warnf("删除网络检查命名空间失败: %v", err)
+		}
+	}()
+
+	cmd := fmt.Sprintf("kubectl rollout status daemonset/canary -n %s --timeout=%ds", canaryNamespace, int(policy.DeploymentTimeout.Seconds()))
+	if _, err := runKubectlIdempotent(client, cmd); err != nil {
+		return nil, m.rolloutError(client, canaryNamespace, "canary", fmt.Sprintf("等待网络检查 Pod 就绪失败: %v", err))
+	}
+
+	result, err := runKubectlIdempotent(client, fmt.Sprintf("kubectl get pods -n %s -l app=canary -o jsonpath='{range .items[*]}{.metadata.name} {.spec.nodeName} {.status.podIP}{\"\\n\"}{end}'", canaryNamespace))
+	if err != nil {
+		return nil, fmt.Errorf("读取网络检查 Pod 失败: %v", err)
+	}
+	var pods []canaryPod
+	for _, line := range strings.Split(strings.TrimSpace(result.Stdout), "\n") {
+		if fields := strings.Fields(line); len(fields) == 3 {
+			pods = append(pods, canaryPod{name: fields[0], node: fields[1], ip: fields[2]})
+		}
+	}
+	if len(pods) == 0 {
+		return nil, fmt.Errorf("没有运行中的网络检查 Pod")
+	}
+	source := pods[0]
+
+	exec := func(name, target, command string) model.NetworkCheck {
+		check := model.NetworkCheck{Name: name, From: source.node, Target: target}
+		result, err := runKubectlIdempotent(client, fmt.Sprintf("kubectl exec -n %s %s -- %s", canaryNamespace, source.name, command))
+		if err != nil {
+			check.Message = commandError(result, err)
+			return check
+		}
+		check.Passed = true
+		check.Message = strings.TrimSpace(result.Stdout)
+		return check
+	}
+
+	checks := []model.NetworkCheck{
+		exec(model.NetworkCheckDNSInternal, "kubernetes.default.svc.cluster.local", "getent hosts kubernetes.default.svc.cluster.local"),
+		exec(model.NetworkCheckDNSExternal, externalName, "getent hosts "+ssh.Quote(externalName)),
+		exec(model.NetworkCheckClusterIP, "canary."+canaryNamespace+".svc", "curl -fsS -o /dev/null -w '%{http_code}' --max-time 5 http://canary."+canaryNamespace+".svc/"),
+	}
+	if len(pods) == 1 {
+		checks = append(checks, model.NetworkCheck{Name: model.NetworkCheckPodNetwork, From: source.node, Passed: true, Skipped: true, Message: "集群只有一个节点，跳过跨节点检查"})
+	}
+	for _, pod := range pods[1:] {
+		check := exec(model.NetworkCheckPodNetwork, pod.node+" ("+pod.ip+")", "curl -fsS -o /dev/null -w '%{http_code}' --max-time 5 http://"+pod.ip+"/")
+		if !check.Passed {
+			check.Message += "（检查节点间 VXLAN 端口是否放行：flannel、Cilium 为 8472/udp，Calico 为 4789/udp）"
+		}
+		checks = append(checks, check)
+	}
+
+	nodePorts, err := m.nodePortChecks(client)
+	if err != nil {
+		return nil, err
+	}
+	checks = append(checks, nodePorts...)
+
+	if checkPolicy {
+		if len(pods) == 1 {
+			checks = append(checks, model.NetworkCheck{Name: model.NetworkCheckPolicy, From: source.node, Passed: true, Skipped: true, Message: "集群只有一个节点，跳过网络策略检查"})
+		} else {
+			checks = append(checks, m.policyCheck(client, ws, source, pods[len(pods)-1]))
+		}
+	}
+
+	for _, check := range checks {
+		if check.Passed {
+			m.logger.Infof("网络检查 %s %s -> %s 通过", check.Name, check.From, check.Target)
+		} else {
+			m.logger.Warnf("网络检查 %s %s -> %s 失败: %s", check.Name, check.From, check.Target, check.Message)
+		}
+	}
+	return checks, nil
+}
+
+// nodePortChecks 由后端直接访问每个节点 InternalIP 上的 NodePort
+func (m *Manager) nodePortChecks(client *ssh.Client) ([]model.NetworkCheck, error) {
+	result, err := runKubectlIdempotent(client, "kubectl get service canary -n "+canaryNamespace+" -o jsonpath='{.spec.ports[0].nodePort}'")
+	if err != nil || strings.TrimSpace(result.Stdout) == "" {
+		return nil, fmt.Errorf("获取网络检查 NodePort 失败: %v", err)
+	}
+	port := strings.TrimSpace(result.Stdout)
+
+	nodes, err := m.ListNodes(client)
+	if err != nil {
+		return nil, err
+	}
+	httpClient := &http.Client{Timeout: 5 * time.Second}
+	var checks []model.NetworkCheck
+	for _, node := range nodes {
+		url := fmt.Sprintf("http://%s:%s/", node.InternalIP, port)
+		check := model.NetworkCheck{Name: model.NetworkCheckNodePort, From: "backend", Target: node.Name + " (" + url + ")"}
+		resp, err := httpClient.Get(url)
+		if err != nil {
+			check.Message = fmt.Sprintf("%v（检查防火墙是否放行 NodePort 范围 30000-32767）", err)
+		} else {
+			resp.Body.Close()
+			check.Passed = resp.StatusCode == http.StatusOK
+			check.Message = resp.Status
+		}
+		checks = append(checks, check)
+	}
+	return checks, nil
+}
+
+// commandError 命令失败时优先使用 stderr 作为说明
+func commandError(result *ssh.CommandResult, err error) string {
+	if result != nil {
+		if stderr := strings.TrimSpace(result.Stderr); stderr != "" {
+			return stderr
+		}
+	}
+	return err.Error()
+}
@@ -0,0 +1,203 @@
+package k3s
+
+import (
+	"fmt"
+	"net"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// policyEnforceTimeout 网络检查中等待网络策略控制器同步规则的最长时间
+const policyEnforceTimeout = 30 * time.Second
+
+// NetworkPolicy 实例命名空间的默认拒绝策略。入站始终只允许同命名空间和应用端口，
+// DenyEgress 时出站只允许同命名空间、集群 DNS、EgressCIDRs 和（ObjectStorage 时）集群内 MinIO；
+// ManagedDatabase 时另外允许 CloudNativePG operator 访问数据库实例
+type NetworkPolicy struct {
+	DenyEgress      bool
+	EgressCIDRs     []string
+	ObjectStorage   bool
+	ManagedDatabase bool
+}
+
+// Validate 检查出站网段格式
+func (p NetworkPolicy) Validate() error {
+	for _, cidr := range p.EgressCIDRs {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return fmt.Errorf("无效的出站网段: %s", cidr)
+		}
+	}
+	return nil
+}
+
+func (p NetworkPolicy) manifest(namespace string) string {
+	policyTypes := "[Ingress]"
+	sameNamespace := `  ingress:
+  - from:
+    - podSelector: {}
+`
+	if p.DenyEgress {
+		policyTypes = "[Ingress, Egress]"
+		sameNamespace += `  egress:
+  - to:
+    - podSelector: {}
+`
+	}
+
+	var b strings.Builder
+	header := func(name string) {
+		fmt.Fprintf(&b, `---
+apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: %s
+  namespace: %s
+  labels:
+    %s: %s
+spec:
+`, name, namespace, ManagedByLabel, ManagedBy)
+	}
+
+	header("default-deny")
+	fmt.Fprintf(&b, "  podSelector: {}\n  policyTypes: %s\n", policyTypes)
+	header("allow-same-namespace")
+	fmt.Fprintf(&b, "  podSelector: {}\n%s", sameNamespace)
+	// 应用端口对所有来源开放，NodePort、Ingress 和 LoadBalancer 访问方式都经过该端口
+	header("allow-app-ingress")
+	b.WriteString(`  podSelector:
+    matchLabels:
+      app: insuite-app
+  ingress:
+  - ports:
+    - port: 80
+      protocol: TCP
+`)
+	if p.ManagedDatabase {
+		// operator 通过实例的 8000 端口读取状态、5432 端口管理数据库
+		header("allow-database-operator")
+		fmt.Fprintf(&b, `  podSelector:
+    matchLabels:
+      cnpg.io/cluster: %s
+  ingress:
+  - from:
+    - namespaceSelector:
+        matchLabels:
+          kubernetes.io/metadata.name: %s
+    ports:
+    - port: 8000
+      protocol: TCP
+    - port: 5432
+      protocol: TCP
+`, databaseCluster, DatabaseOperatorNamespace)
+	}
+	if !p.DenyEgress {
+		return b.String()
+	}
+
+	header("allow-dns-egress")
+	b.WriteString(`  podSelector: {}
+  policyTypes: [Egress]
+  egress:
+  - to:
+    - namespaceSelector:
+        matchLabels:
+          kubernetes.io/metadata.name: kube-system
+    ports:
+    - port: 53
+      protocol: UDP
+    - port: 53
+      protocol: TCP
+`)
+	if p.ObjectStorage {
+		header("allow-object-storage-egress")
+		fmt.Fprintf(&b, `  podSelector: {}
+  policyTypes: [Egress]
+  egress:
+  - to:
+    - namespaceSelector:
+        matchLabels:
+          kubernetes.io/metadata.name: %s
+    ports:
+    - port: 9000
+      protocol: TCP
+`, MinIONamespace)
+	}
+	if len(p.EgressCIDRs) > 0 {
+		header("allow-egress-cidrs")
+		b.WriteString("  podSelector: {}\n  policyTypes: [Egress]\n  egress:\n  - to:\n")
+		for _, cidr := range p.EgressCIDRs {
+			fmt.Fprintf(&b, "    - ipBlock:\n        cidr: %s\n", cidr)
+		}
+	}
+	return b.String()
+}
+
+// applyNetworkPolicy 用本次的策略替换命名空间中由本服务管理的网络策略，policy 为 nil 时只删除
+func (m *Manager) applyNetworkPolicy(client *ssh.Client, ws *Workspace, namespace string, policy *NetworkPolicy) error {
+	selector := ManagedByLabel + "=" + ManagedBy
+	if _, err := runKubectl(client, fmt.Sprintf("kubectl -n %s delete networkpolicy -l %s --ignore-not-found", namespace, selector)); err != nil {
+		return fmt.Errorf("删除网络策略失败: %v", err)
+	}
+	if policy == nil {
+		return nil
+	}
+
+	file, err := ws.Upload("insuite-network-policy.yaml", policy.manifest(namespace))
+	if err != nil {
+		return fmt.Errorf("上传网络策略失败: %v", err)
+	}
+	if _, err := runKubectl(client, "kubectl apply -f "+file); err != nil {
+		return fmt.Errorf("应用网络策略失败: %v", err)
+	}
+	m.logger.Infof("命名空间 %s 已应用默认拒绝网络策略（限制出站: %v）", namespace, policy.DenyEgress)
+	return nil
+}
+
+// canaryDenyPolicy 拒绝所有入站流量，只选中被标记为检查目标的 canary Pod
+const canaryDenyPolicy = `apiVersion: networking.k8s.io/v1
+kind: NetworkPolicy
+metadata:
+  name: canary-deny
+  namespace: %s
+spec:
+  podSelector:
+    matchLabels:
+      canary-role: target
+  policyTypes: [Ingress]
+`
+
+// policyCheck 验证网络策略控制器生效：对目标 Pod 应用拒绝入站策略后，源 Pod 应在 policyEnforceTimeout 内无法访问目标
+func (m *Manager) policyCheck(client *ssh.Client, ws *Workspace, source, target canaryPod) model.NetworkCheck {
+	check := model.NetworkCheck{Name: model.NetworkCheckPolicy, From: source.node, Target: target.node + " (" + target.ip + ")"}
+
+	if _, err := runKubectl(client, fmt.Sprintf("kubectl label pod %s -n %s canary-role=target --overwrite", target.name, canaryNamespace)); err != nil {
+		check.Message = fmt.Sprintf("标记检查目标 Pod 失败: %v", err)
+		return check
+	}
+	file, err := ws.Upload("network-canary-policy.yaml", fmt.Sprintf(canaryDenyPolicy, canaryNamespace))
+	if err == nil {
+		_, err = runKubectl(client, "kubectl apply -f "+file)
+	}
+	if err != nil {
+		check.Message = fmt.Sprintf("应用检查策略失败: %v", err)
+		return check
+	}
+
+	cmd := fmt.Sprintf("kubectl exec -n %s %s -- curl -fsS -o /dev/null --max-time 3 http://%s/", canaryNamespace, source.name, target.ip)
+	deadline := time.Now().Add(policyEnforceTimeout)
+	for {
+		if _, err := runKubectlIdempotent(client, cmd); err != nil {
+			check.Passed = true
+			check.Message = "拒绝策略生效，访问被阻断"
+			return check
+		}
+		if time.Now().After(deadline) {
+			check.Message = fmt.Sprintf("应用拒绝策略 %s 后仍可访问，网络策略控制器未生效（k3s 是否以 --disable-network-policy 安装）", policyEnforceTimeout)
+			return check
+		}
+		time.Sleep(2 * time.Second)
+	}
+}
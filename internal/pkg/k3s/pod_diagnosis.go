@@ -0,0 +1,229 @@
+package k3s
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// podStatusList kubectl get pods -o json 中用于诊断的状态字段
+type podStatusList struct {
+	Items []struct {
+		Metadata struct {
+			Name string `json:"name"`
+		} `json:"metadata"`
+		Status struct {
+			Phase      string `json:"phase"`
+			Conditions []struct {
+				Type    string `json:"type"`
+				Status  string `json:"status"`
+				Reason  string `json:"reason"`
+				Message string `json:"message"`
+			} `json:"conditions"`
+			ContainerStatuses []struct {
+				Name         string `json:"name"`
+				Ready        bool   `json:"ready"`
+				RestartCount int    `json:"restartCount"`
+				State        struct {
+					Waiting *struct {
+						Reason  string `json:"reason"`
+						Message string `json:"message"`
+					} `json:"waiting"`
+				} `json:"state"`
+				LastState struct {
+					Terminated *struct {
+						Reason   string `json:"reason"`
+						ExitCode int    `json:"exitCode"`
+					} `json:"terminated"`
+				} `json:"lastState"`
+			} `json:"containerStatuses"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+// PodFinding 一个未就绪 Pod 的问题及处理建议
+type PodFinding struct {
+	Pod     string
+	Problem string
+	Detail  string
+	Hint    string
+}
+
+func (f PodFinding) String() string {
+	s := fmt.Sprintf("Pod %s: %s", f.Pod, f.Problem)
+	if f.Detail != "" {
+		s += "（" + f.Detail + "）"
+	}
+	if f.Hint != "" {
+		s += "，" + f.Hint
+	}
+	return s
+}
+
+// RolloutError 组件未能就绪，携带对未就绪 Pod 的诊断和 kubectl describe 输出
+type RolloutError struct {
+	Deployment string
+	Cause      string
+	Findings   []PodFinding
+	Describe   string
+}
+
+func (e *RolloutError) Error() string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "组件 %s %s", e.Deployment, e.Cause)
+	if len(e.Findings) > 0 {
+		b.WriteString("，诊断结果:")
+		for _, f := range e.Findings {
+			b.WriteString("\n- " + f.String())
+		}
+	}
+	return b.String()
+}
+
+// Diagnostics 返回未就绪 Pod 的 describe 输出，参与失败分类
+func (e *RolloutError) Diagnostics() string {
+	return e.Describe
+}
+
+// rolloutError 诊断未就绪的 Pod 并构造错误，describe 输出只写入日志
+func (m *Manager) rolloutError(client *ssh.Client, namespace, deployment, cause string) error {
+	findings, describe := m.diagnosePods(client, namespace, deployment)
+	if describe != "" {
+		m.logger.Warnf("组件 %s 未就绪的 Pod:\n%s", deployment, describe)
+	}
+	return &RolloutError{Deployment: deployment, Cause: cause, Findings: findings, Describe: describe}
+}
+
+// schedulingCauses FailedScheduling 消息中的常见原因及处理建议
+var schedulingCauses = []struct {
+	pattern string
+	problem string
+	hint    string
+}{
+	{"Insufficient memory", "节点内存不足", "降低组件内存 requests 或扩容/增加带角色标签的节点"},
+	{"Insufficient cpu", "节点 CPU 不足", "降低组件 CPU requests 或扩容/增加带角色标签的节点"},
+	{"unbound immediate PersistentVolumeClaims", "PVC 未绑定", "检查存储类是否可用（kubectl get pvc,sc）"},
+	{"volume node affinity conflict", "卷所在节点与 Pod 可调度节点冲突", "本地卷只能在创建它的节点上使用"},
+	{"didn't match Pod's node affinity/selector", "没有节点匹配 nodeSelector", "检查节点是否带 insuite.<角色>=true 标签"},
+	{"untolerated taint", "节点污点未被容忍", "移除节点污点或改用其他节点"},
+	{"didn't match pod anti-affinity rules", "反亲和规则无法满足", "增加带角色标签的节点或改用 preferred 反亲和"},
+	{"node(s) were unschedulable", "节点已禁止调度", "对节点执行 kubectl uncordon"},
+	{"Too many pods", "节点 Pod 数量已达上限", "增加节点或调大 kubelet max-pods"},
+}
+
+// waitingHints 容器等待原因对应的处理建议
+var waitingHints = map[string]string{
+	"ImagePullBackOff":           "确认镜像名称和标签正确、节点可以访问镜像仓库，私有仓库需配置 registries.yaml",
+	"ErrImagePull":               "确认镜像名称和标签正确、节点可以访问镜像仓库，私有仓库需配置 registries.yaml",
+	"InvalidImageName":           "镜像名称格式无效",
+	"CrashLoopBackOff":           "容器启动后反复退出，查看 kubectl logs --previous 中的应用日志",
+	"CreateContainerConfigError": "引用的 ConfigMap 或 Secret 不存在或键名错误",
+	"CreateContainerError":       "容器创建失败，查看节点 containerd 日志",
+}
+
+// diagnosePods 分析 Deployment 下未就绪 Pod 的状态与相关事件，找出调度失败、镜像拉取失败、
+// 反复崩溃、OOMKilled 和卷挂载失败等原因
+func (m *Manager) diagnosePods(client *ssh.Client, namespace, deployment string) ([]PodFinding, string) {
+	var findings []PodFinding
+
+	result, err := runKubectlIdempotent(client, fmt.Sprintf("kubectl get pods -n %s -l app=%s -o json", namespace, deployment))
+	if err != nil {
+		m.logger.Warnf("读取组件 %s 的 Pod 状态失败: %v", deployment, err)
+		return nil, ""
+	}
+	var pods podStatusList
+	if err := json.Unmarshal([]byte(result.Stdout), &pods); err != nil {
+		m.logger.Warnf("解析组件 %s 的 Pod 状态失败: %v", deployment, err)
+		return nil, ""
+	}
+	if len(pods.Items) == 0 {
+		findings = append(findings, PodFinding{Pod: deployment + "-*", Problem: "没有创建任何 Pod", Hint: "查看 kubectl describe deployment 中的 ReplicaSet 事件（可能超出命名空间资源配额）"})
+	}
+
+	warnings := m.podWarnings(client, namespace)
+	for _, pod := range pods.Items {
+		name := pod.Metadata.Name
+		before := len(findings)
+
+		for _, cond := range pod.Status.Conditions {
+			if cond.Type != "PodScheduled" || cond.Status != "False" {
+				continue
+			}
+			matched := false
+			for _, cause := range schedulingCauses {
+				if strings.Contains(cond.Message, cause.pattern) {
+					findings = append(findings, PodFinding{Pod: name, Problem: "调度失败: " + cause.problem, Detail: cond.Message, Hint: cause.hint})
+					matched = true
+				}
+			}
+			if !matched {
+				findings = append(findings, PodFinding{Pod: name, Problem: "调度失败", Detail: cond.Message})
+			}
+		}
+
+		for _, status := range pod.Status.ContainerStatuses {
+			if status.Ready {
+				continue
+			}
+			if term := status.LastState.Terminated; term != nil && term.Reason == "OOMKilled" {
+				findings = append(findings, PodFinding{Pod: name, Problem: fmt.Sprintf("容器 %s 内存超出限制被终止（OOMKilled，已重启 %d 次）", status.Name, status.RestartCount),
+					Hint: "调大组件内存 limits"})
+				continue
+			}
+			if waiting := status.State.Waiting; waiting != nil && waitingHints[waiting.Reason] != "" {
+				findings = append(findings, PodFinding{Pod: name, Problem: fmt.Sprintf("容器 %s 处于 %s", status.Name, waiting.Reason),
+					Detail: waiting.Message, Hint: waitingHints[waiting.Reason]})
+			}
+		}
+
+		// 状态中看不出原因时使用 kubelet 和调度器的 Warning 事件（如 FailedMount、FailedCreatePodSandBox）
+		if len(findings) == before && pod.Status.Phase != "Running" {
+			for _, event := range warnings[name] {
+				findings = append(findings, PodFinding{Pod: name, Problem: event.Reason, Detail: event.Message})
+			}
+		}
+	}
+
+	describe := ""
+	if result, err := runKubectlIdempotent(client, fmt.Sprintf("kubectl describe pods -n %s -l app=%s | tail -n 40", namespace, deployment)); err == nil {
+		describe = result.Stdout
+	}
+	return findings, describe
+}
+
+// podWarning Pod 的 Warning 事件
+type podWarning struct {
+	Reason  string
+	Message string
+}
+
+// podWarnings 命名空间中每个 Pod 最近的 Warning 事件（按原因去重，最多 3 条）
+func (m *Manager) podWarnings(client *ssh.Client, namespace string) map[string][]podWarning {
+	warnings := make(map[string][]podWarning)
+	result, err := runKubectlIdempotent(client, fmt.Sprintf("kubectl get events -n %s --field-selector type=Warning,involvedObject.kind=Pod -o json", namespace))
+	if err != nil {
+		return warnings
+	}
+	var list eventList
+	if err := json.Unmarshal([]byte(result.Stdout), &list); err != nil {
+		return warnings
+	}
+	items := list.Items
+	sort.SliceStable(items, func(i, j int) bool {
+		return firstTime(items[i].LastTimestamp, items[i].EventTime).After(firstTime(items[j].LastTimestamp, items[j].EventTime))
+	})
+	seen := make(map[string]bool)
+	for _, item := range items {
+		pod := item.InvolvedObject.Name
+		key := pod + "/" + item.Reason
+		if seen[key] || len(warnings[pod]) >= 3 {
+			continue
+		}
+		seen[key] = true
+		warnings[pod] = append(warnings[pod], podWarning{Reason: item.Reason, Message: strings.TrimSpace(item.Message)})
+	}
+	return warnings
+}
@@ -0,0 +1,31 @@
+package k3s
+
+import (
+	"fmt"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/benchmark"
+	"k3s-deploy-backend/internal/pkg/progress"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// trackPhase 记录阶段耗时（启用基准测试时）并发布阶段开始事件，调用返回的函数发布结束事件
+func trackPhase(client *ssh.Client, node, phase string) func() {
+	stopBenchmark := benchmark.Track(client.RequestID(), node, phase)
+	stopProgress := progress.Phase(client.RequestID(), node, phase)
+	return func() {
+		stopBenchmark()
+		stopProgress()
+	}
+}
+
+// report 发布节点进度事件，node 为空表示集群级操作
+func report(client *ssh.Client, node, level, format string, args ...interface{}) {
+	progress.Publish(model.ProgressEvent{
+		Type:      model.ProgressNode,
+		RequestID: client.RequestID(),
+		Node:      node,
+		Level:     level,
+		Message:   fmt.Sprintf(format, args...),
+	})
+}
@@ -0,0 +1,127 @@
+package k3s
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// WaitNodesReady 等待期望的节点以期望的名称注册并 Ready。expected 中每项需填写 Name、IP 和 ClusterName，
+// 返回期望节点和集群中多出的节点的状态。只剩名称不一致的节点时不再等待
+func (m *Manager) WaitNodesReady(client *ssh.Client, expected []model.NodeReadiness, timeout, interval time.Duration) ([]model.NodeReadiness, error) {
+	m.logger.Infof("等待 %d 个节点加入集群并 Ready", len(expected))
+	deadline := time.Now().Add(timeout)
+	var results []model.NodeReadiness
+	for {
+		nodes, err := m.ListNodes(client)
+		if err == nil {
+			results = nodeReadiness(expected, nodes)
+			if !waitingFor(results) {
+				break
+			}
+		} else {
+			m.logger.Warnf("读取节点列表失败: %v", err)
+		}
+		if time.Now().Add(interval).After(deadline) {
+			if results == nil {
+				return nil, fmt.Errorf("等待节点就绪超时（%s）: %v", timeout, err)
+			}
+			break
+		}
+		time.Sleep(interval)
+	}
+
+	for _, r := range results {
+		if r.Status == model.NodeUnexpected {
+			m.logger.Warnf("集群中存在请求之外的节点 %s (%s)", r.RegisteredAs, r.IP)
+		}
+	}
+	if err := readinessError(results); err != nil {
+		return results, err
+	}
+	m.logger.Info("所有节点已 Ready")
+	return results, nil
+}
+
+// nodeReadiness 将期望节点与集群节点配对：先匹配名称和 IP 都一致的节点，再按 IP 匹配（名称不一致），
+// 最后只按名称匹配（节点经 NAT 访问时请求中的 IP 与 InternalIP 不同）
+func nodeReadiness(expected []model.NodeReadiness, nodes []model.ClusterNode) []model.NodeReadiness {
+	results := make([]model.NodeReadiness, len(expected))
+	registered := make([]bool, len(expected))
+	matched := make([]bool, len(nodes))
+	passes := []func(e model.NodeReadiness, n model.ClusterNode) bool{
+		func(e model.NodeReadiness, n model.ClusterNode) bool {
+			return n.Name == e.ClusterName && n.InternalIP == e.IP
+		},
+		func(e model.NodeReadiness, n model.ClusterNode) bool { return n.InternalIP == e.IP },
+		func(e model.NodeReadiness, n model.ClusterNode) bool { return n.Name == e.ClusterName },
+	}
+	for _, match := range passes {
+		for i, e := range expected {
+			if registered[i] {
+				continue
+			}
+			for j, n := range nodes {
+				if matched[j] || !match(e, n) {
+					continue
+				}
+				registered[i], matched[j] = true, true
+				results[i] = e
+				results[i].RegisteredAs = n.Name
+				switch {
+				case n.Name != e.ClusterName:
+					results[i].Status = model.NodeMisnamed
+				case n.Ready:
+					results[i].Status = model.NodeReady
+				default:
+					results[i].Status = model.NodeNotReady
+				}
+				break
+			}
+		}
+	}
+	for i, e := range expected {
+		if !registered[i] {
+			results[i] = e
+			results[i].Status = model.NodeMissing
+		}
+	}
+	for j, n := range nodes {
+		if !matched[j] {
+			results = append(results, model.NodeReadiness{IP: n.InternalIP, RegisteredAs: n.Name, Status: model.NodeUnexpected})
+		}
+	}
+	return results
+}
+
+// waitingFor 有节点未注册或未 Ready 时需要继续等待
+func waitingFor(results []model.NodeReadiness) bool {
+	for _, r := range results {
+		if r.Status == model.NodeMissing || r.Status == model.NodeNotReady {
+			return true
+		}
+	}
+	return false
+}
+
+// readinessError 汇总未就绪的期望节点，多出的节点只告警
+func readinessError(results []model.NodeReadiness) error {
+	var messages []string
+	for _, r := range results {
+		switch r.Status {
+		case model.NodeNotReady:
+			messages = append(messages, fmt.Sprintf("节点 %s 已注册为 %s 但未 Ready", r.Name, r.RegisteredAs))
+		case model.NodeMissing:
+			messages = append(messages, fmt.Sprintf("节点 %s (%s) 未加入集群（期望名称 %s）", r.Name, r.IP, r.ClusterName))
+		case model.NodeMisnamed:
+			messages = append(messages, fmt.Sprintf("节点 %s (%s) 以非预期的名称 %s 注册（期望 %s）", r.Name, r.IP, r.RegisteredAs, r.ClusterName))
+		}
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("节点就绪检查失败: %s", strings.Join(messages, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,128 @@
+package k3s
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// ScriptSource 自定义安装脚本（fork 或内网镜像），URL 与 Content 二选一
+type ScriptSource struct {
+	URL     string
+	Content string
+	// SHA256 脚本内容（修改前）的校验和，设置后不匹配即中止安装
+	SHA256 string
+	// PatchRegistry 注入 setup_registry 调用，要求脚本与国内镜像脚本一样定义了 setup_registry 函数
+	PatchRegistry bool
+	// PatchCertConfig 写入客户端证书有效期配置
+	PatchCertConfig bool
+}
+
+// Validate 校验脚本来源与校验和格式
+func (s *ScriptSource) Validate() error {
+	if (s.URL == "") == (s.Content == "") {
+		return fmt.Errorf("自定义安装脚本必须且只能设置 URL 或脚本内容之一")
+	}
+	if s.URL != "" && !strings.HasPrefix(s.URL, "https://") && !strings.HasPrefix(s.URL, "http://") {
+		return fmt.Errorf("安装脚本 URL 必须以 http:// 或 https:// 开头")
+	}
+	if s.SHA256 != "" {
+		if b, err := hex.DecodeString(s.SHA256); err != nil || len(b) != sha256.Size {
+			return fmt.Errorf("安装脚本 SHA256 校验和格式无效")
+		}
+	}
+	return nil
+}
+
+// loadScript 获取安装脚本：未指定自定义脚本时按 installURL 下载，并按需校验 SHA256
+func (i *Installer) loadScript(installURL string, custom *ScriptSource, timeout time.Duration) ([]byte, error) {
+	if custom == nil {
+		return downloadScript(installURL, timeout)
+	}
+	if err := custom.Validate(); err != nil {
+		return nil, err
+	}
+
+	var script []byte
+	if custom.Content != "" {
+		i.logger.Info("使用请求中提供的自定义安装脚本")
+		script = []byte(custom.Content)
+	} else {
+		i.logger.Infof("使用自定义安装脚本 URL: %s", custom.URL)
+		var err error
+		if script, err = downloadScript(custom.URL, timeout); err != nil {
+			return nil, err
+		}
+	}
+
+	sum := sha256.Sum256(script)
+	actual := hex.EncodeToString(sum[:])
+	if custom.SHA256 == "" {
+		i.logger.Warnf("自定义安装脚本未固定校验和，当前 SHA256: %s", actual)
+	} else if !strings.EqualFold(custom.SHA256, actual) {
+		return nil, fmt.Errorf("安装脚本校验和不匹配: 期望 %s，实际 %s", strings.ToLower(custom.SHA256), actual)
+	} else {
+		i.logger.Infof("安装脚本 SHA256 校验通过: %s", actual)
+	}
+	if custom.PatchRegistry && !bytes.Contains(script, []byte("setup_registry()")) {
+		return nil, fmt.Errorf("自定义安装脚本未定义 setup_registry 函数，无法应用镜像源补丁")
+	}
+	return script, nil
+}
+
+// scriptPatches 决定对脚本应用的修改：官方脚本只写证书配置，国内镜像脚本额外注入镜像源设置，自定义脚本按请求选择
+func scriptPatches(installURL string, custom *ScriptSource) ModifyOptions {
+	opts := ModifyOptions{
+		ClientExpirationYears: clientExpirationYears,
+		DaysInYear:            daysInYear,
+	}
+	switch {
+	case custom != nil:
+		opts.EnableRegistry = custom.PatchRegistry
+		opts.EnableCertConfig = custom.PatchCertConfig
+	case installURL == officialInstallURL:
+		opts.EnableCertConfig = true
+	case installURL == officialCNInstallURL:
+		opts.EnableRegistry = true
+		opts.EnableCertConfig = true
+	}
+	return opts
+}
+
+// downloadScript 下载安装脚本，timeout 限制连接、响应和读取内容的总时长
+func downloadScript(url string, timeout time.Duration) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, fmt.Errorf("下载安装脚本失败: %v", err)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("下载安装脚本超时（%s）: %s", timeout, url)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("下载安装脚本失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("下载脚本失败: HTTP %d", resp.StatusCode)
+	}
+
+	script, err := io.ReadAll(resp.Body)
+	if errors.Is(err, context.DeadlineExceeded) {
+		return nil, fmt.Errorf("下载安装脚本超时（%s）: %s", timeout, url)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取脚本内容失败: %v", err)
+	}
+	return script, nil
+}
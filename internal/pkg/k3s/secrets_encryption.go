@@ -0,0 +1,118 @@
+package k3s
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// 密钥轮换阶段：start 表示未轮换过，reencrypt_finished 表示上一次轮换已完成，其余阶段表示轮换进行中
+const (
+	encryptionStageStart    = "start"
+	encryptionStageFinished = "reencrypt_finished"
+)
+
+// parseSecretsEncryptionStatus 解析 k3s secrets-encrypt status 的输出：
+//
+//	Encryption Status: Enabled
+//	Current Rotation Stage: start
+//	Server Encryption Hashes: All hashes match
+//
+//	Active  Key Type  Name
+//	------  --------  ----
+//	 *      AES-CBC   aescbckey
+func parseSecretsEncryptionStatus(output string) *model.SecretsEncryptionStatus {
+	status := &model.SecretsEncryptionStatus{}
+	keys := false
+	for _, line := range strings.Split(output, "\n") {
+		trimmed := strings.TrimSpace(line)
+		switch {
+		case strings.HasPrefix(trimmed, "Encryption Status:"):
+			status.Enabled = strings.HasPrefix(strings.TrimSpace(strings.TrimPrefix(trimmed, "Encryption Status:")), "Enabled")
+		case strings.HasPrefix(trimmed, "Current Rotation Stage:"):
+			status.Stage = strings.TrimSpace(strings.TrimPrefix(trimmed, "Current Rotation Stage:"))
+		case strings.HasPrefix(trimmed, "Server Encryption Hashes:"):
+			hashes := strings.TrimSpace(strings.TrimPrefix(trimmed, "Server Encryption Hashes:"))
+			status.HashesMatch = strings.Contains(hashes, "All hashes match")
+			if !status.HashesMatch {
+				status.Hashes = hashes
+			}
+		case strings.HasPrefix(trimmed, "------"):
+			keys = true
+		case keys && trimmed != "":
+			fields := strings.Fields(trimmed)
+			key := model.EncryptionKey{}
+			if fields[0] == "*" {
+				key.Active = true
+				fields = fields[1:]
+			}
+			if len(fields) >= 2 {
+				key.Type, key.Name = fields[0], fields[1]
+				status.Keys = append(status.Keys, key)
+			}
+		}
+	}
+	return status
+}
+
+// activeKey 返回当前用于加密的密钥名
+func activeKey(status *model.SecretsEncryptionStatus) string {
+	for _, key := range status.Keys {
+		if key.Active {
+			return key.Name
+		}
+	}
+	return ""
+}
+
+// SecretsEncryptionStatus 在 Server 节点上读取 Secret 加密状态
+func (m *Manager) SecretsEncryptionStatus(client *ssh.Client) (*model.SecretsEncryptionStatus, error) {
+	result, err := client.ExecuteIdempotentCommand("k3s secrets-encrypt status")
+	if err != nil {
+		return nil, fmt.Errorf("读取 Secret 加密状态失败: %v", err)
+	}
+	return parseSecretsEncryptionStatus(result.Stdout), nil
+}
+
+// RotateSecretsEncryptionKeys 轮换 Secret 加密密钥：生成新密钥并用其重新加密全部 Secret，
+// 等待轮换完成后返回新的状态。需要 k3s v1.28 及以上版本的 rotate-keys 命令，执行期间 k3s 不重启
+func (m *Manager) RotateSecretsEncryptionKeys(client *ssh.Client, policy WaitPolicy) (*model.SecretsEncryptionStatus, error) {
+	policy = policy.WithDefaults()
+	status, err := m.SecretsEncryptionStatus(client)
+	if err != nil {
+		return nil, err
+	}
+	if !status.Enabled {
+		return nil, fmt.Errorf("集群未启用 Secret 加密（需在安装时设置 security.secretsEncryption）")
+	}
+	if status.Stage != encryptionStageStart && status.Stage != encryptionStageFinished {
+		return nil, fmt.Errorf("上一次密钥轮换尚未完成（阶段 %s）", status.Stage)
+	}
+	previous := activeKey(status)
+
+	m.logger.Infof("开始轮换 Secret 加密密钥（当前密钥 %s）", previous)
+	if _, err := client.ExecuteCommand("k3s secrets-encrypt rotate-keys"); err != nil {
+		return nil, fmt.Errorf("轮换 Secret 加密密钥失败: %v", err)
+	}
+
+	// 重新加密在后台进行，完成后阶段变为 reencrypt_finished 且启用新密钥
+	deadline := time.Now().Add(policy.DeploymentTimeout)
+	for {
+		status, err = m.SecretsEncryptionStatus(client)
+		if err == nil && status.Stage == encryptionStageFinished && activeKey(status) != previous {
+			m.logger.Infof("Secret 加密密钥轮换完成，当前密钥 %s", activeKey(status))
+			return status, nil
+		}
+		if time.Now().After(deadline) {
+			stage := "未知"
+			if status != nil {
+				stage = status.Stage
+			}
+			return status, fmt.Errorf("等待重新加密完成超时（%s），当前阶段: %s", policy.DeploymentTimeout, stage)
+		}
+		time.Sleep(policy.PollInterval)
+	}
+}
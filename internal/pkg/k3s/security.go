@@ -0,0 +1,196 @@
+package k3s
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// PodSecurity 准入与审计配置文件。API Server 参数写入 config.yaml.d 下的独立文件，kube-apiserver-arg+ 追加而不覆盖其他参数
+const (
+	PodSecurityConfigPath = "/etc/rancher/k3s/pod-security.yaml"
+	AuditPolicyPath       = "/etc/rancher/k3s/audit-policy.yaml"
+	AuditLogPath          = "/var/lib/rancher/k3s/server/logs/audit.log"
+	SecurityDropInPath    = "/etc/rancher/k3s/config.yaml.d/80-k3s-deploy-security.yaml"
+)
+
+// namespacePattern Kubernetes 命名空间名（DNS 标签）
+var namespacePattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+// defaultAuditPolicy 内置审计策略：忽略健康检查、事件和 kube-proxy 的 watch，Secret 等敏感资源只记元数据，
+// 其余写操作记录请求体，读操作记录元数据
+const defaultAuditPolicy = `apiVersion: audit.k8s.io/v1
+kind: Policy
+omitStages:
+  - RequestReceived
+rules:
+  - level: None
+    nonResourceURLs: ["/healthz*", "/readyz*", "/livez*", "/version", "/metrics"]
+  - level: None
+    resources:
+      - group: ""
+        resources: ["events"]
+  - level: None
+    users: ["system:kube-proxy"]
+    verbs: ["watch"]
+  - level: Metadata
+    resources:
+      - group: ""
+        resources: ["secrets", "configmaps", "serviceaccounts/token"]
+      - group: "authentication.k8s.io"
+        resources: ["tokenreviews"]
+  - level: Request
+    verbs: ["create", "update", "patch", "delete", "deletecollection"]
+  - level: Metadata
+`
+
+// ValidateSecurity 校验安全选项，审计策略需为 audit.k8s.io/v1 Policy
+func ValidateSecurity(opts *model.SecurityOptions) error {
+	if opts == nil {
+		return nil
+	}
+	if psa := opts.PodSecurity; psa != nil {
+		for _, ns := range psa.Exemptions {
+			if !namespacePattern.MatchString(ns) {
+				return fmt.Errorf("无效的豁免命名空间: %q", ns)
+			}
+		}
+		for ns, level := range psa.Namespaces {
+			if !namespacePattern.MatchString(ns) {
+				return fmt.Errorf("无效的命名空间: %q", ns)
+			}
+			if !validPodSecurityLevel(level) {
+				return fmt.Errorf("命名空间 %s 的 PodSecurity 级别 %q 无效，应为 privileged、baseline 或 restricted", ns, level)
+			}
+		}
+	}
+	if audit := opts.Audit; audit != nil && audit.Policy != "" {
+		var policy struct {
+			APIVersion string `yaml:"apiVersion"`
+			Kind       string `yaml:"kind"`
+		}
+		if err := yaml.Unmarshal([]byte(audit.Policy), &policy); err != nil {
+			return fmt.Errorf("审计策略不是有效的 YAML: %v", err)
+		}
+		if policy.APIVersion != "audit.k8s.io/v1" || policy.Kind != "Policy" {
+			return fmt.Errorf("审计策略应为 audit.k8s.io/v1 Policy，实际为 %s %s", policy.APIVersion, policy.Kind)
+		}
+	}
+	return nil
+}
+
+func validPodSecurityLevel(level string) bool {
+	return level == model.PodSecurityPrivileged || level == model.PodSecurityBaseline || level == model.PodSecurityRestricted
+}
+
+// WriteSecurityConfig 在安装 k3s 前上传 PodSecurity 准入配置和审计策略，并写入引用它们的 API Server 参数与 secrets-encryption
+func (i *Installer) WriteSecurityConfig(client *ssh.Client, opts *model.SecurityOptions) error {
+	files := map[string]string{}
+	var args []string
+	if opts.PodSecurity != nil {
+		files[PodSecurityConfigPath] = podSecurityConfig(opts.PodSecurity)
+		args = append(args, "admission-control-config-file="+PodSecurityConfigPath)
+	}
+	if audit := opts.Audit; audit != nil {
+		policy := audit.Policy
+		if policy == "" {
+			policy = defaultAuditPolicy
+		}
+		files[AuditPolicyPath] = policy
+		args = append(args,
+			"audit-policy-file="+AuditPolicyPath,
+			"audit-log-path="+AuditLogPath,
+			fmt.Sprintf("audit-log-maxage=%d", orDefault(audit.MaxAgeDays, 30)),
+			fmt.Sprintf("audit-log-maxbackup=%d", orDefault(audit.MaxBackups, 10)),
+			fmt.Sprintf("audit-log-maxsize=%d", orDefault(audit.MaxSizeMB, 100)),
+		)
+	}
+	var dropIn string
+	if len(args) > 0 {
+		dropIn = "kube-apiserver-arg+:\n  - " + strings.Join(args, "\n  - ") + "\n"
+	}
+	if opts.SecretsEncryption {
+		dropIn += "secrets-encryption: true\n"
+	}
+	if dropIn == "" {
+		return nil
+	}
+	files[SecurityDropInPath] = dropIn
+
+	if _, err := client.ExecuteCommand("mkdir -p /etc/rancher/k3s/config.yaml.d"); err != nil {
+		return fmt.Errorf("创建 k3s 配置目录失败: %v", err)
+	}
+	for path, content := range files {
+		if err := client.UploadFile(content, path); err != nil {
+			return fmt.Errorf("上传 %s 失败: %v", path, err)
+		}
+		if _, err := client.ExecuteCommand("chmod 600 " + path); err != nil {
+			return fmt.Errorf("设置 %s 权限失败: %v", path, err)
+		}
+		i.logger.Infof("已写入 %s", path)
+	}
+	return nil
+}
+
+// podSecurityConfig 生成 PodSecurity 准入插件的 AdmissionConfiguration，kube-system 始终豁免
+func podSecurityConfig(opts *model.PodSecurityOptions) string {
+	exemptions := []string{"kube-system"}
+	for _, ns := range opts.Exemptions {
+		if ns != "kube-system" {
+			exemptions = append(exemptions, ns)
+		}
+	}
+	return fmt.Sprintf(`apiVersion: apiserver.config.k8s.io/v1
+kind: AdmissionConfiguration
+plugins:
+  - name: PodSecurity
+    configuration:
+      apiVersion: pod-security.admission.config.k8s.io/v1
+      kind: PodSecurityConfiguration
+      defaults:
+        enforce: %q
+        enforce-version: "latest"
+        audit: %q
+        audit-version: "latest"
+        warn: %q
+        warn-version: "latest"
+      exemptions:
+        usernames: []
+        runtimeClasses: []
+        namespaces: [%s]
+`, orDefaultLevel(opts.Enforce, model.PodSecurityBaseline), orDefaultLevel(opts.Audit, model.PodSecurityRestricted),
+		orDefaultLevel(opts.Warn, model.PodSecurityRestricted), strings.Join(exemptions, ", "))
+}
+
+// ApplyNamespaceSecurity 为命名空间设置 PodSecurity 级别标签（enforce、audit、warn 相同），命名空间不存在时创建
+func (m *Manager) ApplyNamespaceSecurity(client *ssh.Client, namespaces map[string]string) error {
+	for ns, level := range namespaces {
+		cmd := fmt.Sprintf("kubectl create namespace %[1]s --dry-run=client -o yaml | kubectl apply -f - && "+
+			"kubectl label namespace %[1]s --overwrite pod-security.kubernetes.io/enforce=%[2]s pod-security.kubernetes.io/audit=%[2]s pod-security.kubernetes.io/warn=%[2]s",
+			ns, level)
+		if _, err := runKubectl(client, cmd); err != nil {
+			return fmt.Errorf("设置命名空间 %s 的 PodSecurity 级别失败: %v", ns, err)
+		}
+		m.logger.Infof("命名空间 %s PodSecurity 级别: %s", ns, level)
+	}
+	return nil
+}
+
+func orDefault(value, fallback int) int {
+	if value > 0 {
+		return value
+	}
+	return fallback
+}
+
+func orDefaultLevel(level, fallback string) string {
+	if level != "" {
+		return level
+	}
+	return fallback
+}
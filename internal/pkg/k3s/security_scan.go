@@ -0,0 +1,415 @@
+package k3s
+
+import (
+	"encoding/json"
+	"fmt"
+	"regexp"
+	"slices"
+	"sort"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+const (
+	// scanNamespace 安全扫描 Job 所在的临时命名空间，扫描结束后删除。kube-bench 需要 hostPID 和主机目录，命名空间按 privileged 放行
+	scanNamespace = "k3s-deploy-scan"
+	// trivyCacheDir Master 上保存 trivy 漏洞库的目录，重复扫描时只增量更新
+	trivyCacheDir = "/var/lib/rancher/k3s-deploy/trivy-cache"
+	// maxImageVulnerabilities 每个镜像在报告中保留的 CRITICAL、HIGH 漏洞条数
+	maxImageVulnerabilities = 100
+)
+
+// imageRefPattern 可安全传入扫描脚本的镜像引用
+var imageRefPattern = regexp.MustCompile(`^[A-Za-z0-9][A-Za-z0-9._/:@-]*$`)
+
+// ScanConfig 安全扫描参数
+type ScanConfig struct {
+	KubeBenchImage string
+	// Benchmark kube-bench 使用的基准，如 k3s-cis-1.8
+	Benchmark  string
+	TrivyImage string
+	// DBRepository、JavaDBRepository trivy 漏洞库的 OCI 仓库，为空时使用 trivy 默认仓库；国内或内网环境指向同步的镜像
+	DBRepository     string
+	JavaDBRepository string
+	// Timeout kube-bench 与 trivy 各自的最长执行时间
+	Timeout time.Duration
+}
+
+// kubeBenchJob 在指定节点上运行 kube-bench 的 Job，只读挂载 k3s 的数据与配置目录、systemd 配置和日志
+func kubeBenchJob(name, node, targets string, cfg ScanConfig) string {
+	return fmt.Sprintf(`apiVersion: batch/v1
+kind: Job
+metadata:
+  name: %[1]s
+  namespace: %[2]s
+  labels:
+    app: kube-bench
+spec:
+  backoffLimit: 0
+  template:
+    metadata:
+      labels:
+        app: kube-bench
+    spec:
+      hostPID: true
+      nodeName: %[3]s
+      restartPolicy: Never
+      tolerations:
+      - operator: Exists
+      containers:
+      - name: kube-bench
+        image: %[4]s
+        command: ["kube-bench", "run", "--benchmark", "%[5]s", "--targets", "%[6]s", "--json"]
+        volumeMounts:
+        - {name: var-lib-rancher, mountPath: /var/lib/rancher, readOnly: true}
+        - {name: etc-rancher, mountPath: /etc/rancher, readOnly: true}
+        - {name: etc-systemd, mountPath: /etc/systemd, readOnly: true}
+        - {name: lib-systemd, mountPath: /lib/systemd, readOnly: true}
+        - {name: var-log, mountPath: /var/log, readOnly: true}
+        - {name: usr-local-bin, mountPath: /usr/local/mount-from-host/bin, readOnly: true}
+      volumes:
+      - {name: var-lib-rancher, hostPath: {path: /var/lib/rancher}}
+      - {name: etc-rancher, hostPath: {path: /etc/rancher}}
+      - {name: etc-systemd, hostPath: {path: /etc/systemd}}
+      - {name: lib-systemd, hostPath: {path: /lib/systemd}}
+      - {name: var-log, hostPath: {path: /var/log}}
+      - {name: usr-local-bin, hostPath: {path: /usr/local/bin}}
+`, name, scanNamespace, node, cfg.KubeBenchImage, cfg.Benchmark, targets)
+}
+
+// trivyScript 先更新漏洞库，再逐个扫描镜像：每个镜像输出一行 "=== <镜像>"，随后一行为去掉换行的 JSON 结果，
+// 失败时为 "!!! <错误>"。优先使用 Master 上 containerd 中已有的镜像，不存在时从仓库拉取
+func trivyScript(images []string) string {
+	return fmt.Sprintf(`trivy image --download-db-only --quiet || exit 1
+for img in %s; do
+  echo "=== $img"
+  if out=$(trivy image --skip-db-update --quiet --scanners vuln --format json --image-src containerd,remote "$img" 2>/tmp/err); then
+    echo "$out" | tr -d '\n'; echo
+  else
+    echo "!!! $(tr '\n' ' ' </tmp/err)"
+  fi
+done`, strings.Join(images, " "))
+}
+
+// trivyJob 在 Server 节点上运行 trivy 的 Job，漏洞库缓存在主机目录
+func trivyJob(node string, images []string, cfg ScanConfig) (string, error) {
+	env := []map[string]string{
+		{"name": "TRIVY_CACHE_DIR", "value": "/cache"},
+		{"name": "CONTAINERD_ADDRESS", "value": "/run/containerd/containerd.sock"},
+		{"name": "CONTAINERD_NAMESPACE", "value": "k8s.io"},
+	}
+	if cfg.DBRepository != "" {
+		env = append(env, map[string]string{"name": "TRIVY_DB_REPOSITORY", "value": cfg.DBRepository})
+	}
+	if cfg.JavaDBRepository != "" {
+		env = append(env, map[string]string{"name": "TRIVY_JAVA_DB_REPOSITORY", "value": cfg.JavaDBRepository})
+	}
+	job := map[string]any{
+		"apiVersion": "batch/v1",
+		"kind":       "Job",
+		"metadata":   map[string]any{"name": "trivy", "namespace": scanNamespace, "labels": map[string]string{"app": "trivy"}},
+		"spec": map[string]any{
+			"backoffLimit": 0,
+			"template": map[string]any{
+				"metadata": map[string]any{"labels": map[string]string{"app": "trivy"}},
+				"spec": map[string]any{
+					"nodeName":      node,
+					"restartPolicy": "Never",
+					"tolerations":   []map[string]string{{"operator": "Exists"}},
+					"containers": []map[string]any{{
+						"name":    "trivy",
+						"image":   cfg.TrivyImage,
+						"command": []string{"sh", "-c", trivyScript(images)},
+						"env":     env,
+						"volumeMounts": []map[string]any{
+							{"name": "cache", "mountPath": "/cache"},
+							{"name": "containerd", "mountPath": "/run/containerd/containerd.sock"},
+						},
+					}},
+					"volumes": []map[string]any{
+						{"name": "cache", "hostPath": map[string]string{"path": trivyCacheDir, "type": "DirectoryOrCreate"}},
+						{"name": "containerd", "hostPath": map[string]string{"path": "/run/k3s/containerd/containerd.sock", "type": "Socket"}},
+					},
+				},
+			},
+		},
+	}
+	data, err := json.Marshal(job)
+	if err != nil {
+		return "", err
+	}
+	return string(data), nil
+}
+
+// kubeBenchOutput kube-bench --json 的输出
+type kubeBenchOutput struct {
+	Controls []kubeBenchControls `json:"Controls"`
+}
+
+type kubeBenchControls struct {
+	Tests []struct {
+		Results []struct {
+			TestNumber  string `json:"test_number"`
+			TestDesc    string `json:"test_desc"`
+			Status      string `json:"status"`
+			Remediation string `json:"remediation"`
+		} `json:"results"`
+	} `json:"tests"`
+}
+
+// parseKubeBench 解析 kube-bench 的 JSON 输出，统计各状态数量，保留 FAIL 和 WARN 项
+func parseKubeBench(output string, result *model.BenchNodeResult) error {
+	output = strings.TrimSpace(output)
+	var parsed kubeBenchOutput
+	// 早期版本只有一个目标时直接输出控制项数组
+	if strings.HasPrefix(output, "[") {
+		if err := json.Unmarshal([]byte(output), &parsed.Controls); err != nil {
+			return fmt.Errorf("解析 kube-bench 输出失败: %v", err)
+		}
+	} else if err := json.Unmarshal([]byte(output), &parsed); err != nil {
+		return fmt.Errorf("解析 kube-bench 输出失败: %v", err)
+	}
+	for _, controls := range parsed.Controls {
+		for _, test := range controls.Tests {
+			for _, r := range test.Results {
+				switch r.Status {
+				case "PASS":
+					result.Pass++
+				case "FAIL":
+					result.Fail++
+				case "WARN":
+					result.Warn++
+				default:
+					result.Info++
+				}
+				if r.Status == "FAIL" || r.Status == "WARN" {
+					result.Findings = append(result.Findings, model.BenchFinding{
+						ID:          r.TestNumber,
+						Description: r.TestDesc,
+						Status:      r.Status,
+						Remediation: strings.TrimSpace(r.Remediation),
+					})
+				}
+			}
+		}
+	}
+	return nil
+}
+
+// trivyReport trivy --format json 的输出
+type trivyReport struct {
+	Results []struct {
+		Vulnerabilities []struct {
+			VulnerabilityID  string `json:"VulnerabilityID"`
+			PkgName          string `json:"PkgName"`
+			InstalledVersion string `json:"InstalledVersion"`
+			FixedVersion     string `json:"FixedVersion"`
+			Severity         string `json:"Severity"`
+			Title            string `json:"Title"`
+		} `json:"Vulnerabilities"`
+	} `json:"Results"`
+}
+
+// parseTrivyOutput 解析 trivyScript 的输出，按镜像返回结果
+func parseTrivyOutput(output string) map[string]*model.ImageScanResult {
+	results := map[string]*model.ImageScanResult{}
+	var current *model.ImageScanResult
+	for _, line := range strings.Split(output, "\n") {
+		switch {
+		case strings.HasPrefix(line, "=== "):
+			image := strings.TrimSpace(strings.TrimPrefix(line, "=== "))
+			current = &model.ImageScanResult{Image: image}
+			results[image] = current
+		case current == nil || strings.TrimSpace(line) == "":
+		case strings.HasPrefix(line, "!!! "):
+			current.Error = strings.TrimSpace(strings.TrimPrefix(line, "!!! "))
+		default:
+			var report trivyReport
+			if err := json.Unmarshal([]byte(line), &report); err != nil {
+				current.Error = fmt.Sprintf("解析 trivy 输出失败: %v", err)
+				continue
+			}
+			current.Counts = map[string]int{}
+			for _, r := range report.Results {
+				for _, v := range r.Vulnerabilities {
+					current.Counts[v.Severity]++
+					if v.Severity == "CRITICAL" || v.Severity == "HIGH" {
+						current.Vulnerabilities = append(current.Vulnerabilities, model.ImageVulnerability{
+							ID:               v.VulnerabilityID,
+							Package:          v.PkgName,
+							InstalledVersion: v.InstalledVersion,
+							FixedVersion:     v.FixedVersion,
+							Severity:         v.Severity,
+							Title:            v.Title,
+						})
+					}
+				}
+			}
+			// CRITICAL 在前，同级别下有修复版本的在前
+			sort.SliceStable(current.Vulnerabilities, func(i, j int) bool {
+				a, b := current.Vulnerabilities[i], current.Vulnerabilities[j]
+				if a.Severity != b.Severity {
+					return a.Severity == "CRITICAL"
+				}
+				return a.FixedVersion != "" && b.FixedVersion == ""
+			})
+			if len(current.Vulnerabilities) > maxImageVulnerabilities {
+				current.Vulnerabilities = current.Vulnerabilities[:maxImageVulnerabilities]
+			}
+		}
+	}
+	return results
+}
+
+// waitForJobs 等待命名空间中的 Job 全部结束（成功或失败），超时返回仍在运行的 Job
+func (m *Manager) waitForJobs(client *ssh.Client, selector string, timeout, interval time.Duration) ([]string, error) {
+	deadline := time.Now().Add(timeout)
+	for {
+		result, err := runKubectlIdempotent(client, fmt.Sprintf(
+			`kubectl get jobs -n %s -l %s -o jsonpath='{range .items[*]}{.metadata.name} {.status.succeeded} {.status.failed}{"\n"}{end}'`,
+			scanNamespace, selector))
+		if err == nil {
+			var running []string
+			for _, line := range strings.Split(strings.TrimSpace(result.Stdout), "\n") {
+				if fields := strings.Fields(line); len(fields) == 1 {
+					running = append(running, fields[0])
+				}
+			}
+			if len(running) == 0 {
+				return nil, nil
+			}
+			if time.Now().After(deadline) {
+				return running, fmt.Errorf("等待 %s 超时（%s）", strings.Join(running, ", "), timeout)
+			}
+		} else if time.Now().After(deadline) {
+			return nil, fmt.Errorf("读取扫描 Job 状态失败: %v", err)
+		}
+		time.Sleep(interval)
+	}
+}
+
+// jobLogs 读取 Job 的输出，Job 失败时附带 Pod 状态便于定位（如镜像拉取失败）
+func (m *Manager) jobLogs(client *ssh.Client, name string) (string, error) {
+	result, err := runKubectlIdempotent(client, fmt.Sprintf("kubectl logs -n %s job/%s", scanNamespace, name))
+	if err != nil {
+		reason := ""
+		if status, statusErr := runKubectlIdempotent(client, fmt.Sprintf(
+			`kubectl get pods -n %s -l job-name=%s -o jsonpath='{.items[0].status.containerStatuses[0].state}'`, scanNamespace, name)); statusErr == nil {
+			reason = strings.TrimSpace(status.Stdout)
+		}
+		return "", fmt.Errorf("读取 %s 输出失败: %v %s", name, err, reason)
+	}
+	return result.Stdout, nil
+}
+
+// RunSecurityScan 在临时命名空间中按节点运行 kube-bench，并在 Master 上用 trivy 扫描 images，扫描结束后删除命名空间。
+// 单个节点或镜像失败记录在对应结果中，不影响其他结果
+func (m *Manager) RunSecurityScan(client *ssh.Client, ws *Workspace, cfg ScanConfig, images []string, policy WaitPolicy) ([]model.BenchNodeResult, []model.ImageScanResult, error) {
+	policy = policy.WithDefaults()
+	nodes, err := m.ListNodes(client)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	namespace := fmt.Sprintf(`apiVersion: v1
+kind: Namespace
+metadata:
+  name: %s
+  labels:
+    %s: %s
+    pod-security.kubernetes.io/enforce: privileged
+`, scanNamespace, ManagedByLabel, ManagedBy)
+	var manifests []string
+	server := ""
+	benches := make([]model.BenchNodeResult, len(nodes))
+	for i, node := range nodes {
+		targets := "node"
+		if slices.Contains(node.Roles, "control-plane") || slices.Contains(node.Roles, "master") {
+			targets = "master,controlplane,node"
+			if server == "" {
+				server = node.Name
+			}
+		}
+		benches[i] = model.BenchNodeResult{Node: node.Name, Targets: targets}
+		manifests = append(manifests, kubeBenchJob(fmt.Sprintf("kube-bench-%d", i), node.Name, targets, cfg))
+	}
+
+	var scanned []string
+	imageResults := make([]model.ImageScanResult, 0, len(images))
+	for _, image := range images {
+		if !imageRefPattern.MatchString(image) {
+			imageResults = append(imageResults, model.ImageScanResult{Image: image, Error: "镜像引用包含不支持的字符，跳过"})
+			continue
+		}
+		scanned = append(scanned, image)
+	}
+	if len(scanned) > 0 && server == "" {
+		return nil, nil, fmt.Errorf("集群中没有 Server 节点，无法运行 trivy")
+	}
+	if len(scanned) > 0 {
+		job, err := trivyJob(server, scanned, cfg)
+		if err != nil {
+			return nil, nil, err
+		}
+		manifests = append(manifests, job)
+	}
+
+	file, err := ws.Upload("security-scan.yaml", namespace+"---\n"+strings.Join(manifests, "\n---\n"))
+	if err != nil {
+		return nil, nil, fmt.Errorf("上传扫描清单失败: %v", err)
+	}
+	// 上一次扫描中途退出时可能残留命名空间，先等待其删除
+	runKubectlIdempotent(client, "kubectl delete namespace "+scanNamespace+" --ignore-not-found --timeout=120s")
+	if _, err := runKubectl(client, "kubectl apply -f "+file); err != nil {
+		return nil, nil, fmt.Errorf("创建扫描 Job 失败: %v", err)
+	}
+	defer func() {
+		if _, err := runKubectl(client, "kubectl delete namespace "+scanNamespace+" --wait=false"); err != nil {
+			m.logger.Warnf("删除扫描命名空间失败: %v", err)
+		}
+	}()
+
+	m.logger.Infof("开始安全扫描：%d 个节点运行 kube-bench，trivy 扫描 %d 个镜像", len(nodes), len(scanned))
+	pending, _ := m.waitForJobs(client, "app=kube-bench", cfg.Timeout, policy.PollInterval)
+	for i := range benches {
+		name := fmt.Sprintf("kube-bench-%d", i)
+		if slices.Contains(pending, name) {
+			benches[i].Error = fmt.Sprintf("kube-bench 未在 %s 内完成", cfg.Timeout)
+			continue
+		}
+		output, err := m.jobLogs(client, name)
+		if err == nil {
+			err = parseKubeBench(output, &benches[i])
+		}
+		if err != nil {
+			benches[i].Error = err.Error()
+		}
+	}
+
+	if len(scanned) > 0 {
+		var output string
+		pending, _ := m.waitForJobs(client, "app=trivy", cfg.Timeout, policy.PollInterval)
+		if len(pending) > 0 {
+			err = fmt.Errorf("trivy 未在 %s 内完成", cfg.Timeout)
+		} else {
+			output, err = m.jobLogs(client, "trivy")
+		}
+		parsed := parseTrivyOutput(output)
+		for _, image := range scanned {
+			result, ok := parsed[image]
+			switch {
+			case ok:
+			case err != nil:
+				result = &model.ImageScanResult{Image: image, Error: err.Error()}
+			default:
+				// 漏洞库更新失败时脚本在扫描前退出
+				result = &model.ImageScanResult{Image: image, Error: "trivy 未输出结果（检查漏洞库仓库是否可访问）"}
+			}
+			imageResults = append(imageResults, *result)
+		}
+	}
+	return benches, imageResults, nil
+}
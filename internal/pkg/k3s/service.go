@@ -0,0 +1,141 @@
+package k3s
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// configPath k3s 主配置文件，启动时与 config.yaml.d 下的文件合并
+const configPath = "/etc/rancher/k3s/config.yaml"
+
+// configBackupSuffix 更新配置前的备份文件后缀，重启失败时据此恢复
+const configBackupSuffix = ".k3s-deploy.bak"
+
+// detectServiceManager 识别节点的 init 系统，不支持的 init 系统在安装前直接失败，避免脚本执行到一半才报错
+func detectServiceManager(client *ssh.Client) (*hostos.Info, error) {
+	osInfo, err := hostos.Detect(client)
+	if err != nil {
+		return nil, err
+	}
+	if err := osInfo.CheckServiceManager(); err != nil {
+		return nil, fmt.Errorf("节点 %v", err)
+	}
+	return osInfo, nil
+}
+
+// serviceFile 返回 k3s 安装脚本生成的服务定义文件，其中包含启动参数
+func serviceFile(osInfo *hostos.Info, unit string) string {
+	if osInfo.InitSystem == hostos.InitOpenRC {
+		return "/etc/init.d/" + unit
+	}
+	return "/etc/systemd/system/" + unit + ".service"
+}
+
+// DetectService 识别节点的 init 系统和已安装的 k3s 服务（Server 为 k3s，Agent 为 k3s-agent）
+func DetectService(client *ssh.Client) (*hostos.Info, string, error) {
+	osInfo, err := detectServiceManager(client)
+	if err != nil {
+		return nil, "", err
+	}
+	for _, unit := range []string{"k3s", "k3s-agent"} {
+		if _, err := client.ExecuteIdempotentCommand(osInfo.ServiceExistsCommand(unit)); err == nil {
+			return osInfo, unit, nil
+		}
+	}
+	return nil, "", fmt.Errorf("节点未安装 k3s 服务")
+}
+
+// RestartService 重启 k3s 服务并等待其重新运行，未能运行时返回附带服务日志的 ServiceError
+func (m *Manager) RestartService(client *ssh.Client, osInfo *hostos.Info, unit string, policy WaitPolicy) error {
+	policy = policy.WithDefaults()
+	if _, err := client.ExecuteCommand(osInfo.ServiceRestartCommand(unit)); err != nil {
+		m.logger.Warnf("重启 %s 返回错误，继续等待服务运行: %v", unit, err)
+	}
+	if waitForService(client, osInfo, unit, policy, m.logger.Warnf) {
+		m.logger.Infof("%s 服务已重新运行", unit)
+		return nil
+	}
+	journal, _ := m.ServiceLogs(client, osInfo, unit, 50)
+	return &ServiceError{
+		Unit:    unit,
+		Err:     fmt.Errorf("%s 服务重启后未正常运行（等待 %s）", unit, policy.ServiceTimeout),
+		Journal: journal,
+	}
+}
+
+// ApplyServiceConfig 更新 config.yaml 和 registries.yaml（内容为空的文件不修改）并重启服务，返回内容有变化的文件。
+// 没有文件变化时不重启；重启后服务未能运行则恢复原配置并再次重启
+func (m *Manager) ApplyServiceConfig(client *ssh.Client, ws *Workspace, osInfo *hostos.Info, unit, config, registries string, policy WaitPolicy) ([]string, error) {
+	files := []struct{ path, content string }{{configPath, config}, {registriesPath, registries}}
+	var changed []string
+	for _, f := range files {
+		if f.content == "" {
+			continue
+		}
+		var doc map[string]interface{}
+		if err := yaml.Unmarshal([]byte(f.content), &doc); err != nil {
+			return nil, fmt.Errorf("%s 不是有效的 YAML: %v", path.Base(f.path), err)
+		}
+		if f.path == configPath {
+			if err := ValidateConfigArgs(doc); err != nil {
+				return nil, err
+			}
+		}
+		file, err := ws.Upload(path.Base(f.path), f.content)
+		if err != nil {
+			return nil, fmt.Errorf("上传 %s 失败: %v", path.Base(f.path), err)
+		}
+		cmd := fmt.Sprintf(`if cmp -s %[1]s %[2]s; then echo unchanged; else mkdir -p $(dirname %[2]s)
+if [ -f %[2]s ]; then cp -p %[2]s %[2]s%[3]s; else rm -f %[2]s%[3]s; fi
+cp %[1]s %[2]s && chmod 600 %[2]s && echo changed; fi`, file, f.path, configBackupSuffix)
+		out, err := client.ExecuteCommand(cmd)
+		if err != nil {
+			m.restoreConfig(client, changed)
+			return nil, fmt.Errorf("写入 %s 失败: %v", f.path, err)
+		}
+		if strings.TrimSpace(out.Stdout) == "changed" {
+			changed = append(changed, f.path)
+		}
+	}
+	if len(changed) == 0 {
+		m.logger.Info("配置无变化，不重启服务")
+		return changed, nil
+	}
+
+	m.logger.Infof("已更新 %s，重启 %s", strings.Join(changed, ", "), unit)
+	err := m.RestartService(client, osInfo, unit, policy)
+	if err == nil {
+		return changed, nil
+	}
+	m.logger.Errorf("%s 使用新配置启动失败，恢复原配置: %v", unit, err)
+	m.restoreConfig(client, changed)
+	if restartErr := m.RestartService(client, osInfo, unit, policy); restartErr != nil {
+		m.logger.Errorf("恢复原配置后 %s 仍未运行: %v", unit, restartErr)
+	}
+	return changed, err
+}
+
+// restoreConfig 用备份恢复配置文件，更新前不存在的文件直接删除
+func (m *Manager) restoreConfig(client *ssh.Client, files []string) {
+	for _, file := range files {
+		cmd := fmt.Sprintf("if [ -f %[1]s%[2]s ]; then mv -f %[1]s%[2]s %[1]s; else rm -f %[1]s; fi", file, configBackupSuffix)
+		if _, err := client.ExecuteCommand(cmd); err != nil {
+			m.logger.Errorf("恢复 %s 失败: %v", file, err)
+		}
+	}
+}
+
+// ServiceLogs 读取服务最近 lines 行日志
+func (m *Manager) ServiceLogs(client *ssh.Client, osInfo *hostos.Info, unit string, lines int) (string, error) {
+	out, err := client.ExecuteIdempotentCommand(osInfo.ServiceLogsCommand(unit, lines))
+	if err != nil {
+		return "", fmt.Errorf("读取 %s 日志失败: %v", unit, err)
+	}
+	return out.Stdout, nil
+}
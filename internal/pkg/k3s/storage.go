@@ -0,0 +1,153 @@
+package k3s
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+const (
+	// localStorageSkip 存在时 k3s 不再重新应用内置的 local-storage 清单，避免重启后覆盖路径配置和默认 StorageClass
+	localStorageSkip = "/var/lib/rancher/k3s/server/manifests/local-storage.yaml.skip"
+	// LocalPathDefault local-path-provisioner 的默认数据目录
+	LocalPathDefault = "/var/lib/rancher/k3s/storage"
+	// LonghornDataPath Longhorn 的默认数据目录，节点上可链接到其他分区
+	LonghornDataPath = "/var/lib/longhorn"
+)
+
+// LonghornConfig Longhorn 安装参数
+type LonghornConfig struct {
+	// ManifestURL 安装清单地址，在 Master 节点上下载
+	ManifestURL string
+	// Replicas 卷的默认副本数
+	Replicas int
+}
+
+// localPathNode local-path-provisioner config.json 中的 nodePathMap 项
+type localPathNode struct {
+	Node  string   `json:"node"`
+	Paths []string `json:"paths"`
+}
+
+// ConfigureLocalPath 按节点设置 local-path-provisioner 的数据目录（集群节点名 -> 目录），并设为默认 StorageClass
+func (m *Manager) ConfigureLocalPath(client *ssh.Client, ws *Workspace, nodePaths map[string]string, policy WaitPolicy) error {
+	m.logger.Info("开始配置 local-path 存储")
+
+	if err := m.skipLocalStorageAddon(client); err != nil {
+		return err
+	}
+
+	nodes := make([]string, 0, len(nodePaths))
+	for node := range nodePaths {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	nodeMap := []localPathNode{{Node: "DEFAULT_PATH_FOR_NON_LISTED_NODES", Paths: []string{LocalPathDefault}}}
+	for _, node := range nodes {
+		nodeMap = append(nodeMap, localPathNode{Node: node, Paths: []string{nodePaths[node]}})
+	}
+	config, err := json.Marshal(map[string]any{"nodePathMap": nodeMap})
+	if err != nil {
+		return err
+	}
+	patch, err := json.Marshal(map[string]any{"data": map[string]string{"config.json": string(config)}})
+	if err != nil {
+		return err
+	}
+
+	file, err := ws.Upload("local-path-config.json", string(patch))
+	if err != nil {
+		return fmt.Errorf("上传 local-path 配置失败: %v", err)
+	}
+	if _, err := runKubectl(client, "kubectl -n kube-system patch configmap local-path-config --type merge --patch-file "+file); err != nil {
+		return fmt.Errorf("更新 local-path 配置失败: %v", err)
+	}
+	if _, err := runKubectl(client, "kubectl -n kube-system rollout restart deployment/local-path-provisioner"); err != nil {
+		return fmt.Errorf("重启 local-path-provisioner 失败: %v", err)
+	}
+	if err := m.waitForRollout(client, "kube-system", "local-path-provisioner", policy.WithDefaults()); err != nil {
+		return err
+	}
+
+	if err := m.setDefaultStorageClass(client, "local-path"); err != nil {
+		return err
+	}
+	m.logger.Infof("local-path 存储配置完成: %v", nodePaths)
+	return nil
+}
+
+// InstallLonghorn 安装 Longhorn，设置默认副本数并设为默认 StorageClass
+func (m *Manager) InstallLonghorn(client *ssh.Client, cfg LonghornConfig, policy WaitPolicy) error {
+	m.logger.Infof("开始安装 Longhorn: %s", cfg.ManifestURL)
+	policy = policy.WithDefaults()
+
+	// 切换默认 StorageClass 后同样不能让 k3s 恢复 local-path 的默认标记
+	if err := m.skipLocalStorageAddon(client); err != nil {
+		return err
+	}
+	if _, err := runKubectl(client, "kubectl apply -f "+cfg.ManifestURL); err != nil {
+		return fmt.Errorf("应用 Longhorn 清单失败: %v", err)
+	}
+
+	cmd := fmt.Sprintf("kubectl -n longhorn-system rollout status daemonset/longhorn-manager --timeout=%ds", int(policy.DeploymentTimeout.Seconds()))
+	if _, err := runKubectlIdempotent(client, cmd); err != nil {
+		return fmt.Errorf("等待 longhorn-manager 启动失败（请确认各节点 iscsid 正常运行）: %v", err)
+	}
+	if err := m.waitForRollout(client, "longhorn-system", "longhorn-driver-deployer", policy); err != nil {
+		return err
+	}
+
+	replicas := fmt.Sprintf("%d", cfg.Replicas)
+	if _, err := runKubectl(client, fmt.Sprintf(`kubectl -n longhorn-system patch settings.longhorn.io default-replica-count --type merge -p '{"value":"%s"}'`, replicas)); err != nil {
+		return fmt.Errorf("设置 Longhorn 默认副本数失败: %v", err)
+	}
+	// StorageClass 参数不可修改，longhorn-manager 根据 longhorn-storageclass ConfigMap 重建 StorageClass
+	cmd = fmt.Sprintf(`kubectl -n longhorn-system get configmap longhorn-storageclass -o yaml | sed 's/numberOfReplicas: "[0-9]*"/numberOfReplicas: "%s"/' | kubectl apply -f -`, replicas)
+	if _, err := runKubectl(client, cmd); err != nil {
+		return fmt.Errorf("设置 Longhorn StorageClass 副本数失败: %v", err)
+	}
+	if err := m.waitForStorageClass(client, "longhorn", policy); err != nil {
+		return err
+	}
+
+	if err := m.setDefaultStorageClass(client, "longhorn"); err != nil {
+		return err
+	}
+	m.logger.Infof("Longhorn 安装完成，默认副本数 %d", cfg.Replicas)
+	return nil
+}
+
+func (m *Manager) skipLocalStorageAddon(client *ssh.Client) error {
+	if _, err := client.ExecuteCommand("touch " + localStorageSkip); err != nil {
+		return fmt.Errorf("禁止 k3s 重新应用 local-storage 清单失败: %v", err)
+	}
+	return nil
+}
+
+func (m *Manager) waitForStorageClass(client *ssh.Client, name string, policy WaitPolicy) error {
+	deadline := time.Now().Add(policy.DeploymentTimeout)
+	for {
+		if _, err := runKubectlIdempotent(client, "kubectl get storageclass "+name); err == nil {
+			return nil
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("等待 StorageClass %s 创建超时（%s）", name, policy.DeploymentTimeout)
+		}
+		time.Sleep(policy.PollInterval)
+	}
+}
+
+// setDefaultStorageClass 将 name 设为唯一的默认 StorageClass
+func (m *Manager) setDefaultStorageClass(client *ssh.Client, name string) error {
+	const annotation = "storageclass.kubernetes.io/is-default-class"
+	cmd := fmt.Sprintf("for sc in $(kubectl get storageclass -o name); do kubectl annotate $sc %[1]s=false --overwrite || exit 1; done && kubectl annotate storageclass %[2]s %[1]s=true --overwrite",
+		annotation, name)
+	if _, err := runKubectl(client, cmd); err != nil {
+		return fmt.Errorf("设置默认 StorageClass %s 失败: %v", name, err)
+	}
+	m.logger.Infof("默认 StorageClass 已设置为 %s", name)
+	return nil
+}
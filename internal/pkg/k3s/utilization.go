@@ -0,0 +1,87 @@
+package k3s
+
+import (
+	"encoding/json"
+	"fmt"
+	"slices"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// UtilizationSample 采集节点用量与可分配资源，以及每个 Deployment 运行中 Pod 的用量之和和每副本的资源请求，
+// 作为伸缩建议的依据。依赖 metrics-server
+func (m *Manager) UtilizationSample(client *ssh.Client) (*model.UtilizationSample, error) {
+	metrics, err := m.Metrics(client)
+	if err != nil {
+		return nil, err
+	}
+	nodes, err := m.ListNodes(client)
+	if err != nil {
+		return nil, err
+	}
+	servers := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		servers[node.Name] = slices.Contains(node.Roles, "control-plane") || slices.Contains(node.Roles, "master")
+	}
+
+	result, err := runKubectlIdempotent(client, "kubectl get deployments -A -o json")
+	if err != nil {
+		return nil, fmt.Errorf("读取 Deployment 失败: %v", err)
+	}
+	var deployments workloadList
+	if err := json.Unmarshal([]byte(result.Stdout), &deployments); err != nil {
+		return nil, fmt.Errorf("解析 Deployment 失败: %v", err)
+	}
+	result, err = runKubectlIdempotent(client, "kubectl get pods -A --field-selector=status.phase=Running -o json")
+	if err != nil {
+		return nil, fmt.Errorf("读取 Pod 列表失败: %v", err)
+	}
+	var pods podList
+	if err := json.Unmarshal([]byte(result.Stdout), &pods); err != nil {
+		return nil, fmt.Errorf("解析 Pod 列表失败: %v", err)
+	}
+	usage := make(map[string]model.PodMetrics, len(metrics.Pods))
+	for _, pod := range metrics.Pods {
+		usage[pod.Namespace+"/"+pod.Name] = pod
+	}
+
+	sample := &model.UtilizationSample{
+		Time:      metrics.CollectedAt,
+		Nodes:     make([]model.NodeUtilization, 0, len(metrics.Nodes)),
+		Workloads: []model.WorkloadUtilization{},
+	}
+	for _, node := range metrics.Nodes {
+		sample.Nodes = append(sample.Nodes, model.NodeUtilization{
+			Name:              node.Name,
+			Server:            servers[node.Name],
+			CPUUsage:          node.CPUUsage,
+			CPUAllocatable:    node.CPUAllocatable,
+			MemoryUsage:       node.MemoryUsage,
+			MemoryAllocatable: node.MemoryAllocatable,
+		})
+	}
+	for _, item := range deployments.Items {
+		w := model.WorkloadUtilization{Namespace: item.Metadata.Namespace, Name: item.Metadata.Name, Replicas: 1}
+		if item.Spec.Replicas != nil {
+			w.Replicas = *item.Spec.Replicas
+		}
+		// 缩容到 0 的 Deployment 没有用量可参考
+		if w.Replicas == 0 {
+			continue
+		}
+		for _, c := range item.Spec.Template.Spec.Containers {
+			w.CPURequest += CPUMillis(c.Resources.Requests["cpu"])
+			w.MemoryRequest += MemoryBytes(c.Resources.Requests["memory"])
+		}
+		for _, pod := range pods.Items {
+			if pod.Metadata.Namespace == w.Namespace && matchLabels(item.Spec.Selector.MatchLabels, pod.Metadata.Labels) {
+				used := usage[pod.Metadata.Namespace+"/"+pod.Metadata.Name]
+				w.CPUUsage += used.CPUUsage
+				w.MemoryUsage += used.MemoryUsage
+			}
+		}
+		sample.Workloads = append(sample.Workloads, w)
+	}
+	return sample, nil
+}
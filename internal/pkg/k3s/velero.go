@@ -0,0 +1,473 @@
+package k3s
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"net/url"
+	"regexp"
+	"strings"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+const (
+	// VeleroNamespace Velero 及其备份、恢复、定时备份资源所在的命名空间
+	VeleroNamespace = "velero"
+	// veleroCredentials 对象存储凭据 Secret，内容为 AWS 凭据文件格式
+	veleroCredentials = "k3s-deploy-velero-credentials"
+	// defaultBackupTTL 未指定保留时间时的备份保留时长
+	defaultBackupTTL = 720 * time.Hour
+
+	defaultVeleroChartRepo    = "https://vmware-tanzu.github.io/helm-charts"
+	defaultVeleroChartVersion = "7.2.1"
+	defaultVeleroPlugin       = "v1.10.0"
+)
+
+var (
+	// cronPattern 五段式 cron 表达式或 @daily 等预定义表达式
+	cronPattern = regexp.MustCompile(`^(@(yearly|annually|monthly|weekly|daily|hourly)|[0-9*/,-]+( [0-9*/,-]+){4})$`)
+	// bucketPattern S3 存储桶名
+	bucketPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9.-]{1,61}[a-z0-9]$`)
+	// prefixPattern 桶内路径前缀
+	prefixPattern = regexp.MustCompile(`^[A-Za-z0-9._-]+(/[A-Za-z0-9._-]+)*$`)
+)
+
+// ValidateVelero 校验 Velero 安装参数
+func ValidateVelero(opts *model.VeleroOptions) error {
+	if opts == nil {
+		return nil
+	}
+	if opts.ChartVersion != "" && !chartVersionPattern.MatchString(opts.ChartVersion) {
+		return fmt.Errorf("无效的 Velero chart 版本: %s", opts.ChartVersion)
+	}
+	if opts.ChartRepo != "" && !chartRepoPattern.MatchString(opts.ChartRepo) {
+		return fmt.Errorf("无效的 chart 仓库地址: %s", opts.ChartRepo)
+	}
+	if opts.PluginVersion != "" && !chartVersionPattern.MatchString(opts.PluginVersion) {
+		return fmt.Errorf("无效的 velero-plugin-for-aws 版本: %s", opts.PluginVersion)
+	}
+	// 未设置时由部署流程填入集群内 MinIO 的地址和密钥
+	storage := opts.Storage
+	if storage == nil {
+		return nil
+	}
+	if !bucketPattern.MatchString(storage.Bucket) {
+		return fmt.Errorf("无效的存储桶名: %s", storage.Bucket)
+	}
+	if storage.Prefix != "" && !prefixPattern.MatchString(storage.Prefix) {
+		return fmt.Errorf("无效的路径前缀: %s", storage.Prefix)
+	}
+	if storage.Endpoint != "" {
+		u, err := url.Parse(storage.Endpoint)
+		if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			return fmt.Errorf("无效的对象存储地址: %s", storage.Endpoint)
+		}
+	}
+	if strings.ContainsAny(storage.AccessKey+storage.SecretKey, "\r\n") {
+		return fmt.Errorf("对象存储密钥不能包含换行")
+	}
+	return nil
+}
+
+// veleroManifest 生成安装 Velero 的 HelmChart：使用 AWS 插件访问 S3 兼容存储，
+// 不使用卷快照，部署 node-agent 以文件系统方式备份卷（local-path 和 Longhorn 均适用）
+func veleroManifest(opts *model.VeleroOptions) (string, error) {
+	storage := opts.Storage
+	region := storage.Region
+	if region == "" {
+		region = "us-east-1"
+	}
+	config := map[string]string{"region": region}
+	if storage.Endpoint != "" {
+		config["s3Url"] = storage.Endpoint
+		config["s3ForcePathStyle"] = "true"
+	}
+	if storage.InsecureSkipTLSVerify {
+		config["insecureSkipTLSVerify"] = "true"
+	}
+	location := map[string]any{
+		"name":     "default",
+		"provider": "aws",
+		"bucket":   storage.Bucket,
+		"default":  true,
+		"config":   config,
+	}
+	if storage.Prefix != "" {
+		location["prefix"] = storage.Prefix
+	}
+
+	plugin := opts.PluginVersion
+	if plugin == "" {
+		plugin = defaultVeleroPlugin
+	}
+	values, err := yaml.Marshal(map[string]any{
+		"initContainers": []map[string]any{{
+			"name":            "velero-plugin-for-aws",
+			"image":           "velero/velero-plugin-for-aws:" + plugin,
+			"imagePullPolicy": "IfNotPresent",
+			"volumeMounts":    []map[string]string{{"mountPath": "/target", "name": "plugins"}},
+		}},
+		"configuration": map[string]any{
+			"backupStorageLocation":  []map[string]any{location},
+			"volumeSnapshotLocation": []any{},
+			"uploaderType":           "kopia",
+		},
+		"credentials":      map[string]any{"existingSecret": veleroCredentials},
+		"snapshotsEnabled": false,
+		"deployNodeAgent":  true,
+	})
+	if err != nil {
+		return "", err
+	}
+
+	repo, version := opts.ChartRepo, opts.ChartVersion
+	if repo == "" {
+		repo = defaultVeleroChartRepo
+	}
+	if version == "" {
+		version = defaultVeleroChartVersion
+	}
+	manifest, err := yaml.Marshal(map[string]any{
+		"apiVersion": "helm.cattle.io/v1",
+		"kind":       "HelmChart",
+		"metadata": map[string]any{
+			"name":      "velero",
+			"namespace": "kube-system",
+			"labels":    map[string]string{ManagedByLabel: ManagedBy},
+		},
+		"spec": map[string]any{
+			"repo":            repo,
+			"chart":           "velero",
+			"version":         version,
+			"targetNamespace": VeleroNamespace,
+			"valuesContent":   string(values),
+		},
+	})
+	if err != nil {
+		return "", err
+	}
+	return string(manifest), nil
+}
+
+// InstallVelero 安装（或更新）Velero，等待其运行且备份存储位置可用
+func (m *Manager) InstallVelero(client *ssh.Client, ws *Workspace, opts *model.VeleroOptions, policy WaitPolicy) error {
+	m.logger.Infof("开始安装 Velero，备份存储: %s/%s", opts.Storage.Endpoint, opts.Storage.Bucket)
+	policy = policy.WithDefaults()
+
+	// node-agent 需要挂载 hostPath，命名空间不受集群默认的 PodSecurity 级别限制
+	cmd := fmt.Sprintf("kubectl create namespace %[1]s --dry-run=client -o yaml | kubectl apply -f - && "+
+		"kubectl label namespace %[1]s --overwrite pod-security.kubernetes.io/enforce=privileged", VeleroNamespace)
+	if _, err := runKubectl(client, cmd); err != nil {
+		return fmt.Errorf("创建命名空间 %s 失败: %v", VeleroNamespace, err)
+	}
+
+	credentials := fmt.Sprintf("[default]\naws_access_key_id=%s\naws_secret_access_key=%s\n", opts.Storage.AccessKey, opts.Storage.SecretKey)
+	file, err := ws.Upload("velero-credentials", credentials)
+	if err != nil {
+		return fmt.Errorf("上传对象存储凭据失败: %v", err)
+	}
+	cmd = fmt.Sprintf("kubectl -n %s create secret generic %s --from-file=cloud=%s --dry-run=client -o yaml | kubectl apply -f -", VeleroNamespace, veleroCredentials, file)
+	if _, err := runKubectl(client, cmd); err != nil {
+		return fmt.Errorf("创建对象存储凭据失败: %v", err)
+	}
+
+	manifest, err := veleroManifest(opts)
+	if err != nil {
+		return fmt.Errorf("生成 Velero 清单失败: %v", err)
+	}
+	if file, err = ws.Upload("velero-helmchart.yaml", manifest); err != nil {
+		return fmt.Errorf("上传 Velero 清单失败: %v", err)
+	}
+	if _, err := runKubectl(client, "kubectl apply -f "+file); err != nil {
+		return fmt.Errorf("应用 Velero 清单失败: %v", err)
+	}
+	if err := m.waitForRollout(client, VeleroNamespace, "velero", policy); err != nil {
+		return err
+	}
+
+	deadline := time.Now().Add(policy.DeploymentTimeout)
+	for {
+		result, err := runKubectlIdempotent(client, fmt.Sprintf("kubectl -n %s get backupstoragelocation default -o jsonpath='{.status.phase}{\"\\t\"}{.status.message}'", VeleroNamespace))
+		phase, message := "", ""
+		if err == nil {
+			phase, message, _ = strings.Cut(strings.TrimSpace(result.Stdout), "\t")
+		}
+		if phase == "Available" {
+			break
+		}
+		if time.Now().After(deadline) {
+			return fmt.Errorf("备份存储位置不可用（%s），请检查对象存储地址、存储桶和密钥: %s", phase, message)
+		}
+		time.Sleep(policy.PollInterval)
+	}
+	m.logger.Info("Velero 安装完成，备份存储位置可用")
+	return nil
+}
+
+// requireVelero 确认集群已安装 Velero
+func (m *Manager) requireVelero(client *ssh.Client) error {
+	if _, err := runKubectlIdempotent(client, "kubectl get crd backups.velero.io schedules.velero.io restores.velero.io"); err != nil {
+		return fmt.Errorf("集群未安装 Velero，请在部署请求中设置 velero 并执行 install-velero 步骤")
+	}
+	return nil
+}
+
+// ValidateBackupNamespaces 校验备份涉及的命名空间名
+func ValidateBackupNamespaces(namespaces []string) error {
+	for _, ns := range namespaces {
+		if !namespacePattern.MatchString(ns) {
+			return fmt.Errorf("无效的命名空间: %q", ns)
+		}
+	}
+	return nil
+}
+
+// ValidateSchedule 校验定时备份的 cron 表达式
+func ValidateSchedule(schedule string) error {
+	if !cronPattern.MatchString(schedule) {
+		return fmt.Errorf("无效的 cron 表达式: %q（五段式，如 0 2 * * *）", schedule)
+	}
+	return nil
+}
+
+func backupTTL(hours int) string {
+	ttl := defaultBackupTTL
+	if hours > 0 {
+		ttl = time.Duration(hours) * time.Hour
+	}
+	return ttl.String()
+}
+
+// backupSpec Backup 与 Schedule 模板共用的备份参数
+func backupSpec(namespaces []string, ttlHours int, skipVolumes bool) map[string]any {
+	return map[string]any{
+		"includedNamespaces":       namespaces,
+		"ttl":                      backupTTL(ttlHours),
+		"storageLocation":          "default",
+		"defaultVolumesToFsBackup": !skipVolumes,
+	}
+}
+
+// applyVeleroResource 上传并创建（或更新）Velero 资源
+func (m *Manager) applyVeleroResource(client *ssh.Client, ws *Workspace, kind, name string, spec map[string]any) error {
+	manifest, err := yaml.Marshal(map[string]any{
+		"apiVersion": "velero.io/v1",
+		"kind":       kind,
+		"metadata": map[string]any{
+			"name":      name,
+			"namespace": VeleroNamespace,
+			"labels":    map[string]string{ManagedByLabel: ManagedBy},
+		},
+		"spec": spec,
+	})
+	if err != nil {
+		return err
+	}
+	file, err := ws.Upload(strings.ToLower(kind)+"-"+name+".yaml", string(manifest))
+	if err != nil {
+		return fmt.Errorf("上传 %s 失败: %v", kind, err)
+	}
+	if _, err := runKubectl(client, "kubectl apply -f "+file); err != nil {
+		return fmt.Errorf("创建 %s %s 失败: %v", kind, name, err)
+	}
+	return nil
+}
+
+// nameSuffix 备份和恢复名的随机后缀，同一秒内多次创建也不会重名
+func nameSuffix() string {
+	buf := make([]byte, 3)
+	rand.Read(buf)
+	return hex.EncodeToString(buf)
+}
+
+// CreateBackup 立即备份命名空间，返回备份名
+func (m *Manager) CreateBackup(client *ssh.Client, ws *Workspace, req *model.BackupRequest) (string, error) {
+	if err := m.requireVelero(client); err != nil {
+		return "", err
+	}
+	name := "k3s-deploy-" + time.Now().UTC().Format("20060102-150405") + "-" + nameSuffix()
+	if err := m.applyVeleroResource(client, ws, "Backup", name, backupSpec(req.Namespaces, req.TTLHours, req.SkipVolumes)); err != nil {
+		return "", err
+	}
+	m.logger.Infof("已创建备份 %s: %v", name, req.Namespaces)
+	return name, nil
+}
+
+// CreateRestore 从备份恢复，返回恢复名。备份须已完成
+func (m *Manager) CreateRestore(client *ssh.Client, ws *Workspace, backup string, req *model.RestoreRequest) (string, error) {
+	if err := m.requireVelero(client); err != nil {
+		return "", err
+	}
+	result, err := runKubectlIdempotent(client, fmt.Sprintf("kubectl -n %s get backup %s -o jsonpath='{.status.phase}'", VeleroNamespace, backup))
+	if err != nil {
+		return "", fmt.Errorf("备份 %s 不存在", backup)
+	}
+	if phase := strings.TrimSpace(result.Stdout); phase != "Completed" && phase != "PartiallyFailed" {
+		return "", fmt.Errorf("备份 %s 状态为 %s，只能从已完成的备份恢复", backup, phase)
+	}
+
+	spec := map[string]any{"backupName": backup}
+	if len(req.Namespaces) > 0 {
+		spec["includedNamespaces"] = req.Namespaces
+	}
+	if len(req.NamespaceMapping) > 0 {
+		spec["namespaceMapping"] = req.NamespaceMapping
+	}
+	// 恢复名需唯一，以备份名加时间和随机后缀区分同一备份的多次恢复
+	name := fmt.Sprintf("%s-restore-%s-%s", strings.TrimPrefix(backup, "k3s-deploy-"), time.Now().UTC().Format("150405"), nameSuffix())
+	if len(name) > 63 {
+		name = name[len(name)-63:]
+	}
+	name = strings.Trim(name, "-.")
+	if err := m.applyVeleroResource(client, ws, "Restore", name, spec); err != nil {
+		return "", err
+	}
+	m.logger.Warnf("已从备份 %s 创建恢复 %s", backup, name)
+	return name, nil
+}
+
+// ApplySchedule 创建或更新定时备份
+func (m *Manager) ApplySchedule(client *ssh.Client, ws *Workspace, name string, req *model.BackupScheduleRequest) error {
+	if err := m.requireVelero(client); err != nil {
+		return err
+	}
+	spec := map[string]any{
+		"schedule": req.Schedule,
+		"paused":   req.Paused,
+		"template": backupSpec(req.Namespaces, req.TTLHours, req.SkipVolumes),
+	}
+	if err := m.applyVeleroResource(client, ws, "Schedule", name, spec); err != nil {
+		return err
+	}
+	m.logger.Infof("定时备份 %s 已更新: %s %v", name, req.Schedule, req.Namespaces)
+	return nil
+}
+
+// DeleteSchedule 删除定时备份，已创建的备份保留到各自过期
+func (m *Manager) DeleteSchedule(client *ssh.Client, name string) error {
+	if err := m.requireVelero(client); err != nil {
+		return err
+	}
+	if _, err := runKubectl(client, fmt.Sprintf("kubectl -n %s delete schedule %s", VeleroNamespace, name)); err != nil {
+		return fmt.Errorf("删除定时备份 %s 失败: %v", name, err)
+	}
+	return nil
+}
+
+// veleroList kubectl get backups/restores/schedules -o json 输出中用到的字段
+type veleroList struct {
+	Items []struct {
+		Metadata struct {
+			Name   string            `json:"name"`
+			Labels map[string]string `json:"labels"`
+		} `json:"metadata"`
+		Spec struct {
+			IncludedNamespaces []string          `json:"includedNamespaces"`
+			BackupName         string            `json:"backupName"`
+			NamespaceMapping   map[string]string `json:"namespaceMapping"`
+			Schedule           string            `json:"schedule"`
+			Paused             bool              `json:"paused"`
+			Template           struct {
+				IncludedNamespaces []string `json:"includedNamespaces"`
+				TTL                string   `json:"ttl"`
+			} `json:"template"`
+		} `json:"spec"`
+		Status struct {
+			Phase               string     `json:"phase"`
+			Errors              int        `json:"errors"`
+			Warnings            int        `json:"warnings"`
+			StartTimestamp      *time.Time `json:"startTimestamp"`
+			CompletionTimestamp *time.Time `json:"completionTimestamp"`
+			Expiration          *time.Time `json:"expiration"`
+			LastBackup          *time.Time `json:"lastBackup"`
+		} `json:"status"`
+	} `json:"items"`
+}
+
+func (m *Manager) listVelero(client *ssh.Client, resource string) (*veleroList, error) {
+	if err := m.requireVelero(client); err != nil {
+		return nil, err
+	}
+	result, err := runKubectlIdempotent(client, fmt.Sprintf("kubectl -n %s get %s -o json", VeleroNamespace, resource))
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 失败: %v", resource, err)
+	}
+	var list veleroList
+	if err := json.Unmarshal([]byte(result.Stdout), &list); err != nil {
+		return nil, fmt.Errorf("解析 %s 失败: %v", resource, err)
+	}
+	return &list, nil
+}
+
+// ListBackups 列出备份
+func (m *Manager) ListBackups(client *ssh.Client) ([]model.Backup, error) {
+	list, err := m.listVelero(client, "backups")
+	if err != nil {
+		return nil, err
+	}
+	backups := make([]model.Backup, 0, len(list.Items))
+	for _, item := range list.Items {
+		backups = append(backups, model.Backup{
+			Name:        item.Metadata.Name,
+			Namespaces:  item.Spec.IncludedNamespaces,
+			Phase:       item.Status.Phase,
+			Schedule:    item.Metadata.Labels["velero.io/schedule-name"],
+			Errors:      item.Status.Errors,
+			Warnings:    item.Status.Warnings,
+			StartedAt:   item.Status.StartTimestamp,
+			CompletedAt: item.Status.CompletionTimestamp,
+			ExpiresAt:   item.Status.Expiration,
+		})
+	}
+	return backups, nil
+}
+
+// ListRestores 列出恢复
+func (m *Manager) ListRestores(client *ssh.Client) ([]model.Restore, error) {
+	list, err := m.listVelero(client, "restores")
+	if err != nil {
+		return nil, err
+	}
+	restores := make([]model.Restore, 0, len(list.Items))
+	for _, item := range list.Items {
+		restores = append(restores, model.Restore{
+			Name:        item.Metadata.Name,
+			Backup:      item.Spec.BackupName,
+			Namespaces:  item.Spec.IncludedNamespaces,
+			Mapping:     item.Spec.NamespaceMapping,
+			Phase:       item.Status.Phase,
+			Errors:      item.Status.Errors,
+			Warnings:    item.Status.Warnings,
+			StartedAt:   item.Status.StartTimestamp,
+			CompletedAt: item.Status.CompletionTimestamp,
+		})
+	}
+	return restores, nil
+}
+
+// ListSchedules 列出定时备份
+func (m *Manager) ListSchedules(client *ssh.Client) ([]model.BackupSchedule, error) {
+	list, err := m.listVelero(client, "schedules")
+	if err != nil {
+		return nil, err
+	}
+	schedules := make([]model.BackupSchedule, 0, len(list.Items))
+	for _, item := range list.Items {
+		schedules = append(schedules, model.BackupSchedule{
+			Name:       item.Metadata.Name,
+			Schedule:   item.Spec.Schedule,
+			Namespaces: item.Spec.Template.IncludedNamespaces,
+			TTL:        item.Spec.Template.TTL,
+			Paused:     item.Spec.Paused,
+			Phase:      item.Status.Phase,
+			LastBackup: item.Status.LastBackup,
+		})
+	}
+	return schedules, nil
+}
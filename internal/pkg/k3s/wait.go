@@ -0,0 +1,168 @@
+package k3s
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// WaitPolicy 部署过程中等待服务和工作负载就绪的超时与轮询间隔
+type WaitPolicy struct {
+	// ServiceTimeout 等待 k3s / k3s-agent 服务启动的总时长
+	ServiceTimeout time.Duration
+	// DeploymentTimeout 等待单个 inSuite 组件就绪的总时长
+	DeploymentTimeout time.Duration
+	// PollInterval 服务状态轮询间隔，也是 rollout 观察的分段时长
+	PollInterval time.Duration
+	// InstallTimeout 在节点上执行安装脚本的最长时间，超时后终止安装进程
+	InstallTimeout time.Duration
+	// ScriptDownloadTimeout 后端下载安装脚本的最长时间
+	ScriptDownloadTimeout time.Duration
+}
+
+// waitDefaults 未在请求中设置的等待参数，启动时由配置文件设置
+var waitDefaults = WaitPolicy{
+	ServiceTimeout:        3 * time.Minute,
+	DeploymentTimeout:     5 * time.Minute,
+	PollInterval:          10 * time.Second,
+	InstallTimeout:        15 * time.Minute,
+	ScriptDownloadTimeout: time.Minute,
+}
+
+// SetWaitDefaults 设置默认等待策略，未设置的字段保持内置默认值
+func SetWaitDefaults(p WaitPolicy) {
+	waitDefaults = p.merge(waitDefaults)
+}
+
+// DefaultWaitPolicy 默认等待策略
+func DefaultWaitPolicy() WaitPolicy {
+	return waitDefaults
+}
+
+// WithDefaults 未设置的字段使用默认值
+func (p WaitPolicy) WithDefaults() WaitPolicy {
+	return p.merge(DefaultWaitPolicy())
+}
+
+func (p WaitPolicy) merge(defaults WaitPolicy) WaitPolicy {
+	if p.ServiceTimeout <= 0 {
+		p.ServiceTimeout = defaults.ServiceTimeout
+	}
+	if p.DeploymentTimeout <= 0 {
+		p.DeploymentTimeout = defaults.DeploymentTimeout
+	}
+	if p.PollInterval <= 0 {
+		p.PollInterval = defaults.PollInterval
+	}
+	if p.InstallTimeout <= 0 {
+		p.InstallTimeout = defaults.InstallTimeout
+	}
+	if p.ScriptDownloadTimeout <= 0 {
+		p.ScriptDownloadTimeout = defaults.ScriptDownloadTimeout
+	}
+	return p
+}
+
+// ServiceError systemd 服务未能正常运行，携带服务日志供失败分类使用
+type ServiceError struct {
+	Unit    string
+	Err     error
+	Journal string
+}
+
+func (e *ServiceError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *ServiceError) Unwrap() error {
+	return e.Err
+}
+
+// Diagnostics 返回服务的 journal 日志
+func (e *ServiceError) Diagnostics() string {
+	return e.Journal
+}
+
+// fatalWaitingReasons 出现后不会自行恢复的容器等待原因，检测到即提前失败
+var fatalWaitingReasons = []string{
+	"CrashLoopBackOff",
+	"ImagePullBackOff",
+	"ErrImagePull",
+	"InvalidImageName",
+	"CreateContainerConfigError",
+	"CreateContainerError",
+}
+
+// waitForService 轮询服务（systemd 或 OpenRC）直到 active 或超时
+func waitForService(client *ssh.Client, osInfo *hostos.Info, unit string, policy WaitPolicy, logf func(string, ...interface{})) bool {
+	// 单次检查命令卡住（如节点负载过高、systemctl 无响应）时同样受总时长限制
+	ctx, cancel := context.WithTimeout(context.Background(), policy.ServiceTimeout)
+	defer cancel()
+	deadline, _ := ctx.Deadline()
+	for attempt := 1; ; attempt++ {
+		result, err := client.ExecuteIdempotentCommandContext(ctx, osInfo.ServiceActiveCommand(unit))
+		if err == nil && strings.TrimSpace(result.Stdout) == "active" {
+			return true
+		}
+		if ctx.Err() != nil || time.Now().Add(policy.PollInterval).After(deadline) {
+			return false
+		}
+
+		stdout, stderr := "", ""
+		if result != nil {
+			stdout, stderr = result.Stdout, result.Stderr
+		}
+		logf("%s 服务未就绪（第 %d 次检查，剩余 %s）: %v, Stdout: %s, Stderr: %s",
+			unit, attempt, time.Until(deadline).Round(time.Second), err, stdout, stderr)
+		time.Sleep(policy.PollInterval)
+	}
+}
+
+// waitForRollout 使用 kubectl rollout status 观察 Deployment，按 PollInterval 分段等待，
+// 每段结束时检查 Pod 是否处于不可恢复的等待状态以便提前失败
+func (m *Manager) waitForRollout(client *ssh.Client, namespace, deployment string, policy WaitPolicy) error {
+	deadline := time.Now().Add(policy.DeploymentTimeout)
+	for {
+		remaining := time.Until(deadline)
+		if remaining <= 0 {
+			return m.rolloutError(client, namespace, deployment, fmt.Sprintf("等待启动超时（%s）", policy.DeploymentTimeout))
+		}
+		segment := policy.PollInterval
+		if segment > remaining {
+			segment = remaining
+		}
+		if segment < time.Second {
+			segment = time.Second
+		}
+
+		cmd := fmt.Sprintf("kubectl rollout status deployment/%s -n %s --timeout=%ds", deployment, namespace, int(segment.Seconds()))
+		if _, err := runKubectlIdempotent(client, cmd); err == nil {
+			return nil
+		}
+
+		if reason := m.podFailure(client, namespace, deployment); reason != "" {
+			return m.rolloutError(client, namespace, deployment, "启动失败: "+reason)
+		}
+		m.logger.Infof("组件 %s 尚未就绪，剩余等待时间 %s", deployment, time.Until(deadline).Round(time.Second))
+	}
+}
+
+// podFailure 返回 Deployment 下 Pod 的不可恢复等待原因
+func (m *Manager) podFailure(client *ssh.Client, namespace, deployment string) string {
+	cmd := fmt.Sprintf("kubectl get pods -n %s -l app=%s -o jsonpath='{range .items[*]}{.status.containerStatuses[*].state.waiting.reason}{\"\\n\"}{end}'", namespace, deployment)
+	result, err := runKubectlIdempotent(client, cmd)
+	if err != nil {
+		return ""
+	}
+
+	for _, reason := range fatalWaitingReasons {
+		if strings.Contains(result.Stdout, reason) {
+			return reason
+		}
+	}
+	return ""
+}
@@ -0,0 +1,227 @@
+package k3s
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// inSuite 组件角色
+const (
+	RoleDatabase   = "database"
+	RoleMiddleware = "middleware"
+	RoleApp        = "app"
+)
+
+// 多副本组件在节点间的分散方式
+const (
+	// AntiAffinityPreferred 尽量分散到不同节点，节点不足时允许同节点多副本
+	AntiAffinityPreferred = "preferred"
+	// AntiAffinityRequired 每个节点最多一个副本，副本数不能超过带角色标签的节点数
+	AntiAffinityRequired = "required"
+)
+
+// quantityPattern Kubernetes 资源数量（如 500m、1、256Mi）
+var quantityPattern = regexp.MustCompile(`^[0-9]+(\.[0-9]+)?(m|k|M|G|T|Ki|Mi|Gi|Ti)?$`)
+
+// Resources 容器资源请求与限制，为空的字段不写入清单
+type Resources struct {
+	RequestsCPU    string
+	RequestsMemory string
+	LimitsCPU      string
+	LimitsMemory   string
+}
+
+// Validate 检查资源数量格式
+func (r Resources) Validate() error {
+	for _, q := range []string{r.RequestsCPU, r.RequestsMemory, r.LimitsCPU, r.LimitsMemory} {
+		if q != "" && !quantityPattern.MatchString(q) {
+			return fmt.Errorf("无效的资源数量: %s", q)
+		}
+	}
+	return nil
+}
+
+// Probe 存活与就绪探针参数（秒）。检查方式按组件固定：数据库 pg_isready，中间件 redis-cli ping，应用 HTTP GET
+type Probe struct {
+	Disabled bool
+	// Path 应用组件 HTTP 检查的路径
+	Path             string
+	ReadinessDelay   int
+	LivenessDelay    int
+	Period           int
+	FailureThreshold int
+}
+
+// Component inSuite 组件的副本、资源与探针设置
+type Component struct {
+	Replicas int
+	// AntiAffinity 多副本时的分散方式，为空时使用 AntiAffinityPreferred
+	AntiAffinity string
+	Resources    Resources
+	Probe        Probe
+}
+
+// DefaultComponents 各角色组件的默认设置
+func DefaultComponents() map[string]Component {
+	probe := Probe{ReadinessDelay: 5, LivenessDelay: 30, Period: 10, FailureThreshold: 3}
+	appProbe := probe
+	appProbe.Path = "/"
+	return map[string]Component{
+		RoleDatabase: {
+			Replicas:  1,
+			Resources: Resources{RequestsCPU: "250m", RequestsMemory: "256Mi", LimitsCPU: "1", LimitsMemory: "1Gi"},
+			Probe:     probe,
+		},
+		RoleMiddleware: {
+			Replicas:  1,
+			Resources: Resources{RequestsCPU: "100m", RequestsMemory: "128Mi", LimitsCPU: "500m", LimitsMemory: "512Mi"},
+			Probe:     probe,
+		},
+		RoleApp: {
+			Replicas:  1,
+			Resources: Resources{RequestsCPU: "100m", RequestsMemory: "128Mi", LimitsCPU: "500m", LimitsMemory: "256Mi"},
+			Probe:     appProbe,
+		},
+	}
+}
+
+// probeHandler 各角色组件探针的检查方式（缩进到探针字段下）
+func probeHandler(role string, probe Probe) string {
+	switch role {
+	case RoleDatabase:
+		return "          exec:\n            command: [\"pg_isready\", \"-U\", \"insuite\", \"-d\", \"insuite\"]\n"
+	case RoleMiddleware:
+		return "          exec:\n            command: [\"redis-cli\", \"ping\"]\n"
+	default:
+		return fmt.Sprintf("          httpGet:\n            path: %q\n            port: 80\n", probe.Path)
+	}
+}
+
+// containerSpec 容器的 resources、readinessProbe 和 livenessProbe 字段
+func (c Component) containerSpec(role string) string {
+	var b strings.Builder
+
+	requests := quantities(map[string]string{"cpu": c.Resources.RequestsCPU, "memory": c.Resources.RequestsMemory})
+	limits := quantities(map[string]string{"cpu": c.Resources.LimitsCPU, "memory": c.Resources.LimitsMemory})
+	if requests != "" || limits != "" {
+		b.WriteString("        resources:\n")
+		if requests != "" {
+			b.WriteString("          requests:\n" + requests)
+		}
+		if limits != "" {
+			b.WriteString("          limits:\n" + limits)
+		}
+	}
+
+	if !c.Probe.Disabled {
+		handler := probeHandler(role, c.Probe)
+		for _, p := range []struct {
+			name  string
+			delay int
+		}{{"readinessProbe", c.Probe.ReadinessDelay}, {"livenessProbe", c.Probe.LivenessDelay}} {
+			fmt.Fprintf(&b, "        %s:\n%s          initialDelaySeconds: %d\n          periodSeconds: %d\n          failureThreshold: %d\n",
+				p.name, handler, p.delay, c.Probe.Period, c.Probe.FailureThreshold)
+		}
+	}
+	return b.String()
+}
+
+// strategy Deployment 的更新策略。强制反亲和时节点没有空位容纳新副本，先停旧副本再启动新副本
+func (c Component) strategy() string {
+	if c.Replicas <= 1 || c.AntiAffinity != AntiAffinityRequired {
+		return ""
+	}
+	return "  strategy:\n    type: RollingUpdate\n    rollingUpdate:\n      maxSurge: 0\n      maxUnavailable: 1\n"
+}
+
+// scheduling 多副本时 Pod 的反亲和与拓扑分布约束，使副本分散到带角色标签的不同节点
+func (c Component) scheduling(role string) string {
+	if c.Replicas <= 1 {
+		return ""
+	}
+
+	app := "insuite-" + role
+	var b strings.Builder
+	b.WriteString("      affinity:\n        podAntiAffinity:\n")
+	whenUnsatisfiable := "ScheduleAnyway"
+	if c.AntiAffinity == AntiAffinityRequired {
+		whenUnsatisfiable = "DoNotSchedule"
+		fmt.Fprintf(&b, `          requiredDuringSchedulingIgnoredDuringExecution:
+          - labelSelector:
+              matchLabels:
+                app: %s
+            topologyKey: kubernetes.io/hostname
+`, app)
+	} else {
+		fmt.Fprintf(&b, `          preferredDuringSchedulingIgnoredDuringExecution:
+          - weight: 100
+            podAffinityTerm:
+              labelSelector:
+                matchLabels:
+                  app: %s
+              topologyKey: kubernetes.io/hostname
+`, app)
+	}
+	fmt.Fprintf(&b, `      topologySpreadConstraints:
+      - maxSkew: 1
+        topologyKey: kubernetes.io/hostname
+        whenUnsatisfiable: %s
+        labelSelector:
+          matchLabels:
+            app: %s
+`, whenUnsatisfiable, app)
+	return b.String()
+}
+
+func quantities(values map[string]string) string {
+	var b strings.Builder
+	for _, name := range []string{"cpu", "memory"} {
+		if values[name] != "" {
+			fmt.Fprintf(&b, "            %s: %q\n", name, values[name])
+		}
+	}
+	return b.String()
+}
+
+// AppSpec inSuite 应用的部署参数。同一集群可部署多个实例，每个实例独占一个命名空间
+type AppSpec struct {
+	// Instance 实例名，为空时使用 DefaultInstance
+	Instance string
+	// Namespace 实例的命名空间，为空时与实例名相同
+	Namespace string
+	// Quota 命名空间资源配额，为 nil 时不限制
+	Quota *Quota
+	// LimitRange 命名空间内容器的资源默认值与上限，为 nil 时不限制
+	LimitRange *LimitRange
+	// NetworkPolicy 命名空间的默认拒绝网络策略，为 nil 时不限制
+	NetworkPolicy *NetworkPolicy
+	// ObjectStorage 集群内对象存储，设置时应用组件通过 insuite-object-storage Secret 获得访问信息
+	ObjectStorage *ObjectStorage
+	// Database 由 CloudNativePG 管理的数据库，为 nil 时以单副本 Deployment 部署
+	Database *ManagedDatabase
+	Exposure Exposure
+	// Components 按角色覆盖组件设置，缺少的角色使用 DefaultComponents
+	Components map[string]Component
+}
+
+func (s AppSpec) component(role string) Component {
+	if c, ok := s.Components[role]; ok {
+		return c
+	}
+	return DefaultComponents()[role]
+}
+
+func (s AppSpec) instance() string {
+	if s.Instance == "" {
+		return DefaultInstance
+	}
+	return s.Instance
+}
+
+func (s AppSpec) namespace() string {
+	if s.Namespace == "" {
+		return s.instance()
+	}
+	return s.Namespace
+}
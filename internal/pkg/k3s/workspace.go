@@ -0,0 +1,98 @@
+package k3s
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"sync"
+	"time"
+
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// WorkspaceRoot 远程节点上各次部署工作目录的父目录
+const WorkspaceRoot = "/tmp/k3s-deploy"
+
+var unsafeWorkspaceChars = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+// Workspace 一次部署在远程节点上的独立工作目录，避免并发部署的临时文件互相覆盖
+type Workspace struct {
+	client *ssh.Client
+	dir    string
+
+	mu        sync.Mutex
+	artifacts []string
+}
+
+// NewWorkspace 在远程节点上创建以 id 命名的工作目录
+func NewWorkspace(client *ssh.Client, id string) (*Workspace, error) {
+	id = unsafeWorkspaceChars.ReplaceAllString(id, "-")
+	if id == "" || id == "." || id == ".." {
+		return nil, fmt.Errorf("无效的工作目录标识")
+	}
+
+	dir := path.Join(WorkspaceRoot, id)
+	if _, err := client.ExecuteIdempotentCommand(fmt.Sprintf("mkdir -p %s && chmod 700 %s", dir, dir)); err != nil {
+		return nil, fmt.Errorf("创建工作目录 %s 失败: %v", dir, err)
+	}
+	return &Workspace{client: client, dir: dir}, nil
+}
+
+// Dir 工作目录路径
+func (w *Workspace) Dir() string {
+	return w.dir
+}
+
+// Path 返回工作目录下的文件路径
+func (w *Workspace) Path(name string) string {
+	return path.Join(w.dir, name)
+}
+
+// Upload 上传文件到工作目录并记录路径
+func (w *Workspace) Upload(name, content string) (string, error) {
+	file := w.Path(name)
+	if err := w.client.UploadFile(content, file); err != nil {
+		return "", err
+	}
+
+	w.mu.Lock()
+	w.artifacts = append(w.artifacts, file)
+	w.mu.Unlock()
+	return file, nil
+}
+
+// Remove 删除工作目录中的文件，用于私钥等用完即删的敏感文件，不等到工作目录清理
+func (w *Workspace) Remove(file string) error {
+	if _, err := w.client.ExecuteIdempotentCommand("rm -f " + ssh.Quote(file)); err != nil {
+		return fmt.Errorf("删除 %s 失败: %v", file, err)
+	}
+	return nil
+}
+
+// Artifacts 已上传的文件路径
+func (w *Workspace) Artifacts() []string {
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	return append([]string(nil), w.artifacts...)
+}
+
+// PruneWorkspaces 删除节点上超过 olderThan 未修改的工作目录（异常中断的部署遗留）
+func PruneWorkspaces(client *ssh.Client, olderThan time.Duration) error {
+	minutes := int(olderThan.Minutes())
+	if minutes < 1 {
+		minutes = 1
+	}
+	cmd := fmt.Sprintf("[ ! -d %[1]s ] || find %[1]s -mindepth 1 -maxdepth 1 -type d -mmin +%[2]d -exec rm -rf {} +", WorkspaceRoot, minutes)
+	if _, err := client.ExecuteIdempotentCommand(cmd); err != nil {
+		return fmt.Errorf("清理过期工作目录失败: %v", err)
+	}
+	return nil
+}
+
+// Cleanup 删除工作目录
+func (w *Workspace) Cleanup() error {
+	if _, err := w.client.ExecuteIdempotentCommand("rm -rf " + w.dir); err != nil {
+		return fmt.Errorf("清理工作目录 %s 失败: %v", w.dir, err)
+	}
+	return nil
+}
@@ -0,0 +1,92 @@
+package notify
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// 事件类型
+const (
+	EventAlertFiring   = "alert.firing"
+	EventAlertResolved = "alert.resolved"
+	EventCertExpiring  = "cert.expiring"
+	EventTaskSucceeded = "task.succeeded"
+	EventTaskFailed    = "task.failed"
+)
+
+// Event 通知事件
+type Event struct {
+	Type    string      `json:"type"`
+	Title   string      `json:"title"`
+	Message string      `json:"message"`
+	Time    time.Time   `json:"time"`
+	Data    interface{} `json:"data,omitempty"`
+}
+
+// Notifier 通知渠道
+type Notifier interface {
+	Name() string
+	Notify(event Event) error
+}
+
+// Multi 将事件依次发送到多个通知渠道
+type Multi []Notifier
+
+func (m Multi) Name() string {
+	names := make([]string, 0, len(m))
+	for _, n := range m {
+		names = append(names, n.Name())
+	}
+	return strings.Join(names, ",")
+}
+
+// Notify 发送到全部渠道，单个渠道失败不影响其他渠道
+func (m Multi) Notify(event Event) error {
+	var errs []error
+	for _, n := range m {
+		if err := n.Notify(event); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", n.Name(), err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// WebhookNotifier 以 JSON POST 推送事件
+type WebhookNotifier struct {
+	url    string
+	client *http.Client
+}
+
+func NewWebhookNotifier(url string) *WebhookNotifier {
+	return &WebhookNotifier{
+		url:    url,
+		client: &http.Client{Timeout: 10 * time.Second},
+	}
+}
+
+func (n *WebhookNotifier) Name() string {
+	return "webhook"
+}
+
+func (n *WebhookNotifier) Notify(event Event) error {
+	body, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	resp, err := n.client.Post(n.url, "application/json", bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("推送 Webhook 失败: %v", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode >= 300 {
+		return fmt.Errorf("推送 Webhook 失败: HTTP %d", resp.StatusCode)
+	}
+	return nil
+}
@@ -0,0 +1,252 @@
+package notify
+
+import (
+	"bytes"
+	"crypto/tls"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"mime"
+	"net"
+	"net/smtp"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// SMTPOptions 邮件通知参数
+type SMTPOptions struct {
+	Host     string
+	Port     int
+	Username string
+	Password string
+	From     string
+	// TLS 为 true 时使用隐式 TLS（通常为 465 端口），否则在服务器支持时使用 STARTTLS
+	TLS bool
+	// TemplateDir 自定义模板目录，存在 <事件类型>.html 时覆盖内置模板
+	TemplateDir   string
+	Subscriptions []Subscription
+}
+
+// Subscription 一组收件人及其订阅的事件类型，Events 为空或包含 * 时订阅全部事件
+type Subscription struct {
+	To     []string
+	Events []string
+}
+
+func (s Subscription) wants(eventType string) bool {
+	if len(s.Events) == 0 {
+		return true
+	}
+	for _, e := range s.Events {
+		if e == "*" || e == eventType {
+			return true
+		}
+	}
+	return false
+}
+
+const (
+	smtpDialTimeout = 10 * time.Second
+	// smtpSessionTimeout 从建立连接到发送完成的总期限
+	smtpSessionTimeout = time.Minute
+)
+
+// SMTPNotifier 以 HTML 邮件发送事件
+type SMTPNotifier struct {
+	opts SMTPOptions
+}
+
+func NewSMTPNotifier(opts SMTPOptions) *SMTPNotifier {
+	return &SMTPNotifier{opts: opts}
+}
+
+func (n *SMTPNotifier) Name() string {
+	return "email"
+}
+
+func (n *SMTPNotifier) Notify(event Event) error {
+	var to []string
+	seen := make(map[string]bool)
+	for _, sub := range n.opts.Subscriptions {
+		if !sub.wants(event.Type) {
+			continue
+		}
+		for _, addr := range sub.To {
+			if !seen[addr] {
+				seen[addr] = true
+				to = append(to, addr)
+			}
+		}
+	}
+	if len(to) == 0 {
+		return nil
+	}
+
+	body, err := n.render(event)
+	if err != nil {
+		return err
+	}
+	return n.send(to, event.Title, body)
+}
+
+// defaultTemplate 内置邮件模板
+const defaultTemplate = `<!DOCTYPE html>
+<html>
+<body style="font-family: sans-serif; color: #333;">
+  <h2 style="margin-bottom: 4px;">{{.Title}}</h2>
+  <p style="color: #888; margin-top: 0;">{{.Type}} · {{.Time.Format "2006-01-02 15:04:05"}}</p>
+  <p style="white-space: pre-wrap;">{{.Message}}</p>
+  {{if .Fields}}
+  <table style="border-collapse: collapse;">
+    {{range .Fields}}
+    <tr>
+      <th style="text-align: left; padding: 4px 12px 4px 0; border-bottom: 1px solid #eee;">{{.Name}}</th>
+      <td style="padding: 4px 0; border-bottom: 1px solid #eee;">{{.Value}}</td>
+    </tr>
+    {{end}}
+  </table>
+  {{end}}
+</body>
+</html>
+`
+
+type templateField struct {
+	Name  string
+	Value string
+}
+
+type templateData struct {
+	Event
+	Fields []templateField
+}
+
+func (n *SMTPNotifier) render(event Event) (string, error) {
+	text := defaultTemplate
+	if n.opts.TemplateDir != "" {
+		if custom, err := os.ReadFile(filepath.Join(n.opts.TemplateDir, event.Type+".html")); err == nil {
+			text = string(custom)
+		}
+	}
+
+	tmpl, err := template.New(event.Type).Parse(text)
+	if err != nil {
+		return "", fmt.Errorf("解析邮件模板失败: %v", err)
+	}
+
+	var buf bytes.Buffer
+	if err := tmpl.Execute(&buf, templateData{Event: event, Fields: flattenFields(event.Data)}); err != nil {
+		return "", fmt.Errorf("渲染邮件模板失败: %v", err)
+	}
+	return buf.String(), nil
+}
+
+// flattenFields 将事件数据的顶层字段转换为表格行，嵌套结构以 JSON 展示
+func flattenFields(data interface{}) []templateField {
+	if data == nil {
+		return nil
+	}
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil
+	}
+	var fields map[string]interface{}
+	if err := json.Unmarshal(raw, &fields); err != nil {
+		return nil
+	}
+
+	keys := make([]string, 0, len(fields))
+	for key := range fields {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	result := make([]templateField, 0, len(keys))
+	for _, key := range keys {
+		var value string
+		switch v := fields[key].(type) {
+		case string:
+			value = v
+		case nil:
+			continue
+		default:
+			encoded, _ := json.Marshal(v)
+			value = string(encoded)
+		}
+		result = append(result, templateField{Name: key, Value: value})
+	}
+	return result
+}
+
+func (n *SMTPNotifier) send(to []string, subject, body string) error {
+	var msg bytes.Buffer
+	fmt.Fprintf(&msg, "From: %s\r\n", n.opts.From)
+	fmt.Fprintf(&msg, "To: %s\r\n", strings.Join(to, ", "))
+	fmt.Fprintf(&msg, "Subject: %s\r\n", mime.QEncoding.Encode("utf-8", subject))
+	fmt.Fprintf(&msg, "Date: %s\r\n", time.Now().Format(time.RFC1123Z))
+	msg.WriteString("MIME-Version: 1.0\r\n")
+	msg.WriteString("Content-Type: text/html; charset=UTF-8\r\n")
+	msg.WriteString("\r\n")
+	msg.WriteString(body)
+
+	addr := net.JoinHostPort(n.opts.Host, strconv.Itoa(n.opts.Port))
+	var auth smtp.Auth
+	if n.opts.Username != "" {
+		auth = smtp.PlainAuth("", n.opts.Username, n.opts.Password, n.opts.Host)
+	}
+
+	// 连接和整个会话都有期限，邮件服务器无响应时不会一直占用发送方
+	dialer := &net.Dialer{Timeout: smtpDialTimeout}
+	var conn net.Conn
+	var err error
+	if n.opts.TLS {
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, &tls.Config{ServerName: n.opts.Host})
+	} else {
+		conn, err = dialer.Dial("tcp", addr)
+	}
+	if err != nil {
+		return fmt.Errorf("连接邮件服务器失败: %v", err)
+	}
+	conn.SetDeadline(time.Now().Add(smtpSessionTimeout))
+	client, err := smtp.NewClient(conn, n.opts.Host)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("连接邮件服务器失败: %v", err)
+	}
+	defer client.Close()
+
+	if !n.opts.TLS {
+		if ok, _ := client.Extension("STARTTLS"); ok {
+			if err := client.StartTLS(&tls.Config{ServerName: n.opts.Host}); err != nil {
+				return fmt.Errorf("STARTTLS 失败: %v", err)
+			}
+		}
+	}
+	if auth != nil {
+		if err := client.Auth(auth); err != nil {
+			return fmt.Errorf("邮件服务器认证失败: %v", err)
+		}
+	}
+	if err := client.Mail(n.opts.From); err != nil {
+		return fmt.Errorf("发送邮件失败: %v", err)
+	}
+	for _, rcpt := range to {
+		if err := client.Rcpt(rcpt); err != nil {
+			return fmt.Errorf("收件人 %s 被拒绝: %v", rcpt, err)
+		}
+	}
+	w, err := client.Data()
+	if err != nil {
+		return fmt.Errorf("发送邮件失败: %v", err)
+	}
+	if _, err := w.Write(msg.Bytes()); err != nil {
+		return fmt.Errorf("发送邮件失败: %v", err)
+	}
+	if err := w.Close(); err != nil {
+		return fmt.Errorf("发送邮件失败: %v", err)
+	}
+	return client.Quit()
+}
@@ -0,0 +1,212 @@
+package pki
+
+import (
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const keyBits = 2048
+
+// CertificateAuthority 表示一个 CA
+type CertificateAuthority struct {
+	Cert       *x509.Certificate
+	PrivateKey *rsa.PrivateKey
+}
+
+// GeneratePrivateKey 生成 RSA 私钥
+func GeneratePrivateKey() (*rsa.PrivateKey, error) {
+	return rsa.GenerateKey(rand.Reader, keyBits)
+}
+
+// NewTemplate 创建证书模板，CA 证书额外带有签发证书和 CRL 的用途
+func NewTemplate(cn string, isCA bool, usage []x509.ExtKeyUsage, notAfter time.Time) (*x509.Certificate, error) {
+	serialNumber, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate serial number: %v", err)
+	}
+
+	template := &x509.Certificate{
+		SerialNumber: serialNumber,
+		Subject: pkix.Name{
+			CommonName: cn,
+		},
+		NotBefore:             time.Now(),
+		NotAfter:              notAfter,
+		KeyUsage:              x509.KeyUsageDigitalSignature | x509.KeyUsageKeyEncipherment,
+		ExtKeyUsage:           usage,
+		BasicConstraintsValid: true,
+		IsCA:                  isCA,
+	}
+
+	if isCA {
+		template.KeyUsage |= x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	}
+
+	return template, nil
+}
+
+// GenerateCA 生成自签名 CA 证书
+func GenerateCA(cn string, notAfter time.Time) (*CertificateAuthority, error) {
+	// 生成私钥
+	privateKey, err := GeneratePrivateKey()
+	if err != nil {
+		return nil, fmt.Errorf("failed to generate private key: %v", err)
+	}
+
+	// 创建证书模板
+	template, err := NewTemplate(cn, true, nil, notAfter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate template: %v", err)
+	}
+
+	// 生成自签名证书
+	certDER, err := x509.CreateCertificate(rand.Reader, template, template, &privateKey.PublicKey, privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate: %v", err)
+	}
+
+	// 解析证书
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse certificate: %v", err)
+	}
+
+	return &CertificateAuthority{
+		Cert:       cert,
+		PrivateKey: privateKey,
+	}, nil
+}
+
+// Issue 按模板生成新私钥并用 CA 签发证书
+func (ca *CertificateAuthority) Issue(template *x509.Certificate) (*x509.Certificate, *rsa.PrivateKey, error) {
+	// 生成私钥
+	privateKey, err := GeneratePrivateKey()
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to generate private key: %v", err)
+	}
+
+	// 使用 CA 签名
+	certDER, err := x509.CreateCertificate(rand.Reader, template, ca.Cert, &privateKey.PublicKey, ca.PrivateKey)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to create certificate: %v", err)
+	}
+
+	// 解析证书
+	cert, err := x509.ParseCertificate(certDER)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to parse certificate: %v", err)
+	}
+
+	return cert, privateKey, nil
+}
+
+// IssueIntermediate 签发只能签发终端证书的中间 CA（路径长度为 0），有效期不超过上级 CA。
+// 中间 CA 只能签发服务端证书，且证书中的域名必须位于 dnsDomains 之内（含子域名），不能包含 IP 地址
+func (ca *CertificateAuthority) IssueIntermediate(cn string, notAfter time.Time, dnsDomains []string) (*CertificateAuthority, error) {
+	if len(dnsDomains) == 0 {
+		return nil, errors.New("中间 CA 必须限定允许签发的域名")
+	}
+	if notAfter.After(ca.Cert.NotAfter) {
+		notAfter = ca.Cert.NotAfter
+	}
+	template, err := NewTemplate(cn, true, nil, notAfter)
+	if err != nil {
+		return nil, fmt.Errorf("failed to create certificate template: %v", err)
+	}
+	template.MaxPathLen = 0
+	template.MaxPathLenZero = true
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	template.PermittedDNSDomainsCritical = true
+	template.PermittedDNSDomains = dnsDomains
+	template.ExcludedIPRanges = []*net.IPNet{
+		{IP: net.IPv4zero, Mask: net.CIDRMask(0, 32)},
+		{IP: net.IPv6zero, Mask: net.CIDRMask(0, 128)},
+	}
+
+	cert, privateKey, err := ca.Issue(template)
+	if err != nil {
+		return nil, err
+	}
+	return &CertificateAuthority{Cert: cert, PrivateKey: privateKey}, nil
+}
+
+// EncodeCertificate 编码为 PEM
+func EncodeCertificate(cert *x509.Certificate) []byte {
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: cert.Raw})
+}
+
+// EncodePrivateKey 编码为 PKCS#8 PEM
+func EncodePrivateKey(privateKey *rsa.PrivateKey) ([]byte, error) {
+	privKeyBytes, err := x509.MarshalPKCS8PrivateKey(privateKey)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal private key: %v", err)
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: privKeyBytes}), nil
+}
+
+// WriteCertificateAndKey 将证书和私钥写入本地文件，私钥权限为 0600
+func WriteCertificateAndKey(cert *x509.Certificate, privateKey *rsa.PrivateKey, certFile, keyFile string) error {
+	keyPEM, err := EncodePrivateKey(privateKey)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(certFile), 0700); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0700); err != nil {
+		return err
+	}
+	if err := os.WriteFile(certFile, EncodeCertificate(cert), 0644); err != nil {
+		return err
+	}
+	return os.WriteFile(keyFile, keyPEM, 0600)
+}
+
+// LoadCertificate 读取 PEM 证书文件
+func LoadCertificate(certFile string) (*x509.Certificate, error) {
+	data, err := os.ReadFile(certFile)
+	if err != nil {
+		return nil, err
+	}
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, fmt.Errorf("%s 不是 PEM 证书", certFile)
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+// LoadCA 读取 CA 证书和私钥
+func LoadCA(certFile, keyFile string) (*CertificateAuthority, error) {
+	cert, err := LoadCertificate(certFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取 CA 证书失败: %w", err)
+	}
+
+	data, err := os.ReadFile(keyFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取 CA 私钥失败: %w", err)
+	}
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, fmt.Errorf("%s 不是 PEM 私钥", keyFile)
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析 CA 私钥失败: %w", err)
+	}
+	privateKey, ok := key.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("CA 私钥不是 RSA 密钥")
+	}
+	return &CertificateAuthority{Cert: cert, PrivateKey: privateKey}, nil
+}
@@ -0,0 +1,150 @@
+package pki
+
+import (
+	"crypto/rand"
+	"crypto/x509"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"os"
+	"sync"
+	"time"
+)
+
+// crlValidity 每次吊销后重新签发的 CRL 有效期
+const crlValidity = 365 * 24 * time.Hour
+
+// LoadRevocationList 读取并校验 CRL 文件，文件不存在时返回 nil
+func LoadRevocationList(path string, ca *x509.Certificate) (*x509.RevocationList, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "X509 CRL" {
+		return nil, fmt.Errorf("%s 不是 PEM 格式的 CRL", path)
+	}
+	crl, err := x509.ParseRevocationList(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("解析 CRL 失败: %w", err)
+	}
+	if err := crl.CheckSignatureFrom(ca); err != nil {
+		return nil, fmt.Errorf("CRL 不是由当前 CA 签发: %w", err)
+	}
+	return crl, nil
+}
+
+// LoadCurrentRevocationList 读取用于校验客户端证书的 CRL。与 LoadRevocationList 不同，
+// 文件不存在或已过下次更新时间（NextUpdate）时返回错误，调用方应拒绝连接而不是视为没有吊销的证书
+func LoadCurrentRevocationList(path string, ca *x509.Certificate) (*x509.RevocationList, error) {
+	crl, err := LoadRevocationList(path, ca)
+	if err != nil {
+		return nil, err
+	}
+	if crl == nil {
+		return nil, fmt.Errorf("CRL 文件 %s 不存在，可使用 k3s-deploy-pki crl 生成", path)
+	}
+	if err := checkNextUpdate(crl, time.Now()); err != nil {
+		return nil, err
+	}
+	return crl, nil
+}
+
+// checkNextUpdate 检查 CRL 是否已过下次更新时间，未设置 NextUpdate 的 CRL 不过期
+func checkNextUpdate(crl *x509.RevocationList, now time.Time) error {
+	if !crl.NextUpdate.IsZero() && now.After(crl.NextUpdate) {
+		return fmt.Errorf("CRL 已于 %s 过期，可使用 k3s-deploy-pki crl 重新签发", crl.NextUpdate.Format(time.RFC3339))
+	}
+	return nil
+}
+
+// Revoke 将证书序列号加入 CRL 并重新签发，已吊销的序列号会被忽略；不指定序列号时只续期 CRL
+func (ca *CertificateAuthority) Revoke(path string, serials ...*big.Int) error {
+	current, err := LoadRevocationList(path, ca.Cert)
+	if err != nil {
+		return err
+	}
+
+	number := big.NewInt(1)
+	var entries []x509.RevocationListEntry
+	revoked := make(map[string]bool)
+	if current != nil {
+		number.Add(current.Number, big.NewInt(1))
+		entries = current.RevokedCertificateEntries
+		for _, entry := range entries {
+			revoked[entry.SerialNumber.String()] = true
+		}
+	}
+
+	now := time.Now()
+	for _, serial := range serials {
+		if revoked[serial.String()] {
+			continue
+		}
+		revoked[serial.String()] = true
+		entries = append(entries, x509.RevocationListEntry{SerialNumber: serial, RevocationTime: now})
+	}
+
+	der, err := x509.CreateRevocationList(rand.Reader, &x509.RevocationList{
+		Number:                    number,
+		ThisUpdate:                now,
+		NextUpdate:                now.Add(crlValidity),
+		RevokedCertificateEntries: entries,
+	}, ca.Cert, ca.PrivateKey)
+	if err != nil {
+		return fmt.Errorf("签发 CRL 失败: %w", err)
+	}
+	return os.WriteFile(path, pem.EncodeToMemory(&pem.Block{Type: "X509 CRL", Bytes: der}), 0644)
+}
+
+// RevocationChecker 检查证书是否已被吊销，CRL 文件更新后自动重新加载，无需重启服务
+type RevocationChecker struct {
+	path string
+	ca   *x509.Certificate
+
+	mu      sync.Mutex
+	modTime time.Time
+	crl     *x509.RevocationList
+	revoked map[string]bool
+}
+
+func NewRevocationChecker(path string, ca *x509.Certificate) *RevocationChecker {
+	return &RevocationChecker{path: path, ca: ca, revoked: make(map[string]bool)}
+}
+
+// Revoked 判断序列号是否在 CRL 中；CRL 不存在、无法读取、校验失败或已过期时返回错误，调用方应拒绝连接
+func (r *RevocationChecker) Revoked(serial *big.Int) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	info, err := os.Stat(r.path)
+	if err != nil {
+		r.crl = nil
+		if os.IsNotExist(err) {
+			return false, fmt.Errorf("CRL 文件 %s 不存在，可使用 k3s-deploy-pki crl 生成", r.path)
+		}
+		return false, err
+	}
+	if r.crl == nil || !info.ModTime().Equal(r.modTime) {
+		crl, err := LoadCurrentRevocationList(r.path, r.ca)
+		if err != nil {
+			return false, err
+		}
+		revoked := make(map[string]bool)
+		for _, entry := range crl.RevokedCertificateEntries {
+			revoked[entry.SerialNumber.String()] = true
+		}
+		r.crl = crl
+		r.revoked = revoked
+		r.modTime = info.ModTime()
+	}
+	// 已加载的 CRL 同样需要在每次检查时确认未过期
+	if err := checkNextUpdate(r.crl, time.Now()); err != nil {
+		return false, err
+	}
+	return r.revoked[serial.String()], nil
+}
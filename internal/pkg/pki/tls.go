@@ -0,0 +1,71 @@
+package pki
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+)
+
+// 客户端证书校验模式
+const (
+	// ClientAuthNone 不要求客户端证书
+	ClientAuthNone = "none"
+	// ClientAuthOptional 客户端提供证书时校验，未提供时仍可使用会话令牌访问
+	ClientAuthOptional = "optional"
+	// ClientAuthRequire 所有连接都必须提供有效的客户端证书
+	ClientAuthRequire = "require"
+)
+
+// ServerTLSOptions API 服务端 TLS 配置
+type ServerTLSOptions struct {
+	ClientAuth   string
+	ClientCAFile string
+	// CRLFile 客户端证书吊销列表，为空表示不检查吊销
+	CRLFile string
+}
+
+// ServerTLSConfig 构造服务端 TLS 配置，启用客户端证书校验时拒绝已吊销的证书
+func ServerTLSConfig(opts ServerTLSOptions) (*tls.Config, error) {
+	config := &tls.Config{MinVersion: tls.VersionTLS12}
+	if opts.ClientAuth == "" || opts.ClientAuth == ClientAuthNone {
+		return config, nil
+	}
+
+	ca, err := LoadCertificate(opts.ClientCAFile)
+	if err != nil {
+		return nil, fmt.Errorf("读取客户端 CA 证书失败: %w", err)
+	}
+	pool := x509.NewCertPool()
+	pool.AddCert(ca)
+	config.ClientCAs = pool
+
+	switch opts.ClientAuth {
+	case ClientAuthOptional:
+		config.ClientAuth = tls.VerifyClientCertIfGiven
+	case ClientAuthRequire:
+		config.ClientAuth = tls.RequireAndVerifyClientCert
+	default:
+		return nil, fmt.Errorf("不支持的客户端证书校验模式: %s", opts.ClientAuth)
+	}
+
+	if opts.CRLFile != "" {
+		checker := NewRevocationChecker(opts.CRLFile, ca)
+		if _, err := checker.Revoked(ca.SerialNumber); err != nil {
+			return nil, fmt.Errorf("读取证书吊销列表失败: %w", err)
+		}
+		config.VerifyPeerCertificate = func(_ [][]byte, chains [][]*x509.Certificate) error {
+			for _, chain := range chains {
+				revoked, err := checker.Revoked(chain[0].SerialNumber)
+				if err != nil {
+					return fmt.Errorf("检查证书吊销状态失败: %w", err)
+				}
+				if revoked {
+					return errors.New("客户端证书已被吊销")
+				}
+			}
+			return nil
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,63 @@
+package progress
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sync"
+
+	"k3s-deploy-backend/internal/model"
+)
+
+// FileWriter 将带请求 ID 的事件追加到 <dir>/<请求 ID>.jsonl，用于离线分析部署过程
+type FileWriter struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewFileWriter 创建事件目录
+func NewFileWriter(dir string) (*FileWriter, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("创建进度事件目录失败: %v", err)
+	}
+	return &FileWriter{dir: dir}, nil
+}
+
+// Write 写入一条事件，没有请求 ID 的后台操作不写入
+func (w *FileWriter) Write(event model.ProgressEvent) error {
+	if event.RequestID == "" {
+		return nil
+	}
+	data, err := json.Marshal(&event)
+	if err != nil {
+		return err
+	}
+
+	w.mu.Lock()
+	defer w.mu.Unlock()
+	f, err := os.OpenFile(w.path(event.RequestID), os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0600)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	_, err = f.Write(append(data, '\n'))
+	return err
+}
+
+// Remove 删除请求的事件文件
+func (w *FileWriter) Remove(requestID string) error {
+	if err := os.Remove(w.path(requestID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// unsafeName 请求 ID 可由调用方通过 X-Request-ID 传入，文件名中只保留安全字符
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+func (w *FileWriter) path(requestID string) string {
+	return filepath.Join(w.dir, unsafeName.ReplaceAllString(requestID, "_")+".jsonl")
+}
@@ -0,0 +1,138 @@
+// Package progress 进程内的部署进度事件总线。安装器、Manager、预检和任务队列发布结构化事件，
+// 任务日志、事件文件、日志输出、SSE 推送和 Webhook 作为独立的消费者订阅，发布方不依赖具体的输出方式
+package progress
+
+import (
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+)
+
+// Bus 事件总线。Handle 注册的处理函数在发布事件的 goroutine 中同步调用，保证同一请求的事件按发布顺序处理；
+// Subscribe 注册的订阅通过带缓冲的通道异步接收，缓冲区满时丢弃事件，慢速的客户端和 Webhook 不会阻塞部署
+type Bus struct {
+	mu       sync.RWMutex
+	handlers []func(model.ProgressEvent)
+	subs     map[*Subscription]struct{}
+}
+
+// Subscription 异步订阅
+type Subscription struct {
+	bus     *Bus
+	filter  func(model.ProgressEvent) bool
+	ch      chan model.ProgressEvent
+	dropped atomic.Int64
+	once    sync.Once
+}
+
+// Default 进程默认的事件总线
+var Default = New()
+
+func New() *Bus {
+	return &Bus{subs: make(map[*Subscription]struct{})}
+}
+
+// Publish 发布到默认总线
+func Publish(event model.ProgressEvent) {
+	Default.Publish(event)
+}
+
+// Publish 发布事件，未设置时间和级别时使用当前时间和 info
+func (b *Bus) Publish(event model.ProgressEvent) {
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+	if event.Level == "" {
+		event.Level = model.LogInfo
+	}
+
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	for _, handle := range b.handlers {
+		handle(event)
+	}
+	for sub := range b.subs {
+		if sub.filter != nil && !sub.filter(event) {
+			continue
+		}
+		select {
+		case sub.ch <- event:
+		default:
+			sub.dropped.Add(1)
+		}
+	}
+}
+
+// Handle 注册同步处理函数，用于写任务日志、事件文件等快速的本地操作，处理函数需要支持并发调用
+func (b *Bus) Handle(handle func(model.ProgressEvent)) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.handlers = append(b.handlers, handle)
+}
+
+// Subscribe 注册异步订阅，filter 为 nil 表示接收全部事件
+func (b *Bus) Subscribe(buffer int, filter func(model.ProgressEvent) bool) *Subscription {
+	sub := &Subscription{bus: b, filter: filter, ch: make(chan model.ProgressEvent, buffer)}
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.subs[sub] = struct{}{}
+	return sub
+}
+
+// Events 接收事件的通道，取消订阅后关闭
+func (s *Subscription) Events() <-chan model.ProgressEvent {
+	return s.ch
+}
+
+// Dropped 缓冲区满时丢弃的事件数
+func (s *Subscription) Dropped() int64 {
+	return s.dropped.Load()
+}
+
+// Run 在新的 goroutine 中依次处理事件，直到取消订阅
+func (s *Subscription) Run(handle func(model.ProgressEvent)) {
+	go func() {
+		for event := range s.ch {
+			handle(event)
+		}
+	}()
+}
+
+// Close 取消订阅
+func (s *Subscription) Close() {
+	s.once.Do(func() {
+		s.bus.mu.Lock()
+		delete(s.bus.subs, s)
+		s.bus.mu.Unlock()
+		close(s.ch)
+	})
+}
+
+// Types 只接收指定类型事件的过滤器，types 为空时接收全部事件
+func Types(types []string) func(model.ProgressEvent) bool {
+	if len(types) == 0 {
+		return nil
+	}
+	set := make(map[string]bool, len(types))
+	for _, t := range types {
+		set[t] = true
+	}
+	return func(event model.ProgressEvent) bool { return set[event.Type] }
+}
+
+// Phase 发布节点阶段的开始事件，调用返回的函数发布带耗时的结束事件
+func Phase(requestID, node, phase string) func() {
+	start := time.Now()
+	Publish(model.ProgressEvent{Type: model.ProgressPhaseStarted, RequestID: requestID, Node: node, Phase: phase})
+	return func() {
+		Publish(model.ProgressEvent{
+			Type:       model.ProgressPhaseFinished,
+			RequestID:  requestID,
+			Node:       node,
+			Phase:      phase,
+			DurationMs: time.Since(start).Milliseconds(),
+		})
+	}
+}
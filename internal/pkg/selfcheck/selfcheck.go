@@ -0,0 +1,203 @@
+// Package selfcheck 启动自检：在开始接收请求前检查数据目录可写、TLS 证书有效、存储结构与程序一致，
+// 以及（可选）镜像源可达，任一必需项失败时退出，避免运行到部署中途才暴露问题
+package selfcheck
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/pki"
+	"k3s-deploy-backend/internal/pkg/store"
+)
+
+// expiryWarning 证书剩余有效期少于该值时告警
+const expiryWarning = 30 * 24 * time.Hour
+
+// Check 一项自检。Run 返回的说明写入结果的 Message，返回 Warn 包装的错误时只告警
+type Check struct {
+	Name   string
+	Target string
+	// Optional 失败只告警，不阻止启动（如从后端探测镜像源，后端与节点的网络可能不同）
+	Optional bool
+	Run      func(ctx context.Context) (string, error)
+}
+
+// warning 不影响启动的问题
+type warning struct {
+	err error
+}
+
+func (w *warning) Error() string { return w.err.Error() }
+func (w *warning) Unwrap() error { return w.err }
+
+// Warn 返回只告警的错误
+func Warn(format string, args ...any) error {
+	return &warning{err: fmt.Errorf(format, args...)}
+}
+
+// Run 依次执行各项检查，每项的超时为 timeout
+func Run(checks []Check, timeout time.Duration) model.SelfCheckReport {
+	report := model.SelfCheckReport{Status: model.SelfCheckOK, CheckedAt: time.Now(), Checks: []model.SelfCheckResult{}}
+	for _, check := range checks {
+		start := time.Now()
+		ctx, cancel := context.WithTimeout(context.Background(), timeout)
+		message, err := check.Run(ctx)
+		cancel()
+
+		result := model.SelfCheckResult{
+			Name:       check.Name,
+			Target:     check.Target,
+			Status:     model.SelfCheckOK,
+			Message:    message,
+			DurationMs: time.Since(start).Milliseconds(),
+		}
+		var warn *warning
+		switch {
+		case err == nil:
+		case errors.As(err, &warn) || check.Optional:
+			result.Status = model.SelfCheckWarn
+			result.Message = err.Error()
+			if report.Status == model.SelfCheckOK {
+				report.Status = model.SelfCheckWarn
+			}
+		default:
+			result.Status = model.SelfCheckFail
+			result.Message = err.Error()
+			report.Status = model.SelfCheckFail
+		}
+		report.Checks = append(report.Checks, result)
+	}
+	report.DurationMs = time.Since(report.CheckedAt).Milliseconds()
+	return report
+}
+
+// Public 返回去掉各项检查目标和信息的报告副本，供无需登录的探针接口返回，避免暴露目录、地址和错误详情
+func Public(report model.SelfCheckReport) model.SelfCheckReport {
+	checks := make([]model.SelfCheckResult, len(report.Checks))
+	for i, c := range report.Checks {
+		checks[i] = model.SelfCheckResult{Name: c.Name, Status: c.Status, DurationMs: c.DurationMs}
+	}
+	report.Checks = checks
+	return report
+}
+
+// WritableDir 目录不存在时创建，并写入、删除一个临时文件确认可写
+func WritableDir(dir string) (string, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return "", fmt.Errorf("创建目录失败: %w", err)
+	}
+	f, err := os.CreateTemp(dir, ".selfcheck-*")
+	if err != nil {
+		return "", fmt.Errorf("目录不可写: %w", err)
+	}
+	name := f.Name()
+	_, err = f.WriteString("ok")
+	if closeErr := f.Close(); err == nil {
+		err = closeErr
+	}
+	if removeErr := os.Remove(name); err == nil {
+		err = removeErr
+	}
+	if err != nil {
+		return "", fmt.Errorf("目录不可写: %w", err)
+	}
+	return "可写", nil
+}
+
+// ServerCertificate 检查服务端证书与私钥匹配且在有效期内，即将过期时告警
+func ServerCertificate(certFile, keyFile string) (string, error) {
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	if err != nil {
+		return "", fmt.Errorf("加载证书和私钥失败: %w", err)
+	}
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	if err != nil {
+		return "", fmt.Errorf("解析证书失败: %w", err)
+	}
+	return validity(cert)
+}
+
+// ClientCA 检查客户端 CA 证书，配置了吊销列表时检查其存在、格式、签发者和有效期
+func ClientCA(caFile, crlFile string) (string, error) {
+	ca, err := pki.LoadCertificate(caFile)
+	if err != nil {
+		return "", fmt.Errorf("读取客户端 CA 证书失败: %w", err)
+	}
+	if crlFile != "" {
+		if _, err := pki.LoadCurrentRevocationList(crlFile, ca); err != nil {
+			return "", fmt.Errorf("读取证书吊销列表失败: %w", err)
+		}
+	}
+	return validity(ca)
+}
+
+// IssuingCA 检查签发证书使用的 CA。CA 只在需要签发时加载，尚未生成时不算失败
+func IssuingCA(certFile, keyFile string) (string, error) {
+	for _, file := range []string{certFile, keyFile} {
+		if _, err := os.Stat(file); os.IsNotExist(err) {
+			return fmt.Sprintf("%s 不存在，需要签发证书前使用 cmd/pki init 生成", file), nil
+		}
+	}
+	ca, err := pki.LoadCA(certFile, keyFile)
+	if err != nil {
+		return "", err
+	}
+	return validity(ca.Cert)
+}
+
+func validity(cert *x509.Certificate) (string, error) {
+	now := time.Now()
+	expiry := cert.NotAfter.Format("2006-01-02")
+	switch {
+	case now.Before(cert.NotBefore):
+		return "", fmt.Errorf("证书 %s 尚未生效（%s 起）", cert.Subject.CommonName, cert.NotBefore.Format("2006-01-02"))
+	case now.After(cert.NotAfter):
+		return "", fmt.Errorf("证书 %s 已于 %s 过期", cert.Subject.CommonName, expiry)
+	case cert.NotAfter.Sub(now) < expiryWarning:
+		return "", Warn("证书 %s 将于 %s 过期", cert.Subject.CommonName, expiry)
+	}
+	return fmt.Sprintf("证书 %s 有效期至 %s", cert.Subject.CommonName, expiry), nil
+}
+
+// Store 检查存储的连接和数据结构，存储未实现 store.Checker 时跳过
+func Store(s store.Store) (string, error) {
+	checker, ok := s.(store.Checker)
+	if !ok {
+		return "无需检查", nil
+	}
+	if err := checker.Check(); err != nil {
+		return "", err
+	}
+	return "数据结构与程序版本一致", nil
+}
+
+// Registry 访问镜像源的 /v2/ 接口，返回 200 或 401（需要认证）都视为可达。
+// registry 可以是不带协议的主机名（如 registry.system_default），此时使用 https
+func Registry(ctx context.Context, registry string) (string, error) {
+	base := strings.TrimSuffix(registry, "/")
+	if !strings.Contains(base, "://") {
+		base = "https://" + base
+	}
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, base+"/v2/", nil)
+	if err != nil {
+		return "", err
+	}
+	start := time.Now()
+	resp, err := http.DefaultClient.Do(req)
+	if err != nil {
+		return "", fmt.Errorf("无法访问: %w", err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusUnauthorized {
+		return "", fmt.Errorf("/v2/ 返回 HTTP %d", resp.StatusCode)
+	}
+	return fmt.Sprintf("可达（%dms）", time.Since(start).Milliseconds()), nil
+}
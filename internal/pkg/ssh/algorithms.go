@@ -0,0 +1,43 @@
+package ssh
+
+import (
+	"fmt"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// validateAlgorithms 检查自定义算法偏好是否被 SSH 库支持，避免握手阶段才报出含糊的错误
+func validateAlgorithms(config SSHConfig) error {
+	supported := ssh.SupportedAlgorithms()
+	insecure := ssh.InsecureAlgorithms()
+
+	checks := []struct {
+		kind      string
+		requested []string
+		known     [][]string
+	}{
+		{"加密算法", config.Ciphers, [][]string{supported.Ciphers, insecure.Ciphers}},
+		{"密钥交换算法", config.KeyExchanges, [][]string{supported.KeyExchanges, insecure.KeyExchanges}},
+		{"MAC算法", config.MACs, [][]string{supported.MACs, insecure.MACs}},
+	}
+
+	for _, check := range checks {
+		for _, name := range check.requested {
+			if !containsAlgorithm(check.known, name) {
+				return fmt.Errorf("不支持的%s: %s（支持: %v）", check.kind, name, check.known[0])
+			}
+		}
+	}
+	return nil
+}
+
+func containsAlgorithm(lists [][]string, name string) bool {
+	for _, list := range lists {
+		for _, item := range list {
+			if item == name {
+				return true
+			}
+		}
+	}
+	return false
+}
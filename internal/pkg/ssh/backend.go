@@ -0,0 +1,111 @@
+package ssh
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Backend 替代真实 SSH 连接的命令执行后端（如开发和 CI 使用的模拟后端）。
+// 设置后新建的 Client 不再建立网络连接，命令、文件上传和连接检查都交给后端处理
+type Backend interface {
+	// Connect 建立到目标节点的连接，返回错误表示节点不可达或认证失败
+	Connect(config SSHConfig) error
+	// Run 在 host 上执行命令，stdin 为命令的标准输入（可为 nil）。退出码非 0 时通过 ExitCode 返回，不作为错误
+	Run(host, cmd string, stdin []byte) (*CommandResult, error)
+	// Upload 将 content 写入 host 上的 remotePath
+	Upload(host, remotePath string, content []byte) error
+}
+
+var (
+	backendMu sync.RWMutex
+	backend   Backend
+)
+
+// SetBackend 设置命令执行后端，为 nil 时使用真实 SSH 连接
+func SetBackend(b Backend) {
+	backendMu.Lock()
+	defer backendMu.Unlock()
+	backend = b
+}
+
+func currentBackend() Backend {
+	backendMu.RLock()
+	defer backendMu.RUnlock()
+	return backend
+}
+
+// runBackend 通过后端执行命令，退出码非 0 时与真实连接一样返回错误。env 加在命令前，不记入命令日志。
+// 注入的连接中断与真实连接一样，幂等命令重新连接后重试；ctx 结束时不再等待后端返回
+func (c *Client) runBackend(ctx context.Context, cmd string, env []string, stdin []byte, idempotent bool) (result *CommandResult, err error) {
+	start := time.Now()
+	defer func() { c.logCommand(cmd, start, result, err) }()
+
+	for attempt := 0; c.injectFault(cmd); attempt++ {
+		if !idempotent || attempt >= c.maxReconnects() || c.backend.Connect(c.config) != nil {
+			return nil, fmt.Errorf("命令执行失败: %v", errInjectedDrop)
+		}
+	}
+
+	full := cmd
+	if len(env) > 0 {
+		full = strings.Join(env, " ") + " " + cmd
+	}
+	result, err = runWithContext(ctx, func() (*CommandResult, error) {
+		return c.backend.Run(c.config.Host, full, stdin)
+	})
+	if err != nil {
+		return result, fmt.Errorf("命令执行失败: %w", err)
+	}
+	result.Stdout = strings.TrimSpace(result.Stdout)
+	result.Stderr = strings.TrimSpace(result.Stderr)
+	if result.ExitCode != 0 {
+		return result, fmt.Errorf("命令执行失败: Process exited with status %d", result.ExitCode)
+	}
+	return result, nil
+}
+
+// runWithContext 后端不支持取消，ctx 结束时放弃等待（后端命令在后台结束）
+func runWithContext(ctx context.Context, run func() (*CommandResult, error)) (*CommandResult, error) {
+	if ctx.Done() == nil {
+		return run()
+	}
+	type outcome struct {
+		result *CommandResult
+		err    error
+	}
+	done := make(chan outcome, 1)
+	go func() {
+		result, err := run()
+		done <- outcome{result, err}
+	}()
+	select {
+	case o := <-done:
+		return o.result, o.err
+	case <-ctx.Done():
+		return nil, fmt.Errorf("%w: %v", ErrCommandTimeout, ctx.Err())
+	}
+}
+
+// uploadBackend 读取全部内容后写入后端
+func (c *Client) uploadBackend(open func() (io.ReadCloser, error), remotePath string) error {
+	for attempt := 0; c.injectFault("cat > " + remotePath); attempt++ {
+		if attempt >= c.maxReconnects() || c.backend.Connect(c.config) != nil {
+			return errInjectedDrop
+		}
+	}
+
+	r, err := open()
+	if err != nil {
+		return err
+	}
+	defer r.Close()
+	content, err := io.ReadAll(r)
+	if err != nil {
+		return err
+	}
+	return c.backend.Upload(c.config.Host, remotePath, content)
+}
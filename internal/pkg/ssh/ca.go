@@ -0,0 +1,137 @@
+package ssh
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// certClockSkew 证书生效时间提前量，容忍节点与后端的时钟偏差
+const certClockSkew = time.Minute
+
+// UserCA SSH 用户证书签发机构。每次握手生成临时密钥并签发只对当前登录用户有效的短期证书，
+// 节点通过 sshd 的 TrustedUserCAKeys 信任 CA 公钥，无需为每个节点保存密码或私钥
+type UserCA struct {
+	signer   ssh.Signer
+	validity time.Duration
+}
+
+var (
+	userCAMu sync.RWMutex
+	userCA   *UserCA
+)
+
+// SetUserCA 注册 SSH CA，AuthType 为 ca 的连接使用它签发证书
+func SetUserCA(ca *UserCA) {
+	userCAMu.Lock()
+	defer userCAMu.Unlock()
+	userCA = ca
+}
+
+func currentUserCA() *UserCA {
+	userCAMu.RLock()
+	defer userCAMu.RUnlock()
+	return userCA
+}
+
+// LoadOrCreateUserCA 读取 OpenSSH 格式的 CA 私钥，不存在时生成 ed25519 密钥。
+// 多副本部署时各副本必须使用同一份 CA 私钥
+func LoadOrCreateUserCA(path string, validity time.Duration) (*UserCA, error) {
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		_, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("生成SSH CA密钥失败: %v", err)
+		}
+		block, err := ssh.MarshalPrivateKey(priv, "k3s-deploy-user-ca")
+		if err != nil {
+			return nil, fmt.Errorf("编码SSH CA密钥失败: %v", err)
+		}
+		data = pem.EncodeToMemory(block)
+		if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+			return nil, fmt.Errorf("创建SSH CA目录失败: %v", err)
+		}
+		if err := os.WriteFile(path, data, 0600); err != nil {
+			return nil, fmt.Errorf("写入SSH CA密钥失败: %v", err)
+		}
+	} else if err != nil {
+		return nil, fmt.Errorf("读取SSH CA密钥失败: %v", err)
+	}
+
+	signer, err := ssh.ParsePrivateKey(data)
+	if err != nil {
+		return nil, fmt.Errorf("解析SSH CA密钥失败: %v", err)
+	}
+	return &UserCA{signer: signer, validity: validity}, nil
+}
+
+// PublicKey 返回 authorized_keys 格式的 CA 公钥，用于写入节点的 TrustedUserCAKeys
+func (ca *UserCA) PublicKey() string {
+	return strings.TrimSpace(string(ssh.MarshalAuthorizedKey(ca.signer.PublicKey()))) + " k3s-deploy-user-ca"
+}
+
+// Fingerprint CA 公钥指纹
+func (ca *UserCA) Fingerprint() string {
+	return ssh.FingerprintSHA256(ca.signer.PublicKey())
+}
+
+// Sign 生成临时密钥并签发证书，principal 为登录用户名，keyID 记录在节点的 sshd 日志中
+func (ca *UserCA) Sign(principal, keyID string) (ssh.Signer, error) {
+	_, priv, err := ed25519.GenerateKey(rand.Reader)
+	if err != nil {
+		return nil, fmt.Errorf("生成临时密钥失败: %v", err)
+	}
+	signer, err := ssh.NewSignerFromKey(priv)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	cert := &ssh.Certificate{
+		Key:             signer.PublicKey(),
+		CertType:        ssh.UserCert,
+		KeyId:           keyID,
+		ValidPrincipals: []string{principal},
+		ValidAfter:      uint64(now.Add(-certClockSkew).Unix()),
+		ValidBefore:     uint64(now.Add(ca.validity).Unix()),
+		Permissions: ssh.Permissions{
+			Extensions: map[string]string{
+				"permit-pty":              "",
+				"permit-port-forwarding":  "",
+				"permit-agent-forwarding": "",
+			},
+		},
+	}
+	if err := cert.SignCert(rand.Reader, ca.signer); err != nil {
+		return nil, fmt.Errorf("签发SSH证书失败: %v", err)
+	}
+	return ssh.NewCertSigner(cert, signer)
+}
+
+// certAuth 每次握手（包括自动重连）时签发新证书
+func certAuth(config SSHConfig) (ssh.AuthMethod, error) {
+	ca := currentUserCA()
+	if ca == nil {
+		return nil, errors.New("未启用SSH CA，无法使用证书认证")
+	}
+	keyID := "k3s-deploy:" + config.Username + "@" + config.Host
+	if config.RequestID != "" {
+		keyID += ":" + config.RequestID
+	}
+	return ssh.PublicKeysCallback(func() ([]ssh.Signer, error) {
+		signer, err := ca.Sign(config.Username, keyID)
+		if err != nil {
+			return nil, err
+		}
+		return []ssh.Signer{signer}, nil
+	}), nil
+}
@@ -1,10 +1,15 @@
 package ssh
 
 import (
+	"context"
+	"errors"
 	"fmt"
 	"io"
 	"net"
+	"os"
+	"strconv"
 	"strings"
+	"sync"
 	"time"
 
 	"golang.org/x/crypto/ssh"
@@ -18,11 +23,65 @@ type SSHConfig struct {
 	Password   string
 	PrivateKey string
 	Passphrase string
+	// Transport 为空时直接 ssh.Dial；否则使用 RegisterTransport 注册的同名传输层
+	Transport string
+	// AgentID 反向通道模式下节点 Agent 的标识
+	AgentID string
+	// KeepAliveInterval 心跳间隔，0 使用默认值，负数关闭心跳
+	KeepAliveInterval time.Duration
+	// KeepAliveMaxMissed 连续多少次心跳无响应判定连接断开
+	KeepAliveMaxMissed int
+	// MaxReconnects 连接断开后的最大重连次数，负数关闭自动重连
+	MaxReconnects int
+	// MaxSessions 同一主机同时打开的会话上限，0 使用默认值
+	MaxSessions int
+	// JumpHosts 依次经过的跳板机（跳板机A → 跳板机B → 目标节点）
+	JumpHosts []SSHConfig
+	// ConnectTimeout 建立连接和握手的超时时间，0 使用默认值
+	ConnectTimeout time.Duration
+	// Ciphers、KeyExchanges、MACs 为空时使用 SSH 库默认的算法偏好
+	Ciphers      []string
+	KeyExchanges []string
+	MACs         []string
+	// RequestID 发起本次操作的 API 请求 ID，记录在命令日志中
+	RequestID string
+}
+
+// DialFunc 自定义传输层的拨号函数，target 为传输层自身的寻址标识
+type DialFunc func(target string, timeout time.Duration) (net.Conn, error)
+
+var (
+	transportsMu sync.RWMutex
+	transports   = map[string]DialFunc{}
+)
+
+// RegisterTransport 注册自定义传输层（如 Agent 反向通道）
+func RegisterTransport(name string, dial DialFunc) {
+	transportsMu.Lock()
+	defer transportsMu.Unlock()
+	transports[name] = dial
 }
 
 type Client struct {
 	config SSHConfig
-	conn   *ssh.Client
+	// hops 为跳板机链路加目标主机，最后一项为目标主机
+	hops []hop
+
+	mu            sync.Mutex
+	conn          *ssh.Client
+	jumps         []*ssh.Client
+	stopKeepAlive chan struct{}
+	// redactions Redact 登记的需要从命令日志中替换的内容
+	redactions []string
+
+	// backend 创建时设置了 SetBackend 则不建立真实连接
+	backend Backend
+	// faults 创建时设置的故障注入
+	faults FaultInjector
+
+	// memoMu 保护 memo，见 Memo
+	memoMu sync.Mutex
+	memo   map[string]memoEntry
 }
 
 type CommandResult struct {
@@ -31,43 +90,169 @@ type CommandResult struct {
 	ExitCode int
 }
 
+const defaultConnectTimeout = 30 * time.Second
+
 func NewClient(config SSHConfig) *Client {
 	return &Client{
-		config: config,
+		config:  config,
+		backend: currentBackend(),
+		faults:  currentFaultInjector(),
+	}
+}
+
+func (c *Client) Connect() (err error) {
+	start := time.Now()
+	defer func() { c.logConnectFailure(start, err) }()
+
+	if c.backend != nil {
+		return c.backend.Connect(c.config)
+	}
+
+	hops := make([]hop, 0, len(c.config.JumpHosts)+1)
+	for _, jump := range c.config.JumpHosts {
+		h, err := newHop(jump)
+		if err != nil {
+			return fmt.Errorf("跳板机 %s: %v", jump.Host, err)
+		}
+		hops = append(hops, h)
+	}
+	target, err := newHop(c.config)
+	if err != nil {
+		return err
+	}
+	c.hops = append(hops, target)
+
+	conn, jumps, err := c.dial()
+	if err != nil {
+		return err
 	}
+
+	c.mu.Lock()
+	c.setConn(conn, jumps)
+	c.mu.Unlock()
+	return nil
+}
+
+// hop 链路中的一跳
+type hop struct {
+	addr string
+	cfg  *ssh.ClientConfig
 }
 
-func (c *Client) Connect() error {
+func newHop(config SSHConfig) (hop, error) {
+	if err := validateAlgorithms(config); err != nil {
+		return hop{}, err
+	}
+
 	var auth []ssh.AuthMethod
 
-	if c.config.AuthType == "password" {
-		auth = append(auth, ssh.Password(c.config.Password))
-	} else if c.config.AuthType == "key" {
-		signer, err := c.parsePrivateKey(c.config.PrivateKey, c.config.Passphrase)
+	if config.AuthType == "password" {
+		auth = append(auth, ssh.Password(config.Password))
+	} else if config.AuthType == "key" {
+		signer, err := parsePrivateKey(config.PrivateKey, config.Passphrase)
 		if err != nil {
-			return fmt.Errorf("解析私钥失败: %v", err)
+			return hop{}, fmt.Errorf("解析私钥失败: %v", err)
 		}
 		auth = append(auth, ssh.PublicKeys(signer))
+	} else if config.AuthType == "ca" {
+		method, err := certAuth(config)
+		if err != nil {
+			return hop{}, err
+		}
+		auth = append(auth, method)
+	}
+
+	timeout := config.ConnectTimeout
+	if timeout <= 0 {
+		timeout = defaultConnectTimeout
 	}
 
-	config := &ssh.ClientConfig{
-		User:            c.config.Username,
+	clientCfg := &ssh.ClientConfig{
+		User:            config.Username,
 		Auth:            auth,
-		Timeout:         30 * time.Second,
+		Timeout:         timeout,
 		HostKeyCallback: ssh.InsecureIgnoreHostKey(), // 注意：生产环境应该验证主机密钥
 	}
+	clientCfg.Ciphers = config.Ciphers
+	clientCfg.KeyExchanges = config.KeyExchanges
+	clientCfg.MACs = config.MACs
+
+	return hop{
+		addr: net.JoinHostPort(config.Host, strconv.Itoa(config.Port)),
+		cfg:  clientCfg,
+	}, nil
+}
+
+// dial 建立第一跳连接（直连或自定义传输层），再逐跳经跳板机转发到目标主机
+func (c *Client) dial() (*ssh.Client, []*ssh.Client, error) {
+	client, err := c.dialFirst(c.hops[0])
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var jumps []*ssh.Client
+	for _, next := range c.hops[1:] {
+		jumps = append(jumps, client)
+
+		netConn, err := client.Dial("tcp", next.addr)
+		if err != nil {
+			closeClients(jumps)
+			return nil, nil, fmt.Errorf("经跳板机连接 %s 失败: %v", next.addr, err)
+		}
+
+		netConn.SetDeadline(time.Now().Add(next.cfg.Timeout))
+		sshConn, chans, reqs, err := ssh.NewClientConn(netConn, next.addr, next.cfg)
+		if err != nil {
+			netConn.Close()
+			closeClients(jumps)
+			return nil, nil, fmt.Errorf("与 %s 握手失败: %v", next.addr, err)
+		}
+		netConn.SetDeadline(time.Time{})
+
+		client = ssh.NewClient(sshConn, chans, reqs)
+	}
+
+	return client, jumps, nil
+}
+
+func (c *Client) dialFirst(first hop) (*ssh.Client, error) {
+	if c.config.Transport == "" || c.config.Transport == "ssh" {
+		conn, err := ssh.Dial("tcp", first.addr, first.cfg)
+		if err != nil {
+			return nil, fmt.Errorf("SSH连接失败: %v", err)
+		}
+		return conn, nil
+	}
+
+	transportsMu.RLock()
+	dial, exists := transports[c.config.Transport]
+	transportsMu.RUnlock()
+	if !exists {
+		return nil, fmt.Errorf("不支持的传输方式: %s", c.config.Transport)
+	}
 
-	addr := fmt.Sprintf("%s:%d", c.config.Host, c.config.Port)
-	conn, err := ssh.Dial("tcp", addr, config)
+	netConn, err := dial(c.config.AgentID, first.cfg.Timeout)
 	if err != nil {
-		return fmt.Errorf("SSH连接失败: %v", err)
+		return nil, fmt.Errorf("SSH连接失败: %v", err)
 	}
 
-	c.conn = conn
-	return nil
+	sshConn, chans, reqs, err := ssh.NewClientConn(netConn, first.addr, first.cfg)
+	if err != nil {
+		netConn.Close()
+		return nil, fmt.Errorf("SSH握手失败: %v", err)
+	}
+
+	return ssh.NewClient(sshConn, chans, reqs), nil
 }
 
-func (c *Client) parsePrivateKey(privateKey, passphrase string) (ssh.Signer, error) {
+// closeClients 按从内到外的顺序关闭连接
+func closeClients(clients []*ssh.Client) {
+	for i := len(clients) - 1; i >= 0; i-- {
+		clients[i].Close()
+	}
+}
+
+func parsePrivateKey(privateKey, passphrase string) (ssh.Signer, error) {
 	var signer ssh.Signer
 	var err error
 
@@ -85,51 +270,115 @@ func (c *Client) parsePrivateKey(privateKey, passphrase string) (ssh.Signer, err
 }
 
 func (c *Client) ExecuteCommand(cmd string) (*CommandResult, error) {
-	if c.conn == nil {
-		return nil, fmt.Errorf("SSH连接未建立")
-	}
+	return c.runCommand(context.Background(), cmd, false)
+}
 
-	session, err := c.conn.NewSession()
-	if err != nil {
-		return nil, fmt.Errorf("创建SSH会话失败: %v", err)
-	}
-	defer session.Close()
+// ExecuteIdempotentCommand 执行幂等命令：若执行过程中连接断开，重连后自动重新执行
+func (c *Client) ExecuteIdempotentCommand(cmd string) (*CommandResult, error) {
+	return c.runCommand(context.Background(), cmd, true)
+}
 
-	var stdoutBuf, stderrBuf strings.Builder
-	session.Stdout = &stdoutBuf
-	session.Stderr = &stderrBuf
+// ExecuteIdempotentCommandContext 同 ExecuteIdempotentCommand，ctx 结束时终止远程命令并返回超时错误
+func (c *Client) ExecuteIdempotentCommandContext(ctx context.Context, cmd string) (*CommandResult, error) {
+	return c.runCommand(ctx, cmd, true)
+}
 
-	err = session.Run(cmd)
+// ErrCommandTimeout 命令在 ctx 结束前未完成
+var ErrCommandTimeout = errors.New("命令执行超时")
 
-	result := &CommandResult{
-		Stdout: strings.TrimSpace(stdoutBuf.String()),
-		Stderr: strings.TrimSpace(stderrBuf.String()),
+// watchSession ctx 结束时终止远程进程并关闭会话，使阻塞的 Run/Wait 返回；返回的函数停止监视
+func watchSession(ctx context.Context, session *ssh.Session) func() bool {
+	return context.AfterFunc(ctx, func() {
+		session.Signal(ssh.SIGKILL)
+		session.Close()
+	})
+}
+
+// contextError ctx 已结束时将会话被关闭导致的错误转换为超时错误
+func contextError(ctx context.Context, err error) error {
+	if err != nil && ctx.Err() != nil {
+		return fmt.Errorf("%w: %v", ErrCommandTimeout, ctx.Err())
 	}
+	return err
+}
 
-	if err != nil {
-		if exitError, ok := err.(*ssh.ExitError); ok {
-			result.ExitCode = exitError.ExitStatus()
+func (c *Client) runCommand(ctx context.Context, cmd string, idempotent bool) (result *CommandResult, err error) {
+	if c.backend != nil {
+		return c.runBackend(ctx, cmd, nil, nil, idempotent)
+	}
+	start := time.Now()
+	defer func() { c.logCommand(cmd, start, result, err) }()
+
+	for attempt := 0; ; attempt++ {
+		session, err := c.newSession()
+		if err != nil {
+			return nil, err
+		}
+
+		var stdoutBuf, stderrBuf strings.Builder
+		session.Stdout = &stdoutBuf
+		session.Stderr = &stderrBuf
+
+		if c.injectFault(cmd) {
+			// 模拟命令发出前连接中断
+			session.conn.Close()
+			err = errInjectedDrop
 		} else {
-			result.ExitCode = 1
+			stop := watchSession(ctx, session.Session)
+			err = contextError(ctx, session.Run(cmd))
+			stop()
 		}
-		return result, fmt.Errorf("命令执行失败: %v", err)
-	}
+		session.Close()
 
-	result.ExitCode = 0
-	return result, nil
+		if err != nil && idempotent && ctx.Err() == nil && isConnectionLost(err) && attempt < c.maxReconnects() {
+			if rerr := c.reconnect(session.conn); rerr == nil {
+				continue
+			}
+		}
+
+		result = &CommandResult{
+			Stdout: strings.TrimSpace(stdoutBuf.String()),
+			Stderr: strings.TrimSpace(stderrBuf.String()),
+		}
+
+		if err != nil {
+			if exitError, ok := err.(*ssh.ExitError); ok {
+				result.ExitCode = exitError.ExitStatus()
+			} else {
+				result.ExitCode = 1
+			}
+			return result, fmt.Errorf("命令执行失败: %w", err)
+		}
+
+		result.ExitCode = 0
+		return result, nil
+	}
 }
 
 func (c *Client) ExecuteCommandWithStdin(script []byte, cmd string, env []string) (*CommandResult, error) {
-	if c.conn == nil {
-		return nil, fmt.Errorf("SSH连接未建立")
+	return c.ExecuteCommandWithStdinContext(context.Background(), script, cmd, env)
+}
+
+// ExecuteCommandWithStdinContext 同 ExecuteCommandWithStdin，ctx 结束时终止远程命令并返回超时错误
+func (c *Client) ExecuteCommandWithStdinContext(ctx context.Context, script []byte, cmd string, env []string) (result *CommandResult, err error) {
+	if c.backend != nil {
+		return c.runBackend(ctx, cmd, env, script, false)
 	}
+	// 环境变量中可能包含 token 等敏感信息，日志中只记录命令本身
+	start := time.Now()
+	defer func() { c.logCommand(cmd, start, result, err) }()
 
-	session, err := c.conn.NewSession()
+	session, err := c.newSession()
 	if err != nil {
-		return nil, fmt.Errorf("创建SSH会话失败: %v", err)
+		return nil, err
 	}
 	defer session.Close()
 
+	if c.injectFault(cmd) {
+		session.conn.Close()
+		return nil, fmt.Errorf("命令执行失败: %v", errInjectedDrop)
+	}
+
 	// 创建 stdin pipe
 	w, err := session.StdinPipe()
 	if err != nil {
@@ -154,17 +403,18 @@ func (c *Client) ExecuteCommandWithStdin(script []byte, cmd string, env []string
 	if err := session.Start(cmdWithEnv); err != nil {
 		return nil, fmt.Errorf("启动命令 %s 失败: %v", cmdWithEnv, err)
 	}
+	defer watchSession(ctx, session.Session)()
 
 	// 写入脚本内容到 stdin
 	_, err = w.Write(script)
 	if err != nil {
-		return nil, fmt.Errorf("写入stdin失败: %v", err)
+		return nil, contextError(ctx, fmt.Errorf("写入stdin失败: %v", err))
 	}
 	w.Close()
 
 	// 等待命令完成
-	err = session.Wait()
-	result := &CommandResult{
+	err = contextError(ctx, session.Wait())
+	result = &CommandResult{
 		Stdout: strings.TrimSpace(stdoutBuf.String()),
 		Stderr: strings.TrimSpace(stderrBuf.String()),
 	}
@@ -175,52 +425,116 @@ func (c *Client) ExecuteCommandWithStdin(script []byte, cmd string, env []string
 		} else {
 			result.ExitCode = 1
 		}
-		return result, fmt.Errorf("命令执行失败: %v", err)
+		return result, fmt.Errorf("命令执行失败: %w", err)
 	}
 
 	result.ExitCode = 0
 	return result, nil
 }
 
+// UploadFile 上传文件内容到远程路径；整体覆盖写入是幂等的，连接中断时会重连重试
 func (c *Client) UploadFile(content, remotePath string) error {
-	if c.conn == nil {
-		return fmt.Errorf("SSH连接未建立")
+	return c.upload(func() (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader(content)), nil
+	}, remotePath)
+}
+
+// UploadLocalFile 以流的方式上传本地文件（如离线安装包中的二进制和镜像），不会整体读入内存
+func (c *Client) UploadLocalFile(localPath, remotePath string) error {
+	return c.upload(func() (io.ReadCloser, error) {
+		return os.Open(localPath)
+	}, remotePath)
+}
+
+// upload 每次尝试重新打开数据源，连接中断时重连重试
+func (c *Client) upload(open func() (io.ReadCloser, error), remotePath string) (err error) {
+	start := time.Now()
+	defer func() { c.logUpload(remotePath, start, err) }()
+
+	if c.backend != nil {
+		return c.uploadBackend(open, remotePath)
+	}
+	for attempt := 0; ; attempt++ {
+		r, err := open()
+		if err != nil {
+			return err
+		}
+		conn, err := c.uploadOnce(r, remotePath)
+		r.Close()
+		if err != nil && conn != nil && isConnectionLost(err) && attempt < c.maxReconnects() {
+			if rerr := c.reconnect(conn); rerr == nil {
+				continue
+			}
+		}
+		return err
 	}
+}
 
-	session, err := c.conn.NewSession()
+func (c *Client) uploadOnce(content io.Reader, remotePath string) (*ssh.Client, error) {
+	session, err := c.newSession()
 	if err != nil {
-		return fmt.Errorf("创建SSH会话失败: %v", err)
+		return nil, err
 	}
 	defer session.Close()
+	conn := session.conn
+
+	cmd := fmt.Sprintf("cat > %s", remotePath)
+	if c.injectFault(cmd) {
+		conn.Close()
+		return conn, errInjectedDrop
+	}
 
 	w, err := session.StdinPipe()
 	if err != nil {
-		return err
+		return conn, err
 	}
 
-	cmd := fmt.Sprintf("cat > %s", remotePath)
 	if err := session.Start(cmd); err != nil {
-		return err
+		return conn, err
 	}
 
-	_, err = io.WriteString(w, content)
+	_, err = io.Copy(w, content)
 	if err != nil {
-		return err
+		return conn, err
 	}
 	w.Close()
 
-	return session.Wait()
+	return conn, session.Wait()
+}
+
+// Host 返回目标主机地址
+func (c *Client) Host() string {
+	return c.config.Host
+}
+
+// RequestID 返回发起本次操作的 API 请求 ID
+func (c *Client) RequestID() string {
+	return c.config.RequestID
 }
 
 func (c *Client) Close() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stopKeepAlive != nil {
+		close(c.stopKeepAlive)
+		c.stopKeepAlive = nil
+	}
 	if c.conn != nil {
-		return c.conn.Close()
+		err := c.conn.Close()
+		c.conn = nil
+		closeClients(c.jumps)
+		c.jumps = nil
+		return err
 	}
 	return nil
 }
 
 func (c *Client) IsPortOpen(port int) bool {
-	addr := fmt.Sprintf("%s:%d", c.config.Host, port)
+	if c.backend != nil {
+		return c.backend.Connect(c.config) == nil
+	}
+	addr := net.JoinHostPort(c.config.Host, strconv.Itoa(port))
 	conn, err := net.DialTimeout("tcp", addr, 5*time.Second)
 	if err != nil {
 		return false
@@ -0,0 +1,139 @@
+package ssh
+
+import (
+	"errors"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+)
+
+// CommandLog 一次远程命令执行、文件上传或连接失败的记录。
+// Command、Stdout、Stderr 和 Err 中节点自身的密码、私钥口令以及 Client.Redact 登记的内容已替换为 ******，
+// 令牌、私钥、密码字段等按 RedactSecrets 替换为 <redacted>
+type CommandLog struct {
+	Host      string
+	RequestID string
+	Command   string
+	Stdout    string
+	Stderr    string
+	// Upload 文件上传的目标路径，此时 Command 为空
+	Upload string
+	// Connect 为 true 表示连接节点失败，此时 Command 为空
+	Connect  bool
+	ExitCode int
+	Duration time.Duration
+	Err      error
+}
+
+// redactedCredential 替换命令记录中出现的节点认证信息
+const redactedCredential = "******"
+
+// minRedactedLength 短于该长度的密码不替换，否则会误伤命令和输出中的普通文本
+const minRedactedLength = 6
+
+// RedactCredential 将 s 中出现的 secret 替换为 ******
+func RedactCredential(s, secret string) string {
+	if len(secret) < minRedactedLength {
+		return s
+	}
+	return strings.ReplaceAll(s, secret, redactedCredential)
+}
+
+// redactedSecret 按规则识别出的敏感内容的占位符
+const redactedSecret = "<redacted>"
+
+var (
+	// k3s 集群令牌：K10<CA 哈希>::<用户>:<密码>
+	k3sToken = regexp.MustCompile(`K10[0-9a-f]{16,}::[^\s:]+:[^\s'"]+`)
+	// PEM 私钥
+	privateKey = regexp.MustCompile(`-----BEGIN [A-Z ]*PRIVATE KEY-----[\s\S]*?-----END [A-Z ]*PRIVATE KEY-----`)
+	// kubeconfig 中的客户端私钥与令牌，以及 key=value、key: value 形式的密码和密钥
+	secretField = regexp.MustCompile(`(?i)((?:client-key-data|token|password|passwd|secret[_-]?key|access[_-]?key|secret)["']?\s*[:=]\s*["']?)([^\s"',&}]+)`)
+	// 经 echo 传给 chpasswd 的 用户:密码
+	chpasswdInput = regexp.MustCompile(`(echo\s+'[^':]*:)[^']*('\s*\|\s*(?:sudo\s+)?chpasswd)`)
+)
+
+// RedactSecrets 替换 s 中的令牌、私钥、密码等敏感内容，命令日志和命令录制使用同样的规则
+func RedactSecrets(s string) string {
+	if s == "" {
+		return s
+	}
+	s = k3sToken.ReplaceAllString(s, redactedSecret)
+	s = privateKey.ReplaceAllString(s, redactedSecret)
+	s = chpasswdInput.ReplaceAllString(s, "${1}"+redactedSecret+"${2}")
+	return secretField.ReplaceAllString(s, "${1}"+redactedSecret)
+}
+
+var (
+	commandLoggerMu sync.RWMutex
+	commandLogger   func(CommandLog)
+)
+
+// SetCommandLogger 设置远程命令执行记录的回调，用于按请求 ID 追踪命令
+func SetCommandLogger(fn func(CommandLog)) {
+	commandLoggerMu.Lock()
+	defer commandLoggerMu.Unlock()
+	commandLogger = fn
+}
+
+// Redact 登记需要从该连接的命令日志中替换的内容，如轮换时设置的新密码
+func (c *Client) Redact(secrets ...string) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.redactions = append(c.redactions, secrets...)
+}
+
+func (c *Client) logCommand(cmd string, start time.Time, result *CommandResult, err error) {
+	entry := CommandLog{Command: cmd}
+	if result != nil {
+		entry.Stdout = result.Stdout
+		entry.Stderr = result.Stderr
+		entry.ExitCode = result.ExitCode
+	}
+	c.emit(entry, start, err)
+}
+
+// logUpload 记录文件上传
+func (c *Client) logUpload(remotePath string, start time.Time, err error) {
+	c.emit(CommandLog{Upload: remotePath}, start, err)
+}
+
+// logConnectFailure 记录连接失败，成功的连接不单独记录
+func (c *Client) logConnectFailure(start time.Time, err error) {
+	if err != nil {
+		c.emit(CommandLog{Connect: true}, start, err)
+	}
+}
+
+func (c *Client) emit(entry CommandLog, start time.Time, err error) {
+	commandLoggerMu.RLock()
+	fn := commandLogger
+	commandLoggerMu.RUnlock()
+	if fn == nil {
+		return
+	}
+
+	entry.Host = c.config.Host
+	entry.RequestID = c.config.RequestID
+	entry.Duration = time.Since(start)
+	c.mu.Lock()
+	secrets := append([]string{c.config.Password, c.config.Passphrase}, c.redactions...)
+	c.mu.Unlock()
+	redact := func(s string) string {
+		for _, secret := range secrets {
+			s = RedactCredential(s, secret)
+		}
+		return RedactSecrets(s)
+	}
+	entry.Command = redact(entry.Command)
+	entry.Stdout = redact(entry.Stdout)
+	entry.Stderr = redact(entry.Stderr)
+	entry.Err = err
+	if err != nil {
+		if message := redact(err.Error()); message != err.Error() {
+			entry.Err = errors.New(message)
+		}
+	}
+	fn(entry)
+}
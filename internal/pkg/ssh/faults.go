@@ -0,0 +1,47 @@
+package ssh
+
+import (
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// FaultInjector 故障注入，用于确定性地验证重连重试、回滚和断点续跑逻辑。
+// 每条命令（含文件上传）执行前调用，返回执行前等待的时长以及是否断开连接
+type FaultInjector interface {
+	BeforeCommand(host, cmd string) (delay time.Duration, drop bool)
+}
+
+var (
+	faultsMu sync.RWMutex
+	faults   FaultInjector
+)
+
+// errInjectedDrop 注入的连接中断，与真实断线一样被 isConnectionLost 识别
+var errInjectedDrop = fmt.Errorf("连接被故障注入断开: %w", net.ErrClosed)
+
+// SetFaultInjector 设置故障注入，为 nil 时关闭
+func SetFaultInjector(f FaultInjector) {
+	faultsMu.Lock()
+	defer faultsMu.Unlock()
+	faults = f
+}
+
+func currentFaultInjector() FaultInjector {
+	faultsMu.RLock()
+	defer faultsMu.RUnlock()
+	return faults
+}
+
+// injectFault 命令执行前等待注入的延迟，需要断开连接时返回 true
+func (c *Client) injectFault(cmd string) bool {
+	if c.faults == nil {
+		return false
+	}
+	delay, drop := c.faults.BeforeCommand(c.config.Host, cmd)
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return drop
+}
@@ -0,0 +1,197 @@
+package ssh
+
+import (
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"strings"
+	"time"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const (
+	defaultKeepAliveInterval  = 15 * time.Second
+	defaultKeepAliveMaxMissed = 3
+	defaultMaxReconnects      = 3
+	reconnectBackoff          = 2 * time.Second
+)
+
+func (c *Client) keepAliveInterval() time.Duration {
+	if c.config.KeepAliveInterval == 0 {
+		return defaultKeepAliveInterval
+	}
+	return c.config.KeepAliveInterval
+}
+
+func (c *Client) keepAliveMaxMissed() int {
+	if c.config.KeepAliveMaxMissed <= 0 {
+		return defaultKeepAliveMaxMissed
+	}
+	return c.config.KeepAliveMaxMissed
+}
+
+func (c *Client) maxReconnects() int {
+	if c.config.MaxReconnects == 0 {
+		return defaultMaxReconnects
+	}
+	if c.config.MaxReconnects < 0 {
+		return 0
+	}
+	return c.config.MaxReconnects
+}
+
+// setConn 替换当前连接（及其跳板机链路）并重启心跳，调用方需持有 c.mu
+func (c *Client) setConn(conn *ssh.Client, jumps []*ssh.Client) {
+	if c.stopKeepAlive != nil {
+		close(c.stopKeepAlive)
+		c.stopKeepAlive = nil
+	}
+	c.conn = conn
+	c.jumps = jumps
+
+	if c.keepAliveInterval() > 0 {
+		c.stopKeepAlive = make(chan struct{})
+		go c.keepAlive(conn, c.stopKeepAlive)
+	}
+}
+
+// keepAlive 定期发送 keepalive@openssh.com 请求，连续无响应时主动关闭连接，
+// 使后续会话创建失败并触发重连，而不是在半开连接上无限阻塞
+func (c *Client) keepAlive(conn *ssh.Client, stop <-chan struct{}) {
+	interval := c.keepAliveInterval()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	missed := 0
+	for {
+		select {
+		case <-stop:
+			return
+		case <-ticker.C:
+		}
+
+		errCh := make(chan error, 1)
+		go func() {
+			_, _, err := conn.SendRequest("keepalive@openssh.com", true, nil)
+			errCh <- err
+		}()
+
+		select {
+		case err := <-errCh:
+			if err != nil {
+				missed++
+			} else {
+				missed = 0
+			}
+		case <-time.After(interval):
+			missed++
+		case <-stop:
+			return
+		}
+
+		if missed >= c.keepAliveMaxMissed() {
+			conn.Close()
+			return
+		}
+	}
+}
+
+// currentConn 返回当前连接
+func (c *Client) currentConn() (*ssh.Client, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.conn == nil {
+		return nil, fmt.Errorf("SSH连接未建立")
+	}
+	return c.conn, nil
+}
+
+// newSession 占用主机会话名额并创建会话；创建失败时视为连接断开，
+// 重连后重试一次（命令尚未执行，重试是安全的）
+func (c *Client) newSession() (*session, error) {
+	conn, err := c.currentConn()
+	if err != nil {
+		return nil, err
+	}
+
+	release := c.acquireSession()
+	sess, err := conn.NewSession()
+	if err == nil {
+		return &session{Session: sess, conn: conn, release: release}, nil
+	}
+
+	if c.maxReconnects() == 0 {
+		release()
+		return nil, fmt.Errorf("创建SSH会话失败: %v", err)
+	}
+	if rerr := c.reconnect(conn); rerr != nil {
+		release()
+		return nil, fmt.Errorf("创建SSH会话失败: %v，重连失败: %v", err, rerr)
+	}
+
+	conn, err = c.currentConn()
+	if err != nil {
+		release()
+		return nil, err
+	}
+	sess, err = conn.NewSession()
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("创建SSH会话失败: %v", err)
+	}
+	return &session{Session: sess, conn: conn, release: release}, nil
+}
+
+// reconnect 重新建立连接。broken 为调用方观察到的失效连接，
+// 若其他调用方已完成重连则直接返回
+func (c *Client) reconnect(broken *ssh.Client) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.hops) == 0 {
+		return fmt.Errorf("SSH连接未建立")
+	}
+	if c.conn != nil && c.conn != broken {
+		return nil
+	}
+	if broken != nil {
+		broken.Close()
+	}
+	closeClients(c.jumps)
+	c.jumps = nil
+
+	var lastErr error
+	for attempt := 1; attempt <= c.maxReconnects(); attempt++ {
+		conn, jumps, err := c.dial()
+		if err == nil {
+			c.setConn(conn, jumps)
+			return nil
+		}
+		lastErr = err
+		time.Sleep(time.Duration(attempt) * reconnectBackoff)
+	}
+
+	c.conn = nil
+	return fmt.Errorf("重连 %s 失败（%d 次尝试）: %v", c.config.Host, c.maxReconnects(), lastErr)
+}
+
+// isConnectionLost 判断错误是否由连接中断引起（而非命令自身的非零退出）
+func isConnectionLost(err error) bool {
+	if err == nil {
+		return false
+	}
+
+	var exitMissing *ssh.ExitMissingError
+	if errors.As(err, &exitMissing) {
+		return true
+	}
+	if errors.Is(err, io.EOF) || errors.Is(err, net.ErrClosed) {
+		return true
+	}
+
+	msg := err.Error()
+	return strings.Contains(msg, "connection reset") || strings.Contains(msg, "broken pipe")
+}
@@ -0,0 +1,71 @@
+package ssh
+
+import (
+	"crypto"
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"encoding/pem"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+const rsaKeyBits = 4096
+
+// KeyPair 生成的 SSH 密钥对
+type KeyPair struct {
+	Type string
+	// PrivateKey OpenSSH 格式私钥（PEM）
+	PrivateKey string
+	// AuthorizedKey authorized_keys 格式公钥（单行）
+	AuthorizedKey string
+	Fingerprint   string
+}
+
+// GenerateKeyPair 生成 ed25519 或 RSA 密钥对，comment 写入公钥注释
+func GenerateKeyPair(keyType, comment string) (*KeyPair, error) {
+	var privateKey crypto.PrivateKey
+	var publicKey crypto.PublicKey
+
+	switch keyType {
+	case "", "ed25519":
+		keyType = "ed25519"
+		pub, priv, err := ed25519.GenerateKey(rand.Reader)
+		if err != nil {
+			return nil, fmt.Errorf("生成ed25519密钥失败: %v", err)
+		}
+		privateKey, publicKey = priv, pub
+	case "rsa":
+		priv, err := rsa.GenerateKey(rand.Reader, rsaKeyBits)
+		if err != nil {
+			return nil, fmt.Errorf("生成RSA密钥失败: %v", err)
+		}
+		privateKey, publicKey = priv, &priv.PublicKey
+	default:
+		return nil, fmt.Errorf("不支持的密钥类型: %s", keyType)
+	}
+
+	block, err := ssh.MarshalPrivateKey(privateKey, comment)
+	if err != nil {
+		return nil, fmt.Errorf("编码私钥失败: %v", err)
+	}
+
+	sshPub, err := ssh.NewPublicKey(publicKey)
+	if err != nil {
+		return nil, fmt.Errorf("编码公钥失败: %v", err)
+	}
+
+	authorizedKey := strings.TrimSpace(string(ssh.MarshalAuthorizedKey(sshPub)))
+	if comment != "" {
+		authorizedKey += " " + comment
+	}
+
+	return &KeyPair{
+		Type:          keyType,
+		PrivateKey:    string(pem.EncodeToMemory(block)),
+		AuthorizedKey: authorizedKey,
+		Fingerprint:   ssh.FingerprintSHA256(sshPub),
+	}, nil
+}
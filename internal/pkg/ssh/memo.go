@@ -0,0 +1,30 @@
+package ssh
+
+// memoEntry Memo 缓存的结果
+type memoEntry struct {
+	value any
+	err   error
+}
+
+// Memo 返回 key 对应的缓存结果，首次调用时执行 fn。结果（包括错误）在客户端的生命周期内保留，
+// 用于按连接缓存节点上的检测结果，避免同一连接上反复检测
+func (c *Client) Memo(key string, fn func() (any, error)) (any, error) {
+	c.memoMu.Lock()
+	defer c.memoMu.Unlock()
+	if entry, ok := c.memo[key]; ok {
+		return entry.value, entry.err
+	}
+	value, err := fn()
+	if c.memo == nil {
+		c.memo = make(map[string]memoEntry)
+	}
+	c.memo[key] = memoEntry{value: value, err: err}
+	return value, err
+}
+
+// Forget 丢弃 key 的缓存结果，下次 Memo 时重新执行
+func (c *Client) Forget(key string) {
+	c.memoMu.Lock()
+	defer c.memoMu.Unlock()
+	delete(c.memo, key)
+}
@@ -0,0 +1,8 @@
+package ssh
+
+import "strings"
+
+// Quote 将 s 用单引号包裹为一个 shell 参数，拼接远程命令时用于来自请求或期望状态的取值
+func Quote(s string) string {
+	return "'" + strings.ReplaceAll(s, "'", `'\''`) + "'"
+}
@@ -0,0 +1,94 @@
+package ssh
+
+import (
+	"net"
+	"strconv"
+	"sync"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// defaultMaxSessions 每个主机同时打开的会话上限。
+// OpenSSH 默认 MaxSessions 为 10，这里保留余量给交互登录等其他用途
+const defaultMaxSessions = 8
+
+var (
+	sessionLimitsMu sync.Mutex
+	sessionLimits   = map[string]chan struct{}{}
+)
+
+// hostSemaphore 返回主机级会话信号量，同一主机的所有 Client 共享
+func hostSemaphore(key string, size int) chan struct{} {
+	sessionLimitsMu.Lock()
+	defer sessionLimitsMu.Unlock()
+
+	sem, exists := sessionLimits[key]
+	if !exists {
+		sem = make(chan struct{}, size)
+		sessionLimits[key] = sem
+	}
+	return sem
+}
+
+func (c *Client) sessionKey() string {
+	if c.config.Transport == "agent" {
+		return "agent/" + c.config.AgentID
+	}
+	return net.JoinHostPort(c.config.Host, strconv.Itoa(c.config.Port))
+}
+
+func (c *Client) maxSessions() int {
+	if c.config.MaxSessions <= 0 {
+		return defaultMaxSessions
+	}
+	return c.config.MaxSessions
+}
+
+// acquireSession 占用一个会话名额，返回释放函数
+func (c *Client) acquireSession() func() {
+	sem := hostSemaphore(c.sessionKey(), c.maxSessions())
+	sem <- struct{}{}
+
+	var once sync.Once
+	return func() {
+		once.Do(func() { <-sem })
+	}
+}
+
+// session 封装 ssh.Session，关闭时归还主机会话名额
+type session struct {
+	*ssh.Session
+	conn    *ssh.Client
+	release func()
+}
+
+func (s *session) Close() error {
+	defer s.release()
+	return s.Session.Close()
+}
+
+// ParallelResult 并行执行中单条命令的结果
+type ParallelResult struct {
+	Command string
+	Result  *CommandResult
+	Err     error
+}
+
+// ExecuteParallel 在同一连接上并发执行多条相互独立的命令，
+// 并发度受主机会话上限约束，结果顺序与输入一致
+func (c *Client) ExecuteParallel(cmds []string) []ParallelResult {
+	results := make([]ParallelResult, len(cmds))
+	var wg sync.WaitGroup
+
+	for i, cmd := range cmds {
+		wg.Add(1)
+		go func(index int, command string) {
+			defer wg.Done()
+			result, err := c.ExecuteCommand(command)
+			results[index] = ParallelResult{Command: command, Result: result, Err: err}
+		}(i, cmd)
+	}
+
+	wg.Wait()
+	return results
+}
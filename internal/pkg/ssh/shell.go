@@ -0,0 +1,74 @@
+package ssh
+
+import (
+	"fmt"
+	"io"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// Shell 交互式终端会话
+type Shell struct {
+	session *ssh.Session
+	Stdin   io.WriteCloser
+	// Stdout 终端输出，PTY 模式下已包含标准错误
+	Stdout io.Reader
+}
+
+// OpenShell 在当前连接上申请 PTY 并启动登录 shell。交互式会话持续时间不可预期，
+// 不占用主机会话名额，避免阻塞部署命令
+func (c *Client) OpenShell(term string, cols, rows int) (*Shell, error) {
+	if c.backend != nil {
+		return nil, fmt.Errorf("模拟后端不支持交互式终端")
+	}
+	conn, err := c.currentConn()
+	if err != nil {
+		return nil, err
+	}
+
+	sess, err := conn.NewSession()
+	if err != nil {
+		return nil, fmt.Errorf("创建SSH会话失败: %v", err)
+	}
+
+	stdin, err := sess.StdinPipe()
+	if err != nil {
+		sess.Close()
+		return nil, err
+	}
+	stdout, err := sess.StdoutPipe()
+	if err != nil {
+		sess.Close()
+		return nil, err
+	}
+
+	modes := ssh.TerminalModes{
+		ssh.ECHO:          1,
+		ssh.TTY_OP_ISPEED: 14400,
+		ssh.TTY_OP_OSPEED: 14400,
+	}
+	if err := sess.RequestPty(term, rows, cols, modes); err != nil {
+		sess.Close()
+		return nil, fmt.Errorf("申请终端失败: %v", err)
+	}
+	if err := sess.Shell(); err != nil {
+		sess.Close()
+		return nil, fmt.Errorf("启动shell失败: %v", err)
+	}
+
+	return &Shell{session: sess, Stdin: stdin, Stdout: stdout}, nil
+}
+
+// Resize 调整终端窗口大小
+func (s *Shell) Resize(cols, rows int) error {
+	return s.session.WindowChange(rows, cols)
+}
+
+// Wait 等待 shell 退出
+func (s *Shell) Wait() error {
+	return s.session.Wait()
+}
+
+func (s *Shell) Close() error {
+	return s.session.Close()
+}
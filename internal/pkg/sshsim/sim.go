@@ -0,0 +1,382 @@
+// Package sshsim 模拟 SSH 后端：按命令脚本返回预设的输出，上传的文件保存在内存中，
+// 用于在没有真实节点的情况下运行部署流水线（开发、前端联调和 CI）
+package sshsim
+
+import (
+	_ "embed"
+	"fmt"
+	"os"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+	"text/template"
+	"time"
+
+	"gopkg.in/yaml.v3"
+
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+//go:embed builtin.yaml
+var builtinScript []byte
+
+// Script 命令脚本。命令依次匹配 Rules，都不匹配时按内存文件（cat、test -f）处理，
+// 再匹配内置规则，最后视为执行成功且没有输出
+type Script struct {
+	// Unreachable 无法连接的节点地址，模拟网络不通或认证失败
+	Unreachable []string `yaml:"unreachable"`
+	Rules       []Rule   `yaml:"rules"`
+}
+
+// Rule 命令匹配 Match 正则（且 Hosts 为空或包含目标节点）时返回的结果。
+// Stdout 和 Stderr 为 text/template 模板，可使用 .Host、.Match（正则子匹配）和 .Nodes（已安装 k3s 的节点）
+type Rule struct {
+	Match  string   `yaml:"match"`
+	Hosts  []string `yaml:"hosts"`
+	Stdout string   `yaml:"stdout"`
+	Stderr string   `yaml:"stderr"`
+	Exit   int      `yaml:"exit"`
+	// Delay 返回前等待的时长，模拟耗时命令
+	Delay string `yaml:"delay"`
+	// Times 最多匹配的次数，0 表示不限，可用于模拟先失败后成功
+	Times int `yaml:"times"`
+	// If 节点上必须已设置的标记，以 ! 开头表示必须未设置；Set、Unset 在匹配后设置或清除标记
+	If    []string `yaml:"if"`
+	Set   []string `yaml:"set"`
+	Unset []string `yaml:"unset"`
+}
+
+// Node 模拟集群中的节点，由安装命令中的 K3S_NODE_NAME 登记，带 K3S_URL 的为 Agent，规则清除 k3s 标记时移除
+type Node struct {
+	Name   string
+	IP     string
+	Server bool
+	// Labels 通过 kubectl label nodes 设置的标签
+	Labels map[string]string
+}
+
+// Execution 一次命令执行记录
+type Execution struct {
+	Host     string
+	Command  string
+	ExitCode int
+}
+
+type rule struct {
+	Rule
+	pattern *regexp.Regexp
+	stdout  *template.Template
+	stderr  *template.Template
+	delay   time.Duration
+	hits    int
+}
+
+// Simulator 实现 ssh.Backend
+type Simulator struct {
+	mu          sync.Mutex
+	rules       []*rule
+	builtin     []*rule
+	unreachable map[string]bool
+	files       map[string]map[string][]byte
+	flags       map[string]map[string]bool
+	nodes       map[string]Node
+	history     []Execution
+}
+
+var _ ssh.Backend = (*Simulator)(nil)
+
+// nodeNamePattern 安装命令中的节点名
+var nodeNamePattern = regexp.MustCompile(`K3S_NODE_NAME=(\S+)`)
+
+// kubectlInvocation 部署流程以绝对路径和 --kubeconfig 调用 kubectl（或 k3s kubectl，路径可能带引号），匹配规则前还原为 kubectl
+var kubectlInvocation = regexp.MustCompile(`'?\S*/(?:k3s'? kubectl|kubectl'?) --kubeconfig \S+`)
+
+// labelPattern 设置或删除（key-）节点标签的命令
+var labelPattern = regexp.MustCompile(`^kubectl label nodes? (\S+) (.+)$`)
+
+// 内存文件支持的简单命令
+var (
+	catPattern    = regexp.MustCompile(`^cat (\S+)$`)
+	testPattern   = regexp.MustCompile(`^(?:test -f|\[ -f) (\S+)(?: \])?$`)
+	removePattern = regexp.MustCompile(`^rm -f (\S+)$`)
+)
+
+// Load 读取命令脚本文件，path 为空时只使用内置规则
+func Load(path string) (*Simulator, error) {
+	script := &Script{}
+	if path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("读取模拟脚本失败: %v", err)
+		}
+		if err := yaml.Unmarshal(data, script); err != nil {
+			return nil, fmt.Errorf("解析模拟脚本 %s 失败: %v", path, err)
+		}
+	}
+	return New(script)
+}
+
+// New 根据命令脚本创建模拟后端
+func New(script *Script) (*Simulator, error) {
+	var builtin Script
+	if err := yaml.Unmarshal(builtinScript, &builtin); err != nil {
+		return nil, fmt.Errorf("解析内置模拟脚本失败: %v", err)
+	}
+
+	s := &Simulator{
+		unreachable: make(map[string]bool),
+		files:       make(map[string]map[string][]byte),
+		flags:       make(map[string]map[string]bool),
+		nodes:       make(map[string]Node),
+	}
+	var err error
+	if s.rules, err = compile(script.Rules); err != nil {
+		return nil, err
+	}
+	if s.builtin, err = compile(builtin.Rules); err != nil {
+		return nil, fmt.Errorf("内置模拟脚本: %v", err)
+	}
+	for _, host := range script.Unreachable {
+		s.unreachable[host] = true
+	}
+	return s, nil
+}
+
+func compile(rules []Rule) ([]*rule, error) {
+	compiled := make([]*rule, 0, len(rules))
+	for i, r := range rules {
+		c := &rule{Rule: r}
+		var err error
+		if c.pattern, err = regexp.Compile(r.Match); err != nil {
+			return nil, fmt.Errorf("第 %d 条规则的 match 无效: %v", i+1, err)
+		}
+		if c.stdout, err = template.New("stdout").Parse(r.Stdout); err != nil {
+			return nil, fmt.Errorf("第 %d 条规则的 stdout 模板无效: %v", i+1, err)
+		}
+		if c.stderr, err = template.New("stderr").Parse(r.Stderr); err != nil {
+			return nil, fmt.Errorf("第 %d 条规则的 stderr 模板无效: %v", i+1, err)
+		}
+		if r.Delay != "" {
+			if c.delay, err = time.ParseDuration(r.Delay); err != nil {
+				return nil, fmt.Errorf("第 %d 条规则的 delay 无效: %v", i+1, err)
+			}
+		}
+		compiled = append(compiled, c)
+	}
+	return compiled, nil
+}
+
+// Connect 脚本中列为不可达的节点返回错误
+func (s *Simulator) Connect(config ssh.SSHConfig) error {
+	if s.unreachable[config.Host] {
+		return fmt.Errorf("dial tcp %s:%d: connect: no route to host（模拟）", config.Host, config.Port)
+	}
+	return nil
+}
+
+// Upload 文件保存在内存中，可通过 cat 读取
+func (s *Simulator) Upload(host, remotePath string, content []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.files[host] == nil {
+		s.files[host] = make(map[string][]byte)
+	}
+	s.files[host][remotePath] = content
+	return nil
+}
+
+// Run 按规则返回命令结果
+func (s *Simulator) Run(host, cmd string, stdin []byte) (*ssh.CommandResult, error) {
+	executed := cmd
+	cmd = kubectlInvocation.ReplaceAllString(cmd, "kubectl")
+	s.mu.Lock()
+	if m := nodeNamePattern.FindStringSubmatch(cmd); m != nil {
+		s.nodes[host] = Node{Name: m[1], IP: host, Server: !strings.Contains(cmd, "K3S_URL="), Labels: make(map[string]string)}
+	}
+	if m := labelPattern.FindStringSubmatch(cmd); m != nil {
+		s.label(m[1], strings.Fields(m[2]))
+	}
+	r, match := s.find(host, cmd, s.rules)
+	var result *ssh.CommandResult
+	if r == nil {
+		result = s.runFile(host, cmd)
+	}
+	if r == nil && result == nil {
+		r, match = s.find(host, cmd, s.builtin)
+	}
+	var delay time.Duration
+	var err error
+	switch {
+	case r != nil:
+		result, err = s.apply(host, r, match)
+		delay = r.delay
+	case result == nil:
+		result = &ssh.CommandResult{}
+	}
+	if err == nil {
+		s.history = append(s.history, Execution{Host: host, Command: executed, ExitCode: result.ExitCode})
+	}
+	s.mu.Unlock()
+
+	if delay > 0 {
+		time.Sleep(delay)
+	}
+	return result, err
+}
+
+// find 返回第一条匹配且未用完次数、标记满足条件的规则，调用方需持有 s.mu
+func (s *Simulator) find(host, cmd string, rules []*rule) (*rule, []string) {
+	for _, r := range rules {
+		if r.Times > 0 && r.hits >= r.Times {
+			continue
+		}
+		if len(r.Hosts) > 0 && !contains(r.Hosts, host) {
+			continue
+		}
+		if !s.flagsMatch(host, r.If) {
+			continue
+		}
+		if match := r.pattern.FindStringSubmatch(cmd); match != nil {
+			return r, match
+		}
+	}
+	return nil, nil
+}
+
+func (s *Simulator) flagsMatch(host string, conditions []string) bool {
+	for _, cond := range conditions {
+		if name, negated := strings.CutPrefix(cond, "!"); negated {
+			if s.flags[host][name] {
+				return false
+			}
+		} else if !s.flags[host][cond] {
+			return false
+		}
+	}
+	return true
+}
+
+// apply 渲染规则的输出并更新节点标记，调用方需持有 s.mu
+func (s *Simulator) apply(host string, r *rule, match []string) (*ssh.CommandResult, error) {
+	r.hits++
+	data := struct {
+		Host  string
+		Match []string
+		Nodes []Node
+	}{host, match, s.sortedNodes()}
+
+	var stdout, stderr strings.Builder
+	if err := r.stdout.Execute(&stdout, data); err != nil {
+		return nil, fmt.Errorf("渲染模拟输出失败: %v", err)
+	}
+	if err := r.stderr.Execute(&stderr, data); err != nil {
+		return nil, fmt.Errorf("渲染模拟输出失败: %v", err)
+	}
+
+	if s.flags[host] == nil {
+		s.flags[host] = make(map[string]bool)
+	}
+	for _, flag := range r.Set {
+		s.flags[host][flag] = true
+	}
+	for _, flag := range r.Unset {
+		delete(s.flags[host], flag)
+		// 清除 k3s 标记表示已卸载，节点从模拟集群中移除
+		if flag == "k3s" {
+			delete(s.nodes, host)
+		}
+	}
+	return &ssh.CommandResult{Stdout: stdout.String(), Stderr: stderr.String(), ExitCode: r.Exit}, nil
+}
+
+// runFile 处理读取、检查和删除内存文件的命令，不是这类命令时返回 nil，调用方需持有 s.mu
+func (s *Simulator) runFile(host, cmd string) *ssh.CommandResult {
+	cmd = strings.TrimSpace(cmd)
+	if m := catPattern.FindStringSubmatch(cmd); m != nil {
+		if content, ok := s.files[host][m[1]]; ok {
+			return &ssh.CommandResult{Stdout: string(content)}
+		}
+		return nil
+	}
+	if m := testPattern.FindStringSubmatch(cmd); m != nil {
+		if _, ok := s.files[host][m[1]]; ok {
+			return &ssh.CommandResult{}
+		}
+		return nil
+	}
+	if m := removePattern.FindStringSubmatch(cmd); m != nil {
+		delete(s.files[host], m[1])
+		return &ssh.CommandResult{}
+	}
+	return nil
+}
+
+// label 更新名为 name 的节点标签，调用方需持有 s.mu
+func (s *Simulator) label(name string, args []string) {
+	for _, node := range s.nodes {
+		if node.Name != name {
+			continue
+		}
+		for _, arg := range args {
+			if key, value, ok := strings.Cut(arg, "="); ok {
+				node.Labels[key] = value
+			} else if key, ok := strings.CutSuffix(arg, "-"); ok {
+				delete(node.Labels, key)
+			}
+		}
+	}
+}
+
+func (s *Simulator) sortedNodes() []Node {
+	nodes := make([]Node, 0, len(s.nodes))
+	for _, node := range s.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Slice(nodes, func(i, j int) bool {
+		if nodes[i].Server != nodes[j].Server {
+			return nodes[i].Server
+		}
+		return nodes[i].Name < nodes[j].Name
+	})
+	return nodes
+}
+
+// Nodes 返回模拟集群中的节点，Server 节点在前、其余按节点名排序
+func (s *Simulator) Nodes() []Node {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	nodes := s.sortedNodes()
+	for i, node := range nodes {
+		labels := make(map[string]string, len(node.Labels))
+		for k, v := range node.Labels {
+			labels[k] = v
+		}
+		nodes[i].Labels = labels
+	}
+	return nodes
+}
+
+// File 返回上传到节点的文件内容
+func (s *Simulator) File(host, path string) ([]byte, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	content, ok := s.files[host][path]
+	return content, ok
+}
+
+// History 返回全部命令执行记录，按执行顺序排列
+func (s *Simulator) History() []Execution {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Execution(nil), s.history...)
+}
+
+func contains(values []string, value string) bool {
+	for _, v := range values {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,122 @@
+package store
+
+import (
+	"sync"
+	"time"
+)
+
+type lease struct {
+	owner   string
+	expires time.Time
+}
+
+// MemoryStore 进程内存储，仅适用于单副本部署
+type MemoryStore struct {
+	mu          sync.Mutex
+	collections map[string]map[string][]byte
+	leases      map[string]lease
+	taskLogs    map[string][]string
+}
+
+func NewMemoryStore() *MemoryStore {
+	return &MemoryStore{
+		collections: make(map[string]map[string][]byte),
+		leases:      make(map[string]lease),
+		taskLogs:    make(map[string][]string),
+	}
+}
+
+func (s *MemoryStore) Get(collection, id string) ([]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	value, exists := s.collections[collection][id]
+	if !exists {
+		return nil, ErrNotFound
+	}
+	return append([]byte(nil), value...), nil
+}
+
+func (s *MemoryStore) Put(collection, id string, value []byte) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	records, exists := s.collections[collection]
+	if !exists {
+		records = make(map[string][]byte)
+		s.collections[collection] = records
+	}
+	records[id] = append([]byte(nil), value...)
+	return nil
+}
+
+func (s *MemoryStore) Delete(collection, id string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if _, exists := s.collections[collection][id]; !exists {
+		return ErrNotFound
+	}
+	delete(s.collections[collection], id)
+	return nil
+}
+
+func (s *MemoryStore) List(collection string) (map[string][]byte, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make(map[string][]byte, len(s.collections[collection]))
+	for id, value := range s.collections[collection] {
+		result[id] = append([]byte(nil), value...)
+	}
+	return result, nil
+}
+
+func (s *MemoryStore) AppendTaskLog(taskID, line string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.taskLogs[taskID] = append(s.taskLogs[taskID], line)
+	return nil
+}
+
+func (s *MemoryStore) TaskLogs(taskID string) ([]string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string{}, s.taskLogs[taskID]...), nil
+}
+
+func (s *MemoryStore) DeleteTaskLogs(taskID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.taskLogs, taskID)
+	return nil
+}
+
+func (s *MemoryStore) AcquireLease(name, owner string, ttl time.Duration) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	now := time.Now()
+	if current, exists := s.leases[name]; exists && current.owner != owner && now.Before(current.expires) {
+		return false, nil
+	}
+	s.leases[name] = lease{owner: owner, expires: now.Add(ttl)}
+	return true, nil
+}
+
+func (s *MemoryStore) ReleaseLease(name, owner string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if current, exists := s.leases[name]; exists && current.owner == owner {
+		delete(s.leases, name)
+	}
+	return nil
+}
+
+func (s *MemoryStore) Close() error {
+	return nil
+}
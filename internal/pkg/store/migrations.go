@@ -0,0 +1,106 @@
+package store
+
+import (
+	"database/sql"
+	"fmt"
+	"time"
+)
+
+// migrations 按版本顺序执行的数据库结构变更，已发布的条目不可修改，只能追加
+var migrations = []string{
+	// 1: 初始结构
+	`
+	CREATE TABLE nodes (id TEXT PRIMARY KEY, data BLOB NOT NULL, updated_at DATETIME NOT NULL);
+	CREATE TABLE credentials (id TEXT PRIMARY KEY, data BLOB NOT NULL, updated_at DATETIME NOT NULL);
+	CREATE TABLE clusters (id TEXT PRIMARY KEY, data BLOB NOT NULL, updated_at DATETIME NOT NULL);
+	CREATE TABLE tasks (id TEXT PRIMARY KEY, data BLOB NOT NULL, updated_at DATETIME NOT NULL);
+	CREATE TABLE task_requests (id TEXT PRIMARY KEY, data BLOB NOT NULL, updated_at DATETIME NOT NULL);
+	CREATE TABLE task_logs (
+		seq INTEGER PRIMARY KEY AUTOINCREMENT,
+		task_id TEXT NOT NULL,
+		line TEXT NOT NULL,
+		created_at DATETIME NOT NULL
+	);
+	CREATE INDEX idx_task_logs_task_id ON task_logs (task_id, seq);
+	CREATE TABLE audit_events (id TEXT PRIMARY KEY, data BLOB NOT NULL, updated_at DATETIME NOT NULL);
+	CREATE TABLE leases (name TEXT PRIMARY KEY, owner TEXT NOT NULL, expires_at INTEGER NOT NULL);
+	`,
+	// 2: 集群告警
+	`
+	CREATE TABLE alerts (id TEXT PRIMARY KEY, data BLOB NOT NULL, updated_at DATETIME NOT NULL);
+	`,
+	// 3: WebSSH 连接票据
+	`
+	CREATE TABLE webssh_tickets (id TEXT PRIMARY KEY, data BLOB NOT NULL, updated_at DATETIME NOT NULL);
+	`,
+	// 4: 集群事件
+	`
+	CREATE TABLE cluster_events (id TEXT PRIMARY KEY, data BLOB NOT NULL, updated_at DATETIME NOT NULL);
+	`,
+	// 5: 节点变更日志
+	`
+	CREATE TABLE node_changes (id TEXT PRIMARY KEY, data BLOB NOT NULL, updated_at DATETIME NOT NULL);
+	`,
+	// 6: API Server 审计记录
+	`
+	CREATE TABLE kube_audit_logs (id TEXT PRIMARY KEY, data BLOB NOT NULL, updated_at DATETIME NOT NULL);
+	`,
+	// 7: 安全扫描结果
+	`
+	CREATE TABLE security_scans (id TEXT PRIMARY KEY, data BLOB NOT NULL, updated_at DATETIME NOT NULL);
+	`,
+	// 8: 伸缩建议使用的资源用量采样
+	`
+	CREATE TABLE utilization (id TEXT PRIMARY KEY, data BLOB NOT NULL, updated_at DATETIME NOT NULL);
+	`,
+	// 9: 部署任务的基准测试记录
+	`
+	CREATE TABLE benchmarks (id TEXT PRIMARY KEY, data BLOB NOT NULL, updated_at DATETIME NOT NULL);
+	`,
+}
+
+// checkSchema 数据库版本必须等于当前程序的最新版本
+func checkSchema(db *sql.DB) error {
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("读取数据库版本失败: %w", err)
+	}
+	if current != len(migrations) {
+		return fmt.Errorf("数据库版本 %d 与当前程序的版本 %d 不一致", current, len(migrations))
+	}
+	return nil
+}
+
+// migrate 启动时自动将数据库升级到最新结构
+func migrate(db *sql.DB) error {
+	if _, err := db.Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (version INTEGER PRIMARY KEY, applied_at DATETIME NOT NULL)`); err != nil {
+		return fmt.Errorf("初始化迁移表失败: %w", err)
+	}
+
+	var current int
+	if err := db.QueryRow(`SELECT COALESCE(MAX(version), 0) FROM schema_migrations`).Scan(&current); err != nil {
+		return fmt.Errorf("读取数据库版本失败: %w", err)
+	}
+	if current > len(migrations) {
+		return fmt.Errorf("数据库版本 %d 高于当前程序支持的版本 %d，请升级程序", current, len(migrations))
+	}
+
+	for version := current + 1; version <= len(migrations); version++ {
+		tx, err := db.Begin()
+		if err != nil {
+			return err
+		}
+		if _, err := tx.Exec(migrations[version-1]); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("执行数据库迁移 %d 失败: %w", version, err)
+		}
+		if _, err := tx.Exec(`INSERT INTO schema_migrations (version, applied_at) VALUES (?, ?)`, version, time.Now().UTC()); err != nil {
+			tx.Rollback()
+			return fmt.Errorf("记录数据库迁移 %d 失败: %w", version, err)
+		}
+		if err := tx.Commit(); err != nil {
+			return fmt.Errorf("提交数据库迁移 %d 失败: %w", version, err)
+		}
+	}
+	return nil
+}
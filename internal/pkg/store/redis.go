@@ -0,0 +1,170 @@
+package store
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+// redisTimeout 单次 Redis 操作超时时间
+const redisTimeout = 5 * time.Second
+
+// acquireScript 租约空闲或已由 owner 持有时设置/续期
+var acquireScript = redis.NewScript(`
+local current = redis.call("GET", KEYS[1])
+if current == false or current == ARGV[1] then
+	redis.call("SET", KEYS[1], ARGV[1], "PX", ARGV[2])
+	return 1
+end
+return 0
+`)
+
+// releaseScript 仅当租约由 owner 持有时删除
+var releaseScript = redis.NewScript(`
+if redis.call("GET", KEYS[1]) == ARGV[1] then
+	return redis.call("DEL", KEYS[1])
+end
+return 0
+`)
+
+// RedisStore 基于 Redis 的共享存储：每个集合对应一个 Hash，租约使用带过期时间的字符串键
+type RedisStore struct {
+	client *redis.Client
+	prefix string
+}
+
+func NewRedisStore(opts Options) (*RedisStore, error) {
+	if opts.Addr == "" {
+		return nil, errors.New("Redis 地址不能为空")
+	}
+
+	client := redis.NewClient(&redis.Options{
+		Addr:     opts.Addr,
+		Password: opts.Password,
+		DB:       opts.DB,
+	})
+
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		client.Close()
+		return nil, fmt.Errorf("连接 Redis 失败: %w", err)
+	}
+
+	prefix := opts.KeyPrefix
+	if prefix == "" {
+		prefix = "k3s-deploy"
+	}
+	return &RedisStore{client: client, prefix: prefix}, nil
+}
+
+func (s *RedisStore) collectionKey(collection string) string {
+	return s.prefix + ":" + collection
+}
+
+func (s *RedisStore) leaseKey(name string) string {
+	return s.prefix + ":lease:" + name
+}
+
+func (s *RedisStore) Get(collection, id string) ([]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	value, err := s.client.HGet(ctx, s.collectionKey(collection), id).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, ErrNotFound
+	}
+	return value, err
+}
+
+func (s *RedisStore) Put(collection, id string, value []byte) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	return s.client.HSet(ctx, s.collectionKey(collection), id, value).Err()
+}
+
+func (s *RedisStore) Delete(collection, id string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	deleted, err := s.client.HDel(ctx, s.collectionKey(collection), id).Result()
+	if err != nil {
+		return err
+	}
+	if deleted == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *RedisStore) List(collection string) (map[string][]byte, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	values, err := s.client.HGetAll(ctx, s.collectionKey(collection)).Result()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string][]byte, len(values))
+	for id, value := range values {
+		result[id] = []byte(value)
+	}
+	return result, nil
+}
+
+func (s *RedisStore) AppendTaskLog(taskID, line string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	return s.client.RPush(ctx, s.prefix+":task_logs:"+taskID, line).Err()
+}
+
+func (s *RedisStore) TaskLogs(taskID string) ([]string, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	return s.client.LRange(ctx, s.prefix+":task_logs:"+taskID, 0, -1).Result()
+}
+
+func (s *RedisStore) DeleteTaskLogs(taskID string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	return s.client.Del(ctx, s.prefix+":task_logs:"+taskID).Err()
+}
+
+func (s *RedisStore) AcquireLease(name, owner string, ttl time.Duration) (bool, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	acquired, err := acquireScript.Run(ctx, s.client, []string{s.leaseKey(name)}, owner, ttl.Milliseconds()).Int()
+	if err != nil {
+		return false, err
+	}
+	return acquired == 1, nil
+}
+
+func (s *RedisStore) ReleaseLease(name, owner string) error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+
+	return releaseScript.Run(ctx, s.client, []string{s.leaseKey(name)}, owner).Err()
+}
+
+// Check 检查 Redis 连接
+func (s *RedisStore) Check() error {
+	ctx, cancel := context.WithTimeout(context.Background(), redisTimeout)
+	defer cancel()
+	if err := s.client.Ping(ctx).Err(); err != nil {
+		return fmt.Errorf("连接 Redis 失败: %w", err)
+	}
+	return nil
+}
+
+func (s *RedisStore) Close() error {
+	return s.client.Close()
+}
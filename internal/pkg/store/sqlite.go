@@ -0,0 +1,217 @@
+package store
+
+import (
+	"database/sql"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "github.com/mattn/go-sqlite3"
+)
+
+// sqliteTables 集合与数据表的对应关系，只允许访问已建表的集合
+var sqliteTables = map[string]string{
+	CollectionTasks:         "tasks",
+	CollectionTaskRequests:  "task_requests",
+	CollectionCredentials:   "credentials",
+	CollectionNodes:         "nodes",
+	CollectionClusters:      "clusters",
+	CollectionAuditEvents:   "audit_events",
+	CollectionAlerts:        "alerts",
+	CollectionWebSSHTickets: "webssh_tickets",
+	CollectionClusterEvents: "cluster_events",
+	CollectionNodeChanges:   "node_changes",
+	CollectionKubeAuditLogs: "kube_audit_logs",
+	CollectionSecurityScans: "security_scans",
+	CollectionUtilization:   "utilization",
+	CollectionBenchmarks:    "benchmarks",
+}
+
+// SQLiteStore 嵌入式 SQLite 存储，适用于单副本持久化部署
+type SQLiteStore struct {
+	db *sql.DB
+}
+
+func NewSQLiteStore(path string) (*SQLiteStore, error) {
+	if path == "" {
+		return nil, errors.New("SQLite 数据库路径不能为空")
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return nil, fmt.Errorf("创建数据库目录失败: %w", err)
+	}
+
+	db, err := sql.Open("sqlite3", path+"?_busy_timeout=5000&_journal_mode=WAL&_foreign_keys=on")
+	if err != nil {
+		return nil, fmt.Errorf("打开数据库失败: %w", err)
+	}
+	// SQLite 同一时刻只允许一个写入者，统一使用单连接避免锁冲突
+	db.SetMaxOpenConns(1)
+
+	if err := migrate(db); err != nil {
+		db.Close()
+		return nil, err
+	}
+	// 数据库中保存任务日志、审计事件和加密的凭据记录，只允许后端自身读取
+	for _, file := range []string{path, path + "-wal", path + "-shm"} {
+		if err := os.Chmod(file, 0600); err != nil && !os.IsNotExist(err) {
+			db.Close()
+			return nil, fmt.Errorf("设置数据库文件权限失败: %w", err)
+		}
+	}
+	return &SQLiteStore{db: db}, nil
+}
+
+func tableFor(collection string) (string, error) {
+	table, exists := sqliteTables[collection]
+	if !exists {
+		return "", fmt.Errorf("未知的数据集合: %s", collection)
+	}
+	return table, nil
+}
+
+func (s *SQLiteStore) Get(collection, id string) ([]byte, error) {
+	table, err := tableFor(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	var data []byte
+	err = s.db.QueryRow("SELECT data FROM "+table+" WHERE id = ?", id).Scan(&data)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, ErrNotFound
+	}
+	return data, err
+}
+
+func (s *SQLiteStore) Put(collection, id string, value []byte) error {
+	table, err := tableFor(collection)
+	if err != nil {
+		return err
+	}
+
+	_, err = s.db.Exec(
+		"INSERT INTO "+table+" (id, data, updated_at) VALUES (?, ?, ?) "+
+			"ON CONFLICT(id) DO UPDATE SET data = excluded.data, updated_at = excluded.updated_at",
+		id, value, time.Now().UTC(),
+	)
+	return err
+}
+
+func (s *SQLiteStore) Delete(collection, id string) error {
+	table, err := tableFor(collection)
+	if err != nil {
+		return err
+	}
+
+	result, err := s.db.Exec("DELETE FROM "+table+" WHERE id = ?", id)
+	if err != nil {
+		return err
+	}
+	if affected, _ := result.RowsAffected(); affected == 0 {
+		return ErrNotFound
+	}
+	return nil
+}
+
+func (s *SQLiteStore) List(collection string) (map[string][]byte, error) {
+	table, err := tableFor(collection)
+	if err != nil {
+		return nil, err
+	}
+
+	rows, err := s.db.Query("SELECT id, data FROM " + table)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	result := make(map[string][]byte)
+	for rows.Next() {
+		var id string
+		var data []byte
+		if err := rows.Scan(&id, &data); err != nil {
+			return nil, err
+		}
+		result[id] = data
+	}
+	return result, rows.Err()
+}
+
+func (s *SQLiteStore) AppendTaskLog(taskID, line string) error {
+	_, err := s.db.Exec("INSERT INTO task_logs (task_id, line, created_at) VALUES (?, ?, ?)", taskID, line, time.Now().UTC())
+	return err
+}
+
+func (s *SQLiteStore) TaskLogs(taskID string) ([]string, error) {
+	rows, err := s.db.Query("SELECT line FROM task_logs WHERE task_id = ? ORDER BY seq", taskID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	lines := []string{}
+	for rows.Next() {
+		var line string
+		if err := rows.Scan(&line); err != nil {
+			return nil, err
+		}
+		lines = append(lines, line)
+	}
+	return lines, rows.Err()
+}
+
+func (s *SQLiteStore) DeleteTaskLogs(taskID string) error {
+	_, err := s.db.Exec("DELETE FROM task_logs WHERE task_id = ?", taskID)
+	return err
+}
+
+func (s *SQLiteStore) AcquireLease(name, owner string, ttl time.Duration) (bool, error) {
+	now := time.Now().UnixMilli()
+	result, err := s.db.Exec(
+		"INSERT INTO leases (name, owner, expires_at) VALUES (?, ?, ?) "+
+			"ON CONFLICT(name) DO UPDATE SET owner = excluded.owner, expires_at = excluded.expires_at "+
+			"WHERE leases.owner = excluded.owner OR leases.expires_at < ?",
+		name, owner, now+ttl.Milliseconds(), now,
+	)
+	if err != nil {
+		return false, err
+	}
+	affected, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+	return affected == 1, nil
+}
+
+func (s *SQLiteStore) ReleaseLease(name, owner string) error {
+	_, err := s.db.Exec("DELETE FROM leases WHERE name = ? AND owner = ?", name, owner)
+	return err
+}
+
+// Check 检查数据库版本与当前程序一致且各集合的数据表存在（如被手动删除或从其他版本的备份恢复）
+func (s *SQLiteStore) Check() error {
+	if err := checkSchema(s.db); err != nil {
+		return err
+	}
+	tables := []string{"task_logs", "leases"}
+	for _, table := range sqliteTables {
+		tables = append(tables, table)
+	}
+	for _, table := range tables {
+		var name string
+		err := s.db.QueryRow(`SELECT name FROM sqlite_master WHERE type = 'table' AND name = ?`, table).Scan(&name)
+		if errors.Is(err, sql.ErrNoRows) {
+			return fmt.Errorf("数据表 %s 不存在", table)
+		}
+		if err != nil {
+			return fmt.Errorf("检查数据表 %s 失败: %w", table, err)
+		}
+	}
+	return nil
+}
+
+func (s *SQLiteStore) Close() error {
+	return s.db.Close()
+}
@@ -0,0 +1,87 @@
+package store
+
+import (
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrNotFound 记录不存在
+var ErrNotFound = errors.New("记录不存在")
+
+// 集合名称
+const (
+	CollectionTasks        = "tasks"
+	CollectionTaskRequests = "task_requests"
+	CollectionCredentials  = "credentials"
+	CollectionNodes        = "nodes"
+	CollectionClusters     = "clusters"
+	CollectionAuditEvents  = "audit_events"
+	CollectionAlerts       = "alerts"
+	// CollectionWebSSHTickets WebSSH 一次性连接票据
+	CollectionWebSSHTickets = "webssh_tickets"
+	// CollectionClusterEvents 每个集群最近收集的 Kubernetes 事件，以集群 ID 为键
+	CollectionClusterEvents = "cluster_events"
+	// CollectionNodeChanges 部署对节点系统配置的变更日志，以节点 IP 为键
+	CollectionNodeChanges = "node_changes"
+	// CollectionKubeAuditLogs 每个集群最近收集的 API Server 审计记录，以集群 ID 为键
+	CollectionKubeAuditLogs = "kube_audit_logs"
+	// CollectionSecurityScans 每个集群最近一次安全扫描，以集群 ID 为键
+	CollectionSecurityScans = "security_scans"
+	// CollectionUtilization 每个集群的资源用量采样，以集群 ID 为键
+	CollectionUtilization = "utilization"
+	// CollectionBenchmarks 启用了基准测试的部署任务的耗时记录，以任务 ID 为键
+	CollectionBenchmarks = "benchmarks"
+)
+
+// Store 多副本共享状态存储。记录按集合组织，值为序列化后的 JSON；
+// 租约用于保证同一资源同一时刻只被一个副本持有。
+type Store interface {
+	Get(collection, id string) ([]byte, error)
+	Put(collection, id string, value []byte) error
+	Delete(collection, id string) error
+	List(collection string) (map[string][]byte, error)
+
+	// AppendTaskLog 追加一行任务日志，TaskLogs 按写入顺序返回
+	AppendTaskLog(taskID, line string) error
+	TaskLogs(taskID string) ([]string, error)
+	// DeleteTaskLogs 删除任务的全部日志
+	DeleteTaskLogs(taskID string) error
+
+	// AcquireLease 获取或续期租约：租约空闲、已过期或已由 owner 持有时返回 true
+	AcquireLease(name, owner string, ttl time.Duration) (bool, error)
+	// ReleaseLease 释放 owner 持有的租约，租约已被他人持有时不做处理
+	ReleaseLease(name, owner string) error
+
+	Close() error
+}
+
+// Checker 可选接口：启动自检时检查存储的连接和数据结构
+type Checker interface {
+	Check() error
+}
+
+// Options 存储后端参数
+type Options struct {
+	Backend   string
+	Addr      string
+	Password  string
+	DB        int
+	KeyPrefix string
+	// Path SQLite 数据库文件路径
+	Path string
+}
+
+// Open 按配置打开存储后端
+func Open(opts Options) (Store, error) {
+	switch opts.Backend {
+	case "", "memory":
+		return NewMemoryStore(), nil
+	case "redis":
+		return NewRedisStore(opts)
+	case "sqlite":
+		return NewSQLiteStore(opts.Path)
+	default:
+		return nil, fmt.Errorf("不支持的存储后端: %s", opts.Backend)
+	}
+}
@@ -0,0 +1,144 @@
+package transcript
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// generatedID 任务、请求等由 utils.GenerateID 生成的标识（<前缀>-<16 位十六进制>），
+// 每次执行都不同，比对命令时统一替换
+var generatedID = regexp.MustCompile(`\b([a-z]+)-[0-9a-f]{16}\b`)
+
+// Replayer 实现 ssh.Backend，按录制记录应答节点命令。
+// 每个节点的命令按录制顺序匹配第一条未使用的相同命令；命令比录制执行了更多次（如轮询）时重复最后一次的结果，
+// 录制中没有的命令返回错误，便于发现回放与录制的执行路径出现分歧
+type Replayer struct {
+	mu      sync.Mutex
+	entries map[string][]*replayEntry
+	// secrets 节点的密码和私钥口令，录制时已被替换
+	secrets map[string][]string
+}
+
+type replayEntry struct {
+	Entry
+	key  string
+	used bool
+}
+
+// Load 读取录制文件
+func Load(path string) (*Replayer, error) {
+	entries, err := ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("读取命令录制失败: %v", err)
+	}
+	if len(entries) == 0 {
+		return nil, fmt.Errorf("命令录制 %s 为空", path)
+	}
+	return NewReplayer(entries), nil
+}
+
+// NewReplayer 使用录制记录创建回放后端
+func NewReplayer(entries []Entry) *Replayer {
+	r := &Replayer{
+		entries: make(map[string][]*replayEntry),
+		secrets: make(map[string][]string),
+	}
+	for _, e := range entries {
+		r.entries[e.Host] = append(r.entries[e.Host], &replayEntry{Entry: e, key: normalize(e.Command)})
+	}
+	return r
+}
+
+// Connect 节点在录制中没有任何记录时视为不可达；下一条未使用的记录为连接失败时返回该错误
+func (r *Replayer) Connect(config ssh.SSHConfig) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	entries, ok := r.entries[config.Host]
+	if !ok {
+		return fmt.Errorf("命令录制中没有节点 %s", config.Host)
+	}
+	r.secrets[config.Host] = []string{config.Password, config.Passphrase}
+	for _, e := range entries {
+		if e.used {
+			continue
+		}
+		if e.Connect {
+			e.used = true
+			return fmt.Errorf("%s", e.Error)
+		}
+		break
+	}
+	return nil
+}
+
+func (r *Replayer) Run(host, cmd string, stdin []byte) (*ssh.CommandResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := normalize(r.mask(host, cmd))
+	var last *replayEntry
+	for _, e := range r.entries[host] {
+		if e.Connect || e.Upload != "" || !sameCommand(key, e.key) {
+			continue
+		}
+		if !e.used {
+			e.used = true
+			return e.result()
+		}
+		last = e
+	}
+	if last != nil {
+		return last.result()
+	}
+	return nil, fmt.Errorf("命令录制中没有节点 %s 上的命令: %s", host, cmd)
+}
+
+// Upload 上传不校验文件内容，录制中同一路径的上传失败时返回该错误
+func (r *Replayer) Upload(host, remotePath string, content []byte) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	key := normalize(remotePath)
+	for _, e := range r.entries[host] {
+		if e.used || e.Upload == "" || normalize(e.Upload) != key {
+			continue
+		}
+		e.used = true
+		if e.Error != "" {
+			return fmt.Errorf("%s", e.Error)
+		}
+		return nil
+	}
+	return nil
+}
+
+func (e *replayEntry) result() (*ssh.CommandResult, error) {
+	if e.ExitCode == 0 && e.Error != "" {
+		// 录制的是 Client 包装后的错误，回放时由 Client 重新包装
+		return nil, fmt.Errorf("%s", strings.TrimPrefix(e.Error, "命令执行失败: "))
+	}
+	return &ssh.CommandResult{Stdout: e.Stdout, Stderr: e.Stderr, ExitCode: e.ExitCode}, nil
+}
+
+// mask 与录制时一样替换节点认证信息
+func (r *Replayer) mask(host, cmd string) string {
+	for _, secret := range r.secrets[host] {
+		cmd = ssh.RedactCredential(cmd, secret)
+	}
+	return cmd
+}
+
+// normalize 脱敏并替换生成的标识，使不同次执行的同一命令可以比对
+func normalize(cmd string) string {
+	return generatedID.ReplaceAllString(redactText(cmd), "$1-*")
+}
+
+// sameCommand 录制的命令不含环境变量前缀，回放收到的命令可能以 KEY=value 开头
+func sameCommand(actual, recorded string) bool {
+	return actual == recorded || strings.HasSuffix(actual, " "+recorded)
+}
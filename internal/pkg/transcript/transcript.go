@@ -0,0 +1,170 @@
+// Package transcript 按请求录制节点命令及其输出，并可将录制结果作为 SSH 后端回放，
+// 用于离线复现用户报告的安装失败
+package transcript
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// Entry 录制的一次命令执行、文件上传或连接失败
+type Entry struct {
+	Time    time.Time `json:"time"`
+	Host    string    `json:"host"`
+	Command string    `json:"command,omitempty"`
+	// Upload 文件上传的目标路径，文件内容不录制
+	Upload string `json:"upload,omitempty"`
+	// Connect 连接节点失败
+	Connect    bool   `json:"connect,omitempty"`
+	Stdout     string `json:"stdout,omitempty"`
+	Stderr     string `json:"stderr,omitempty"`
+	ExitCode   int    `json:"exitCode"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+// Recorder 将带请求 ID 的命令记录脱敏后追加到 <dir>/<请求 ID>.jsonl
+type Recorder struct {
+	dir string
+	mu  sync.Mutex
+}
+
+// NewRecorder 创建录制目录
+func NewRecorder(dir string) (*Recorder, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, fmt.Errorf("创建命令录制目录失败: %v", err)
+	}
+	return &Recorder{dir: dir}, nil
+}
+
+// Record 录制一条命令记录，没有请求 ID 的后台操作不录制
+func (r *Recorder) Record(log ssh.CommandLog) error {
+	if log.RequestID == "" {
+		return nil
+	}
+	entry := redact(Entry{
+		Time:       time.Now().Add(-log.Duration),
+		Host:       log.Host,
+		Command:    log.Command,
+		Upload:     log.Upload,
+		Connect:    log.Connect,
+		Stdout:     log.Stdout,
+		Stderr:     log.Stderr,
+		ExitCode:   log.ExitCode,
+		DurationMs: log.Duration.Milliseconds(),
+	})
+	if log.Err != nil {
+		entry.Error = redactText(log.Err.Error())
+	}
+	// 命令中常见的 <、>、& 不转义，便于直接阅读录制文件
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	if err := enc.Encode(entry); err != nil {
+		return err
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	f, err := os.OpenFile(r.path(log.RequestID), os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0600)
+	if err != nil {
+		return err
+	}
+	if _, err := f.Write(buf.Bytes()); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Read 读取请求的录制记录，没有录制时返回空列表
+func (r *Recorder) Read(requestID string) ([]Entry, error) {
+	entries, err := ReadFile(r.path(requestID))
+	if errors.Is(err, os.ErrNotExist) {
+		return []Entry{}, nil
+	}
+	return entries, err
+}
+
+// Remove 删除请求的录制记录
+func (r *Recorder) Remove(requestID string) error {
+	if err := os.Remove(r.path(requestID)); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// unsafeName 请求 ID 可由调用方通过 X-Request-ID 传入，文件名中只保留安全字符
+var unsafeName = regexp.MustCompile(`[^A-Za-z0-9._-]`)
+
+func (r *Recorder) path(requestID string) string {
+	return filepath.Join(r.dir, unsafeName.ReplaceAllString(requestID, "_")+".jsonl")
+}
+
+// ReadFile 读取 JSON Lines 格式的录制文件
+func ReadFile(path string) ([]Entry, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	var entries []Entry
+	scanner := bufio.NewScanner(f)
+	scanner.Buffer(make([]byte, 0, 64*1024), 16*1024*1024)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var entry Entry
+		if err := json.Unmarshal(scanner.Bytes(), &entry); err != nil {
+			return nil, fmt.Errorf("%s 第 %d 行格式无效: %v", path, line, err)
+		}
+		entries = append(entries, entry)
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// redacted 替换敏感内容的占位符
+const redacted = "<redacted>"
+
+// secretFiles 内容整体视为敏感信息的节点文件
+var secretFiles = []string{
+	"/var/lib/rancher/k3s/server/node-token",
+	"/var/lib/rancher/k3s/server/token",
+	"/var/lib/rancher/k3s/server/agent-token",
+}
+
+// redact 替换记录中的令牌、私钥、密码等敏感内容。回放时对实际命令做同样的处理后再与录制比对
+func redact(entry Entry) Entry {
+	entry.Command = redactText(entry.Command)
+	for _, file := range secretFiles {
+		if strings.Contains(entry.Command, file) && entry.Stdout != "" {
+			entry.Stdout = redacted
+		}
+	}
+	if strings.Contains(entry.Command, "get secret") && entry.Stdout != "" {
+		entry.Stdout = redacted
+	}
+	entry.Stdout = redactText(entry.Stdout)
+	entry.Stderr = redactText(entry.Stderr)
+	return entry
+}
+
+func redactText(s string) string {
+	return ssh.RedactSecrets(s)
+}
@@ -0,0 +1,170 @@
+package vault
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"k3s-deploy-backend/internal/pkg/store"
+)
+
+// backend 凭据记录（已加密）的持久化方式
+type backend interface {
+	list() ([]record, error)
+	get(id string) (record, bool, error)
+	put(r record) error
+	delete(id string) error
+}
+
+// fileBackend 单机 JSON 文件存储，全部记录缓存在内存中
+type fileBackend struct {
+	path    string
+	records map[string]record
+}
+
+func newFileBackend(path string) (*fileBackend, error) {
+	b := &fileBackend{path: path, records: make(map[string]record)}
+
+	data, err := os.ReadFile(path)
+	if os.IsNotExist(err) {
+		return b, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取凭据库失败: %w", err)
+	}
+
+	var records []record
+	if err := json.Unmarshal(data, &records); err != nil {
+		return nil, fmt.Errorf("解析凭据库失败: %w", err)
+	}
+	for _, r := range records {
+		b.records[r.ID] = r
+	}
+	return b, nil
+}
+
+func (b *fileBackend) list() ([]record, error) {
+	list := make([]record, 0, len(b.records))
+	for _, r := range b.records {
+		list = append(list, r)
+	}
+	return list, nil
+}
+
+func (b *fileBackend) get(id string) (record, bool, error) {
+	r, exists := b.records[id]
+	return r, exists, nil
+}
+
+func (b *fileBackend) put(r record) error {
+	previous, hadPrevious := b.records[r.ID]
+	b.records[r.ID] = r
+
+	if err := b.persist(); err != nil {
+		// 持久化失败时回滚内存状态
+		if hadPrevious {
+			b.records[r.ID] = previous
+		} else {
+			delete(b.records, r.ID)
+		}
+		return err
+	}
+	return nil
+}
+
+func (b *fileBackend) delete(id string) error {
+	previous, exists := b.records[id]
+	if !exists {
+		return fmt.Errorf("凭据 %s 不存在", id)
+	}
+	delete(b.records, id)
+
+	if err := b.persist(); err != nil {
+		b.records[id] = previous
+		return err
+	}
+	return nil
+}
+
+// persist 将全部记录写入临时文件后原子替换
+func (b *fileBackend) persist() error {
+	records, _ := b.list()
+	sort.Slice(records, func(i, j int) bool { return records[i].ID < records[j].ID })
+
+	data, err := json.MarshalIndent(records, "", "  ")
+	if err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(b.path), 0700); err != nil {
+		return fmt.Errorf("创建凭据库目录失败: %w", err)
+	}
+	tmp := b.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0600); err != nil {
+		return fmt.Errorf("写入凭据库失败: %w", err)
+	}
+	if err := os.Rename(tmp, b.path); err != nil {
+		return fmt.Errorf("替换凭据库失败: %w", err)
+	}
+	return nil
+}
+
+// storeBackend 共享存储，供多副本部署使用；记录不在本地缓存
+type storeBackend struct {
+	store store.Store
+}
+
+func (b *storeBackend) list() ([]record, error) {
+	values, err := b.store.List(store.CollectionCredentials)
+	if err != nil {
+		return nil, fmt.Errorf("读取凭据库失败: %w", err)
+	}
+
+	list := make([]record, 0, len(values))
+	for id, data := range values {
+		var r record
+		if err := json.Unmarshal(data, &r); err != nil {
+			return nil, fmt.Errorf("解析凭据 %s 失败: %w", id, err)
+		}
+		list = append(list, r)
+	}
+	return list, nil
+}
+
+func (b *storeBackend) get(id string) (record, bool, error) {
+	data, err := b.store.Get(store.CollectionCredentials, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return record{}, false, nil
+	}
+	if err != nil {
+		return record{}, false, fmt.Errorf("读取凭据 %s 失败: %w", id, err)
+	}
+
+	var r record
+	if err := json.Unmarshal(data, &r); err != nil {
+		return record{}, false, fmt.Errorf("解析凭据 %s 失败: %w", id, err)
+	}
+	return r, true, nil
+}
+
+func (b *storeBackend) put(r record) error {
+	data, err := json.Marshal(r)
+	if err != nil {
+		return err
+	}
+	if err := b.store.Put(store.CollectionCredentials, r.ID, data); err != nil {
+		return fmt.Errorf("写入凭据库失败: %w", err)
+	}
+	return nil
+}
+
+func (b *storeBackend) delete(id string) error {
+	err := b.store.Delete(store.CollectionCredentials, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return fmt.Errorf("凭据 %s 不存在", id)
+	}
+	return err
+}
@@ -0,0 +1,84 @@
+package vault
+
+import (
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"encoding/base64"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const keySize = 32
+
+// loadOrCreateKey 读取主密钥文件，不存在时生成新密钥（权限 0600）
+func loadOrCreateKey(keyFile string) ([]byte, error) {
+	data, err := os.ReadFile(keyFile)
+	if err == nil {
+		key, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(data)))
+		if err != nil || len(key) != keySize {
+			return nil, fmt.Errorf("主密钥文件 %s 格式无效", keyFile)
+		}
+		return key, nil
+	}
+	if !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取主密钥文件失败: %w", err)
+	}
+
+	key := make([]byte, keySize)
+	if _, err := rand.Read(key); err != nil {
+		return nil, fmt.Errorf("生成主密钥失败: %w", err)
+	}
+	if err := os.MkdirAll(filepath.Dir(keyFile), 0700); err != nil {
+		return nil, fmt.Errorf("创建密钥目录失败: %w", err)
+	}
+	if err := os.WriteFile(keyFile, []byte(base64.StdEncoding.EncodeToString(key)+"\n"), 0600); err != nil {
+		return nil, fmt.Errorf("写入主密钥文件失败: %w", err)
+	}
+	return key, nil
+}
+
+// seal 使用 AES-256-GCM 加密，输出 base64(nonce || ciphertext)
+func seal(key, plaintext []byte) (string, error) {
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return "", err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return "", err
+	}
+
+	nonce := make([]byte, gcm.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+
+	sealed := gcm.Seal(nonce, nonce, plaintext, nil)
+	return base64.StdEncoding.EncodeToString(sealed), nil
+}
+
+// open 解密 seal 的输出
+func open(key []byte, encoded string) ([]byte, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
+	if err != nil {
+		return nil, err
+	}
+
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	gcm, err := cipher.NewGCM(block)
+	if err != nil {
+		return nil, err
+	}
+
+	if len(data) < gcm.NonceSize() {
+		return nil, fmt.Errorf("密文长度无效")
+	}
+	nonce, ciphertext := data[:gcm.NonceSize()], data[gcm.NonceSize():]
+	return gcm.Open(nil, nonce, ciphertext, nil)
+}
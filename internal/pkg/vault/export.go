@@ -0,0 +1,105 @@
+package vault
+
+import (
+	"crypto/rand"
+	"encoding/base64"
+	"errors"
+	"fmt"
+
+	"golang.org/x/crypto/scrypt"
+)
+
+// Bundle 可迁移的凭据导出包：敏感字段使用导出口令派生的密钥重新加密，
+// 与本实例主密钥无关，可在另一台后端上用同一口令导入
+type Bundle struct {
+	Salt    string   `json:"salt"`
+	Records []record `json:"records"`
+}
+
+// deriveKey 由导出口令派生 AES-256 密钥
+func deriveKey(passphrase string, salt []byte) ([]byte, error) {
+	if len(passphrase) < 8 {
+		return nil, errors.New("导出口令长度不能少于 8 位")
+	}
+	return scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, keySize)
+}
+
+// Export 导出全部凭据，敏感字段使用口令重新加密
+func (v *Vault) Export(passphrase string) (*Bundle, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	exportKey, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	records, err := v.backend.list()
+	if err != nil {
+		return nil, err
+	}
+
+	bundle := &Bundle{Salt: base64.StdEncoding.EncodeToString(salt), Records: make([]record, 0, len(records))}
+	for _, r := range records {
+		plain, err := open(v.key, r.Sealed)
+		if err != nil {
+			return nil, fmt.Errorf("解密凭据 %s 失败: %w", r.ID, err)
+		}
+		sealed, err := seal(exportKey, plain)
+		if err != nil {
+			return nil, err
+		}
+		bundle.Records = append(bundle.Records, record{Credential: r.Credential, Sealed: sealed})
+	}
+	return bundle, nil
+}
+
+// Import 导入凭据导出包，使用本实例主密钥重新加密。
+// overwrite 为 false 时跳过已存在的凭据 ID，返回导入和跳过的条数
+func (v *Vault) Import(bundle *Bundle, passphrase string, overwrite bool) (imported, skipped int, err error) {
+	salt, err := base64.StdEncoding.DecodeString(bundle.Salt)
+	if err != nil {
+		return 0, 0, fmt.Errorf("导出包格式无效: %w", err)
+	}
+	exportKey, err := deriveKey(passphrase, salt)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	// 先全部解密校验口令，避免导入一半失败
+	resealed := make([]record, 0, len(bundle.Records))
+	for _, r := range bundle.Records {
+		plain, err := open(exportKey, r.Sealed)
+		if err != nil {
+			return 0, 0, fmt.Errorf("解密凭据 %s 失败（导出口令错误？）", r.ID)
+		}
+		sealed, err := seal(v.key, plain)
+		if err != nil {
+			return 0, 0, err
+		}
+		resealed = append(resealed, record{Credential: r.Credential, Sealed: sealed})
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	for _, r := range resealed {
+		_, exists, err := v.backend.get(r.ID)
+		if err != nil {
+			return imported, skipped, err
+		}
+		if exists && !overwrite {
+			skipped++
+			continue
+		}
+		if err := v.backend.put(r); err != nil {
+			return imported, skipped, err
+		}
+		imported++
+	}
+	return imported, skipped, nil
+}
@@ -0,0 +1,253 @@
+package vault
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"k3s-deploy-backend/internal/pkg/store"
+)
+
+// Credential 节点登录凭据。Secret 中的敏感字段只以密文形式落盘
+type Credential struct {
+	ID        string    `json:"id"`
+	Name      string    `json:"name"`
+	Host      string    `json:"host"`
+	Port      int       `json:"port"`
+	Username  string    `json:"username"`
+	AuthType  string    `json:"authType"`
+	PublicKey string    `json:"publicKey,omitempty"`
+	Secret    Secret    `json:"-"`
+	CreatedAt time.Time `json:"createdAt"`
+	UpdatedAt time.Time `json:"updatedAt"`
+}
+
+// Secret 凭据中的敏感部分
+type Secret struct {
+	Password   string `json:"password,omitempty"`
+	PrivateKey string `json:"privateKey,omitempty"`
+	Passphrase string `json:"passphrase,omitempty"`
+	// Kubeconfig 集群管理员 kubeconfig，仅用于 kubeconfig 类型的记录
+	Kubeconfig string `json:"kubeconfig,omitempty"`
+	// Token 集群 join token，仅用于 kubeconfig 类型的记录
+	Token string `json:"token,omitempty"`
+	// Fields 字段路径 -> 取值，仅用于保存任务请求中敏感字段的记录
+	Fields map[string]string `json:"fields,omitempty"`
+}
+
+// record 落盘格式：元数据明文，Secret 加密
+type record struct {
+	Credential
+	Sealed string `json:"sealed"`
+}
+
+// Vault 加密的凭据库
+type Vault struct {
+	key     []byte
+	backend backend
+
+	mu sync.RWMutex
+}
+
+// Open 打开本地文件凭据库，主密钥不存在时自动生成
+func Open(path, keyFile string) (*Vault, error) {
+	b, err := newFileBackend(path)
+	if err != nil {
+		return nil, err
+	}
+	return newVault(b, keyFile)
+}
+
+// OpenStore 打开基于共享存储的凭据库，各副本需使用相同的主密钥
+func OpenStore(st store.Store, keyFile string) (*Vault, error) {
+	return newVault(&storeBackend{store: st}, keyFile)
+}
+
+// ImportFile 将本地凭据库文件中的记录导入状态存储（已存在的 ID 跳过），
+// 完成后文件重命名为 .migrated，返回导入条数。记录保持加密状态，需使用同一主密钥。
+func ImportFile(path string, st store.Store) (int, error) {
+	if _, err := os.Stat(path); os.IsNotExist(err) {
+		return 0, nil
+	}
+
+	source, err := newFileBackend(path)
+	if err != nil {
+		return 0, err
+	}
+	target := &storeBackend{store: st}
+
+	records, err := source.list()
+	if err != nil {
+		return 0, fmt.Errorf("读取凭据库文件失败: %w", err)
+	}
+	imported := 0
+	for _, r := range records {
+		if _, exists, err := target.get(r.ID); err != nil {
+			return imported, err
+		} else if exists {
+			continue
+		}
+		if err := target.put(r); err != nil {
+			return imported, err
+		}
+		imported++
+	}
+
+	// 全部记录导入后才重命名，中途失败时保留原文件，下次启动重新导入（已导入的记录跳过）
+	if err := os.Rename(path, path+".migrated"); err != nil {
+		return imported, fmt.Errorf("重命名凭据库文件失败: %w", err)
+	}
+	return imported, nil
+}
+
+func newVault(b backend, keyFile string) (*Vault, error) {
+	key, err := loadOrCreateKey(keyFile)
+	if err != nil {
+		return nil, err
+	}
+
+	v := &Vault{key: key, backend: b}
+
+	// 启动时校验主密钥能否解密已有凭据
+	records, err := b.list()
+	if err != nil {
+		return nil, err
+	}
+	for _, r := range records {
+		if _, err := v.unseal(r); err != nil {
+			return nil, err
+		}
+	}
+	return v, nil
+}
+
+func (v *Vault) unseal(r record) (*Credential, error) {
+	plain, err := open(v.key, r.Sealed)
+	if err != nil {
+		return nil, fmt.Errorf("解密凭据 %s 失败（主密钥不匹配？）: %w", r.ID, err)
+	}
+	cred := r.Credential
+	if err := json.Unmarshal(plain, &cred.Secret); err != nil {
+		return nil, fmt.Errorf("解析凭据 %s 失败: %w", r.ID, err)
+	}
+	return &cred, nil
+}
+
+func (v *Vault) sealRecord(cred *Credential) (record, error) {
+	plain, err := json.Marshal(cred.Secret)
+	if err != nil {
+		return record{}, err
+	}
+	sealed, err := seal(v.key, plain)
+	if err != nil {
+		return record{}, fmt.Errorf("加密凭据 %s 失败: %w", cred.ID, err)
+	}
+	return record{Credential: *cred, Sealed: sealed}, nil
+}
+
+// Get 按 ID 获取凭据（含敏感字段）
+func (v *Vault) Get(id string) (*Credential, error) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	r, exists, err := v.backend.get(id)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return nil, fmt.Errorf("凭据 %s 不存在", id)
+	}
+	return v.unseal(r)
+}
+
+// Put 新增或更新凭据并立即持久化，ID 为空时自动生成
+func (v *Vault) Put(cred *Credential) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	now := time.Now()
+	if cred.ID == "" {
+		id, err := newID()
+		if err != nil {
+			return err
+		}
+		cred.ID = id
+	}
+
+	existing, exists, err := v.backend.get(cred.ID)
+	if err != nil {
+		return err
+	}
+	if exists {
+		cred.CreatedAt = existing.CreatedAt
+	} else {
+		cred.CreatedAt = now
+	}
+	cred.UpdatedAt = now
+
+	r, err := v.sealRecord(cred)
+	if err != nil {
+		return err
+	}
+	return v.backend.put(r)
+}
+
+// Delete 删除凭据
+func (v *Vault) Delete(id string) error {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	return v.backend.delete(id)
+}
+
+// List 列出所有凭据（不含敏感字段）
+func (v *Vault) List() []Credential {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	records, err := v.backend.list()
+	if err != nil {
+		return []Credential{}
+	}
+
+	list := make([]Credential, 0, len(records))
+	for _, r := range records {
+		list = append(list, r.Credential)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].ID < list[j].ID })
+	return list
+}
+
+func newID() (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成凭据ID失败: %w", err)
+	}
+	return "cred-" + hex.EncodeToString(buf), nil
+}
+
+// Find 按主机、端口和用户名查找凭据
+func (v *Vault) Find(host string, port int, username string) (*Credential, bool) {
+	v.mu.RLock()
+	defer v.mu.RUnlock()
+
+	records, err := v.backend.list()
+	if err != nil {
+		return nil, false
+	}
+	for _, r := range records {
+		if r.Host == host && r.Port == port && r.Username == username {
+			cred, err := v.unseal(r)
+			if err != nil {
+				return nil, false
+			}
+			return cred, true
+		}
+	}
+	return nil, false
+}
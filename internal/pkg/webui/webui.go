@@ -0,0 +1,126 @@
+// Package webui 由后端直接提供前端静态文件：前端构建产物复制到 dist 目录后随后端一起编译，
+// 小规模部署只需一个二进制，不再需要单独的 Web 服务器
+package webui
+
+import (
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"k3s-deploy-backend/internal/model"
+)
+
+//go:embed all:dist
+var embedded embed.FS
+
+// Files 返回前端文件：dir 非空时使用磁盘目录（单独升级前端时不必重新编译），否则使用内嵌的 dist 目录
+func Files(dir string) (fs.FS, error) {
+	if dir != "" {
+		return os.DirFS(dir), nil
+	}
+	return fs.Sub(embedded, "dist")
+}
+
+// Embedded 编译时是否内嵌了前端
+func Embedded() bool {
+	_, err := fs.Stat(embedded, "dist/index.html")
+	return err == nil
+}
+
+// Handler 作为 NoRoute 处理器：存在的文件直接返回，其余前端路由返回 index.html 由前端路由处理，
+// /api 下不存在的接口和非 GET 请求返回 404 JSON
+type Handler struct {
+	files    fs.FS
+	basePath string
+	index    []byte
+}
+
+// NewHandler basePath 为路由的路径前缀，前端文件中必须包含 index.html
+func NewHandler(files fs.FS, basePath string) (*Handler, error) {
+	index, err := fs.ReadFile(files, "index.html")
+	if err != nil {
+		return nil, fmt.Errorf("前端文件中没有 index.html（构建前端后将 dist 目录内容复制到 internal/pkg/webui/dist 再编译，或配置 server.frontend.dir）: %w", err)
+	}
+	return &Handler{files: files, basePath: basePath, index: index}, nil
+}
+
+// Serve 返回前端文件或 index.html
+func (h *Handler) Serve(c *gin.Context) {
+	urlPath := c.Request.URL.Path
+	if h.basePath != "" {
+		if urlPath == h.basePath {
+			c.Redirect(http.StatusMovedPermanently, h.basePath+"/")
+			return
+		}
+		if !strings.HasPrefix(urlPath, h.basePath+"/") {
+			notFound(c)
+			return
+		}
+		urlPath = strings.TrimPrefix(urlPath, h.basePath)
+	}
+	if c.Request.Method != http.MethodGet && c.Request.Method != http.MethodHead ||
+		urlPath == "/api" || strings.HasPrefix(urlPath, "/api/") {
+		notFound(c)
+		return
+	}
+
+	name := strings.TrimPrefix(path.Clean(urlPath), "/")
+	if name == "" || name == "index.html" {
+		h.serveIndex(c)
+		return
+	}
+	if err := h.serveFile(c, name); err != nil {
+		// 带扩展名的路径是静态资源，不存在时返回 404，避免浏览器把 index.html 当作脚本或样式解析
+		if errors.Is(err, fs.ErrNotExist) && path.Ext(name) == "" {
+			h.serveIndex(c)
+			return
+		}
+		notFound(c)
+	}
+}
+
+// serveIndex index.html 不缓存，前端更新后刷新即可加载新的资源文件
+func (h *Handler) serveIndex(c *gin.Context) {
+	c.Header("Cache-Control", "no-cache")
+	c.Data(http.StatusOK, "text/html; charset=utf-8", h.index)
+}
+
+func (h *Handler) serveFile(c *gin.Context, name string) error {
+	f, err := h.files.Open(name)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+	info, err := f.Stat()
+	if err != nil {
+		return err
+	}
+	if info.IsDir() {
+		return fs.ErrNotExist
+	}
+	content, ok := f.(io.ReadSeeker)
+	if !ok {
+		return fmt.Errorf("文件 %s 不支持随机读取", name)
+	}
+
+	// 构建工具输出到 assets 目录的文件名带内容哈希，可以长期缓存
+	if strings.HasPrefix(name, "assets/") {
+		c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	}
+	http.ServeContent(c.Writer, c.Request, name, info.ModTime(), content)
+	return nil
+}
+
+func notFound(c *gin.Context) {
+	c.JSON(http.StatusNotFound, model.ErrorResponse{
+		Success: false,
+		Message: "请求的路径不存在",
+	})
+}
@@ -3,20 +3,187 @@ package router
 import (
 	"github.com/gin-gonic/gin"
 	"k3s-deploy-backend/internal/handler"
+	"k3s-deploy-backend/internal/middleware"
 )
 
-func RegisterRoutes(r *gin.Engine, sshHandler *handler.SSHHandler, k3sHandler *handler.K3sHandler) {
-	api := r.Group("/api")
+// Handlers 路由使用的全部处理器
+type Handlers struct {
+	SSH         *handler.SSHHandler
+	K3s         *handler.K3sHandler
+	Agent       *handler.AgentHandler
+	Credential  *handler.CredentialHandler
+	Task        *handler.TaskHandler
+	State       *handler.StateHandler
+	Cluster     *handler.ClusterHandler
+	GitOps      *handler.GitOpsHandler
+	Alert       *handler.AlertHandler
+	Event       *handler.EventHandler
+	KubeAudit   *handler.KubeAuditHandler
+	Maintenance *handler.MaintenanceHandler
+	Backup      *handler.BackupHandler
+	// SecretsEncryption Secret 加密状态与密钥轮换
+	SecretsEncryption *handler.SecretsEncryptionHandler
+	// SecurityScan kube-bench 与 trivy 安全扫描
+	SecurityScan *handler.SecurityScanHandler
+	// JoinBundle 集群加入信息包的导出与校验
+	JoinBundle *handler.JoinBundleHandler
+	// ScaleAdvisor 节点与副本数伸缩建议
+	ScaleAdvisor *handler.ScaleAdvisorHandler
+	// Benchmark 部署任务的阶段耗时记录与对比
+	Benchmark *handler.BenchmarkHandler
+	Audit     *handler.AuditHandler
+	Auth      *handler.AuthHandler
+	WebSSH    *handler.WebSSHHandler
+}
+
+// RegisterRoutes 注册 /api/v1（统一响应信封）以及兼容现有前端的 /api 旧路由，
+// r 为配置了路径前缀的路由组，authenticate 为登录和权限校验中间件
+func RegisterRoutes(r gin.IRouter, h Handlers, authenticate gin.HandlerFunc) {
+	registerAPI(r.Group("/api/v1", middleware.Envelope()), h, authenticate)
+	registerAPI(r.Group("/api"), h, authenticate)
+}
+
+func registerAPI(public *gin.RouterGroup, h Handlers, authenticate gin.HandlerFunc) {
+	// 无需登录的接口：登录本身、Agent 通道（使用注册令牌）、GitOps Webhook（使用签名）、
+	// WebSSH 终端（使用一次性票据，浏览器无法为 WebSocket 设置 Authorization 头）
+	authPublic := public.Group("/auth")
+	{
+		authPublic.POST("/login", h.Auth.Login)
+		authPublic.GET("/oidc/login", h.Auth.OIDCLogin)
+		authPublic.GET("/oidc/callback", h.Auth.OIDCCallback)
+	}
+	agentPublic := public.Group("/agent")
 	{
-		ssh := api.Group("/ssh")
-		{
-			ssh.POST("/test", sshHandler.TestConnection)
-			ssh.POST("/test-batch", sshHandler.BatchTestConnection)
-		}
+		agentPublic.GET("/connect", h.Agent.Connect)
+		agentPublic.GET("/tunnel", h.Agent.Tunnel)
+		agentPublic.GET("/install.sh", h.Agent.Bootstrap)
+		agentPublic.GET("/binary", h.Agent.Binary)
+	}
+	public.POST("/gitops/webhook", h.GitOps.Webhook)
+	public.GET("/webssh/connect", h.WebSSH.Connect)
+
+	api := public.Group("", authenticate)
+
+	api.GET("/auth/me", h.Auth.Me)
 
-		k3s := api.Group("/k3s")
-		{
-			k3s.POST("/deploy", k3sHandler.Deploy)
-		}
+	ssh := api.Group("/ssh")
+	{
+		ssh.POST("/test", h.SSH.TestConnection)
+		ssh.POST("/test-batch", h.SSH.BatchTestConnection)
 	}
+
+	k3s := api.Group("/k3s")
+	{
+		k3s.POST("/deploy", h.K3s.Deploy)
+		k3s.POST("/label-plan", h.K3s.PlanLabels)
+		k3s.GET("/role-templates", h.K3s.RoleTemplates)
+		k3s.POST("/hosts", h.K3s.SyncHosts)
+		k3s.POST("/hosts/remove", h.K3s.RemoveHosts)
+		k3s.POST("/tuning", h.K3s.Tune)
+		k3s.POST("/tuning/revert", h.K3s.RevertTuning)
+		k3s.POST("/uninstall", h.K3s.Uninstall)
+		k3s.POST("/service/restart", h.K3s.RestartService)
+		k3s.POST("/service/config", h.K3s.ApplyServiceConfig)
+		k3s.POST("/service/logs", h.K3s.ServiceLogs)
+		k3s.GET("/:clusterId/events", h.Event.List)
+		k3s.GET("/:clusterId/audit-log", h.KubeAudit.List)
+		k3s.GET("/:clusterId/workloads", h.Cluster.Workloads)
+		k3s.GET("/:clusterId/metrics", h.Cluster.Metrics)
+		k3s.GET("/:clusterId/scale-recommendations", h.ScaleAdvisor.Report)
+		k3s.PUT("/:clusterId/labels", h.Cluster.ReconcileLabels)
+		k3s.POST("/:clusterId/network-check", h.Cluster.CheckNetwork)
+		k3s.GET("/:clusterId/maintenance", h.Maintenance.Windows)
+		k3s.POST("/:clusterId/nodes/:node/maintenance", h.Maintenance.Start)
+		k3s.DELETE("/:clusterId/nodes/:node/maintenance", h.Maintenance.End)
+		k3s.GET("/:clusterId/backups", h.Backup.Backups)
+		k3s.POST("/:clusterId/backups", h.Backup.CreateBackup)
+		k3s.POST("/:clusterId/backups/:name/restore", h.Backup.Restore)
+		k3s.GET("/:clusterId/restores", h.Backup.Restores)
+		k3s.GET("/:clusterId/backup-schedules", h.Backup.Schedules)
+		k3s.PUT("/:clusterId/backup-schedules/:name", h.Backup.ApplySchedule)
+		k3s.DELETE("/:clusterId/backup-schedules/:name", h.Backup.DeleteSchedule)
+		k3s.GET("/:clusterId/secrets-encryption", h.SecretsEncryption.Status)
+		k3s.POST("/:clusterId/secrets-encryption/rotate", h.SecretsEncryption.Rotate)
+		k3s.POST("/:clusterId/security-scan", h.SecurityScan.Start)
+		k3s.GET("/:clusterId/security-scan", h.SecurityScan.Latest)
+	}
+
+	clusters := api.Group("/clusters")
+	{
+		clusters.GET("", h.Cluster.List)
+		clusters.POST("/adopt", h.Cluster.Adopt)
+		clusters.GET("/:id", h.Cluster.Get)
+		clusters.DELETE("/:id", h.Cluster.Delete)
+		clusters.POST("/:id/refresh", h.Cluster.Refresh)
+		clusters.POST("/:id/verify", h.Cluster.Verify)
+		clusters.PUT("/:id/desired", h.Cluster.SetDesired)
+		clusters.PUT("/:id/defaults", h.Cluster.SetDefaults)
+		clusters.POST("/:id/drift", h.Cluster.Drift)
+		clusters.GET("/:id/releases", h.Cluster.Releases)
+		clusters.DELETE("/:id/releases/:name", h.Cluster.DeleteRelease)
+		clusters.GET("/:id/object-store", h.Cluster.ObjectStore)
+		clusters.GET("/:id/cis-report", h.Cluster.CISReport)
+	}
+
+	api.GET("/kubeconfig/merged", h.Cluster.MergedKubeconfig)
+
+	joinBundles := api.Group("/join-bundles")
+	{
+		joinBundles.POST("", h.JoinBundle.Export)
+		joinBundles.POST("/import", h.JoinBundle.Import)
+		joinBundles.GET("/public-key", h.JoinBundle.PublicKey)
+	}
+
+	gitops := api.Group("/gitops")
+	{
+		gitops.POST("/sync", h.GitOps.Sync)
+		gitops.GET("/status", h.GitOps.Status)
+	}
+
+	alerts := api.Group("/alerts")
+	{
+		alerts.GET("", h.Alert.List)
+		alerts.POST("/evaluate", h.Alert.Evaluate)
+	}
+
+	api.GET("/audit", h.Audit.List)
+
+	api.POST("/webssh/tickets", h.WebSSH.CreateTicket)
+
+	tasks := api.Group("/tasks")
+	{
+		tasks.POST("", h.Task.Submit)
+		tasks.POST("/takeover", h.Task.Takeover)
+		tasks.GET("", h.Task.List)
+		tasks.GET("/:id", h.Task.Get)
+		tasks.GET("/:id/transcript", h.Task.Transcript)
+		tasks.GET("/:id/events", h.Task.Events)
+		tasks.POST("/:id/rerun", h.Task.Rerun)
+	}
+
+	benchmarks := api.Group("/benchmarks")
+	{
+		benchmarks.GET("", h.Benchmark.List)
+		benchmarks.GET("/compare", h.Benchmark.Compare)
+		benchmarks.GET("/:id", h.Benchmark.Get)
+		benchmarks.DELETE("/:id", h.Benchmark.Delete)
+	}
+
+	credentials := api.Group("/credentials")
+	{
+		credentials.GET("", h.Credential.List)
+		credentials.POST("/keys", h.Credential.DistributeKeys)
+		credentials.POST("/rotate", h.Credential.Rotate)
+		credentials.GET("/ssh-ca", h.Credential.SSHCA)
+		credentials.POST("/ssh-ca/bootstrap", h.Credential.BootstrapSSHCA)
+		credentials.DELETE("/:id", h.Credential.Delete)
+	}
+
+	state := api.Group("/state")
+	{
+		state.POST("/export", h.State.Export)
+		state.POST("/import", h.State.Import)
+	}
+
+	api.GET("/agent/list", h.Agent.List)
 }
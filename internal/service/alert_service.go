@@ -0,0 +1,261 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/notify"
+	"k3s-deploy-backend/internal/pkg/store"
+	"k3s-deploy-backend/pkg/utils"
+)
+
+// alertLease 多副本部署时同一周期只由一个副本评估告警
+const alertLease = "alerts"
+
+// AlertOptions 告警评估参数
+type AlertOptions struct {
+	Interval time.Duration
+	// CertExpiryWarning 证书剩余有效期低于该值时告警
+	CertExpiryWarning time.Duration
+}
+
+// AlertService 定期评估受管集群健康状态，维护告警记录并发送通知
+type AlertService struct {
+	opts           AlertOptions
+	store          store.Store
+	clusterService *ClusterService
+	k3sService     *K3sService
+	notifier       notify.Notifier
+	logger         *logger.Logger
+	owner          string
+}
+
+func NewAlertService(opts AlertOptions, st store.Store, clusterService *ClusterService, k3sService *K3sService, notifier notify.Notifier, logger *logger.Logger) *AlertService {
+	hostname, _ := os.Hostname()
+	owner, err := utils.GenerateID(hostname)
+	if err != nil {
+		owner = fmt.Sprintf("%s-%d", hostname, time.Now().UnixNano())
+	}
+
+	return &AlertService{
+		opts:           opts,
+		store:          st,
+		clusterService: clusterService,
+		k3sService:     k3sService,
+		notifier:       notifier,
+		logger:         logger,
+		owner:          owner,
+	}
+}
+
+// Start 按固定周期评估告警
+func (s *AlertService) Start() {
+	s.logger.Infof("集群告警已启用，周期 %s", s.opts.Interval)
+	go func() {
+		ticker := time.NewTicker(s.opts.Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			acquired, err := s.store.AcquireLease(alertLease, s.owner, s.opts.Interval-time.Second)
+			if err != nil {
+				s.logger.Errorf("获取告警租约失败: %v", err)
+				continue
+			}
+			if !acquired {
+				continue
+			}
+			if err := s.Evaluate(); err != nil {
+				s.logger.Errorf("告警评估失败: %v", err)
+			}
+		}
+	}()
+}
+
+// finding 一次评估中发现的异常
+type finding struct {
+	rule     string
+	severity string
+	target   string
+	message  string
+}
+
+// Evaluate 评估所有受管集群：新发现的异常产生告警，已恢复的告警标记为 resolved
+func (s *AlertService) Evaluate() error {
+	clusters, err := s.clusterService.List()
+	if err != nil {
+		return err
+	}
+
+	for _, cluster := range clusters {
+		findings := s.inspect(cluster)
+		if err := s.reconcile(cluster, findings); err != nil {
+			s.logger.Errorf("集群 %s 告警记录更新失败: %v", cluster.Name, err)
+		}
+	}
+	return nil
+}
+
+// inspect 检查单个集群的健康状态
+func (s *AlertService) inspect(cluster *model.Cluster) []finding {
+	master, err := s.clusterService.MasterNode(cluster)
+	if err != nil {
+		return []finding{{model.AlertClusterUnreachable, model.SeverityCritical, cluster.Name, err.Error()}}
+	}
+	health, err := s.k3sService.CheckHealth(master)
+	if err != nil {
+		return []finding{{model.AlertClusterUnreachable, model.SeverityCritical, cluster.Name, err.Error()}}
+	}
+
+	var findings []finding
+	for _, node := range health.Nodes {
+		if !node.Ready {
+			findings = append(findings, finding{model.AlertNodeNotReady, model.SeverityCritical, node.Name,
+				fmt.Sprintf("节点 %s 处于 NotReady 状态", node.Name)})
+		}
+		for _, cond := range node.Conditions {
+			if cond == "DiskPressure" {
+				findings = append(findings, finding{model.AlertDiskPressure, model.SeverityWarning, node.Name,
+					fmt.Sprintf("节点 %s 磁盘空间不足（DiskPressure）", node.Name)})
+			}
+		}
+	}
+
+	if !health.CertNotAfter.IsZero() {
+		remaining := time.Until(health.CertNotAfter)
+		if remaining < s.opts.CertExpiryWarning {
+			severity := model.SeverityWarning
+			if remaining < 7*24*time.Hour {
+				severity = model.SeverityCritical
+			}
+			findings = append(findings, finding{model.AlertCertExpiring, severity, "kube-apiserver",
+				fmt.Sprintf("API Server 证书将于 %s 到期（剩余 %d 天），可重启 k3s 服务自动续期",
+					health.CertNotAfter.Format("2006-01-02"), int(remaining.Hours()/24))})
+		}
+	}
+	return findings
+}
+
+// reconcile 将本次发现与已有告警比对并保存，状态变化时发送通知
+func (s *AlertService) reconcile(cluster *model.Cluster, findings []finding) error {
+	existing, err := s.List()
+	if err != nil {
+		return err
+	}
+	current := make(map[string]*model.Alert)
+	for _, alert := range existing {
+		if alert.ClusterID == cluster.ID {
+			current[alert.ID] = alert
+		}
+	}
+
+	now := time.Now()
+	seen := make(map[string]bool)
+	for _, f := range findings {
+		id := alertID(cluster.ID, f.rule, f.target)
+		seen[id] = true
+
+		alert, exists := current[id]
+		if exists && alert.Status == model.AlertFiring {
+			alert.LastSeenAt = now
+			alert.Message = f.message
+			alert.Severity = f.severity
+			if err := s.save(alert); err != nil {
+				return err
+			}
+			continue
+		}
+
+		alert = &model.Alert{
+			ID:          id,
+			ClusterID:   cluster.ID,
+			ClusterName: cluster.Name,
+			Rule:        f.rule,
+			Severity:    f.severity,
+			Target:      f.target,
+			Message:     f.message,
+			Status:      model.AlertFiring,
+			FiredAt:     now,
+			LastSeenAt:  now,
+		}
+		if err := s.save(alert); err != nil {
+			return err
+		}
+		s.logger.Warnf("集群 %s 告警: %s", cluster.Name, f.message)
+		if alert.Rule == model.AlertCertExpiring {
+			s.notify(notify.EventCertExpiring, alert)
+		} else {
+			s.notify(notify.EventAlertFiring, alert)
+		}
+	}
+
+	for id, alert := range current {
+		if seen[id] || alert.Status != model.AlertFiring {
+			continue
+		}
+		alert.Status = model.AlertResolved
+		alert.ResolvedAt = &now
+		if err := s.save(alert); err != nil {
+			return err
+		}
+		s.logger.Infof("集群 %s 告警已恢复: %s", cluster.Name, alert.Message)
+		s.notify(notify.EventAlertResolved, alert)
+	}
+	return nil
+}
+
+// List 返回全部告警，按触发时间倒序
+func (s *AlertService) List() ([]*model.Alert, error) {
+	records, err := s.store.List(store.CollectionAlerts)
+	if err != nil {
+		return nil, err
+	}
+
+	alerts := make([]*model.Alert, 0, len(records))
+	for id, data := range records {
+		var alert model.Alert
+		if err := json.Unmarshal(data, &alert); err != nil {
+			s.logger.Warnf("解析告警 %s 失败: %v", id, err)
+			continue
+		}
+		alerts = append(alerts, &alert)
+	}
+	sort.Slice(alerts, func(i, j int) bool { return alerts[i].FiredAt.After(alerts[j].FiredAt) })
+	return alerts, nil
+}
+
+func (s *AlertService) save(alert *model.Alert) error {
+	data, err := json.Marshal(alert)
+	if err != nil {
+		return err
+	}
+	return s.store.Put(store.CollectionAlerts, alert.ID, data)
+}
+
+func (s *AlertService) notify(eventType string, alert *model.Alert) {
+	title := fmt.Sprintf("[%s] %s: %s", alert.Severity, alert.ClusterName, alert.Rule)
+	if eventType == notify.EventAlertResolved {
+		title = fmt.Sprintf("[resolved] %s: %s", alert.ClusterName, alert.Rule)
+	}
+	event := notify.Event{
+		Type:    eventType,
+		Title:   title,
+		Message: alert.Message,
+		Time:    time.Now(),
+		Data:    alert,
+	}
+	if err := s.notifier.Notify(event); err != nil {
+		s.logger.Warnf("发送告警通知失败: %v", err)
+	}
+}
+
+// alertID 同一集群、规则和对象生成固定 ID，重复发现时更新同一条记录
+func alertID(clusterID, rule, target string) string {
+	sum := sha256.Sum256([]byte(clusterID + "/" + rule + "/" + target))
+	return "alert-" + hex.EncodeToString(sum[:8])
+}
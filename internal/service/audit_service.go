@@ -0,0 +1,74 @@
+package service
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/store"
+	"k3s-deploy-backend/pkg/utils"
+)
+
+// AuditService 保存运维操作审计记录，同时以 type=audit 写入日志
+type AuditService struct {
+	store  store.Store
+	logger *logger.Logger
+}
+
+func NewAuditService(st store.Store, logger *logger.Logger) *AuditService {
+	return &AuditService{store: st, logger: logger}
+}
+
+// Record 保存一条审计记录，补全 ID 和时间
+func (s *AuditService) Record(event model.AuditEvent) error {
+	id, err := utils.GenerateID("audit")
+	if err != nil {
+		return err
+	}
+	event.ID = id
+	if event.Time.IsZero() {
+		event.Time = time.Now()
+	}
+
+	s.logger.WithFields(logrus.Fields{
+		"type":      "audit",
+		"action":    event.Action,
+		"actor":     event.Actor,
+		"requestId": event.RequestID,
+		"clusterId": event.ClusterID,
+		"target":    event.Target,
+		"success":   event.Success,
+	}).Info(event.Message)
+
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Put(store.CollectionAuditEvents, event.ID, data); err != nil {
+		return fmt.Errorf("保存审计记录失败: %v", err)
+	}
+	return nil
+}
+
+// List 返回全部审计记录，按时间先后排列
+func (s *AuditService) List() ([]model.AuditEvent, error) {
+	records, err := s.store.List(store.CollectionAuditEvents)
+	if err != nil {
+		return nil, err
+	}
+	events := make([]model.AuditEvent, 0, len(records))
+	for id, data := range records {
+		var event model.AuditEvent
+		if err := json.Unmarshal(data, &event); err != nil {
+			s.logger.Warnf("解析审计记录 %s 失败: %v", id, err)
+			continue
+		}
+		events = append(events, event)
+	}
+	sort.Slice(events, func(i, j int) bool { return events[i].Time.Before(events[j].Time) })
+	return events, nil
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/auth"
+	"k3s-deploy-backend/internal/pkg/logger"
+)
+
+var (
+	// ErrNoRole 身份提供方认证通过，但用户组没有映射到任何角色
+	ErrNoRole = errors.New("用户没有被授予任何角色")
+	// ErrOIDCDisabled 未启用 OIDC 登录
+	ErrOIDCDisabled = errors.New("未启用 OIDC 登录")
+)
+
+// AuthOptions 认证参数
+type AuthOptions struct {
+	SessionTTL time.Duration
+	// GroupRoles 用户组 -> 角色
+	GroupRoles map[string]string
+	// DefaultRole 未匹配任何组时的角色，为空表示拒绝登录
+	DefaultRole string
+}
+
+// AuthService 用户登录与会话令牌。本地用户优先，其次 LDAP；OIDC 走授权码跳转流程。
+// 外部身份提供方的用户组按 GroupRoles 映射为 admin/operator/viewer 角色
+type AuthService struct {
+	opts   AuthOptions
+	signer *auth.Signer
+	local  *auth.LocalProvider
+	ldap   *auth.LDAPProvider
+	oidc   *auth.OIDCProvider
+	logger *logger.Logger
+}
+
+// NewAuthService ldap、oidc 为 nil 表示未启用对应的登录方式
+func NewAuthService(opts AuthOptions, signer *auth.Signer, local *auth.LocalProvider, ldap *auth.LDAPProvider, oidc *auth.OIDCProvider, logger *logger.Logger) *AuthService {
+	return &AuthService{
+		opts:   opts,
+		signer: signer,
+		local:  local,
+		ldap:   ldap,
+		oidc:   oidc,
+		logger: logger,
+	}
+}
+
+// Login 用户名密码登录
+func (s *AuthService) Login(username, password string) (*model.LoginResponse, error) {
+	identity, err := s.local.Authenticate(username, password)
+	if errors.Is(err, auth.ErrInvalidCredentials) && s.ldap != nil {
+		identity, err = s.ldap.Authenticate(username, password)
+	}
+	if err != nil {
+		s.logger.Warnf("用户 %s 登录失败: %v", username, err)
+		return nil, err
+	}
+	return s.issue(identity)
+}
+
+// OIDCAuthURL 返回 OIDC 登录跳转地址，login 为本次登录的 state、nonce 和 PKCE code_verifier
+func (s *AuthService) OIDCAuthURL(login *auth.OIDCLogin) (string, error) {
+	if s.oidc == nil {
+		return "", ErrOIDCDisabled
+	}
+	return s.oidc.AuthURL(login)
+}
+
+// OIDCCallback 处理 OIDC 回调，用授权码换取用户身份并签发会话，login 为发起登录时生成的值
+func (s *AuthService) OIDCCallback(code string, login *auth.OIDCLogin) (*model.LoginResponse, error) {
+	if s.oidc == nil {
+		return nil, ErrOIDCDisabled
+	}
+	identity, err := s.oidc.Exchange(code, login)
+	if err != nil {
+		s.logger.Warnf("OIDC 登录失败: %v", err)
+		return nil, err
+	}
+	return s.issue(identity)
+}
+
+// Verify 校验会话令牌
+func (s *AuthService) Verify(token string) (*auth.Claims, error) {
+	return s.signer.Verify(token)
+}
+
+// VerifyCertificate 使用已通过 TLS 校验的客户端证书认证，证书不签发会话令牌
+func (s *AuthService) VerifyCertificate(cert *x509.Certificate) (*auth.Claims, error) {
+	identity := auth.CertificateIdentity(cert)
+	roles, err := s.roles(identity)
+	if err != nil {
+		return nil, err
+	}
+	return &auth.Claims{
+		Subject:   identity.Subject,
+		Name:      identity.Name,
+		Provider:  identity.Provider,
+		Roles:     roles,
+		ExpiresAt: cert.NotAfter.Unix(),
+	}, nil
+}
+
+// roles 直接指定的角色优先，否则按用户组映射
+func (s *AuthService) roles(identity *auth.Identity) ([]string, error) {
+	roles := identity.Roles
+	if len(roles) == 0 {
+		roles = auth.MapGroups(identity.Groups, s.opts.GroupRoles, s.opts.DefaultRole)
+	}
+	if len(roles) == 0 {
+		s.logger.Warnf("用户 %s（%s）的用户组 %v 没有映射到任何角色", identity.Name, identity.Provider, identity.Groups)
+		return nil, ErrNoRole
+	}
+	return roles, nil
+}
+
+func (s *AuthService) issue(identity *auth.Identity) (*model.LoginResponse, error) {
+	roles, err := s.roles(identity)
+	if err != nil {
+		return nil, err
+	}
+
+	expiresAt := time.Now().Add(s.opts.SessionTTL)
+	token, err := s.signer.Sign(auth.Claims{
+		Subject:   identity.Subject,
+		Name:      identity.Name,
+		Provider:  identity.Provider,
+		Roles:     roles,
+		ExpiresAt: expiresAt.Unix(),
+	})
+	if err != nil {
+		return nil, fmt.Errorf("签发会话令牌失败: %w", err)
+	}
+
+	s.logger.Infof("用户 %s 通过 %s 登录，角色 %v", identity.Name, identity.Provider, roles)
+	return &model.LoginResponse{
+		Token:     token,
+		ExpiresAt: expiresAt,
+		User: model.UserInfo{
+			Subject:  identity.Subject,
+			Name:     identity.Name,
+			Provider: identity.Provider,
+			Roles:    roles,
+		},
+	}, nil
+}
@@ -0,0 +1,338 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/ssh"
+	"k3s-deploy-backend/pkg/utils"
+)
+
+func (s *DeployService) installVeleroStep(req *model.DeployRequest) error {
+	if req.Velero == nil {
+		s.logger.Info("未设置 velero，跳过应用备份组件安装")
+		return nil
+	}
+
+	var masterNode model.NodeConfig
+	for _, node := range req.Nodes {
+		if node.Name == "k3s-master" {
+			masterNode = node
+			break
+		}
+	}
+	if masterNode.Name == "" {
+		return fmt.Errorf("未找到Master节点")
+	}
+
+	// 未指定备份存储时使用集群内 MinIO
+	opts := *req.Velero
+	if opts.Storage == nil {
+		objectStore, err := s.clusterService.ObjectStoreByMaster(masterNode.IP)
+		if err != nil {
+			return err
+		}
+		if objectStore == nil {
+			return fmt.Errorf("未设置 velero.storage，且集群未安装对象存储（可设置 minio 并执行 install-minio）")
+		}
+		opts.Storage = &model.BackupStorageOptions{
+			Endpoint:  objectStore.Endpoint,
+			Bucket:    k3s.VeleroBucket,
+			AccessKey: objectStore.AccessKey,
+			SecretKey: objectStore.SecretKey,
+		}
+	}
+
+	artifacts, err := s.k3sService.InstallVelero(masterNode, req.WorkspaceID, &opts, waitPolicy(req.Wait))
+	req.Artifacts = append(req.Artifacts, artifacts...)
+	return err
+}
+
+// InstallVelero 在 Master 节点安装 Velero，返回上传过的文件路径
+func (s *K3sService) InstallVelero(masterNode model.NodeConfig, workspaceID string, opts *model.VeleroOptions, policy k3s.WaitPolicy) ([]string, error) {
+	s.logger.DeploymentStep("install-velero", "cluster")
+
+	client := newNodeClient(masterNode)
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	ws, err := k3s.NewWorkspace(client, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer s.cleanupWorkspace(ws)
+
+	err = s.manager.InstallVelero(client, ws, opts, policy)
+	return ws.Artifacts(), err
+}
+
+// operationWorkspace 为备份、恢复等集群级操作创建临时工作目录
+func (s *K3sService) operationWorkspace(client *ssh.Client, prefix string) (*k3s.Workspace, error) {
+	id, err := utils.GenerateID(prefix)
+	if err != nil {
+		return nil, err
+	}
+	return k3s.NewWorkspace(client, id)
+}
+
+// CreateBackup 立即备份命名空间，返回备份名
+func (s *K3sService) CreateBackup(masterNode model.NodeConfig, req *model.BackupRequest) (string, error) {
+	client := newNodeClient(masterNode)
+	if err := client.Connect(); err != nil {
+		return "", fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	ws, err := s.operationWorkspace(client, "backup")
+	if err != nil {
+		return "", err
+	}
+	defer s.cleanupWorkspace(ws)
+
+	return s.manager.CreateBackup(client, ws, req)
+}
+
+// CreateRestore 从备份恢复，返回恢复名
+func (s *K3sService) CreateRestore(masterNode model.NodeConfig, backup string, req *model.RestoreRequest) (string, error) {
+	client := newNodeClient(masterNode)
+	if err := client.Connect(); err != nil {
+		return "", fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	ws, err := s.operationWorkspace(client, "restore")
+	if err != nil {
+		return "", err
+	}
+	defer s.cleanupWorkspace(ws)
+
+	return s.manager.CreateRestore(client, ws, backup, req)
+}
+
+// ApplySchedule 创建或更新定时备份
+func (s *K3sService) ApplySchedule(masterNode model.NodeConfig, name string, req *model.BackupScheduleRequest) error {
+	client := newNodeClient(masterNode)
+	if err := client.Connect(); err != nil {
+		return fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	ws, err := s.operationWorkspace(client, "schedule")
+	if err != nil {
+		return err
+	}
+	defer s.cleanupWorkspace(ws)
+
+	return s.manager.ApplySchedule(client, ws, name, req)
+}
+
+// DeleteSchedule 删除定时备份
+func (s *K3sService) DeleteSchedule(masterNode model.NodeConfig, name string) error {
+	client := newNodeClient(masterNode)
+	if err := client.Connect(); err != nil {
+		return fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.DeleteSchedule(client, name)
+}
+
+// ListBackups 列出集群中的 Velero 备份
+func (s *K3sService) ListBackups(masterNode model.NodeConfig) ([]model.Backup, error) {
+	client := newNodeClient(masterNode)
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.ListBackups(client)
+}
+
+// ListRestores 列出集群中的 Velero 恢复
+func (s *K3sService) ListRestores(masterNode model.NodeConfig) ([]model.Restore, error) {
+	client := newNodeClient(masterNode)
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.ListRestores(client)
+}
+
+// ListSchedules 列出集群中的 Velero 定时备份
+func (s *K3sService) ListSchedules(masterNode model.NodeConfig) ([]model.BackupSchedule, error) {
+	client := newNodeClient(masterNode)
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.ListSchedules(client)
+}
+
+// BackupService 基于 Velero 的命名空间级应用备份与恢复，创建备份、恢复和修改定时备份记入审计日志
+type BackupService struct {
+	clusterService *ClusterService
+	k3sService     *K3sService
+	auditService   *AuditService
+	logger         *logger.Logger
+}
+
+func NewBackupService(clusterService *ClusterService, k3sService *K3sService, auditService *AuditService, logger *logger.Logger) *BackupService {
+	return &BackupService{
+		clusterService: clusterService,
+		k3sService:     k3sService,
+		auditService:   auditService,
+		logger:         logger,
+	}
+}
+
+// master 返回集群的 Master 节点
+func (s *BackupService) master(clusterID string) (*model.Cluster, model.NodeConfig, error) {
+	cluster, err := s.clusterService.Get(clusterID)
+	if err != nil {
+		return nil, model.NodeConfig{}, err
+	}
+	master, err := s.clusterService.MasterNode(cluster)
+	return cluster, master, err
+}
+
+// ErrInvalidBackupRequest 备份、恢复或定时备份的参数无效
+var ErrInvalidBackupRequest = errors.New("备份参数无效")
+
+// CreateBackup 立即备份命名空间。Velero 异步执行备份，进度通过备份列表查看
+func (s *BackupService) CreateBackup(clusterID string, req *model.BackupRequest, actor, requestID string) (backup *model.Backup, err error) {
+	event := s.event(model.AuditBackupCreate, clusterID, actor, requestID)
+	event.Message = fmt.Sprintf("命名空间: %v", req.Namespaces)
+	defer func() { s.record(event, err) }()
+
+	if err := k3s.ValidateBackupNamespaces(req.Namespaces); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackupRequest, err)
+	}
+	_, master, err := s.master(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	name, err := s.k3sService.CreateBackup(master, req)
+	if err != nil {
+		return nil, err
+	}
+	event.Target = name
+	return &model.Backup{Name: name, Namespaces: req.Namespaces, Phase: "New"}, nil
+}
+
+// CreateRestore 从备份恢复。恢复不覆盖已存在的资源，覆盖前需先删除命名空间或使用 namespaceMapping 恢复到新命名空间
+func (s *BackupService) CreateRestore(clusterID, backup string, req *model.RestoreRequest, actor, requestID string) (restore *model.Restore, err error) {
+	event := s.event(model.AuditRestoreCreate, clusterID, actor, requestID)
+	event.Target = backup
+	defer func() { s.record(event, err) }()
+
+	if err := k3s.ValidateBackupNamespaces([]string{backup}); err != nil {
+		return nil, fmt.Errorf("%w: 无效的备份名 %q", ErrInvalidBackupRequest, backup)
+	}
+	if err := k3s.ValidateBackupNamespaces(req.Namespaces); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidBackupRequest, err)
+	}
+	for from, to := range req.NamespaceMapping {
+		if err := k3s.ValidateBackupNamespaces([]string{from, to}); err != nil {
+			return nil, fmt.Errorf("%w: %v", ErrInvalidBackupRequest, err)
+		}
+	}
+	_, master, err := s.master(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	name, err := s.k3sService.CreateRestore(master, backup, req)
+	if err != nil {
+		return nil, err
+	}
+	event.Message = name
+	return &model.Restore{Name: name, Backup: backup, Namespaces: req.Namespaces, Mapping: req.NamespaceMapping, Phase: "New"}, nil
+}
+
+// ApplySchedule 创建或更新定时备份
+func (s *BackupService) ApplySchedule(clusterID, name string, req *model.BackupScheduleRequest, actor, requestID string) (err error) {
+	event := s.event(model.AuditScheduleApply, clusterID, actor, requestID)
+	event.Target = name
+	event.Message = fmt.Sprintf("%s 命名空间: %v", req.Schedule, req.Namespaces)
+	defer func() { s.record(event, err) }()
+
+	if err := k3s.ValidateBackupNamespaces([]string{name}); err != nil {
+		return fmt.Errorf("%w: 无效的定时备份名 %q", ErrInvalidBackupRequest, name)
+	}
+	if err := k3s.ValidateSchedule(req.Schedule); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackupRequest, err)
+	}
+	if err := k3s.ValidateBackupNamespaces(req.Namespaces); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidBackupRequest, err)
+	}
+	_, master, err := s.master(clusterID)
+	if err != nil {
+		return err
+	}
+	return s.k3sService.ApplySchedule(master, name, req)
+}
+
+// DeleteSchedule 删除定时备份，已创建的备份不受影响
+func (s *BackupService) DeleteSchedule(clusterID, name, actor, requestID string) (err error) {
+	event := s.event(model.AuditScheduleDelete, clusterID, actor, requestID)
+	event.Target = name
+	defer func() { s.record(event, err) }()
+
+	if err := k3s.ValidateBackupNamespaces([]string{name}); err != nil {
+		return fmt.Errorf("%w: 无效的定时备份名 %q", ErrInvalidBackupRequest, name)
+	}
+	_, master, err := s.master(clusterID)
+	if err != nil {
+		return err
+	}
+	return s.k3sService.DeleteSchedule(master, name)
+}
+
+// Backups 列出集群的备份
+func (s *BackupService) Backups(clusterID string) ([]model.Backup, error) {
+	_, master, err := s.master(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	return s.k3sService.ListBackups(master)
+}
+
+// Restores 列出集群的恢复
+func (s *BackupService) Restores(clusterID string) ([]model.Restore, error) {
+	_, master, err := s.master(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	return s.k3sService.ListRestores(master)
+}
+
+// Schedules 列出集群的定时备份
+func (s *BackupService) Schedules(clusterID string) ([]model.BackupSchedule, error) {
+	_, master, err := s.master(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	return s.k3sService.ListSchedules(master)
+}
+
+// event 备份操作的审计记录，结果由 record 填写
+func (s *BackupService) event(action, clusterID, actor, requestID string) model.AuditEvent {
+	return model.AuditEvent{Action: action, Actor: actor, RequestID: requestID, ClusterID: clusterID}
+}
+
+// record 按操作结果写入审计记录，参数无效和执行失败的操作也记录。写入失败只告警（操作已经生效）
+func (s *BackupService) record(event model.AuditEvent, opErr error) {
+	event.Success = opErr == nil
+	if opErr != nil {
+		event.Details = opErr.Error()
+	}
+	if err := s.auditService.Record(event); err != nil {
+		s.logger.Warnf("记录备份审计 %s 失败: %v", event.Action, err)
+	}
+}
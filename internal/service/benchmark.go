@@ -0,0 +1,216 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/benchmark"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/store"
+)
+
+// BenchmarkService 查询部署任务的基准记录，并生成多次运行的耗时对比报告
+type BenchmarkService struct {
+	store  store.Store
+	logger *logger.Logger
+}
+
+func NewBenchmarkService(st store.Store, logger *logger.Logger) *BenchmarkService {
+	return &BenchmarkService{
+		store:  st,
+		logger: logger,
+	}
+}
+
+// List 按创建时间倒序返回全部基准记录
+func (s *BenchmarkService) List() ([]*model.BenchmarkRun, error) {
+	records, err := s.store.List(store.CollectionBenchmarks)
+	if err != nil {
+		return nil, fmt.Errorf("读取基准记录失败: %w", err)
+	}
+	runs := make([]*model.BenchmarkRun, 0, len(records))
+	for id, data := range records {
+		var run model.BenchmarkRun
+		if err := json.Unmarshal(data, &run); err != nil {
+			s.logger.Warnf("解析基准记录 %s 失败: %v", id, err)
+			continue
+		}
+		runs = append(runs, &run)
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].CreatedAt.After(runs[j].CreatedAt) })
+	return runs, nil
+}
+
+// Get 返回任务的基准记录
+func (s *BenchmarkService) Get(taskID string) (*model.BenchmarkRun, error) {
+	data, err := s.store.Get(store.CollectionBenchmarks, taskID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, fmt.Errorf("任务 %s 没有基准记录", taskID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	var run model.BenchmarkRun
+	if err := json.Unmarshal(data, &run); err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+// Delete 删除任务的基准记录
+func (s *BenchmarkService) Delete(taskID string) error {
+	if err := s.store.Delete(store.CollectionBenchmarks, taskID); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("任务 %s 没有基准记录", taskID)
+		}
+		return err
+	}
+	return nil
+}
+
+// Compare 对比多次运行的各阶段、各步骤和总耗时，以第一个任务为基准计算变化百分比
+func (s *BenchmarkService) Compare(taskIDs []string) (*model.BenchmarkComparison, error) {
+	if len(taskIDs) < 2 {
+		return nil, fmt.Errorf("至少需要两次运行才能对比")
+	}
+	report := &model.BenchmarkComparison{}
+	for _, id := range taskIDs {
+		run, err := s.Get(id)
+		if err != nil {
+			return nil, err
+		}
+		report.Runs = append(report.Runs, *run)
+	}
+
+	// 行按第一次出现的顺序排列，阶段再按部署流程排序
+	type phaseKey struct{ node, phase string }
+	var phaseKeys []phaseKey
+	phases := make(map[phaseKey][]*int64)
+	var stepKeys []string
+	steps := make(map[string][]*int64)
+	for i, run := range report.Runs {
+		for _, t := range run.Phases {
+			key := phaseKey{t.Node, t.Phase}
+			if _, ok := phases[key]; !ok {
+				phaseKeys = append(phaseKeys, key)
+				phases[key] = make([]*int64, len(report.Runs))
+			}
+			phases[key][i] = durationOf(t.DurationMs)
+		}
+		for _, t := range run.Steps {
+			if _, ok := steps[t.Step]; !ok {
+				stepKeys = append(stepKeys, t.Step)
+				steps[t.Step] = make([]*int64, len(report.Runs))
+			}
+			steps[t.Step][i] = durationOf(t.DurationMs)
+		}
+	}
+	sort.SliceStable(phaseKeys, func(i, j int) bool {
+		return model.PhaseOrder(phaseKeys[i].phase) < model.PhaseOrder(phaseKeys[j].phase)
+	})
+
+	for _, key := range phaseKeys {
+		row := benchmarkRow(phases[key])
+		row.Phase, row.Node = key.phase, key.node
+		report.Phases = append(report.Phases, row)
+	}
+	for _, step := range stepKeys {
+		row := benchmarkRow(steps[step])
+		row.Step = step
+		report.Steps = append(report.Steps, row)
+	}
+	totals := make([]*int64, len(report.Runs))
+	for i, run := range report.Runs {
+		totals[i] = durationOf(run.TotalMs)
+	}
+	report.Total = benchmarkRow(totals)
+	return report, nil
+}
+
+func durationOf(ms int64) *int64 {
+	return &ms
+}
+
+// benchmarkRow 计算相对第一次运行的变化百分比和耗时最短的运行
+func benchmarkRow(durations []*int64) model.BenchmarkRow {
+	row := model.BenchmarkRow{
+		DurationsMs:  durations,
+		DeltaPercent: make([]*float64, len(durations)),
+		Fastest:      -1,
+	}
+	base := durations[0]
+	for i, d := range durations {
+		if d == nil {
+			continue
+		}
+		if row.Fastest < 0 || *d < *durations[row.Fastest] {
+			row.Fastest = i
+		}
+		if base != nil && *base > 0 {
+			delta := float64(*d-*base) * 100 / float64(*base)
+			row.DeltaPercent[i] = &delta
+		}
+	}
+	return row
+}
+
+// saveBenchmark 保存任务的基准记录，steps 为各步骤的耗时
+func (s *TaskService) saveBenchmark(task *model.Task, req *model.DeployRequest, steps []model.StepTiming) {
+	phases, settings := benchmark.End(task.ID)
+	if settings == nil {
+		settings = make(map[string]string)
+	}
+	for key, value := range benchmarkSettings(req) {
+		settings[key] = value
+	}
+
+	run := model.BenchmarkRun{
+		TaskID:     task.ID,
+		Label:      req.Benchmark.Label,
+		ClusterKey: task.ClusterKey,
+		Status:     task.Status,
+		Settings:   settings,
+		Phases:     phases,
+		Steps:      steps,
+		CreatedAt:  task.CreatedAt,
+	}
+	if run.Phases == nil {
+		run.Phases = []model.PhaseTiming{}
+	}
+	if task.StartedAt != nil && task.FinishedAt != nil {
+		run.TotalMs = task.FinishedAt.Sub(*task.StartedAt).Milliseconds()
+	}
+
+	data, err := json.Marshal(&run)
+	if err == nil {
+		err = s.store.Put(store.CollectionBenchmarks, task.ID, data)
+	}
+	if err != nil {
+		s.logger.Warnf("保存任务 %s 的基准记录失败: %v", task.ID, err)
+		return
+	}
+	s.publish(task, model.ProgressEvent{Type: model.ProgressDetail, Message: fmt.Sprintf("基准记录已保存，总耗时 %s", time.Duration(run.TotalMs)*time.Millisecond)})
+}
+
+// benchmarkSettings 请求中影响耗时的设置
+func benchmarkSettings(req *model.DeployRequest) map[string]string {
+	policy := waitPolicy(req.Wait).WithDefaults()
+	settings := map[string]string{
+		"step":           req.Step,
+		"nodes":          strconv.Itoa(len(req.Nodes)),
+		"serviceTimeout": policy.ServiceTimeout.String(),
+		"pollInterval":   policy.PollInterval.String(),
+	}
+	if req.Airgap != nil {
+		settings["airgap"] = req.Airgap.Bundle
+	}
+	if req.InstallScript != nil && req.InstallScript.URL != "" {
+		settings["installScript"] = req.InstallScript.URL
+	}
+	return settings
+}
@@ -0,0 +1,192 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/pki"
+)
+
+const (
+	defaultCertManagerVersion = "v1.16.1"
+	// defaultACMEServer Let's Encrypt 生产环境
+	defaultACMEServer = "https://acme-v02.api.letsencrypt.org/directory"
+)
+
+var certManagerVersionPattern = regexp.MustCompile(`^v\d+\.\d+\.\d+$`)
+
+// tsigAlgorithms cert-manager rfc2136 支持的 TSIG 算法
+var tsigAlgorithms = []string{"HMACMD5", "HMACSHA1", "HMACSHA256", "HMACSHA512"}
+
+// validateCertManager 校验 cert-manager 参数并补全默认值，不读取 CA 文件
+func validateCertManager(opts *model.CertManagerOptions, exposure *model.ExposureOptions) (k3s.CertManagerConfig, error) {
+	var cfg k3s.CertManagerConfig
+	cfg.ManifestURL = opts.ManifestURL
+	if cfg.ManifestURL == "" {
+		version := opts.Version
+		if version == "" {
+			version = defaultCertManagerVersion
+		}
+		if !certManagerVersionPattern.MatchString(version) {
+			return cfg, fmt.Errorf("无效的 cert-manager 版本: %s", version)
+		}
+		cfg.ManifestURL = fmt.Sprintf("https://github.com/cert-manager/cert-manager/releases/download/%s/cert-manager.yaml", version)
+	} else if !strings.HasPrefix(cfg.ManifestURL, "https://") && !strings.HasPrefix(cfg.ManifestURL, "http://") ||
+		strings.ContainsAny(cfg.ManifestURL, " '\"$`;&|") {
+		return cfg, fmt.Errorf("无效的 cert-manager 清单地址: %s", cfg.ManifestURL)
+	}
+
+	if opts.Issuer != model.IssuerACME {
+		if opts.ACME != nil {
+			return cfg, fmt.Errorf("acme 仅适用于 issuer 为 acme")
+		}
+		_, err := issuerDomains(opts, exposure)
+		return cfg, err
+	}
+	if len(opts.DNSNames) > 0 {
+		return cfg, fmt.Errorf("dnsNames 仅适用于 issuer 为 ca")
+	}
+	if opts.ACME == nil {
+		return cfg, fmt.Errorf("issuer 为 acme 时必须设置 acme")
+	}
+	acme := *opts.ACME
+	if acme.Server == "" {
+		acme.Server = defaultACMEServer
+	} else if !strings.HasPrefix(acme.Server, "https://") {
+		return cfg, fmt.Errorf("无效的 ACME 目录地址: %s", acme.Server)
+	}
+	if acme.Solver == "" {
+		acme.Solver = model.SolverHTTP01
+	}
+	if acme.Solver == model.SolverDNS01 {
+		if acme.DNS == nil {
+			return cfg, fmt.Errorf("dns01 质询需要设置 acme.dns")
+		}
+		dns := *acme.DNS
+		switch dns.Provider {
+		case "cloudflare":
+			if dns.APIToken == "" {
+				return cfg, fmt.Errorf("cloudflare 需要设置 apiToken")
+			}
+		case "rfc2136":
+			if _, _, err := net.SplitHostPort(dns.Nameserver); err != nil {
+				return cfg, fmt.Errorf("rfc2136 的 nameserver 应为 host:port 形式: %s", dns.Nameserver)
+			}
+			if dns.TSIGKeyName == "" || dns.TSIGSecret == "" {
+				return cfg, fmt.Errorf("rfc2136 需要设置 tsigKeyName 和 tsigSecret")
+			}
+			if dns.TSIGAlgorithm == "" {
+				dns.TSIGAlgorithm = "HMACSHA256"
+			}
+			valid := false
+			for _, algorithm := range tsigAlgorithms {
+				valid = valid || dns.TSIGAlgorithm == algorithm
+			}
+			if !valid {
+				return cfg, fmt.Errorf("不支持的 TSIG 算法 %s（支持 %s）", dns.TSIGAlgorithm, strings.Join(tsigAlgorithms, "、"))
+			}
+		}
+		acme.DNS = &dns
+	} else if acme.DNS != nil {
+		return cfg, fmt.Errorf("acme.dns 仅适用于 dns01 质询")
+	}
+	cfg.ACME = &acme
+	return cfg, nil
+}
+
+// clusterCAValidity 每个集群的中间 CA 的有效期，不超过内部 CA 本身
+const clusterCAValidity = 5 * 365 * 24 * time.Hour
+
+// issuerDomains 集群中间 CA 允许签发的域名：certManager.dnsNames，未设置时为 exposure.hosts
+func issuerDomains(opts *model.CertManagerOptions, exposure *model.ExposureOptions) ([]string, error) {
+	domains := opts.DNSNames
+	if len(domains) == 0 && exposure != nil {
+		domains = exposure.Hosts
+	}
+	if len(domains) == 0 {
+		return nil, fmt.Errorf("issuer 为 ca 时需要在 certManager.dnsNames 或 exposure.hosts 中指定中间 CA 允许签发的域名")
+	}
+	for _, domain := range domains {
+		if !dnsNamePattern.MatchString(domain) || strings.HasSuffix(domain, ".") {
+			return nil, fmt.Errorf("无效的域名: %s", domain)
+		}
+	}
+	return domains, nil
+}
+
+// certManagerConfig 校验参数，CA 签发时由 Ingress CA 为集群签发独立的中间 CA。中间 CA 只能签发
+// 该集群域名内的服务端证书，Ingress CA 与签发 API 客户端证书的 CA 相互独立，泄露的中间 CA 无法用于登录后端
+func (s *DeployService) certManagerConfig(opts *model.CertManagerOptions, exposure *model.ExposureOptions, master model.NodeConfig) (k3s.CertManagerConfig, error) {
+	cfg, err := validateCertManager(opts, exposure)
+	if err != nil || cfg.ACME != nil {
+		return cfg, err
+	}
+	domains, err := issuerDomains(opts, exposure)
+	if err != nil {
+		return cfg, err
+	}
+	ca, err := pki.LoadCA(s.ingressCA.CertFile, s.ingressCA.KeyFile)
+	if err != nil {
+		return cfg, fmt.Errorf("加载 Ingress CA 失败（可使用 cmd/pki init-ingress 生成）: %v", err)
+	}
+	intermediate, err := ca.IssueIntermediate(fmt.Sprintf("k3s-deploy ingress CA (%s)", master.IP), time.Now().Add(clusterCAValidity), domains)
+	if err != nil {
+		return cfg, fmt.Errorf("签发集群中间 CA 失败: %v", err)
+	}
+	if cfg.CAKey, err = pki.EncodePrivateKey(intermediate.PrivateKey); err != nil {
+		return cfg, err
+	}
+	cfg.CACert = append(pki.EncodeCertificate(intermediate.Cert), pki.EncodeCertificate(ca.Cert)...)
+	return cfg, nil
+}
+
+func (s *DeployService) installCertManagerStep(req *model.DeployRequest) error {
+	if req.CertManager == nil {
+		s.logger.Info("未设置 certManager，跳过 cert-manager 安装")
+		return nil
+	}
+
+	var masterNode model.NodeConfig
+	for _, node := range req.Nodes {
+		if node.Name == "k3s-master" {
+			masterNode = node
+			break
+		}
+	}
+	if masterNode.Name == "" {
+		return fmt.Errorf("未找到Master节点")
+	}
+
+	cfg, err := s.certManagerConfig(req.CertManager, req.Exposure, masterNode)
+	if err != nil {
+		return err
+	}
+	artifacts, err := s.k3sService.InstallCertManager(masterNode, req.WorkspaceID, cfg, waitPolicy(req.Wait))
+	req.Artifacts = append(req.Artifacts, artifacts...)
+	return err
+}
+
+// InstallCertManager 在 Master 节点安装 cert-manager 并创建 ClusterIssuer，返回上传过的文件路径
+func (s *K3sService) InstallCertManager(masterNode model.NodeConfig, workspaceID string, cfg k3s.CertManagerConfig, policy k3s.WaitPolicy) ([]string, error) {
+	s.logger.DeploymentStep("install-cert-manager", "cluster")
+
+	client := newNodeClient(masterNode)
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	ws, err := k3s.NewWorkspace(client, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer s.cleanupWorkspace(ws)
+
+	err = s.manager.InstallCertManager(client, ws, cfg, policy)
+	return ws.Artifacts(), err
+}
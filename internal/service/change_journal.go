@@ -0,0 +1,169 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+	"sync"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/ssh"
+	"k3s-deploy-backend/internal/pkg/store"
+)
+
+// machineIDPattern systemd/dbus machine-id 的格式
+var machineIDPattern = regexp.MustCompile(`^[0-9a-f]{32}$`)
+
+// ChangeJournal 记录预检和节点准备对系统配置的修改，卸载或回滚时逆序撤销。
+// 以节点的 machine-id 为键持久化，IP 被其他主机复用或节点更换 IP 时不会撤销到错误的主机上
+type ChangeJournal struct {
+	store  store.Store
+	logger *logger.Logger
+	mu     sync.Mutex
+}
+
+func NewChangeJournal(st store.Store, logger *logger.Logger) *ChangeJournal {
+	return &ChangeJournal{store: st, logger: logger}
+}
+
+// record 在修改生效后追加一条变更，撤销命令相同的变更只保留首次记录（重复预检不会覆盖最初的备份）。
+// 写入失败只告警，不中断部署
+func (j *ChangeJournal) record(client *ssh.Client, nodeName, kind, description, undo string) {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	journal, err := j.Load(client)
+	if err == nil {
+		for _, change := range journal.Changes {
+			if change.Undo == undo {
+				return
+			}
+		}
+		journal.Node = nodeName
+		journal.Changes = append(journal.Changes, model.NodeChange{Kind: kind, Description: description, Undo: undo, At: time.Now()})
+		err = j.save(journal)
+	}
+	if err != nil {
+		j.logger.Warnf("节点 %s 记录系统变更「%s」失败，卸载时需手动恢复: %v", nodeName, description, err)
+	}
+}
+
+// forget 删除节点某一类型的变更，用于已单独撤销的修改（如 tuning/revert）
+func (j *ChangeJournal) forget(client *ssh.Client, kind string) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	journal, err := j.Load(client)
+	if err != nil {
+		return err
+	}
+	changes := journal.Changes[:0]
+	for _, change := range journal.Changes {
+		if change.Kind != kind {
+			changes = append(changes, change)
+		}
+	}
+	journal.Changes = changes
+	return j.save(journal)
+}
+
+// replace 以撤销后剩余的变更替换节点的变更日志
+func (j *ChangeJournal) replace(client *ssh.Client, journal *model.NodeJournal, remaining []model.NodeChange) error {
+	j.mu.Lock()
+	defer j.mu.Unlock()
+
+	current, err := j.Load(client)
+	if err != nil {
+		return err
+	}
+	// 撤销期间新增的变更保留在剩余变更之后
+	if len(current.Changes) > len(journal.Changes) {
+		remaining = append(remaining, current.Changes[len(journal.Changes):]...)
+	}
+	current.Changes = remaining
+	return j.save(current)
+}
+
+// Load 返回 client 所连节点的变更日志，没有记录时返回空日志。
+// 旧版本以 IP 为键、未记录 machine-id 的日志视为该节点的日志，下次保存时迁移到 machine-id 下
+func (j *ChangeJournal) Load(client *ssh.Client) (*model.NodeJournal, error) {
+	id, err := nodeID(client)
+	if err != nil {
+		return nil, err
+	}
+	journal, err := j.get(id)
+	if err != nil || journal != nil {
+		return journal, err
+	}
+	ip := client.Host()
+	legacy, err := j.get(ip)
+	if err != nil {
+		return nil, err
+	}
+	if legacy != nil && legacy.ID == "" {
+		legacy.ID = id
+		legacy.IP = ip
+		return legacy, nil
+	}
+	return &model.NodeJournal{ID: id, IP: ip, Changes: []model.NodeChange{}}, nil
+}
+
+// get 读取 key 下的变更日志，不存在时返回 nil
+func (j *ChangeJournal) get(key string) (*model.NodeJournal, error) {
+	data, err := j.store.Get(store.CollectionNodeChanges, key)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	journal := &model.NodeJournal{}
+	if err := json.Unmarshal(data, journal); err != nil {
+		return nil, fmt.Errorf("解析节点 %s 变更日志失败: %v", key, err)
+	}
+	return journal, nil
+}
+
+func (j *ChangeJournal) save(journal *model.NodeJournal) error {
+	// 迁移旧版本以 IP 为键的日志
+	if legacy, err := j.get(journal.IP); err == nil && legacy != nil && legacy.ID == "" {
+		if err := j.store.Delete(store.CollectionNodeChanges, journal.IP); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+	}
+	if len(journal.Changes) == 0 {
+		if err := j.store.Delete(store.CollectionNodeChanges, journal.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return err
+		}
+		return nil
+	}
+	journal.UpdatedAt = time.Now()
+	data, err := json.Marshal(journal)
+	if err != nil {
+		return err
+	}
+	return j.store.Put(store.CollectionNodeChanges, journal.ID, data)
+}
+
+// nodeID 读取节点的 machine-id 作为变更日志的键，结果在连接上缓存
+func nodeID(client *ssh.Client) (string, error) {
+	id, err := client.Memo("machine-id", func() (any, error) {
+		result, err := client.ExecuteIdempotentCommand("cat /etc/machine-id 2>/dev/null || cat /var/lib/dbus/machine-id")
+		if err != nil {
+			return "", fmt.Errorf("读取 machine-id 失败: %v", err)
+		}
+		id := strings.TrimSpace(result.Stdout)
+		if !machineIDPattern.MatchString(id) {
+			return "", fmt.Errorf("machine-id %q 无效", id)
+		}
+		return "machine-id:" + id, nil
+	})
+	if err != nil {
+		return "", err
+	}
+	return id.(string), nil
+}
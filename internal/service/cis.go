@@ -0,0 +1,67 @@
+package service
+
+import (
+	"fmt"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+func (s *DeployService) hardenNodesStep(req *model.DeployRequest) error {
+	if req.Security == nil || !req.Security.CIS {
+		s.logger.Info("未设置 security.cis，跳过 CIS 加固")
+		return nil
+	}
+	results, err := s.k3sService.HardenNodes(req.Nodes)
+	nodes, err := s.nodeResults(req, "CIS 加固失败", req.Nodes, results, err)
+	if err != nil {
+		return err
+	}
+	recordSucceeded(req, nodes)
+	return nil
+}
+
+// HardenNodes 在安装 k3s 前并行为各节点写入 CIS 要求的内核参数和 k3s 配置，记入变更日志以便卸载时删除，
+// 返回各节点的结果
+func (s *K3sService) HardenNodes(nodes []model.NodeConfig) ([]model.NodeResult, error) {
+	return runOnNodes(nodes, func(i int, client *ssh.Client) error {
+		node := nodes[i]
+		if err := s.installer.WriteCISConfig(client, node.Name == "k3s-master"); err != nil {
+			return fmt.Errorf("节点 %s CIS 加固失败: %w", node.Name, err)
+		}
+		s.journal.record(client, node.Name, model.ChangeCIS, "写入 CIS 内核参数与 k3s 配置", k3s.CISUndoCommand)
+		s.logger.Infof("节点 %s 已完成 CIS 加固配置", node.Name)
+		return nil
+	}), nil
+}
+
+// ScanCIS 在 Master 节点上执行 CIS 合规检查
+func (s *K3sService) ScanCIS(masterNode model.NodeConfig) (*model.CISReport, error) {
+	client := newNodeClient(masterNode)
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.ScanCIS(client)
+}
+
+// CISReport 检查集群 Master 节点的 CIS 控制项，生成逐项合规报告
+func (s *ClusterService) CISReport(id string) (*model.CISReport, error) {
+	cluster, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	master, err := s.MasterNode(cluster)
+	if err != nil {
+		return nil, err
+	}
+	report, err := s.k3sService.ScanCIS(master)
+	if err != nil {
+		return nil, err
+	}
+	report.ClusterID = cluster.ID
+	report.Node = master.Name
+	return report, nil
+}
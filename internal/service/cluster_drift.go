@@ -0,0 +1,251 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"slices"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+)
+
+// manifestNamePattern 清单名称会作为远端文件名，只允许安全字符
+var manifestNamePattern = regexp.MustCompile(`^[a-zA-Z0-9][a-zA-Z0-9_.-]*$`)
+
+// SetDesired 设置集群期望状态
+func (s *ClusterService) SetDesired(id string, desired *model.DesiredState) (*model.Cluster, error) {
+	for name := range desired.Manifests {
+		if !manifestNamePattern.MatchString(name) {
+			return nil, fmt.Errorf("清单名称 %q 无效", name)
+		}
+	}
+	if desired.LogRotation != nil {
+		if err := validateLogRotation(desired.LogRotation); err != nil {
+			return nil, err
+		}
+	}
+	if err := k3s.ValidateNodeLabels(desired.Labels, desired.Taints); err != nil {
+		return nil, err
+	}
+
+	cluster, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if err := checkKnownNodes(cluster, desired); err != nil {
+		return nil, err
+	}
+	cluster.Desired = desired
+	cluster.UpdatedAt = time.Now()
+	if err := s.save(cluster); err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// checkKnownNodes 标签和污点只能指向集群中已有的节点，尚未发现节点列表时不检查
+func checkKnownNodes(cluster *model.Cluster, desired *model.DesiredState) error {
+	if len(cluster.Nodes) == 0 {
+		return nil
+	}
+	known := make(map[string]bool, len(cluster.Nodes))
+	for _, node := range cluster.Nodes {
+		known[node.Name] = true
+	}
+	for _, nodes := range []map[string][]string{desired.Labels, desired.Taints} {
+		for name := range nodes {
+			if !known[name] {
+				return fmt.Errorf("节点 %s 不在集群 %s 中", name, cluster.Name)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckDrift 检测集群配置漂移并保存结果，reconcile 为 true 时将漂移项恢复为期望状态
+func (s *ClusterService) CheckDrift(id string, reconcile bool) (*model.DriftReport, error) {
+	cluster, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if cluster.Desired == nil {
+		return nil, fmt.Errorf("集群 %s 未设置期望状态", cluster.Name)
+	}
+	master, err := s.MasterNode(cluster)
+	if err != nil {
+		return nil, err
+	}
+	agents := slices.Clone(cluster.Agents)
+	if err := s.credentialService.ResolveNodes(agents); err != nil {
+		return nil, err
+	}
+
+	items, err := s.k3sService.CheckDrift(master, agents, cluster.Desired, reconcile)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &model.DriftReport{CheckedAt: time.Now(), InSync: true, Items: items}
+	for _, item := range items {
+		if !item.Reconciled {
+			report.InSync = false
+		}
+	}
+
+	// 检测期间记录可能被修改，重新读取后只更新漂移结果
+	if latest, err := s.Get(id); err == nil {
+		cluster = latest
+	}
+	cluster.Drift = report
+	if err := s.save(cluster); err != nil {
+		return nil, err
+	}
+
+	if len(items) > 0 {
+		s.logger.Warnf("集群 %s 检测到 %d 项配置漂移", cluster.Name, len(items))
+	}
+	return report, nil
+}
+
+// StartDriftScheduler 按固定周期对所有设置了期望状态的集群执行漂移检测
+func (s *ClusterService) StartDriftScheduler(interval time.Duration, reconcile bool) {
+	s.logger.Infof("配置漂移检测已启用，周期 %s，自动修复: %v", interval, reconcile)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			clusters, err := s.List()
+			if err != nil {
+				s.logger.Errorf("读取集群列表失败: %v", err)
+				continue
+			}
+			for _, cluster := range clusters {
+				if cluster.Desired == nil {
+					continue
+				}
+				if _, err := s.CheckDrift(cluster.ID, reconcile); err != nil {
+					s.logger.Errorf("集群 %s 漂移检测失败: %v", cluster.Name, err)
+				}
+			}
+		}
+	}()
+}
+
+// RegisterDeployed 登记通过部署流程安装的集群，Master 认证信息存入凭据库
+func (s *ClusterService) RegisterDeployed(master model.NodeConfig) (*model.Cluster, error) {
+	cluster, err := s.FindByMaster(master.IP)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if cluster == nil {
+		id, err := s.newID()
+		if err != nil {
+			return nil, err
+		}
+		cluster = &model.Cluster{ID: id, Name: master.IP, Source: model.ClusterSourceDeployed, CreatedAt: now}
+	}
+
+	if discovery, err := s.k3sService.DiscoverCluster(master); err == nil {
+		cluster.Version = discovery.Version
+		cluster.Nodes = discovery.Nodes
+	} else {
+		s.logger.Warnf("发现集群 %s 信息失败: %v", master.IP, err)
+	}
+	s.storeAccess(cluster, master)
+
+	if err := s.credentialService.Persist(&master); err != nil {
+		return nil, err
+	}
+	cluster.Master = master
+	cluster.UpdatedAt = now
+	if err := s.save(cluster); err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// RecordLabels 将部署流程应用的节点标签合并进集群期望状态
+func (s *ClusterService) RecordLabels(masterIP string, labels map[string][]string) error {
+	cluster, err := s.FindByMaster(masterIP)
+	if err != nil || cluster == nil {
+		return err
+	}
+
+	if cluster.Desired == nil {
+		cluster.Desired = &model.DesiredState{}
+	}
+	if cluster.Desired.Labels == nil {
+		cluster.Desired.Labels = make(map[string][]string)
+	}
+	for node, nodeLabels := range labels {
+		cluster.Desired.Labels[node] = nodeLabels
+	}
+	cluster.UpdatedAt = time.Now()
+	return s.save(cluster)
+}
+
+// RecordDegraded 按步骤各节点的结果更新集群记录中的降级节点：失败的节点加入，成功的节点移除
+func (s *ClusterService) RecordDegraded(masterIP, step string, results []model.NodeResult) error {
+	cluster, err := s.FindByMaster(masterIP)
+	if err != nil || cluster == nil {
+		return err
+	}
+
+	cluster.Degraded = degradedNodes(cluster.Degraded, step, results, time.Now())
+	cluster.UpdatedAt = time.Now()
+	return s.save(cluster)
+}
+
+// degradedNodes 合并步骤结果，已记录的失败节点保留首次失败时间
+func degradedNodes(degraded []model.DegradedNode, step string, results []model.NodeResult, now time.Time) []model.DegradedNode {
+	for _, r := range results {
+		i := slices.IndexFunc(degraded, func(d model.DegradedNode) bool { return d.IP == r.IP })
+		switch {
+		case r.Success && i >= 0:
+			degraded = slices.Delete(degraded, i, i+1)
+		case !r.Success && i >= 0:
+			degraded[i].Name, degraded[i].Step, degraded[i].Message = r.Name, step, r.Message
+		case !r.Success:
+			degraded = append(degraded, model.DegradedNode{Name: r.Name, IP: r.IP, Step: step, Message: r.Message, Since: now})
+		}
+	}
+	return degraded
+}
+
+// RecordAgents 登记配置成功的 Agent 节点，认证信息存入凭据库，同一 IP 的记录被替换
+func (s *ClusterService) RecordAgents(masterIP string, agents []model.NodeConfig) error {
+	cluster, err := s.FindByMaster(masterIP)
+	if err != nil || cluster == nil {
+		return err
+	}
+
+	for _, agent := range agents {
+		if err := s.credentialService.Persist(&agent); err != nil {
+			return err
+		}
+		if i := slices.IndexFunc(cluster.Agents, func(n model.NodeConfig) bool { return n.IP == agent.IP }); i >= 0 {
+			cluster.Agents[i] = agent
+		} else {
+			cluster.Agents = append(cluster.Agents, agent)
+		}
+	}
+	cluster.UpdatedAt = time.Now()
+	return s.save(cluster)
+}
+
+// RecordLogRotation 将 prepare-nodes 配置的日志轮转写入集群期望状态
+func (s *ClusterService) RecordLogRotation(masterIP string, opts *model.LogRotationOptions) error {
+	cluster, err := s.FindByMaster(masterIP)
+	if err != nil || cluster == nil {
+		return err
+	}
+
+	if cluster.Desired == nil {
+		cluster.Desired = &model.DesiredState{}
+	}
+	cluster.Desired.LogRotation = opts
+	cluster.UpdatedAt = time.Now()
+	return s.save(cluster)
+}
@@ -0,0 +1,48 @@
+package service
+
+import (
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+)
+
+// Workloads 实时读取集群中的 Deployment、StatefulSet 和 DaemonSet
+func (s *ClusterService) Workloads(id string) ([]model.Workload, error) {
+	cluster, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	master, err := s.MasterNode(cluster)
+	if err != nil {
+		return nil, err
+	}
+	return s.k3sService.ListWorkloads(master)
+}
+
+// CheckNetwork 对集群执行 DNS、Service、跨节点 Pod 网络和 NodePort 检查，checkPolicy 时同时验证网络策略生效
+func (s *ClusterService) CheckNetwork(id, externalName string, checkPolicy bool) ([]model.NetworkCheck, error) {
+	if err := k3s.ValidateExternalName(externalName); err != nil {
+		return nil, err
+	}
+	cluster, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	master, err := s.MasterNode(cluster)
+	if err != nil {
+		return nil, err
+	}
+	return s.k3sService.CheckNetwork(master, "", externalName, checkPolicy, k3s.DefaultWaitPolicy())
+}
+
+// Metrics 实时读取集群节点和 Pod 的 CPU、内存用量
+func (s *ClusterService) Metrics(id string) (*model.ClusterMetrics, error) {
+	cluster, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	master, err := s.MasterNode(cluster)
+	if err != nil {
+		return nil, err
+	}
+	return s.k3sService.Metrics(master)
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/vault"
+)
+
+const (
+	// kubeconfigAuthType 凭据库中保存集群 kubeconfig 的记录类型，不是节点登录凭据
+	kubeconfigAuthType = "kubeconfig"
+	// accessCheckTTL 确认有效后的这段时间内，清理周期和合并 kubeconfig 下载直接使用保存的内容，不再 SSH 到 Master 校验
+	accessCheckTTL = 10 * time.Minute
+)
+
+// SaveKubeconfig 将集群 kubeconfig 和 join token 加密存入凭据库，同一 server 地址复用已有记录，返回凭据 ID
+func (s *CredentialService) SaveKubeconfig(name, host, content, token string) (string, error) {
+	return s.upsert(name, host, 6443, kubeconfigAuthType, kubeconfigAuthType, vault.Secret{Kubeconfig: content, Token: token})
+}
+
+// ClusterAccess 读取凭据库中保存的 kubeconfig 和 join token
+func (s *CredentialService) ClusterAccess(id string) (string, string, error) {
+	cred, err := s.vault.Get(id)
+	if err != nil {
+		return "", "", err
+	}
+	if cred.AuthType != kubeconfigAuthType {
+		return "", "", fmt.Errorf("凭据 %s 不是 kubeconfig 记录", id)
+	}
+	return cred.Secret.Kubeconfig, cred.Secret.Token, nil
+}
+
+// storeAccess 从 Master 重新读取 kubeconfig 和 join token 存入凭据库并记录到集群，失败时只告警，不影响纳管和登记
+func (s *ClusterService) storeAccess(cluster *model.Cluster, master model.NodeConfig) {
+	kubeconfig, token, _, err := s.k3sService.EnsureAccess(master, "", "")
+	if err != nil {
+		s.logger.Warnf("集群 %s: %v", cluster.Name, err)
+		return
+	}
+	id, err := s.credentialService.SaveKubeconfig("kubeconfig:"+cluster.Name, master.IP, kubeconfig, token)
+	if err != nil {
+		s.logger.Warnf("集群 %s 保存 kubeconfig 失败: %v", cluster.Name, err)
+		return
+	}
+	cluster.KubeconfigID = id
+}
+
+// ensureAccess 返回集群有效的 kubeconfig 和 join token。保存的内容因手动轮换证书、重新生成 token 等原因失效时，
+// 通过 SSH 重新读取并更新记录，避免使用方拿到失效凭据后出现难以理解的证书或认证错误；
+// Master 暂时无法连接时沿用保存的内容
+func (s *ClusterService) ensureAccess(cluster *model.Cluster) (string, string, error) {
+	var kubeconfig, token string
+	if cluster.KubeconfigID != "" {
+		var err error
+		if kubeconfig, token, err = s.credentialService.ClusterAccess(cluster.KubeconfigID); err != nil {
+			return "", "", err
+		}
+	}
+
+	master, err := s.MasterNode(cluster)
+	if err != nil {
+		return "", "", err
+	}
+	current, currentToken, refreshed, err := s.k3sService.EnsureAccess(master, kubeconfig, token)
+	if err != nil {
+		if kubeconfig == "" {
+			return "", "", err
+		}
+		s.logger.Warnf("集群 %s 无法校验保存的 kubeconfig，沿用已保存内容: %v", cluster.Name, err)
+		return kubeconfig, token, nil
+	}
+	if !refreshed {
+		s.markAccessChecked(cluster.ID)
+		return kubeconfig, token, nil
+	}
+
+	id, err := s.credentialService.SaveKubeconfig("kubeconfig:"+cluster.Name, master.IP, current, currentToken)
+	if err != nil {
+		return "", "", fmt.Errorf("保存 kubeconfig 失败: %v", err)
+	}
+	cluster.KubeconfigID = id
+	cluster.UpdatedAt = time.Now()
+	if err := s.save(cluster); err != nil {
+		return "", "", err
+	}
+	s.markAccessChecked(cluster.ID)
+	s.logger.Infof("集群 %s 的 kubeconfig 和 join token 已重新读取", cluster.Name)
+	return current, currentToken, nil
+}
+
+// cachedAccess 与 ensureAccess 相同，但 accessCheckTTL 内已确认有效的集群直接返回保存的内容，
+// 用于对所有集群逐个执行的清理周期和合并 kubeconfig 下载
+func (s *ClusterService) cachedAccess(cluster *model.Cluster) (string, string, error) {
+	s.accessMu.Lock()
+	checked, ok := s.accessChecked[cluster.ID]
+	s.accessMu.Unlock()
+	if ok && time.Since(checked) < accessCheckTTL && cluster.KubeconfigID != "" {
+		kubeconfig, token, err := s.credentialService.ClusterAccess(cluster.KubeconfigID)
+		if err == nil {
+			return kubeconfig, token, nil
+		}
+	}
+	return s.ensureAccess(cluster)
+}
+
+// markAccessChecked 记录集群保存的 kubeconfig 和 join token 刚确认有效
+func (s *ClusterService) markAccessChecked(id string) {
+	s.accessMu.Lock()
+	defer s.accessMu.Unlock()
+	s.accessChecked[id] = time.Now()
+}
+
+// CheckAccess 检查所有受管集群保存的 kubeconfig 和 join token，失效时重新读取，返回重新读取的集群数
+func (s *ClusterService) CheckAccess() (int, error) {
+	clusters, err := s.List()
+	if err != nil {
+		return 0, err
+	}
+
+	refreshed := 0
+	for _, cluster := range clusters {
+		before := cluster.UpdatedAt
+		if _, _, err := s.cachedAccess(cluster); err != nil {
+			s.logger.Warnf("集群 %s: %v", cluster.Name, err)
+			continue
+		}
+		if cluster.UpdatedAt != before {
+			refreshed++
+		}
+	}
+	return refreshed, nil
+}
+
+// MergedKubeconfig 合并受管集群的 kubeconfig，上下文以集群名称为前缀。
+// include 非空时只包含其中的集群，exclude 中的集群被排除，两者均可使用集群名称或 ID；无法获取 kubeconfig 的集群跳过
+func (s *ClusterService) MergedKubeconfig(include, exclude []string) ([]byte, error) {
+	clusters, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+
+	matches := func(list []string, cluster *model.Cluster) bool {
+		return slices.Contains(list, cluster.Name) || slices.Contains(list, cluster.ID)
+	}
+	var configs []k3s.NamedKubeconfig
+	names := make(map[string]bool)
+	for _, cluster := range clusters {
+		if len(include) > 0 && !matches(include, cluster) || matches(exclude, cluster) {
+			continue
+		}
+		content, _, err := s.cachedAccess(cluster)
+		if err != nil {
+			s.logger.Warnf("集群 %s 没有可用的 kubeconfig，跳过: %v", cluster.Name, err)
+			continue
+		}
+		// 集群名称可能重复，重复时改用 ID 作为前缀
+		name := cluster.Name
+		if names[name] {
+			name = cluster.ID
+		}
+		names[name] = true
+		configs = append(configs, k3s.NamedKubeconfig{Name: name, Content: content})
+	}
+	if len(configs) == 0 {
+		return nil, fmt.Errorf("没有符合条件且保存了 kubeconfig 的集群")
+	}
+	return k3s.MergeKubeconfigs(configs)
+}
@@ -0,0 +1,69 @@
+package service
+
+import (
+	"fmt"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+)
+
+// ReconcileLabels 将请求中列出节点的标签和污点对账为期望值，并写入集群期望状态。
+// 与只追加的 apply-labels 不同，会移除由工具维护但不再期望的标签和污点；dryRun 为 true 时只返回变更计划
+func (s *ClusterService) ReconcileLabels(id string, req *model.NodeLabelsRequest, dryRun bool) ([]model.LabelChange, error) {
+	if len(req.Labels) == 0 && len(req.Taints) == 0 {
+		return nil, fmt.Errorf("labels 和 taints 不能同时为空")
+	}
+	if err := k3s.ValidateNodeLabels(req.Labels, req.Taints); err != nil {
+		return nil, err
+	}
+
+	cluster, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	master, err := s.MasterNode(cluster)
+	if err != nil {
+		return nil, err
+	}
+	previous := cluster.Desired
+	if previous == nil {
+		previous = &model.DesiredState{}
+	}
+
+	desired := &model.DesiredState{Labels: req.Labels, Taints: req.Taints}
+	changes, err := s.k3sService.ReconcileNodeLabels(master, desired, previous, dryRun)
+	if err != nil || dryRun {
+		return changes, err
+	}
+
+	// 对账期间记录可能被修改，重新读取后只更新标签和污点
+	if latest, err := s.Get(id); err == nil {
+		cluster = latest
+	}
+	if cluster.Desired == nil {
+		cluster.Desired = &model.DesiredState{}
+	}
+	cluster.Desired.Labels = mergeNodeLists(cluster.Desired.Labels, req.Labels)
+	cluster.Desired.Taints = mergeNodeLists(cluster.Desired.Taints, req.Taints)
+	cluster.UpdatedAt = time.Now()
+	if err := s.save(cluster); err != nil {
+		return nil, err
+	}
+	return changes, nil
+}
+
+// mergeNodeLists 用 updates 中的节点覆盖 current，列表为空的节点从结果中删除
+func mergeNodeLists(current, updates map[string][]string) map[string][]string {
+	if current == nil {
+		current = make(map[string][]string)
+	}
+	for node, values := range updates {
+		if len(values) == 0 {
+			delete(current, node)
+			continue
+		}
+		current[node] = values
+	}
+	return current
+}
@@ -0,0 +1,80 @@
+package service
+
+import (
+	"fmt"
+	"sort"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+)
+
+// RecordRelease 记录部署到集群的实例，同名实例递增版本。集群未登记时忽略
+func (s *ClusterService) RecordRelease(masterIP string, release model.Release) error {
+	cluster, err := s.FindByMaster(masterIP)
+	if err != nil || cluster == nil {
+		return err
+	}
+
+	release.Revision = 1
+	kept := cluster.Releases[:0]
+	for _, existing := range cluster.Releases {
+		if existing.Name == release.Name {
+			release.Revision = existing.Revision + 1
+			continue
+		}
+		kept = append(kept, existing)
+	}
+	cluster.Releases = append(kept, release)
+	sort.Slice(cluster.Releases, func(i, j int) bool { return cluster.Releases[i].Name < cluster.Releases[j].Name })
+	cluster.UpdatedAt = time.Now()
+	return s.save(cluster)
+}
+
+// Releases 返回集群上已记录的实例
+func (s *ClusterService) Releases(id string) ([]model.Release, error) {
+	cluster, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if cluster.Releases == nil {
+		return []model.Release{}, nil
+	}
+	return cluster.Releases, nil
+}
+
+// DeleteRelease 删除集群上的实例（命名空间及其中全部资源）并移除记录
+func (s *ClusterService) DeleteRelease(id, name string) error {
+	cluster, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+
+	index := -1
+	for i, release := range cluster.Releases {
+		if release.Name == name {
+			index = i
+			break
+		}
+	}
+	if index < 0 {
+		return fmt.Errorf("集群 %s 上没有实例 %s", cluster.Name, name)
+	}
+
+	master, err := s.MasterNode(cluster)
+	if err != nil {
+		return err
+	}
+	release := cluster.Releases[index]
+	if err := s.k3sService.DeleteInstance(master, release.Name, release.Namespace, k3s.WaitPolicy{}); err != nil {
+		return err
+	}
+
+	cluster.Releases = append(cluster.Releases[:index], cluster.Releases[index+1:]...)
+	cluster.UpdatedAt = time.Now()
+	if err := s.save(cluster); err != nil {
+		return err
+	}
+	s.logger.Infof("集群 %s 的实例 %s 已删除", cluster.Name, name)
+	return nil
+}
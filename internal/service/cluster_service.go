@@ -0,0 +1,322 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/store"
+	"k3s-deploy-backend/pkg/utils"
+)
+
+// ClusterService 受管集群记录：纳管已有集群并为后续运维操作提供 Master 连接
+type ClusterService struct {
+	store             store.Store
+	k3sService        *K3sService
+	credentialService *CredentialService
+	logger            *logger.Logger
+
+	// accessMu 保护 accessChecked：集群 ID -> 最近一次通过 SSH 确认 kubeconfig 和 join token 有效的时间
+	accessMu      sync.Mutex
+	accessChecked map[string]time.Time
+}
+
+func NewClusterService(st store.Store, k3sService *K3sService, credentialService *CredentialService, logger *logger.Logger) *ClusterService {
+	return &ClusterService{
+		store:             st,
+		k3sService:        k3sService,
+		credentialService: credentialService,
+		logger:            logger,
+		accessChecked:     make(map[string]time.Time),
+	}
+}
+
+// Adopt 纳管手动安装的 k3s 集群：发现版本、token 和节点后登记集群，
+// Master 认证信息存入凭据库。同一 Master 重复纳管时刷新已有记录
+func (s *ClusterService) Adopt(req *model.ClusterAdoptRequest) (*model.Cluster, error) {
+	master := req.Master
+	if master.Name == "" {
+		master.Name = "k3s-master"
+	}
+	if master.Port == 0 {
+		master.Port = 22
+	}
+
+	resolved := []model.NodeConfig{master}
+	if err := s.credentialService.ResolveNodes(resolved); err != nil {
+		return nil, err
+	}
+	discovery, err := s.k3sService.DiscoverCluster(resolved[0])
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.credentialService.Persist(&master); err != nil {
+		return nil, err
+	}
+
+	cluster, err := s.FindByMaster(master.IP)
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	if cluster == nil {
+		id, err := s.newID()
+		if err != nil {
+			return nil, err
+		}
+		cluster = &model.Cluster{ID: id, Source: model.ClusterSourceAdopted, CreatedAt: now}
+	}
+
+	cluster.Name = req.Name
+	if cluster.Name == "" {
+		cluster.Name = master.IP
+	}
+	cluster.Version = discovery.Version
+	cluster.Master = master
+	cluster.Nodes = discovery.Nodes
+	cluster.UpdatedAt = now
+	s.storeAccess(cluster, resolved[0])
+	// 读取 kubeconfig 失败时仍保存纳管时读到的 join token，扩容和导出加入信息包可以使用
+	if cluster.KubeconfigID == "" && discovery.Token != "" {
+		if id, err := s.credentialService.SaveKubeconfig("kubeconfig:"+cluster.Name, master.IP, "", discovery.Token); err != nil {
+			s.logger.Warnf("集群 %s 保存 join token 失败: %v", cluster.Name, err)
+		} else {
+			cluster.KubeconfigID = id
+		}
+	}
+
+	if err := s.save(cluster); err != nil {
+		return nil, err
+	}
+	s.logger.Infof("已纳管集群 %s (%s)，版本 %s，%d 个节点", cluster.Name, cluster.ID, cluster.Version, len(cluster.Nodes))
+	return cluster, nil
+}
+
+// ClusterNotFoundError 集群记录不存在
+type ClusterNotFoundError struct {
+	ID string
+}
+
+func (e *ClusterNotFoundError) Error() string {
+	return fmt.Sprintf("集群 %s 不存在", e.ID)
+}
+
+// Get 按 ID 获取集群记录
+func (s *ClusterService) Get(id string) (*model.Cluster, error) {
+	data, err := s.store.Get(store.CollectionClusters, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, &ClusterNotFoundError{ID: id}
+	}
+	if err != nil {
+		return nil, err
+	}
+	var cluster model.Cluster
+	if err := json.Unmarshal(data, &cluster); err != nil {
+		return nil, fmt.Errorf("解析集群 %s 失败: %v", id, err)
+	}
+	return &cluster, nil
+}
+
+// List 按名称返回所有集群记录
+func (s *ClusterService) List() ([]*model.Cluster, error) {
+	records, err := s.store.List(store.CollectionClusters)
+	if err != nil {
+		return nil, err
+	}
+
+	clusters := make([]*model.Cluster, 0, len(records))
+	for id, data := range records {
+		var cluster model.Cluster
+		if err := json.Unmarshal(data, &cluster); err != nil {
+			s.logger.Warnf("解析集群 %s 失败: %v", id, err)
+			continue
+		}
+		clusters = append(clusters, &cluster)
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Name < clusters[j].Name })
+	return clusters, nil
+}
+
+// FindByMaster 按 Master 地址查找集群，不存在时返回 nil
+func (s *ClusterService) FindByMaster(ip string) (*model.Cluster, error) {
+	clusters, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, cluster := range clusters {
+		if cluster.Master.IP == ip {
+			return cluster, nil
+		}
+	}
+	return nil, nil
+}
+
+// FindByName 按名称查找集群，不存在时返回 nil
+func (s *ClusterService) FindByName(name string) (*model.Cluster, error) {
+	clusters, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	for _, cluster := range clusters {
+		if cluster.Name == name {
+			return cluster, nil
+		}
+	}
+	return nil, nil
+}
+
+// Delete 删除集群记录及保存的 kubeconfig、对象存储密钥（不会卸载集群）
+func (s *ClusterService) Delete(id string) error {
+	cluster, err := s.Get(id)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Delete(store.CollectionClusters, id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return fmt.Errorf("集群 %s 不存在", id)
+		}
+		return err
+	}
+	if cluster.KubeconfigID != "" {
+		if err := s.credentialService.Delete(cluster.KubeconfigID); err != nil {
+			s.logger.Warnf("删除集群 %s 的 kubeconfig 失败: %v", cluster.Name, err)
+		}
+	}
+	if cluster.ObjectStore != nil {
+		if err := s.credentialService.Delete(cluster.ObjectStore.CredentialID); err != nil {
+			s.logger.Warnf("删除集群 %s 的对象存储密钥失败: %v", cluster.Name, err)
+		}
+	}
+	if err := s.store.Delete(store.CollectionClusterEvents, id); err != nil && !errors.Is(err, store.ErrNotFound) {
+		s.logger.Warnf("删除集群 %s 的事件记录失败: %v", cluster.Name, err)
+	}
+	if err := s.store.Delete(store.CollectionKubeAuditLogs, id); err != nil && !errors.Is(err, store.ErrNotFound) {
+		s.logger.Warnf("删除集群 %s 的审计记录失败: %v", cluster.Name, err)
+	}
+	if err := s.store.Delete(store.CollectionSecurityScans, id); err != nil && !errors.Is(err, store.ErrNotFound) {
+		s.logger.Warnf("删除集群 %s 的安全扫描结果失败: %v", cluster.Name, err)
+	}
+	if err := s.store.Delete(store.CollectionUtilization, id); err != nil && !errors.Is(err, store.ErrNotFound) {
+		s.logger.Warnf("删除集群 %s 的资源用量采样失败: %v", cluster.Name, err)
+	}
+	return nil
+}
+
+// MasterNode 返回可直接连接的 Master 节点配置（已解析凭据）
+func (s *ClusterService) MasterNode(cluster *model.Cluster) (model.NodeConfig, error) {
+	nodes := []model.NodeConfig{cluster.Master}
+	if err := s.credentialService.ResolveNodes(nodes); err != nil {
+		return model.NodeConfig{}, err
+	}
+	return nodes[0], nil
+}
+
+// PruneWorkspaces 清理所有受管集群 Master 节点上过期的部署工作目录，返回成功清理的集群数
+func (s *ClusterService) PruneWorkspaces(olderThan time.Duration) (int, error) {
+	clusters, err := s.List()
+	if err != nil {
+		return 0, err
+	}
+
+	pruned := 0
+	for _, cluster := range clusters {
+		master, err := s.MasterNode(cluster)
+		if err == nil {
+			err = s.k3sService.PruneWorkspaces(master, olderThan)
+		}
+		if err != nil {
+			s.logger.Warnf("集群 %s: %v", cluster.Name, err)
+			continue
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+// Verify 对受管集群执行部署验证，逐个检查已记录的实例；没有记录时检查默认实例
+func (s *ClusterService) Verify(id string) (*model.SecretsEncryptionStatus, error) {
+	cluster, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	master, err := s.MasterNode(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	// 加密状态只作报告，读取失败（如 k3s 版本过旧）不影响验证结果
+	encryption, err := s.k3sService.SecretsEncryptionStatus(master)
+	if err != nil {
+		s.logger.Warnf("集群 %s: %v", cluster.Name, err)
+	}
+	if encryption != nil && encryption.Enabled && !encryption.HashesMatch {
+		return encryption, fmt.Errorf("各 Server 节点的 Secret 加密配置不一致: %s", encryption.Hashes)
+	}
+
+	namespaces := []string{k3s.DefaultInstance}
+	if len(cluster.Releases) > 0 {
+		namespaces = namespaces[:0]
+		for _, release := range cluster.Releases {
+			namespaces = append(namespaces, release.Namespace)
+		}
+	}
+	for _, namespace := range namespaces {
+		if err := s.k3sService.VerifyDeployment(master, namespace); err != nil {
+			return encryption, fmt.Errorf("命名空间 %s: %w", namespace, err)
+		}
+	}
+	return encryption, nil
+}
+
+// Refresh 重新发现集群版本和节点，并读取实例命名空间的资源配额用量
+func (s *ClusterService) Refresh(id string) (*model.Cluster, error) {
+	cluster, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	master, err := s.MasterNode(cluster)
+	if err != nil {
+		return nil, err
+	}
+	discovery, err := s.k3sService.DiscoverCluster(master)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster.Version = discovery.Version
+	cluster.Nodes = discovery.Nodes
+	// 配额用量读取失败时保留上一次的结果
+	if quotas, err := s.k3sService.QuotaUsage(master); err != nil {
+		s.logger.Warnf("读取集群 %s 资源配额用量失败: %v", cluster.Name, err)
+	} else {
+		cluster.Quotas = quotas
+	}
+	cluster.UpdatedAt = time.Now()
+	s.storeAccess(cluster, master)
+	if err := s.save(cluster); err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+func (s *ClusterService) newID() (string, error) {
+	return utils.GenerateID("cluster")
+}
+
+func (s *ClusterService) save(cluster *model.Cluster) error {
+	data, err := json.Marshal(cluster)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Put(store.CollectionClusters, cluster.ID, data); err != nil {
+		return fmt.Errorf("保存集群 %s 失败: %v", cluster.ID, err)
+	}
+	return nil
+}
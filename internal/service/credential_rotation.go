@@ -0,0 +1,290 @@
+package service
+
+import (
+	"crypto/rand"
+	"fmt"
+	"math/big"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+	"k3s-deploy-backend/internal/pkg/vault"
+)
+
+const (
+	rotatedPasswordLength = 24
+	passwordAlphabet      = "ABCDEFGHJKLMNPQRSTUVWXYZabcdefghijkmnopqrstuvwxyz23456789"
+)
+
+// RotateCredentials 轮换节点凭据：使用旧凭据登录下发新凭据，
+// 新凭据登录验证通过后才更新凭据库并吊销旧凭据
+func (s *CredentialService) RotateCredentials(req *model.CredentialRotationRequest) []*model.CredentialRotationResult {
+	s.rotateMu.Lock()
+	defer s.rotateMu.Unlock()
+
+	ids := req.CredentialIDs
+	if len(ids) == 0 {
+		for _, cred := range s.vault.List() {
+			// SSH CA 证书按次签发，没有需要轮换的长期凭据
+			if cred.AuthType == "ca" || !nodeCredential(cred.AuthType) {
+				continue
+			}
+			ids = append(ids, cred.ID)
+		}
+	}
+
+	s.logger.Infof("开始轮换 %d 个节点凭据", len(ids))
+	results := make([]*model.CredentialRotationResult, 0, len(ids))
+	for _, id := range ids {
+		results = append(results, s.rotateCredential(id, req.Mode))
+	}
+	return results
+}
+
+func (s *CredentialService) rotateCredential(id, mode string) *model.CredentialRotationResult {
+	result := &model.CredentialRotationResult{CredentialID: id, Mode: mode}
+	fail := func(format string, args ...interface{}) *model.CredentialRotationResult {
+		result.Message = fmt.Sprintf(format, args...)
+		s.logger.Errorf("凭据 %s 轮换失败: %s", id, result.Message)
+		return result
+	}
+
+	cred, err := s.vault.Get(id)
+	if err != nil {
+		return fail("%v", err)
+	}
+	result.Host = cred.Host
+	if !nodeCredential(cred.AuthType) {
+		return fail("%s 记录不是节点凭据，不支持轮换", cred.AuthType)
+	}
+	if result.Mode == "" {
+		result.Mode = cred.AuthType
+	}
+
+	oldClient := newNodeClient(credentialNode(cred))
+	if err := oldClient.Connect(); err != nil {
+		return fail("使用当前凭据登录失败: %v", err)
+	}
+	defer oldClient.Close()
+
+	var updated *vault.Credential
+	switch result.Mode {
+	case "key":
+		updated, err = s.rotateKey(oldClient, cred)
+	case "password":
+		updated, err = s.rotatePassword(oldClient, cred)
+	default:
+		err = fmt.Errorf("不支持的轮换方式: %s", result.Mode)
+	}
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	result.Success = true
+	result.Message = "凭据已轮换并验证"
+	result.PublicKey = updated.PublicKey
+	s.logger.Infof("凭据 %s (%s) 轮换成功", id, cred.Host)
+	return result
+}
+
+func (s *CredentialService) rotateKey(oldClient *ssh.Client, cred *vault.Credential) (*vault.Credential, error) {
+	keyPair, err := ssh.GenerateKeyPair("", fmt.Sprintf("k3s-deploy@%s", cred.Host))
+	if err != nil {
+		return nil, err
+	}
+	if err := installAuthorizedKey(oldClient, keyPair.AuthorizedKey); err != nil {
+		return nil, err
+	}
+
+	updated := *cred
+	updated.AuthType = "key"
+	updated.PublicKey = keyPair.AuthorizedKey
+	updated.Secret = vault.Secret{PrivateKey: keyPair.PrivateKey}
+
+	// 新密钥不可用或无法保存时撤回，保持旧凭据有效
+	rollback := func(err error) error {
+		if rbErr := removeAuthorizedKey(oldClient, keyPair.AuthorizedKey); rbErr != nil {
+			return fmt.Errorf("%v；撤回新公钥失败，需手动从 authorized_keys 删除: %v", err, rbErr)
+		}
+		return err
+	}
+	if err := verifyLogin(credentialNode(&updated)); err != nil {
+		return nil, rollback(fmt.Errorf("新密钥登录验证失败: %v", err))
+	}
+
+	if err := s.vault.Put(&updated); err != nil {
+		return nil, rollback(fmt.Errorf("保存凭据失败: %v", err))
+	}
+
+	// 凭据库更新成功后吊销旧公钥
+	if cred.AuthType == "key" && cred.PublicKey != "" {
+		if err := removeAuthorizedKey(oldClient, cred.PublicKey); err != nil {
+			s.logger.Warnf("节点 %s 吊销旧公钥失败: %v", cred.Host, err)
+		}
+	}
+	return &updated, nil
+}
+
+func (s *CredentialService) rotatePassword(oldClient *ssh.Client, cred *vault.Credential) (*vault.Credential, error) {
+	newPassword, err := randomPassword(rotatedPasswordLength)
+	if err != nil {
+		return nil, err
+	}
+
+	// 非 root 用户通过 sudo 提权，需要密码时使用当前登录密码
+	sudo := &sudoer{client: oldClient, username: cred.Username, password: cred.Secret.Password}
+	if cred.AuthType != "password" {
+		sudo.password = ""
+	}
+
+	// 先保存 shadow 中的原密码哈希：chpasswd 会立即替换旧密码，旧连接保持打开，
+	// 验证失败时用原哈希恢复（密钥认证切换为密码时原密码未知，同样可以恢复）
+	oldHash, err := sudo.shadowHash()
+	if err != nil {
+		return nil, err
+	}
+	if err := sudo.chpasswd(cred.Username+":"+newPassword, false); err != nil {
+		return nil, err
+	}
+	sudo.password = newPassword
+
+	rollback := func(err error) error {
+		if rbErr := sudo.chpasswd(cred.Username+":"+oldHash, true); rbErr != nil {
+			return fmt.Errorf("%v；恢复原密码失败，节点当前密码已变更: %v", err, rbErr)
+		}
+		return err
+	}
+
+	updated := *cred
+	updated.AuthType = "password"
+	updated.PublicKey = ""
+	updated.Secret = vault.Secret{Password: newPassword}
+
+	if err := verifyLogin(credentialNode(&updated)); err != nil {
+		return nil, rollback(fmt.Errorf("新密码登录验证失败: %v", err))
+	}
+
+	if err := s.vault.Put(&updated); err != nil {
+		return nil, rollback(fmt.Errorf("保存凭据失败: %v", err))
+	}
+
+	// 从密钥认证切换为密码认证时吊销旧公钥
+	if cred.AuthType == "key" && cred.PublicKey != "" {
+		if err := removeAuthorizedKey(oldClient, cred.PublicKey); err != nil {
+			s.logger.Warnf("节点 %s 吊销旧公钥失败: %v", cred.Host, err)
+		}
+	}
+	return &updated, nil
+}
+
+// StartRotationScheduler 按固定周期轮换全部凭据
+func (s *CredentialService) StartRotationScheduler(interval time.Duration) {
+	s.logger.Infof("凭据定期轮换已启用，周期 %s", interval)
+	go func() {
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			results := s.RotateCredentials(&model.CredentialRotationRequest{})
+			failed := 0
+			for _, r := range results {
+				if !r.Success {
+					failed++
+				}
+			}
+			s.logger.Infof("定期凭据轮换完成: 共 %d 个，失败 %d 个", len(results), failed)
+		}
+	}()
+}
+
+// credentialNode 将凭据转换为节点连接配置
+func credentialNode(cred *vault.Credential) model.NodeConfig {
+	return model.NodeConfig{
+		Name:       cred.Name,
+		IP:         cred.Host,
+		Port:       cred.Port,
+		Username:   cred.Username,
+		AuthType:   cred.AuthType,
+		Password:   cred.Secret.Password,
+		PrivateKey: cred.Secret.PrivateKey,
+		Passphrase: cred.Secret.Passphrase,
+	}
+}
+
+// removeAuthorizedKey 从 authorized_keys 中删除公钥，grep 没有剩余行（退出码 1）时写入空文件
+func removeAuthorizedKey(client *ssh.Client, authorizedKey string) error {
+	cmd := fmt.Sprintf("{ grep -vxF %s ~/.ssh/authorized_keys; [ $? -le 1 ]; } > ~/.ssh/authorized_keys.k3s-deploy && "+
+		"cat ~/.ssh/authorized_keys.k3s-deploy > ~/.ssh/authorized_keys && rm -f ~/.ssh/authorized_keys.k3s-deploy", ssh.Quote(authorizedKey))
+	if _, err := client.ExecuteCommand(cmd); err != nil {
+		return fmt.Errorf("移除公钥失败: %v", err)
+	}
+	return nil
+}
+
+// sudoer 以 root 权限在节点上执行命令：root 用户直接执行，其他用户优先使用免密 sudo，
+// 否则以 password 通过 sudo -S 提权。密码只经 stdin 传递，不出现在命令行和进程列表中
+type sudoer struct {
+	client   *ssh.Client
+	username string
+	// password 当前登录密码，为空表示没有可用于 sudo 的密码
+	password string
+}
+
+// run 执行 cmd，stdin 依次写入 sudo 密码（需要时）和 input
+func (s *sudoer) run(cmd string, input string) (*ssh.CommandResult, error) {
+	if s.username != "root" {
+		if _, err := s.client.ExecuteCommand("sudo -n true"); err == nil {
+			cmd = "sudo -n " + cmd
+		} else if s.password != "" {
+			// sudo -S 逐字节读取第一行作为密码，其余输入留给 cmd
+			s.client.Redact(s.password)
+			cmd = "sudo -S -p '' " + cmd
+			input = s.password + "\n" + input
+		} else {
+			return nil, fmt.Errorf("用户 %s 不是 root，且没有免密 sudo 权限", s.username)
+		}
+	}
+	return s.client.ExecuteCommandWithStdin([]byte(input), cmd, nil)
+}
+
+// shadowHash 读取用户在 /etc/shadow 中的密码哈希
+func (s *sudoer) shadowHash() (string, error) {
+	result, err := s.run("getent shadow "+ssh.Quote(s.username), "")
+	if err != nil {
+		return "", fmt.Errorf("读取原密码哈希失败: %v", err)
+	}
+	fields := strings.Split(result.Stdout, ":")
+	if len(fields) < 2 || fields[1] == "" {
+		return "", fmt.Errorf("读取原密码哈希失败: shadow 中没有用户 %s", s.username)
+	}
+	s.client.Redact(fields[1])
+	return fields[1], nil
+}
+
+// chpasswd 通过 stdin 设置 用户:密码，encrypted 为 true 时 entry 中为 shadow 哈希
+func (s *sudoer) chpasswd(entry string, encrypted bool) error {
+	if _, secret, ok := strings.Cut(entry, ":"); ok {
+		s.client.Redact(secret)
+	}
+	cmd := "chpasswd"
+	if encrypted {
+		cmd += " -e"
+	}
+	if _, err := s.run(cmd, entry+"\n"); err != nil {
+		return fmt.Errorf("修改密码失败: %v", err)
+	}
+	return nil
+}
+
+func randomPassword(length int) (string, error) {
+	buf := make([]byte, length)
+	max := big.NewInt(int64(len(passwordAlphabet)))
+	for i := range buf {
+		n, err := rand.Int(rand.Reader, max)
+		if err != nil {
+			return "", fmt.Errorf("生成随机密码失败: %v", err)
+		}
+		buf[i] = passwordAlphabet[n.Int64()]
+	}
+	return string(buf), nil
+}
@@ -0,0 +1,270 @@
+package service
+
+import (
+	"fmt"
+	"sync"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/ssh"
+	"k3s-deploy-backend/internal/pkg/vault"
+)
+
+type CredentialService struct {
+	vault *vault.Vault
+	// userCA 为 nil 表示未启用 SSH CA
+	userCA   *ssh.UserCA
+	logger   *logger.Logger
+	rotateMu sync.Mutex
+}
+
+func NewCredentialService(v *vault.Vault, userCA *ssh.UserCA, logger *logger.Logger) *CredentialService {
+	return &CredentialService{
+		vault:  v,
+		userCA: userCA,
+		logger: logger,
+	}
+}
+
+// ResolveNodes 将引用凭据库的节点（及其跳板机）补全为完整的认证信息
+func (s *CredentialService) ResolveNodes(nodes []model.NodeConfig) error {
+	for i := range nodes {
+		if nodes[i].CredentialID != "" {
+			cred, err := s.vault.Get(nodes[i].CredentialID)
+			if err != nil {
+				return fmt.Errorf("节点 %s: %v", nodes[i].Name, err)
+			}
+			nodes[i].Username = cred.Username
+			nodes[i].AuthType = cred.AuthType
+			nodes[i].Password = cred.Secret.Password
+			nodes[i].PrivateKey = cred.Secret.PrivateKey
+			nodes[i].Passphrase = cred.Secret.Passphrase
+		}
+
+		for j := range nodes[i].JumpHosts {
+			jump := &nodes[i].JumpHosts[j]
+			if jump.CredentialID == "" {
+				continue
+			}
+			cred, err := s.vault.Get(jump.CredentialID)
+			if err != nil {
+				return fmt.Errorf("节点 %s 跳板机 %s: %v", nodes[i].Name, jump.IP, err)
+			}
+			jump.Username = cred.Username
+			jump.AuthType = cred.AuthType
+			jump.Password = cred.Secret.Password
+			jump.PrivateKey = cred.Secret.PrivateKey
+			jump.Passphrase = cred.Secret.Passphrase
+		}
+	}
+	return nil
+}
+
+// NodeFromCredential 根据凭据库记录构造可直接连接的节点配置
+func (s *CredentialService) NodeFromCredential(id string) (model.NodeConfig, error) {
+	cred, err := s.vault.Get(id)
+	if err != nil {
+		return model.NodeConfig{}, err
+	}
+	return credentialNode(cred), nil
+}
+
+// Persist 将节点（及其跳板机）请求中的认证信息存入凭据库，改为引用 CredentialID 并清空敏感字段，
+// 用于需要长期保存节点连接方式的记录
+func (s *CredentialService) Persist(node *model.NodeConfig) error {
+	if node.CredentialID == "" {
+		id, err := s.save(node.Name, node.IP, node.Port, node.Username, node.AuthType,
+			vault.Secret{Password: node.Password, PrivateKey: node.PrivateKey, Passphrase: node.Passphrase})
+		if err != nil {
+			return fmt.Errorf("节点 %s: %v", node.Name, err)
+		}
+		node.CredentialID = id
+	}
+	node.Password, node.PrivateKey, node.Passphrase = "", "", ""
+
+	for j := range node.JumpHosts {
+		jump := &node.JumpHosts[j]
+		if jump.CredentialID == "" {
+			id, err := s.save(jump.IP, jump.IP, jump.Port, jump.Username, jump.AuthType,
+				vault.Secret{Password: jump.Password, PrivateKey: jump.PrivateKey, Passphrase: jump.Passphrase})
+			if err != nil {
+				return fmt.Errorf("节点 %s 跳板机 %s: %v", node.Name, jump.IP, err)
+			}
+			jump.CredentialID = id
+		}
+		jump.Password, jump.PrivateKey, jump.Passphrase = "", "", ""
+	}
+	return nil
+}
+
+// save 保存节点登录凭据：同一主机、端口和用户名已有认证信息相同的凭据时复用其 ID，否则新建一条。
+// 不覆盖已有凭据，其他集群或节点可能仍引用它（如分发的专用密钥），覆盖后会无法登录
+func (s *CredentialService) save(name, host string, port int, username, authType string, secret vault.Secret) (string, error) {
+	for _, meta := range s.vault.List() {
+		if meta.Host != host || meta.Port != port || meta.Username != username || meta.AuthType != authType {
+			continue
+		}
+		existing, err := s.vault.Get(meta.ID)
+		if err != nil {
+			continue
+		}
+		if existing.Secret.Password == secret.Password && existing.Secret.PrivateKey == secret.PrivateKey &&
+			existing.Secret.Passphrase == secret.Passphrase {
+			return existing.ID, nil
+		}
+	}
+	cred := &vault.Credential{
+		Name:     name,
+		Host:     host,
+		Port:     port,
+		Username: username,
+		AuthType: authType,
+		Secret:   secret,
+	}
+	if err := s.vault.Put(cred); err != nil {
+		return "", fmt.Errorf("保存凭据失败: %v", err)
+	}
+	return cred.ID, nil
+}
+
+// upsert 保存后端生成的记录（kubeconfig、对象存储密钥），同一地址和类型复用已有凭据 ID 并更新内容
+func (s *CredentialService) upsert(name, host string, port int, username, authType string, secret vault.Secret) (string, error) {
+	cred := &vault.Credential{
+		Name:     name,
+		Host:     host,
+		Port:     port,
+		Username: username,
+		AuthType: authType,
+		Secret:   secret,
+	}
+	if existing, found := s.vault.Find(host, port, username); found && existing.AuthType == authType {
+		cred.ID = existing.ID
+	}
+	if err := s.vault.Put(cred); err != nil {
+		return "", fmt.Errorf("保存凭据失败: %v", err)
+	}
+	return cred.ID, nil
+}
+
+// DistributeKeys 为每个节点生成独立密钥对，用一次性密码安装公钥，
+// 验证密钥登录成功后将私钥存入凭据库，节点记录切换为密钥认证
+func (s *CredentialService) DistributeKeys(req *model.KeyDistributionRequest) []*model.KeyDistributionResult {
+	s.logger.Infof("开始为 %d 个节点分发SSH密钥", len(req.Nodes))
+
+	results := make([]*model.KeyDistributionResult, len(req.Nodes))
+	var wg sync.WaitGroup
+
+	for i, node := range req.Nodes {
+		wg.Add(1)
+		go func(index int, n model.KeyDistributionNode) {
+			defer wg.Done()
+			results[index] = s.distributeKey(req.KeyType, n)
+		}(i, node)
+	}
+
+	wg.Wait()
+	return results
+}
+
+func (s *CredentialService) distributeKey(keyType string, node model.KeyDistributionNode) *model.KeyDistributionResult {
+	result := &model.KeyDistributionResult{Name: node.Name, IP: node.IP}
+	fail := func(format string, args ...interface{}) *model.KeyDistributionResult {
+		result.Message = fmt.Sprintf(format, args...)
+		s.logger.Errorf("节点 %s 密钥分发失败: %s", node.IP, result.Message)
+		return result
+	}
+
+	keyPair, err := ssh.GenerateKeyPair(keyType, fmt.Sprintf("k3s-deploy@%s", node.IP))
+	if err != nil {
+		return fail("%v", err)
+	}
+
+	passwordClient := newNodeClient(model.NodeConfig{
+		IP:       node.IP,
+		Port:     node.Port,
+		Username: node.Username,
+		AuthType: "password",
+		Password: node.Password,
+	})
+	if err := passwordClient.Connect(); err != nil {
+		return fail("密码登录失败: %v", err)
+	}
+	defer passwordClient.Close()
+
+	if err := installAuthorizedKey(passwordClient, keyPair.AuthorizedKey); err != nil {
+		return fail("%v", err)
+	}
+
+	keyNode := model.NodeConfig{
+		IP:         node.IP,
+		Port:       node.Port,
+		Username:   node.Username,
+		AuthType:   "key",
+		PrivateKey: keyPair.PrivateKey,
+	}
+	if err := verifyLogin(keyNode); err != nil {
+		return fail("密钥登录验证失败: %v", err)
+	}
+
+	cred := &vault.Credential{
+		Name:      node.Name,
+		Host:      node.IP,
+		Port:      node.Port,
+		Username:  node.Username,
+		AuthType:  "key",
+		PublicKey: keyPair.AuthorizedKey,
+		Secret:    vault.Secret{PrivateKey: keyPair.PrivateKey},
+	}
+	if existing, found := s.vault.Find(node.IP, node.Port, node.Username); found {
+		cred.ID = existing.ID
+	}
+	if err := s.vault.Put(cred); err != nil {
+		return fail("保存凭据失败: %v", err)
+	}
+
+	s.logger.Infof("节点 %s 已切换为密钥认证 (%s)", node.IP, keyPair.Fingerprint)
+	result.Success = true
+	result.Message = "密钥已安装并验证"
+	result.CredentialID = cred.ID
+	result.PublicKey = keyPair.AuthorizedKey
+	result.Fingerprint = keyPair.Fingerprint
+	return result
+}
+
+// List 列出凭据库中的凭据（不含敏感字段），不包括任务请求的敏感字段记录
+func (s *CredentialService) List() []vault.Credential {
+	list := []vault.Credential{}
+	for _, cred := range s.vault.List() {
+		if cred.AuthType != taskRequestAuthType {
+			list = append(list, cred)
+		}
+	}
+	return list
+}
+
+// Delete 删除凭据
+func (s *CredentialService) Delete(id string) error {
+	return s.vault.Delete(id)
+}
+
+// installAuthorizedKey 幂等地将公钥追加到 authorized_keys
+func installAuthorizedKey(client *ssh.Client, authorizedKey string) error {
+	cmd := fmt.Sprintf("mkdir -p ~/.ssh && chmod 700 ~/.ssh && touch ~/.ssh/authorized_keys && chmod 600 ~/.ssh/authorized_keys && "+
+		"(grep -qxF '%[1]s' ~/.ssh/authorized_keys || echo '%[1]s' >> ~/.ssh/authorized_keys)", authorizedKey)
+	if _, err := client.ExecuteCommand(cmd); err != nil {
+		return fmt.Errorf("安装公钥失败: %v", err)
+	}
+	return nil
+}
+
+// verifyLogin 使用给定认证信息建立新连接并执行一条命令
+func verifyLogin(node model.NodeConfig) error {
+	client := newNodeClient(node)
+	if err := client.Connect(); err != nil {
+		return err
+	}
+	defer client.Close()
+
+	_, err := client.ExecuteCommand("true")
+	return err
+}
@@ -0,0 +1,162 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/vault"
+)
+
+// ErrSSHCADisabled 未启用 SSH CA
+var ErrSSHCADisabled = errors.New("未启用 SSH CA")
+
+// installUserCAScript 将 CA 公钥加入 sshd 的 TrustedUserCAKeys（已配置时追加到现有文件），
+// 校验配置后重新加载 sshd。需要修改 sshd_config 时先用 sshd -t -f 校验新配置，
+// 原配置备份为 sshd_config.k3s-deploy.bak 后再替换。公钥通过 $1 传入
+const installUserCAScript = `set -e
+KEY="$1"
+SSHD=$(command -v sshd || echo /usr/sbin/sshd)
+CONFIG=/etc/ssh/sshd_config
+CA_FILE=$($SSHD -T 2>/dev/null | awk '$1 == "trustedusercakeys" { print $2 }')
+UPDATE=
+if [ -z "$CA_FILE" ] || [ "$CA_FILE" = "none" ]; then
+  CA_FILE=/etc/ssh/k3s-deploy-user-ca.pub
+  UPDATE=1
+fi
+touch "$CA_FILE"
+chmod 644 "$CA_FILE"
+grep -qxF "$KEY" "$CA_FILE" || echo "$KEY" >> "$CA_FILE"
+if [ -n "$UPDATE" ]; then
+  # 写在文件开头，避免落入末尾的 Match 块
+  { echo "TrustedUserCAKeys $CA_FILE"; cat "$CONFIG"; } > "$CONFIG.k3s-deploy"
+  if ! $SSHD -t -f "$CONFIG.k3s-deploy"; then
+    rm -f "$CONFIG.k3s-deploy"
+    echo "新的 sshd 配置校验失败，未修改 $CONFIG" >&2
+    exit 1
+  fi
+  [ -f "$CONFIG.k3s-deploy.bak" ] || cp -p "$CONFIG" "$CONFIG.k3s-deploy.bak"
+  cat "$CONFIG.k3s-deploy" > "$CONFIG"
+  rm -f "$CONFIG.k3s-deploy"
+fi
+$SSHD -t
+systemctl reload sshd 2>/dev/null || systemctl reload ssh 2>/dev/null || service sshd reload 2>/dev/null || service ssh reload
+`
+
+// SSHCAInfo 返回 SSH CA 公钥
+func (s *CredentialService) SSHCAInfo() (*model.SSHCAInfo, error) {
+	if s.userCA == nil {
+		return nil, ErrSSHCADisabled
+	}
+	return &model.SSHCAInfo{
+		PublicKey:   s.userCA.PublicKey(),
+		Fingerprint: s.userCA.Fingerprint(),
+	}, nil
+}
+
+// BootstrapSSHCA 在节点上信任 SSH CA，验证证书登录成功后将凭据切换为 ca 认证并清除保存的密码和私钥，
+// 之前分发的公钥也会从 authorized_keys 中移除
+func (s *CredentialService) BootstrapSSHCA(req *model.SSHCABootstrapRequest) ([]*model.SSHCABootstrapResult, error) {
+	if s.userCA == nil {
+		return nil, ErrSSHCADisabled
+	}
+
+	type target struct {
+		node     model.NodeConfig
+		existing *vault.Credential
+	}
+	var targets []target
+	var results []*model.SSHCABootstrapResult
+	for _, id := range req.CredentialIDs {
+		cred, err := s.vault.Get(id)
+		if err != nil {
+			results = append(results, &model.SSHCABootstrapResult{CredentialID: id, Message: err.Error()})
+			continue
+		}
+		targets = append(targets, target{node: credentialNode(cred), existing: cred})
+	}
+	for _, n := range req.Nodes {
+		targets = append(targets, target{node: model.NodeConfig{
+			Name:     n.Name,
+			IP:       n.IP,
+			Port:     n.Port,
+			Username: n.Username,
+			AuthType: "password",
+			Password: n.Password,
+		}})
+	}
+
+	s.logger.Infof("开始为 %d 个节点引导 SSH CA", len(targets))
+	offset := len(results)
+	results = append(results, make([]*model.SSHCABootstrapResult, len(targets))...)
+	var wg sync.WaitGroup
+	for i, t := range targets {
+		wg.Add(1)
+		go func(index int, t target) {
+			defer wg.Done()
+			results[offset+index] = s.bootstrapNode(t.node, t.existing)
+		}(i, t)
+	}
+	wg.Wait()
+	return results, nil
+}
+
+func (s *CredentialService) bootstrapNode(node model.NodeConfig, existing *vault.Credential) *model.SSHCABootstrapResult {
+	result := &model.SSHCABootstrapResult{Name: node.Name, IP: node.IP}
+	fail := func(format string, args ...interface{}) *model.SSHCABootstrapResult {
+		result.Message = fmt.Sprintf(format, args...)
+		s.logger.Errorf("节点 %s SSH CA 引导失败: %s", node.IP, result.Message)
+		return result
+	}
+
+	client := newNodeClient(node)
+	if err := client.Connect(); err != nil {
+		return fail("使用当前凭据登录失败: %v", err)
+	}
+	defer client.Close()
+
+	if _, err := client.ExecuteCommandWithStdin([]byte(installUserCAScript), "/bin/sh -s -- '"+s.userCA.PublicKey()+"'", nil); err != nil {
+		return fail("配置 TrustedUserCAKeys 失败: %v", err)
+	}
+
+	caNode := model.NodeConfig{IP: node.IP, Port: node.Port, Username: node.Username, AuthType: "ca"}
+	if err := verifyLogin(caNode); err != nil {
+		return fail("证书登录验证失败: %v", err)
+	}
+
+	cred := &vault.Credential{
+		Name:     node.Name,
+		Host:     node.IP,
+		Port:     node.Port,
+		Username: node.Username,
+		AuthType: "ca",
+	}
+	if existing == nil {
+		if found, ok := s.vault.Find(node.IP, node.Port, node.Username); ok {
+			existing = found
+		}
+	}
+	if existing != nil {
+		cred.ID = existing.ID
+		if cred.Name == "" {
+			cred.Name = existing.Name
+		}
+	}
+	if err := s.vault.Put(cred); err != nil {
+		return fail("保存凭据失败: %v", err)
+	}
+
+	// 证书登录已验证，移除之前分发的专用公钥
+	if existing != nil && existing.AuthType == "key" && existing.PublicKey != "" {
+		if err := removeAuthorizedKey(client, existing.PublicKey); err != nil {
+			s.logger.Warnf("节点 %s 移除旧公钥失败: %v", node.IP, err)
+		}
+	}
+
+	s.logger.Infof("节点 %s 已切换为 SSH 证书认证", node.IP)
+	result.Success = true
+	result.Message = "已信任 SSH CA 并验证证书登录"
+	result.CredentialID = cred.ID
+	return result
+}
@@ -2,36 +2,106 @@ package service
 
 import (
 	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/benchmark"
+	"k3s-deploy-backend/internal/pkg/bundle"
+	"k3s-deploy-backend/internal/pkg/diagnose"
+	"k3s-deploy-backend/internal/pkg/k3s"
 	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/progress"
+	"k3s-deploy-backend/pkg/utils"
 )
 
 type DeployService struct {
-	sshService *SSHService
-	k3sService *K3sService
-	logger     *logger.Logger
+	k3sService        K3sOperations
+	credentialService NodeCredentials
+	clusterService    ClusterRegistry
+	ingressCA         IngressCAConfig
+	bundles           *bundle.Catalog
+	logger            *logger.Logger
+	// faults 测试用的步骤故障注入，默认不启用
+	faults StepFaults
+	// joinBundles 校验 edge.joinBundle，未设置时不支持加入信息包
+	joinBundles JoinBundles
+	// defaults 全局部署设置，见 ResolveSettings
+	defaults model.DeploySettings
+
+	// airgapMu 保护 airgap
+	airgapMu sync.Mutex
+	// airgap 任务内已校验的离线安装包，按 RequestID 和安装包名称索引，任务的各步骤不再重复计算文件摘要
+	airgap map[string]map[string]*bundle.Bundle
 }
 
-func NewDeployService(sshService *SSHService, k3sService *K3sService, logger *logger.Logger) *DeployService {
+func NewDeployService(k3sService K3sOperations, credentialService NodeCredentials, clusterService ClusterRegistry, ingressCA IngressCAConfig, bundles *bundle.Catalog, logger *logger.Logger) *DeployService {
 	return &DeployService{
-		sshService: sshService,
-		k3sService: k3sService,
-		logger:     logger,
+		k3sService:        k3sService,
+		credentialService: credentialService,
+		clusterService:    clusterService,
+		ingressCA:         ingressCA,
+		bundles:           bundles,
+		logger:            logger,
 	}
 }
 
+// SetFaults 启用步骤故障注入，用于验证任务重试和断点续跑
+func (s *DeployService) SetFaults(faults StepFaults) {
+	s.faults = faults
+}
+
+// SetJoinBundles 设置加入信息包的校验，部署请求可通过 edge.joinBundle 加入已有集群
+func (s *DeployService) SetJoinBundles(joinBundles JoinBundles) {
+	s.joinBundles = joinBundles
+}
+
 var stepHandlers = map[string]func(*DeployService, *model.DeployRequest) error{
-	"validate":        (*DeployService).validateStep,
-	"install-master":  (*DeployService).installMasterStep,
-	"configure-agent": (*DeployService).configureAgentStep,
-	"apply-labels":    (*DeployService).applyLabelsStep,
-	"deploy-insuite":  (*DeployService).deployInSuiteStep,
-	"verify":          (*DeployService).verifyStep,
+	"validate":             (*DeployService).validateStep,
+	"prepare-nodes":        (*DeployService).prepareNodesStep,
+	"prepare-disks":        (*DeployService).prepareDisksStep,
+	"tune-nodes":           (*DeployService).tuneNodesStep,
+	"harden-nodes":         (*DeployService).hardenNodesStep,
+	"check-mirrors":        (*DeployService).checkMirrorsStep,
+	"install-master":       (*DeployService).installMasterStep,
+	"configure-agent":      (*DeployService).configureAgentStep,
+	"wait-nodes":           (*DeployService).waitNodesStep,
+	"configure-dns":        (*DeployService).configureDNSStep,
+	"configure-storage":    (*DeployService).configureStorageStep,
+	"install-cert-manager": (*DeployService).installCertManagerStep,
+	"install-minio":        (*DeployService).installMinIOStep,
+	"install-velero":       (*DeployService).installVeleroStep,
+	"apply-labels":         (*DeployService).applyLabelsStep,
+	"prepull-images":       (*DeployService).prePullImagesStep,
+	"deploy-insuite":       (*DeployService).deployInSuiteStep,
+	"verify":               (*DeployService).verifyStep,
+	"patch-os":             (*DeployService).patchOSStep,
+	"rotate-secrets-key":   (*DeployService).rotateSecretsKeyStep,
+}
+
+// KnownStep 报告 step 是否为部署步骤，用于校验故障注入等配置中的步骤名
+func KnownStep(step string) bool {
+	_, ok := stepHandlers[step]
+	return ok
+}
+
+// centralSteps 依赖 Master 节点 SSH 的集群级步骤，加入中心 server 时由中心集群负责
+var centralSteps = map[string]bool{
+	"wait-nodes":           true,
+	"configure-dns":        true,
+	"configure-storage":    true,
+	"install-cert-manager": true,
+	"install-minio":        true,
+	"install-velero":       true,
+	"apply-labels":         true,
+	"deploy-insuite":       true,
+	"verify":               true,
 }
 
 func (s *DeployService) ExecuteStep(req *model.DeployRequest) *model.DeployResponse {
-	s.logger.Infof("执行部署步骤: %s", req.Step)
+	s.logger.WithField("requestId", req.RequestID).Infof("执行部署步骤: %s", req.Step)
 
 	handler, exists := stepHandlers[req.Step]
 	if !exists {
@@ -42,28 +112,320 @@ func (s *DeployService) ExecuteStep(req *model.DeployRequest) *model.DeployRespo
 		}
 	}
 
-	if err := handler(s, req); err != nil {
-		s.logger.DeploymentError(req.Step, err)
-		return &model.DeployResponse{
-			Success: false,
-			Message: err.Error(),
-			Step:    req.Step,
+	for i := range req.Nodes {
+		req.Nodes[i].RequestID = req.RequestID
+	}
+	if req.WorkspaceID == "" {
+		req.WorkspaceID = req.RequestID
+	}
+	if req.WorkspaceID == "" {
+		id, err := utils.GenerateID("deploy")
+		if err != nil {
+			return s.failed(req, err)
+		}
+		req.WorkspaceID = id
+	}
+
+	if err := s.credentialService.ResolveNodes(req.Nodes); err != nil {
+		return s.failed(req, err)
+	}
+	if err := s.ResolveJoinBundle(req); err != nil {
+		return s.failed(req, err)
+	}
+	if err := s.ResolveRoles(req); err != nil {
+		return s.failed(req, err)
+	}
+	if !req.SettingsResolved {
+		if err := s.ResolveSettings(req); err != nil {
+			return s.failed(req, err)
+		}
+	}
+
+	if s.faults != nil {
+		if err := s.faults.StepFault(req.Step); err != nil {
+			return s.failed(req, err)
+		}
+	}
+
+	if centralSteps[req.Step] && joinsServer(req) {
+		s.logger.Infof("节点加入中心 server，步骤 %s 由中心集群负责，跳过", req.Step)
+	} else if err := handler(s, req); err != nil {
+		return s.failed(req, err)
+	}
+
+	message := fmt.Sprintf("步骤 %s 执行成功", req.Step)
+	failed := failedNodes(req.NodeResults)
+	if err := droppedRoleError(req.RoleAssignment, failed); err != nil {
+		return s.failed(req, err)
+	}
+	if len(failed) > 0 {
+		names := make([]string, len(failed))
+		for i, r := range failed {
+			names[i] = r.Name
 		}
+		message = fmt.Sprintf("步骤 %s 部分成功，%d/%d 个节点失败: %s", req.Step, len(failed), len(req.NodeResults), strings.Join(names, ", "))
+		s.logger.WithField("requestId", req.RequestID).Warn(message)
 	}
 
 	s.logger.DeploymentSuccess(req.Step)
 	return &model.DeployResponse{
-		Success: true,
-		Message: fmt.Sprintf("步骤 %s 执行成功", req.Step),
-		Step:    req.Step,
+		Success:           true,
+		Message:           message,
+		Step:              req.Step,
+		Artifacts:         req.Artifacts,
+		URL:               req.AccessURL,
+		Digests:           req.Digests,
+		SecretsEncryption: req.SecretsEncryption,
+		Preflight:         req.Preflight,
+		Partial:           len(failed) > 0,
+		Nodes:             req.NodeResults,
+		Readiness:         req.Readiness,
+	}
+}
+
+// failed 记录步骤失败并附带失败分类与处理建议
+func (s *DeployService) failed(req *model.DeployRequest, err error) *model.DeployResponse {
+	s.logger.DeploymentError(req.Step, err)
+
+	diagnosis := diagnose.Classify(err)
+	s.logger.WithField("requestId", req.RequestID).Warnf("步骤 %s 失败分类: %s，建议: %s", req.Step, diagnosis.Category, diagnosis.Hint)
+	return &model.DeployResponse{
+		Success:   false,
+		Message:   err.Error(),
+		Step:      req.Step,
+		Artifacts: req.Artifacts,
+		Digests:   req.Digests,
+		// verify 步骤因加密配置失败时同时返回读取到的状态
+		SecretsEncryption: req.SecretsEncryption,
+		// validate 步骤失败时返回全部节点的预检结果
+		Preflight: req.Preflight,
+		Nodes:     req.NodeResults,
+		Readiness: req.Readiness,
+		Failure: &model.FailureInfo{
+			Category: diagnosis.Category,
+			Hint:     diagnosis.Hint,
+		},
 	}
 }
 
 func (s *DeployService) validateStep(req *model.DeployRequest) error {
-	return s.k3sService.ValidateNodes(req.Nodes)
+	opts, err := s.installOptions(req)
+	if err != nil {
+		return err
+	}
+	if opts.Script != nil {
+		if err := opts.Script.Validate(); err != nil {
+			return err
+		}
+	}
+	if err := validateExposure(req.Exposure); err != nil {
+		return err
+	}
+	if req.NetworkCheck != nil {
+		if err := k3s.ValidateExternalName(req.NetworkCheck.ExternalName); err != nil {
+			return err
+		}
+	}
+	// 显式设置的 roleAssignment 不按部署模式分配，不要求节点数；异步任务提交时已按模式分配并检查
+	if !joinsServer(req) && len(req.RoleAssignment) == 0 {
+		if err := checkModeNodes(req); err != nil {
+			return err
+		}
+	}
+	if err := validateEdge(req); err != nil {
+		return err
+	}
+	if err := k3s.ValidateSecurity(req.Security); err != nil {
+		return err
+	}
+	if _, err := appSpec(req); err != nil {
+		return err
+	}
+	if req.CertManager != nil {
+		if _, err := validateCertManager(req.CertManager, req.Exposure); err != nil {
+			return err
+		}
+	}
+	if err := validateMinIO(req); err != nil {
+		return err
+	}
+	if err := k3s.ValidateVelero(req.Velero); err != nil {
+		return err
+	}
+	if req.DiskPrep != nil {
+		if err := validateDiskPrep(req.DiskPrep, req.Nodes); err != nil {
+			return err
+		}
+	}
+	if req.Tuning != nil {
+		if _, err := tuningProfile(req.Tuning); err != nil {
+			return err
+		}
+	}
+	results, err := s.k3sService.ValidateNodes(req.Nodes, *req.PreflightThresholds, req.Runtime, req.DNS, req.DiskPrep, req.Firewall, opts.CNI)
+	req.Preflight = results
+	if err != nil {
+		return err
+	}
+	if err := preflightError(results); err != nil {
+		return err
+	}
+	if joinsServer(req) {
+		return s.k3sService.CheckServerReachable(req.Nodes, req.Edge.ServerURL)
+	}
+	return nil
+}
+
+// prepareNodesStep 依次配置节点 DNS、系统设置和 /etc/hosts，某项失败的节点不再执行之后的配置
+func (s *DeployService) prepareNodesStep(req *model.DeployRequest) error {
+	nodes := req.Nodes
+	if req.DNS != nil {
+		results, err := s.k3sService.ConfigureNodeDNS(nodes, req.DNS)
+		if nodes, err = s.nodeResults(req, "节点 DNS 配置失败", nodes, results, err); err != nil {
+			return err
+		}
+	}
+	if req.NodePrep == nil {
+		s.logger.Info("未设置 nodePrep，跳过节点系统设置")
+	} else {
+		results, err := s.k3sService.PrepareNodes(nodes, req.NodePrep)
+		if nodes, err = s.nodeResults(req, "节点系统设置失败", nodes, results, err); err != nil {
+			return err
+		}
+	}
+	if req.Hosts != nil {
+		results, err := s.k3sService.SyncHosts(nodes, req.Hosts)
+		if nodes, err = s.nodeResults(req, "同步 /etc/hosts 失败", nodes, hostsNodeResults(results), err); err != nil {
+			return err
+		}
+	}
+	recordSucceeded(req, nodes)
+	return nil
+}
+
+func (s *DeployService) prepareDisksStep(req *model.DeployRequest) error {
+	if req.DiskPrep == nil {
+		s.logger.Info("未设置 diskPrep，跳过数据盘准备")
+		return nil
+	}
+	results, err := s.k3sService.PrepareDisks(req.Nodes, req.DiskPrep)
+	nodes, err := s.nodeResults(req, "准备数据盘失败", req.Nodes, results, err)
+	if err != nil {
+		return err
+	}
+	recordSucceeded(req, nodes)
+	return nil
+}
+
+func (s *DeployService) tuneNodesStep(req *model.DeployRequest) error {
+	if req.Tuning == nil {
+		s.logger.Info("未设置 tuning，跳过节点调优")
+		return nil
+	}
+	results, err := s.k3sService.TuneNodes(req.Nodes, req.Tuning)
+	nodes, err := s.nodeResults(req, "节点调优失败", req.Nodes, tuningNodeResults(results), err)
+	if err != nil {
+		return err
+	}
+	recordSucceeded(req, nodes)
+	return nil
+}
+
+// TuneNodes 在各节点应用内核参数与文件句柄限制
+func (s *DeployService) TuneNodes(req *model.TuningRequest) ([]model.TuningResult, error) {
+	if err := s.credentialService.ResolveNodes(req.Nodes); err != nil {
+		return nil, err
+	}
+	return s.k3sService.TuneNodes(req.Nodes, &req.Tuning)
+}
+
+// RevertTuning 按备份恢复各节点调优前的内核参数并删除调优配置
+func (s *DeployService) RevertTuning(nodes []model.NodeConfig) ([]model.TuningResult, error) {
+	if err := s.credentialService.ResolveNodes(nodes); err != nil {
+		return nil, err
+	}
+	return s.k3sService.TuneNodes(nodes, nil)
+}
+
+// SyncHosts 将 hosts 记录写入各节点的 /etc/hosts
+func (s *DeployService) SyncHosts(req *model.HostsSyncRequest) ([]model.HostsSyncResult, error) {
+	if err := s.credentialService.ResolveNodes(req.Nodes); err != nil {
+		return nil, err
+	}
+	return s.k3sService.SyncHosts(req.Nodes, &req.Hosts)
+}
+
+// RemoveHosts 删除各节点 /etc/hosts 中由部署维护的记录
+func (s *DeployService) RemoveHosts(nodes []model.NodeConfig) ([]model.HostsSyncResult, error) {
+	if err := s.credentialService.ResolveNodes(nodes); err != nil {
+		return nil, err
+	}
+	return s.k3sService.SyncHosts(nodes, nil)
+}
+
+// Uninstall 卸载各节点的 k3s 并逆序撤销部署对系统配置的修改
+func (s *DeployService) Uninstall(req *model.UninstallRequest) ([]model.UninstallResult, error) {
+	if err := s.credentialService.ResolveNodes(req.Nodes); err != nil {
+		return nil, err
+	}
+	return s.k3sService.UninstallNodes(req.Nodes, req.RollbackOnly), nil
+}
+
+// RestartServices 逐个重启各节点的 k3s / k3s-agent 服务
+func (s *DeployService) RestartServices(req *model.NodeServiceRequest) ([]model.NodeServiceResult, error) {
+	if err := s.credentialService.ResolveNodes(req.Nodes); err != nil {
+		return nil, err
+	}
+	return s.k3sService.RestartServices(req.Nodes, waitPolicy(req.Wait)), nil
+}
+
+// ApplyServiceConfig 逐个节点更新 k3s 配置文件并重启服务
+func (s *DeployService) ApplyServiceConfig(req *model.NodeServiceRequest) ([]model.NodeServiceResult, error) {
+	if req.Config == "" && req.Registries == "" {
+		return nil, fmt.Errorf("config 和 registries 至少需要设置一项")
+	}
+	if err := s.credentialService.ResolveNodes(req.Nodes); err != nil {
+		return nil, err
+	}
+	workspaceID := req.Nodes[0].RequestID
+	if workspaceID == "" {
+		id, err := utils.GenerateID("service")
+		if err != nil {
+			return nil, err
+		}
+		workspaceID = id
+	}
+	return s.k3sService.ApplyServiceConfig(req.Nodes, workspaceID, req.Config, req.Registries, waitPolicy(req.Wait)), nil
+}
+
+// ServiceLogs 读取各节点 k3s / k3s-agent 服务最近的日志
+func (s *DeployService) ServiceLogs(req *model.NodeServiceRequest) ([]model.NodeServiceResult, error) {
+	if err := s.credentialService.ResolveNodes(req.Nodes); err != nil {
+		return nil, err
+	}
+	return s.k3sService.ServiceLogs(req.Nodes, req.Lines), nil
+}
+
+func (s *DeployService) checkMirrorsStep(req *model.DeployRequest) error {
+	if req.Airgap != nil {
+		s.logger.Info("离线安装，跳过镜像源检查")
+		return nil
+	}
+	results, err := s.k3sService.CheckMirrors(req.Nodes, req.Mirrors)
+	nodes, err := s.nodeResults(req, "镜像源检查失败", req.Nodes, results, err)
+	if err != nil {
+		return err
+	}
+	recordSucceeded(req, nodes)
+	return nil
 }
 
 func (s *DeployService) installMasterStep(req *model.DeployRequest) error {
+	if joinsServer(req) {
+		s.logger.Infof("节点加入中心 server %s，跳过 Master 安装", req.Edge.ServerURL)
+		return nil
+	}
+
 	// 找到Master节点
 	var masterNode model.NodeConfig
 	for _, node := range req.Nodes {
@@ -77,10 +439,32 @@ func (s *DeployService) installMasterStep(req *model.DeployRequest) error {
 		return fmt.Errorf("未找到Master节点")
 	}
 
-	return s.k3sService.InstallMaster(masterNode)
+	opts, err := s.installOptions(req)
+	if err != nil {
+		return err
+	}
+	digests, err := s.k3sService.InstallMaster(masterNode, waitPolicy(req.Wait), opts)
+	req.Digests = append(req.Digests, artifactDigests(digests)...)
+	if err != nil {
+		return err
+	}
+
+	// 登记集群记录，供后续漂移检测等运维操作使用
+	if _, err := s.clusterService.RegisterDeployed(masterNode); err != nil {
+		s.logger.Warnf("登记集群记录失败: %v", err)
+	} else if req.NodePrep != nil && req.NodePrep.LogRotation != nil {
+		if err := s.clusterService.RecordLogRotation(masterNode.IP, req.NodePrep.LogRotation); err != nil {
+			s.logger.Warnf("记录集群期望日志轮转配置失败: %v", err)
+		}
+	}
+	return nil
 }
 
 func (s *DeployService) configureAgentStep(req *model.DeployRequest) error {
+	if joinsServer(req) {
+		return s.joinAgents(req)
+	}
+
 	// 找到Master节点
 	var masterNode model.NodeConfig
 	for _, node := range req.Nodes {
@@ -94,20 +478,246 @@ func (s *DeployService) configureAgentStep(req *model.DeployRequest) error {
 		return fmt.Errorf("未找到Master节点")
 	}
 
+	opts, err := s.installOptions(req)
+	if err != nil {
+		return err
+	}
+
 	// 配置所有Agent节点，使用索引生成节点名称
 	agentIndex := 0
+	names := clusterNodeNames(req.Nodes)
+	var agents []string
+	var configured []model.NodeConfig
 	for _, node := range req.Nodes {
-		if node.Name != "k3s-master" {
-			if err := s.k3sService.ConfigureAgent(masterNode, node, agentIndex); err != nil {
-				return fmt.Errorf("配置Agent节点 %s 失败: %v", node.Name, err)
+		if node.Name == "k3s-master" {
+			continue
+		}
+		digests, err := s.k3sService.ConfigureAgent(masterNode, node, agentIndex, waitPolicy(req.Wait), opts)
+		req.Digests = append(req.Digests, artifactDigests(digests)...)
+		agentIndex++
+		if err != nil {
+			err = fmt.Errorf("配置Agent节点 %s 失败: %w", node.Name, err)
+			req.NodeResults = append(req.NodeResults, model.NodeResult{Name: node.Name, IP: node.IP, Message: err.Error()})
+			if !req.ContinueOnError {
+				s.recordDegraded(masterNode, req)
+				s.recordAgents(masterNode, configured)
+				return err
 			}
-			agentIndex++
+			s.logger.Warnf("%v，继续配置其余节点", err)
+			continue
+		}
+		req.NodeResults = append(req.NodeResults, model.NodeResult{Name: node.Name, IP: node.IP, Success: true})
+		agents = append(agents, names[node.Name])
+		configured = append(configured, node)
+	}
+	s.recordDegraded(masterNode, req)
+	s.recordAgents(masterNode, configured)
+	if err := partialError(req.NodeResults); err != nil {
+		return err
+	}
+
+	// 替换 CNI 时 Agent 在 CNI Pod 启动后才会 Ready
+	if opts.CNI != nil && len(agents) > 0 {
+		return s.k3sService.WaitCNI(masterNode, opts.CNI, agents, waitPolicy(req.Wait))
+	}
+	return nil
+}
+
+// waitNodesStep 部署应用前确认请求中的每个节点都以期望的名称加入集群并 Ready
+func (s *DeployService) waitNodesStep(req *model.DeployRequest) error {
+	var masterNode model.NodeConfig
+	for _, node := range req.Nodes {
+		if node.Name == "k3s-master" {
+			masterNode = node
+			break
+		}
+	}
+
+	if masterNode.Name == "" {
+		return fmt.Errorf("未找到Master节点")
+	}
+
+	names := clusterNodeNames(req.Nodes)
+	expected := make([]model.NodeReadiness, len(req.Nodes))
+	for i, node := range req.Nodes {
+		expected[i] = model.NodeReadiness{Name: node.Name, IP: node.IP, ClusterName: names[node.Name]}
+	}
+	results, err := s.k3sService.WaitNodesReady(masterNode, expected, waitPolicy(req.Wait))
+	req.Readiness = results
+	return err
+}
+
+// recordDegraded 将配置失败的节点记入集群记录，配置成功的节点从中移除
+func (s *DeployService) recordDegraded(masterNode model.NodeConfig, req *model.DeployRequest) {
+	if err := s.clusterService.RecordDegraded(masterNode.IP, req.Step, req.NodeResults); err != nil {
+		s.logger.Warnf("记录集群 %s 的降级节点失败: %v", masterNode.IP, err)
+	}
+}
+
+// recordAgents 将配置成功的 Agent 节点登记到集群记录，供漂移修复等需要登录各节点的操作使用
+func (s *DeployService) recordAgents(masterNode model.NodeConfig, agents []model.NodeConfig) {
+	if len(agents) == 0 {
+		return
+	}
+	if err := s.clusterService.RecordAgents(masterNode.IP, agents); err != nil {
+		s.logger.Warnf("登记集群 %s 的 Agent 节点失败: %v", masterNode.IP, err)
+	}
+}
+
+// partialError 所有节点都失败时返回错误；部分失败由 ExecuteStep 报告为部分成功
+func partialError(results []model.NodeResult) error {
+	var messages []string
+	for _, r := range results {
+		if r.Success {
+			return nil
+		}
+		messages = append(messages, r.Message)
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("所有节点均失败: %s", strings.Join(messages, "; "))
+	}
+	return nil
+}
+
+// nodeResults 处理逐节点操作的结果，返回成功的节点供同一步骤之后的操作使用。err 为操作整体的错误，直接返回；
+// 未设置 continueOnError 时任一节点失败即返回错误，设置时失败的节点记入 req.NodeResults，
+// Master 失败或所有节点都失败时仍返回错误
+func (s *DeployService) nodeResults(req *model.DeployRequest, action string, nodes []model.NodeConfig, results []model.NodeResult, err error) ([]model.NodeConfig, error) {
+	if err != nil {
+		return nil, err
+	}
+	err = nodesError(action, results)
+	if err == nil {
+		return nodes, nil
+	}
+	if !req.ContinueOnError {
+		return nil, err
+	}
+
+	failed := make(map[string]string)
+	for _, r := range results {
+		if !r.Success {
+			failed[r.IP] = r.Message
 		}
 	}
+	var kept []model.NodeConfig
+	for _, node := range nodes {
+		message, ok := failed[node.IP]
+		if !ok {
+			kept = append(kept, node)
+			continue
+		}
+		if node.Name == "k3s-master" {
+			return nil, fmt.Errorf("%s: Master节点失败，无法继续: %s", action, message)
+		}
+		req.NodeResults = append(req.NodeResults, model.NodeResult{Name: node.Name, IP: node.IP, Message: fmt.Sprintf("%s: %s", action, message)})
+	}
+	if len(kept) == 0 {
+		return nil, partialError(req.NodeResults)
+	}
+	s.logger.Warnf("%v，继续处理其余节点", err)
+	return kept, nil
+}
+
+// recordSucceeded 将完成逐节点操作的节点记入 req.NodeResults，与失败的节点一起在响应中列出
+func recordSucceeded(req *model.DeployRequest, nodes []model.NodeConfig) {
+	for _, node := range nodes {
+		req.NodeResults = append(req.NodeResults, model.NodeResult{Name: node.Name, IP: node.IP, Success: true})
+	}
+}
 
+// droppedRoleError 失败的节点承担 roleAssignment 中的角色时返回错误：
+// 后续步骤不再包含这些节点，对应组件将没有可调度的节点
+func droppedRoleError(roleAssignment map[string]string, failed []model.NodeResult) error {
+	for _, r := range failed {
+		var roles []string
+		for role, node := range roleAssignment {
+			if node == r.Name {
+				roles = append(roles, role)
+			}
+		}
+		if len(roles) > 0 {
+			sort.Strings(roles)
+			return fmt.Errorf("节点 %s 承担组件角色 %s，失败后无法继续部署，请修复节点后重新执行或调整 roleAssignment: %s",
+				r.Name, strings.Join(roles, "、"), r.Message)
+		}
+	}
 	return nil
 }
 
+// failedNodes 部分成功时失败的节点
+func failedNodes(results []model.NodeResult) []model.NodeResult {
+	var failed []model.NodeResult
+	for _, r := range results {
+		if !r.Success {
+			failed = append(failed, r)
+		}
+	}
+	return failed
+}
+
+// joinAgents 将所有节点以 Agent 身份加入 edge.serverUrl 指定的中心 server
+func (s *DeployService) joinAgents(req *model.DeployRequest) error {
+	opts, err := s.installOptions(req)
+	if err != nil {
+		return err
+	}
+	for _, node := range req.Nodes {
+		digests, err := s.k3sService.JoinAgent(node, req.Edge.ServerURL, req.Edge.Token, waitPolicy(req.Wait), opts)
+		req.Digests = append(req.Digests, artifactDigests(digests)...)
+		if err != nil {
+			req.NodeResults = append(req.NodeResults, model.NodeResult{Name: node.Name, IP: node.IP, Message: err.Error()})
+			if !req.ContinueOnError {
+				return err
+			}
+			s.logger.Warnf("%v，继续加入其余节点", err)
+			continue
+		}
+		req.NodeResults = append(req.NodeResults, model.NodeResult{Name: node.Name, IP: node.IP, Success: true})
+	}
+	// 中心 server 不在本服务登记，不记录降级节点
+	return partialError(req.NodeResults)
+}
+
+func (s *DeployService) configureDNSStep(req *model.DeployRequest) error {
+	if req.DNS == nil || req.DNS.CoreDNS == nil {
+		s.logger.Info("未设置 dns.coredns，跳过 CoreDNS 自定义")
+		return nil
+	}
+
+	// 找到Master节点
+	var masterNode model.NodeConfig
+	for _, node := range req.Nodes {
+		if node.Name == "k3s-master" {
+			masterNode = node
+			break
+		}
+	}
+
+	if masterNode.Name == "" {
+		return fmt.Errorf("未找到Master节点")
+	}
+
+	cfg := k3s.CoreDNSConfig{
+		StubDomains: req.DNS.CoreDNS.StubDomains,
+		Hosts:       req.DNS.CoreDNS.Hosts,
+	}
+	artifacts, err := s.k3sService.ConfigureCoreDNS(masterNode, req.WorkspaceID, cfg, waitPolicy(req.Wait))
+	req.Artifacts = append(req.Artifacts, artifacts...)
+	return err
+}
+
+func (s *DeployService) configureStorageStep(req *model.DeployRequest) error {
+	if req.Storage == nil {
+		s.logger.Info("未设置 storage，保留 k3s 默认的 local-path 存储")
+		return nil
+	}
+
+	artifacts, err := s.k3sService.ConfigureStorage(req.Nodes, req.WorkspaceID, req.Storage, waitPolicy(req.Wait))
+	req.Artifacts = append(req.Artifacts, artifacts...)
+	return err
+}
+
 func (s *DeployService) applyLabelsStep(req *model.DeployRequest) error {
 	// 找到Master节点
 	var masterNode model.NodeConfig
@@ -122,7 +732,28 @@ func (s *DeployService) applyLabelsStep(req *model.DeployRequest) error {
 		return fmt.Errorf("未找到Master节点")
 	}
 
-	return s.k3sService.ApplyLabels(masterNode, req.Labels)
+	if err := s.k3sService.ApplyLabels(masterNode, req.Labels); err != nil {
+		return err
+	}
+
+	if err := s.clusterService.RecordLabels(masterNode.IP, req.Labels); err != nil {
+		s.logger.Warnf("记录集群期望标签失败: %v", err)
+	}
+	return nil
+}
+
+func (s *DeployService) prePullImagesStep(req *model.DeployRequest) error {
+	if req.Airgap != nil {
+		s.logger.Info("离线安装，组件镜像已随安装包导入，跳过预拉取")
+		return nil
+	}
+	results, err := s.k3sService.PrePullImages(req.Nodes, req.RoleAssignment)
+	nodes, err := s.nodeResults(req, "预拉取镜像失败", req.Nodes, results, err)
+	if err != nil {
+		return err
+	}
+	recordSucceeded(req, nodes)
+	return nil
 }
 
 func (s *DeployService) deployInSuiteStep(req *model.DeployRequest) error {
@@ -139,10 +770,111 @@ func (s *DeployService) deployInSuiteStep(req *model.DeployRequest) error {
 		return fmt.Errorf("未找到Master节点")
 	}
 
-	return s.k3sService.DeployInSuite(masterNode, req.RoleAssignment)
+	spec, err := appSpec(req)
+	if err != nil {
+		return err
+	}
+	// 集群安装了对象存储时提供给实例
+	objectStore, err := s.clusterService.ObjectStoreByMaster(masterNode.IP)
+	if err != nil {
+		return err
+	}
+	if objectStore != nil {
+		spec.ObjectStorage = instanceObjectStorage(objectStore, spec.Instance)
+		if spec.NetworkPolicy != nil {
+			spec.NetworkPolicy.ObjectStorage = true
+		}
+	}
+	// 数据库备份未指定存储时以实例专用用户写入集群内 MinIO 的 insuite 存储桶中实例的前缀下
+	if db := spec.Database; db != nil && db.Backup != nil && db.Backup.Bucket == "" {
+		if objectStore == nil {
+			return fmt.Errorf("未设置 database.backup.storage，且集群未安装对象存储（可设置 minio 并执行 install-minio）")
+		}
+		store := spec.ObjectStorage
+		db.Backup.Endpoint, db.Backup.Bucket, db.Backup.Prefix = store.Endpoint, store.Bucket, store.Prefix
+		db.Backup.AccessKey, db.Backup.SecretKey = store.AccessKey, store.SecretKey
+	}
+	if spec.Exposure.TLS && spec.Exposure.Issuer == "" {
+		cert, key, err := s.issueIngressCert(spec.Exposure.Hosts)
+		if err != nil {
+			return err
+		}
+		spec.Exposure.TLSCert, spec.Exposure.TLSKey = cert, key
+	}
+
+	if !req.SkipCapacityCheck {
+		report, err := s.k3sService.CheckCapacity(masterNode, spec)
+		if err != nil {
+			return err
+		}
+		if !report.Fits {
+			return fmt.Errorf("节点容量不足，组件将无法调度（可设置 skipCapacityCheck 跳过检查）:%s", k3s.FormatCapacityReport(report))
+		}
+		s.logger.Infof("节点容量检查通过:%s", k3s.FormatCapacityReport(report))
+	}
+
+	artifacts, url, err := s.k3sService.DeployInSuite(masterNode, req.WorkspaceID, req.RoleAssignment, waitPolicy(req.Wait), spec)
+	req.Artifacts = append(req.Artifacts, artifacts...)
+	req.AccessURL = url
+	if err != nil {
+		return err
+	}
+
+	databaseImage := k3s.DatabaseImage
+	if spec.Database != nil {
+		databaseImage = spec.Database.Image
+	}
+	release := model.Release{
+		Name:       spec.Instance,
+		Namespace:  spec.Namespace,
+		Images:     []string{databaseImage, k3s.MiddlewareImage, k3s.AppImage},
+		Exposure:   spec.Exposure.Type,
+		URL:        url,
+		DeployID:   req.WorkspaceID,
+		DeployedAt: time.Now(),
+	}
+	if req.Instance != nil {
+		release.Quota = req.Instance.Quota
+		release.LimitRange = req.Instance.LimitRange
+	}
+	if err := s.clusterService.RecordRelease(masterNode.IP, release); err != nil {
+		s.logger.Warnf("记录实例版本失败: %v", err)
+	}
+	return nil
+}
+
+// verifyNetwork 执行集群网络检查，任一项失败时返回包含全部失败项的错误
+func (s *DeployService) verifyNetwork(req *model.DeployRequest, masterNode model.NodeConfig) error {
+	opts := req.NetworkCheck
+	if opts == nil {
+		opts = &model.NetworkCheckOptions{}
+	}
+	if opts.Disabled {
+		return nil
+	}
+	if err := k3s.ValidateExternalName(opts.ExternalName); err != nil {
+		return err
+	}
+
+	checks, err := s.k3sService.CheckNetwork(masterNode, req.WorkspaceID, opts.ExternalName, req.NetworkPolicy != nil, waitPolicy(req.Wait))
+	if err != nil {
+		return fmt.Errorf("集群网络检查失败: %v", err)
+	}
+	var failed []string
+	for _, check := range checks {
+		if !check.Passed {
+			failed = append(failed, fmt.Sprintf("- %s %s -> %s: %s", check.Name, check.From, check.Target, check.Message))
+		}
+	}
+	if len(failed) > 0 {
+		return fmt.Errorf("集群网络检查有 %d 项未通过:\n%s", len(failed), strings.Join(failed, "\n"))
+	}
+	return nil
 }
 
 func (s *DeployService) verifyStep(req *model.DeployRequest) error {
+	defer benchmark.Track(req.RequestID, "", model.PhaseVerify)()
+	defer progress.Phase(req.RequestID, "", model.PhaseVerify)()
 	// 找到Master节点
 	var masterNode model.NodeConfig
 	for _, node := range req.Nodes {
@@ -156,5 +888,150 @@ func (s *DeployService) verifyStep(req *model.DeployRequest) error {
 		return fmt.Errorf("未找到Master节点")
 	}
 
-	return s.k3sService.VerifyDeployment(masterNode)
+	spec, err := appSpec(req)
+	if err != nil {
+		return err
+	}
+	if err := s.k3sService.VerifyDeployment(masterNode, spec.Namespace); err != nil {
+		return err
+	}
+	if err := s.verifyNetwork(req, masterNode); err != nil {
+		return err
+	}
+	if err := s.verifySecretsEncryption(req, masterNode); err != nil {
+		return err
+	}
+
+	url, err := s.k3sService.AccessURL(masterNode, spec, waitPolicy(req.Wait))
+	if err != nil {
+		return err
+	}
+	s.logger.Infof("inSuite 访问地址: %s", url)
+	req.AccessURL = url
+	return nil
+}
+
+// waitPolicy 将请求中的等待参数（秒）转换为等待策略，未设置的字段使用默认值
+func waitPolicy(opts *model.WaitOptions) k3s.WaitPolicy {
+	var policy k3s.WaitPolicy
+	if opts != nil {
+		policy.ServiceTimeout = time.Duration(opts.ServiceTimeout) * time.Second
+		policy.DeploymentTimeout = time.Duration(opts.DeploymentTimeout) * time.Second
+		policy.PollInterval = time.Duration(opts.PollInterval) * time.Second
+		policy.InstallTimeout = time.Duration(opts.InstallTimeout) * time.Second
+		policy.ScriptDownloadTimeout = time.Duration(opts.ScriptDownloadTimeout) * time.Second
+	}
+	return policy.WithDefaults()
+}
+
+// artifactDigests 转换安装过程中记录的文件摘要
+func artifactDigests(digests []k3s.Digest) []model.ArtifactDigest {
+	result := make([]model.ArtifactDigest, 0, len(digests))
+	for _, d := range digests {
+		result = append(result, model.ArtifactDigest{
+			Node:     d.Node,
+			Name:     d.Name,
+			SHA256:   d.SHA256,
+			Source:   d.Source,
+			Verified: d.Verified,
+		})
+	}
+	return result
+}
+
+// RetainBundles 在任务开始时调用，任务的各步骤复用首次校验的离线安装包，直到 ReleaseBundles
+func (s *DeployService) RetainBundles(requestID string) {
+	s.airgapMu.Lock()
+	defer s.airgapMu.Unlock()
+	if s.airgap == nil {
+		s.airgap = make(map[string]map[string]*bundle.Bundle)
+	}
+	s.airgap[requestID] = make(map[string]*bundle.Bundle)
+}
+
+// ReleaseBundles 任务结束时丢弃已校验的离线安装包
+func (s *DeployService) ReleaseBundles(requestID string) {
+	s.airgapMu.Lock()
+	defer s.airgapMu.Unlock()
+	delete(s.airgap, requestID)
+}
+
+// openBundle 打开并校验离线安装包；任务内已校验过的直接返回，不在任务中执行的步骤每次都校验
+func (s *DeployService) openBundle(requestID, name string) (*bundle.Bundle, error) {
+	s.airgapMu.Lock()
+	opened, retained := s.airgap[requestID]
+	b := opened[name]
+	s.airgapMu.Unlock()
+	if b != nil {
+		return b, nil
+	}
+
+	b, err := s.bundles.Open(name)
+	if err != nil {
+		return nil, err
+	}
+	if retained {
+		s.airgapMu.Lock()
+		if opened, ok := s.airgap[requestID]; ok {
+			opened[name] = b
+		}
+		s.airgapMu.Unlock()
+	}
+	return b, nil
+}
+
+// installOptions 汇总请求中影响 k3s 安装参数的选项，离线安装时打开并校验安装包
+func (s *DeployService) installOptions(req *model.DeployRequest) (k3s.InstallOptions, error) {
+	var opts k3s.InstallOptions
+	if req.Airgap != nil {
+		b, err := s.openBundle(req.RequestID, req.Airgap.Bundle)
+		if err != nil {
+			return opts, err
+		}
+		opts.Airgap = b
+	}
+	opts.AllowUnverified = req.AllowUnverifiedArtifacts
+	opts.Mirrors = req.Mirrors
+	opts.Proxy = req.Proxy
+	opts.Security = k3s.CISSecurity(req.Security)
+	if req.CNI != nil {
+		if err := k3s.ValidateCNI(req.CNI); err != nil {
+			return opts, err
+		}
+		if req.Airgap != nil {
+			return opts, fmt.Errorf("离线安装不支持替换 CNI：%s 的 chart 和镜像需要在线获取", req.CNI.Plugin)
+		}
+		opts.CNI = req.CNI
+	}
+	if req.Runtime != nil {
+		opts.Docker = req.Runtime.Docker
+	}
+	if req.InstallScript != nil {
+		opts.Script = &k3s.ScriptSource{
+			URL:             req.InstallScript.URL,
+			Content:         req.InstallScript.Content,
+			SHA256:          req.InstallScript.SHA256,
+			PatchRegistry:   req.InstallScript.ApplyRegistryPatch,
+			PatchCertConfig: req.InstallScript.ApplyCertPatch,
+		}
+	}
+	if req.DNS != nil && len(k3sUpstreams(req.DNS)) > 0 {
+		opts.ResolvConf = true
+	}
+	if req.Profile == model.ProfileEdge {
+		var disable []string
+		if req.Edge != nil {
+			disable = req.Edge.Disable
+			opts.Hardened = req.Edge.AutoRestart
+		}
+		opts.ServerArgs, opts.ExtraArgs = k3s.EdgeArgs(disable)
+	}
+	serverArgs, extraArgs, err := k3s.ComponentArgs(req.ComponentArgs)
+	if err != nil {
+		return opts, err
+	}
+	opts.ServerArgs = append(opts.ServerArgs, serverArgs...)
+	opts.ServerArgs = append(opts.ServerArgs, k3s.CNIServerArgs(req.CNI)...)
+	opts.ExtraArgs = append(opts.ExtraArgs, extraArgs...)
+	return opts, nil
 }
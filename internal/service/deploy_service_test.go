@@ -0,0 +1,183 @@
+package service_test
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/service"
+	"k3s-deploy-backend/internal/service/servicetest"
+)
+
+type deployFixture struct {
+	k3s      *servicetest.K3s
+	clusters *servicetest.Clusters
+	deploy   *service.DeployService
+}
+
+func newDeployFixture() *deployFixture {
+	f := &deployFixture{k3s: servicetest.NewK3s(), clusters: servicetest.NewClusters()}
+	f.deploy = service.NewDeployService(f.k3s, servicetest.NewCredentials(), f.clusters, service.IngressCAConfig{}, nil, logger.NewLogger())
+	return f
+}
+
+func deployRequest(step string, nodes ...string) *model.DeployRequest {
+	req := &model.DeployRequest{Step: step, RequestID: "req-test"}
+	for i, name := range nodes {
+		req.Nodes = append(req.Nodes, model.NodeConfig{
+			Name:     name,
+			IP:       fmt.Sprintf("10.0.0.%d", i+1),
+			Port:     22,
+			Username: "root",
+			AuthType: "password",
+			Password: "secret",
+		})
+	}
+	return req
+}
+
+func TestInstallMasterSelectsMasterNode(t *testing.T) {
+	f := newDeployFixture()
+	req := deployRequest("install-master", "node-a", "k3s-master", "node-b")
+
+	resp := f.deploy.ExecuteStep(req)
+	if !resp.Success {
+		t.Fatalf("install-master 失败: %s", resp.Message)
+	}
+	calls := f.k3s.Calls("InstallMaster")
+	if len(calls) != 1 {
+		t.Fatalf("InstallMaster 调用 %d 次，期望 1 次", len(calls))
+	}
+	if node := calls[0].Args[0].(model.NodeConfig); node.Name != "k3s-master" || node.IP != "10.0.0.2" {
+		t.Errorf("InstallMaster 的节点为 %s(%s)，期望 k3s-master(10.0.0.2)", node.Name, node.IP)
+	}
+	if cluster, _ := f.clusters.FindByMaster("10.0.0.2"); cluster == nil {
+		t.Error("安装完成后未登记集群")
+	}
+}
+
+func TestInstallMasterWithoutMaster(t *testing.T) {
+	f := newDeployFixture()
+	req := deployRequest("install-master", "node-a", "node-b")
+
+	resp := f.deploy.ExecuteStep(req)
+	if resp.Success || !strings.Contains(resp.Message, "未找到Master节点") {
+		t.Fatalf("缺少 Master 时的结果为 %v %q", resp.Success, resp.Message)
+	}
+	if calls := f.k3s.Calls("InstallMaster"); len(calls) != 0 {
+		t.Errorf("缺少 Master 时调用了 InstallMaster %d 次", len(calls))
+	}
+}
+
+func TestInstallMasterFailure(t *testing.T) {
+	f := newDeployFixture()
+	f.k3s.Fail("InstallMaster", errors.New("安装脚本退出码 1"))
+	req := deployRequest("install-master", "k3s-master")
+
+	resp := f.deploy.ExecuteStep(req)
+	if resp.Success || resp.Failure == nil {
+		t.Fatalf("安装失败时的结果为 %v，失败分类 %v", resp.Success, resp.Failure)
+	}
+	if calls := f.clusters.Calls("RegisterDeployed"); len(calls) != 0 {
+		t.Error("安装失败时不应登记集群")
+	}
+}
+
+func TestConfigureAgentIteratesAgents(t *testing.T) {
+	f := newDeployFixture()
+	req := deployRequest("configure-agent", "k3s-master", "node-a", "node-b", "node-c")
+
+	resp := f.deploy.ExecuteStep(req)
+	if !resp.Success || resp.Partial {
+		t.Fatalf("configure-agent 结果为 %v（部分成功 %v）: %s", resp.Success, resp.Partial, resp.Message)
+	}
+	calls := f.k3s.Calls("ConfigureAgent")
+	if len(calls) != 3 {
+		t.Fatalf("ConfigureAgent 调用 %d 次，期望 3 次", len(calls))
+	}
+	for i, call := range calls {
+		master := call.Args[0].(model.NodeConfig)
+		agent := call.Args[1].(model.NodeConfig)
+		index := call.Args[2].(int)
+		if master.Name != "k3s-master" {
+			t.Errorf("第 %d 次调用的 Master 为 %s", i, master.Name)
+		}
+		if agent.Name != req.Nodes[i+1].Name || index != i {
+			t.Errorf("第 %d 次调用配置 %s（序号 %d），期望 %s（序号 %d）", i, agent.Name, index, req.Nodes[i+1].Name, i)
+		}
+	}
+	if len(resp.Nodes) != 3 || len(failed(resp.Nodes)) != 0 {
+		t.Errorf("节点结果 %+v，期望 3 个成功", resp.Nodes)
+	}
+}
+
+func TestConfigureAgentStopsOnError(t *testing.T) {
+	f := newDeployFixture()
+	f.k3s.FailTimes("ConfigureAgent", errors.New("连接超时"), 1)
+	req := deployRequest("configure-agent", "k3s-master", "node-a", "node-b")
+
+	resp := f.deploy.ExecuteStep(req)
+	if resp.Success {
+		t.Fatal("Agent 失败且未设置 continueOnError 时步骤应失败")
+	}
+	if calls := f.k3s.Calls("ConfigureAgent"); len(calls) != 1 {
+		t.Errorf("ConfigureAgent 调用 %d 次，期望失败后停止", len(calls))
+	}
+	if !strings.Contains(resp.Message, "node-a") {
+		t.Errorf("错误信息未包含失败的节点: %s", resp.Message)
+	}
+	if calls := f.clusters.Calls("RecordDegraded"); len(calls) != 1 {
+		t.Errorf("RecordDegraded 调用 %d 次，期望 1 次", len(calls))
+	}
+}
+
+func TestConfigureAgentContinueOnError(t *testing.T) {
+	f := newDeployFixture()
+	f.k3s.FailTimes("ConfigureAgent", errors.New("连接超时"), 1)
+	req := deployRequest("configure-agent", "k3s-master", "node-a", "node-b")
+	req.ContinueOnError = true
+
+	resp := f.deploy.ExecuteStep(req)
+	if !resp.Success || !resp.Partial {
+		t.Fatalf("continueOnError 时结果为 %v（部分成功 %v）: %s", resp.Success, resp.Partial, resp.Message)
+	}
+	if calls := f.k3s.Calls("ConfigureAgent"); len(calls) != 2 {
+		t.Errorf("ConfigureAgent 调用 %d 次，期望 2 次", len(calls))
+	}
+	if bad := failed(resp.Nodes); len(bad) != 1 || bad[0].Name != "node-a" {
+		t.Errorf("失败节点 %+v，期望 node-a", bad)
+	}
+}
+
+func TestConfigureAgentAllFailed(t *testing.T) {
+	f := newDeployFixture()
+	f.k3s.Fail("ConfigureAgent", errors.New("连接超时"))
+	req := deployRequest("configure-agent", "k3s-master", "node-a", "node-b")
+	req.ContinueOnError = true
+
+	resp := f.deploy.ExecuteStep(req)
+	if resp.Success || !strings.Contains(resp.Message, "所有节点均失败") {
+		t.Fatalf("全部 Agent 失败时结果为 %v %q", resp.Success, resp.Message)
+	}
+}
+
+func TestUnknownStep(t *testing.T) {
+	f := newDeployFixture()
+	resp := f.deploy.ExecuteStep(deployRequest("no-such-step", "k3s-master"))
+	if resp.Success || len(f.k3s.Calls("")) != 0 {
+		t.Fatalf("未知步骤结果为 %v，调用 %v", resp.Success, f.k3s.Methods())
+	}
+}
+
+func failed(results []model.NodeResult) []model.NodeResult {
+	var bad []model.NodeResult
+	for _, r := range results {
+		if !r.Success {
+			bad = append(bad, r)
+		}
+	}
+	return bad
+}
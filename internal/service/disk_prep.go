@@ -0,0 +1,253 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+const (
+	// k3sDataDir 数据盘的默认挂载点
+	k3sDataDir = "/var/lib/rancher/k3s"
+	// dataDiskLabel 格式化时写入的文件系统卷标，重复执行时据此识别已准备好的数据盘
+	dataDiskLabel = "k3s-data"
+	// fstabMarker 部署写入的 fstab 条目以此结尾
+	fstabMarker = "# k3s-deploy-backend"
+	// swapFstabBackup 预检关闭 swap 时从 fstab 删除的条目，卸载时追加回 fstab
+	swapFstabBackup = "/etc/fstab.k3s-deploy.swap"
+	// removeDataDirLinkScript 删除预检创建的指向大分区的数据目录软链接
+	removeDataDirLinkScript = "if [ -L " + k3sDataDir + " ]; then rm -f " + k3sDataDir + "; fi"
+)
+
+// removeDataDirScript 删除预检在大分区上新建的数据目录 dir：只删除该路径本身，
+// 路径已被替换为软链接、本身或其下仍有挂载点时跳过，不会跟随到其他目录
+func removeDataDirScript(dir string) string {
+	return fmt.Sprintf(`d=%s; if [ -d "$d" ] && [ ! -L "$d" ] && ! awk -v d="$d" '$2 == d || index($2, d "/") == 1 { found = 1 } END { exit !found }' /proc/mounts; then rm -rf -- "$d"; fi`, ssh.Quote(dir))
+}
+
+var (
+	devicePattern     = regexp.MustCompile(`^/dev/[A-Za-z0-9/_-]+$`)
+	mountPointPattern = regexp.MustCompile(`^(/[A-Za-z0-9._-]+)+$`)
+	// systemMountPoints 不允许作为数据盘挂载点的系统目录
+	systemMountPoints = map[string]bool{"/boot": true, "/etc": true, "/usr": true, "/var": true, "/var/lib": true, "/root": true, "/home": true, "/opt": true, "/tmp": true}
+)
+
+// validateDiskPrep 校验数据盘参数并补全默认值，格式化必须显式确认
+func validateDiskPrep(opts *model.DiskPrepOptions, nodes []model.NodeConfig) error {
+	if !opts.ConfirmFormat {
+		return fmt.Errorf("diskPrep 会格式化 %d 块磁盘，必须设置 confirmFormat 为 true", len(opts.Disks))
+	}
+	if opts.MountPoint == "" {
+		opts.MountPoint = k3sDataDir
+	}
+	if opts.Filesystem == "" {
+		opts.Filesystem = "ext4"
+	}
+	if !mountPointPattern.MatchString(opts.MountPoint) || systemMountPoints[opts.MountPoint] {
+		return fmt.Errorf("无效的数据盘挂载点: %s", opts.MountPoint)
+	}
+
+	known := make(map[string]bool, len(nodes))
+	for _, node := range nodes {
+		known[node.Name] = true
+	}
+	for name, device := range opts.Disks {
+		if !known[name] {
+			return fmt.Errorf("diskPrep 中的节点 %s 不在部署节点中", name)
+		}
+		if !devicePattern.MatchString(device) {
+			return fmt.Errorf("节点 %s 的数据盘设备无效: %s", name, device)
+		}
+	}
+	return nil
+}
+
+// mountsDataDir 节点的 k3s 数据目录是否由 prepare-disks 挂载数据盘，是则预检不再创建指向大分区的软链接
+func mountsDataDir(opts *model.DiskPrepOptions, nodeName string) bool {
+	if opts == nil || opts.Disks[nodeName] == "" {
+		return false
+	}
+	return opts.MountPoint == "" || opts.MountPoint == k3sDataDir
+}
+
+// PrepareDisks 并行在各节点格式化并挂载指定的数据盘，写入 fstab 使重启后自动挂载。
+// 只格式化没有分区和文件系统签名的磁盘；带 k3s-data 卷标的磁盘视为已准备，只补齐挂载和 fstab。
+// 返回指定了数据盘的节点的结果
+func (s *K3sService) PrepareDisks(nodes []model.NodeConfig, opts *model.DiskPrepOptions) ([]model.NodeResult, error) {
+	s.logger.DeploymentStep("prepare-disks", "cluster")
+
+	if err := validateDiskPrep(opts, nodes); err != nil {
+		return nil, err
+	}
+
+	var targets []model.NodeConfig
+	for _, node := range nodes {
+		if opts.Disks[node.Name] != "" {
+			targets = append(targets, node)
+		}
+	}
+	return runOnNodes(targets, func(i int, client *ssh.Client) error {
+		name := targets[i].Name
+		if err := s.prepareDisk(client, name, opts.Disks[name], opts); err != nil {
+			return fmt.Errorf("节点 %s %v", name, err)
+		}
+		return nil
+	}), nil
+}
+
+func (s *K3sService) prepareDisk(client *ssh.Client, nodeName, device string, opts *model.DiskPrepOptions) error {
+	mountPoint := opts.MountPoint
+
+	result, err := client.ExecuteIdempotentCommand("lsblk -dn -o TYPE " + device)
+	if err != nil {
+		return fmt.Errorf("设备 %s 不存在: %v", device, err)
+	}
+	if devType := strings.TrimSpace(result.Stdout); devType != "disk" && devType != "part" {
+		return fmt.Errorf("%s 类型为 %s，只能使用磁盘或分区", device, devType)
+	}
+	result, err = client.ExecuteIdempotentCommand("lsblk -n -o NAME " + device + " | wc -l")
+	if err != nil {
+		return fmt.Errorf("读取 %s 分区信息失败: %v", device, err)
+	}
+	if strings.TrimSpace(result.Stdout) != "1" {
+		return fmt.Errorf("%s 上已有分区，为避免数据丢失不格式化", device)
+	}
+
+	fs, err := blockInfo(client, device)
+	if err != nil {
+		return err
+	}
+	mounted, err := client.ExecuteIdempotentCommand("lsblk -n -o MOUNTPOINT " + device)
+	if err != nil {
+		return fmt.Errorf("读取 %s 挂载状态失败: %v", device, err)
+	}
+	if current := strings.TrimSpace(mounted.Stdout); current != "" && (current != mountPoint || fs["LABEL"] != dataDiskLabel) {
+		return fmt.Errorf("%s 已挂载在 %s", device, current)
+	}
+
+	switch {
+	case fs["TYPE"] == "":
+		if err := s.formatDisk(client, nodeName, device, opts.Filesystem); err != nil {
+			return err
+		}
+		if fs, err = blockInfo(client, device); err != nil {
+			return err
+		}
+	case fs["LABEL"] == dataDiskLabel:
+		s.logger.Infof("节点 %s %s 已格式化为 %s（卷标 %s），跳过格式化", nodeName, device, fs["TYPE"], dataDiskLabel)
+	default:
+		return fmt.Errorf("%s 上已有 %s 文件系统，为避免数据丢失不格式化（确认不再需要时先执行 wipefs -a %s）", device, fs["TYPE"], device)
+	}
+	if fs["UUID"] == "" {
+		return fmt.Errorf("无法读取 %s 的文件系统 UUID", device)
+	}
+
+	// 挂载点为软链接（预检指向大分区）或已有数据时不覆盖
+	check := fmt.Sprintf(`if [ -L %[1]s ]; then echo symlink; elif mountpoint -q %[1]s; then echo mounted; elif [ -d %[1]s ] && [ -n "$(ls -A %[1]s)" ]; then echo nonempty; fi`, mountPoint)
+	if result, err = client.ExecuteIdempotentCommand(check); err != nil {
+		return fmt.Errorf("检查挂载点 %s 失败: %v", mountPoint, err)
+	}
+	switch state := strings.TrimSpace(result.Stdout); {
+	case state == "symlink":
+		return fmt.Errorf("挂载点 %s 是软链接，请先删除后重试", mountPoint)
+	case state == "nonempty":
+		return fmt.Errorf("挂载点 %s 不为空，为避免覆盖现有数据不挂载", mountPoint)
+	case state == "mounted" && strings.TrimSpace(mounted.Stdout) != mountPoint:
+		return fmt.Errorf("挂载点 %s 已挂载其他设备", mountPoint)
+	}
+
+	if err := s.writeFstab(client, mountPoint, fs["UUID"], fs["TYPE"]); err != nil {
+		return err
+	}
+	s.journal.record(client, nodeName, model.ChangeFstab, fmt.Sprintf("挂载数据盘 %s 到 %s 并写入 /etc/fstab", device, mountPoint),
+		fmt.Sprintf(`umount %[1]s 2>/dev/null; awk '!($2 == "%[1]s" && $0 ~ /%[2]s$/)' /etc/fstab > /etc/fstab.k3s-deploy.tmp && cat /etc/fstab.k3s-deploy.tmp > /etc/fstab && rm -f /etc/fstab.k3s-deploy.tmp`, mountPoint, fstabMarker))
+
+	if strings.TrimSpace(mounted.Stdout) == "" {
+		cmd := fmt.Sprintf("mkdir -p %[1]s && (systemctl daemon-reload 2>/dev/null || true) && mount %[1]s", mountPoint)
+		if _, err := client.ExecuteCommand(cmd); err != nil {
+			return fmt.Errorf("挂载 %s 到 %s 失败: %v", device, mountPoint, err)
+		}
+	}
+	result, err = client.ExecuteIdempotentCommand("findmnt -n -o UUID " + mountPoint)
+	if err != nil || strings.TrimSpace(result.Stdout) != fs["UUID"] {
+		return fmt.Errorf("%s 挂载后校验失败", mountPoint)
+	}
+	s.logger.Infof("节点 %s 数据盘 %s（%s，UUID %s）已挂载到 %s", nodeName, device, fs["TYPE"], fs["UUID"], mountPoint)
+	return nil
+}
+
+// formatDisk 格式化空白磁盘并写入 k3s-data 卷标，缺少 mkfs 工具时自动安装
+func (s *K3sService) formatDisk(client *ssh.Client, nodeName, device, filesystem string) error {
+	p := hostos.FilesystemPrerequisites[filesystem]
+	if _, err := client.ExecuteIdempotentCommand(p.Check); err != nil {
+		osInfo, err := hostos.Detect(client)
+		if err != nil {
+			return err
+		}
+		pkg, ok := osInfo.PackageFor(p)
+		if !ok {
+			return fmt.Errorf("缺少 %s，请手动安装", p.Name)
+		}
+		install, err := osInfo.InstallCommand(pkg)
+		if err != nil {
+			return err
+		}
+		if _, err := client.ExecuteCommand(install); err != nil {
+			return fmt.Errorf("安装 %s 失败: %v", pkg, err)
+		}
+	}
+
+	force := "-F"
+	if filesystem == "xfs" {
+		force = "-f"
+	}
+	s.logger.Warnf("节点 %s 格式化 %s 为 %s", nodeName, device, filesystem)
+	if _, err := client.ExecuteCommand(fmt.Sprintf("mkfs.%s %s -L %s %s", filesystem, force, dataDiskLabel, device)); err != nil {
+		return fmt.Errorf("格式化 %s 失败: %v", device, err)
+	}
+	return nil
+}
+
+// writeFstab 写入以 UUID 挂载的 fstab 条目（nofail，磁盘缺失时不阻塞开机），替换之前部署写入的同一挂载点条目。
+// fstab 中已有其他挂载该目录的条目时失败
+func (s *K3sService) writeFstab(client *ssh.Client, mountPoint, uuid, fsType string) error {
+	result, err := client.ExecuteIdempotentCommand(fmt.Sprintf(`awk '$1 !~ /^#/ && $2 == "%s" && $0 !~ /%s$/' /etc/fstab`, mountPoint, fstabMarker))
+	if err != nil {
+		return fmt.Errorf("读取 /etc/fstab 失败: %v", err)
+	}
+	if existing := strings.TrimSpace(result.Stdout); existing != "" {
+		return fmt.Errorf("/etc/fstab 中已有挂载 %s 的条目: %s", mountPoint, existing)
+	}
+
+	entry := fmt.Sprintf("UUID=%s %s %s defaults,nofail 0 2 %s", uuid, mountPoint, fsType, fstabMarker)
+	cmd := fmt.Sprintf(`[ -e /etc/fstab.k3s-deploy.bak ] || cp /etc/fstab /etc/fstab.k3s-deploy.bak; `+
+		`awk '!($2 == "%[1]s" && $0 ~ /%[2]s$/)' /etc/fstab > /etc/fstab.k3s-deploy.tmp && echo '%[3]s' >> /etc/fstab.k3s-deploy.tmp && `+
+		`cat /etc/fstab.k3s-deploy.tmp > /etc/fstab && rm -f /etc/fstab.k3s-deploy.tmp`, mountPoint, fstabMarker, entry)
+	if _, err := client.ExecuteCommand(cmd); err != nil {
+		return fmt.Errorf("写入 /etc/fstab 失败: %v", err)
+	}
+	return nil
+}
+
+// blockInfo 读取设备的文件系统签名（TYPE、LABEL、UUID），空白设备返回空值
+func blockInfo(client *ssh.Client, device string) (map[string]string, error) {
+	result, err := client.ExecuteIdempotentCommand("blkid -p -o export " + device + " || true")
+	if err != nil {
+		return nil, fmt.Errorf("读取 %s 文件系统信息失败: %v", device, err)
+	}
+	info := make(map[string]string)
+	for _, line := range strings.Split(result.Stdout, "\n") {
+		if key, value, ok := strings.Cut(strings.TrimSpace(line), "="); ok {
+			info[key] = value
+		}
+	}
+	// blkid -p 对分区表报告 PTTYPE，视为已有数据
+	if info["TYPE"] == "" && info["PTTYPE"] != "" {
+		info["TYPE"] = "分区表 " + info["PTTYPE"]
+	}
+	return info, nil
+}
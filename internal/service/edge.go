@@ -0,0 +1,86 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+)
+
+// disableableComponents k3s --disable 支持的内置组件
+var disableableComponents = []string{"coredns", "servicelb", "traefik", "local-storage", "metrics-server", "runtimes"}
+
+// validateEdge 检查 edge 配置档的选项及其与访问方式的组合
+func validateEdge(req *model.DeployRequest) error {
+	if req.Profile != model.ProfileEdge {
+		if req.Edge != nil {
+			return fmt.Errorf("edge 选项仅适用于 edge 配置档")
+		}
+		return nil
+	}
+
+	disabled := k3s.EdgeDisabledComponents
+	if req.Edge != nil && len(req.Edge.Disable) > 0 {
+		disabled = req.Edge.Disable
+	}
+	for _, component := range disabled {
+		if !slices.Contains(disableableComponents, component) {
+			return fmt.Errorf("不支持禁用组件 %s（可选: %v）", component, disableableComponents)
+		}
+	}
+	if req.Exposure != nil {
+		if req.Exposure.Type == k3s.ExposeIngress && slices.Contains(disabled, "traefik") {
+			return fmt.Errorf("ingress 方式依赖 traefik，edge 配置档需在 edge.disable 中保留 traefik")
+		}
+		if req.Exposure.Type == k3s.ExposeLoadBalancer && slices.Contains(disabled, "servicelb") {
+			return fmt.Errorf("loadbalancer 方式依赖 servicelb，edge 配置档需在 edge.disable 中保留 servicelb")
+		}
+	}
+
+	if !joinsServer(req) {
+		return nil
+	}
+	if err := k3s.ValidateServerURL(req.Edge.ServerURL); err != nil {
+		return err
+	}
+	if req.Edge.Token == "" {
+		return fmt.Errorf("加入中心 server 需要设置 edge.token")
+	}
+	for _, node := range req.Nodes {
+		if node.Name == "k3s-master" {
+			return fmt.Errorf("加入中心 server 时所有节点均为 Agent，不能包含 k3s-master 节点")
+		}
+	}
+	return nil
+}
+
+// ResolveJoinBundle 校验 edge.joinBundle 并用其中的 server 地址和 token 填写 edge.serverUrl、edge.token，
+// 之后清空 joinBundle：任务在提交时校验，排队期间加入信息包过期不影响执行
+func (s *DeployService) ResolveJoinBundle(req *model.DeployRequest) error {
+	if req.Edge == nil || req.Edge.JoinBundle == "" {
+		return nil
+	}
+	if s.joinBundles == nil {
+		return fmt.Errorf("后端未启用加入信息包")
+	}
+	if req.Edge.ServerURL != "" || req.Edge.Token != "" {
+		return fmt.Errorf("edge.joinBundle 不能与 edge.serverUrl、edge.token 同时设置")
+	}
+	bundle, err := s.joinBundles.Open(req.Edge.JoinBundle)
+	if err != nil {
+		return err
+	}
+	req.Edge.ServerURL = bundle.ServerURL
+	req.Edge.Token = bundle.Token
+	req.Edge.JoinBundle = ""
+	s.logger.WithField("requestId", req.RequestID).Infof("使用集群 %s 的加入信息包，server %s，有效期至 %s",
+		bundle.ClusterName, bundle.ServerURL, bundle.ExpiresAt.Format(time.RFC3339))
+	return nil
+}
+
+// joinsServer 请求是否将所有节点以 Agent 身份加入外部的中心 server
+func joinsServer(req *model.DeployRequest) bool {
+	return req.Profile == model.ProfileEdge && req.Edge != nil && req.Edge.ServerURL != ""
+}
@@ -0,0 +1,172 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/store"
+	"k3s-deploy-backend/pkg/utils"
+)
+
+// eventLease 多副本部署时同一周期只由一个副本收集事件
+const eventLease = "events"
+
+// maxClusterEvents 每个集群最多保留的事件数，超出时丢弃最旧的
+const maxClusterEvents = 1000
+
+// EventOptions 事件收集参数
+type EventOptions struct {
+	Interval time.Duration
+	// Retention 超过该时长未再出现的事件被丢弃
+	Retention time.Duration
+}
+
+// EventService 定期从受管集群收集 Kubernetes 事件并保存最近的记录。
+// API Server 默认只保留 1 小时事件，部署步骤超时后往往已经查不到原因，因此在本地留存一份
+type EventService struct {
+	opts           EventOptions
+	store          store.Store
+	clusterService *ClusterService
+	k3sService     *K3sService
+	logger         *logger.Logger
+	owner          string
+}
+
+func NewEventService(opts EventOptions, st store.Store, clusterService *ClusterService, k3sService *K3sService, logger *logger.Logger) *EventService {
+	hostname, _ := os.Hostname()
+	owner, err := utils.GenerateID(hostname)
+	if err != nil {
+		owner = fmt.Sprintf("%s-%d", hostname, time.Now().UnixNano())
+	}
+
+	return &EventService{
+		opts:           opts,
+		store:          st,
+		clusterService: clusterService,
+		k3sService:     k3sService,
+		logger:         logger,
+		owner:          owner,
+	}
+}
+
+// Start 按固定周期收集事件
+func (s *EventService) Start() {
+	s.logger.Infof("集群事件收集已启用，周期 %s，保留 %s", s.opts.Interval, s.opts.Retention)
+	go func() {
+		ticker := time.NewTicker(s.opts.Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			acquired, err := s.store.AcquireLease(eventLease, s.owner, s.opts.Interval-time.Second)
+			if err != nil {
+				s.logger.Errorf("获取事件收集租约失败: %v", err)
+				continue
+			}
+			if !acquired {
+				continue
+			}
+			if err := s.Collect(); err != nil {
+				s.logger.Errorf("集群事件收集失败: %v", err)
+			}
+		}
+	}()
+}
+
+// Collect 收集所有受管集群的事件，单个集群失败不影响其他集群
+func (s *EventService) Collect() error {
+	clusters, err := s.clusterService.List()
+	if err != nil {
+		return err
+	}
+	for _, cluster := range clusters {
+		if _, err := s.collect(cluster); err != nil {
+			s.logger.Warnf("收集集群 %s 事件失败: %v", cluster.Name, err)
+		}
+	}
+	return nil
+}
+
+// CollectCluster 立即收集指定集群的事件
+func (s *EventService) CollectCluster(clusterID string) (*model.ClusterEvents, error) {
+	cluster, err := s.clusterService.Get(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	return s.collect(cluster)
+}
+
+// collect 将本次读取的事件按 UID 合并到已保存的记录中，并丢弃过期事件
+func (s *EventService) collect(cluster *model.Cluster) (*model.ClusterEvents, error) {
+	master, err := s.clusterService.MasterNode(cluster)
+	if err != nil {
+		return nil, err
+	}
+	events, err := s.k3sService.ListEvents(master)
+	if err != nil {
+		return nil, err
+	}
+
+	record, err := s.load(cluster.ID)
+	if err != nil {
+		return nil, err
+	}
+	merged := make(map[string]model.ClusterEvent, len(record.Events)+len(events))
+	for _, event := range record.Events {
+		merged[event.UID] = event
+	}
+	for _, event := range events {
+		merged[event.UID] = event
+	}
+
+	now := time.Now()
+	cutoff := now.Add(-s.opts.Retention)
+	record.Events = record.Events[:0]
+	for _, event := range merged {
+		if event.LastSeen.Before(cutoff) {
+			continue
+		}
+		record.Events = append(record.Events, event)
+	}
+	sort.Slice(record.Events, func(i, j int) bool { return record.Events[i].LastSeen.After(record.Events[j].LastSeen) })
+	if len(record.Events) > maxClusterEvents {
+		record.Events = record.Events[:maxClusterEvents]
+	}
+	record.CollectedAt = now
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Put(store.CollectionClusterEvents, cluster.ID, data); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Events 返回集群最近一次收集保存的事件，尚未收集过时返回空列表
+func (s *EventService) Events(clusterID string) (*model.ClusterEvents, error) {
+	if _, err := s.clusterService.Get(clusterID); err != nil {
+		return nil, err
+	}
+	return s.load(clusterID)
+}
+
+func (s *EventService) load(clusterID string) (*model.ClusterEvents, error) {
+	record := &model.ClusterEvents{ClusterID: clusterID, Events: []model.ClusterEvent{}}
+	data, err := s.store.Get(store.CollectionClusterEvents, clusterID)
+	if errors.Is(err, store.ErrNotFound) {
+		return record, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("解析集群 %s 事件记录失败: %v", clusterID, err)
+	}
+	return record, nil
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"crypto/x509"
+	"fmt"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/pki"
+)
+
+// IngressCAConfig 签发 inSuite Ingress 证书的内部 CA
+type IngressCAConfig struct {
+	CertFile string
+	KeyFile  string
+	// Validity 签发证书的有效期
+	Validity time.Duration
+}
+
+// validateExposure 检查 inSuite 访问方式的参数组合
+func validateExposure(opts *model.ExposureOptions) error {
+	if opts == nil {
+		return nil
+	}
+	switch opts.Type {
+	case "", k3s.ExposeNodePort:
+		if len(opts.Hosts) > 0 || opts.TLS {
+			return fmt.Errorf("NodePort 方式不支持 hosts 和 tls，请使用 ingress")
+		}
+	case k3s.ExposeIngress:
+		if len(opts.Hosts) == 0 {
+			return fmt.Errorf("ingress 方式至少需要一个主机名")
+		}
+		for _, host := range opts.Hosts {
+			if !dnsNamePattern.MatchString(host) || strings.HasSuffix(host, ".") {
+				return fmt.Errorf("无效的 Ingress 主机名: %s", host)
+			}
+		}
+	case k3s.ExposeLoadBalancer:
+		if opts.TLS {
+			return fmt.Errorf("loadbalancer 方式不支持 tls，请使用 ingress")
+		}
+		if opts.Port == 80 || opts.Port == 443 {
+			return fmt.Errorf("端口 %d 已被 k3s 内置的 Traefik 占用", opts.Port)
+		}
+	}
+	if opts.NodePort > 0 && opts.Type != "" && opts.Type != k3s.ExposeNodePort {
+		return fmt.Errorf("nodePort 仅适用于 nodeport 方式")
+	}
+	if opts.Port > 0 && opts.Type != k3s.ExposeLoadBalancer {
+		return fmt.Errorf("port 仅适用于 loadbalancer 方式")
+	}
+	return nil
+}
+
+// exposure 将请求中的访问方式转换为部署参数，未设置时使用随机分配的 NodePort
+func exposure(opts *model.ExposureOptions) k3s.Exposure {
+	if opts == nil {
+		return k3s.Exposure{Type: k3s.ExposeNodePort}
+	}
+	e := k3s.Exposure{
+		Type:     opts.Type,
+		NodePort: opts.NodePort,
+		Hosts:    opts.Hosts,
+		Port:     opts.Port,
+		TLS:      opts.TLS,
+	}
+	if e.Type == "" {
+		e.Type = k3s.ExposeNodePort
+	}
+	return e
+}
+
+// issueIngressCert 使用内部 CA 为 Ingress 主机名签发服务端证书
+func (s *DeployService) issueIngressCert(hosts []string) ([]byte, []byte, error) {
+	ca, err := pki.LoadCA(s.ingressCA.CertFile, s.ingressCA.KeyFile)
+	if err != nil {
+		return nil, nil, fmt.Errorf("加载 Ingress CA 失败（可使用 cmd/pki init-ingress 生成）: %v", err)
+	}
+
+	template, err := pki.NewTemplate(hosts[0], false,
+		[]x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}, time.Now().Add(s.ingressCA.Validity))
+	if err != nil {
+		return nil, nil, err
+	}
+	template.DNSNames = hosts
+
+	cert, privateKey, err := ca.Issue(template)
+	if err != nil {
+		return nil, nil, fmt.Errorf("签发 Ingress 证书失败: %v", err)
+	}
+	keyPEM, err := pki.EncodePrivateKey(privateKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pki.EncodeCertificate(cert), keyPEM, nil
+}
@@ -0,0 +1,214 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// firewallPortPattern 端口/协议 或 起始-结束/协议
+var firewallPortPattern = regexp.MustCompile(`^[0-9]{1,5}(-[0-9]{1,5})?/(tcp|udp)$`)
+
+// firewallPort k3s 需要放行的端口
+type firewallPort struct {
+	port string
+	// serverOnly 只在 Server 节点放行
+	serverOnly bool
+	purpose    string
+}
+
+// k3sFirewallPorts k3s 文档列出的入站端口，以及 inSuite 默认 NodePort 访问方式使用的端口范围
+var k3sFirewallPorts = []firewallPort{
+	{"6443/tcp", true, "Kubernetes API Server"},
+	{"10250/tcp", false, "kubelet metrics 与 logs/exec"},
+	{"8472/udp", false, "flannel VXLAN"},
+	{"30000-32767/tcp", false, "NodePort Service"},
+}
+
+// firewallOptions 补全默认网段并校验防火墙配置，未设置时返回 disable 模式
+func firewallOptions(opts *model.FirewallOptions) (*model.FirewallOptions, error) {
+	resolved := model.FirewallOptions{Mode: model.FirewallDisable}
+	if opts != nil {
+		resolved = *opts
+	}
+	if resolved.Mode == "" {
+		resolved.Mode = model.FirewallDisable
+	}
+	if resolved.ClusterCIDR == "" {
+		resolved.ClusterCIDR = "10.42.0.0/16"
+	}
+	if resolved.ServiceCIDR == "" {
+		resolved.ServiceCIDR = "10.43.0.0/16"
+	}
+	for _, cidr := range []string{resolved.ClusterCIDR, resolved.ServiceCIDR} {
+		if _, _, err := net.ParseCIDR(cidr); err != nil {
+			return nil, fmt.Errorf("无效的网段: %s", cidr)
+		}
+	}
+	for _, port := range resolved.ExtraPorts {
+		if !firewallPortPattern.MatchString(port) {
+			return nil, fmt.Errorf("无效的防火墙端口 %q（格式 80/tcp 或 9100-9200/tcp）", port)
+		}
+	}
+	return &resolved, nil
+}
+
+// allowFirewallPorts 保持防火墙启用，放行 k3s 端口并信任 Pod 与 Service 网段，规则永久生效。
+// 只处理已启用的 ufw 和 firewalld；其他防火墙无法自动配置，返回错误提示改用 disable 模式或手动放行
+func (s *K3sService) allowFirewallPorts(client *ssh.Client, nodeName string, osInfo *hostos.Info, isServer bool, opts *model.FirewallOptions) error {
+	ports := make([]string, 0, len(k3sFirewallPorts)+len(opts.ExtraPorts))
+	for _, p := range k3sFirewallPorts {
+		if p.serverOnly && !isServer {
+			continue
+		}
+		ports = append(ports, p.port)
+	}
+	ports = append(ports, opts.ExtraPorts...)
+	cidrs := []string{opts.ClusterCIDR, opts.ServiceCIDR}
+
+	configured := false
+	result, err := client.ExecuteCommand("command -v ufw >/dev/null 2>&1 && ufw status || echo inactive")
+	if err == nil && strings.Contains(strings.ToLower(result.Stdout), "status: active") {
+		// 只撤销本次新增的规则，已有的同名规则保留
+		var cmds, undo []string
+		for _, port := range ports {
+			// ufw 的端口范围使用冒号
+			rule := "allow " + strings.Replace(port, "-", ":", 1)
+			cmds = append(cmds, "ufw "+rule+" comment k3s-deploy")
+			if !strings.Contains(result.Stdout, strings.Replace(port, "-", ":", 1)) {
+				undo = append(undo, "ufw delete "+rule)
+			}
+		}
+		for _, cidr := range cidrs {
+			rule := "allow from " + cidr
+			cmds = append(cmds, "ufw "+rule+" comment k3s-deploy")
+			if !strings.Contains(result.Stdout, cidr) {
+				undo = append(undo, "ufw delete "+rule)
+			}
+		}
+		if _, err := client.ExecuteCommand(strings.Join(cmds, " && ")); err != nil {
+			return fmt.Errorf("节点 %s 添加 ufw 规则失败: %v", nodeName, err)
+		}
+		if len(undo) > 0 {
+			s.journal.record(client, nodeName, model.ChangeFirewall, "添加 ufw 规则", strings.Join(undo, "; "))
+		}
+		result, err := client.ExecuteIdempotentCommand("ufw status")
+		if err != nil {
+			return fmt.Errorf("节点 %s 读取 ufw 规则失败: %v", nodeName, err)
+		}
+		for _, port := range ports {
+			if !strings.Contains(result.Stdout, strings.Replace(port, "-", ":", 1)) {
+				return fmt.Errorf("节点 %s ufw 规则 %s 未生效", nodeName, port)
+			}
+		}
+		s.logger.Infof("节点 %s ufw 保持启用，已放行 %v 及网段 %v", nodeName, ports, cidrs)
+		configured = true
+	}
+
+	if _, err := client.ExecuteCommand("command -v firewall-cmd"); err == nil {
+		result, err = client.ExecuteCommand(osInfo.ServiceActiveCommand("firewalld") + " || true")
+		if err == nil && strings.TrimSpace(result.Stdout) == "active" {
+			existing, err := client.ExecuteIdempotentCommand("firewall-cmd --permanent --list-ports && firewall-cmd --permanent --zone=trusted --list-sources")
+			if err != nil {
+				return fmt.Errorf("节点 %s 读取 firewalld 规则失败: %v", nodeName, err)
+			}
+			var cmds, undo []string
+			for _, port := range ports {
+				cmds = append(cmds, "firewall-cmd --permanent --add-port="+port)
+				if !strings.Contains(existing.Stdout, port) {
+					undo = append(undo, "firewall-cmd --permanent --remove-port="+port)
+				}
+			}
+			for _, cidr := range cidrs {
+				cmds = append(cmds, "firewall-cmd --permanent --zone=trusted --add-source="+cidr)
+				if !strings.Contains(existing.Stdout, cidr) {
+					undo = append(undo, "firewall-cmd --permanent --zone=trusted --remove-source="+cidr)
+				}
+			}
+			cmds = append(cmds, "firewall-cmd --reload")
+			if _, err := client.ExecuteCommand(strings.Join(cmds, " && ")); err != nil {
+				return fmt.Errorf("节点 %s 添加 firewalld 规则失败: %v", nodeName, err)
+			}
+			if len(undo) > 0 {
+				s.journal.record(client, nodeName, model.ChangeFirewall, "添加 firewalld 规则", strings.Join(append(undo, "firewall-cmd --reload"), "; "))
+			}
+			result, err := client.ExecuteIdempotentCommand("firewall-cmd --list-ports && firewall-cmd --zone=trusted --list-sources")
+			if err != nil {
+				return fmt.Errorf("节点 %s 读取 firewalld 规则失败: %v", nodeName, err)
+			}
+			for _, item := range append(append([]string{}, ports...), cidrs...) {
+				if !strings.Contains(result.Stdout, item) {
+					return fmt.Errorf("节点 %s firewalld 规则 %s 未生效", nodeName, item)
+				}
+			}
+			s.logger.Infof("节点 %s firewalld 保持启用，已放行 %v 并信任网段 %v", nodeName, ports, cidrs)
+			configured = true
+		}
+	}
+
+	if osInfo.Family == hostos.FamilySUSE {
+		if _, err := client.ExecuteCommand("command -v SuSEfirewall2"); err == nil {
+			return fmt.Errorf("节点 %s 使用 SuSEfirewall2，无法自动放行端口，请手动放行 %v 或改用 disable 模式", nodeName, ports)
+		}
+	}
+	if !configured {
+		s.logger.Infof("节点 %s 未启用 ufw 或 firewalld，无需放行端口", nodeName)
+	}
+	return nil
+}
+
+// verifyFirewallPorts 从各 Agent 访问 Master 的 6443、10250 端口，并从 Master 访问各 Agent 的 10250 端口。
+// 安装前端口上没有服务，连接被拒绝说明数据包已到达主机、未被防火墙丢弃；超时或 No route to host 说明仍被拦截。
+// 8472/udp 无法用 TCP 探测，由安装后的网络检查覆盖
+func (s *K3sService) verifyFirewallPorts(nodes []model.NodeConfig) error {
+	var master *model.NodeConfig
+	for i := range nodes {
+		if nodes[i].Name == "k3s-master" {
+			master = &nodes[i]
+		}
+	}
+	if master == nil || len(nodes) < 2 {
+		return nil
+	}
+
+	probe := func(from model.NodeConfig, to model.NodeConfig, port int) error {
+		client := newNodeClient(from)
+		if err := client.Connect(); err != nil {
+			return fmt.Errorf("节点 %s (%s) 连接失败: %v", from.Name, from.IP, err)
+		}
+		defer client.Close()
+
+		// curl 退出码 7 为无法连接、28 为超时，其余（含 0、52 空响应）说明 TCP 连接已建立
+		cmd := fmt.Sprintf("curl -sS -o /dev/null --connect-timeout 5 --max-time 8 http://%s:%d/ 2>&1; echo \"exit=$?\"", to.IP, port)
+		result, err := client.ExecuteIdempotentCommand(cmd)
+		if err != nil {
+			return fmt.Errorf("节点 %s 探测 %s:%d 失败: %v", from.Name, to.IP, port, err)
+		}
+		out := result.Stdout
+		if strings.Contains(out, "Connection refused") || !(strings.Contains(out, "exit=7") || strings.Contains(out, "exit=28")) {
+			return nil
+		}
+		return fmt.Errorf("节点 %s 无法访问 %s (%s) 的 %d 端口，请检查防火墙或安全组: %s", from.Name, to.Name, to.IP, port, strings.TrimSpace(out))
+	}
+
+	for _, node := range nodes {
+		if node.Name == master.Name {
+			continue
+		}
+		for _, port := range []int{6443, 10250} {
+			if err := probe(node, *master, port); err != nil {
+				return err
+			}
+		}
+		if err := probe(*master, node, 10250); err != nil {
+			return err
+		}
+	}
+	s.logger.Info("节点间 6443、10250 端口连通性验证通过")
+	return nil
+}
@@ -0,0 +1,293 @@
+package service
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/hex"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+
+	"gopkg.in/yaml.v3"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/pkg/utils"
+)
+
+// gitAskpass 由 GIT_ASKPASS 调用，从环境变量返回用户名和令牌，脚本本身不含敏感内容
+const gitAskpass = `#!/bin/sh
+case "$1" in
+Username*) printf '%s\n' "$K3S_DEPLOY_GIT_USERNAME" ;;
+*) printf '%s\n' "$K3S_DEPLOY_GIT_TOKEN" ;;
+esac
+`
+
+// defaultGitUsername 未配置用户名时使用，GitHub、GitLab 的访问令牌对用户名不做校验
+const defaultGitUsername = "oauth2"
+
+// GitOpsOptions Git 仓库同步参数
+type GitOpsOptions struct {
+	Repo          string
+	Branch        string
+	Username      string
+	Token         string
+	Path          string
+	WorkDir       string
+	WebhookSecret string
+}
+
+// GitOpsService 从 Git 仓库拉取集群期望状态和工作负载清单，并同步到受管集群。
+// 仓库目录结构：<path>/<集群名称>/cluster.yaml（期望状态）和 <path>/<集群名称>/manifests/*.yaml
+type GitOpsService struct {
+	options        GitOpsOptions
+	clusterService *ClusterService
+	logger         *logger.Logger
+
+	syncMu sync.Mutex
+	mu     sync.RWMutex
+	status model.GitOpsStatus
+}
+
+func NewGitOpsService(options GitOpsOptions, clusterService *ClusterService, logger *logger.Logger) *GitOpsService {
+	return &GitOpsService{
+		options:        options,
+		clusterService: clusterService,
+		logger:         logger,
+	}
+}
+
+// Status 返回最近一次同步结果
+func (s *GitOpsService) Status() model.GitOpsStatus {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return s.status
+}
+
+// VerifyWebhook 校验 Webhook 请求：支持 GitHub 的 X-Hub-Signature-256 和 GitLab 的 X-Gitlab-Token
+func (s *GitOpsService) VerifyWebhook(body []byte, signature, token string) bool {
+	if s.options.WebhookSecret == "" {
+		return false
+	}
+	if token != "" {
+		return subtle.ConstantTimeCompare([]byte(token), []byte(s.options.WebhookSecret)) == 1
+	}
+
+	mac := hmac.New(sha256.New, []byte(s.options.WebhookSecret))
+	mac.Write(body)
+	expected := "sha256=" + hex.EncodeToString(mac.Sum(nil))
+	return hmac.Equal([]byte(signature), []byte(expected))
+}
+
+// Sync 拉取仓库最新提交并将每个集群目录同步到同名受管集群
+func (s *GitOpsService) Sync() model.GitOpsStatus {
+	s.syncMu.Lock()
+	defer s.syncMu.Unlock()
+
+	status := model.GitOpsStatus{SyncedAt: time.Now(), Clusters: []model.GitOpsClusterResult{}}
+	commit, err := s.pull()
+	if err != nil {
+		status.Message = err.Error()
+		s.logger.Errorf("GitOps 拉取仓库失败: %v", err)
+		s.setStatus(status)
+		return status
+	}
+	status.Commit = commit
+
+	root := filepath.Join(s.options.WorkDir, s.options.Path)
+	entries, err := os.ReadDir(root)
+	if err != nil {
+		status.Message = fmt.Sprintf("读取目录 %s 失败: %v", s.options.Path, err)
+		s.setStatus(status)
+		return status
+	}
+
+	for _, entry := range entries {
+		if !entry.IsDir() {
+			continue
+		}
+		status.Clusters = append(status.Clusters, s.syncCluster(entry.Name(), filepath.Join(root, entry.Name())))
+	}
+
+	status.Success = true
+	for _, result := range status.Clusters {
+		if !result.Success {
+			status.Success = false
+		}
+	}
+	status.Message = fmt.Sprintf("已同步提交 %s，共 %d 个集群", shortCommit(commit), len(status.Clusters))
+	s.logger.Info(status.Message)
+	s.setStatus(status)
+	return status
+}
+
+func (s *GitOpsService) setStatus(status model.GitOpsStatus) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.status = status
+}
+
+func (s *GitOpsService) syncCluster(name, dir string) model.GitOpsClusterResult {
+	result := model.GitOpsClusterResult{Cluster: name}
+
+	desired, err := loadClusterSpec(dir)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+
+	cluster, err := s.clusterService.FindByName(name)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	if cluster == nil {
+		result.Message = "未找到同名受管集群，请先部署或纳管"
+		return result
+	}
+
+	if _, err := s.clusterService.SetDesired(cluster.ID, desired); err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	report, err := s.clusterService.CheckDrift(cluster.ID, true)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+
+	result.Success = report.InSync
+	result.Drift = report
+	result.Message = fmt.Sprintf("%d 项漂移", len(report.Items))
+	return result
+}
+
+// loadClusterSpec 读取集群目录下的 cluster.yaml 和 manifests/*.yaml
+func loadClusterSpec(dir string) (*model.DesiredState, error) {
+	desired := &model.DesiredState{}
+	data, err := os.ReadFile(filepath.Join(dir, "cluster.yaml"))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("读取 cluster.yaml 失败: %v", err)
+	}
+	if err == nil {
+		if err := yaml.Unmarshal(data, desired); err != nil {
+			return nil, fmt.Errorf("解析 cluster.yaml 失败: %v", err)
+		}
+	}
+
+	files, _ := filepath.Glob(filepath.Join(dir, "manifests", "*.yaml"))
+	for _, file := range files {
+		content, err := os.ReadFile(file)
+		if err != nil {
+			return nil, fmt.Errorf("读取清单 %s 失败: %v", filepath.Base(file), err)
+		}
+		if desired.Manifests == nil {
+			desired.Manifests = make(map[string]string)
+		}
+		desired.Manifests[strings.TrimSuffix(filepath.Base(file), ".yaml")] = string(content)
+	}
+	return desired, nil
+}
+
+// pull 首次克隆仓库，之后强制对齐远端分支，返回当前提交
+func (s *GitOpsService) pull() (string, error) {
+	if _, err := os.Stat(filepath.Join(s.options.WorkDir, ".git")); os.IsNotExist(err) {
+		if err := os.MkdirAll(filepath.Dir(s.options.WorkDir), 0700); err != nil {
+			return "", err
+		}
+		if _, err := s.runGit("", "clone", "--depth", "1", "--branch", s.options.Branch, s.options.Repo, s.options.WorkDir); err != nil {
+			return "", err
+		}
+	} else {
+		// 早期版本允许在地址中携带令牌，会保存在 .git/config 中，每次拉取前改回配置的地址
+		if _, err := s.runGit(s.options.WorkDir, "remote", "set-url", "origin", s.options.Repo); err != nil {
+			return "", err
+		}
+		if _, err := s.runGit(s.options.WorkDir, "fetch", "--depth", "1", "origin", s.options.Branch); err != nil {
+			return "", err
+		}
+		if _, err := s.runGit(s.options.WorkDir, "reset", "--hard", "FETCH_HEAD"); err != nil {
+			return "", err
+		}
+	}
+	return s.runGit(s.options.WorkDir, "rev-parse", "HEAD")
+}
+
+// runGit 执行 git 命令。配置了令牌时通过临时 askpass 脚本提供，并禁用凭据助手，令牌不会被保存；
+// 错误信息中的令牌和地址中的用户信息被隐藏
+func (s *GitOpsService) runGit(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", append([]string{"-c", "credential.helper="}, args...)...)
+	cmd.Dir = dir
+	cmd.Env = append(os.Environ(), "GIT_TERMINAL_PROMPT=0")
+	if s.options.Token != "" {
+		askpass, err := writeAskpass()
+		if err != nil {
+			return "", err
+		}
+		defer os.Remove(askpass)
+		username := s.options.Username
+		if username == "" {
+			username = defaultGitUsername
+		}
+		cmd.Env = append(cmd.Env, "GIT_ASKPASS="+askpass,
+			"K3S_DEPLOY_GIT_USERNAME="+username, "K3S_DEPLOY_GIT_TOKEN="+s.options.Token)
+	}
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return "", fmt.Errorf("git %s 失败: %v: %s", args[0], err, s.redact(strings.TrimSpace(stderr.String())))
+	}
+	return strings.TrimSpace(stdout.String()), nil
+}
+
+// redact 隐藏 git 输出中的令牌和 URL 中的用户信息
+func (s *GitOpsService) redact(output string) string {
+	if s.options.Token != "" {
+		output = strings.ReplaceAll(output, s.options.Token, "***")
+	}
+	return utils.RedactURL(output)
+}
+
+// writeAskpass 写入只有当前用户可执行的 askpass 脚本，返回路径，调用方负责删除
+func writeAskpass() (string, error) {
+	f, err := os.CreateTemp("", "k3s-deploy-askpass-*")
+	if err != nil {
+		return "", fmt.Errorf("创建 askpass 脚本失败: %v", err)
+	}
+	defer f.Close()
+	if _, err := f.WriteString(gitAskpass); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("创建 askpass 脚本失败: %v", err)
+	}
+	if err := f.Chmod(0700); err != nil {
+		os.Remove(f.Name())
+		return "", fmt.Errorf("创建 askpass 脚本失败: %v", err)
+	}
+	return f.Name(), nil
+}
+
+func shortCommit(commit string) string {
+	if len(commit) > 8 {
+		return commit[:8]
+	}
+	return commit
+}
+
+// StartSyncScheduler 按固定周期同步仓库
+func (s *GitOpsService) StartSyncScheduler(interval time.Duration) {
+	s.logger.Infof("GitOps 定期同步已启用，周期 %s", interval)
+	go func() {
+		s.Sync()
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.Sync()
+		}
+	}()
+}
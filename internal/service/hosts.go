@@ -0,0 +1,79 @@
+package service
+
+import (
+	"fmt"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// hostsEntries 汇总写入 /etc/hosts 的记录，IncludeNodes 时加入各节点的主机名（与 prepare-nodes 设置的主机名一致）
+func hostsEntries(nodes []model.NodeConfig, opts *model.HostsOptions) ([]hostos.HostsEntry, error) {
+	if opts == nil {
+		return nil, nil
+	}
+
+	var entries []hostos.HostsEntry
+	for _, e := range opts.Entries {
+		entry := hostos.HostsEntry{IP: e.IP, Names: e.Names}
+		if err := entry.Validate(); err != nil {
+			return nil, err
+		}
+		entries = append(entries, entry)
+	}
+	if opts.IncludeNodes {
+		for _, node := range nodes {
+			hostname, err := hostos.NormalizeHostname(node.Name)
+			if err != nil {
+				return nil, err
+			}
+			entry := hostos.HostsEntry{IP: node.IP, Names: []string{hostname}}
+			if err := entry.Validate(); err != nil {
+				return nil, fmt.Errorf("节点 %s: %v", node.Name, err)
+			}
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
+// SyncHosts 并行更新各节点 /etc/hosts 中由部署维护的区段，opts 为 nil 时删除该区段。
+// 每次整段替换，新增节点后重新同步即可让所有节点的记录保持一致
+func (s *K3sService) SyncHosts(nodes []model.NodeConfig, opts *model.HostsOptions) ([]model.HostsSyncResult, error) {
+	entries, err := hostsEntries(nodes, opts)
+	if err != nil {
+		return nil, err
+	}
+	cmd := hostos.UpdateHostsCommand(entries)
+
+	nodeResults := runOnNodes(nodes, func(i int, client *ssh.Client) error {
+		if _, err := client.ExecuteCommand(cmd); err != nil {
+			return fmt.Errorf("节点 %s 更新 /etc/hosts 失败: %v", nodes[i].Name, err)
+		}
+		if len(entries) == 0 {
+			s.logger.Infof("节点 %s 已删除 /etc/hosts 受管记录", nodes[i].Name)
+		} else {
+			s.logger.Infof("节点 %s 已写入 %d 条 /etc/hosts 记录", nodes[i].Name, len(entries))
+		}
+		return nil
+	})
+
+	results := make([]model.HostsSyncResult, len(nodes))
+	for i, r := range nodeResults {
+		results[i] = model.HostsSyncResult{NodeResult: r}
+		if r.Success {
+			results[i].Entries = len(entries)
+		}
+	}
+	return results, nil
+}
+
+// hostsNodeResults 同步结果中各节点的成败
+func hostsNodeResults(results []model.HostsSyncResult) []model.NodeResult {
+	nodeResults := make([]model.NodeResult, len(results))
+	for i, r := range results {
+		nodeResults[i] = r.NodeResult
+	}
+	return nodeResults
+}
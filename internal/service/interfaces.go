@@ -0,0 +1,187 @@
+package service
+
+import (
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/bundle"
+	"k3s-deploy-backend/internal/pkg/faults"
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// K3sOperations DeployService 使用的节点与集群操作，由 K3sService 实现。
+// 部署步骤只依赖该接口，单元测试可以替换为 servicetest.K3s，不连接节点
+type K3sOperations interface {
+	// 预检与节点准备
+	ValidateNodes(nodes []model.NodeConfig, thresholds model.PreflightThresholds, runtime *model.RuntimeOptions, dns *model.DNSOptions, diskPrep *model.DiskPrepOptions, firewall *model.FirewallOptions, cni *model.CNIOptions) ([]model.PreflightResult, error)
+	CheckServerReachable(nodes []model.NodeConfig, serverURL string) error
+	PrepareNodes(nodes []model.NodeConfig, opts *model.NodePrepOptions) ([]model.NodeResult, error)
+	ConfigureNodeDNS(nodes []model.NodeConfig, opts *model.DNSOptions) ([]model.NodeResult, error)
+	SyncHosts(nodes []model.NodeConfig, opts *model.HostsOptions) ([]model.HostsSyncResult, error)
+	PrepareDisks(nodes []model.NodeConfig, opts *model.DiskPrepOptions) ([]model.NodeResult, error)
+	TuneNodes(nodes []model.NodeConfig, opts *model.TuningOptions) ([]model.TuningResult, error)
+	HardenNodes(nodes []model.NodeConfig) ([]model.NodeResult, error)
+	CheckMirrors(nodes []model.NodeConfig, mirrors *model.MirrorOptions) ([]model.NodeResult, error)
+
+	// k3s 安装
+	InstallMaster(node model.NodeConfig, policy k3s.WaitPolicy, opts k3s.InstallOptions) ([]k3s.Digest, error)
+	ConfigureAgent(masterNode, agentNode model.NodeConfig, agentIndex int, policy k3s.WaitPolicy, opts k3s.InstallOptions) ([]k3s.Digest, error)
+	JoinAgent(node model.NodeConfig, serverURL, token string, policy k3s.WaitPolicy, opts k3s.InstallOptions) ([]k3s.Digest, error)
+	WaitCNI(masterNode model.NodeConfig, cni *model.CNIOptions, nodes []string, policy k3s.WaitPolicy) error
+	WaitNodesReady(masterNode model.NodeConfig, expected []model.NodeReadiness, policy k3s.WaitPolicy) ([]model.NodeReadiness, error)
+	UninstallNodes(nodes []model.NodeConfig, rollbackOnly bool) []model.UninstallResult
+
+	// 集群组件与应用
+	ConfigureCoreDNS(masterNode model.NodeConfig, workspaceID string, cfg k3s.CoreDNSConfig, policy k3s.WaitPolicy) ([]string, error)
+	ConfigureStorage(nodes []model.NodeConfig, workspaceID string, opts *model.StorageOptions, policy k3s.WaitPolicy) ([]string, error)
+	InstallCertManager(masterNode model.NodeConfig, workspaceID string, cfg k3s.CertManagerConfig, policy k3s.WaitPolicy) ([]string, error)
+	ApplyLabels(masterNode model.NodeConfig, labels map[string][]string) error
+	InstallMinIO(masterNode model.NodeConfig, workspaceID string, cfg k3s.MinIOConfig, policy k3s.WaitPolicy) ([]string, int, error)
+	InstallVelero(masterNode model.NodeConfig, workspaceID string, opts *model.VeleroOptions, policy k3s.WaitPolicy) ([]string, error)
+	PrePullImages(nodes []model.NodeConfig, roleAssignment map[string]string) ([]model.NodeResult, error)
+	CheckCapacity(masterNode model.NodeConfig, spec k3s.AppSpec) (*model.CapacityReport, error)
+	DeployInSuite(masterNode model.NodeConfig, workspaceID string, roleAssignment map[string]string, policy k3s.WaitPolicy, spec k3s.AppSpec) ([]string, string, error)
+	VerifyDeployment(masterNode model.NodeConfig, namespace string) error
+	CheckNetwork(masterNode model.NodeConfig, workspaceID, externalName string, checkPolicy bool, policy k3s.WaitPolicy) ([]model.NetworkCheck, error)
+	AccessURL(masterNode model.NodeConfig, spec k3s.AppSpec, policy k3s.WaitPolicy) (string, error)
+
+	// 运维
+	ApplyServiceConfig(nodes []model.NodeConfig, workspaceID, config, registries string, policy k3s.WaitPolicy) []model.NodeServiceResult
+	RestartServices(nodes []model.NodeConfig, policy k3s.WaitPolicy) []model.NodeServiceResult
+	ServiceLogs(nodes []model.NodeConfig, lines int) []model.NodeServiceResult
+	SecretsEncryptionStatus(masterNode model.NodeConfig) (*model.SecretsEncryptionStatus, error)
+	RotateSecretsEncryptionKeys(masterNode model.NodeConfig, policy k3s.WaitPolicy) (*model.SecretsEncryptionStatus, error)
+	DrainNode(masterNode model.NodeConfig, node string, opts k3s.DrainOptions) (*model.DrainResult, error)
+	UncordonNode(masterNode model.NodeConfig, node string) error
+	WaitNodeReady(masterNode model.NodeConfig, node string, timeout, interval time.Duration) error
+	PatchNode(node model.NodeConfig, b *bundle.Bundle, reboot string, timeout, interval time.Duration, workspaceID string) (bool, error)
+}
+
+// ClusterManager K3sService 在已连接节点上执行的集群操作，由 k3s.Manager 实现
+type ClusterManager interface {
+	// 集群组件与应用
+	ApplyCoreDNS(client *ssh.Client, ws *k3s.Workspace, cfg k3s.CoreDNSConfig, policy k3s.WaitPolicy) error
+	ConfigureLocalPath(client *ssh.Client, ws *k3s.Workspace, nodePaths map[string]string, policy k3s.WaitPolicy) error
+	InstallLonghorn(client *ssh.Client, cfg k3s.LonghornConfig, policy k3s.WaitPolicy) error
+	InstallCertManager(client *ssh.Client, ws *k3s.Workspace, cfg k3s.CertManagerConfig, policy k3s.WaitPolicy) error
+	InstallMinIO(client *ssh.Client, ws *k3s.Workspace, cfg k3s.MinIOConfig, policy k3s.WaitPolicy) (int, error)
+	InstallVelero(client *ssh.Client, ws *k3s.Workspace, opts *model.VeleroOptions, policy k3s.WaitPolicy) error
+	ApplyNodeLabels(client *ssh.Client, labels map[string][]string) error
+	ApplyNamespaceSecurity(client *ssh.Client, namespaces map[string]string) error
+	PrePullImages(client *ssh.Client, nodeName string, images []string) error
+	CheckCapacity(client *ssh.Client, spec k3s.AppSpec) (*model.CapacityReport, error)
+	DeployInSuite(client *ssh.Client, ws *k3s.Workspace, roleAssignment map[string]string, policy k3s.WaitPolicy, spec k3s.AppSpec) error
+	DeleteInstance(client *ssh.Client, instance, namespace string, policy k3s.WaitPolicy) error
+	VerifyDeployment(client *ssh.Client, namespace string) error
+	AccessURL(client *ssh.Client, spec k3s.AppSpec, nodeIP string, policy k3s.WaitPolicy) (string, error)
+	CheckNetwork(client *ssh.Client, ws *k3s.Workspace, externalName string, checkPolicy bool, policy k3s.WaitPolicy) ([]model.NetworkCheck, error)
+	WaitCNI(client *ssh.Client, opts *model.CNIOptions, nodes []string, policy k3s.WaitPolicy) error
+	WaitNodesReady(client *ssh.Client, expected []model.NodeReadiness, timeout, interval time.Duration) ([]model.NodeReadiness, error)
+	WaitNodeReady(client *ssh.Client, node string, timeout, interval time.Duration) error
+	ValidateNodeName(client *ssh.Client, node string) error
+
+	// 集群访问与状态
+	GetNodeToken(client *ssh.Client) (string, error)
+	CreateJoinToken(client *ssh.Client, ttl time.Duration, caHash string) (string, error)
+	EnsureAccess(client *ssh.Client, serverIP, content, token string) (string, string, bool, error)
+	DiscoverCluster(client *ssh.Client) (*k3s.Discovery, error)
+	CheckHealth(client *ssh.Client) (*k3s.Health, error)
+	ListWorkloads(client *ssh.Client) ([]model.Workload, error)
+	ListEvents(client *ssh.Client) ([]model.ClusterEvent, error)
+	Metrics(client *ssh.Client) (*model.ClusterMetrics, error)
+	UtilizationSample(client *ssh.Client) (*model.UtilizationSample, error)
+	QuotaUsage(client *ssh.Client) ([]model.NamespaceQuota, error)
+	ReadAuditLog(client *ssh.Client, since string) ([]model.KubeAuditEntry, string, error)
+	ScanCIS(client *ssh.Client) (*model.CISReport, error)
+	RunSecurityScan(client *ssh.Client, ws *k3s.Workspace, cfg k3s.ScanConfig, images []string, policy k3s.WaitPolicy) ([]model.BenchNodeResult, []model.ImageScanResult, error)
+
+	// 运维
+	ApplyServiceConfig(client *ssh.Client, ws *k3s.Workspace, osInfo *hostos.Info, unit, config, registries string, policy k3s.WaitPolicy) ([]string, error)
+	RestartService(client *ssh.Client, osInfo *hostos.Info, unit string, policy k3s.WaitPolicy) error
+	ServiceLogs(client *ssh.Client, osInfo *hostos.Info, unit string, lines int) (string, error)
+	ConfigureLogRotation(client *ssh.Client, nodeName string, osInfo *hostos.Info, opts *model.LogRotationOptions) error
+	SecretsEncryptionStatus(client *ssh.Client) (*model.SecretsEncryptionStatus, error)
+	RotateSecretsEncryptionKeys(client *ssh.Client, policy k3s.WaitPolicy) (*model.SecretsEncryptionStatus, error)
+	Cordon(client *ssh.Client, node string) error
+	Uncordon(client *ssh.Client, node string) error
+	Drain(client *ssh.Client, node string, opts k3s.DrainOptions) (*model.DrainResult, error)
+	DetectDrift(client *ssh.Client, ws *k3s.Workspace, desired *model.DesiredState) ([]model.DriftItem, error)
+	ReconcileDrift(client *ssh.Client, ws *k3s.Workspace, desired *model.DesiredState, items []model.DriftItem)
+	JournaldDrift(client *ssh.Client, nodeName string, opts *model.LogRotationOptions) (*model.DriftItem, error)
+	ReconcileJournald(client *ssh.Client, opts *model.LogRotationOptions) error
+	ReconcileNodeLabels(client *ssh.Client, desired, previous *model.DesiredState, dryRun bool) ([]model.LabelChange, error)
+
+	// 备份
+	CreateBackup(client *ssh.Client, ws *k3s.Workspace, req *model.BackupRequest) (string, error)
+	ListBackups(client *ssh.Client) ([]model.Backup, error)
+	CreateRestore(client *ssh.Client, ws *k3s.Workspace, backup string, req *model.RestoreRequest) (string, error)
+	ListRestores(client *ssh.Client) ([]model.Restore, error)
+	ApplySchedule(client *ssh.Client, ws *k3s.Workspace, name string, req *model.BackupScheduleRequest) error
+	ListSchedules(client *ssh.Client) ([]model.BackupSchedule, error)
+	DeleteSchedule(client *ssh.Client, name string) error
+}
+
+// NodeCredentials DeployService 使用的凭据操作，由 CredentialService 实现
+type NodeCredentials interface {
+	// ResolveNodes 将引用凭据库的节点替换为实际的认证信息
+	ResolveNodes(nodes []model.NodeConfig) error
+}
+
+// RequestSecrets TaskService 使用的凭据库操作，由 CredentialService 实现：任务请求的敏感字段保存在凭据库中
+type RequestSecrets interface {
+	SaveRequestSecrets(taskID string, fields map[string]string) (string, error)
+	RequestSecrets(id string) (map[string]string, error)
+	Delete(id string) error
+}
+
+// ClusterRegistry DeployService 使用的集群记录操作，由 ClusterService 实现
+type ClusterRegistry interface {
+	FindByMaster(ip string) (*model.Cluster, error)
+	RegisterDeployed(master model.NodeConfig) (*model.Cluster, error)
+	RecordLabels(masterIP string, labels map[string][]string) error
+	RecordLogRotation(masterIP string, opts *model.LogRotationOptions) error
+	RecordDegraded(masterIP, step string, results []model.NodeResult) error
+	RecordAgents(masterIP string, agents []model.NodeConfig) error
+	RecordRelease(masterIP string, release model.Release) error
+	RecordObjectStore(masterIP string, objectStore model.ObjectStore, accessKey, secretKey string) error
+	ObjectStoreByMaster(masterIP string) (*model.ObjectStoreAccess, error)
+}
+
+// Installer K3sService 使用的 k3s 安装操作，由 k3s.Installer 实现
+type Installer interface {
+	CheckMirrors(client *ssh.Client, nodeName string, mirrors *model.MirrorOptions) error
+	InstallMaster(client *ssh.Client, nodeName string, policy k3s.WaitPolicy, opts k3s.InstallOptions) ([]k3s.Digest, error)
+	ServerURL(masterClient *ssh.Client) (string, error)
+	InstallAgent(client *ssh.Client, serverURL string, nodeName string, token string, policy k3s.WaitPolicy, opts k3s.InstallOptions) ([]k3s.Digest, error)
+	WriteCISConfig(client *ssh.Client, server bool) error
+	UploadOSPackages(client *ssh.Client, b *bundle.Bundle, dir string) (int, error)
+}
+
+// ConnectionTester SSHHandler 使用的节点连接测试，由 SSHService 实现
+type ConnectionTester interface {
+	TestConnection(req *model.SSHTestRequest) *model.SSHTestResponse
+	BatchTestConnection(req *model.BatchSSHTestRequest) []*model.SSHTestResponse
+}
+
+// JoinBundles 部署请求中加入信息包的校验，由 JoinBundleService 实现
+type JoinBundles interface {
+	// Open 校验签名、格式和有效期后返回加入信息
+	Open(text string) (*model.JoinBundle, error)
+}
+
+// StepFaults 部署步骤的故障注入，由 faults.Injector 实现。返回错误时步骤不执行、直接失败
+type StepFaults interface {
+	StepFault(step string) error
+}
+
+var (
+	_ K3sOperations    = (*K3sService)(nil)
+	_ NodeCredentials  = (*CredentialService)(nil)
+	_ ClusterRegistry  = (*ClusterService)(nil)
+	_ Installer        = (*k3s.Installer)(nil)
+	_ ClusterManager   = (*k3s.Manager)(nil)
+	_ ConnectionTester = (*SSHService)(nil)
+	_ StepFaults       = (*faults.Injector)(nil)
+)
@@ -0,0 +1,105 @@
+package service
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/store"
+	"k3s-deploy-backend/pkg/utils"
+)
+
+// janitorLease 多副本部署时同一周期只由一个副本执行清理
+const janitorLease = "janitor"
+
+// JanitorOptions 清理周期与各类数据的保留时长，保留时长为 0 表示不清理该类数据
+type JanitorOptions struct {
+	Interval           time.Duration
+	TaskRetention      time.Duration
+	WorkspaceRetention time.Duration
+	// WebSSHTimeout 终端会话超过该时长未收到浏览器消息时关闭
+	WebSSHTimeout time.Duration
+}
+
+// JanitorService 后台清理过期任务记录、节点上遗留的临时文件和失去浏览器的终端会话，并检查受管集群保存的访问凭据
+type JanitorService struct {
+	opts           JanitorOptions
+	store          store.Store
+	taskService    *TaskService
+	clusterService *ClusterService
+	webSSHService  *WebSSHService
+	logger         *logger.Logger
+	owner          string
+}
+
+func NewJanitorService(opts JanitorOptions, st store.Store, taskService *TaskService, clusterService *ClusterService, webSSHService *WebSSHService, logger *logger.Logger) *JanitorService {
+	hostname, _ := os.Hostname()
+	owner, err := utils.GenerateID(hostname)
+	if err != nil {
+		owner = fmt.Sprintf("%s-%d", hostname, time.Now().UnixNano())
+	}
+
+	return &JanitorService{
+		opts:           opts,
+		store:          st,
+		taskService:    taskService,
+		clusterService: clusterService,
+		webSSHService:  webSSHService,
+		logger:         logger,
+		owner:          owner,
+	}
+}
+
+// Start 按固定周期执行清理
+func (s *JanitorService) Start() {
+	s.logger.Infof("后台清理已启用，周期 %s，任务保留 %s，工作目录保留 %s",
+		s.opts.Interval, s.opts.TaskRetention, s.opts.WorkspaceRetention)
+	go func() {
+		ticker := time.NewTicker(s.opts.Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.RunOnce()
+		}
+	}()
+}
+
+// RunOnce 执行一轮清理。租约不主动释放，在一个周期内阻止其他副本重复清理
+func (s *JanitorService) RunOnce() {
+	// 终端会话只存在于建立它的副本上，每个副本都要检查，不受清理租约限制
+	if s.opts.WebSSHTimeout > 0 {
+		if closed := s.webSSHService.CloseOrphaned(s.opts.WebSSHTimeout); closed > 0 {
+			s.logger.Infof("已关闭 %d 个失去浏览器连接的 WebSSH 会话", closed)
+		}
+	}
+
+	acquired, err := s.store.AcquireLease(janitorLease, s.owner, s.opts.Interval-time.Second)
+	if err != nil {
+		s.logger.Errorf("获取清理租约失败: %v", err)
+		return
+	}
+	if !acquired {
+		return
+	}
+
+	if s.opts.TaskRetention > 0 {
+		pruned, err := s.taskService.Prune(s.opts.TaskRetention)
+		if err != nil {
+			s.logger.Errorf("清理过期任务失败: %v", err)
+		} else if pruned > 0 {
+			s.logger.Infof("已清理 %d 个过期任务", pruned)
+		}
+	}
+
+	if s.opts.WorkspaceRetention > 0 {
+		if _, err := s.clusterService.PruneWorkspaces(s.opts.WorkspaceRetention); err != nil {
+			s.logger.Errorf("清理节点工作目录失败: %v", err)
+		}
+	}
+
+	if refreshed, err := s.clusterService.CheckAccess(); err != nil {
+		s.logger.Errorf("检查集群访问凭据失败: %v", err)
+	} else if refreshed > 0 {
+		s.logger.Infof("已重新读取 %d 个集群失效的 kubeconfig 和 join token", refreshed)
+	}
+}
@@ -0,0 +1,150 @@
+package service
+
+import (
+	"context"
+	"crypto/ed25519"
+	"fmt"
+	"net"
+	"time"
+
+	"k3s-deploy-backend/internal/config"
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/joinbundle"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/logger"
+)
+
+// joinProbeTimeout 导入时从后端访问 server /cacerts 的超时时间
+const joinProbeTimeout = 10 * time.Second
+
+// JoinBundleService 导出带签名的集群加入信息包，以及导入时校验加入信息包。导出记入审计日志
+type JoinBundleService struct {
+	clusterService *ClusterService
+	auditService   *AuditService
+	key            ed25519.PrivateKey
+	ttl            time.Duration
+	logger         *logger.Logger
+}
+
+func NewJoinBundleService(key ed25519.PrivateKey, ttl time.Duration, clusterService *ClusterService, auditService *AuditService, logger *logger.Logger) *JoinBundleService {
+	return &JoinBundleService{
+		clusterService: clusterService,
+		auditService:   auditService,
+		key:            key,
+		ttl:            ttl,
+		logger:         logger,
+	}
+}
+
+func (s *JoinBundleService) publicKey() ed25519.PublicKey {
+	return s.key.Public().(ed25519.PublicKey)
+}
+
+// PublicKey 返回签名公钥（PEM）及其指纹，供其他工具校验加入信息包
+func (s *JoinBundleService) PublicKey() (string, string, error) {
+	pem, err := joinbundle.PublicKeyPEM(s.publicKey())
+	return pem, joinbundle.KeyID(s.publicKey()), err
+}
+
+// Export 在 Master 上创建与加入信息包同样有效期的引导 token，连同 CA 摘要签名后导出加入信息包。
+// 集群保存的永久 server token 只用于计算 CA 摘要，不写入加入信息包
+func (s *JoinBundleService) Export(req *model.JoinBundleRequest, actor, requestID string) (*model.JoinBundleExport, error) {
+	ttl := s.ttl
+	if req.TTL != "" {
+		d, err := time.ParseDuration(req.TTL)
+		if err != nil || d < time.Minute || d > config.MaxJoinBundleTTL {
+			return nil, fmt.Errorf("有效期 %q 无效，应在 1m-%s 范围内", req.TTL, config.MaxJoinBundleTTL)
+		}
+		ttl = d
+	}
+
+	cluster, err := s.clusterService.Get(req.ClusterID)
+	if err != nil {
+		return nil, err
+	}
+	kubeconfig, serverToken, err := s.clusterService.ensureAccess(cluster)
+	if err != nil {
+		return nil, fmt.Errorf("读取集群 %s 的访问信息失败: %w", cluster.Name, err)
+	}
+	caHash := k3s.TokenCAHash(serverToken)
+	if caHash == "" {
+		if caHash, err = k3s.KubeconfigCAHash(kubeconfig); err != nil {
+			return nil, fmt.Errorf("计算集群 %s 的 CA 摘要失败: %w", cluster.Name, err)
+		}
+	}
+	master, err := s.clusterService.MasterNode(cluster)
+	if err != nil {
+		return nil, err
+	}
+	token, err := s.clusterService.k3sService.CreateJoinToken(master, ttl, caHash)
+	if err != nil {
+		return nil, fmt.Errorf("集群 %s: %w", cluster.Name, err)
+	}
+
+	now := time.Now()
+	bundle := &model.JoinBundle{
+		ClusterID:   cluster.ID,
+		ClusterName: cluster.Name,
+		ServerURL:   "https://" + net.JoinHostPort(cluster.Master.IP, "6443"),
+		Token:       token,
+		CAHash:      caHash,
+		K3sVersion:  cluster.Version,
+		IssuedAt:    now,
+		ExpiresAt:   now.Add(ttl),
+	}
+	if cluster.Desired != nil {
+		bundle.Flags = k3s.AgentArgs(cluster.Desired.K3sArgs)
+	}
+	text, err := joinbundle.Encode(bundle, s.key)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.auditService.Record(model.AuditEvent{
+		Action:    model.AuditJoinBundleExport,
+		Actor:     actor,
+		RequestID: requestID,
+		ClusterID: cluster.ID,
+		Target:    cluster.Name,
+		Success:   true,
+		Message:   fmt.Sprintf("有效期至 %s，签名密钥 %s，已创建同样有效期的引导 token", bundle.ExpiresAt.Format(time.RFC3339), bundle.KeyID),
+	}); err != nil {
+		s.logger.Warnf("记录导出加入信息包审计失败: %v", err)
+	}
+	return &model.JoinBundleExport{Bundle: text, Content: *bundle, Command: joinbundle.Command(bundle)}, nil
+}
+
+// Open 校验加入信息包的签名、格式和有效期
+func (s *JoinBundleService) Open(text string) (*model.JoinBundle, error) {
+	return joinbundle.Decode(text, s.publicKey())
+}
+
+// Import 校验加入信息包，未设置 skipProbe 时从后端访问 server 确认其 CA 与加入信息包一致。
+// CA 不一致时返回错误；后端无法访问 server 只告警，节点与 server 的网络可能与后端不同
+func (s *JoinBundleService) Import(req *model.JoinBundleImportRequest) (*model.JoinBundleImport, error) {
+	bundle, err := s.Open(req.Bundle)
+	if err != nil {
+		return nil, err
+	}
+	result := &model.JoinBundleImport{Content: *bundle, Command: joinbundle.Command(bundle), Warnings: []string{}}
+	if remaining := time.Until(bundle.ExpiresAt); remaining < time.Hour {
+		result.Warnings = append(result.Warnings, fmt.Sprintf("加入信息包将在 %s 后过期", remaining.Round(time.Minute)))
+	}
+	if req.SkipProbe {
+		result.Warnings = append(result.Warnings, "未从后端核对 server 的 CA")
+		return result, nil
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), joinProbeTimeout)
+	defer cancel()
+	hash, err := joinbundle.FetchCAHash(ctx, bundle.ServerURL)
+	switch {
+	case err != nil:
+		result.Warnings = append(result.Warnings, fmt.Sprintf("未能核对 server 的 CA: %v", err))
+	case hash != bundle.CAHash:
+		return nil, fmt.Errorf("server %s 当前的 CA 与加入信息包不一致（集群可能已重建），请重新导出", bundle.ServerURL)
+	default:
+		result.Verified = true
+	}
+	return result, nil
+}
@@ -3,97 +3,175 @@ package service
 import (
 	"fmt"
 	"path/filepath"
+	"slices"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
 
 	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/benchmark"
+	"k3s-deploy-backend/internal/pkg/hostos"
 	"k3s-deploy-backend/internal/pkg/k3s"
 	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/progress"
 	"k3s-deploy-backend/internal/pkg/ssh"
+	"k3s-deploy-backend/pkg/utils"
 )
 
 type K3sService struct {
-	installer *k3s.Installer
-	manager   *k3s.Manager
-	logger    *logger.Logger
+	installer Installer
+	manager   ClusterManager
+	journal   *ChangeJournal
+	// preflightConcurrency 同时预检的节点数上限
+	preflightConcurrency int
+	logger               *logger.Logger
 }
 
-func NewK3sService(logger *logger.Logger) *K3sService {
+// defaultPreflightConcurrency 未配置时同时预检的节点数
+const defaultPreflightConcurrency = 10
+
+func NewK3sService(installer Installer, journal *ChangeJournal, preflightConcurrency int, logger *logger.Logger) *K3sService {
+	if preflightConcurrency <= 0 {
+		preflightConcurrency = defaultPreflightConcurrency
+	}
 	return &K3sService{
-		installer: k3s.NewInstaller(logger),
-		manager:   k3s.NewManager(logger),
-		logger:    logger,
+		installer:            installer,
+		manager:              k3s.NewManager(logger),
+		journal:              journal,
+		preflightConcurrency: preflightConcurrency,
+		logger:               logger,
 	}
 }
 
-func (s *K3sService) ValidateNodes(nodes []model.NodeConfig) error {
-	s.logger.Info("开始验证节点连接状态")
+// 预检资源建议值的内置值，低于建议值时只告警
+var (
+	standardThresholds = model.PreflightThresholds{CPUCores: 4, MemoryMB: 16384, DiskGB: 450}
+	// edgeThresholds 按 k3s 官方的最低要求，适用于树莓派等小型 ARM 设备
+	edgeThresholds = model.PreflightThresholds{CPUCores: 1, MemoryMB: 512, DiskGB: 8}
+)
 
-	for _, node := range nodes {
-		client := ssh.NewClient(ssh.SSHConfig{
-			Host:       node.IP,
-			Port:       node.Port,
-			Username:   node.Username,
-			AuthType:   node.AuthType,
-			Password:   node.Password,
-			PrivateKey: node.PrivateKey,
-			Passphrase: node.Passphrase,
-		})
+func thresholdsFor(profile string) model.PreflightThresholds {
+	if profile == model.ProfileEdge {
+		return edgeThresholds
+	}
+	return standardThresholds
+}
 
-		if err := client.Connect(); err != nil {
-			return fmt.Errorf("节点 %s (%s) 连接失败: %v", node.Name, node.IP, err)
+// ValidateNodes 并行预检各节点，同时预检的节点数受 preflightConcurrency 限制。
+// 某个节点失败不影响其他节点的检查，返回全部节点的结果；选项无效或防火墙端口探测失败时返回错误
+func (s *K3sService) ValidateNodes(nodes []model.NodeConfig, thresholds model.PreflightThresholds, runtime *model.RuntimeOptions, dns *model.DNSOptions, diskPrep *model.DiskPrepOptions, firewall *model.FirewallOptions, cni *model.CNIOptions) ([]model.PreflightResult, error) {
+	if runtime == nil {
+		runtime = &model.RuntimeOptions{}
+	}
+	firewall, err := firewallOptions(firewall)
+	if err != nil {
+		return nil, err
+	}
+	firewall.ExtraPorts = slices.Concat(firewall.ExtraPorts, k3s.CNIPorts(cni))
+	if dns != nil {
+		if err := validateDNSOptions(dns); err != nil {
+			return nil, err
 		}
+	}
 
-		if err := s.checkSystemRequirements(client, node.Name); err != nil {
-			client.Close()
-			return fmt.Errorf("节点 %s 系统检查失败: %v", node.Name, err)
-		}
+	s.logger.Infof("开始验证节点连接状态（%d 个节点，并发 %d）", len(nodes), s.preflightConcurrency)
 
-		client.Close()
-		s.logger.Infof("节点 %s 验证通过", node.Name)
+	results := make([]model.PreflightResult, len(nodes))
+	sem := make(chan struct{}, s.preflightConcurrency)
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node model.NodeConfig) {
+			defer wg.Done()
+			sem <- struct{}{}
+			defer func() { <-sem }()
+
+			start := time.Now()
+			results[i] = model.PreflightResult{Name: node.Name, IP: node.IP, Success: true}
+			if err := s.validateNode(node, thresholds, runtime, dns, firewall, cni, mountsDataDir(diskPrep, node.Name)); err != nil {
+				results[i].Success = false
+				results[i].Message = err.Error()
+			}
+			results[i].DurationMs = time.Since(start).Milliseconds()
+			publishPreflight(node.RequestID, results[i])
+		}(i, node)
 	}
+	wg.Wait()
 
+	// 端口探测需要各节点都已通过检查（防火墙状态已确认）
+	if firewall.Mode == model.FirewallPreserve && preflightError(results) == nil {
+		return results, s.verifyFirewallPorts(nodes)
+	}
+	return results, nil
+}
+
+// publishPreflight 发布单个节点的预检结果
+func publishPreflight(requestID string, result model.PreflightResult) {
+	event := model.ProgressEvent{
+		Type:       model.ProgressPreflight,
+		RequestID:  requestID,
+		Node:       result.Name,
+		Level:      model.LogInfo,
+		Message:    fmt.Sprintf("节点 %s (%s) 预检通过（%dms）", result.Name, result.IP, result.DurationMs),
+		DurationMs: result.DurationMs,
+	}
+	if !result.Success {
+		event.Level = model.LogError
+		event.Message = "预检失败: " + result.Message
+	}
+	progress.Publish(event)
+}
+
+// preflightError 汇总预检失败的节点
+func preflightError(results []model.PreflightResult) error {
+	var messages []string
+	for _, r := range results {
+		if !r.Success {
+			messages = append(messages, r.Message)
+		}
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("%d/%d 个节点预检失败: %s", len(messages), len(results), strings.Join(messages, "; "))
+	}
 	return nil
 }
 
-func (s *K3sService) checkSystemRequirements(client *ssh.Client, nodeName string) error {
-	const (
-		requiredSpaceGB = 450
-		defaultDataDir  = "/var/lib/rancher/k3s"
-	)
+// validateNode 连接节点并检查系统要求
+func (s *K3sService) validateNode(node model.NodeConfig, thresholds model.PreflightThresholds, runtime *model.RuntimeOptions, dns *model.DNSOptions, firewall *model.FirewallOptions, cni *model.CNIOptions, dataDisk bool) error {
+	defer benchmark.Track(node.RequestID, node.Name, model.PhasePreflight)()
+	defer progress.Phase(node.RequestID, node.Name, model.PhasePreflight)()
 
+	client := newNodeClient(node)
+	if err := client.Connect(); err != nil {
+		return fmt.Errorf("节点 %s (%s) 连接失败: %v", node.Name, node.IP, err)
+	}
+	defer client.Close()
+
+	if err := s.checkSystemRequirements(client, node.Name, node.Name == "k3s-master", thresholds, runtime, dns, firewall, cni, dataDisk); err != nil {
+		return fmt.Errorf("节点 %s 系统检查失败: %v", node.Name, err)
+	}
+	s.logger.Infof("节点 %s 验证通过", node.Name)
+	return nil
+}
+
+// checkSystemRequirements 检查节点系统要求。dataDisk 为 true 时 k3s 数据目录由 prepare-disks 挂载数据盘，不再链接到大分区
+func (s *K3sService) checkSystemRequirements(client *ssh.Client, nodeName string, isServer bool, thresholds model.PreflightThresholds, runtime *model.RuntimeOptions, dns *model.DNSOptions, firewall *model.FirewallOptions, cni *model.CNIOptions, dataDisk bool) error {
 	// 操作系统支持检测
-	result, err := client.ExecuteCommand("cat /etc/os-release")
+	osInfo, err := hostos.Detect(client)
 	if err != nil {
 		return fmt.Errorf("节点 %s 无法获取系统信息: %v", nodeName, err)
 	}
-	osRelease := strings.ToLower(result.Stdout)
-	supportedDistros := []string{"ubuntu", "debian", "raspbian", "rhel", "centos", "fedora", "opensuse", "suse", "alpine", "uoss", "kylin", "deepin"}
-	osID := ""
-	for _, line := range strings.Split(osRelease, "\n") {
-		if strings.HasPrefix(line, "id=") {
-			osID = strings.TrimPrefix(line, "id=")
-			osID = strings.Trim(osID, "\"")
-			break
-		}
-	}
-	if osID == "" {
-		return fmt.Errorf("节点 %s 无法解析操作系统 ID", nodeName)
-	}
-	supported := false
-	for _, distro := range supportedDistros {
-		if osID == distro {
-			supported = true
-			break
-		}
+	if !osInfo.Supported() {
+		return fmt.Errorf("节点 %s 操作系统不支持: %s（支持的系统: %v）", nodeName, osInfo.ID, hostos.SupportedFamilies())
 	}
-	if !supported {
-		return fmt.Errorf("节点 %s 操作系统不支持: %s（支持的系统: %v）", nodeName, osID, supportedDistros)
+	if err := osInfo.CheckServiceManager(); err != nil {
+		return fmt.Errorf("节点 %s %v", nodeName, err)
 	}
-	s.logger.Infof("节点 %s 操作系统验证通过: %s", nodeName, osID)
+	s.logger.Infof("节点 %s 操作系统验证通过: %s", nodeName, osInfo)
 
 	// root 权限检查
-	result, err = client.ExecuteCommand("id -u")
+	result, err := client.ExecuteCommand("id -u")
 	if err != nil {
 		return fmt.Errorf("节点 %s 无法获取用户权限信息: %v", nodeName, err)
 	}
@@ -102,16 +180,25 @@ func (s *K3sService) checkSystemRequirements(client *ssh.Client, nodeName string
 	}
 	s.logger.Infof("节点 %s root 权限验证通过", nodeName)
 
-	// DNS 功能检查并修复
+	// 依赖命令检查并安装
+	if err := s.ensurePrerequisites(client, nodeName, osInfo); err != nil {
+		return err
+	}
+
+	// DNS 功能检查并修复：设置了站点 DNS 时直接向站点 DNS 查询，由 prepare-nodes 统一配置，否则在解析失败时追加公共 DNS
+	managedDNS := dns != nil && len(dns.Servers) > 0
 	testDomain := "www.baidu.com" // 国内环境使用 baidu.com
-	result, err = client.ExecuteCommand(fmt.Sprintf("nslookup %s", testDomain))
+	result, err = client.ExecuteCommand(lookupCommand(testDomain, dns))
 	dnsOk := err == nil && strings.Contains(result.Stdout, "Name:")
-	if !dnsOk {
+	if !dnsOk && managedDNS {
+		return fmt.Errorf("节点 %s 使用站点 DNS %v 无法解析 %s: %v", nodeName, dns.Servers, testDomain, err)
+	} else if !dnsOk {
 		s.logger.Warnf("节点 %s 初始 DNS 解析失败，将尝试修复 /etc/resolv.conf", nodeName)
-		_, err = client.ExecuteCommand("cp /etc/resolv.conf /etc/resolv.conf.backup")
+		_, err = client.ExecuteCommand("[ -e " + resolvConfBackup + " ] || cp -P /etc/resolv.conf " + resolvConfBackup)
 		if err != nil {
 			return fmt.Errorf("节点 %s 备份 /etc/resolv.conf 失败: %v", nodeName, err)
 		}
+		s.journal.record(client, nodeName, model.ChangeResolvConf, "修改 /etc/resolv.conf", restoreResolvConfScript)
 		_, err = client.ExecuteCommand("echo 'nameserver 114.114.114.114' >> /etc/resolv.conf && echo 'nameserver 8.8.8.8' >> /etc/resolv.conf")
 		if err != nil {
 			return fmt.Errorf("节点 %s 添加 DNS 到 /etc/resolv.conf 失败: %v", nodeName, err)
@@ -128,7 +215,7 @@ func (s *K3sService) checkSystemRequirements(client *ssh.Client, nodeName string
 	// 自定义 DNS 站点解析检查
 	testDomains := []string{"get.k3s.io", "rancher-mirror.rancher.cn", "registry.cn-hangzhou.aliyuncs.com", "cdn.jsdelivr.net", "ghproxy.com"}
 	for _, domain := range testDomains {
-		result, err = client.ExecuteCommand(fmt.Sprintf("nslookup %s", domain))
+		result, err = client.ExecuteCommand(lookupCommand(domain, dns))
 		if err != nil || !strings.Contains(result.Stdout, "Name:") {
 			return fmt.Errorf("节点 %s 无法解析域名 %s: %v", nodeName, domain, err)
 		}
@@ -150,10 +237,12 @@ func (s *K3sService) checkSystemRequirements(client *ssh.Client, nodeName string
 		if err != nil {
 			return fmt.Errorf("节点 %s 临时关闭 swap 失败: %v", nodeName, err)
 		}
-		_, err = client.ExecuteCommand("sed -i '/swap/d' /etc/fstab")
+		_, err = client.ExecuteCommand("grep swap /etc/fstab >> " + swapFstabBackup + "; sed -i '/swap/d' /etc/fstab")
 		if err != nil {
 			return fmt.Errorf("节点 %s 持久关闭 swap 失败: %v", nodeName, err)
 		}
+		s.journal.record(client, nodeName, model.ChangeSwap, "关闭 swap 并删除 /etc/fstab 中的 swap 条目",
+			fmt.Sprintf("if [ -f %[1]s ]; then cat %[1]s >> /etc/fstab && rm -f %[1]s; fi; swapon -a", swapFstabBackup))
 		result, err = client.ExecuteCommand("swapon -s")
 		if err == nil && strings.TrimSpace(result.Stdout) != "" {
 			return fmt.Errorf("节点 %s swap 关闭失败，仍有 swap 启用", nodeName)
@@ -164,67 +253,46 @@ func (s *K3sService) checkSystemRequirements(client *ssh.Client, nodeName string
 	}
 
 	// nm-cloud-setup 检查并禁用（RHEL 要求）
-	result, err = client.ExecuteCommand("systemctl is-active nm-cloud-setup || echo inactive")
-	if err == nil && strings.TrimSpace(result.Stdout) == "active" {
-		s.logger.Warnf("节点 %s nm-cloud-setup 已启用，将尝试禁用", nodeName)
-		_, err = client.ExecuteCommand("systemctl disable nm-cloud-setup.service nm-cloud-setup.timer --now")
-		if err != nil {
-			return fmt.Errorf("节点 %s 禁用 nm-cloud-setup 失败: %v", nodeName, err)
-		}
-		s.logger.Infof("节点 %s nm-cloud-setup 已禁用（建议重启节点以确保生效）", nodeName)
-	} else {
-		s.logger.Infof("节点 %s nm-cloud-setup 未启用或未安装", nodeName)
-	}
-
-	// 防火墙检查并关闭
-	isUbuntu := strings.Contains(osRelease, "ubuntu") || strings.Contains(osRelease, "debian") || strings.Contains(osRelease, "raspbian")
-	isFirewalldBased := strings.Contains(osRelease, "centos") || strings.Contains(osRelease, "rhel") || strings.Contains(osRelease, "fedora") || strings.Contains(osRelease, "opensuse") || strings.Contains(osRelease, "suse")
-
-	if isUbuntu {
-		// 检查 ufw（Ubuntu/Debian/Raspberry Pi）
-		result, err = client.ExecuteCommand("command -v ufw && dpkg -l ufw >/dev/null 2>&1 && ufw status || echo inactive")
-		if err == nil && strings.Contains(strings.ToLower(result.Stdout), "status: active") {
-			s.logger.Warnf("节点 %s ufw 已启用，将尝试关闭", nodeName)
-			_, err = client.ExecuteCommand("ufw disable")
-			if err != nil {
-				return fmt.Errorf("节点 %s 禁用 ufw 失败: %v", nodeName, err)
-			}
-			result, err = client.ExecuteCommand("ufw status")
-			if err == nil && strings.Contains(strings.ToLower(result.Stdout), "status: active") {
-				return fmt.Errorf("节点 %s ufw 关闭失败，状态仍为 active", nodeName)
-			}
-			s.logger.Infof("节点 %s ufw 已成功关闭", nodeName)
-		} else {
-			s.logger.Infof("节点 %s ufw 未启用或未安装", nodeName)
-		}
-	} else if isFirewalldBased {
-		// 检查 firewalld（CentOS/RHEL/Fedora/openSUSE）
-		result, err = client.ExecuteCommand("command -v systemctl && rpm -q firewalld >/dev/null 2>&1 && systemctl is-active firewalld || echo inactive")
+	if osInfo.InitSystem == hostos.InitSystemd {
+		result, err = client.ExecuteCommand("systemctl is-active nm-cloud-setup || echo inactive")
 		if err == nil && strings.TrimSpace(result.Stdout) == "active" {
-			s.logger.Warnf("节点 %s firewalld 已启用，将尝试关闭", nodeName)
-			_, err = client.ExecuteCommand("systemctl stop firewalld")
+			s.logger.Warnf("节点 %s nm-cloud-setup 已启用，将尝试禁用", nodeName)
+			_, err = client.ExecuteCommand("systemctl disable nm-cloud-setup.service nm-cloud-setup.timer --now")
 			if err != nil {
-				return fmt.Errorf("节点 %s 停止 firewalld 失败: %v", nodeName, err)
-			}
-			_, err = client.ExecuteCommand("systemctl disable firewalld")
-			if err != nil {
-				return fmt.Errorf("节点 %s 禁用 firewalld 失败: %v", nodeName, err)
+				return fmt.Errorf("节点 %s 禁用 nm-cloud-setup 失败: %v", nodeName, err)
 			}
-			result, err = client.ExecuteCommand("systemctl is-active firewalld || echo inactive")
-			if err == nil && strings.TrimSpace(result.Stdout) != "inactive" {
-				return fmt.Errorf("节点 %s firewalld 关闭失败，状态仍为 active", nodeName)
-			}
-			s.logger.Infof("节点 %s firewalld 已成功关闭", nodeName)
+			s.journal.record(client, nodeName, model.ChangeNMCloudSetup, "禁用 nm-cloud-setup", "systemctl enable nm-cloud-setup.service nm-cloud-setup.timer --now")
+			s.logger.Infof("节点 %s nm-cloud-setup 已禁用（建议重启节点以确保生效）", nodeName)
 		} else {
-			s.logger.Infof("节点 %s firewalld 未启用或未安装", nodeName)
+			s.logger.Infof("节点 %s nm-cloud-setup 未启用或未安装", nodeName)
 		}
-	} else {
-		// 其他系统（如 Alpine、Arch）无需检查防火墙
-		s.logger.Infof("节点 %s 无需检查防火墙（非 Ubuntu 或 firewalld 基于系统）", nodeName)
+	}
+
+	// 防火墙检查：默认关闭，preserve 模式保持启用并放行 k3s 端口
+	if firewall.Mode == model.FirewallPreserve {
+		if err := s.allowFirewallPorts(client, nodeName, osInfo, isServer, firewall); err != nil {
+			return err
+		}
+	} else if err := s.disableFirewalls(client, nodeName, osInfo); err != nil {
+		return err
 	}
 
 	s.logger.Infof("节点 %s 防火墙验证通过", nodeName)
 
+	// cgroup 与已有容器运行时检查
+	if err := s.checkCgroups(client, nodeName); err != nil {
+		return err
+	}
+	if err := s.checkRuntimeConflicts(client, nodeName, osInfo, isServer, runtime); err != nil {
+		return err
+	}
+	if cni != nil {
+		if err := k3s.CheckCNIKernel(client, osInfo, cni); err != nil {
+			return fmt.Errorf("节点 %s %v", nodeName, err)
+		}
+		s.logger.Infof("节点 %s 内核满足 %s 要求", nodeName, cni.Plugin)
+	}
+
 	// CPU 检查
 	result, err = client.ExecuteCommand("nproc")
 	if err != nil {
@@ -234,8 +302,8 @@ func (s *K3sService) checkSystemRequirements(client *ssh.Client, nodeName string
 	if convErr != nil {
 		return fmt.Errorf("节点 %s CPU 核心数解析失败: %v", nodeName, convErr)
 	}
-	if cpuCoresInt < 4 {
-		s.logger.Warnf("节点 %s CPU 核心数不足: %d < 4，建议增加 CPU 资源", nodeName, cpuCoresInt)
+	if cpuCoresInt < thresholds.CPUCores {
+		s.logger.Warnf("节点 %s CPU 核心数不足: %d < %d，建议增加 CPU 资源", nodeName, cpuCoresInt, thresholds.CPUCores)
 	} else {
 		s.logger.Infof("节点 %s CPU 验证通过: %d 核", nodeName, cpuCoresInt)
 	}
@@ -249,60 +317,40 @@ func (s *K3sService) checkSystemRequirements(client *ssh.Client, nodeName string
 	if convErr != nil {
 		return fmt.Errorf("节点 %s 内存解析失败: %v", nodeName, convErr)
 	}
-	if memMB < 16384 {
-		s.logger.Warnf("节点 %s 内存不足: %d MB < 16384 MB，建议增加内存资源", nodeName, memMB)
+	if memMB < thresholds.MemoryMB {
+		s.logger.Warnf("节点 %s 内存不足: %d MB < %d MB，建议增加内存资源", nodeName, memMB, thresholds.MemoryMB)
 	} else {
 		s.logger.Infof("节点 %s 内存验证通过: %d MB", nodeName, memMB)
 	}
 
 	// 磁盘空间检查
-	result, err = client.ExecuteCommand("df -h --output=source,target,avail | grep -v tmpfs")
+	maxMountPoint, maxSpaceGB, err := largestPartition(client)
 	if err != nil {
-		return fmt.Errorf("节点 %s 无法获取磁盘分区信息: %v", nodeName, err)
+		return fmt.Errorf("节点 %s %v", nodeName, err)
 	}
-	maxSpaceGB := float64(0)
-	var maxMountPoint string
-	lines := strings.Split(strings.TrimSpace(result.Stdout), "\n")
-	for _, line := range lines {
-		fields := strings.Fields(line)
-		if len(fields) < 3 {
-			continue
-		}
-		mountPoint := fields[1]
-		avail := fields[2]
-		var availGB float64
-		if strings.HasSuffix(avail, "G") {
-			availGB, _ = strconv.ParseFloat(strings.TrimSuffix(avail, "G"), 64)
-		} else if strings.HasSuffix(avail, "M") {
-			availMB, _ := strconv.ParseFloat(strings.TrimSuffix(avail, "M"), 64)
-			availGB = availMB / 1024
-		} else if strings.HasSuffix(avail, "T") {
-			availTB, _ := strconv.ParseFloat(strings.TrimSuffix(avail, "T"), 64)
-			availGB = availTB * 1024
-		} else {
-			continue
-		}
-		if availGB > maxSpaceGB {
-			maxSpaceGB = availGB
-			maxMountPoint = mountPoint
-		}
-	}
-	if maxMountPoint == "" {
-		return fmt.Errorf("节点 %s 没有找到可用磁盘分区", nodeName)
-	}
-	if maxSpaceGB < 450 {
-		s.logger.Warnf("节点 %s 最大分区 %s 可用空间不足: %.1fGB < 450GB，建议增加磁盘空间", nodeName, maxMountPoint, maxSpaceGB)
+	if maxSpaceGB < thresholds.DiskGB {
+		s.logger.Warnf("节点 %s 最大分区 %s 可用空间不足: %.1fGB < %.0fGB，建议增加磁盘空间", nodeName, maxMountPoint, maxSpaceGB, thresholds.DiskGB)
 	} else {
-		s.logger.Infof("节点 %s 最大分区 %s 可用空间: %.1fGB，满足 450GB 要求", nodeName, maxMountPoint, maxSpaceGB)
+		s.logger.Infof("节点 %s 最大分区 %s 可用空间: %.1fGB，满足 %.0fGB 要求", nodeName, maxMountPoint, maxSpaceGB, thresholds.DiskGB)
 	}
 
 	// 软连接创建
 	newDataDir := filepath.Join(maxMountPoint, "rancher", "k3s")
-	if maxMountPoint != "/" {
+	if dataDisk {
+		s.logger.Infof("节点 %s k3s 数据目录将由 prepare-disks 挂载数据盘，跳过软链接创建", nodeName)
+	} else if maxMountPoint != "/" {
+		result, err = client.ExecuteIdempotentCommand(fmt.Sprintf("[ -e %s ] && echo exists || true", newDataDir))
+		if err != nil {
+			return fmt.Errorf("节点 %s 检查目录 %s 失败: %v", nodeName, newDataDir, err)
+		}
+		created := strings.TrimSpace(result.Stdout) != "exists"
 		_, err = client.ExecuteCommand(fmt.Sprintf("mkdir -p %s", newDataDir))
 		if err != nil {
 			return fmt.Errorf("节点 %s 创建目录 %s 失败: %v", nodeName, newDataDir, err)
 		}
+		if created {
+			s.journal.record(client, nodeName, model.ChangeDataDir, "创建数据目录 "+newDataDir, removeDataDirScript(newDataDir))
+		}
 		s.logger.Infof("节点 %s 创建数据目录 %s 成功", nodeName, newDataDir)
 
 		result, err = client.ExecuteCommand("stat /var/lib/rancher/k3s")
@@ -323,6 +371,7 @@ func (s *K3sService) checkSystemRequirements(client *ssh.Client, nodeName string
 					if err != nil {
 						return fmt.Errorf("节点 %s 创建软链接 %s -> /var/lib/rancher/k3s 失败: %v", nodeName, newDataDir, err)
 					}
+					s.journal.record(client, nodeName, model.ChangeDataDir, "创建软链接 /var/lib/rancher/k3s -> "+newDataDir, removeDataDirLinkScript)
 					s.logger.Infof("节点 %s 默认数据目录 /var/lib/rancher/k3s 已链接到 %s", nodeName, newDataDir)
 				}
 			}
@@ -335,6 +384,7 @@ func (s *K3sService) checkSystemRequirements(client *ssh.Client, nodeName string
 			if err != nil {
 				return fmt.Errorf("节点 %s 创建软链接 %s -> /var/lib/rancher/k3s 失败: %v", nodeName, newDataDir, err)
 			}
+			s.journal.record(client, nodeName, model.ChangeDataDir, "创建软链接 /var/lib/rancher/k3s -> "+newDataDir, removeDataDirLinkScript)
 			s.logger.Infof("节点 %s 默认数据目录 /var/lib/rancher/k3s 已链接到 %s", nodeName, newDataDir)
 		}
 	} else {
@@ -345,95 +395,315 @@ func (s *K3sService) checkSystemRequirements(client *ssh.Client, nodeName string
 	return nil
 }
 
-func (s *K3sService) InstallMaster(node model.NodeConfig) error {
+// largestPartition 返回可用空间最大的分区挂载点及可用空间（GB），预检据此放置 k3s 数据目录，存储配置据此放置卷数据
+func largestPartition(client *ssh.Client) (string, float64, error) {
+	result, err := client.ExecuteCommand("df -h --output=source,target,avail | grep -v tmpfs")
+	if err != nil {
+		return "", 0, fmt.Errorf("无法获取磁盘分区信息: %v", err)
+	}
+	maxSpaceGB := float64(0)
+	var maxMountPoint string
+	lines := strings.Split(strings.TrimSpace(result.Stdout), "\n")
+	for _, line := range lines {
+		fields := strings.Fields(line)
+		if len(fields) < 3 {
+			continue
+		}
+		mountPoint := fields[1]
+		avail := fields[2]
+		var availGB float64
+		if strings.HasSuffix(avail, "G") {
+			availGB, _ = strconv.ParseFloat(strings.TrimSuffix(avail, "G"), 64)
+		} else if strings.HasSuffix(avail, "M") {
+			availMB, _ := strconv.ParseFloat(strings.TrimSuffix(avail, "M"), 64)
+			availGB = availMB / 1024
+		} else if strings.HasSuffix(avail, "T") {
+			availTB, _ := strconv.ParseFloat(strings.TrimSuffix(avail, "T"), 64)
+			availGB = availTB * 1024
+		} else {
+			continue
+		}
+		if availGB > maxSpaceGB {
+			maxSpaceGB = availGB
+			maxMountPoint = mountPoint
+		}
+	}
+	if maxMountPoint == "" {
+		return "", 0, fmt.Errorf("没有找到可用磁盘分区")
+	}
+	return maxMountPoint, maxSpaceGB, nil
+}
+
+// ensurePrerequisites 检查预检和安装脚本依赖的命令，缺失时通过节点的包管理器安装
+func (s *K3sService) ensurePrerequisites(client *ssh.Client, nodeName string, osInfo *hostos.Info) error {
+	var missing, packages []string
+	for _, p := range hostos.Prerequisites {
+		if _, err := client.ExecuteIdempotentCommand(p.Check); err == nil {
+			continue
+		}
+		missing = append(missing, p.Name)
+		if pkg, ok := osInfo.PackageFor(p); ok && !slices.Contains(packages, pkg) {
+			packages = append(packages, pkg)
+		}
+	}
+	if len(missing) == 0 {
+		s.logger.Infof("节点 %s 依赖命令验证通过", nodeName)
+		return nil
+	}
+
+	s.logger.Warnf("节点 %s 缺少依赖命令 %v，将尝试安装 %v", nodeName, missing, packages)
+	cmd, err := osInfo.InstallCommand(packages...)
+	if err != nil {
+		return fmt.Errorf("节点 %s 缺少依赖命令 %v: %v", nodeName, missing, err)
+	}
+	if _, err := client.ExecuteCommand(cmd); err != nil {
+		return fmt.Errorf("节点 %s 安装依赖 %v 失败: %v", nodeName, packages, err)
+	}
+	for _, p := range hostos.Prerequisites {
+		if !slices.Contains(missing, p.Name) {
+			continue
+		}
+		if _, err := client.ExecuteIdempotentCommand(p.Check); err != nil {
+			return fmt.Errorf("节点 %s 安装后仍缺少依赖命令 %s", nodeName, p.Name)
+		}
+	}
+	s.logger.Infof("节点 %s 依赖已安装: %v", nodeName, packages)
+	return nil
+}
+
+// disableFirewalls 按实际安装的防火墙关闭，而不是按发行版猜测：
+// ufw 常见于 Debian 系，firewalld 见于 RHEL 与 openSUSE，SuSEfirewall2 见于 SLES 12 及更早版本，
+// Alpine 默认没有防火墙服务，但 iptables/awall 服务会在开机时恢复旧规则
+func (s *K3sService) disableFirewalls(client *ssh.Client, nodeName string, osInfo *hostos.Info) error {
+	result, err := client.ExecuteCommand("command -v ufw >/dev/null 2>&1 && ufw status || echo inactive")
+	if err == nil && strings.Contains(strings.ToLower(result.Stdout), "status: active") {
+		s.logger.Warnf("节点 %s ufw 已启用，将尝试关闭", nodeName)
+		if _, err = client.ExecuteCommand("ufw disable"); err != nil {
+			return fmt.Errorf("节点 %s 禁用 ufw 失败: %v", nodeName, err)
+		}
+		s.journal.record(client, nodeName, model.ChangeFirewall, "关闭 ufw", "ufw --force enable")
+		result, err = client.ExecuteCommand("ufw status")
+		if err == nil && strings.Contains(strings.ToLower(result.Stdout), "status: active") {
+			return fmt.Errorf("节点 %s ufw 关闭失败，状态仍为 active", nodeName)
+		}
+		s.logger.Infof("节点 %s ufw 已成功关闭", nodeName)
+	}
+
+	if _, err := client.ExecuteCommand("command -v firewall-cmd"); err == nil {
+		result, err = client.ExecuteCommand(osInfo.ServiceActiveCommand("firewalld") + " || true")
+		if err == nil && strings.TrimSpace(result.Stdout) == "active" {
+			s.logger.Warnf("节点 %s firewalld 已启用，将尝试关闭", nodeName)
+			if _, err = client.ExecuteCommand(osInfo.ServiceStopDisableCommand("firewalld")); err != nil {
+				return fmt.Errorf("节点 %s 停止 firewalld 失败: %v", nodeName, err)
+			}
+			s.journal.record(client, nodeName, model.ChangeFirewall, "停止并禁用 firewalld", osInfo.ServiceEnableStartCommand("firewalld"))
+			result, err = client.ExecuteCommand(osInfo.ServiceActiveCommand("firewalld") + " || true")
+			if err == nil && strings.TrimSpace(result.Stdout) == "active" {
+				return fmt.Errorf("节点 %s firewalld 关闭失败，状态仍为 active", nodeName)
+			}
+			s.logger.Infof("节点 %s firewalld 已成功关闭", nodeName)
+		} else {
+			s.logger.Infof("节点 %s firewalld 未启用", nodeName)
+		}
+	}
+
+	if osInfo.Family == hostos.FamilySUSE {
+		if _, err := client.ExecuteCommand("command -v SuSEfirewall2"); err == nil {
+			s.logger.Warnf("节点 %s 检测到 SuSEfirewall2，将尝试关闭", nodeName)
+			if _, err = client.ExecuteCommand("SuSEfirewall2 off"); err != nil {
+				return fmt.Errorf("节点 %s 关闭 SuSEfirewall2 失败: %v", nodeName, err)
+			}
+			s.journal.record(client, nodeName, model.ChangeFirewall, "关闭 SuSEfirewall2", "SuSEfirewall2 on")
+			s.logger.Infof("节点 %s SuSEfirewall2 已关闭", nodeName)
+		}
+	}
+
+	if osInfo.InitSystem == hostos.InitOpenRC {
+		for _, unit := range []string{"iptables", "ip6tables", "awall"} {
+			result, err = client.ExecuteCommand(osInfo.ServiceActiveCommand(unit) + " || true")
+			if err == nil && strings.TrimSpace(result.Stdout) == "active" {
+				s.logger.Warnf("节点 %s OpenRC 服务 %s 已启用，重启后恢复的规则可能覆盖 k3s 写入的 iptables 规则，建议执行 rc-update del %s", nodeName, unit, unit)
+			}
+		}
+	}
+	return nil
+}
+
+// CheckMirrors 并行在各节点上检查镜像源能否提供所需镜像，返回各节点的结果，没有可用镜像源的节点提前失败
+func (s *K3sService) CheckMirrors(nodes []model.NodeConfig, mirrors *model.MirrorOptions) ([]model.NodeResult, error) {
+	s.logger.DeploymentStep("check-mirrors", "cluster")
+
+	return runOnNodes(nodes, func(i int, client *ssh.Client) error {
+		return s.installer.CheckMirrors(client, nodes[i].Name, mirrors)
+	}), nil
+}
+
+// InstallMaster 安装 Master 节点，返回安装过程中校验的文件摘要
+func (s *K3sService) InstallMaster(node model.NodeConfig, policy k3s.WaitPolicy, opts k3s.InstallOptions) ([]k3s.Digest, error) {
 	s.logger.DeploymentStep("install-master", node.Name)
 
-	client := ssh.NewClient(ssh.SSHConfig{
-		Host:       node.IP,
-		Port:       node.Port,
-		Username:   node.Username,
-		AuthType:   node.AuthType,
-		Password:   node.Password,
-		PrivateKey: node.PrivateKey,
-		Passphrase: node.Passphrase,
-	})
+	client := newNodeClient(node)
+
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	digests, err := s.installer.InstallMaster(client, node.Name, policy, opts)
+	if err != nil {
+		return digests, err
+	}
+	if opts.Security != nil && opts.Security.PodSecurity != nil {
+		if err := s.manager.ApplyNamespaceSecurity(client, opts.Security.PodSecurity.Namespaces); err != nil {
+			return digests, err
+		}
+	}
+	if opts.CNI != nil {
+		if err := s.manager.WaitCNI(client, opts.CNI, []string{"k3s-master"}, policy); err != nil {
+			return digests, err
+		}
+	}
+	return digests, nil
+}
+
+// WaitCNI 通过 Master 等待 CNI 插件在各节点就绪
+func (s *K3sService) WaitCNI(masterNode model.NodeConfig, cni *model.CNIOptions, nodes []string, policy k3s.WaitPolicy) error {
+	client := newNodeClient(masterNode)
 
 	if err := client.Connect(); err != nil {
 		return fmt.Errorf("连接Master节点失败: %v", err)
 	}
 	defer client.Close()
 
-	return s.installer.InstallMaster(client, node.Name)
+	return s.manager.WaitCNI(client, cni, nodes, policy)
+}
+
+// WaitNodesReady 在 Master 上等待期望的节点以期望的名称注册并 Ready
+func (s *K3sService) WaitNodesReady(masterNode model.NodeConfig, expected []model.NodeReadiness, policy k3s.WaitPolicy) ([]model.NodeReadiness, error) {
+	s.logger.DeploymentStep("wait-nodes", "cluster")
+
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	policy = policy.WithDefaults()
+	return s.manager.WaitNodesReady(client, expected, policy.DeploymentTimeout, policy.PollInterval)
 }
 
-func (s *K3sService) ConfigureAgent(masterNode, agentNode model.NodeConfig, agentIndex int) error {
+// ConfigureAgent 安装第 agentIndex 个 Agent 节点，返回安装过程中校验的文件摘要
+func (s *K3sService) ConfigureAgent(masterNode, agentNode model.NodeConfig, agentIndex int, policy k3s.WaitPolicy, opts k3s.InstallOptions) ([]k3s.Digest, error) {
 	s.logger.DeploymentStep("configure-agent", agentNode.Name)
 
 	// 获取Master节点token
-	masterClient := ssh.NewClient(ssh.SSHConfig{
-		Host:       masterNode.IP,
-		Port:       masterNode.Port,
-		Username:   masterNode.Username,
-		AuthType:   masterNode.AuthType,
-		Password:   masterNode.Password,
-		PrivateKey: masterNode.PrivateKey,
-		Passphrase: masterNode.Passphrase,
-	})
+	masterClient := newNodeClient(masterNode)
 
 	if err := masterClient.Connect(); err != nil {
-		return fmt.Errorf("连接Master节点获取token失败: %v", err)
+		return nil, fmt.Errorf("连接Master节点获取token失败: %v", err)
 	}
 
 	token, err := s.manager.GetNodeToken(masterClient)
 	if err != nil {
 		masterClient.Close()
-		return fmt.Errorf("获取节点token失败: %v", err)
+		return nil, fmt.Errorf("获取节点token失败: %v", err)
+	}
+
+	serverURL, err := s.installer.ServerURL(masterClient)
+	masterClient.Close()
+	if err != nil {
+		return nil, err
 	}
 
 	// 连接Agent节点
-	agentClient := ssh.NewClient(ssh.SSHConfig{
-		Host:       agentNode.IP,
-		Port:       agentNode.Port,
-		Username:   agentNode.Username,
-		AuthType:   agentNode.AuthType,
-		Password:   agentNode.Password,
-		PrivateKey: agentNode.PrivateKey,
-		Passphrase: agentNode.Passphrase,
-	})
+	agentClient := newNodeClient(agentNode)
 
 	if err := agentClient.Connect(); err != nil {
-		masterClient.Close()
-		return fmt.Errorf("连接Agent节点失败: %v", err)
+		return nil, fmt.Errorf("连接Agent节点失败: %v", err)
 	}
 	defer agentClient.Close()
 
 	// 动态生成Agent节点名称
-	agentNodeName := "k3s-agent"
-	if agentIndex > 0 {
-		agentNodeName = fmt.Sprintf("k3s-agent-%d", agentIndex+1)
+	agentNodeName := clusterAgentName(agentIndex)
+
+	digests, err := s.installer.InstallAgent(agentClient, serverURL, agentNodeName, token, policy, opts)
+	if err != nil {
+		return digests, fmt.Errorf("配置Agent节点 %s 失败: %w", agentNodeName, err)
 	}
 
-	err = s.installer.InstallAgent(agentClient, masterClient, agentNodeName, token)
-	masterClient.Close()
+	return digests, nil
+}
+
+// JoinAgent 将节点以 Agent 身份加入外部的中心 server。多个站点加入同一集群，节点名称使用请求中的节点名以免冲突
+func (s *K3sService) JoinAgent(node model.NodeConfig, serverURL, token string, policy k3s.WaitPolicy, opts k3s.InstallOptions) ([]k3s.Digest, error) {
+	s.logger.DeploymentStep("configure-agent", node.Name)
+
+	nodeName, err := hostos.NormalizeHostname(node.Name)
+	if err != nil {
+		return nil, err
+	}
+
+	client := newNodeClient(node)
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Agent节点失败: %v", err)
+	}
+	defer client.Close()
+
+	digests, err := s.installer.InstallAgent(client, serverURL, nodeName, token, policy, opts)
 	if err != nil {
-		return fmt.Errorf("配置Agent节点 %s 失败: %v", agentNodeName, err)
+		return digests, fmt.Errorf("节点 %s 加入 %s 失败: %w", node.Name, serverURL, err)
 	}
+	return digests, nil
+}
 
+// CheckServerReachable 检查各节点能否访问中心 server
+func (s *K3sService) CheckServerReachable(nodes []model.NodeConfig, serverURL string) error {
+	for _, node := range nodes {
+		client := newNodeClient(node)
+		if err := client.Connect(); err != nil {
+			return fmt.Errorf("节点 %s (%s) 连接失败: %v", node.Name, node.IP, err)
+		}
+		err := k3s.CheckServerReachable(client, serverURL)
+		client.Close()
+		if err != nil {
+			return fmt.Errorf("节点 %s %v", node.Name, err)
+		}
+	}
+	s.logger.Infof("所有节点均可访问 server %s", serverURL)
 	return nil
 }
 
+// clusterAgentName 第 index 个 Agent 节点（按请求中的顺序，不含 Master）在集群中的节点名称
+func clusterAgentName(index int) string {
+	if index > 0 {
+		return fmt.Sprintf("k3s-agent-%d", index+1)
+	}
+	return "k3s-agent"
+}
+
+// clusterNodeNames 请求中的节点名称 -> 集群中的节点名称，与 install-master、configure-agent 的命名一致
+func clusterNodeNames(nodes []model.NodeConfig) map[string]string {
+	names := make(map[string]string, len(nodes))
+	agentIndex := 0
+	for _, node := range nodes {
+		if node.Name == "k3s-master" {
+			names[node.Name] = "k3s-master"
+			continue
+		}
+		names[node.Name] = clusterAgentName(agentIndex)
+		if node.ClusterName != "" {
+			names[node.Name] = node.ClusterName
+		}
+		agentIndex++
+	}
+	return names
+}
+
 func (s *K3sService) ApplyLabels(masterNode model.NodeConfig, labels map[string][]string) error {
 	s.logger.DeploymentStep("apply-labels", "cluster")
 
-	client := ssh.NewClient(ssh.SSHConfig{
-		Host:       masterNode.IP,
-		Port:       masterNode.Port,
-		Username:   masterNode.Username,
-		AuthType:   masterNode.AuthType,
-		Password:   masterNode.Password,
-		PrivateKey: masterNode.PrivateKey,
-		Passphrase: masterNode.Passphrase,
-	})
+	client := newNodeClient(masterNode)
 
 	if err := client.Connect(); err != nil {
 		return fmt.Errorf("连接Master节点失败: %v", err)
@@ -443,44 +713,391 @@ func (s *K3sService) ApplyLabels(masterNode model.NodeConfig, labels map[string]
 	return s.manager.ApplyNodeLabels(client, labels)
 }
 
-func (s *K3sService) DeployInSuite(masterNode model.NodeConfig, roleAssignment map[string]string) error {
+// DeployInSuite 在 Master 节点以 workspaceID 命名的独立工作目录中上传清单并部署，
+// 完成后清理工作目录，返回上传过的文件路径
+func (s *K3sService) DeployInSuite(masterNode model.NodeConfig, workspaceID string, roleAssignment map[string]string, policy k3s.WaitPolicy, spec k3s.AppSpec) ([]string, string, error) {
 	s.logger.DeploymentStep("deploy-insuite", "cluster")
 
-	client := ssh.NewClient(ssh.SSHConfig{
-		Host:       masterNode.IP,
-		Port:       masterNode.Port,
-		Username:   masterNode.Username,
-		AuthType:   masterNode.AuthType,
-		Password:   masterNode.Password,
-		PrivateKey: masterNode.PrivateKey,
-		Passphrase: masterNode.Passphrase,
-	})
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return nil, "", fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	ws, err := k3s.NewWorkspace(client, workspaceID)
+	if err != nil {
+		return nil, "", err
+	}
+	defer s.cleanupWorkspace(ws)
+
+	if err := s.manager.DeployInSuite(client, ws, roleAssignment, policy, spec); err != nil {
+		return ws.Artifacts(), "", err
+	}
+
+	url, err := s.manager.AccessURL(client, spec, masterNode.IP, policy)
+	if err != nil {
+		return ws.Artifacts(), "", err
+	}
+	s.logger.Infof("inSuite 访问地址: %s", url)
+	return ws.Artifacts(), url, nil
+}
+
+// ConfigureCoreDNS 在 Master 节点应用 CoreDNS 自定义配置，返回上传过的文件路径
+func (s *K3sService) ConfigureCoreDNS(masterNode model.NodeConfig, workspaceID string, cfg k3s.CoreDNSConfig, policy k3s.WaitPolicy) ([]string, error) {
+	s.logger.DeploymentStep("configure-dns", "cluster")
+
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	ws, err := k3s.NewWorkspace(client, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer s.cleanupWorkspace(ws)
+
+	err = s.manager.ApplyCoreDNS(client, ws, cfg, policy)
+	return ws.Artifacts(), err
+}
+
+// CheckHealth 读取集群节点状态和证书有效期
+func (s *K3sService) CheckHealth(masterNode model.NodeConfig) (*k3s.Health, error) {
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.CheckHealth(client)
+}
+
+// ListEvents 读取集群当前保留的 Kubernetes 事件
+func (s *K3sService) ListEvents(masterNode model.NodeConfig) ([]model.ClusterEvent, error) {
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.ListEvents(client)
+}
+
+// ReadAuditLog 读取 Master 审计日志中不早于 since 的记录
+func (s *K3sService) ReadAuditLog(masterNode model.NodeConfig, since string) ([]model.KubeAuditEntry, string, error) {
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return nil, "", fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.ReadAuditLog(client, since)
+}
+
+// CheckCapacity 检查带角色标签的节点能否容纳 inSuite 各组件的副本
+func (s *K3sService) CheckCapacity(masterNode model.NodeConfig, spec k3s.AppSpec) (*model.CapacityReport, error) {
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.CheckCapacity(client, spec)
+}
+
+// ListWorkloads 读取集群中的工作负载清单
+func (s *K3sService) ListWorkloads(masterNode model.NodeConfig) ([]model.Workload, error) {
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.ListWorkloads(client)
+}
+
+// Metrics 通过 metrics-server 读取节点和 Pod 资源用量
+func (s *K3sService) Metrics(masterNode model.NodeConfig) (*model.ClusterMetrics, error) {
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.Metrics(client)
+}
+
+// PruneWorkspaces 清理 Master 节点上过期的部署工作目录
+func (s *K3sService) PruneWorkspaces(masterNode model.NodeConfig, olderThan time.Duration) error {
+	client := newNodeClient(masterNode)
 
 	if err := client.Connect(); err != nil {
 		return fmt.Errorf("连接Master节点失败: %v", err)
 	}
 	defer client.Close()
 
-	return s.manager.DeployInSuite(client, roleAssignment)
+	return k3s.PruneWorkspaces(client, olderThan)
 }
 
-func (s *K3sService) VerifyDeployment(masterNode model.NodeConfig) error {
+func (s *K3sService) cleanupWorkspace(ws *k3s.Workspace) {
+	if err := ws.Cleanup(); err != nil {
+		s.logger.Warnf("%v", err)
+	}
+}
+
+func (s *K3sService) VerifyDeployment(masterNode model.NodeConfig, namespace string) error {
 	s.logger.DeploymentStep("verify", "cluster")
 
-	client := ssh.NewClient(ssh.SSHConfig{
-		Host:       masterNode.IP,
-		Port:       masterNode.Port,
-		Username:   masterNode.Username,
-		AuthType:   masterNode.AuthType,
-		Password:   masterNode.Password,
-		PrivateKey: masterNode.PrivateKey,
-		Passphrase: masterNode.Passphrase,
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.VerifyDeployment(client, namespace)
+}
+
+// AccessURL 按访问方式获取 inSuite 应用实例的访问地址
+func (s *K3sService) AccessURL(masterNode model.NodeConfig, spec k3s.AppSpec, policy k3s.WaitPolicy) (string, error) {
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return "", fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.AccessURL(client, spec, masterNode.IP, policy)
+}
+
+// CheckNetwork 部署临时检查 Pod 验证集群 DNS、Service 与跨节点网络，workspaceID 为空时生成
+func (s *K3sService) CheckNetwork(masterNode model.NodeConfig, workspaceID, externalName string, checkPolicy bool, policy k3s.WaitPolicy) ([]model.NetworkCheck, error) {
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	if workspaceID == "" {
+		id, err := utils.GenerateID("network")
+		if err != nil {
+			return nil, err
+		}
+		workspaceID = id
+	}
+	ws, err := k3s.NewWorkspace(client, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer s.cleanupWorkspace(ws)
+
+	return s.manager.CheckNetwork(client, ws, externalName, checkPolicy, policy)
+}
+
+// DeleteInstance 删除 inSuite 应用实例的命名空间
+func (s *K3sService) DeleteInstance(masterNode model.NodeConfig, instance, namespace string, policy k3s.WaitPolicy) error {
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.DeleteInstance(client, instance, namespace, policy)
+}
+
+// DiscoverCluster 连接已有集群的 server 节点，发现版本、token 和节点
+func (s *K3sService) DiscoverCluster(masterNode model.NodeConfig) (*k3s.Discovery, error) {
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.DiscoverCluster(client)
+}
+
+// QuotaUsage 读取实例命名空间资源配额的上限与用量
+func (s *K3sService) QuotaUsage(masterNode model.NodeConfig) ([]model.NamespaceQuota, error) {
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.QuotaUsage(client)
+}
+
+// EnsureAccess 检查保存的管理员 kubeconfig 和 join token，失效或为空时从 Master 重新读取，
+// 返回有效的 kubeconfig、token 以及是否重新读取
+func (s *K3sService) EnsureAccess(masterNode model.NodeConfig, kubeconfig, token string) (string, string, bool, error) {
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return "", "", false, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.EnsureAccess(client, masterNode.IP, kubeconfig, token)
+}
+
+// CreateJoinToken 在 Master 上创建 ttl 后过期的引导 token
+func (s *K3sService) CreateJoinToken(masterNode model.NodeConfig, ttl time.Duration, caHash string) (string, error) {
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return "", fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.CreateJoinToken(client, ttl, caHash)
+}
+
+// CheckDrift 比对期望状态与集群实际状态，reconcile 为 true 时尝试修复。
+// journald 配置在 Master 和 agents 的每个节点上检查和修复
+func (s *K3sService) CheckDrift(masterNode model.NodeConfig, agents []model.NodeConfig, desired *model.DesiredState, reconcile bool) ([]model.DriftItem, error) {
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	id, err := utils.GenerateID("drift")
+	if err != nil {
+		return nil, err
+	}
+	ws, err := k3s.NewWorkspace(client, id)
+	if err != nil {
+		return nil, err
+	}
+	defer s.cleanupWorkspace(ws)
+
+	items, err := s.manager.DetectDrift(client, ws, desired)
+	if err != nil {
+		return nil, err
+	}
+	if reconcile && len(items) > 0 {
+		s.manager.ReconcileDrift(client, ws, desired, items)
+	}
+	if desired.LogRotation != nil {
+		items = append(items, s.journaldDrift(append([]model.NodeConfig{masterNode}, agents...), desired.LogRotation, reconcile)...)
+	}
+	return items, nil
+}
+
+// journaldDrift 并行检查各节点的 journald 配置，reconcile 为 true 时重写不一致的配置。
+// 无法连接或检查失败的节点作为未修复的漂移项返回
+func (s *K3sService) journaldDrift(nodes []model.NodeConfig, opts *model.LogRotationOptions, reconcile bool) []model.DriftItem {
+	drifted := make([]*model.DriftItem, len(nodes))
+	results := runOnNodes(nodes, func(i int, client *ssh.Client) error {
+		item, err := s.manager.JournaldDrift(client, nodes[i].Name, opts)
+		if err != nil || item == nil {
+			return err
+		}
+		drifted[i] = item
+		if !reconcile {
+			return nil
+		}
+		if err := s.manager.ReconcileJournald(client, opts); err != nil {
+			item.Message = fmt.Sprintf("修复失败: %v", err)
+			s.logger.Errorf("修复节点 %s 的 journald 配置失败: %v", nodes[i].Name, err)
+			return nil
+		}
+		item.Reconciled = true
+		s.logger.Infof("已修复节点 %s 的 journald 配置", nodes[i].Name)
+		return nil
 	})
 
+	var items []model.DriftItem
+	for i, r := range results {
+		switch {
+		case !r.Success:
+			items = append(items, model.DriftItem{
+				Kind:    model.DriftLogRotation,
+				Target:  nodes[i].Name + ":" + k3s.JournaldDropInPath,
+				Message: fmt.Sprintf("检查失败: %s", r.Message),
+			})
+		case drifted[i] != nil:
+			items = append(items, *drifted[i])
+		}
+	}
+	return items
+}
+
+// ReconcileNodeLabels 将节点标签与污点调整为期望状态，dryRun 时只返回变更计划
+func (s *K3sService) ReconcileNodeLabels(masterNode model.NodeConfig, desired, previous *model.DesiredState, dryRun bool) ([]model.LabelChange, error) {
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.ReconcileNodeLabels(client, desired, previous, dryRun)
+}
+
+// DrainNode 将节点标记为不可调度并驱逐其上的 Pod，驱逐失败时节点保持 cordon
+func (s *K3sService) DrainNode(masterNode model.NodeConfig, node string, opts k3s.DrainOptions) (*model.DrainResult, error) {
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	if err := s.manager.ValidateNodeName(client, node); err != nil {
+		return nil, err
+	}
+	if err := s.manager.Cordon(client, node); err != nil {
+		return nil, err
+	}
+	return s.manager.Drain(client, node, opts)
+}
+
+// UncordonNode 恢复节点调度
+func (s *K3sService) UncordonNode(masterNode model.NodeConfig, node string) error {
+	client := newNodeClient(masterNode)
+
 	if err := client.Connect(); err != nil {
 		return fmt.Errorf("连接Master节点失败: %v", err)
 	}
 	defer client.Close()
 
-	return s.manager.VerifyDeployment(client)
+	if err := s.manager.ValidateNodeName(client, node); err != nil {
+		return err
+	}
+	return s.manager.Uncordon(client, node)
+}
+
+// PrePullImages 按角色分配在对应节点上并行预拉取 inSuite 组件镜像，返回承担角色的节点的结果
+func (s *K3sService) PrePullImages(nodes []model.NodeConfig, roleAssignment map[string]string) ([]model.NodeResult, error) {
+	s.logger.DeploymentStep("prepull-images", "cluster")
+
+	nodeImages := make(map[string][]string)
+	for role, nodeName := range roleAssignment {
+		nodeImages[nodeName] = append(nodeImages[nodeName], k3s.InSuiteImages[role]...)
+	}
+
+	var targets []model.NodeConfig
+	for _, node := range nodes {
+		if len(nodeImages[node.Name]) > 0 {
+			targets = append(targets, node)
+		}
+	}
+	return runOnNodes(targets, func(i int, client *ssh.Client) error {
+		return s.manager.PrePullImages(client, targets[i].Name, nodeImages[targets[i].Name])
+	}), nil
 }
@@ -0,0 +1,178 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/store"
+	"k3s-deploy-backend/pkg/utils"
+)
+
+// kubeAuditLease 多副本部署时同一周期只由一个副本收集审计日志
+const kubeAuditLease = "kube-audit"
+
+// maxClusterAuditEntries 每个集群最多保留的审计记录数，超出时丢弃最旧的
+const maxClusterAuditEntries = 5000
+
+// KubeAuditOptions 审计日志收集参数
+type KubeAuditOptions struct {
+	Interval time.Duration
+	// Retention 超过该时长的记录被丢弃
+	Retention time.Duration
+}
+
+// KubeAuditService 定期通过 SSH 读取受管集群 Master 上的 API Server 审计日志，在后端保存最近的记录供合规审查。
+// 节点上的日志按大小轮转且只有 root 可读，审查时不必登录节点
+type KubeAuditService struct {
+	opts           KubeAuditOptions
+	store          store.Store
+	clusterService *ClusterService
+	k3sService     *K3sService
+	logger         *logger.Logger
+	owner          string
+}
+
+func NewKubeAuditService(opts KubeAuditOptions, st store.Store, clusterService *ClusterService, k3sService *K3sService, logger *logger.Logger) *KubeAuditService {
+	hostname, _ := os.Hostname()
+	owner, err := utils.GenerateID(hostname)
+	if err != nil {
+		owner = fmt.Sprintf("%s-%d", hostname, time.Now().UnixNano())
+	}
+
+	return &KubeAuditService{
+		opts:           opts,
+		store:          st,
+		clusterService: clusterService,
+		k3sService:     k3sService,
+		logger:         logger,
+		owner:          owner,
+	}
+}
+
+// Start 按固定周期收集审计日志
+func (s *KubeAuditService) Start() {
+	s.logger.Infof("审计日志收集已启用，周期 %s，保留 %s", s.opts.Interval, s.opts.Retention)
+	go func() {
+		ticker := time.NewTicker(s.opts.Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			acquired, err := s.store.AcquireLease(kubeAuditLease, s.owner, s.opts.Interval-time.Second)
+			if err != nil {
+				s.logger.Errorf("获取审计日志收集租约失败: %v", err)
+				continue
+			}
+			if !acquired {
+				continue
+			}
+			if err := s.Collect(); err != nil {
+				s.logger.Errorf("审计日志收集失败: %v", err)
+			}
+		}
+	}()
+}
+
+// Collect 收集所有受管集群的审计日志，未启用审计日志的集群跳过，单个集群失败不影响其他集群
+func (s *KubeAuditService) Collect() error {
+	clusters, err := s.clusterService.List()
+	if err != nil {
+		return err
+	}
+	for _, cluster := range clusters {
+		if _, err := s.collect(cluster); err != nil && !errors.Is(err, k3s.ErrAuditLogMissing) {
+			s.logger.Warnf("收集集群 %s 审计日志失败: %v", cluster.Name, err)
+		}
+	}
+	return nil
+}
+
+// CollectCluster 立即收集指定集群的审计日志
+func (s *KubeAuditService) CollectCluster(clusterID string) (*model.ClusterAuditLog, error) {
+	cluster, err := s.clusterService.Get(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	return s.collect(cluster)
+}
+
+// collect 从上次收集的位置读取新记录，按审计 ID 和阶段去重后合并，并丢弃过期记录
+func (s *KubeAuditService) collect(cluster *model.Cluster) (*model.ClusterAuditLog, error) {
+	master, err := s.clusterService.MasterNode(cluster)
+	if err != nil {
+		return nil, err
+	}
+	record, err := s.load(cluster.ID)
+	if err != nil {
+		return nil, err
+	}
+	entries, cursor, err := s.k3sService.ReadAuditLog(master, record.Cursor)
+	if err != nil {
+		return nil, err
+	}
+
+	// 与游标同一时刻的记录会被再次读取
+	seen := make(map[string]bool, len(record.Entries))
+	for _, entry := range record.Entries {
+		seen[entry.AuditID+"/"+entry.Stage] = true
+	}
+	for _, entry := range entries {
+		if key := entry.AuditID + "/" + entry.Stage; !seen[key] {
+			seen[key] = true
+			record.Entries = append(record.Entries, entry)
+		}
+	}
+
+	now := time.Now()
+	cutoff := now.Add(-s.opts.Retention)
+	kept := record.Entries[:0]
+	for _, entry := range record.Entries {
+		if !entry.Time.Before(cutoff) {
+			kept = append(kept, entry)
+		}
+	}
+	record.Entries = kept
+	sort.SliceStable(record.Entries, func(i, j int) bool { return record.Entries[i].Time.After(record.Entries[j].Time) })
+	if len(record.Entries) > maxClusterAuditEntries {
+		record.Entries = record.Entries[:maxClusterAuditEntries]
+	}
+	record.Cursor = cursor
+	record.CollectedAt = now
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Put(store.CollectionKubeAuditLogs, cluster.ID, data); err != nil {
+		return nil, err
+	}
+	return record, nil
+}
+
+// Entries 返回集群最近一次收集保存的审计记录，尚未收集过时返回空列表
+func (s *KubeAuditService) Entries(clusterID string) (*model.ClusterAuditLog, error) {
+	if _, err := s.clusterService.Get(clusterID); err != nil {
+		return nil, err
+	}
+	return s.load(clusterID)
+}
+
+func (s *KubeAuditService) load(clusterID string) (*model.ClusterAuditLog, error) {
+	record := &model.ClusterAuditLog{ClusterID: clusterID, Entries: []model.KubeAuditEntry{}}
+	data, err := s.store.Get(store.CollectionKubeAuditLogs, clusterID)
+	if errors.Is(err, store.ErrNotFound) {
+		return record, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("解析集群 %s 审计记录失败: %v", clusterID, err)
+	}
+	return record, nil
+}
@@ -0,0 +1,232 @@
+package service
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+)
+
+// modeNodes 各部署模式使用的节点数
+var modeNodes = map[string]int{
+	"single": 1,
+	"dual":   2,
+	"triple": 3,
+}
+
+// modeLayout 各部署模式下每个角色的节点序号（0 为 Master，其余为 Agent）：
+// dual 将 I/O 密集的数据库单独放在 Agent 上；triple 三个角色各占一个节点，Master 只承载较轻的中间件
+var modeLayout = map[string]map[string]int{
+	"single": {k3s.RoleDatabase: 0, k3s.RoleMiddleware: 0, k3s.RoleApp: 0},
+	"dual":   {k3s.RoleDatabase: 1, k3s.RoleMiddleware: 0, k3s.RoleApp: 0},
+	"triple": {k3s.RoleDatabase: 1, k3s.RoleMiddleware: 0, k3s.RoleApp: 2},
+}
+
+// modeDescriptions 各部署模式的说明，随角色模板返回
+var modeDescriptions = map[string]string{
+	"single": "单节点：Master 同时承载数据库、中间件和应用",
+	"dual":   "双节点：数据库独占第一个 Agent，中间件和应用在 Master",
+	"triple": "三节点：中间件在 Master，数据库和应用分别在两个 Agent",
+}
+
+// roleSteps 使用 roleAssignment 或 insuite 标签的步骤，执行前按角色模板补全
+var roleSteps = map[string]bool{
+	pipelineStep:     true,
+	"apply-labels":   true,
+	"prepull-images": true,
+	"deploy-insuite": true,
+}
+
+// RoleTemplates 返回各部署模式的角色模板
+func (s *DeployService) RoleTemplates() []model.RoleTemplate {
+	templates := make([]model.RoleTemplate, 0, len(modeNodes))
+	for _, mode := range []string{"single", "dual", "triple"} {
+		roles := make(map[string]string, len(modeLayout[mode]))
+		for role, index := range modeLayout[mode] {
+			roles[role] = "master"
+			if index > 0 {
+				roles[role] = fmt.Sprintf("agent-%d", index)
+			}
+		}
+		templates = append(templates, model.RoleTemplate{
+			DeployMode:  mode,
+			Nodes:       modeNodes[mode],
+			Server:      "k3s-master",
+			Roles:       roles,
+			Description: modeDescriptions[mode],
+		})
+	}
+	return templates
+}
+
+// ResolveRoles 请求未设置 roleAssignment 时按部署模式的角色模板分配，labels 同时未设置时一并生成，
+// 已设置的 roleAssignment 和 labels 保持不变
+func (s *DeployService) ResolveRoles(req *model.DeployRequest) error {
+	if len(req.RoleAssignment) > 0 || !roleSteps[req.Step] || joinsServer(req) {
+		return nil
+	}
+	if _, ok := modeNodes[req.DeployMode]; !ok {
+		return fmt.Errorf("未设置 roleAssignment 时需要指定部署模式 single、dual 或 triple")
+	}
+	names := make([]string, 0, len(req.Nodes))
+	for _, node := range req.Nodes {
+		names = append(names, node.Name)
+	}
+	plan, err := s.PlanLabels(&model.LabelPlanRequest{
+		DeployMode: req.DeployMode,
+		Nodes:      names,
+		Components: req.Components,
+	})
+	if err != nil {
+		return fmt.Errorf("按 %s 模式分配组件角色失败: %w", req.DeployMode, err)
+	}
+	req.RoleAssignment = plan.RoleAssignment
+	if req.Labels == nil {
+		req.Labels = plan.Labels
+	}
+
+	log := s.logger.WithField("requestId", req.RequestID)
+	log.Infof("未设置 roleAssignment，按 %s 模式分配: database=%s，middleware=%s，app=%s", req.DeployMode,
+		plan.RoleAssignment[k3s.RoleDatabase], plan.RoleAssignment[k3s.RoleMiddleware], plan.RoleAssignment[k3s.RoleApp])
+	for _, note := range plan.Notes {
+		log.Info(note)
+	}
+	return nil
+}
+
+// checkModeNodes 节点数不少于部署模式需要的节点数，只用于按部署模式分配角色的请求
+func checkModeNodes(req *model.DeployRequest) error {
+	if need := modeNodes[req.DeployMode]; len(req.Nodes) < need {
+		return fmt.Errorf("%s 模式需要 %d 个节点，请求中只有 %d 个", req.DeployMode, need, len(req.Nodes))
+	}
+	return nil
+}
+
+// PlanLabels 根据部署模式和节点生成 insuite.database/middleware/app 标签及 roleAssignment，
+// 只计算方案不连接节点，由用户确认后随部署请求提交
+func (s *DeployService) PlanLabels(req *model.LabelPlanRequest) (*model.LabelPlan, error) {
+	nodes, err := planNodes(req.Nodes)
+	if err != nil {
+		return nil, err
+	}
+	need := modeNodes[req.DeployMode]
+	if len(nodes) < need {
+		return nil, fmt.Errorf("%s 模式需要 %d 个节点，请求中只有 %d 个", req.DeployMode, need, len(nodes))
+	}
+
+	plan := &model.LabelPlan{
+		DeployMode:     req.DeployMode,
+		RoleAssignment: make(map[string]string),
+		Labels:         make(map[string][]string),
+		Notes:          []string{},
+	}
+	roles := []string{k3s.RoleDatabase, k3s.RoleMiddleware, k3s.RoleApp}
+	for _, role := range roles {
+		node := nodes[modeLayout[req.DeployMode][role]]
+		if pinned, ok := req.RoleAssignment[role]; ok {
+			if !slices.Contains(nodes, pinned) {
+				return nil, fmt.Errorf("roleAssignment 中角色 %s 指定的节点 %s 不在节点列表中", role, pinned)
+			}
+			node = pinned
+		}
+		plan.RoleAssignment[role] = node
+	}
+	for role := range req.RoleAssignment {
+		if _, ok := modeLayout[req.DeployMode][role]; !ok {
+			return nil, fmt.Errorf("未知的组件角色: %s（可选 database、middleware、app）", role)
+		}
+	}
+
+	labeled := make(map[string]map[string]bool)
+	addLabel := func(node, role string) {
+		if labeled[node] == nil {
+			labeled[node] = make(map[string]bool)
+		}
+		labeled[node][role] = true
+	}
+	for _, role := range roles {
+		addLabel(plan.RoleAssignment[role], role)
+	}
+
+	// 应用组件多副本时将 insuite.app 标签扩展到更多节点，优先不承载数据库的节点
+	replicas, antiAffinity := 1, k3s.AntiAffinityPreferred
+	if opts := req.Components[k3s.RoleApp]; opts != nil {
+		if opts.Replicas > 0 {
+			replicas = opts.Replicas
+		}
+		if opts.AntiAffinity != "" {
+			antiAffinity = opts.AntiAffinity
+		}
+	}
+	if replicas > 1 {
+		candidates := make([]string, 0, len(nodes))
+		for _, node := range nodes {
+			if !labeled[node][k3s.RoleDatabase] {
+				candidates = append(candidates, node)
+			}
+		}
+		for _, node := range nodes {
+			if labeled[node][k3s.RoleDatabase] {
+				candidates = append(candidates, node)
+			}
+		}
+		appNodes := 1
+		for _, node := range candidates {
+			if appNodes >= replicas {
+				break
+			}
+			if labeled[node][k3s.RoleApp] {
+				continue
+			}
+			addLabel(node, k3s.RoleApp)
+			appNodes++
+		}
+		if appNodes < replicas {
+			if antiAffinity == k3s.AntiAffinityRequired {
+				return nil, fmt.Errorf("应用组件要求每个节点最多一个副本，但只有 %d 个节点，少于副本数 %d", appNodes, replicas)
+			}
+			plan.Notes = append(plan.Notes, fmt.Sprintf("应用组件 %d 个副本分布在 %d 个节点上，部分节点将运行多个副本", replicas, appNodes))
+		} else {
+			plan.Notes = append(plan.Notes, fmt.Sprintf("应用组件 %d 个副本分散到 %d 个带 insuite.app 标签的节点", replicas, appNodes))
+		}
+	}
+
+	for _, node := range nodes {
+		if len(labeled[node]) == 0 {
+			plan.Notes = append(plan.Notes, fmt.Sprintf("节点 %s 未分配 inSuite 角色，仅作为集群计算资源", node))
+			continue
+		}
+		for _, role := range roles {
+			if labeled[node][role] {
+				plan.Labels[node] = append(plan.Labels[node], fmt.Sprintf("insuite.%s=true", role))
+			}
+		}
+	}
+	if db := plan.RoleAssignment[k3s.RoleDatabase]; db == "k3s-master" && len(nodes) > 1 {
+		plan.Notes = append(plan.Notes, "数据库与控制平面位于同一节点，磁盘 I/O 压力较大时可能影响 API Server")
+	}
+	return plan, nil
+}
+
+// planNodes 检查节点名并将 Master 排在首位，其余节点保持请求中的顺序
+func planNodes(names []string) ([]string, error) {
+	seen := make(map[string]bool, len(names))
+	nodes := make([]string, 0, len(names))
+	for _, name := range names {
+		if name == "" {
+			return nil, fmt.Errorf("节点名不能为空")
+		}
+		if seen[name] {
+			return nil, fmt.Errorf("节点 %s 重复", name)
+		}
+		seen[name] = true
+		nodes = append(nodes, name)
+	}
+	if !seen["k3s-master"] {
+		return nil, fmt.Errorf("节点列表中缺少 k3s-master")
+	}
+	sort.SliceStable(nodes, func(i, j int) bool { return nodes[i] == "k3s-master" && nodes[j] != "k3s-master" })
+	return nodes, nil
+}
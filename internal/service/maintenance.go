@@ -0,0 +1,141 @@
+package service
+
+import (
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/logger"
+)
+
+// MaintenanceService 节点维护：cordon 并驱逐 Pod 后进行系统维护，完成后恢复调度，维护窗口记入审计日志
+type MaintenanceService struct {
+	clusterService *ClusterService
+	k3sService     *K3sService
+	auditService   *AuditService
+	logger         *logger.Logger
+}
+
+func NewMaintenanceService(clusterService *ClusterService, k3sService *K3sService, auditService *AuditService, logger *logger.Logger) *MaintenanceService {
+	return &MaintenanceService{
+		clusterService: clusterService,
+		k3sService:     k3sService,
+		auditService:   auditService,
+		logger:         logger,
+	}
+}
+
+// Start 将节点置于维护状态。节点已 cordon 但驱逐失败时同样记录维护开始（Success 为 false），
+// 节点保持不可调度，可调整 PDB 或副本后重试，或结束维护恢复调度
+func (s *MaintenanceService) Start(clusterID, node string, req *model.MaintenanceRequest, actor, requestID string) (*model.DrainResult, error) {
+	cluster, err := s.clusterService.Get(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	master, err := s.clusterService.MasterNode(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	opts := k3s.DrainOptions{
+		Timeout:          time.Duration(req.TimeoutSeconds) * time.Second,
+		Force:            req.Force,
+		KeepEmptyDirData: req.KeepEmptyDirData,
+	}
+	result, err := s.k3sService.DrainNode(master, node, opts)
+	if result == nil {
+		return nil, err
+	}
+
+	event := model.AuditEvent{
+		Action:    model.AuditMaintenanceStart,
+		Actor:     actor,
+		RequestID: requestID,
+		ClusterID: cluster.ID,
+		Target:    node,
+		Success:   err == nil,
+		Message:   req.Reason,
+	}
+	if err != nil {
+		event.Details = err.Error()
+	}
+	s.record(event)
+	return result, err
+}
+
+// End 结束节点维护，恢复调度
+func (s *MaintenanceService) End(clusterID, node, actor, requestID string) error {
+	cluster, err := s.clusterService.Get(clusterID)
+	if err != nil {
+		return err
+	}
+	master, err := s.clusterService.MasterNode(cluster)
+	if err != nil {
+		return err
+	}
+	if err := s.k3sService.UncordonNode(master, node); err != nil {
+		return err
+	}
+	s.record(model.AuditEvent{
+		Action:    model.AuditMaintenanceEnd,
+		Actor:     actor,
+		RequestID: requestID,
+		ClusterID: cluster.ID,
+		Target:    node,
+		Success:   true,
+	})
+	return nil
+}
+
+// Windows 按审计日志中的维护开始与结束记录还原集群的维护窗口。
+// 同一节点连续的开始记录（驱逐失败后重试）合并到同一窗口
+func (s *MaintenanceService) Windows(clusterID string) ([]model.MaintenanceWindow, error) {
+	if _, err := s.clusterService.Get(clusterID); err != nil {
+		return nil, err
+	}
+	events, err := s.auditService.List()
+	if err != nil {
+		return nil, err
+	}
+
+	windows := []model.MaintenanceWindow{}
+	open := make(map[string]int)
+	for _, event := range events {
+		if event.ClusterID != clusterID {
+			continue
+		}
+		switch event.Action {
+		case model.AuditMaintenanceStart:
+			if i, ok := open[event.Target]; ok {
+				windows[i].Drained = windows[i].Drained || event.Success
+				continue
+			}
+			open[event.Target] = len(windows)
+			windows = append(windows, model.MaintenanceWindow{
+				ClusterID: clusterID,
+				Node:      event.Target,
+				Reason:    event.Message,
+				StartedBy: event.Actor,
+				StartedAt: event.Time,
+				Drained:   event.Success,
+			})
+		case model.AuditMaintenanceEnd:
+			i, ok := open[event.Target]
+			if !ok {
+				continue
+			}
+			endedAt := event.Time
+			windows[i].EndedBy = event.Actor
+			windows[i].EndedAt = &endedAt
+			delete(open, event.Target)
+		}
+	}
+	return windows, nil
+}
+
+// record 写入审计记录，失败只告警（维护操作已经生效）
+func (s *MaintenanceService) record(event model.AuditEvent) {
+	if err := s.auditService.Record(event); err != nil {
+		s.logger.Warnf("记录节点 %s 维护审计失败: %v", event.Target, err)
+	}
+}
@@ -0,0 +1,220 @@
+package service
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"slices"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/vault"
+)
+
+const (
+	// objectStoreAuthType 凭据库中保存对象存储访问密钥的记录类型，不是节点登录凭据
+	objectStoreAuthType = "object-store"
+	// MinIO 根用户名至少 3 位、密码至少 8 位
+	minioAccessKeyLength = 20
+	minioSecretKeyLength = 40
+)
+
+// SaveObjectStoreKeys 将对象存储访问密钥加密存入凭据库，返回凭据 ID
+func (s *CredentialService) SaveObjectStoreKeys(name, host, accessKey, secretKey string) (string, error) {
+	return s.upsert(name, host, 9000, accessKey, objectStoreAuthType, vault.Secret{Password: secretKey})
+}
+
+// ObjectStoreKeys 读取凭据库中保存的对象存储访问密钥
+func (s *CredentialService) ObjectStoreKeys(id string) (string, string, error) {
+	cred, err := s.vault.Get(id)
+	if err != nil {
+		return "", "", err
+	}
+	if cred.AuthType != objectStoreAuthType {
+		return "", "", fmt.Errorf("凭据 %s 不是对象存储记录", id)
+	}
+	return cred.Username, cred.Secret.Password, nil
+}
+
+// RecordObjectStore 将 install-minio 安装的对象存储及其访问密钥记录到集群
+func (s *ClusterService) RecordObjectStore(masterIP string, objectStore model.ObjectStore, accessKey, secretKey string) error {
+	cluster, err := s.FindByMaster(masterIP)
+	if err != nil {
+		return err
+	}
+	if cluster == nil {
+		return fmt.Errorf("集群 %s 未登记，无法保存对象存储访问密钥", masterIP)
+	}
+	id, err := s.credentialService.SaveObjectStoreKeys("minio:"+cluster.Name, masterIP, accessKey, secretKey)
+	if err != nil {
+		return err
+	}
+	objectStore.CredentialID = id
+	cluster.ObjectStore = &objectStore
+	cluster.UpdatedAt = time.Now()
+	return s.save(cluster)
+}
+
+// objectStoreAccess 返回集群对象存储的地址和访问密钥，集群未安装对象存储时返回 nil
+func (s *ClusterService) objectStoreAccess(cluster *model.Cluster) (*model.ObjectStoreAccess, error) {
+	if cluster == nil || cluster.ObjectStore == nil {
+		return nil, nil
+	}
+	accessKey, secretKey, err := s.credentialService.ObjectStoreKeys(cluster.ObjectStore.CredentialID)
+	if err != nil {
+		return nil, fmt.Errorf("读取集群 %s 的对象存储访问密钥失败: %v", cluster.Name, err)
+	}
+	return &model.ObjectStoreAccess{
+		Endpoint:  cluster.ObjectStore.Endpoint,
+		Buckets:   cluster.ObjectStore.Buckets,
+		AccessKey: accessKey,
+		SecretKey: secretKey,
+	}, nil
+}
+
+// ObjectStoreAccess 返回集群对象存储的地址和访问密钥，供集群外的应用配置使用
+func (s *ClusterService) ObjectStoreAccess(id string) (*model.ObjectStoreAccess, error) {
+	cluster, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	access, err := s.objectStoreAccess(cluster)
+	if err == nil && access == nil {
+		err = fmt.Errorf("集群 %s 未安装对象存储", cluster.Name)
+	}
+	return access, err
+}
+
+// ObjectStoreByMaster 按 Master 地址返回集群对象存储的访问信息，集群未登记或未安装对象存储时返回 nil
+func (s *ClusterService) ObjectStoreByMaster(masterIP string) (*model.ObjectStoreAccess, error) {
+	cluster, err := s.FindByMaster(masterIP)
+	if err != nil {
+		return nil, err
+	}
+	return s.objectStoreAccess(cluster)
+}
+
+// instanceObjectStorage 返回实例使用的对象存储：实例专用用户只能访问 insuite 存储桶中以实例名为前缀的对象。
+// 用户密钥由根密钥按实例名派生，重复部署时保持不变，也无需另行保存
+func instanceObjectStorage(access *model.ObjectStoreAccess, instance string) *k3s.ObjectStorage {
+	if instance == "" {
+		instance = k3s.DefaultInstance
+	}
+	derive := func(purpose string, length int) string {
+		mac := hmac.New(sha256.New, []byte(access.SecretKey))
+		mac.Write([]byte(purpose + ":" + instance))
+		return hex.EncodeToString(mac.Sum(nil))[:length]
+	}
+	return &k3s.ObjectStorage{
+		Endpoint: access.Endpoint,
+		Bucket:   k3s.AppBucket,
+		Prefix:   instance,
+		// MinIO 访问密钥最长 20 位
+		AccessKey: "app-" + derive("access-key", 16),
+		SecretKey: derive("secret-key", minioSecretKeyLength),
+	}
+}
+
+// validateMinIO 校验 MinIO 参数，distributed 模式检查请求中带 MinIO 标签的节点数
+func validateMinIO(req *model.DeployRequest) error {
+	if err := k3s.ValidateMinIO(req.MinIO); err != nil || req.MinIO == nil || req.MinIO.Mode != model.MinIODistributed {
+		return err
+	}
+	labeled := 0
+	for _, labels := range req.Labels {
+		if slices.Contains(labels, k3s.MinIOLabel+"=true") {
+			labeled++
+		}
+	}
+	if labeled < k3s.MinIODistributedNodes {
+		return fmt.Errorf("MinIO distributed 模式需要在 labels 中为至少 %d 个节点设置 %s=true，当前 %d 个", k3s.MinIODistributedNodes, k3s.MinIOLabel, labeled)
+	}
+	return nil
+}
+
+func (s *DeployService) installMinIOStep(req *model.DeployRequest) error {
+	if req.MinIO == nil {
+		s.logger.Info("未设置 minio，跳过对象存储安装")
+		return nil
+	}
+
+	var masterNode model.NodeConfig
+	for _, node := range req.Nodes {
+		if node.Name == "k3s-master" {
+			masterNode = node
+			break
+		}
+	}
+	if masterNode.Name == "" {
+		return fmt.Errorf("未找到Master节点")
+	}
+
+	// 重复执行时沿用已保存的访问密钥，已使用的应用和 Velero 不受影响
+	cfg := k3s.MinIOConfig{
+		Mode:         req.MinIO.Mode,
+		Image:        req.MinIO.Image,
+		Size:         req.MinIO.Size,
+		StorageClass: req.MinIO.StorageClass,
+		Buckets:      k3s.MinIOBuckets(req.MinIO.Buckets),
+	}
+	if cfg.Mode == "" {
+		cfg.Mode = model.MinIOStandalone
+	}
+	cluster, err := s.clusterService.FindByMaster(masterNode.IP)
+	if err != nil {
+		return err
+	}
+	if cluster == nil {
+		return fmt.Errorf("集群 %s 未登记，无法保存 MinIO 访问密钥（请重新执行 install-master）", masterNode.IP)
+	}
+	existing, err := s.clusterService.ObjectStoreByMaster(masterNode.IP)
+	if err != nil {
+		return err
+	}
+	if existing != nil {
+		cfg.AccessKey, cfg.SecretKey = existing.AccessKey, existing.SecretKey
+	} else {
+		if cfg.AccessKey, err = randomPassword(minioAccessKeyLength); err != nil {
+			return err
+		}
+		if cfg.SecretKey, err = randomPassword(minioSecretKeyLength); err != nil {
+			return err
+		}
+	}
+
+	artifacts, replicas, err := s.k3sService.InstallMinIO(masterNode, req.WorkspaceID, cfg, waitPolicy(req.Wait))
+	req.Artifacts = append(req.Artifacts, artifacts...)
+	if err != nil {
+		return err
+	}
+	objectStore := model.ObjectStore{
+		Endpoint:    k3s.MinIOEndpoint,
+		Mode:        cfg.Mode,
+		Replicas:    replicas,
+		Buckets:     cfg.Buckets,
+		InstalledAt: time.Now(),
+	}
+	return s.clusterService.RecordObjectStore(masterNode.IP, objectStore, cfg.AccessKey, cfg.SecretKey)
+}
+
+// InstallMinIO 在 Master 节点安装 MinIO，返回上传过的文件路径和副本数
+func (s *K3sService) InstallMinIO(masterNode model.NodeConfig, workspaceID string, cfg k3s.MinIOConfig, policy k3s.WaitPolicy) ([]string, int, error) {
+	s.logger.DeploymentStep("install-minio", "cluster")
+
+	client := newNodeClient(masterNode)
+	if err := client.Connect(); err != nil {
+		return nil, 0, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	ws, err := k3s.NewWorkspace(client, workspaceID)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer s.cleanupWorkspace(ws)
+
+	replicas, err := s.manager.InstallMinIO(client, ws, cfg, policy)
+	return ws.Artifacts(), replicas, err
+}
@@ -0,0 +1,51 @@
+package service
+
+import (
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// newNodeClient 根据节点配置创建 SSH 客户端
+func newNodeClient(node model.NodeConfig) *ssh.Client {
+	config := ssh.SSHConfig{
+		Host:       node.IP,
+		Port:       node.Port,
+		Username:   node.Username,
+		AuthType:   node.AuthType,
+		Password:   node.Password,
+		PrivateKey: node.PrivateKey,
+		Passphrase: node.Passphrase,
+		Transport:  node.Transport,
+		AgentID:    node.AgentID,
+		RequestID:  node.RequestID,
+	}
+
+	for _, jump := range node.JumpHosts {
+		config.JumpHosts = append(config.JumpHosts, ssh.SSHConfig{
+			Host:       jump.IP,
+			Port:       jump.Port,
+			Username:   jump.Username,
+			AuthType:   jump.AuthType,
+			Password:   jump.Password,
+			PrivateKey: jump.PrivateKey,
+			Passphrase: jump.Passphrase,
+		})
+	}
+
+	if opts := node.DialOptions; opts != nil {
+		config.ConnectTimeout = time.Duration(opts.ConnectTimeout) * time.Second
+		config.Ciphers = opts.Ciphers
+		config.KeyExchanges = opts.KeyExchanges
+		config.MACs = opts.MACs
+		for i := range config.JumpHosts {
+			config.JumpHosts[i].ConnectTimeout = config.ConnectTimeout
+			config.JumpHosts[i].Ciphers = opts.Ciphers
+			config.JumpHosts[i].KeyExchanges = opts.KeyExchanges
+			config.JumpHosts[i].MACs = opts.MACs
+		}
+	}
+
+	return ssh.NewClient(config)
+}
@@ -0,0 +1,262 @@
+package service
+
+import (
+	"fmt"
+	"net"
+	"regexp"
+	"strings"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// 节点 DNS 解析方式
+const (
+	dnsForwarderAuto     = "auto"
+	dnsForwarderResolved = "systemd-resolved"
+	dnsForwarderDnsmasq  = "dnsmasq"
+	dnsForwarderNone     = "none"
+)
+
+// dnsNamePattern 域名（搜索域、转发域和静态记录）
+var dnsNamePattern = regexp.MustCompile(`^([A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?\.)*[A-Za-z0-9]([A-Za-z0-9-]*[A-Za-z0-9])?\.?$`)
+
+// resolvConfBackup 首次修改 /etc/resolv.conf 前的备份
+const resolvConfBackup = "/etc/resolv.conf.k3s-deploy.bak"
+
+// restoreResolvConfScript 用备份恢复 /etc/resolv.conf，并删除让 NetworkManager 不再管理 DNS 的配置
+const restoreResolvConfScript = `if [ -e /etc/resolv.conf.k3s-deploy.bak ] || [ -L /etc/resolv.conf.k3s-deploy.bak ]; then
+  mv -f /etc/resolv.conf.k3s-deploy.bak /etc/resolv.conf
+fi
+if [ -e /etc/NetworkManager/conf.d/k3s-deploy-dns.conf ]; then
+  rm -f /etc/NetworkManager/conf.d/k3s-deploy-dns.conf
+  nmcli general reload 2>/dev/null || pkill -HUP NetworkManager || true
+fi`
+
+// releaseResolvConfScript 让 NetworkManager 不再改写 /etc/resolv.conf，首次修改前备份原文件（保留符号链接）
+const releaseResolvConfScript = `if command -v nmcli >/dev/null 2>&1 && nmcli -t general status >/dev/null 2>&1; then
+  mkdir -p /etc/NetworkManager/conf.d
+  printf '[main]\ndns=none\n' > /etc/NetworkManager/conf.d/k3s-deploy-dns.conf
+  nmcli general reload 2>/dev/null || pkill -HUP NetworkManager
+fi
+[ -e /etc/resolv.conf.k3s-deploy.bak ] || [ ! -e /etc/resolv.conf ] || cp -P /etc/resolv.conf /etc/resolv.conf.k3s-deploy.bak`
+
+// resolvConfTemp 写入 /etc/resolv.conf 前的临时文件，与其同目录以便 mv 原子替换
+const resolvConfTemp = "/etc/resolv.conf.k3s-deploy.tmp"
+
+// validateDNSOptions 校验 DNS 配置，DNS 服务器只接受 IP（resolv.conf 与 systemd-resolved 均不支持主机名）
+func validateDNSOptions(opts *model.DNSOptions) error {
+	ips := append(append([]string{}, opts.Servers...), k3sUpstreams(opts)...)
+	for _, name := range opts.SearchDomains {
+		if !dnsNamePattern.MatchString(name) {
+			return fmt.Errorf("无效的搜索域: %s", name)
+		}
+	}
+	if opts.CoreDNS != nil {
+		for domain, servers := range opts.CoreDNS.StubDomains {
+			if !dnsNamePattern.MatchString(domain) {
+				return fmt.Errorf("无效的转发域: %s", domain)
+			}
+			if len(servers) == 0 {
+				return fmt.Errorf("转发域 %s 未指定 DNS 服务器", domain)
+			}
+			ips = append(ips, servers...)
+		}
+		for name, ip := range opts.CoreDNS.Hosts {
+			if !dnsNamePattern.MatchString(name) {
+				return fmt.Errorf("无效的静态解析域名: %s", name)
+			}
+			// 静态记录与转发域各自生成 server 块，同一域名重复时 CoreDNS 无法启动
+			if _, exists := opts.CoreDNS.StubDomains[name]; exists {
+				return fmt.Errorf("静态解析域名 %s 与转发域重复", name)
+			}
+			ips = append(ips, ip)
+		}
+	}
+	for _, ip := range ips {
+		if net.ParseIP(ip) == nil {
+			return fmt.Errorf("无效的 DNS 服务器或解析地址: %s", ip)
+		}
+	}
+	return nil
+}
+
+// k3sUpstreams CoreDNS 解析集群外域名使用的上游 DNS，未单独设置时使用站点 DNS
+func k3sUpstreams(opts *model.DNSOptions) []string {
+	if opts.CoreDNS != nil && len(opts.CoreDNS.Upstreams) > 0 {
+		return opts.CoreDNS.Upstreams
+	}
+	return opts.Servers
+}
+
+// ConfigureNodeDNS 在 prepare-nodes 步骤将各节点解析指向站点 DNS，并写入 k3s 使用的 resolv.conf，
+// 返回各节点的结果
+func (s *K3sService) ConfigureNodeDNS(nodes []model.NodeConfig, opts *model.DNSOptions) ([]model.NodeResult, error) {
+	if err := validateDNSOptions(opts); err != nil {
+		return nil, err
+	}
+	return runOnNodes(nodes, func(i int, client *ssh.Client) error {
+		osInfo, err := hostos.Detect(client)
+		if err != nil {
+			return fmt.Errorf("节点 %s %v", nodes[i].Name, err)
+		}
+		return s.configureNodeDNS(client, nodes[i].Name, osInfo, opts)
+	}), nil
+}
+
+// configureNodeDNS 将节点解析指向站点 DNS，并写入 k3s 使用的 resolv.conf
+func (s *K3sService) configureNodeDNS(client *ssh.Client, nodeName string, osInfo *hostos.Info, opts *model.DNSOptions) error {
+	if len(opts.Servers) > 0 {
+		forwarder, err := s.dnsForwarder(client, osInfo, opts.Forwarder)
+		if err != nil {
+			return fmt.Errorf("节点 %s %v", nodeName, err)
+		}
+		s.journal.record(client, nodeName, model.ChangeResolvConf, "修改 /etc/resolv.conf", restoreResolvConfScript)
+		switch forwarder {
+		case dnsForwarderResolved:
+			s.journal.record(client, nodeName, model.ChangeDNS, "systemd-resolved 使用站点 DNS",
+				"rm -f /etc/systemd/resolved.conf.d/k3s-deploy.conf; systemctl restart systemd-resolved")
+			err = s.configureResolved(client, opts)
+		case dnsForwarderDnsmasq:
+			s.journal.record(client, nodeName, model.ChangeDNS, "dnsmasq 转发站点 DNS",
+				"rm -f /etc/dnsmasq.d/k3s-deploy.conf; "+osInfo.ServiceStopDisableCommand("dnsmasq"))
+			err = s.configureDnsmasq(client, osInfo, opts)
+		default:
+			err = writeResolvConf(client, opts.Servers, opts.SearchDomains)
+		}
+		if err != nil {
+			return fmt.Errorf("节点 %s 配置 DNS（%s）失败: %v", nodeName, forwarder, err)
+		}
+		s.logger.Infof("节点 %s 已通过 %s 使用站点 DNS %v", nodeName, forwarder, opts.Servers)
+	}
+
+	if upstreams := k3sUpstreams(opts); len(upstreams) > 0 {
+		content := resolvConf(upstreams, nil)
+		if _, err := client.ExecuteCommand("mkdir -p " + strings.TrimSuffix(k3s.ResolvConfPath, "/resolv.conf")); err != nil {
+			return fmt.Errorf("节点 %s 创建 k3s 配置目录失败: %v", nodeName, err)
+		}
+		if err := client.UploadFile(content, k3s.ResolvConfPath); err != nil {
+			return fmt.Errorf("节点 %s 写入 %s 失败: %v", nodeName, k3s.ResolvConfPath, err)
+		}
+		s.journal.record(client, nodeName, model.ChangeDNS, "写入 "+k3s.ResolvConfPath, "rm -f "+k3s.ResolvConfPath)
+	}
+	return nil
+}
+
+// dnsForwarder 确定节点的解析方式：auto 在 systemd-resolved 运行时使用它，否则直接写 resolv.conf；
+// dnsmasq 与 systemd-resolved 的 53 端口监听冲突
+func (s *K3sService) dnsForwarder(client *ssh.Client, osInfo *hostos.Info, forwarder string) (string, error) {
+	resolvedActive := false
+	if osInfo.InitSystem == hostos.InitSystemd {
+		result, err := client.ExecuteIdempotentCommand(osInfo.ServiceActiveCommand("systemd-resolved"))
+		resolvedActive = err == nil && strings.TrimSpace(result.Stdout) == "active"
+	}
+
+	switch forwarder {
+	case "", dnsForwarderAuto:
+		if resolvedActive {
+			return dnsForwarderResolved, nil
+		}
+		return dnsForwarderNone, nil
+	case dnsForwarderResolved:
+		if !resolvedActive {
+			return "", fmt.Errorf("systemd-resolved 未运行")
+		}
+	case dnsForwarderDnsmasq:
+		if resolvedActive {
+			return "", fmt.Errorf("systemd-resolved 正在运行并占用 53 端口，请使用 systemd-resolved 作为转发方式")
+		}
+	}
+	return forwarder, nil
+}
+
+func (s *K3sService) configureResolved(client *ssh.Client, opts *model.DNSOptions) error {
+	conf := "[Resolve]\nDNS=" + strings.Join(opts.Servers, " ") + "\n"
+	if len(opts.SearchDomains) > 0 {
+		conf += "Domains=" + strings.Join(opts.SearchDomains, " ") + "\n"
+	}
+	if _, err := client.ExecuteCommand("mkdir -p /etc/systemd/resolved.conf.d"); err != nil {
+		return err
+	}
+	if err := client.UploadFile(conf, "/etc/systemd/resolved.conf.d/k3s-deploy.conf"); err != nil {
+		return err
+	}
+	if _, err := client.ExecuteCommand("systemctl restart systemd-resolved"); err != nil {
+		return err
+	}
+	// /etc/resolv.conf 未指向 systemd-resolved 的 stub 时改为指向它
+	stub := "/run/systemd/resolve/stub-resolv.conf"
+	_, err := client.ExecuteCommand(fmt.Sprintf(`[ "$(readlink -f /etc/resolv.conf)" = %[1]s ] || { [ -e %[2]s ] || cp -P /etc/resolv.conf %[2]s; ln -sf %[1]s /etc/resolv.conf; }`, stub, resolvConfBackup))
+	return err
+}
+
+func (s *K3sService) configureDnsmasq(client *ssh.Client, osInfo *hostos.Info, opts *model.DNSOptions) error {
+	install, err := osInfo.InstallCommand("dnsmasq")
+	if err != nil {
+		return err
+	}
+	if _, err := client.ExecuteCommand("command -v dnsmasq >/dev/null 2>&1 || { " + install + "; }"); err != nil {
+		return fmt.Errorf("安装 dnsmasq 失败: %v", err)
+	}
+
+	var conf strings.Builder
+	conf.WriteString("listen-address=127.0.0.1\nbind-interfaces\nno-resolv\ncache-size=1000\n")
+	for _, server := range opts.Servers {
+		fmt.Fprintf(&conf, "server=%s\n", server)
+	}
+	// Debian 的 dnsmasq 启动脚本已加载 /etc/dnsmasq.d，其余发行版需在主配置中声明
+	prepare := "mkdir -p /etc/dnsmasq.d"
+	if osInfo.PackageManager != hostos.PackageManagerApt {
+		prepare += " && { grep -q '^conf-dir=/etc/dnsmasq.d' /etc/dnsmasq.conf 2>/dev/null || echo 'conf-dir=/etc/dnsmasq.d/,*.conf' >> /etc/dnsmasq.conf; }"
+	}
+	if _, err := client.ExecuteCommand(prepare); err != nil {
+		return err
+	}
+	if err := client.UploadFile(conf.String(), "/etc/dnsmasq.d/k3s-deploy.conf"); err != nil {
+		return err
+	}
+	if _, err := client.ExecuteCommand(osInfo.ServiceEnableStartCommand("dnsmasq") + "; " + osInfo.ServiceRestartCommand("dnsmasq")); err != nil {
+		return fmt.Errorf("启动 dnsmasq 失败: %v", err)
+	}
+	return writeResolvConf(client, []string{"127.0.0.1"}, opts.SearchDomains)
+}
+
+// writeResolvConf 写入临时文件后用 mv 替换 /etc/resolv.conf，替换的是符号链接本身而不会写到
+// systemd-resolved 等管理的文件，写入失败时原文件保持不变
+func writeResolvConf(client *ssh.Client, servers, searchDomains []string) error {
+	if _, err := client.ExecuteCommand(releaseResolvConfScript); err != nil {
+		return err
+	}
+	if err := client.UploadFile(resolvConf(servers, searchDomains), resolvConfTemp); err != nil {
+		client.ExecuteCommand("rm -f " + resolvConfTemp)
+		return err
+	}
+	_, err := client.ExecuteCommand("chmod 644 " + resolvConfTemp + " && mv -f " + resolvConfTemp + " /etc/resolv.conf")
+	return err
+}
+
+// lookupCommand 解析 domain 的命令。设置了站点 DNS 时直接向站点 DNS 查询，不依赖节点当前的解析配置
+func lookupCommand(domain string, dns *model.DNSOptions) string {
+	if dns == nil || len(dns.Servers) == 0 {
+		return "nslookup " + domain
+	}
+	lookups := make([]string, len(dns.Servers))
+	for i, server := range dns.Servers {
+		lookups[i] = "nslookup " + domain + " " + server
+	}
+	return strings.Join(lookups, " || ")
+}
+
+func resolvConf(servers, searchDomains []string) string {
+	var b strings.Builder
+	b.WriteString("# 由 k3s-deploy-backend 生成\n")
+	if len(searchDomains) > 0 {
+		b.WriteString("search " + strings.Join(searchDomains, " ") + "\n")
+	}
+	for _, server := range servers {
+		b.WriteString("nameserver " + server + "\n")
+	}
+	return b.String()
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// PrepareNodes 并行统一各节点的主机名、时区和 locale，按需配置节点间的时间同步。
+// 部分中文精简镜像默认使用 GBK 等非 UTF-8 locale，会导致命令输出解析和证书生成异常。
+// 返回各节点的结果，系统设置失败的节点不再配置时间同步
+func (s *K3sService) PrepareNodes(nodes []model.NodeConfig, opts *model.NodePrepOptions) ([]model.NodeResult, error) {
+	s.logger.DeploymentStep("prepare-nodes", "cluster")
+
+	if opts.Timezone != "" {
+		if err := hostos.ValidateTimezone(opts.Timezone); err != nil {
+			return nil, err
+		}
+	}
+	if opts.Locale != "" {
+		if err := hostos.ValidateLocale(opts.Locale); err != nil {
+			return nil, err
+		}
+	}
+	if opts.LogRotation != nil {
+		if err := validateLogRotation(opts.LogRotation); err != nil {
+			return nil, err
+		}
+	}
+	hostnames := make(map[string]string, len(nodes))
+	if opts.Hostname {
+		owners := make(map[string]string, len(nodes))
+		for _, node := range nodes {
+			hostname, err := hostos.NormalizeHostname(node.Name)
+			if err != nil {
+				return nil, err
+			}
+			if owner, exists := owners[hostname]; exists {
+				return nil, fmt.Errorf("节点 %s 与 %s 的主机名相同: %s", owner, node.Name, hostname)
+			}
+			owners[hostname] = node.Name
+			hostnames[node.Name] = hostname
+		}
+	}
+
+	results := runOnNodes(nodes, func(i int, client *ssh.Client) error {
+		return s.prepareNode(client, nodes[i].Name, hostnames[nodes[i].Name], opts)
+	})
+	if opts.TimeSync == nil {
+		return results, nil
+	}
+
+	var synced []model.NodeConfig
+	index := make(map[string]int, len(nodes))
+	for i, r := range results {
+		if !r.Success {
+			// Master 是其余节点的时间源，失败时不再配置时间同步
+			if nodes[i].Name == "k3s-master" {
+				return results, nil
+			}
+			continue
+		}
+		index[nodes[i].IP] = i
+		synced = append(synced, nodes[i])
+	}
+	syncResults, err := s.syncTime(synced, opts.TimeSync)
+	if err != nil {
+		return results, err
+	}
+	for _, r := range syncResults {
+		results[index[r.IP]] = r
+	}
+	return results, nil
+}
+
+func (s *K3sService) prepareNode(client *ssh.Client, nodeName, hostname string, opts *model.NodePrepOptions) error {
+	osInfo, err := hostos.Detect(client)
+	if err != nil {
+		return fmt.Errorf("节点 %s %v", nodeName, err)
+	}
+
+	if hostname != "" {
+		if _, err := client.ExecuteCommand(osInfo.SetHostnameCommand(hostname)); err != nil {
+			return fmt.Errorf("节点 %s 设置主机名失败: %v", nodeName, err)
+		}
+		result, err := client.ExecuteIdempotentCommand("hostname")
+		if err != nil || strings.TrimSpace(result.Stdout) != hostname {
+			return fmt.Errorf("节点 %s 主机名设置后校验失败", nodeName)
+		}
+		s.logger.Infof("节点 %s 主机名已设置为 %s", nodeName, hostname)
+	}
+
+	if opts.Timezone != "" {
+		if _, err := client.ExecuteCommand(osInfo.SetTimezoneCommand(opts.Timezone)); err != nil {
+			return fmt.Errorf("节点 %s 设置时区 %s 失败: %v", nodeName, opts.Timezone, err)
+		}
+		s.logger.Infof("节点 %s 时区已设置为 %s", nodeName, opts.Timezone)
+	}
+
+	if opts.Locale != "" {
+		if _, err := client.ExecuteCommand(osInfo.SetLocaleCommand(opts.Locale)); err != nil {
+			return fmt.Errorf("节点 %s 设置 locale %s 失败: %v", nodeName, opts.Locale, err)
+		}
+		s.logger.Infof("节点 %s 默认 locale 已设置为 %s（新登录会话生效）", nodeName, opts.Locale)
+	}
+
+	if opts.LogRotation != nil {
+		if err := s.manager.ConfigureLogRotation(client, nodeName, osInfo, opts.LogRotation); err != nil {
+			return fmt.Errorf("节点 %s %v", nodeName, err)
+		}
+	}
+	return nil
+}
+
+var (
+	journaldSizePattern     = regexp.MustCompile(`^\d+[KMGT]?$`)
+	containerLogSizePattern = regexp.MustCompile(`^\d+(Ki|Mi|Gi)$`)
+)
+
+// validateLogRotation 校验日志轮转参数并补全默认值
+func validateLogRotation(opts *model.LogRotationOptions) error {
+	if opts.JournaldMaxUse == "" {
+		opts.JournaldMaxUse = "1G"
+	}
+	if opts.ContainerLogMaxSize == "" {
+		opts.ContainerLogMaxSize = "10Mi"
+	}
+	if opts.ContainerLogMaxFiles == 0 {
+		opts.ContainerLogMaxFiles = 5
+	}
+	if !journaldSizePattern.MatchString(opts.JournaldMaxUse) {
+		return fmt.Errorf("journald 占用上限 %q 无效，应为数字加 K/M/G/T 后缀，如 1G", opts.JournaldMaxUse)
+	}
+	if !containerLogSizePattern.MatchString(opts.ContainerLogMaxSize) {
+		return fmt.Errorf("容器日志文件上限 %q 无效，应为数字加 Ki/Mi/Gi 后缀，如 10Mi", opts.ContainerLogMaxSize)
+	}
+	if opts.ContainerLogMaxFiles < 2 {
+		return fmt.Errorf("容器日志保留文件数至少为 2")
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+	"sync"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// runOnNodes 并行连接各节点执行 fn（i 为节点在 nodes 中的序号），返回与 nodes 顺序一致的结果，
+// fn 返回的错误作为该节点的失败信息
+func runOnNodes(nodes []model.NodeConfig, fn func(i int, client *ssh.Client) error) []model.NodeResult {
+	results := make([]model.NodeResult, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node model.NodeConfig) {
+			defer wg.Done()
+			results[i] = model.NodeResult{Name: node.Name, IP: node.IP}
+
+			client := newNodeClient(node)
+			if err := client.Connect(); err != nil {
+				results[i].Message = fmt.Sprintf("连接节点 %s 失败: %v", node.Name, err)
+				return
+			}
+			defer client.Close()
+
+			if err := fn(i, client); err != nil {
+				results[i].Message = err.Error()
+				return
+			}
+			results[i].Success = true
+		}(i, node)
+	}
+	wg.Wait()
+	return results
+}
+
+// nodesError 任一节点失败时返回汇总了失败信息的错误，action 为错误前缀
+func nodesError(action string, results []model.NodeResult) error {
+	var messages []string
+	for _, r := range results {
+		if !r.Success {
+			messages = append(messages, r.Message)
+		}
+	}
+	if len(messages) > 0 {
+		return fmt.Errorf("%s: %s", action, strings.Join(messages, "; "))
+	}
+	return nil
+}
@@ -0,0 +1,115 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"sync"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// defaultServiceLogLines 查看服务日志时默认返回的行数
+const defaultServiceLogLines = 200
+
+// serviceOperation 在已识别 k3s 服务的节点上执行的操作
+type serviceOperation func(client *ssh.Client, osInfo *hostos.Info, node model.NodeConfig, result *model.NodeServiceResult) error
+
+// RestartServices 按请求顺序逐个重启节点的 k3s / k3s-agent 服务，等待其重新运行后再处理下一个节点；
+// 任一节点失败即停止，避免同时中断多个节点
+func (s *K3sService) RestartServices(nodes []model.NodeConfig, policy k3s.WaitPolicy) []model.NodeServiceResult {
+	return s.rollServices(nodes, func(client *ssh.Client, osInfo *hostos.Info, node model.NodeConfig, result *model.NodeServiceResult) error {
+		s.logger.Infof("节点 %s 重启 %s", node.Name, result.Unit)
+		result.Restarted = true
+		return s.manager.RestartService(client, osInfo, result.Unit, policy)
+	})
+}
+
+// ApplyServiceConfig 按请求顺序逐个节点更新 config.yaml / registries.yaml 并重启服务，
+// 新配置导致服务无法启动时该节点恢复原配置，并停止处理后续节点
+func (s *K3sService) ApplyServiceConfig(nodes []model.NodeConfig, workspaceID, config, registries string, policy k3s.WaitPolicy) []model.NodeServiceResult {
+	return s.rollServices(nodes, func(client *ssh.Client, osInfo *hostos.Info, node model.NodeConfig, result *model.NodeServiceResult) error {
+		ws, err := k3s.NewWorkspace(client, workspaceID)
+		if err != nil {
+			return err
+		}
+		defer s.cleanupWorkspace(ws)
+
+		changed, err := s.manager.ApplyServiceConfig(client, ws, osInfo, result.Unit, config, registries, policy)
+		result.Changed = changed
+		result.Restarted = len(changed) > 0
+		return err
+	})
+}
+
+// ServiceLogs 并行读取各节点 k3s / k3s-agent 服务最近的日志
+func (s *K3sService) ServiceLogs(nodes []model.NodeConfig, lines int) []model.NodeServiceResult {
+	if lines <= 0 {
+		lines = defaultServiceLogLines
+	}
+	results := make([]model.NodeServiceResult, len(nodes))
+	var wg sync.WaitGroup
+	for i, node := range nodes {
+		wg.Add(1)
+		go func(i int, node model.NodeConfig) {
+			defer wg.Done()
+			results[i] = s.serviceOperation(node, func(client *ssh.Client, osInfo *hostos.Info, node model.NodeConfig, result *model.NodeServiceResult) error {
+				logs, err := s.manager.ServiceLogs(client, osInfo, result.Unit, lines)
+				result.Logs = logs
+				return err
+			})
+		}(i, node)
+	}
+	wg.Wait()
+	return results
+}
+
+// rollServices 逐个节点执行操作，首个失败节点之后的节点不再处理
+func (s *K3sService) rollServices(nodes []model.NodeConfig, op serviceOperation) []model.NodeServiceResult {
+	results := make([]model.NodeServiceResult, len(nodes))
+	failed := ""
+	for i, node := range nodes {
+		if failed != "" {
+			results[i] = model.NodeServiceResult{Name: node.Name, IP: node.IP, Message: fmt.Sprintf("节点 %s 失败，未执行", failed)}
+			continue
+		}
+		results[i] = s.serviceOperation(node, op)
+		if !results[i].Success {
+			failed = node.Name
+		}
+	}
+	return results
+}
+
+// serviceOperation 连接节点、识别 k3s 服务后执行操作。服务未能运行时结果中附带服务日志
+func (s *K3sService) serviceOperation(node model.NodeConfig, op serviceOperation) model.NodeServiceResult {
+	result := model.NodeServiceResult{Name: node.Name, IP: node.IP}
+
+	client := newNodeClient(node)
+	if err := client.Connect(); err != nil {
+		result.Message = fmt.Sprintf("连接节点失败: %v", err)
+		return result
+	}
+	defer client.Close()
+
+	osInfo, unit, err := k3s.DetectService(client)
+	if err != nil {
+		result.Message = err.Error()
+		return result
+	}
+	result.Unit = unit
+
+	if err := op(client, osInfo, node, &result); err != nil {
+		result.Message = err.Error()
+		var serviceErr *k3s.ServiceError
+		if errors.As(err, &serviceErr) {
+			result.Logs = serviceErr.Diagnostics()
+		}
+		s.logger.Errorf("节点 %s %s 操作失败: %v", node.Name, unit, err)
+		return result
+	}
+	result.Success = true
+	return result
+}
@@ -0,0 +1,91 @@
+package service
+
+import (
+	"fmt"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+const defaultTuningProfile = "default"
+
+// tuningProfile 以内置配置为基础合并请求中的内核参数与 nofile
+func tuningProfile(opts *model.TuningOptions) (hostos.TuningProfile, error) {
+	name := opts.Profile
+	if name == "" {
+		name = defaultTuningProfile
+	}
+	base, ok := hostos.TuningProfiles[name]
+	if !ok {
+		return hostos.TuningProfile{}, fmt.Errorf("未知的调优配置: %s", name)
+	}
+
+	profile := hostos.TuningProfile{Sysctl: make(map[string]string, len(base.Sysctl)+len(opts.Sysctl)), NoFile: base.NoFile}
+	for key, value := range base.Sysctl {
+		profile.Sysctl[key] = value
+	}
+	for key, value := range opts.Sysctl {
+		profile.Sysctl[key] = value
+	}
+	if opts.NoFile > 0 {
+		profile.NoFile = opts.NoFile
+	}
+	return profile, profile.Validate()
+}
+
+// TuneNodes 并行在各节点应用内核参数与文件句柄限制，opts 为 nil 时按备份恢复原始值并删除配置。
+// 返回每个节点首次调优前的参数值
+func (s *K3sService) TuneNodes(nodes []model.NodeConfig, opts *model.TuningOptions) ([]model.TuningResult, error) {
+	cmd := hostos.RevertTuningCommand()
+	if opts != nil {
+		profile, err := tuningProfile(opts)
+		if err != nil {
+			return nil, err
+		}
+		cmd = profile.ApplyTuningCommand()
+	}
+
+	results := make([]model.TuningResult, len(nodes))
+	nodeResults := runOnNodes(nodes, func(i int, client *ssh.Client) error {
+		node := nodes[i]
+		if opts == nil {
+			backup, _ := client.ExecuteIdempotentCommand("cat " + hostos.TuningBackupPath + " 2>/dev/null")
+			if backup != nil {
+				results[i].Previous = hostos.ParseTuningBackup(backup.Stdout)
+			}
+		}
+		if _, err := client.ExecuteCommand(cmd); err != nil {
+			return fmt.Errorf("节点 %s 应用内核参数失败: %v", node.Name, err)
+		}
+		if opts == nil {
+			if err := s.journal.forget(client, model.ChangeSysctl); err != nil {
+				s.logger.Warnf("节点 %s 删除内核参数变更记录失败: %v", node.Name, err)
+			}
+			s.logger.Infof("节点 %s 已恢复 %d 项内核参数并删除调优配置", node.Name, len(results[i].Previous))
+			return nil
+		}
+
+		backup, err := client.ExecuteIdempotentCommand("cat " + hostos.TuningBackupPath)
+		if err != nil {
+			return fmt.Errorf("节点 %s 读取调优前参数失败: %v", node.Name, err)
+		}
+		results[i].Previous = hostos.ParseTuningBackup(backup.Stdout)
+		s.journal.record(client, node.Name, model.ChangeSysctl, "应用内核参数与文件句柄限制", hostos.RevertTuningCommand())
+		s.logger.Infof("节点 %s 已应用调优配置，调优前参数: %v", node.Name, results[i].Previous)
+		return nil
+	})
+	for i, r := range nodeResults {
+		results[i].NodeResult = r
+	}
+	return results, nil
+}
+
+// tuningNodeResults 调优结果中各节点的成败
+func tuningNodeResults(results []model.TuningResult) []model.NodeResult {
+	nodeResults := make([]model.NodeResult, len(results))
+	for i, r := range results {
+		nodeResults[i] = r.NodeResult
+	}
+	return nodeResults
+}
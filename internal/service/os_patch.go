@@ -0,0 +1,224 @@
+package service
+
+import (
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/bundle"
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+const (
+	// defaultPatchReadyTimeout 未指定时等待节点重启完成并 Ready 的超时
+	defaultPatchReadyTimeout = 10 * time.Minute
+	// rebootScript 延迟执行重启，留出时间结束当前 SSH 会话
+	rebootScript = "nohup sh -c 'sleep 2; reboot' >/dev/null 2>&1 &"
+	// bootIDPath 每次启动生成的随机 ID，用于确认节点确实已重启
+	bootIDPath = "/proc/sys/kernel/random/boot_id"
+)
+
+// patchOSStep 逐个节点滚动升级系统软件包：驱逐 -> 升级（或安装离线补丁）-> 按需重启 -> 等待 Ready -> 恢复调度。
+// 并发度为 1，先 Agent 后 Master；任一节点失败立即中止，失败节点保持 cordon 以便人工处理
+func (s *DeployService) patchOSStep(req *model.DeployRequest) error {
+	if joinsServer(req) {
+		return fmt.Errorf("加入中心 server 的节点请在中心集群上执行 patch-os")
+	}
+	var masterNode model.NodeConfig
+	for _, node := range req.Nodes {
+		if node.Name == "k3s-master" {
+			masterNode = node
+			break
+		}
+	}
+	if masterNode.Name == "" {
+		return fmt.Errorf("未找到Master节点")
+	}
+
+	opts := model.PatchOptions{}
+	if req.Patch != nil {
+		opts = *req.Patch
+	}
+	if opts.Reboot == "" {
+		opts.Reboot = model.RebootAuto
+	}
+	readyTimeout := defaultPatchReadyTimeout
+	if opts.ReadyTimeoutSeconds > 0 {
+		readyTimeout = time.Duration(opts.ReadyTimeoutSeconds) * time.Second
+	}
+	var b *bundle.Bundle
+	if opts.Bundle != "" {
+		var err error
+		if b, err = s.openBundle(req.RequestID, opts.Bundle); err != nil {
+			return err
+		}
+	}
+	policy := waitPolicy(req.Wait)
+	drain := k3s.DrainOptions{Timeout: time.Duration(opts.DrainTimeoutSeconds) * time.Second, Force: opts.Force}
+
+	// Master 最后处理，升级 Agent 期间控制面保持可用
+	names := clusterNodeNames(req.Nodes)
+	order := make([]model.NodeConfig, 0, len(req.Nodes))
+	for _, node := range req.Nodes {
+		if node.Name != "k3s-master" {
+			order = append(order, node)
+		}
+	}
+	order = append(order, masterNode)
+
+	var done []string
+	for _, node := range order {
+		nodeName := names[node.Name]
+		if err := s.patchNode(masterNode, node, nodeName, b, opts.Reboot, drain, readyTimeout, policy.PollInterval, req.WorkspaceID); err != nil {
+			completed := "无"
+			if len(done) > 0 {
+				completed = strings.Join(done, ", ")
+			}
+			return fmt.Errorf("节点 %s 补丁失败，已中止（已完成: %s；%s 保持不可调度）: %v", node.Name, completed, nodeName, err)
+		}
+		done = append(done, node.Name)
+	}
+	s.logger.Infof("系统补丁完成，共 %d 个节点", len(done))
+	return nil
+}
+
+func (s *DeployService) patchNode(masterNode, node model.NodeConfig, nodeName string, b *bundle.Bundle, reboot string, drain k3s.DrainOptions, readyTimeout, interval time.Duration, workspaceID string) error {
+	s.logger.DeploymentStep("patch-os", node.Name)
+	result, err := s.k3sService.DrainNode(masterNode, nodeName, drain)
+	if err != nil {
+		return err
+	}
+	s.logger.Infof("节点 %s 已驱逐 %d 个 Pod", nodeName, len(result.Evicted))
+
+	rebooted, err := s.k3sService.PatchNode(node, b, reboot, readyTimeout, interval, workspaceID)
+	if err != nil {
+		return err
+	}
+	if rebooted {
+		s.logger.Infof("节点 %s 已重启", node.Name)
+	}
+	if err := s.k3sService.WaitNodeReady(masterNode, nodeName, readyTimeout, interval); err != nil {
+		return err
+	}
+	return s.k3sService.UncordonNode(masterNode, nodeName)
+}
+
+// PatchNode 升级节点的系统软件包，b 非空时改为安装离线安装包中的补丁；
+// 按重启策略重启后等待节点重新接受 SSH 连接并确认 boot_id 已变化，返回是否重启
+func (s *K3sService) PatchNode(node model.NodeConfig, b *bundle.Bundle, reboot string, timeout, interval time.Duration, workspaceID string) (bool, error) {
+	client := newNodeClient(node)
+	if err := client.Connect(); err != nil {
+		return false, fmt.Errorf("连接节点失败: %v", err)
+	}
+	defer client.Close()
+
+	osInfo, err := hostos.Detect(client)
+	if err != nil {
+		return false, err
+	}
+
+	if b != nil {
+		err = s.installOSPackages(client, osInfo, node.Name, b, workspaceID)
+	} else {
+		err = s.upgradeOSPackages(client, osInfo, node.Name)
+	}
+	if err != nil {
+		return false, err
+	}
+
+	switch reboot {
+	case model.RebootNever:
+		return false, nil
+	case model.RebootAuto:
+		out, err := client.ExecuteIdempotentCommand(hostos.RebootRequiredScript)
+		if err != nil {
+			return false, fmt.Errorf("检查是否需要重启失败: %v", err)
+		}
+		if strings.TrimSpace(out.Stdout) != "reboot" {
+			s.logger.Infof("节点 %s 无需重启", node.Name)
+			return false, nil
+		}
+	}
+
+	bootID, err := client.ExecuteIdempotentCommand("cat " + bootIDPath)
+	if err != nil {
+		return false, fmt.Errorf("读取 boot_id 失败: %v", err)
+	}
+	s.logger.Warnf("节点 %s 重启中", node.Name)
+	if _, err := client.ExecuteCommand(rebootScript); err != nil {
+		return false, fmt.Errorf("重启节点失败: %v", err)
+	}
+	return true, s.waitRebooted(node, strings.TrimSpace(bootID.Stdout), timeout, interval)
+}
+
+// upgradeOSPackages 通过软件源升级全部软件包
+func (s *K3sService) upgradeOSPackages(client *ssh.Client, osInfo *hostos.Info, nodeName string) error {
+	cmd, err := osInfo.UpgradeCommand()
+	if err != nil {
+		return err
+	}
+	s.logger.Infof("节点 %s（%s）升级系统软件包", nodeName, osInfo)
+	if _, err := client.ExecuteCommand(cmd); err != nil {
+		return fmt.Errorf("升级软件包失败: %v", err)
+	}
+	return nil
+}
+
+// installOSPackages 上传离线安装包中的补丁软件包到工作目录并安装
+func (s *K3sService) installOSPackages(client *ssh.Client, osInfo *hostos.Info, nodeName string, b *bundle.Bundle, workspaceID string) error {
+	ws, err := k3s.NewWorkspace(client, workspaceID)
+	if err != nil {
+		return err
+	}
+	defer s.cleanupWorkspace(ws)
+
+	dir := path.Join(ws.Dir(), "packages")
+	cmd, err := osInfo.InstallLocalPackagesCommand(dir)
+	if err != nil {
+		return err
+	}
+	count, err := s.installer.UploadOSPackages(client, b, dir)
+	if err != nil {
+		return err
+	}
+	s.logger.Infof("节点 %s 安装 %d 个离线补丁软件包", nodeName, count)
+	if _, err := client.ExecuteCommand(cmd); err != nil {
+		return fmt.Errorf("安装离线补丁失败: %v", err)
+	}
+	return nil
+}
+
+// waitRebooted 轮询节点直到可以重新连接且 boot_id 与重启前不同
+func (s *K3sService) waitRebooted(node model.NodeConfig, bootID string, timeout, interval time.Duration) error {
+	deadline := time.Now().Add(timeout)
+	for {
+		time.Sleep(interval)
+		client := newNodeClient(node)
+		if err := client.Connect(); err == nil {
+			out, err := client.ExecuteIdempotentCommand("cat " + bootIDPath)
+			client.Close()
+			if err == nil && strings.TrimSpace(out.Stdout) != bootID {
+				return nil
+			}
+		}
+		if time.Now().Add(interval).After(deadline) {
+			return fmt.Errorf("等待节点 %s 重启完成超时（%s）", node.Name, timeout)
+		}
+	}
+}
+
+// WaitNodeReady 通过 Master 等待节点 Ready
+func (s *K3sService) WaitNodeReady(masterNode model.NodeConfig, node string, timeout, interval time.Duration) error {
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.WaitNodeReady(client, node, timeout, interval)
+}
@@ -0,0 +1,103 @@
+package service_test
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/notify"
+	"k3s-deploy-backend/internal/pkg/ssh"
+	"k3s-deploy-backend/internal/pkg/sshsim"
+	"k3s-deploy-backend/internal/pkg/store"
+	"k3s-deploy-backend/internal/pkg/vault"
+	"k3s-deploy-backend/internal/service"
+)
+
+// simRequest 两节点 dual 模式的完整部署，安装脚本内联以免下载
+const simRequest = `{
+	"deployMode": "dual",
+	"step": "all",
+	"allowUnverifiedArtifacts": true,
+	"installScript": {"content": "#!/bin/sh\nsetup_env() {\n}\n"},
+	"nodes": [
+		{"name": "k3s-master", "ip": "10.0.0.1", "port": 22, "username": "root", "authType": "password", "password": "sim-password"},
+		{"name": "k3s-agent", "ip": "10.0.0.2", "port": 22, "username": "root", "authType": "password", "password": "sim-password"}
+	],
+	"roleAssignment": {"app": "k3s-master", "middleware": "k3s-master", "database": "k3s-agent"},
+	"labels": {"k3s-master": ["insuite.middleware=true", "insuite.app=true"], "k3s-agent": ["insuite.database=true"]},
+	"wait": {"serviceTimeout": 10, "deploymentTimeout": 10, "pollInterval": 1},
+	"networkCheck": {"disabled": true}
+}`
+
+// TestPipelineAgainstSimulator 使用内置模拟规则执行完整部署流水线，经过任务队列、凭据库和集群登记
+func TestPipelineAgainstSimulator(t *testing.T) {
+	if testing.Short() {
+		t.Skip("完整流水线耗时较长")
+	}
+	sim, err := sshsim.Load("")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ssh.SetBackend(sim)
+	t.Cleanup(func() { ssh.SetBackend(nil) })
+
+	log := logger.NewLogger()
+	st := store.NewMemoryStore()
+	v, err := vault.OpenStore(st, filepath.Join(t.TempDir(), "vault.key"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	k3sService := service.NewK3sService(k3s.NewInstaller(k3s.MirrorConfig{}, log), service.NewChangeJournal(st, log), 0, log)
+	credentials := service.NewCredentialService(v, nil, log)
+	clusters := service.NewClusterService(st, k3sService, credentials, log)
+	deploy := service.NewDeployService(k3sService, credentials, clusters, service.IngressCAConfig{}, nil, log)
+	tasks := service.NewTaskService(deploy, credentials, st, 1, notify.Multi{}, nil, log)
+
+	var req model.DeployRequest
+	if err := json.Unmarshal([]byte(simRequest), &req); err != nil {
+		t.Fatal(err)
+	}
+	task, err := tasks.Submit(&req)
+	if err != nil {
+		t.Fatalf("提交任务失败: %v", err)
+	}
+
+	deadline := time.Now().Add(2 * time.Minute)
+	for task.Status == model.TaskQueued || task.Status == model.TaskRunning {
+		if time.Now().After(deadline) {
+			t.Fatalf("任务未在期限内完成，当前步骤 %s", task.CurrentStep)
+		}
+		time.Sleep(200 * time.Millisecond)
+		if task, err = tasks.Get(task.ID, service.LogFilter{}); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if task.Status != model.TaskSucceeded {
+		t.Fatalf("任务状态 %s（步骤 %s）: %s\n%s", task.Status, task.CurrentStep, task.Message, strings.Join(task.Logs, "\n"))
+	}
+
+	installed := map[string]bool{}
+	for _, e := range sim.History() {
+		if strings.Contains(e.Command, "INSTALL_K3S") || strings.Contains(e.Command, "K3S_NODE_NAME") {
+			installed[e.Host] = true
+		}
+	}
+	for _, ip := range []string{"10.0.0.1", "10.0.0.2"} {
+		if !installed[ip] {
+			t.Errorf("节点 %s 未执行 k3s 安装", ip)
+		}
+	}
+
+	cluster, err := clusters.FindByMaster("10.0.0.1")
+	if err != nil || cluster == nil {
+		t.Fatalf("部署完成后未登记集群: %v", err)
+	}
+	if cluster.Source != model.ClusterSourceDeployed {
+		t.Errorf("集群来源为 %s", cluster.Source)
+	}
+}
@@ -0,0 +1,197 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// k3sPort k3s 需要独占的端口
+type k3sPort struct {
+	port     int
+	protocol string
+	purpose  string
+	// serverOnly 仅 Server 节点监听
+	serverOnly bool
+}
+
+var k3sPorts = []k3sPort{
+	{6443, "tcp", "Kubernetes API Server", true},
+	{2379, "tcp", "etcd 客户端", true},
+	{2380, "tcp", "etcd 节点通信", true},
+	{10250, "tcp", "kubelet", false},
+	{8472, "udp", "flannel VXLAN", false},
+}
+
+// checkCgroups 检查 k3s 必需的 cgroup 控制器。缺少 memory 控制器时 k3s 启动即失败，
+// 常见于树莓派等默认关闭 memory cgroup 的内核
+func (s *K3sService) checkCgroups(client *ssh.Client, nodeName string) error {
+	result, err := client.ExecuteIdempotentCommand("stat -fc %T /sys/fs/cgroup")
+	if err != nil {
+		return fmt.Errorf("节点 %s 无法识别 cgroup 版本: %v", nodeName, err)
+	}
+
+	var enabled string
+	version := "v1"
+	if strings.TrimSpace(result.Stdout) == "cgroup2fs" {
+		version = "v2"
+		result, err = client.ExecuteIdempotentCommand("cat /sys/fs/cgroup/cgroup.controllers")
+		if err != nil {
+			return fmt.Errorf("节点 %s 无法读取 cgroup 控制器: %v", nodeName, err)
+		}
+		enabled = result.Stdout
+	} else {
+		// /proc/cgroups 第四列为 enabled 标志
+		result, err = client.ExecuteIdempotentCommand("awk '$4 == 1 { print $1 }' /proc/cgroups")
+		if err != nil {
+			return fmt.Errorf("节点 %s 无法读取 cgroup 控制器: %v", nodeName, err)
+		}
+		enabled = result.Stdout
+	}
+
+	controllers := strings.Fields(enabled)
+	var missing []string
+	for _, required := range []string{"cpuset", "memory"} {
+		found := false
+		for _, c := range controllers {
+			if c == required {
+				found = true
+				break
+			}
+		}
+		if !found {
+			missing = append(missing, required)
+		}
+	}
+	if len(missing) > 0 {
+		// 错误信息包含 cgroup_memory 以便失败分类给出内核参数建议
+		return fmt.Errorf("节点 %s 未启用 cgroup 控制器 %v（cgroup %s），请在内核启动参数中添加 cgroup_memory=1 cgroup_enable=memory cgroup_enable=cpuset 后重启",
+			nodeName, missing, version)
+	}
+	s.logger.Infof("节点 %s cgroup %s 验证通过", nodeName, version)
+	return nil
+}
+
+// checkRuntimeConflicts 检查节点上已有的容器运行时、旧 Kubernetes 残留和端口占用。
+// k3s 内置 containerd，与已有运行时可以共存，因此运行时只给出警告；端口被占用和 --docker 缺少 Docker 时预检失败
+func (s *K3sService) checkRuntimeConflicts(client *ssh.Client, nodeName string, osInfo *hostos.Info, isServer bool, runtime *model.RuntimeOptions) error {
+	dockerActive := s.runtimeActive(client, osInfo, "docker", "docker")
+	if runtime.Docker && !dockerActive {
+		return fmt.Errorf("节点 %s 选择使用 Docker 作为容器运行时，但 Docker 未安装或未运行", nodeName)
+	}
+	if dockerActive {
+		if runtime.Docker {
+			s.logger.Infof("节点 %s k3s 将使用已有的 Docker 作为容器运行时", nodeName)
+		} else {
+			s.logger.Warnf("节点 %s 已运行 Docker，k3s 将使用内置 containerd，镜像不与 Docker 共享；如需复用 Docker 请设置 runtime.docker", nodeName)
+		}
+		// Docker 会把 FORWARD 链默认策略设为 DROP，导致跨节点 Pod 流量被丢弃
+		result, err := client.ExecuteIdempotentCommand("iptables -S FORWARD 2>/dev/null | head -1")
+		if err == nil && strings.TrimSpace(result.Stdout) == "-P FORWARD DROP" {
+			s.logger.Warnf("节点 %s iptables FORWARD 默认策略为 DROP（通常由 Docker 设置），可能导致跨节点 Pod 通信失败，建议执行 iptables -P FORWARD ACCEPT", nodeName)
+		}
+	}
+	if !runtime.Docker && s.runtimeActive(client, osInfo, "containerd", "containerd") {
+		s.logger.Warnf("节点 %s 已运行独立的 containerd，k3s 使用 /run/k3s/containerd 下的内置实例，两者会各自占用资源", nodeName)
+	}
+	if _, err := client.ExecuteIdempotentCommand("command -v podman"); err == nil {
+		s.logger.Warnf("节点 %s 已安装 podman，其 CNI 网络可能与 flannel 的 10.42.0.0/16 网段或 iptables 规则冲突", nodeName)
+	}
+
+	remnants := s.kubernetesRemnants(client)
+	if len(remnants) > 0 && !runtime.CleanupKubernetes {
+		s.logger.Warnf("节点 %s 存在旧 Kubernetes 安装残留 %v，可能导致端口冲突或网络异常；设置 runtime.cleanupKubernetes 可在预检时清理", nodeName, remnants)
+	} else if len(remnants) > 0 {
+		if err := s.cleanupKubernetes(client, nodeName, osInfo); err != nil {
+			return err
+		}
+	}
+
+	return s.checkPorts(client, nodeName, isServer)
+}
+
+// runtimeActive 检查容器运行时是否安装且服务正在运行
+func (s *K3sService) runtimeActive(client *ssh.Client, osInfo *hostos.Info, binary, unit string) bool {
+	if _, err := client.ExecuteIdempotentCommand("command -v " + binary); err != nil {
+		return false
+	}
+	result, err := client.ExecuteIdempotentCommand(osInfo.ServiceActiveCommand(unit))
+	return err == nil && strings.TrimSpace(result.Stdout) == "active"
+}
+
+// kubernetesRemnants 列出 kubeadm/kubelet 安装留下的文件和命令
+func (s *K3sService) kubernetesRemnants(client *ssh.Client) []string {
+	var found []string
+	for _, bin := range []string{"kubeadm", "kubelet"} {
+		if _, err := client.ExecuteIdempotentCommand("command -v " + bin); err == nil {
+			found = append(found, bin)
+		}
+	}
+	for _, dir := range []string{"/etc/kubernetes/manifests", "/var/lib/kubelet", "/var/lib/etcd"} {
+		if _, err := client.ExecuteIdempotentCommand("test -d " + dir); err == nil {
+			found = append(found, dir)
+		}
+	}
+	return found
+}
+
+// cleanupKubernetesScript 清理旧 Kubernetes 安装：重置 kubeadm、删除数据目录、CNI 配置与虚拟网卡和 KUBE-/CNI- iptables 规则。
+// podman 的 CNI 配置保留
+const cleanupKubernetesScript = `command -v kubeadm >/dev/null 2>&1 && kubeadm reset -f
+rm -rf /etc/kubernetes /var/lib/kubelet /var/lib/etcd /var/lib/cni
+[ -d /etc/cni/net.d ] && find /etc/cni/net.d -type f ! -name '*podman*' -delete
+for link in cni0 flannel.1 cali-tunl tunl0 kube-ipvs0 weave vxlan.calico cilium_host cilium_net cilium_vxlan; do
+  ip link delete $link 2>/dev/null
+done
+if command -v iptables-save >/dev/null 2>&1; then
+  iptables-save | grep -v -e KUBE- -e CNI- -e cali- | iptables-restore
+fi
+true`
+
+func (s *K3sService) cleanupKubernetes(client *ssh.Client, nodeName string, osInfo *hostos.Info) error {
+	s.logger.Warnf("节点 %s 开始清理旧 Kubernetes 安装", nodeName)
+	if _, err := client.ExecuteIdempotentCommand(osInfo.ServiceExistsCommand("kubelet")); err == nil {
+		if _, err := client.ExecuteCommand(osInfo.ServiceStopDisableCommand("kubelet")); err != nil {
+			return fmt.Errorf("节点 %s 停止 kubelet 失败: %v", nodeName, err)
+		}
+	}
+	if _, err := client.ExecuteCommand(cleanupKubernetesScript); err != nil {
+		return fmt.Errorf("节点 %s 清理旧 Kubernetes 安装失败: %v", nodeName, err)
+	}
+	s.logger.Infof("节点 %s 旧 Kubernetes 安装已清理", nodeName)
+	return nil
+}
+
+// checkPorts 检查 k3s 所需端口是否已被其他进程占用。已安装 k3s 的节点跳过检查，端口由 k3s 自身占用
+func (s *K3sService) checkPorts(client *ssh.Client, nodeName string, isServer bool) error {
+	if _, err := client.ExecuteIdempotentCommand("command -v k3s"); err == nil {
+		return nil
+	}
+	if _, err := client.ExecuteIdempotentCommand("command -v ss"); err != nil {
+		s.logger.Warnf("节点 %s 缺少 ss 命令，跳过端口占用检查", nodeName)
+		return nil
+	}
+
+	var conflicts []string
+	for _, p := range k3sPorts {
+		if p.serverOnly && !isServer {
+			continue
+		}
+		flag := "-ltnH"
+		if p.protocol == "udp" {
+			flag = "-lunH"
+		}
+		result, err := client.ExecuteIdempotentCommand(fmt.Sprintf("ss %s 'sport = :%d'", flag, p.port))
+		if err == nil && strings.TrimSpace(result.Stdout) != "" {
+			conflicts = append(conflicts, fmt.Sprintf("%d/%s（%s）", p.port, p.protocol, p.purpose))
+		}
+	}
+	if len(conflicts) > 0 {
+		return fmt.Errorf("节点 %s 端口已被占用: %s", nodeName, strings.Join(conflicts, ", "))
+	}
+	s.logger.Infof("节点 %s 端口占用验证通过", nodeName)
+	return nil
+}
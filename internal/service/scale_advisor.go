@@ -0,0 +1,415 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"os"
+	"sort"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/store"
+	"k3s-deploy-backend/pkg/utils"
+)
+
+// scaleAdvisorLease 多副本部署时同一周期只由一个副本采集用量
+const scaleAdvisorLease = "scale-advisor"
+
+// minScaleSamples 时间窗口内至少需要的采样数，采样过少时无法判断利用率是否持续
+const minScaleSamples = 3
+
+// singleInstanceDeployments inSuite 中不支持多副本的组件，利用率持续偏高时只建议提高资源
+var singleInstanceDeployments = map[string]bool{
+	"insuite-database":   true,
+	"insuite-middleware": true,
+}
+
+// ErrInsufficientSamples 时间窗口内的用量采样不足
+var ErrInsufficientSamples = errors.New("时间窗口内的用量采样不足")
+
+// UtilizationSample 采集节点与 Deployment 的资源用量
+func (s *K3sService) UtilizationSample(masterNode model.NodeConfig) (*model.UtilizationSample, error) {
+	client := newNodeClient(masterNode)
+
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.UtilizationSample(client)
+}
+
+// ScaleAdvisorOptions 用量采集与伸缩建议参数，百分比均相对于可分配资源或资源请求
+type ScaleAdvisorOptions struct {
+	Interval  time.Duration
+	Retention time.Duration
+	// Window 未指定时间窗口时分析的时长
+	Window           time.Duration
+	ScaleUpPercent   int
+	ScaleDownPercent int
+	TargetPercent    int
+}
+
+// ScaleAdvisorService 定期通过 metrics-server 采集受管集群的资源用量，按时间窗口内持续的利用率
+// 给出增减 Agent 节点和调整 Deployment 副本数的建议。只生成报告，不执行任何伸缩操作
+type ScaleAdvisorService struct {
+	opts           ScaleAdvisorOptions
+	store          store.Store
+	clusterService *ClusterService
+	k3sService     *K3sService
+	logger         *logger.Logger
+	owner          string
+}
+
+func NewScaleAdvisorService(opts ScaleAdvisorOptions, st store.Store, clusterService *ClusterService, k3sService *K3sService, logger *logger.Logger) *ScaleAdvisorService {
+	hostname, _ := os.Hostname()
+	owner, err := utils.GenerateID(hostname)
+	if err != nil {
+		owner = fmt.Sprintf("%s-%d", hostname, time.Now().UnixNano())
+	}
+
+	return &ScaleAdvisorService{
+		opts:           opts,
+		store:          st,
+		clusterService: clusterService,
+		k3sService:     k3sService,
+		logger:         logger,
+		owner:          owner,
+	}
+}
+
+// Start 按固定周期采集资源用量
+func (s *ScaleAdvisorService) Start() {
+	s.logger.Infof("资源用量采集已启用，周期 %s，保留 %s", s.opts.Interval, s.opts.Retention)
+	go func() {
+		ticker := time.NewTicker(s.opts.Interval)
+		defer ticker.Stop()
+		for range ticker.C {
+			acquired, err := s.store.AcquireLease(scaleAdvisorLease, s.owner, s.opts.Interval-time.Second)
+			if err != nil {
+				s.logger.Errorf("获取资源用量采集租约失败: %v", err)
+				continue
+			}
+			if !acquired {
+				continue
+			}
+			if err := s.Collect(); err != nil {
+				s.logger.Errorf("资源用量采集失败: %v", err)
+			}
+		}
+	}()
+}
+
+// Collect 采集所有受管集群的资源用量，单个集群失败（如未启用 metrics-server）不影响其他集群
+func (s *ScaleAdvisorService) Collect() error {
+	clusters, err := s.clusterService.List()
+	if err != nil {
+		return err
+	}
+	for _, cluster := range clusters {
+		if err := s.collect(cluster); err != nil {
+			s.logger.Warnf("采集集群 %s 资源用量失败: %v", cluster.Name, err)
+		}
+	}
+	return nil
+}
+
+// collect 追加一次采样并丢弃超过保留时长的采样
+func (s *ScaleAdvisorService) collect(cluster *model.Cluster) error {
+	master, err := s.clusterService.MasterNode(cluster)
+	if err != nil {
+		return err
+	}
+	sample, err := s.k3sService.UtilizationSample(master)
+	if err != nil {
+		return err
+	}
+	record, err := s.load(cluster.ID)
+	if err != nil {
+		return err
+	}
+
+	cutoff := sample.Time.Add(-s.opts.Retention)
+	kept := record.Samples[:0]
+	for _, existing := range record.Samples {
+		if existing.Time.After(cutoff) {
+			kept = append(kept, existing)
+		}
+	}
+	record.Samples = append(kept, *sample)
+
+	data, err := json.Marshal(record)
+	if err != nil {
+		return err
+	}
+	return s.store.Put(store.CollectionUtilization, cluster.ID, data)
+}
+
+// Report 分析集群最近 window 内的采样（为 0 时使用配置的窗口）并给出伸缩建议
+func (s *ScaleAdvisorService) Report(clusterID string, window time.Duration) (*model.ScaleReport, error) {
+	if _, err := s.clusterService.Get(clusterID); err != nil {
+		return nil, err
+	}
+	if window <= 0 {
+		window = s.opts.Window
+	}
+	record, err := s.load(clusterID)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	var samples []model.UtilizationSample
+	for _, sample := range record.Samples {
+		if !sample.Time.Before(now.Add(-window)) {
+			samples = append(samples, sample)
+		}
+	}
+	if len(samples) < minScaleSamples {
+		return nil, fmt.Errorf("%w：最近 %s 内只有 %d 个采样，至少需要 %d 个（检查 scale_advisor.interval 是否已配置、metrics-server 是否可用）",
+			ErrInsufficientSamples, window, len(samples), minScaleSamples)
+	}
+
+	report := &model.ScaleReport{
+		ClusterID:        clusterID,
+		GeneratedAt:      now,
+		Window:           window.String(),
+		Samples:          len(samples),
+		ScaleUpPercent:   s.opts.ScaleUpPercent,
+		ScaleDownPercent: s.opts.ScaleDownPercent,
+		TargetPercent:    s.opts.TargetPercent,
+	}
+	report.Nodes = recommendNodes(samples, s.opts)
+	report.Workloads = recommendReplicas(samples, s.opts)
+	return report, nil
+}
+
+func (s *ScaleAdvisorService) load(clusterID string) (*model.ClusterUtilization, error) {
+	record := &model.ClusterUtilization{ClusterID: clusterID}
+	data, err := s.store.Get(store.CollectionUtilization, clusterID)
+	if errors.Is(err, store.ErrNotFound) {
+		return record, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if err := json.Unmarshal(data, record); err != nil {
+		return nil, fmt.Errorf("解析集群 %s 资源用量采样失败: %v", clusterID, err)
+	}
+	return record, nil
+}
+
+// utilizationRange 汇总一组百分比的最小值、平均值和最大值，保留一位小数
+func utilizationRange(values []float64) model.UtilizationRange {
+	r := model.UtilizationRange{Min: math.Inf(1), Max: math.Inf(-1)}
+	sum := 0.0
+	for _, v := range values {
+		r.Min = math.Min(r.Min, v)
+		r.Max = math.Max(r.Max, v)
+		sum += v
+	}
+	r.Avg = sum / float64(len(values))
+	round := func(v float64) float64 { return math.Round(v*10) / 10 }
+	r.Min, r.Avg, r.Max = round(r.Min), round(r.Avg), round(r.Max)
+	return r
+}
+
+func ratio(used, total int64) float64 {
+	if total <= 0 {
+		return 0
+	}
+	return float64(used) / float64(total) * 100
+}
+
+// recommendNodes 按全部节点用量之和占可分配资源之和的比例判断：CPU 或内存在窗口内始终不低于扩容阈值时，
+// 按窗口内的峰值用量计算达到目标利用率所需增加的 Agent 节点数（新节点容量按现有 Agent 的平均值估算）；
+// CPU 和内存都始终不高于缩容阈值时，按利用率从低到高依次移除 Agent，直到再移除会使峰值用量超过目标利用率
+func recommendNodes(samples []model.UtilizationSample, opts ScaleAdvisorOptions) model.NodeRecommendation {
+	var cpuValues, memoryValues []float64
+	var peakCPU, peakMemory int64
+	nodeUsage := make(map[string][]float64)
+	for _, sample := range samples {
+		var cpu, cpuAlloc, memory, memoryAlloc int64
+		for _, node := range sample.Nodes {
+			cpu += node.CPUUsage
+			cpuAlloc += node.CPUAllocatable
+			memory += node.MemoryUsage
+			memoryAlloc += node.MemoryAllocatable
+			nodeUsage[node.Name] = append(nodeUsage[node.Name],
+				math.Max(ratio(node.CPUUsage, node.CPUAllocatable), ratio(node.MemoryUsage, node.MemoryAllocatable)))
+		}
+		cpuValues = append(cpuValues, ratio(cpu, cpuAlloc))
+		memoryValues = append(memoryValues, ratio(memory, memoryAlloc))
+		peakCPU = max(peakCPU, cpu)
+		peakMemory = max(peakMemory, memory)
+	}
+
+	// 节点容量取最近一次采样
+	latest := samples[len(samples)-1]
+	var agents []model.NodeUtilization
+	var cpuAlloc, memoryAlloc int64
+	for _, node := range latest.Nodes {
+		cpuAlloc += node.CPUAllocatable
+		memoryAlloc += node.MemoryAllocatable
+		if !node.Server {
+			agents = append(agents, node)
+		}
+	}
+	rec := model.NodeRecommendation{
+		Action:        model.ScaleNone,
+		Agents:        len(agents),
+		CPUPercent:    utilizationRange(cpuValues),
+		MemoryPercent: utilizationRange(memoryValues),
+		Reason:        "利用率在扩缩容阈值之间",
+	}
+	target := float64(opts.TargetPercent) / 100
+	up, down := float64(opts.ScaleUpPercent), float64(opts.ScaleDownPercent)
+
+	if rec.CPUPercent.Min >= up || rec.MemoryPercent.Min >= up {
+		reference := agents
+		if len(reference) == 0 {
+			reference = latest.Nodes
+		}
+		var perCPU, perMemory int64
+		for _, node := range reference {
+			perCPU += node.CPUAllocatable
+			perMemory += node.MemoryAllocatable
+		}
+		perCPU /= int64(len(reference))
+		perMemory /= int64(len(reference))
+
+		count := 1
+		if perCPU > 0 {
+			count = max(count, int(math.Ceil((float64(peakCPU)/target-float64(cpuAlloc))/float64(perCPU))))
+		}
+		if perMemory > 0 {
+			count = max(count, int(math.Ceil((float64(peakMemory)/target-float64(memoryAlloc))/float64(perMemory))))
+		}
+		rec.Action = model.ScaleAdd
+		rec.Count = count
+		rec.Reason = fmt.Sprintf("CPU 或内存利用率在窗口内始终不低于 %d%%，增加 %d 个 Agent 节点后峰值利用率约为 %d%%", opts.ScaleUpPercent, count, opts.TargetPercent)
+		return rec
+	}
+
+	if rec.CPUPercent.Max <= down && rec.MemoryPercent.Max <= down && len(agents) > 0 {
+		average := func(name string) float64 {
+			values := nodeUsage[name]
+			sum := 0.0
+			for _, v := range values {
+				sum += v
+			}
+			return sum / float64(len(values))
+		}
+		sort.SliceStable(agents, func(i, j int) bool { return average(agents[i].Name) < average(agents[j].Name) })
+
+		for _, agent := range agents {
+			cpuLeft, memoryLeft := cpuAlloc-agent.CPUAllocatable, memoryAlloc-agent.MemoryAllocatable
+			if float64(peakCPU) > target*float64(cpuLeft) || float64(peakMemory) > target*float64(memoryLeft) {
+				break
+			}
+			cpuAlloc, memoryAlloc = cpuLeft, memoryLeft
+			rec.Candidates = append(rec.Candidates, agent.Name)
+		}
+		if len(rec.Candidates) > 0 {
+			rec.Action = model.ScaleRemove
+			rec.Count = len(rec.Candidates)
+			rec.Reason = fmt.Sprintf("CPU 和内存利用率在窗口内始终不高于 %d%%，移除 %d 个 Agent 节点后峰值利用率不超过 %d%%（移除前先驱逐节点上的 Pod）",
+				opts.ScaleDownPercent, rec.Count, opts.TargetPercent)
+		} else {
+			rec.Reason = fmt.Sprintf("利用率低于 %d%%，但移除任一 Agent 节点都会使峰值利用率超过 %d%%", opts.ScaleDownPercent, opts.TargetPercent)
+		}
+	}
+	return rec
+}
+
+// recommendReplicas 按 Deployment 用量占全部副本资源请求之和的比例判断（与 HPA 相同）：CPU 或内存在窗口内
+// 始终不低于扩容阈值时按平均利用率计算达到目标利用率的副本数；设置了请求的资源都始终不高于缩容阈值时按峰值计算，
+// 至少保留 1 个副本。未设置资源请求的 Deployment 和系统命名空间不参与分析
+func recommendReplicas(samples []model.UtilizationSample, opts ScaleAdvisorOptions) []model.ReplicaRecommendation {
+	type series struct {
+		latest      model.WorkloadUtilization
+		cpu, memory []float64
+	}
+	workloads := make(map[string]*series)
+	for _, sample := range samples {
+		for _, w := range sample.Workloads {
+			if reservedNamespaces[w.Namespace] {
+				continue
+			}
+			key := w.Namespace + "/" + w.Name
+			s, ok := workloads[key]
+			if !ok {
+				s = &series{}
+				workloads[key] = s
+			}
+			s.latest = w
+			if w.CPURequest > 0 {
+				s.cpu = append(s.cpu, ratio(w.CPUUsage, w.CPURequest*int64(w.Replicas)))
+			}
+			if w.MemoryRequest > 0 {
+				s.memory = append(s.memory, ratio(w.MemoryUsage, w.MemoryRequest*int64(w.Replicas)))
+			}
+		}
+	}
+
+	// 只分析最近一次采样中仍存在的 Deployment
+	current := make(map[string]bool)
+	for _, w := range samples[len(samples)-1].Workloads {
+		current[w.Namespace+"/"+w.Name] = true
+	}
+
+	target := float64(opts.TargetPercent)
+	recs := []model.ReplicaRecommendation{}
+	for key, s := range workloads {
+		// 新建的 Deployment 采样不足时不作判断
+		if !current[key] || (len(s.cpu) < minScaleSamples && len(s.memory) < minScaleSamples) {
+			continue
+		}
+		w := s.latest
+		rec := model.ReplicaRecommendation{Namespace: w.Namespace, Name: w.Name, Replicas: w.Replicas, Recommended: w.Replicas}
+		scaleUp, scaleDown := 0, 0
+		allLow := true
+		for _, resource := range []struct {
+			values []float64
+			field  **model.UtilizationRange
+		}{{s.cpu, &rec.CPUPercent}, {s.memory, &rec.MemoryPercent}} {
+			if len(resource.values) < minScaleSamples {
+				continue
+			}
+			r := utilizationRange(resource.values)
+			*resource.field = &r
+			if r.Min >= float64(opts.ScaleUpPercent) {
+				scaleUp = max(scaleUp, int(math.Ceil(float64(w.Replicas)*r.Avg/target)))
+			}
+			if r.Max <= float64(opts.ScaleDownPercent) {
+				scaleDown = max(scaleDown, 1, int(math.Ceil(float64(w.Replicas)*r.Max/target)))
+			} else {
+				allLow = false
+			}
+		}
+
+		switch {
+		case scaleUp > w.Replicas && singleInstanceDeployments[w.Name]:
+			rec.Reason = fmt.Sprintf("利用率在窗口内始终不低于 %d%%，该组件为单实例，建议提高资源请求与限制", opts.ScaleUpPercent)
+		case scaleUp > w.Replicas:
+			rec.Recommended = scaleUp
+			rec.Reason = fmt.Sprintf("利用率在窗口内始终不低于 %d%%，按平均利用率扩容到 %d 个副本后约为 %d%%", opts.ScaleUpPercent, scaleUp, opts.TargetPercent)
+		case allLow && scaleDown < w.Replicas:
+			rec.Recommended = scaleDown
+			rec.Reason = fmt.Sprintf("利用率在窗口内始终不高于 %d%%，按峰值利用率缩容到 %d 个副本后不超过 %d%%", opts.ScaleDownPercent, scaleDown, opts.TargetPercent)
+		default:
+			continue
+		}
+		recs = append(recs, rec)
+	}
+	sort.Slice(recs, func(i, j int) bool {
+		if recs[i].Namespace != recs[j].Namespace {
+			return recs[i].Namespace < recs[j].Namespace
+		}
+		return recs[i].Name < recs[j].Name
+	})
+	return recs
+}
@@ -0,0 +1,145 @@
+package service
+
+import (
+	"fmt"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/logger"
+)
+
+// SecretsEncryptionStatus 读取 Master 节点上的 Secret 加密状态
+func (s *K3sService) SecretsEncryptionStatus(masterNode model.NodeConfig) (*model.SecretsEncryptionStatus, error) {
+	client := newNodeClient(masterNode)
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.SecretsEncryptionStatus(client)
+}
+
+// RotateSecretsEncryptionKeys 在 Master 节点轮换 Secret 加密密钥并等待重新加密完成
+func (s *K3sService) RotateSecretsEncryptionKeys(masterNode model.NodeConfig, policy k3s.WaitPolicy) (*model.SecretsEncryptionStatus, error) {
+	client := newNodeClient(masterNode)
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	return s.manager.RotateSecretsEncryptionKeys(client, policy)
+}
+
+// verifySecretsEncryption 读取 Secret 加密状态，安装时要求加密（含 CIS 加固）而集群未启用时失败；
+// 未要求加密时读取失败（如 k3s 版本过旧）只告警
+func (s *DeployService) verifySecretsEncryption(req *model.DeployRequest, masterNode model.NodeConfig) error {
+	required := req.Security != nil && (req.Security.SecretsEncryption || req.Security.CIS)
+	status, err := s.k3sService.SecretsEncryptionStatus(masterNode)
+	if err != nil {
+		if required {
+			return err
+		}
+		s.logger.Warnf("%v", err)
+		return nil
+	}
+	req.SecretsEncryption = status
+	if required && !status.Enabled {
+		return fmt.Errorf("部署请求设置了 security.secretsEncryption 或 security.cis，但集群未启用 Secret 加密（只能在首次安装 Master 时启用）")
+	}
+	if status.Enabled && !status.HashesMatch {
+		return fmt.Errorf("各 Server 节点的 Secret 加密配置不一致: %s", status.Hashes)
+	}
+	return nil
+}
+
+// rotateSecretsKeyStep 轮换 Secret 加密密钥，由 SecretsEncryptionService.Rotate 作为异步任务提交，不在完整部署流水线中
+func (s *DeployService) rotateSecretsKeyStep(req *model.DeployRequest) error {
+	var masterNode model.NodeConfig
+	for _, node := range req.Nodes {
+		if node.Name == "k3s-master" {
+			masterNode = node
+			break
+		}
+	}
+	if masterNode.Name == "" {
+		return fmt.Errorf("未找到Master节点")
+	}
+
+	status, err := s.k3sService.RotateSecretsEncryptionKeys(masterNode, waitPolicy(req.Wait))
+	if err != nil {
+		return err
+	}
+	req.SecretsEncryption = status
+	for _, key := range status.Keys {
+		if key.Active {
+			s.logger.Infof("Secret 加密密钥已轮换，当前密钥: %s", key.Name)
+		}
+	}
+	return nil
+}
+
+// SecretsEncryptionService Secret 静态加密的状态查询与密钥轮换，轮换记入审计日志
+type SecretsEncryptionService struct {
+	clusterService *ClusterService
+	k3sService     *K3sService
+	taskService    *TaskService
+	auditService   *AuditService
+	logger         *logger.Logger
+}
+
+func NewSecretsEncryptionService(clusterService *ClusterService, k3sService *K3sService, taskService *TaskService, auditService *AuditService, logger *logger.Logger) *SecretsEncryptionService {
+	return &SecretsEncryptionService{
+		clusterService: clusterService,
+		k3sService:     k3sService,
+		taskService:    taskService,
+		auditService:   auditService,
+		logger:         logger,
+	}
+}
+
+// Status 返回集群的 Secret 加密状态
+func (s *SecretsEncryptionService) Status(clusterID string) (*model.SecretsEncryptionStatus, error) {
+	cluster, err := s.clusterService.Get(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	master, err := s.clusterService.MasterNode(cluster)
+	if err != nil {
+		return nil, err
+	}
+	return s.k3sService.SecretsEncryptionStatus(master)
+}
+
+// Rotate 提交轮换集群 Secret 加密密钥的任务（rotate-secrets-key 步骤），重新加密最长需要数分钟，进度和结果通过任务查看
+func (s *SecretsEncryptionService) Rotate(clusterID, actor, requestID string) (*model.Task, error) {
+	cluster, err := s.clusterService.Get(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	// 凭据引用由任务执行时解析，请求中不保存明文
+	master := cluster.Master
+	master.Name = "k3s-master"
+	task, err := s.taskService.Submit(&model.DeployRequest{
+		Step:      "rotate-secrets-key",
+		RequestID: requestID,
+		Nodes:     []model.NodeConfig{master},
+	})
+
+	event := model.AuditEvent{
+		Action:    model.AuditSecretsRotate,
+		Actor:     actor,
+		RequestID: requestID,
+		ClusterID: cluster.ID,
+		Target:    cluster.Name,
+		Success:   err == nil,
+	}
+	if err != nil {
+		event.Details = err.Error()
+	} else {
+		event.Message = "已提交任务 " + task.ID
+	}
+	if auditErr := s.auditService.Record(event); auditErr != nil {
+		s.logger.Warnf("记录密钥轮换审计失败: %v", auditErr)
+	}
+	return task, err
+}
@@ -0,0 +1,243 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"sort"
+	"sync"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/store"
+	"k3s-deploy-backend/pkg/utils"
+)
+
+// ErrSecurityScanRunning 集群已有正在进行的安全扫描
+var ErrSecurityScanRunning = errors.New("集群正在进行安全扫描")
+
+// ErrNoSecurityScan 集群尚未扫描过
+var ErrNoSecurityScan = errors.New("集群尚未进行安全扫描")
+
+// RunSecurityScan 在 Master 上创建扫描 Job，对集群节点运行 kube-bench，并用 trivy 扫描 images
+func (s *K3sService) RunSecurityScan(masterNode model.NodeConfig, cfg k3s.ScanConfig, images []string) ([]model.BenchNodeResult, []model.ImageScanResult, error) {
+	client := newNodeClient(masterNode)
+	if err := client.Connect(); err != nil {
+		return nil, nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	ws, err := s.operationWorkspace(client, "scan")
+	if err != nil {
+		return nil, nil, err
+	}
+	defer s.cleanupWorkspace(ws)
+
+	return s.manager.RunSecurityScan(client, ws, cfg, images, waitPolicy(nil))
+}
+
+// SecurityScanService 按需对集群运行 kube-bench 与 trivy，每个集群保存最近一次扫描结果。
+// 扫描在后台执行，同一集群同时只有一次扫描，多副本部署时通过租约互斥
+type SecurityScanService struct {
+	cfg            k3s.ScanConfig
+	store          store.Store
+	clusterService *ClusterService
+	k3sService     *K3sService
+	auditService   *AuditService
+	logger         *logger.Logger
+	owner          string
+
+	// 租约对同一 owner 可重入，本副本内的并发扫描另外互斥
+	mu      sync.Mutex
+	running map[string]bool
+}
+
+func NewSecurityScanService(cfg k3s.ScanConfig, st store.Store, clusterService *ClusterService, k3sService *K3sService, auditService *AuditService, logger *logger.Logger) *SecurityScanService {
+	hostname, _ := os.Hostname()
+	owner, err := utils.GenerateID(hostname)
+	if err != nil {
+		owner = fmt.Sprintf("%s-%d", hostname, time.Now().UnixNano())
+	}
+
+	return &SecurityScanService{
+		cfg:            cfg,
+		store:          st,
+		clusterService: clusterService,
+		k3sService:     k3sService,
+		auditService:   auditService,
+		logger:         logger,
+		owner:          owner,
+		running:        make(map[string]bool),
+	}
+}
+
+// Start 开始扫描集群并立即返回状态为 running 的记录，扫描结果通过 Latest 查询
+func (s *SecurityScanService) Start(clusterID, actor, requestID string) (*model.SecurityScan, error) {
+	cluster, err := s.clusterService.Get(clusterID)
+	if err != nil {
+		return nil, err
+	}
+	master, err := s.clusterService.MasterNode(cluster)
+	if err != nil {
+		return nil, err
+	}
+
+	lease := "security-scan:" + cluster.ID
+	s.mu.Lock()
+	if s.running[cluster.ID] {
+		s.mu.Unlock()
+		return nil, ErrSecurityScanRunning
+	}
+	s.running[cluster.ID] = true
+	s.mu.Unlock()
+	release := func() {
+		s.store.ReleaseLease(lease, s.owner)
+		s.mu.Lock()
+		delete(s.running, cluster.ID)
+		s.mu.Unlock()
+	}
+
+	// kube-bench 与 trivy 依次等待，租约覆盖两者的超时；进程退出时未释放的租约到期后失效
+	acquired, err := s.store.AcquireLease(lease, s.owner, 2*s.cfg.Timeout+5*time.Minute)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("获取扫描租约失败: %v", err)
+	}
+	if !acquired {
+		release()
+		return nil, ErrSecurityScanRunning
+	}
+
+	id, err := utils.GenerateID("scan")
+	if err != nil {
+		release()
+		return nil, err
+	}
+	scan := &model.SecurityScan{
+		ID:        id,
+		ClusterID: cluster.ID,
+		Status:    model.SecurityScanRunning,
+		StartedBy: actor,
+		StartedAt: time.Now(),
+	}
+	if err := s.save(scan); err != nil {
+		release()
+		return nil, err
+	}
+	if err := s.auditService.Record(model.AuditEvent{
+		Action:    model.AuditSecurityScan,
+		Actor:     actor,
+		RequestID: requestID,
+		ClusterID: cluster.ID,
+		Target:    cluster.Name,
+		Success:   true,
+		Message:   "扫描 " + scan.ID,
+	}); err != nil {
+		s.logger.Warnf("记录安全扫描审计失败: %v", err)
+	}
+
+	running := *scan
+	go func() {
+		defer release()
+		s.run(cluster, master, scan)
+	}()
+	return &running, nil
+}
+
+// run 收集工作负载使用的镜像并执行扫描，结束后保存结果
+func (s *SecurityScanService) run(cluster *model.Cluster, master model.NodeConfig, scan *model.SecurityScan) {
+	err := func() error {
+		workloads, err := s.k3sService.ListWorkloads(master)
+		if err != nil {
+			return fmt.Errorf("读取工作负载失败: %v", err)
+		}
+		owners := make(map[string][]string)
+		var images []string
+		for _, workload := range workloads {
+			ref := workload.Namespace + "/" + workload.Kind + "/" + workload.Name
+			for _, image := range workload.Images {
+				if _, ok := owners[image]; !ok {
+					images = append(images, image)
+				}
+				owners[image] = append(owners[image], ref)
+			}
+		}
+		sort.Strings(images)
+
+		benches, results, err := s.k3sService.RunSecurityScan(master, s.cfg, images)
+		if err != nil {
+			return err
+		}
+		for i := range results {
+			results[i].Workloads = owners[results[i].Image]
+		}
+		scan.Nodes, scan.Images = benches, results
+		scan.Summary = summarizeScan(benches, results)
+		return nil
+	}()
+
+	finished := time.Now()
+	scan.FinishedAt = &finished
+	scan.Status = model.SecurityScanCompleted
+	if err != nil {
+		scan.Status = model.SecurityScanFailed
+		scan.Error = err.Error()
+		s.logger.Errorf("集群 %s 安全扫描失败: %v", cluster.Name, err)
+	} else {
+		s.logger.Infof("集群 %s 安全扫描完成：kube-bench 失败 %d 项，镜像严重漏洞 %d 个、高危漏洞 %d 个",
+			cluster.Name, scan.Summary.BenchFail, scan.Summary.Critical, scan.Summary.High)
+	}
+	if err := s.save(scan); err != nil {
+		s.logger.Errorf("保存集群 %s 安全扫描结果失败: %v", cluster.Name, err)
+	}
+}
+
+// summarizeScan 汇总 kube-bench 失败/警告项、镜像严重与高危漏洞数，以及未能完成扫描的节点和镜像数
+func summarizeScan(benches []model.BenchNodeResult, images []model.ImageScanResult) model.ScanSummary {
+	var summary model.ScanSummary
+	for _, bench := range benches {
+		summary.BenchFail += bench.Fail
+		summary.BenchWarn += bench.Warn
+		if bench.Error != "" {
+			summary.FailedTargets++
+		}
+	}
+	for _, image := range images {
+		summary.Critical += image.Counts["CRITICAL"]
+		summary.High += image.Counts["HIGH"]
+		if image.Error != "" {
+			summary.FailedTargets++
+		}
+	}
+	return summary
+}
+
+// Latest 返回集群最近一次扫描，扫描进行中时状态为 running
+func (s *SecurityScanService) Latest(clusterID string) (*model.SecurityScan, error) {
+	if _, err := s.clusterService.Get(clusterID); err != nil {
+		return nil, err
+	}
+	data, err := s.store.Get(store.CollectionSecurityScans, clusterID)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, ErrNoSecurityScan
+	}
+	if err != nil {
+		return nil, err
+	}
+	scan := &model.SecurityScan{}
+	if err := json.Unmarshal(data, scan); err != nil {
+		return nil, fmt.Errorf("解析集群 %s 安全扫描结果失败: %v", clusterID, err)
+	}
+	return scan, nil
+}
+
+func (s *SecurityScanService) save(scan *model.SecurityScan) error {
+	data, err := json.Marshal(scan)
+	if err != nil {
+		return err
+	}
+	return s.store.Put(store.CollectionSecurityScans, scan.ClusterID, data)
+}
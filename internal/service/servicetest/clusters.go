@@ -0,0 +1,225 @@
+package servicetest
+
+import (
+	"fmt"
+	"slices"
+	"sort"
+	"sync"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/service"
+)
+
+// Clusters service.ClusterRegistry 的内存实现，按 Master 地址保存集群记录，
+// 与 ClusterService 一样忽略未登记集群的期望状态和实例记录
+type Clusters struct {
+	Recorder
+
+	mu       sync.Mutex
+	clusters map[string]*model.Cluster
+	keys     map[string][2]string
+}
+
+var _ service.ClusterRegistry = (*Clusters)(nil)
+
+// NewClusters 创建没有集群记录的 Clusters
+func NewClusters() *Clusters {
+	return &Clusters{
+		clusters: make(map[string]*model.Cluster),
+		keys:     make(map[string][2]string),
+	}
+}
+
+// Add 预置一条集群记录，模拟已登记的集群
+func (c *Clusters) Add(cluster *model.Cluster) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.clusters[cluster.Master.IP] = cluster
+}
+
+// List 返回全部集群记录，按 Master 地址排序
+func (c *Clusters) List() []*model.Cluster {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	clusters := make([]*model.Cluster, 0, len(c.clusters))
+	for _, cluster := range c.clusters {
+		clusters = append(clusters, cluster)
+	}
+	sort.Slice(clusters, func(i, j int) bool { return clusters[i].Master.IP < clusters[j].Master.IP })
+	return clusters
+}
+
+func (c *Clusters) FindByMaster(ip string) (*model.Cluster, error) {
+	if err := c.record("FindByMaster", ip); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.clusters[ip], nil
+}
+
+func (c *Clusters) RegisterDeployed(master model.NodeConfig) (*model.Cluster, error) {
+	if err := c.record("RegisterDeployed", master); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	now := time.Now()
+	cluster, ok := c.clusters[master.IP]
+	if !ok {
+		cluster = &model.Cluster{
+			ID:        fmt.Sprintf("cluster-%d", len(c.clusters)+1),
+			Name:      master.IP,
+			Source:    model.ClusterSourceDeployed,
+			CreatedAt: now,
+		}
+		c.clusters[master.IP] = cluster
+	}
+	cluster.Master = master
+	cluster.UpdatedAt = now
+	return cluster, nil
+}
+
+func (c *Clusters) RecordLabels(masterIP string, labels map[string][]string) error {
+	if err := c.record("RecordLabels", masterIP, labels); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cluster := c.desired(masterIP)
+	if cluster == nil {
+		return nil
+	}
+	if cluster.Desired.Labels == nil {
+		cluster.Desired.Labels = make(map[string][]string)
+	}
+	for node, nodeLabels := range labels {
+		cluster.Desired.Labels[node] = nodeLabels
+	}
+	return nil
+}
+
+func (c *Clusters) RecordLogRotation(masterIP string, opts *model.LogRotationOptions) error {
+	if err := c.record("RecordLogRotation", masterIP, opts); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cluster := c.desired(masterIP); cluster != nil {
+		cluster.Desired.LogRotation = opts
+	}
+	return nil
+}
+
+func (c *Clusters) RecordDegraded(masterIP, step string, results []model.NodeResult) error {
+	if err := c.record("RecordDegraded", masterIP, step, results); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cluster := c.clusters[masterIP]
+	if cluster == nil {
+		return nil
+	}
+	for _, r := range results {
+		i := slices.IndexFunc(cluster.Degraded, func(d model.DegradedNode) bool { return d.IP == r.IP })
+		if i >= 0 {
+			cluster.Degraded = slices.Delete(cluster.Degraded, i, i+1)
+		}
+		if !r.Success {
+			cluster.Degraded = append(cluster.Degraded, model.DegradedNode{Name: r.Name, IP: r.IP, Step: step, Message: r.Message, Since: time.Now()})
+		}
+	}
+	return nil
+}
+
+func (c *Clusters) RecordAgents(masterIP string, agents []model.NodeConfig) error {
+	if err := c.record("RecordAgents", masterIP, agents); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cluster := c.clusters[masterIP]
+	if cluster == nil {
+		return nil
+	}
+	for _, agent := range agents {
+		agent.Password, agent.PrivateKey, agent.Passphrase = "", "", ""
+		if i := slices.IndexFunc(cluster.Agents, func(n model.NodeConfig) bool { return n.IP == agent.IP }); i >= 0 {
+			cluster.Agents[i] = agent
+		} else {
+			cluster.Agents = append(cluster.Agents, agent)
+		}
+	}
+	return nil
+}
+
+func (c *Clusters) RecordRelease(masterIP string, release model.Release) error {
+	if err := c.record("RecordRelease", masterIP, release); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cluster := c.clusters[masterIP]
+	if cluster == nil {
+		return nil
+	}
+	release.Revision = 1
+	kept := cluster.Releases[:0]
+	for _, existing := range cluster.Releases {
+		if existing.Name == release.Name {
+			release.Revision = existing.Revision + 1
+			continue
+		}
+		kept = append(kept, existing)
+	}
+	cluster.Releases = append(kept, release)
+	return nil
+}
+
+func (c *Clusters) RecordObjectStore(masterIP string, objectStore model.ObjectStore, accessKey, secretKey string) error {
+	if err := c.record("RecordObjectStore", masterIP, objectStore, accessKey, secretKey); err != nil {
+		return err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cluster := c.clusters[masterIP]
+	if cluster == nil {
+		return fmt.Errorf("集群 %s 未登记，无法保存对象存储访问密钥", masterIP)
+	}
+	cluster.ObjectStore = &objectStore
+	c.keys[masterIP] = [2]string{accessKey, secretKey}
+	return nil
+}
+
+func (c *Clusters) ObjectStoreByMaster(masterIP string) (*model.ObjectStoreAccess, error) {
+	if err := c.record("ObjectStoreByMaster", masterIP); err != nil {
+		return nil, err
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cluster := c.clusters[masterIP]
+	if cluster == nil || cluster.ObjectStore == nil {
+		return nil, nil
+	}
+	keys := c.keys[masterIP]
+	return &model.ObjectStoreAccess{
+		Endpoint:  cluster.ObjectStore.Endpoint,
+		Buckets:   cluster.ObjectStore.Buckets,
+		AccessKey: keys[0],
+		SecretKey: keys[1],
+	}, nil
+}
+
+// desired 返回已登记且期望状态已初始化的集群，调用方持有 mu
+func (c *Clusters) desired(masterIP string) *model.Cluster {
+	cluster := c.clusters[masterIP]
+	if cluster == nil {
+		return nil
+	}
+	if cluster.Desired == nil {
+		cluster.Desired = &model.DesiredState{}
+	}
+	return cluster
+}
@@ -0,0 +1,22 @@
+package servicetest
+
+import (
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/service"
+)
+
+// Credentials service.NodeCredentials 的内存实现，节点认证信息原样保留
+type Credentials struct {
+	Recorder
+}
+
+var _ service.NodeCredentials = (*Credentials)(nil)
+
+// NewCredentials 创建 Credentials
+func NewCredentials() *Credentials {
+	return &Credentials{}
+}
+
+func (c *Credentials) ResolveNodes(nodes []model.NodeConfig) error {
+	return c.record("ResolveNodes", nodes)
+}
@@ -0,0 +1,54 @@
+package servicetest
+
+import (
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/bundle"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/ssh"
+	"k3s-deploy-backend/internal/service"
+)
+
+// Installer service.Installer 的内存实现，不在节点上执行安装脚本
+type Installer struct {
+	Recorder
+
+	// ServerAddress ServerURL 返回的地址，为空时为 https://<节点地址>:6443
+	ServerAddress string
+}
+
+var _ service.Installer = (*Installer)(nil)
+
+// NewInstaller 创建所有操作都成功的 Installer
+func NewInstaller() *Installer {
+	return &Installer{}
+}
+
+func (i *Installer) CheckMirrors(client *ssh.Client, nodeName string, mirrors *model.MirrorOptions) error {
+	return i.record("CheckMirrors", nodeName, mirrors)
+}
+
+func (i *Installer) InstallMaster(client *ssh.Client, nodeName string, policy k3s.WaitPolicy, opts k3s.InstallOptions) ([]k3s.Digest, error) {
+	return nil, i.record("InstallMaster", nodeName, policy, opts)
+}
+
+func (i *Installer) ServerURL(masterClient *ssh.Client) (string, error) {
+	if err := i.record("ServerURL"); err != nil {
+		return "", err
+	}
+	if i.ServerAddress != "" {
+		return i.ServerAddress, nil
+	}
+	return "https://" + masterClient.Host() + ":6443", nil
+}
+
+func (i *Installer) InstallAgent(client *ssh.Client, serverURL string, nodeName string, token string, policy k3s.WaitPolicy, opts k3s.InstallOptions) ([]k3s.Digest, error) {
+	return nil, i.record("InstallAgent", serverURL, nodeName, token, policy, opts)
+}
+
+func (i *Installer) WriteCISConfig(client *ssh.Client, server bool) error {
+	return i.record("WriteCISConfig", server)
+}
+
+func (i *Installer) UploadOSPackages(client *ssh.Client, b *bundle.Bundle, dir string) (int, error) {
+	return 0, i.record("UploadOSPackages", b, dir)
+}
@@ -0,0 +1,225 @@
+package servicetest
+
+import (
+	"fmt"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/bundle"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/service"
+)
+
+// K3s service.K3sOperations 的内存实现。未通过 Fail 设置错误的操作都成功，
+// 返回空结果或下列字段中的预设值
+type K3s struct {
+	Recorder
+
+	// URL DeployInSuite 和 AccessURL 返回的访问地址，为空时为 http://<Master IP>:30080/
+	URL string
+	// Capacity CheckCapacity 返回的容量报告，为空时所有组件都能调度
+	Capacity *model.CapacityReport
+	// NetworkChecks CheckNetwork 返回的检查结果
+	NetworkChecks []model.NetworkCheck
+	// SecretsEncryption SecretsEncryptionStatus 返回的状态，为空时为未启用
+	SecretsEncryption *model.SecretsEncryptionStatus
+}
+
+var _ service.K3sOperations = (*K3s)(nil)
+
+// NewK3s 创建所有操作都成功的 K3s
+func NewK3s() *K3s {
+	return &K3s{}
+}
+
+func (k *K3s) accessURL(masterNode model.NodeConfig) string {
+	if k.URL != "" {
+		return k.URL
+	}
+	return fmt.Sprintf("http://%s:30080/", masterNode.IP)
+}
+
+func (k *K3s) ValidateNodes(nodes []model.NodeConfig, thresholds model.PreflightThresholds, runtime *model.RuntimeOptions, dns *model.DNSOptions, diskPrep *model.DiskPrepOptions, firewall *model.FirewallOptions, cni *model.CNIOptions) ([]model.PreflightResult, error) {
+	return nil, k.record("ValidateNodes", nodes, thresholds, runtime, dns, diskPrep, firewall, cni)
+}
+
+func (k *K3s) CheckServerReachable(nodes []model.NodeConfig, serverURL string) error {
+	return k.record("CheckServerReachable", nodes, serverURL)
+}
+
+func (k *K3s) PrepareNodes(nodes []model.NodeConfig, opts *model.NodePrepOptions) ([]model.NodeResult, error) {
+	return nil, k.record("PrepareNodes", nodes, opts)
+}
+
+func (k *K3s) ConfigureNodeDNS(nodes []model.NodeConfig, opts *model.DNSOptions) ([]model.NodeResult, error) {
+	return nil, k.record("ConfigureNodeDNS", nodes, opts)
+}
+
+func (k *K3s) SyncHosts(nodes []model.NodeConfig, opts *model.HostsOptions) ([]model.HostsSyncResult, error) {
+	return nil, k.record("SyncHosts", nodes, opts)
+}
+
+func (k *K3s) PrepareDisks(nodes []model.NodeConfig, opts *model.DiskPrepOptions) ([]model.NodeResult, error) {
+	return nil, k.record("PrepareDisks", nodes, opts)
+}
+
+func (k *K3s) TuneNodes(nodes []model.NodeConfig, opts *model.TuningOptions) ([]model.TuningResult, error) {
+	return nil, k.record("TuneNodes", nodes, opts)
+}
+
+func (k *K3s) HardenNodes(nodes []model.NodeConfig) ([]model.NodeResult, error) {
+	return nil, k.record("HardenNodes", nodes)
+}
+
+func (k *K3s) CheckMirrors(nodes []model.NodeConfig, mirrors *model.MirrorOptions) ([]model.NodeResult, error) {
+	return nil, k.record("CheckMirrors", nodes, mirrors)
+}
+
+func (k *K3s) InstallMaster(node model.NodeConfig, policy k3s.WaitPolicy, opts k3s.InstallOptions) ([]k3s.Digest, error) {
+	return nil, k.record("InstallMaster", node, policy, opts)
+}
+
+func (k *K3s) ConfigureAgent(masterNode, agentNode model.NodeConfig, agentIndex int, policy k3s.WaitPolicy, opts k3s.InstallOptions) ([]k3s.Digest, error) {
+	return nil, k.record("ConfigureAgent", masterNode, agentNode, agentIndex, policy, opts)
+}
+
+func (k *K3s) JoinAgent(node model.NodeConfig, serverURL, token string, policy k3s.WaitPolicy, opts k3s.InstallOptions) ([]k3s.Digest, error) {
+	return nil, k.record("JoinAgent", node, serverURL, token, policy, opts)
+}
+
+func (k *K3s) WaitCNI(masterNode model.NodeConfig, cni *model.CNIOptions, nodes []string, policy k3s.WaitPolicy) error {
+	return k.record("WaitCNI", masterNode, cni, nodes, policy)
+}
+
+// WaitNodesReady 所有期望节点都以期望名称注册并 Ready
+func (k *K3s) WaitNodesReady(masterNode model.NodeConfig, expected []model.NodeReadiness, policy k3s.WaitPolicy) ([]model.NodeReadiness, error) {
+	if err := k.record("WaitNodesReady", masterNode, expected, policy); err != nil {
+		return nil, err
+	}
+	results := make([]model.NodeReadiness, len(expected))
+	for i, e := range expected {
+		results[i] = e
+		results[i].RegisteredAs = e.ClusterName
+		results[i].Status = model.NodeReady
+	}
+	return results, nil
+}
+
+func (k *K3s) UninstallNodes(nodes []model.NodeConfig, rollbackOnly bool) []model.UninstallResult {
+	k.record("UninstallNodes", nodes, rollbackOnly)
+	return nil
+}
+
+func (k *K3s) ConfigureCoreDNS(masterNode model.NodeConfig, workspaceID string, cfg k3s.CoreDNSConfig, policy k3s.WaitPolicy) ([]string, error) {
+	return nil, k.record("ConfigureCoreDNS", masterNode, workspaceID, cfg, policy)
+}
+
+func (k *K3s) ConfigureStorage(nodes []model.NodeConfig, workspaceID string, opts *model.StorageOptions, policy k3s.WaitPolicy) ([]string, error) {
+	return nil, k.record("ConfigureStorage", nodes, workspaceID, opts, policy)
+}
+
+func (k *K3s) InstallCertManager(masterNode model.NodeConfig, workspaceID string, cfg k3s.CertManagerConfig, policy k3s.WaitPolicy) ([]string, error) {
+	return nil, k.record("InstallCertManager", masterNode, workspaceID, cfg, policy)
+}
+
+func (k *K3s) ApplyLabels(masterNode model.NodeConfig, labels map[string][]string) error {
+	return k.record("ApplyLabels", masterNode, labels)
+}
+
+func (k *K3s) InstallMinIO(masterNode model.NodeConfig, workspaceID string, cfg k3s.MinIOConfig, policy k3s.WaitPolicy) ([]string, int, error) {
+	return nil, 1, k.record("InstallMinIO", masterNode, workspaceID, cfg, policy)
+}
+
+func (k *K3s) InstallVelero(masterNode model.NodeConfig, workspaceID string, opts *model.VeleroOptions, policy k3s.WaitPolicy) ([]string, error) {
+	return nil, k.record("InstallVelero", masterNode, workspaceID, opts, policy)
+}
+
+func (k *K3s) PrePullImages(nodes []model.NodeConfig, roleAssignment map[string]string) ([]model.NodeResult, error) {
+	return nil, k.record("PrePullImages", nodes, roleAssignment)
+}
+
+func (k *K3s) CheckCapacity(masterNode model.NodeConfig, spec k3s.AppSpec) (*model.CapacityReport, error) {
+	if err := k.record("CheckCapacity", masterNode, spec); err != nil {
+		return nil, err
+	}
+	if k.Capacity != nil {
+		return k.Capacity, nil
+	}
+	return &model.CapacityReport{Fits: true}, nil
+}
+
+func (k *K3s) DeployInSuite(masterNode model.NodeConfig, workspaceID string, roleAssignment map[string]string, policy k3s.WaitPolicy, spec k3s.AppSpec) ([]string, string, error) {
+	if err := k.record("DeployInSuite", masterNode, workspaceID, roleAssignment, policy, spec); err != nil {
+		return nil, "", err
+	}
+	return nil, k.accessURL(masterNode), nil
+}
+
+func (k *K3s) VerifyDeployment(masterNode model.NodeConfig, namespace string) error {
+	return k.record("VerifyDeployment", masterNode, namespace)
+}
+
+func (k *K3s) CheckNetwork(masterNode model.NodeConfig, workspaceID, externalName string, checkPolicy bool, policy k3s.WaitPolicy) ([]model.NetworkCheck, error) {
+	if err := k.record("CheckNetwork", masterNode, workspaceID, externalName, checkPolicy, policy); err != nil {
+		return nil, err
+	}
+	return k.NetworkChecks, nil
+}
+
+func (k *K3s) AccessURL(masterNode model.NodeConfig, spec k3s.AppSpec, policy k3s.WaitPolicy) (string, error) {
+	if err := k.record("AccessURL", masterNode, spec, policy); err != nil {
+		return "", err
+	}
+	return k.accessURL(masterNode), nil
+}
+
+func (k *K3s) ApplyServiceConfig(nodes []model.NodeConfig, workspaceID, config, registries string, policy k3s.WaitPolicy) []model.NodeServiceResult {
+	k.record("ApplyServiceConfig", nodes, workspaceID, config, registries, policy)
+	return nil
+}
+
+func (k *K3s) RestartServices(nodes []model.NodeConfig, policy k3s.WaitPolicy) []model.NodeServiceResult {
+	k.record("RestartServices", nodes, policy)
+	return nil
+}
+
+func (k *K3s) ServiceLogs(nodes []model.NodeConfig, lines int) []model.NodeServiceResult {
+	k.record("ServiceLogs", nodes, lines)
+	return nil
+}
+
+func (k *K3s) SecretsEncryptionStatus(masterNode model.NodeConfig) (*model.SecretsEncryptionStatus, error) {
+	if err := k.record("SecretsEncryptionStatus", masterNode); err != nil {
+		return nil, err
+	}
+	if k.SecretsEncryption != nil {
+		return k.SecretsEncryption, nil
+	}
+	return &model.SecretsEncryptionStatus{HashesMatch: true}, nil
+}
+
+func (k *K3s) RotateSecretsEncryptionKeys(masterNode model.NodeConfig, policy k3s.WaitPolicy) (*model.SecretsEncryptionStatus, error) {
+	if err := k.record("RotateSecretsEncryptionKeys", masterNode, policy); err != nil {
+		return nil, err
+	}
+	return &model.SecretsEncryptionStatus{Enabled: true, HashesMatch: true}, nil
+}
+
+func (k *K3s) DrainNode(masterNode model.NodeConfig, node string, opts k3s.DrainOptions) (*model.DrainResult, error) {
+	if err := k.record("DrainNode", masterNode, node, opts); err != nil {
+		return nil, err
+	}
+	return &model.DrainResult{Node: node}, nil
+}
+
+func (k *K3s) UncordonNode(masterNode model.NodeConfig, node string) error {
+	return k.record("UncordonNode", masterNode, node)
+}
+
+func (k *K3s) WaitNodeReady(masterNode model.NodeConfig, node string, timeout, interval time.Duration) error {
+	return k.record("WaitNodeReady", masterNode, node, timeout, interval)
+}
+
+func (k *K3s) PatchNode(node model.NodeConfig, b *bundle.Bundle, reboot string, timeout, interval time.Duration, workspaceID string) (bool, error) {
+	return false, k.record("PatchNode", node, b, reboot, timeout, interval, workspaceID)
+}
@@ -0,0 +1,90 @@
+// Package servicetest 提供 service 包依赖接口的内存实现，记录调用并按方法名返回预设错误，
+// 用于在不连接节点的情况下测试 DeployService 的步骤逻辑
+package servicetest
+
+import (
+	"sync"
+)
+
+// Call 一次方法调用
+type Call struct {
+	Method string
+	Args   []interface{}
+}
+
+// Recorder 记录方法调用，Fail 设置的方法返回预设错误
+type Recorder struct {
+	mu     sync.Mutex
+	calls  []Call
+	errors map[string]error
+	// remaining 方法剩余的失败次数，0 表示一直失败
+	remaining map[string]int
+}
+
+// Fail 之后调用 method 都返回 err
+func (r *Recorder) Fail(method string, err error) {
+	r.FailTimes(method, err, 0)
+}
+
+// FailTimes 之后 times 次调用 method 返回 err，times 为 0 时一直返回
+func (r *Recorder) FailTimes(method string, err error, times int) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.errors == nil {
+		r.errors = make(map[string]error)
+		r.remaining = make(map[string]int)
+	}
+	r.errors[method] = err
+	r.remaining[method] = times
+}
+
+// Calls 返回 method 的调用记录，method 为空时返回全部记录，按调用顺序排列
+func (r *Recorder) Calls(method string) []Call {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var calls []Call
+	for _, c := range r.calls {
+		if method == "" || c.Method == method {
+			calls = append(calls, c)
+		}
+	}
+	return calls
+}
+
+// Methods 返回按调用顺序排列的方法名
+func (r *Recorder) Methods() []string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	methods := make([]string, len(r.calls))
+	for i, c := range r.calls {
+		methods[i] = c.Method
+	}
+	return methods
+}
+
+// Reset 清除调用记录和预设错误
+func (r *Recorder) Reset() {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = nil
+	r.errors = nil
+	r.remaining = nil
+}
+
+// record 记录调用并返回预设错误
+func (r *Recorder) record(method string, args ...interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.calls = append(r.calls, Call{Method: method, Args: args})
+	err, ok := r.errors[method]
+	if !ok {
+		return nil
+	}
+	switch n := r.remaining[method]; {
+	case n == 1:
+		delete(r.errors, method)
+	case n > 1:
+		r.remaining[method] = n - 1
+	}
+	return err
+}
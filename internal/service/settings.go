@@ -0,0 +1,173 @@
+package service
+
+import (
+	"fmt"
+	"net/url"
+	"regexp"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+)
+
+// SetDefaults 设置全局部署设置（来自配置文件），作为集群默认值和请求之上的最后一级
+func (s *DeployService) SetDefaults(settings model.DeploySettings) {
+	s.defaults = settings
+}
+
+// SetDefaults 设置集群的部署默认值，全部字段为空时清除
+func (s *ClusterService) SetDefaults(id string, settings *model.DeploySettings) (*model.Cluster, error) {
+	if err := validateSettings(*settings); err != nil {
+		return nil, err
+	}
+
+	cluster, err := s.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	cluster.Defaults = settings
+	if *settings == (model.DeploySettings{}) {
+		cluster.Defaults = nil
+	}
+	cluster.UpdatedAt = time.Now()
+	if err := s.save(cluster); err != nil {
+		return nil, err
+	}
+	return cluster, nil
+}
+
+// ResolveSettings 将请求的部署设置解析为生效值：请求中未设置的字段依次继承集群默认值（按 Master 地址匹配受管集群）
+// 和全局配置，预检建议值最后按配置档取内置值。结果写回请求，已解析的请求再次解析结果不变
+func (s *DeployService) ResolveSettings(req *model.DeployRequest) error {
+	settings := req.DeploySettings
+	cluster, err := s.clusterService.FindByMaster(clusterKey(req.Nodes))
+	if err != nil {
+		return fmt.Errorf("读取集群默认设置失败: %w", err)
+	}
+	if cluster != nil && cluster.Defaults != nil {
+		settings = inheritSettings(settings, *cluster.Defaults)
+	}
+	settings = inheritSettings(settings, s.defaults)
+	builtin := thresholdsFor(req.Profile)
+	settings = inheritSettings(settings, model.DeploySettings{PreflightThresholds: &builtin})
+
+	if err := validateSettings(settings); err != nil {
+		return err
+	}
+	req.DeploySettings = settings
+	return nil
+}
+
+// inheritSettings 返回 settings 的副本，其中未设置的字段取 parent 的值
+func inheritSettings(settings, parent model.DeploySettings) model.DeploySettings {
+	if parent.Wait != nil {
+		wait := *parent.Wait
+		if settings.Wait != nil {
+			wait = inheritWait(*settings.Wait, wait)
+		}
+		settings.Wait = &wait
+	}
+	if parent.Mirrors != nil {
+		mirrors := *parent.Mirrors
+		if settings.Mirrors != nil {
+			if settings.Mirrors.SystemDefault != "" {
+				mirrors.SystemDefault = settings.Mirrors.SystemDefault
+			}
+			if settings.Mirrors.Mirrors != nil {
+				mirrors.Mirrors = settings.Mirrors.Mirrors
+			}
+		}
+		settings.Mirrors = &mirrors
+	}
+	if parent.PreflightThresholds != nil {
+		preflight := *parent.PreflightThresholds
+		if settings.PreflightThresholds != nil {
+			preflight = inheritPreflight(*settings.PreflightThresholds, preflight)
+		}
+		settings.PreflightThresholds = &preflight
+	}
+	if parent.Proxy != nil {
+		proxy := *parent.Proxy
+		if settings.Proxy != nil {
+			proxy = inheritProxy(*settings.Proxy, proxy)
+		}
+		settings.Proxy = &proxy
+	}
+	return settings
+}
+
+func inheritWait(wait, parent model.WaitOptions) model.WaitOptions {
+	if wait.ServiceTimeout == 0 {
+		wait.ServiceTimeout = parent.ServiceTimeout
+	}
+	if wait.DeploymentTimeout == 0 {
+		wait.DeploymentTimeout = parent.DeploymentTimeout
+	}
+	if wait.PollInterval == 0 {
+		wait.PollInterval = parent.PollInterval
+	}
+	if wait.InstallTimeout == 0 {
+		wait.InstallTimeout = parent.InstallTimeout
+	}
+	if wait.ScriptDownloadTimeout == 0 {
+		wait.ScriptDownloadTimeout = parent.ScriptDownloadTimeout
+	}
+	return wait
+}
+
+func inheritPreflight(preflight, parent model.PreflightThresholds) model.PreflightThresholds {
+	if preflight.CPUCores == 0 {
+		preflight.CPUCores = parent.CPUCores
+	}
+	if preflight.MemoryMB == 0 {
+		preflight.MemoryMB = parent.MemoryMB
+	}
+	if preflight.DiskGB == 0 {
+		preflight.DiskGB = parent.DiskGB
+	}
+	return preflight
+}
+
+func inheritProxy(proxy, parent model.ProxyOptions) model.ProxyOptions {
+	if proxy.HTTPProxy == "" {
+		proxy.HTTPProxy = parent.HTTPProxy
+	}
+	if proxy.HTTPSProxy == "" {
+		proxy.HTTPSProxy = parent.HTTPSProxy
+	}
+	if proxy.NoProxy == "" {
+		proxy.NoProxy = parent.NoProxy
+	}
+	return proxy
+}
+
+// noProxyPattern NO_PROXY 以环境变量形式拼接到安装命令中，只允许主机名、IP、CIDR 和通配符
+var noProxyPattern = regexp.MustCompile(`^[A-Za-z0-9.,:/*_-]+$`)
+
+// proxyURLPattern 代理地址同样拼接到命令中，不允许空白和 shell 特殊字符
+var proxyURLPattern = regexp.MustCompile(`^[A-Za-z0-9.:/@%_~+-]+$`)
+
+// validateSettings 校验会拼接到节点命令中的代理和镜像源地址
+func validateSettings(settings model.DeploySettings) error {
+	if proxy := settings.Proxy; proxy != nil {
+		for _, value := range []string{proxy.HTTPProxy, proxy.HTTPSProxy} {
+			if value == "" {
+				continue
+			}
+			u, err := url.Parse(value)
+			if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" || !proxyURLPattern.MatchString(value) {
+				return fmt.Errorf("代理地址 %q 无效，应为 http://host:port 形式", value)
+			}
+		}
+		if proxy.NoProxy != "" && !noProxyPattern.MatchString(proxy.NoProxy) {
+			return fmt.Errorf("noProxy %q 无效，应为逗号分隔的主机名、IP 或 CIDR", proxy.NoProxy)
+		}
+	}
+	if mirrors := settings.Mirrors; mirrors != nil {
+		for _, mirror := range append([]string{mirrors.SystemDefault}, mirrors.Mirrors...) {
+			if mirror != "" && !proxyURLPattern.MatchString(mirror) {
+				return fmt.Errorf("镜像源地址 %q 无效", mirror)
+			}
+		}
+	}
+	return nil
+}
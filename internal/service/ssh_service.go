@@ -4,32 +4,74 @@ import (
 	"fmt"
 	"k3s-deploy-backend/internal/model"
 	"k3s-deploy-backend/internal/pkg/logger"
-	"k3s-deploy-backend/internal/pkg/ssh"
+	"k3s-deploy-backend/pkg/utils"
+	"strings"
 	"sync"
 )
 
 type SSHService struct {
-	logger *logger.Logger
+	// credentials 解析跳板机引用的凭据库记录
+	credentials NodeCredentials
+	logger      *logger.Logger
 }
 
-func NewSSHService(logger *logger.Logger) *SSHService {
+func NewSSHService(credentials NodeCredentials, logger *logger.Logger) *SSHService {
 	return &SSHService{
-		logger: logger,
+		credentials: credentials,
+		logger:      logger,
 	}
 }
 
 func (s *SSHService) TestConnection(req *model.SSHTestRequest) *model.SSHTestResponse {
 	s.logger.SSHConnectionAttempt("single", req.IP)
 
-	client := ssh.NewClient(ssh.SSHConfig{
-		Host:       req.IP,
-		Port:       req.Port,
-		Username:   req.Username,
-		AuthType:   req.AuthType,
-		Password:   req.Password,
-		PrivateKey: req.PrivateKey,
-		Passphrase: req.Passphrase,
-	})
+	// 密钥认证时先在本地解析私钥，给出明确的格式/口令错误
+	var keyDetail, keyType string
+	if req.AuthType == "key" {
+		keyInfo, err := utils.ValidatePrivateKey(req.PrivateKey, req.Passphrase)
+		if err != nil {
+			return &model.SSHTestResponse{
+				Success: false,
+				Details: []string{
+					"✗ 私钥校验失败",
+					fmt.Sprintf("错误信息: %s", err.Error()),
+					fmt.Sprintf("支持的私钥类型: %s（格式: %s）", strings.Join(utils.SupportedKeyTypes, ", "), strings.Join(utils.SupportedKeyFormats, ", ")),
+				},
+				SupportedKeyTypes: utils.SupportedKeyTypes,
+			}
+		}
+		keyType = keyInfo.Type
+		keyDetail = fmt.Sprintf("✓ 私钥类型: %s（%s 格式）", keyInfo.Type, keyInfo.Format)
+		if keyInfo.Bits > 0 {
+			keyDetail = fmt.Sprintf("✓ 私钥类型: %s %d 位（%s 格式）", keyInfo.Type, keyInfo.Bits, keyInfo.Format)
+		}
+	}
+
+	// 跳板机可引用凭据库中的凭据（credentialId），与部署请求一致
+	nodes := []model.NodeConfig{{
+		IP:          req.IP,
+		Port:        req.Port,
+		Username:    req.Username,
+		AuthType:    req.AuthType,
+		Password:    req.Password,
+		PrivateKey:  req.PrivateKey,
+		Passphrase:  req.Passphrase,
+		Transport:   req.Transport,
+		AgentID:     req.AgentID,
+		JumpHosts:   append([]model.JumpHost(nil), req.JumpHosts...),
+		DialOptions: req.DialOptions,
+	}}
+	if err := s.credentials.ResolveNodes(nodes); err != nil {
+		return &model.SSHTestResponse{
+			Success: false,
+			Details: []string{
+				"✗ 读取跳板机凭据失败",
+				fmt.Sprintf("错误信息: %s", err.Error()),
+			},
+		}
+	}
+
+	client := newNodeClient(nodes[0])
 
 	if err := client.Connect(); err != nil {
 		s.logger.Errorf("SSH connection failed for %s: %v", req.IP, err)
@@ -45,24 +87,25 @@ func (s *SSHService) TestConnection(req *model.SSHTestRequest) *model.SSHTestRes
 
 	// 执行基本命令测试
 	details := []string{"✓ SSH连接成功"}
-
-	// 测试基本命令
-	if result, err := client.ExecuteCommand("whoami"); err == nil {
-		details = append(details, fmt.Sprintf("✓ 当前用户: %s", result.Stdout))
-	}
-
-	if result, err := client.ExecuteCommand("uname -a"); err == nil {
-		details = append(details, fmt.Sprintf("✓ 系统信息: %s", result.Stdout))
+	if keyDetail != "" {
+		details = append(details, keyDetail)
 	}
 
-	if result, err := client.ExecuteCommand("free -m"); err == nil {
-		details = append(details, fmt.Sprintf("✓ 内存信息: %s", result.Stdout))
+	// 测试基本命令（同一连接上并行执行）
+	labels := []string{"当前用户", "系统信息", "内存信息"}
+	results := client.ExecuteParallel([]string{"whoami", "uname -a", "free -m"})
+	for i, r := range results {
+		if r.Err == nil {
+			details = append(details, fmt.Sprintf("✓ %s: %s", labels[i], r.Result.Stdout))
+		}
 	}
 
 	s.logger.Infof("SSH connection successful for %s", req.IP)
 	return &model.SSHTestResponse{
-		Success: true,
-		Details: details,
+		Success:           true,
+		Details:           details,
+		KeyType:           keyType,
+		SupportedKeyTypes: utils.SupportedKeyTypes,
 	}
 }
 
@@ -78,13 +121,17 @@ func (s *SSHService) BatchTestConnection(req *model.BatchSSHTestRequest) []*mode
 			defer wg.Done()
 
 			testReq := &model.SSHTestRequest{
-				IP:         n.IP,
-				Port:       n.Port,
-				Username:   n.Username,
-				AuthType:   n.AuthType,
-				Password:   n.Password,
-				PrivateKey: n.PrivateKey,
-				Passphrase: n.Passphrase,
+				IP:          n.IP,
+				Port:        n.Port,
+				Username:    n.Username,
+				AuthType:    n.AuthType,
+				Password:    n.Password,
+				PrivateKey:  n.PrivateKey,
+				Passphrase:  n.Passphrase,
+				Transport:   n.Transport,
+				AgentID:     n.AgentID,
+				JumpHosts:   n.JumpHosts,
+				DialOptions: n.DialOptions,
 			}
 
 			result := s.TestConnection(testReq)
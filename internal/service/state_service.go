@@ -0,0 +1,270 @@
+package service
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/store"
+	"k3s-deploy-backend/internal/pkg/vault"
+)
+
+// stateArchiveVersion 导出包格式版本，导入时拒绝不兼容的版本
+const stateArchiveVersion = 1
+
+// stateCollections 随导出包迁移的存储集合（凭据单独处理）
+var stateCollections = []string{store.CollectionNodes, store.CollectionClusters}
+
+const (
+	// MaxStateArchiveSize 上传的导出包大小上限
+	MaxStateArchiveSize = 64 << 20
+	// maxStateEntrySize 导出包中单个文件解压后的大小上限，maxStateTotalSize 为全部文件之和的上限
+	maxStateEntrySize = 64 << 20
+	maxStateTotalSize = 256 << 20
+)
+
+// ErrInvalidStateArchive 导出包格式无效、超出大小限制或包含无效记录，导入前校验，校验失败时不写入任何记录
+var ErrInvalidStateArchive = errors.New("导出包无效")
+
+// stateValidators 导入前逐条校验各集合的记录
+var stateValidators = map[string]func(id string, data json.RawMessage) error{
+	store.CollectionNodes:    validateStateObject,
+	store.CollectionClusters: validateStateCluster,
+}
+
+type stateManifest struct {
+	Version    int            `json:"version"`
+	ExportedAt time.Time      `json:"exportedAt"`
+	Counts     map[string]int `json:"counts"`
+}
+
+// StateService 后端状态导出与导入，用于迁移和灾备
+type StateService struct {
+	store  store.Store
+	vault  *vault.Vault
+	logger *logger.Logger
+}
+
+func NewStateService(st store.Store, v *vault.Vault, logger *logger.Logger) *StateService {
+	return &StateService{
+		store:  st,
+		vault:  v,
+		logger: logger,
+	}
+}
+
+// Export 将集群、节点清单和凭据打包为 tar.gz，凭据使用导出口令加密。
+// 导出包不包含 SSH CA 私钥（ssh_ca.key_file），需要单独备份
+func (s *StateService) Export(passphrase string) ([]byte, error) {
+	bundle, err := s.vault.Export(passphrase)
+	if err != nil {
+		return nil, err
+	}
+
+	manifest := stateManifest{
+		Version:    stateArchiveVersion,
+		ExportedAt: time.Now(),
+		Counts:     map[string]int{store.CollectionCredentials: len(bundle.Records)},
+	}
+	files := map[string]interface{}{store.CollectionCredentials: bundle}
+
+	for _, collection := range stateCollections {
+		records, err := s.store.List(collection)
+		if err != nil {
+			return nil, fmt.Errorf("读取 %s 失败: %w", collection, err)
+		}
+		raw := make(map[string]json.RawMessage, len(records))
+		for id, data := range records {
+			raw[id] = data
+		}
+		files[collection] = raw
+		manifest.Counts[collection] = len(raw)
+	}
+	files["manifest"] = manifest
+
+	var buf bytes.Buffer
+	gz := gzip.NewWriter(&buf)
+	tw := tar.NewWriter(gz)
+	for name, content := range files {
+		data, err := json.MarshalIndent(content, "", "  ")
+		if err != nil {
+			return nil, err
+		}
+		header := &tar.Header{Name: name + ".json", Mode: 0600, Size: int64(len(data)), ModTime: manifest.ExportedAt}
+		if err := tw.WriteHeader(header); err != nil {
+			return nil, err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return nil, err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return nil, err
+	}
+	if err := gz.Close(); err != nil {
+		return nil, err
+	}
+
+	s.logger.Infof("已导出后端状态: %v", manifest.Counts)
+	return buf.Bytes(), nil
+}
+
+// Import 导入 Export 生成的导出包；overwrite 为 false 时保留本地已存在的记录。
+// 全部记录（包括凭据能否用口令解密）校验通过后才开始写入，导出包无效时不修改任何数据
+func (s *StateService) Import(archive io.Reader, passphrase string, overwrite bool) (*model.StateImportResult, error) {
+	files, err := readStateArchive(archive)
+	if err != nil {
+		return nil, err
+	}
+
+	var manifest stateManifest
+	if err := unmarshalStateFile(files, "manifest", &manifest); err != nil {
+		return nil, err
+	}
+	if manifest.Version != stateArchiveVersion {
+		return nil, fmt.Errorf("%w: 不支持的导出包版本 %d", ErrInvalidStateArchive, manifest.Version)
+	}
+
+	var bundle vault.Bundle
+	if err := unmarshalStateFile(files, store.CollectionCredentials, &bundle); err != nil {
+		return nil, err
+	}
+	collections := make(map[string]map[string]json.RawMessage, len(stateCollections))
+	for _, collection := range stateCollections {
+		var records map[string]json.RawMessage
+		if err := unmarshalStateFile(files, collection, &records); err != nil {
+			return nil, err
+		}
+		validate := stateValidators[collection]
+		for id, data := range records {
+			if err := validate(id, data); err != nil {
+				return nil, fmt.Errorf("%w: %s/%s: %v", ErrInvalidStateArchive, collection, id, err)
+			}
+		}
+		collections[collection] = records
+	}
+
+	result := &model.StateImportResult{
+		Imported: make(map[string]int),
+		Skipped:  make(map[string]int),
+	}
+
+	// 凭据库先解密全部凭据再写入，口令错误时同样不写入
+	imported, skipped, err := s.vault.Import(&bundle, passphrase, overwrite)
+	if err != nil {
+		return nil, err
+	}
+	result.Imported[store.CollectionCredentials] = imported
+	result.Skipped[store.CollectionCredentials] = skipped
+
+	for _, collection := range stateCollections {
+		for id, data := range collections[collection] {
+			if !overwrite {
+				if _, err := s.store.Get(collection, id); err == nil {
+					result.Skipped[collection]++
+					continue
+				} else if !errors.Is(err, store.ErrNotFound) {
+					return nil, err
+				}
+			}
+			if err := s.store.Put(collection, id, data); err != nil {
+				return nil, fmt.Errorf("写入 %s/%s 失败: %w", collection, id, err)
+			}
+			result.Imported[collection]++
+		}
+	}
+
+	s.logger.Infof("已导入后端状态: 导入 %v，跳过 %v", result.Imported, result.Skipped)
+	return result, nil
+}
+
+// readStateArchive 读取导出包中的文件，只保留导出包格式中的文件，单个文件和解压后的总大小超出上限时拒绝
+func readStateArchive(archive io.Reader) (map[string][]byte, error) {
+	gz, err := gzip.NewReader(archive)
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStateArchive, err)
+	}
+	defer gz.Close()
+
+	known := map[string]bool{"manifest.json": true, store.CollectionCredentials + ".json": true}
+	for _, collection := range stateCollections {
+		known[collection+".json"] = true
+	}
+
+	files := make(map[string][]byte)
+	total := int64(0)
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%w: 读取失败: %v", ErrInvalidStateArchive, err)
+		}
+		if !known[header.Name] || header.Typeflag != tar.TypeReg {
+			continue
+		}
+		data, err := io.ReadAll(io.LimitReader(tr, maxStateEntrySize+1))
+		if err != nil {
+			return nil, fmt.Errorf("%w: 读取 %s 失败: %v", ErrInvalidStateArchive, header.Name, err)
+		}
+		if len(data) > maxStateEntrySize {
+			return nil, fmt.Errorf("%w: %s 超过 %d MiB", ErrInvalidStateArchive, header.Name, maxStateEntrySize>>20)
+		}
+		if total += int64(len(data)); total > maxStateTotalSize {
+			return nil, fmt.Errorf("%w: 解压后超过 %d MiB", ErrInvalidStateArchive, maxStateTotalSize>>20)
+		}
+		files[header.Name] = data
+	}
+	return files, nil
+}
+
+func unmarshalStateFile(files map[string][]byte, name string, v interface{}) error {
+	data, exists := files[name+".json"]
+	if !exists {
+		return fmt.Errorf("%w: 缺少 %s.json", ErrInvalidStateArchive, name)
+	}
+	if err := json.Unmarshal(data, v); err != nil {
+		return fmt.Errorf("%w: 解析 %s.json 失败: %v", ErrInvalidStateArchive, name, err)
+	}
+	return nil
+}
+
+// validateStateObject 记录必须是 JSON 对象，ID 不能为空
+func validateStateObject(id string, data json.RawMessage) error {
+	if strings.TrimSpace(id) == "" {
+		return errors.New("ID 为空")
+	}
+	var object map[string]json.RawMessage
+	if err := json.Unmarshal(data, &object); err != nil {
+		return fmt.Errorf("不是 JSON 对象: %v", err)
+	}
+	return nil
+}
+
+// validateStateCluster 集群记录需能解析为集群，记录中的 ID 与键一致，且有 Master 地址
+func validateStateCluster(id string, data json.RawMessage) error {
+	if err := validateStateObject(id, data); err != nil {
+		return err
+	}
+	var cluster model.Cluster
+	if err := json.Unmarshal(data, &cluster); err != nil {
+		return fmt.Errorf("解析集群失败: %v", err)
+	}
+	if cluster.ID != id {
+		return fmt.Errorf("记录中的 ID %q 与键不一致", cluster.ID)
+	}
+	if cluster.Master.IP == "" {
+		return errors.New("缺少 Master 地址")
+	}
+	return nil
+}
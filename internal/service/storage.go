@@ -0,0 +1,177 @@
+package service
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+	"sync"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/k3s"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+const defaultLonghornVersion = "v1.7.2"
+
+var longhornVersionPattern = regexp.MustCompile(`^v\d+\.\d+\.\d+$`)
+
+// longhornConfig 校验 Longhorn 参数并补全默认值
+func longhornConfig(opts *model.LonghornOptions, nodeCount int) (k3s.LonghornConfig, error) {
+	if opts == nil {
+		opts = &model.LonghornOptions{}
+	}
+	cfg := k3s.LonghornConfig{ManifestURL: opts.ManifestURL, Replicas: opts.Replicas}
+
+	if cfg.ManifestURL == "" {
+		version := opts.Version
+		if version == "" {
+			version = defaultLonghornVersion
+		}
+		if !longhornVersionPattern.MatchString(version) {
+			return cfg, fmt.Errorf("无效的 Longhorn 版本: %s", version)
+		}
+		cfg.ManifestURL = fmt.Sprintf("https://raw.githubusercontent.com/longhorn/longhorn/%s/deploy/longhorn.yaml", version)
+	} else if !strings.HasPrefix(cfg.ManifestURL, "https://") && !strings.HasPrefix(cfg.ManifestURL, "http://") ||
+		strings.ContainsAny(cfg.ManifestURL, " '\"$`;&|") {
+		return cfg, fmt.Errorf("无效的 Longhorn 清单地址: %s", cfg.ManifestURL)
+	}
+
+	if cfg.Replicas == 0 {
+		cfg.Replicas = min(nodeCount, 3)
+	}
+	if cfg.Replicas > nodeCount {
+		return cfg, fmt.Errorf("Longhorn 副本数 %d 超过节点数 %d", cfg.Replicas, nodeCount)
+	}
+	return cfg, nil
+}
+
+// ConfigureStorage 在各节点准备存储目录（放在预检发现的可用空间最大的分区上），
+// 然后在 Master 节点配置 local-path 或安装 Longhorn 并设为默认 StorageClass，返回上传过的文件路径
+func (s *K3sService) ConfigureStorage(nodes []model.NodeConfig, workspaceID string, opts *model.StorageOptions, policy k3s.WaitPolicy) ([]string, error) {
+	s.logger.DeploymentStep("configure-storage", "cluster")
+
+	var master model.NodeConfig
+	for _, node := range nodes {
+		if node.Name == "k3s-master" {
+			master = node
+			break
+		}
+	}
+	if master.Name == "" {
+		return nil, fmt.Errorf("未找到Master节点")
+	}
+
+	var longhorn k3s.LonghornConfig
+	if opts.Provider == model.StorageLonghorn {
+		var err error
+		if longhorn, err = longhornConfig(opts.Longhorn, len(nodes)); err != nil {
+			return nil, err
+		}
+	}
+
+	names := clusterNodeNames(nodes)
+	paths := make(map[string]string, len(nodes))
+	var mu sync.Mutex
+	results := runOnNodes(nodes, func(i int, client *ssh.Client) error {
+		dir, err := s.prepareStorageNode(client, nodes[i].Name, opts.Provider)
+		if err != nil {
+			return err
+		}
+		mu.Lock()
+		paths[names[nodes[i].Name]] = dir
+		mu.Unlock()
+		return nil
+	})
+	if err := nodesError("准备存储目录失败", results); err != nil {
+		return nil, err
+	}
+
+	client := newNodeClient(master)
+	if err := client.Connect(); err != nil {
+		return nil, fmt.Errorf("连接Master节点失败: %v", err)
+	}
+	defer client.Close()
+
+	if opts.Provider == model.StorageLonghorn {
+		return nil, s.manager.InstallLonghorn(client, longhorn, policy)
+	}
+
+	ws, err := k3s.NewWorkspace(client, workspaceID)
+	if err != nil {
+		return nil, err
+	}
+	defer s.cleanupWorkspace(ws)
+
+	err = s.manager.ConfigureLocalPath(client, ws, paths, policy)
+	return ws.Artifacts(), err
+}
+
+// prepareStorageNode 在可用空间最大的分区上创建存储目录并返回数据目录路径。
+// Longhorn 的数据目录固定为 /var/lib/longhorn，与预检放置 k3s 数据目录的方式一致，通过软链接指向大分区
+func (s *K3sService) prepareStorageNode(client *ssh.Client, nodeName, provider string) (string, error) {
+	mount, availGB, err := largestPartition(client)
+	if err != nil {
+		return "", fmt.Errorf("节点 %s %v", nodeName, err)
+	}
+
+	if provider == model.StorageLocalPath {
+		dir := k3s.LocalPathDefault
+		if mount != "/" {
+			dir = path.Join(mount, "k3s-storage")
+		}
+		if _, err := client.ExecuteCommand("mkdir -p " + dir); err != nil {
+			return "", fmt.Errorf("节点 %s 创建存储目录 %s 失败: %v", nodeName, dir, err)
+		}
+		s.logger.Infof("节点 %s local-path 数据目录: %s（可用 %.1fGB）", nodeName, dir, availGB)
+		return dir, nil
+	}
+
+	if err := s.ensureISCSI(client, nodeName); err != nil {
+		return "", err
+	}
+	if mount != "/" {
+		target := path.Join(mount, "longhorn")
+		cmd := fmt.Sprintf("if [ -e %[2]s ]; then echo exists; else mkdir -p %[1]s && ln -s %[1]s %[2]s; fi", target, k3s.LonghornDataPath)
+		result, err := client.ExecuteCommand(cmd)
+		if err != nil {
+			return "", fmt.Errorf("节点 %s 创建软链接 %s -> %s 失败: %v", nodeName, target, k3s.LonghornDataPath, err)
+		}
+		if strings.TrimSpace(result.Stdout) == "exists" {
+			s.logger.Warnf("节点 %s %s 已存在，保留现有数据目录", nodeName, k3s.LonghornDataPath)
+		} else {
+			s.logger.Infof("节点 %s Longhorn 数据目录 %s 已链接到 %s（可用 %.1fGB）", nodeName, k3s.LonghornDataPath, target, availGB)
+		}
+	}
+	return k3s.LonghornDataPath, nil
+}
+
+// ensureISCSI 安装 iSCSI 工具并启动 iscsid，Longhorn 依赖它挂载卷
+func (s *K3sService) ensureISCSI(client *ssh.Client, nodeName string) error {
+	osInfo, err := hostos.Detect(client)
+	if err != nil {
+		return fmt.Errorf("节点 %s %v", nodeName, err)
+	}
+
+	p := hostos.ISCSIPrerequisite
+	if _, err := client.ExecuteIdempotentCommand(p.Check); err != nil {
+		pkg, ok := osInfo.PackageFor(p)
+		if !ok {
+			return fmt.Errorf("节点 %s 缺少 %s，请手动安装 iSCSI 工具", nodeName, p.Name)
+		}
+		install, err := osInfo.InstallCommand(pkg)
+		if err != nil {
+			return fmt.Errorf("节点 %s %v", nodeName, err)
+		}
+		if _, err := client.ExecuteCommand(install); err != nil {
+			return fmt.Errorf("节点 %s 安装 %s 失败: %v", nodeName, pkg, err)
+		}
+		s.logger.Infof("节点 %s 已安装 %s", nodeName, pkg)
+	}
+
+	if _, err := client.ExecuteCommand(osInfo.ServiceEnableStartCommand("iscsid")); err != nil {
+		return fmt.Errorf("节点 %s 启动 iscsid 失败: %v", nodeName, err)
+	}
+	return nil
+}
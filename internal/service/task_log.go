@@ -0,0 +1,148 @@
+package service
+
+import (
+	"encoding/json"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/progress"
+)
+
+// LogFilter 查询任务时的日志过滤条件，用于前端增量轮询
+type LogFilter struct {
+	// Since 只返回序号大于 Since 的日志
+	Since int
+	// SinceTime 只返回晚于该时间的日志，零值表示不限
+	SinceTime time.Time
+	// Level 只返回不低于该级别的日志，为空表示全部
+	Level string
+}
+
+func (f LogFilter) match(entry model.TaskLogEntry) bool {
+	if entry.Seq <= f.Since {
+		return false
+	}
+	if !f.SinceTime.IsZero() && !entry.Timestamp.After(f.SinceTime) {
+		return false
+	}
+	return f.Level == "" || model.LogLevelRank(entry.Level) >= model.LogLevelRank(f.Level)
+}
+
+// runningTask 本副本正在执行的任务，用于将只带请求 ID 的事件归入任务。
+// 提交任务的 X-Request-ID 可能重复（调用方自行传入、重跑沿用原请求 ID），
+// 任务执行时以任务 ID 作为步骤的请求 ID，事件按任务 ID 归属
+type runningTask struct {
+	taskID string
+	step   string
+}
+
+// publish 发布任务的进度事件，任务日志由 recordEvent 写入
+func (s *TaskService) publish(task *model.Task, event model.ProgressEvent) {
+	event.TaskID = task.ID
+	event.RequestID = task.ID
+	progress.Publish(event)
+}
+
+// recordEvent 将进度事件写入所属任务的日志。任务队列发布的事件带任务 ID，
+// 安装器、Manager 和预检发布的事件只带请求 ID，归入本副本上正在执行该请求的任务的当前步骤；
+// 没有消息的事件（阶段开始、结束）只用于计时和推送，不写入日志
+func (s *TaskService) recordEvent(event model.ProgressEvent) {
+	if event.Message == "" {
+		return
+	}
+	taskID, step := event.TaskID, event.Step
+	if event.RequestID != "" {
+		s.runningMu.Lock()
+		if r, ok := s.running[event.RequestID]; ok && (taskID == "" || taskID == r.taskID) {
+			taskID = r.taskID
+			if event.Type == model.ProgressStepStarted {
+				r.step = event.Step
+			}
+			if step == "" {
+				step = r.step
+			}
+		}
+		s.runningMu.Unlock()
+	}
+	if taskID == "" {
+		return
+	}
+	s.appendEntry(taskID, model.TaskLogEntry{
+		Timestamp: event.Time,
+		Level:     event.Level,
+		Step:      step,
+		Node:      event.Node,
+		Message:   event.Message,
+	})
+}
+
+// trackRunning 登记本副本开始执行的任务，调用返回的函数取消登记
+func (s *TaskService) trackRunning(task *model.Task) func() {
+	s.runningMu.Lock()
+	s.running[task.ID] = &runningTask{taskID: task.ID}
+	s.runningMu.Unlock()
+	return func() {
+		s.runningMu.Lock()
+		delete(s.running, task.ID)
+		s.runningMu.Unlock()
+	}
+}
+
+// appendEntry 追加一条日志，序号由写入顺序决定，不保存在日志中
+func (s *TaskService) appendEntry(id string, entry model.TaskLogEntry) {
+	entry.Seq = 0
+	if entry.Timestamp.IsZero() {
+		entry.Timestamp = time.Now()
+	}
+	data, err := json.Marshal(&entry)
+	if err == nil {
+		err = s.store.AppendTaskLog(id, string(data))
+	}
+	if err != nil {
+		s.logger.Warnf("写入任务 %s 日志失败: %v", id, err)
+	}
+}
+
+// taskTimeline 解析存储中的日志行并按过滤条件筛选。升级前写入的日志是纯文本，作为没有时间的 info 日志返回
+func taskTimeline(lines []string, filter LogFilter) []model.TaskLogEntry {
+	timeline := make([]model.TaskLogEntry, 0, len(lines))
+	for i, line := range lines {
+		var entry model.TaskLogEntry
+		if !strings.HasPrefix(line, "{") || json.Unmarshal([]byte(line), &entry) != nil || entry.Message == "" {
+			entry = model.TaskLogEntry{Level: model.LogInfo, Message: line}
+		}
+		entry.Seq = i + 1
+		if filter.match(entry) {
+			timeline = append(timeline, entry)
+		}
+	}
+	return timeline
+}
+
+// resultLevel 步骤结果的日志级别：失败为 error，部分成功为 warn
+func resultLevel(result *model.DeployResponse) string {
+	switch {
+	case !result.Success:
+		return model.LogError
+	case result.Partial:
+		return model.LogWarn
+	}
+	return model.LogInfo
+}
+
+// taskLevel 任务结束日志的级别
+func taskLevel(task *model.Task) string {
+	switch {
+	case task.Status == model.TaskFailed:
+		return model.LogError
+	case len(task.FailedNodes) > 0:
+		return model.LogWarn
+	}
+	return model.LogInfo
+}
+
+// stepDetail 步骤的补充信息事件
+func stepDetail(step, node, level, message string) model.ProgressEvent {
+	return model.ProgressEvent{Type: model.ProgressDetail, Step: step, Node: node, Level: level, Message: message}
+}
@@ -0,0 +1,237 @@
+package service
+
+import (
+	"errors"
+	"fmt"
+	"slices"
+	"strings"
+	"sync/atomic"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/pkg/utils"
+)
+
+// NodeConflictError 请求中的节点正被其他集群的排队中或执行中的任务使用
+type NodeConflictError struct {
+	Conflicts []model.NodeConflict
+}
+
+func (e *NodeConflictError) Error() string {
+	parts := make([]string, 0, len(e.Conflicts))
+	for _, c := range e.Conflicts {
+		detail := c.Status
+		if c.Step != "" {
+			detail += "，步骤 " + c.Step
+		}
+		parts = append(parts, fmt.Sprintf("节点 %s 正被任务 %s 使用（%s）", c.IP, c.TaskID, detail))
+	}
+	return strings.Join(parts, "；")
+}
+
+// nodeLease 节点锁：任务执行期间持有其全部节点的租约，避免不同集群的任务同时在同一台主机上安装
+func nodeLease(ip string) string {
+	return "node/" + ip
+}
+
+// nodeLeaseOwner 节点锁的持有者只与任务相关（不含副本 ID），强制接管时可以在任务启动前为其预留节点
+func nodeLeaseOwner(taskID string) string {
+	return "task/" + taskID
+}
+
+// taskNodes 请求中节点的地址（去重）
+func taskNodes(nodes []model.NodeConfig) []string {
+	ips := make([]string, 0, len(nodes))
+	for _, node := range nodes {
+		if node.IP != "" && !slices.Contains(ips, node.IP) {
+			ips = append(ips, node.IP)
+		}
+	}
+	return ips
+}
+
+// nodeConflicts 返回与 ips 重叠、属于其他集群且尚未结束的任务。同一集群的任务由集群租约串行执行，
+// 后提交的任务排队等待，不算冲突
+func (s *TaskService) nodeConflicts(key string, ips []string) ([]model.NodeConflict, error) {
+	tasks, err := s.loadTasks()
+	if err != nil {
+		return nil, err
+	}
+	var conflicts []model.NodeConflict
+	for _, task := range tasks {
+		if task.FinishedAt != nil || task.ClusterKey == key {
+			continue
+		}
+		step := task.CurrentStep
+		if step == "" {
+			step = task.Step
+		}
+		for _, ip := range task.Nodes {
+			if slices.Contains(ips, ip) {
+				conflicts = append(conflicts, model.NodeConflict{
+					IP:         ip,
+					TaskID:     task.ID,
+					ClusterKey: task.ClusterKey,
+					Status:     task.Status,
+					Step:       step,
+				})
+			}
+		}
+	}
+	return conflicts, nil
+}
+
+// checkNodeConflicts 有冲突时返回 *NodeConflictError
+func (s *TaskService) checkNodeConflicts(req *model.DeployRequest) error {
+	conflicts, err := s.nodeConflicts(clusterKey(req.Nodes), taskNodes(req.Nodes))
+	if err != nil {
+		return fmt.Errorf("检查节点冲突失败: %w", err)
+	}
+	if len(conflicts) > 0 {
+		return &NodeConflictError{Conflicts: conflicts}
+	}
+	return nil
+}
+
+// ErrNodeLockHeld 强制接管时节点锁被冲突任务之外的任务取得
+var ErrNodeLockHeld = errors.New("节点锁被其他任务持有")
+
+// ErrTakeoverFailed 强制接管因存储错误失败
+var ErrTakeoverFailed = errors.New("接管节点失败")
+
+// Takeover 强制提交任务（管理员操作）：忽略节点冲突，先把冲突节点的锁转给新任务，全部转移成功后再入队，
+// 任一节点转移失败时释放已取得的锁且不创建任务。
+// 执行中的冲突任务在当前步骤结束后停止（正在执行的步骤无法中断），排队中的冲突任务等新任务释放节点后再执行。
+// 转移的锁在新任务开始执行前按 taskLeaseTTL 过期，并发槽位或集群被占用导致新任务迟迟不能开始时可能被其他任务取得
+func (s *TaskService) Takeover(req *model.DeployRequest) (*model.Task, error) {
+	if err := s.prepare(req); err != nil {
+		return nil, err
+	}
+	conflicts, err := s.nodeConflicts(clusterKey(req.Nodes), taskNodes(req.Nodes))
+	if err != nil {
+		return nil, fmt.Errorf("%w: 检查节点冲突失败: %v", ErrTakeoverFailed, err)
+	}
+	id, err := utils.GenerateID("task")
+	if err != nil {
+		return nil, err
+	}
+
+	// 同一节点可能被多个任务使用（执行中的任务持有锁，排队中的任务没有），按节点汇总
+	var ips []string
+	holders := make(map[string][]string)
+	for _, c := range conflicts {
+		if _, ok := holders[c.IP]; !ok {
+			ips = append(ips, c.IP)
+		}
+		holders[c.IP] = append(holders[c.IP], c.TaskID)
+	}
+	owner := nodeLeaseOwner(id)
+	var transferred []string
+	release := func() {
+		for _, ip := range transferred {
+			s.store.ReleaseLease(nodeLease(ip), owner)
+		}
+	}
+	for _, ip := range ips {
+		if err := s.transferNode(ip, holders[ip], owner); err != nil {
+			release()
+			return nil, err
+		}
+		transferred = append(transferred, ip)
+	}
+
+	task, err := s.enqueueAs(id, req)
+	if err != nil {
+		release()
+		return nil, fmt.Errorf("%w: %v", ErrTakeoverFailed, err)
+	}
+	for _, c := range conflicts {
+		message := fmt.Sprintf("节点 %s 被任务 %s 强制接管，在该任务结束后继续排队", c.IP, task.ID)
+		if c.Status == model.TaskRunning {
+			message = fmt.Sprintf("节点 %s 被任务 %s 强制接管，当前步骤结束后停止", c.IP, task.ID)
+		}
+		if previous, err := s.loadTask(c.TaskID); err == nil {
+			s.publish(previous, model.ProgressEvent{Type: model.ProgressTaskTakeover, Level: model.LogWarn, Message: message})
+		}
+		s.publish(task, model.ProgressEvent{Type: model.ProgressTaskTakeover, Level: model.LogWarn,
+			Message: fmt.Sprintf("已接管任务 %s（%s）使用的节点 %s", c.TaskID, c.Status, c.IP)})
+		s.logger.WithField("requestId", req.RequestID).Warnf("任务 %s 强制接管了任务 %s 的节点 %s", task.ID, c.TaskID, c.IP)
+	}
+	// 入队时的调度因节点被占用未能启动新任务，接管后重新调度
+	s.dispatch()
+	return s.Get(task.ID, LogFilter{})
+}
+
+// transferNode 释放冲突任务 taskIDs 持有的节点锁并以 owner 取得
+func (s *TaskService) transferNode(ip string, taskIDs []string, owner string) error {
+	// 释放与获取之间被接管的任务可能恰好续期，重试几次
+	for attempt := 0; attempt < 3; attempt++ {
+		for _, taskID := range taskIDs {
+			if err := s.store.ReleaseLease(nodeLease(ip), nodeLeaseOwner(taskID)); err != nil {
+				return fmt.Errorf("%w: 释放节点 %s 的锁失败: %v", ErrTakeoverFailed, ip, err)
+			}
+		}
+		acquired, err := s.store.AcquireLease(nodeLease(ip), owner, taskLeaseTTL)
+		if err != nil {
+			return fmt.Errorf("%w: 接管节点 %s: %v", ErrTakeoverFailed, ip, err)
+		}
+		if acquired {
+			return nil
+		}
+	}
+	return fmt.Errorf("接管节点 %s 失败: %w", ip, ErrNodeLockHeld)
+}
+
+// nodesBusy 是否有其他执行中的任务使用了 task 的节点。强制接管后新任务已持有节点锁，
+// 但被接管的任务要到当前步骤结束后才停止
+func nodesBusy(task *model.Task, tasks []*model.Task) bool {
+	for _, other := range tasks {
+		if other.ID != task.ID && other.Status == model.TaskRunning &&
+			slices.ContainsFunc(other.Nodes, func(ip string) bool { return slices.Contains(task.Nodes, ip) }) {
+			return true
+		}
+	}
+	return false
+}
+
+// acquireNodes 获取任务全部节点的锁，任一节点被占用时释放已获取的锁并返回 false
+func (s *TaskService) acquireNodes(task *model.Task) bool {
+	owner := nodeLeaseOwner(task.ID)
+	for i, ip := range task.Nodes {
+		acquired, err := s.store.AcquireLease(nodeLease(ip), owner, taskLeaseTTL)
+		if err != nil || !acquired {
+			for _, held := range task.Nodes[:i] {
+				s.store.ReleaseLease(nodeLease(held), owner)
+			}
+			return false
+		}
+	}
+	return true
+}
+
+// renewNodes 续期任务的节点锁。某个节点的锁已被其他任务持有（被强制接管）时标记 lost，
+// 之后不再续期，由任务在下一个步骤开始前停止
+func (s *TaskService) renewNodes(task *model.Task, lost *atomic.Bool) {
+	if lost.Load() {
+		return
+	}
+	owner := nodeLeaseOwner(task.ID)
+	for _, ip := range task.Nodes {
+		acquired, err := s.store.AcquireLease(nodeLease(ip), owner, taskLeaseTTL)
+		if err != nil {
+			s.logger.Warnf("任务 %s 续期节点 %s 的锁失败: %v", task.ID, ip, err)
+			continue
+		}
+		if !acquired {
+			lost.Store(true)
+			s.logger.Warnf("任务 %s 的节点 %s 已被其他任务接管", task.ID, ip)
+			return
+		}
+	}
+}
+
+func (s *TaskService) releaseNodes(task *model.Task) {
+	owner := nodeLeaseOwner(task.ID)
+	for _, ip := range task.Nodes {
+		s.store.ReleaseLease(nodeLease(ip), owner)
+	}
+}
@@ -0,0 +1,99 @@
+package service
+
+import (
+	"fmt"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/vault"
+)
+
+// taskRequestAuthType 凭据库中保存任务请求敏感字段的记录类型，不是节点登录凭据
+const taskRequestAuthType = "task-request"
+
+// nodeCredential 凭据库记录是否为节点登录凭据（可轮换、可用于连接节点）
+func nodeCredential(authType string) bool {
+	return authType != kubeconfigAuthType && authType != objectStoreAuthType && authType != taskRequestAuthType
+}
+
+// SaveRequestSecrets 将任务请求的敏感字段加密存入凭据库，返回凭据 ID
+func (s *CredentialService) SaveRequestSecrets(taskID string, fields map[string]string) (string, error) {
+	cred := &vault.Credential{
+		Name:     "task:" + taskID,
+		Username: taskRequestAuthType,
+		AuthType: taskRequestAuthType,
+		Secret:   vault.Secret{Fields: fields},
+	}
+	if err := s.vault.Put(cred); err != nil {
+		return "", fmt.Errorf("保存任务请求敏感字段失败: %v", err)
+	}
+	return cred.ID, nil
+}
+
+// RequestSecrets 读取凭据库中保存的任务请求敏感字段
+func (s *CredentialService) RequestSecrets(id string) (map[string]string, error) {
+	cred, err := s.vault.Get(id)
+	if err != nil {
+		return nil, err
+	}
+	if cred.AuthType != taskRequestAuthType {
+		return nil, fmt.Errorf("凭据 %s 不是任务请求记录", id)
+	}
+	return cred.Secret.Fields, nil
+}
+
+// requestSecretFields 返回部署请求中全部敏感字段的地址，键为字段路径（如 nodes[0].password、velero.storage.secretKey）
+func requestSecretFields(req *model.DeployRequest) map[string]*string {
+	fields := make(map[string]*string)
+	for i := range req.Nodes {
+		node := &req.Nodes[i]
+		prefix := fmt.Sprintf("nodes[%d].", i)
+		fields[prefix+"password"] = &node.Password
+		fields[prefix+"privateKey"] = &node.PrivateKey
+		fields[prefix+"passphrase"] = &node.Passphrase
+		for j := range node.JumpHosts {
+			jump := &node.JumpHosts[j]
+			jumpPrefix := fmt.Sprintf("%sjumpHosts[%d].", prefix, j)
+			fields[jumpPrefix+"password"] = &jump.Password
+			fields[jumpPrefix+"privateKey"] = &jump.PrivateKey
+			fields[jumpPrefix+"passphrase"] = &jump.Passphrase
+		}
+	}
+	if req.Edge != nil {
+		fields["edge.token"] = &req.Edge.Token
+		fields["edge.joinBundle"] = &req.Edge.JoinBundle
+	}
+	if req.Velero != nil && req.Velero.Storage != nil {
+		fields["velero.storage.accessKey"] = &req.Velero.Storage.AccessKey
+		fields["velero.storage.secretKey"] = &req.Velero.Storage.SecretKey
+	}
+	if req.Database != nil && req.Database.Backup != nil && req.Database.Backup.Storage != nil {
+		fields["database.backup.storage.accessKey"] = &req.Database.Backup.Storage.AccessKey
+		fields["database.backup.storage.secretKey"] = &req.Database.Backup.Storage.SecretKey
+	}
+	if req.CertManager != nil && req.CertManager.ACME != nil && req.CertManager.ACME.DNS != nil {
+		fields["certManager.acme.dns.apiToken"] = &req.CertManager.ACME.DNS.APIToken
+		fields["certManager.acme.dns.tsigSecret"] = &req.CertManager.ACME.DNS.TSIGSecret
+	}
+	return fields
+}
+
+// extractRequestSecrets 取出请求中非空的敏感字段并清空，返回字段路径 -> 取值
+func extractRequestSecrets(req *model.DeployRequest) map[string]string {
+	secrets := make(map[string]string)
+	for path, field := range requestSecretFields(req) {
+		if *field != "" {
+			secrets[path] = *field
+			*field = ""
+		}
+	}
+	return secrets
+}
+
+// restoreRequestSecrets 将 extractRequestSecrets 取出的字段写回请求
+func restoreRequestSecrets(req *model.DeployRequest, secrets map[string]string) {
+	for path, field := range requestSecretFields(req) {
+		if value, ok := secrets[path]; ok {
+			*field = value
+		}
+	}
+}
@@ -0,0 +1,904 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"maps"
+	"os"
+	"runtime/debug"
+	"slices"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/benchmark"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/notify"
+	"k3s-deploy-backend/internal/pkg/progress"
+	"k3s-deploy-backend/internal/pkg/store"
+	"k3s-deploy-backend/internal/pkg/transcript"
+	"k3s-deploy-backend/pkg/utils"
+)
+
+// pipelineStep 表示按顺序执行全部部署步骤
+const pipelineStep = "all"
+
+// pipelineSteps 完整部署流水线的步骤顺序
+var pipelineSteps = []string{"validate", "prepare-nodes", "prepare-disks", "tune-nodes", "harden-nodes", "check-mirrors", "install-master", "configure-agent", "wait-nodes", "configure-dns", "configure-storage", "install-cert-manager", "apply-labels", "install-minio", "install-velero", "prepull-images", "deploy-insuite", "verify"}
+
+const (
+	// taskLeaseTTL 任务租约有效期，执行副本失联超过该时间后任务被判定中断
+	taskLeaseTTL = 30 * time.Second
+	// taskPollInterval 各副本扫描共享队列的间隔
+	taskPollInterval = 2 * time.Second
+	// taskStallTimeout 超过该时间没有完成一次队列扫描时，就绪检查认为任务调度卡住
+	taskStallTimeout = 15 * taskPollInterval
+)
+
+// TaskService 异步任务队列：限制全局并发数，并保证同一集群同一时刻只有一个任务在修改、
+// 同一节点同一时刻只被一个任务使用。
+// 任务状态保存在共享存储中，多个后端副本通过租约协调，任意副本均可查询任务进度。
+type TaskService struct {
+	deployService *DeployService
+	// secrets 保存任务请求中的敏感字段，共享存储中的请求不含密码、密钥和 token
+	secrets       RequestSecrets
+	store         store.Store
+	logger        *logger.Logger
+	maxConcurrent int
+	replicaID     string
+	notifier      notify.Notifier
+	// transcripts 节点命令录制，未启用时为 nil
+	transcripts *transcript.Recorder
+	// progressLog 进度事件文件，未启用时为 nil
+	progressLog *progress.FileWriter
+
+	// dispatchMu 保证本副本内调度串行执行
+	dispatchMu sync.Mutex
+	// mu 保护本副本正在执行的任务记录的读写
+	mu sync.Mutex
+	// running 按请求 ID 记录本副本正在执行的任务
+	runningMu sync.Mutex
+	running   map[string]*runningTask
+
+	// healthMu 保护调度循环的状态，供就绪检查使用
+	healthMu sync.Mutex
+	// started 调度循环已启动
+	started bool
+	// lastDispatch 最近一次成功完成队列扫描的时间（启动时为启动时间）
+	lastDispatch time.Time
+	// dispatchErr 最近一次队列扫描的错误，成功后清除
+	dispatchErr error
+}
+
+func NewTaskService(deployService *DeployService, secrets RequestSecrets, st store.Store, maxConcurrent int, notifier notify.Notifier, transcripts *transcript.Recorder, logger *logger.Logger) *TaskService {
+	if maxConcurrent <= 0 {
+		maxConcurrent = 1
+	}
+
+	hostname, _ := os.Hostname()
+	replicaID, err := utils.GenerateID(hostname)
+	if err != nil {
+		replicaID = fmt.Sprintf("%s-%d", hostname, time.Now().UnixNano())
+	}
+
+	s := &TaskService{
+		deployService: deployService,
+		secrets:       secrets,
+		store:         st,
+		logger:        logger,
+		maxConcurrent: maxConcurrent,
+		replicaID:     replicaID,
+		notifier:      notifier,
+		transcripts:   transcripts,
+		running:       make(map[string]*runningTask),
+	}
+	progress.Default.Handle(s.recordEvent)
+	return s
+}
+
+// Start 启动队列轮询，用于领取其他副本提交的任务并回收失联副本的任务
+func (s *TaskService) Start() {
+	s.healthMu.Lock()
+	s.started = true
+	s.lastDispatch = time.Now()
+	s.healthMu.Unlock()
+
+	go func() {
+		ticker := time.NewTicker(taskPollInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			s.dispatch()
+		}
+	}()
+	s.logger.Infof("任务队列已启动，副本 ID: %s", s.replicaID)
+}
+
+// Ready 就绪检查：调度循环已启动，最近一次队列扫描成功，且在 taskStallTimeout 内完成过扫描
+func (s *TaskService) Ready() error {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+	if !s.started {
+		return errors.New("任务队列未启动")
+	}
+	if s.dispatchErr != nil {
+		return fmt.Errorf("扫描任务队列失败: %w", s.dispatchErr)
+	}
+	if since := time.Since(s.lastDispatch); since > taskStallTimeout {
+		return fmt.Errorf("任务调度已 %s 未完成队列扫描", since.Round(time.Second))
+	}
+	return nil
+}
+
+// dispatched 记录一次队列扫描的结果
+func (s *TaskService) dispatched(err error) {
+	s.healthMu.Lock()
+	defer s.healthMu.Unlock()
+	s.dispatchErr = err
+	if err == nil {
+		s.lastDispatch = time.Now()
+	}
+}
+
+// Submit 提交部署任务并进入队列。节点正被其他集群的任务使用时返回 *NodeConflictError
+func (s *TaskService) Submit(req *model.DeployRequest) (*model.Task, error) {
+	if err := s.prepare(req); err != nil {
+		return nil, err
+	}
+	if err := s.checkNodeConflicts(req); err != nil {
+		return nil, err
+	}
+	return s.enqueue(req)
+}
+
+// prepare 检查步骤，校验加入信息包，按角色模板补全 roleAssignment 并解析生效设置
+func (s *TaskService) prepare(req *model.DeployRequest) error {
+	if _, exists := stepHandlers[req.Step]; !exists && req.Step != pipelineStep {
+		return fmt.Errorf("未知的部署步骤: %s", req.Step)
+	}
+	if err := s.deployService.ResolveJoinBundle(req); err != nil {
+		return err
+	}
+	if err := s.deployService.ResolveRoles(req); err != nil {
+		return err
+	}
+	// 提交时解析生效设置并随请求保存，之后修改集群默认值或全局配置不影响已提交和重新执行的任务
+	return s.deployService.ResolveSettings(req)
+}
+
+// Rerun 以原任务保存的请求（包括提交时解析的生效设置）提交新任务，requestID 为本次 API 请求的 ID
+func (s *TaskService) Rerun(id, requestID string) (*model.Task, error) {
+	task, err := s.loadTask(id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, fmt.Errorf("任务 %s 不存在", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	if task.FinishedAt == nil {
+		return nil, fmt.Errorf("任务 %s 尚未结束", id)
+	}
+	req, err := s.loadRequest(id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, fmt.Errorf("任务 %s 的请求已被清理，无法重跑", id)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("读取任务 %s 的请求失败: %w", id, err)
+	}
+	req.RequestID = requestID
+	// 升级前提交的任务没有记录生效设置，按当前设置解析
+	if req.PreflightThresholds == nil {
+		if err := s.deployService.ResolveSettings(req); err != nil {
+			return nil, err
+		}
+	}
+	if err := s.checkNodeConflicts(req); err != nil {
+		return nil, err
+	}
+	return s.enqueue(req)
+}
+
+func (s *TaskService) enqueue(req *model.DeployRequest) (*model.Task, error) {
+	id, err := utils.GenerateID("task")
+	if err != nil {
+		return nil, err
+	}
+	return s.enqueueAs(id, req)
+}
+
+// enqueueAs 以指定的任务 ID 保存请求和任务并调度
+func (s *TaskService) enqueueAs(id string, req *model.DeployRequest) (*model.Task, error) {
+	key := clusterKey(req.Nodes)
+	task := &model.Task{
+		ID:         id,
+		ClusterKey: key,
+		Nodes:      taskNodes(req.Nodes),
+		Step:       req.Step,
+		RequestID:  req.RequestID,
+		Status:     model.TaskQueued,
+		Settings:   &req.DeploySettings,
+		CreatedAt:  time.Now(),
+	}
+
+	if err := s.saveRequest(id, req); err != nil {
+		return nil, err
+	}
+	if err := s.saveTask(task); err != nil {
+		s.deleteRequest(id)
+		return nil, err
+	}
+	s.publish(task, model.ProgressEvent{Type: model.ProgressTaskQueued, Message: fmt.Sprintf("任务已创建，集群 %s，步骤 %s，请求 %s", key, req.Step, req.RequestID)})
+
+	s.logger.WithField("requestId", req.RequestID).Infof("任务 %s 已入队（集群 %s，步骤 %s）", id, task.ClusterKey, task.Step)
+	s.dispatch()
+	return s.Get(id, LogFilter{})
+}
+
+// Get 返回任务快照（包含排队位置和按 filter 过滤的执行日志）
+func (s *TaskService) Get(id string, filter LogFilter) (*model.Task, error) {
+	tasks, err := s.loadTasks()
+	if err != nil {
+		return nil, err
+	}
+	for _, task := range tasks {
+		if task.ID == id {
+			logs, err := s.store.TaskLogs(id)
+			if err != nil {
+				return nil, fmt.Errorf("读取任务日志失败: %w", err)
+			}
+			task.Timeline = taskTimeline(logs, filter)
+			task.Logs = make([]string, 0, len(task.Timeline))
+			for _, entry := range task.Timeline {
+				task.Logs = append(task.Logs, entry.Message)
+			}
+			return task, nil
+		}
+	}
+	return nil, fmt.Errorf("任务 %s 不存在", id)
+}
+
+// Transcript 返回任务执行期间录制的节点命令。录制文件保存在执行任务的副本上，按任务 ID 命名
+func (s *TaskService) Transcript(id string) ([]transcript.Entry, error) {
+	if s.transcripts == nil {
+		return nil, fmt.Errorf("未启用节点命令录制")
+	}
+	task, err := s.loadTask(id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, fmt.Errorf("任务 %s 不存在", id)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return s.transcripts.Read(task.ID)
+}
+
+// List 按创建时间倒序返回所有任务（不含日志）
+func (s *TaskService) List() ([]*model.Task, error) {
+	tasks, err := s.loadTasks()
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].CreatedAt.After(tasks[j].CreatedAt) })
+	return tasks, nil
+}
+
+// SetProgressLog 设置进度事件文件，清理任务时一并删除
+func (s *TaskService) SetProgressLog(w *progress.FileWriter) {
+	s.progressLog = w
+}
+
+// Prune 删除结束时间早于 olderThan 之前的任务及其请求和日志，返回删除的任务数
+func (s *TaskService) Prune(olderThan time.Duration) (int, error) {
+	tasks, err := s.loadTasks()
+	if err != nil {
+		return 0, err
+	}
+
+	cutoff := time.Now().Add(-olderThan)
+	pruned := 0
+	for _, task := range tasks {
+		if task.FinishedAt == nil || task.FinishedAt.After(cutoff) {
+			continue
+		}
+		if err := s.deleteRequest(task.ID); err != nil {
+			return pruned, err
+		}
+		if err := s.store.DeleteTaskLogs(task.ID); err != nil {
+			return pruned, err
+		}
+		if s.transcripts != nil {
+			if err := s.transcripts.Remove(task.ID); err != nil {
+				return pruned, err
+			}
+		}
+		if s.progressLog != nil {
+			if err := s.progressLog.Remove(task.ID); err != nil {
+				return pruned, err
+			}
+		}
+		if err := s.store.Delete(store.CollectionTasks, task.ID); err != nil && !errors.Is(err, store.ErrNotFound) {
+			return pruned, err
+		}
+		pruned++
+	}
+	return pruned, nil
+}
+
+// loadTasks 读取全部任务，按创建时间升序排列并计算排队位置
+func (s *TaskService) loadTasks() ([]*model.Task, error) {
+	records, err := s.store.List(store.CollectionTasks)
+	if err != nil {
+		return nil, fmt.Errorf("读取任务列表失败: %w", err)
+	}
+
+	tasks := make([]*model.Task, 0, len(records))
+	for id, data := range records {
+		var task model.Task
+		if err := json.Unmarshal(data, &task); err != nil {
+			s.logger.Warnf("解析任务 %s 失败: %v", id, err)
+			continue
+		}
+		tasks = append(tasks, &task)
+	}
+	sort.Slice(tasks, func(i, j int) bool { return tasks[i].CreatedAt.Before(tasks[j].CreatedAt) })
+
+	position := 0
+	for _, task := range tasks {
+		task.QueuePosition = 0
+		if task.Status == model.TaskQueued {
+			position++
+			task.QueuePosition = position
+		}
+	}
+	return tasks, nil
+}
+
+func (s *TaskService) loadTask(id string) (*model.Task, error) {
+	data, err := s.store.Get(store.CollectionTasks, id)
+	if err != nil {
+		return nil, err
+	}
+	var task model.Task
+	if err := json.Unmarshal(data, &task); err != nil {
+		return nil, err
+	}
+	return &task, nil
+}
+
+// saveTask 保存任务状态，日志单独追加存储
+func (s *TaskService) saveTask(task *model.Task) error {
+	copied := *task
+	copied.Logs = nil
+	copied.QueuePosition = 0
+	data, err := json.Marshal(&copied)
+	if err != nil {
+		return err
+	}
+	if err := s.store.Put(store.CollectionTasks, task.ID, data); err != nil {
+		return fmt.Errorf("保存任务 %s 失败: %w", task.ID, err)
+	}
+	return nil
+}
+
+// storedRequest 共享存储中的任务请求：敏感字段已移入凭据库记录 SecretsID，执行和重跑时再读取补全
+type storedRequest struct {
+	Request   *model.DeployRequest `json:"request"`
+	SecretsID string               `json:"secretsId,omitempty"`
+}
+
+// saveRequest 将请求的敏感字段存入凭据库后保存不含敏感字段的副本
+func (s *TaskService) saveRequest(id string, req *model.DeployRequest) error {
+	data, err := json.Marshal(req)
+	if err != nil {
+		return err
+	}
+	stored := storedRequest{}
+	if err := json.Unmarshal(data, &stored.Request); err != nil {
+		return err
+	}
+	if secrets := extractRequestSecrets(stored.Request); len(secrets) > 0 {
+		if stored.SecretsID, err = s.secrets.SaveRequestSecrets(id, secrets); err != nil {
+			return err
+		}
+	}
+	if data, err = json.Marshal(&stored); err == nil {
+		err = s.store.Put(store.CollectionTaskRequests, id, data)
+	}
+	if err != nil {
+		if stored.SecretsID != "" {
+			s.secrets.Delete(stored.SecretsID)
+		}
+		return fmt.Errorf("保存任务请求失败: %w", err)
+	}
+	return nil
+}
+
+// readRequest 读取保存的请求，升级前保存的请求没有外层结构，整体即为请求
+func (s *TaskService) readRequest(id string) (*storedRequest, error) {
+	data, err := s.store.Get(store.CollectionTaskRequests, id)
+	if err != nil {
+		return nil, err
+	}
+	var stored storedRequest
+	if err := json.Unmarshal(data, &stored); err != nil {
+		return nil, err
+	}
+	if stored.Request == nil {
+		if err := json.Unmarshal(data, &stored.Request); err != nil {
+			return nil, err
+		}
+	}
+	return &stored, nil
+}
+
+// loadRequest 读取任务请求并从凭据库补全敏感字段
+func (s *TaskService) loadRequest(id string) (*model.DeployRequest, error) {
+	stored, err := s.readRequest(id)
+	if err != nil {
+		return nil, err
+	}
+	if stored.SecretsID != "" {
+		secrets, err := s.secrets.RequestSecrets(stored.SecretsID)
+		if err != nil {
+			return nil, fmt.Errorf("读取任务请求的敏感字段失败: %w", err)
+		}
+		restoreRequestSecrets(stored.Request, secrets)
+	}
+	return stored.Request, nil
+}
+
+// deleteRequest 删除任务请求及其在凭据库中的敏感字段
+func (s *TaskService) deleteRequest(id string) error {
+	stored, err := s.readRequest(id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil
+	}
+	if err == nil && stored.SecretsID != "" {
+		if err := s.secrets.Delete(stored.SecretsID); err != nil {
+			s.logger.Warnf("删除任务 %s 请求的敏感字段失败: %v", id, err)
+		}
+	}
+	if err := s.store.Delete(store.CollectionTaskRequests, id); err != nil && !errors.Is(err, store.ErrNotFound) {
+		return err
+	}
+	return nil
+}
+
+// SealLegacyRequests 将升级前保存的、包含敏感字段的任务请求改为凭据库引用，返回处理的请求数
+func (s *TaskService) SealLegacyRequests() (int, error) {
+	records, err := s.store.List(store.CollectionTaskRequests)
+	if err != nil {
+		return 0, err
+	}
+	sealed := 0
+	for id, data := range records {
+		var stored storedRequest
+		if err := json.Unmarshal(data, &stored); err != nil || stored.Request != nil {
+			continue
+		}
+		var req model.DeployRequest
+		if err := json.Unmarshal(data, &req); err != nil {
+			continue
+		}
+		if err := s.saveRequest(id, &req); err != nil {
+			return sealed, err
+		}
+		sealed++
+	}
+	return sealed, nil
+}
+
+// leaseOwner 租约持有者标识，精确到副本和任务
+func (s *TaskService) leaseOwner(taskID string) string {
+	return s.replicaID + "/" + taskID
+}
+
+func clusterLease(key string) string {
+	return "cluster/" + key
+}
+
+func slotLease(index int) string {
+	return fmt.Sprintf("task-slot/%d", index)
+}
+
+// dispatch 按入队顺序启动可运行的任务：全局并发槽位有空闲且所属集群未被占用
+func (s *TaskService) dispatch() {
+	s.dispatchMu.Lock()
+	defer s.dispatchMu.Unlock()
+
+	tasks, err := s.loadTasks()
+	s.dispatched(err)
+	if err != nil {
+		s.logger.Errorf("调度任务失败: %v", err)
+		return
+	}
+
+	for _, task := range tasks {
+		switch task.Status {
+		case model.TaskRunning:
+			s.recoverOrphan(task)
+		case model.TaskQueued:
+			s.tryStart(task, tasks)
+		}
+	}
+}
+
+// tryStart 依次获取并发槽位、集群租约和节点锁，成功后在本副本执行任务
+func (s *TaskService) tryStart(task *model.Task, tasks []*model.Task) {
+	// 被接管的任务仍在执行当前步骤时等待其结束
+	if nodesBusy(task, tasks) {
+		return
+	}
+	// 槽位、集群或节点被占用时继续排队
+	slot := s.acquireLeases(task)
+	if slot < 0 {
+		return
+	}
+
+	// 获得租约后重新读取，避免其他副本已执行完该任务
+	current, err := s.loadTask(task.ID)
+	if err != nil || current.Status != model.TaskQueued {
+		s.releaseLeases(task, slot)
+		return
+	}
+
+	req, err := s.loadRequest(task.ID)
+	if err != nil {
+		s.releaseLeases(task, slot)
+		s.logger.Errorf("读取任务 %s 请求失败: %v", task.ID, err)
+		return
+	}
+
+	now := time.Now()
+	current.Status = model.TaskRunning
+	current.Owner = s.replicaID
+	current.StartedAt = &now
+	if err := s.saveTask(current); err != nil {
+		s.releaseLeases(task, slot)
+		s.logger.Error(err)
+		return
+	}
+	s.publish(current, model.ProgressEvent{Type: model.ProgressTaskStarted, Message: fmt.Sprintf("任务开始执行（副本 %s）", s.replicaID)})
+
+	go s.run(current, req, slot)
+}
+
+// acquireLeases 依次获取并发槽位、集群租约和节点锁，返回槽位序号；任一租约被占用时释放已获取的租约并返回 -1
+func (s *TaskService) acquireLeases(task *model.Task) int {
+	owner := s.leaseOwner(task.ID)
+
+	slot := -1
+	for i := 0; i < s.maxConcurrent; i++ {
+		acquired, err := s.store.AcquireLease(slotLease(i), owner, taskLeaseTTL)
+		if err != nil {
+			s.logger.Errorf("获取任务槽位失败: %v", err)
+			return -1
+		}
+		if acquired {
+			slot = i
+			break
+		}
+	}
+	if slot < 0 {
+		return -1
+	}
+
+	acquired, err := s.store.AcquireLease(clusterLease(task.ClusterKey), owner, taskLeaseTTL)
+	if err != nil || !acquired {
+		s.store.ReleaseLease(slotLease(slot), owner)
+		return -1
+	}
+
+	if !s.acquireNodes(task) {
+		s.store.ReleaseLease(clusterLease(task.ClusterKey), owner)
+		s.store.ReleaseLease(slotLease(slot), owner)
+		return -1
+	}
+	return slot
+}
+
+// ErrDeployBusy 同步执行步骤时并发槽位已满，或集群、节点正被任务占用
+var ErrDeployBusy = errors.New("并发槽位已满，或集群、节点正被其他任务占用，请稍后重试或改为提交异步任务")
+
+// ExecuteNow 同步执行单个步骤（POST /api/k3s/deploy）。与任务一样先取得并发槽位、集群租约和节点锁，
+// 执行期间续期，结束后释放并调度排队中的任务；租约被占用时不排队，直接返回 ErrDeployBusy
+func (s *TaskService) ExecuteNow(req *model.DeployRequest) (*model.DeployResponse, error) {
+	id, err := utils.GenerateID("sync")
+	if err != nil {
+		return nil, err
+	}
+	task := &model.Task{ID: id, ClusterKey: clusterKey(req.Nodes), Nodes: taskNodes(req.Nodes)}
+	slot := s.acquireLeases(task)
+	if slot < 0 {
+		return nil, ErrDeployBusy
+	}
+	var nodesLost atomic.Bool
+	stopRenew := s.renewLeases(task, slot, &nodesLost)
+	defer func() {
+		close(stopRenew)
+		s.releaseLeases(task, slot)
+		s.dispatch()
+	}()
+
+	return s.deployService.ExecuteStep(req), nil
+}
+
+func (s *TaskService) releaseLeases(task *model.Task, slot int) {
+	owner := s.leaseOwner(task.ID)
+	s.store.ReleaseLease(clusterLease(task.ClusterKey), owner)
+	s.store.ReleaseLease(slotLease(slot), owner)
+	s.releaseNodes(task)
+}
+
+// recoverOrphan 集群租约已过期的运行中任务说明执行副本已失联，将其标记为失败
+func (s *TaskService) recoverOrphan(task *model.Task) {
+	if task.Owner == s.replicaID {
+		return
+	}
+
+	probe := s.leaseOwner(task.ID)
+	acquired, err := s.store.AcquireLease(clusterLease(task.ClusterKey), probe, taskLeaseTTL)
+	if err != nil || !acquired {
+		return
+	}
+	defer s.store.ReleaseLease(clusterLease(task.ClusterKey), probe)
+
+	current, err := s.loadTask(task.ID)
+	if err != nil || current.Status != model.TaskRunning {
+		return
+	}
+	now := time.Now()
+	current.Status = model.TaskFailed
+	current.FinishedAt = &now
+	current.Message = fmt.Sprintf("执行副本 %s 失联，任务中断", current.Owner)
+	if err := s.saveTask(current); err != nil {
+		s.logger.Error(err)
+		return
+	}
+	s.publish(current, model.ProgressEvent{Type: model.ProgressTaskFinished, Level: model.LogError, Message: current.Message})
+	s.logger.Warnf("任务 %s 的执行副本 %s 失联，已标记为失败", current.ID, current.Owner)
+	s.notifyFinished(current)
+}
+
+func (s *TaskService) run(task *model.Task, req *model.DeployRequest, slot int) {
+	var nodesLost atomic.Bool
+	stopRenew := s.renewLeases(task, slot, &nodesLost)
+	released := false
+	// 步骤 panic 时任务以失败结束并释放租约，避免任务停留在执行中、槽位和节点锁一直被续期
+	defer func() {
+		r := recover()
+		if r == nil {
+			return
+		}
+		s.logger.Errorf("任务 %s 执行异常: %v\n%s", task.ID, r, debug.Stack())
+		// 已写入终态并释放租约后的异常不再改变任务状态
+		if released {
+			return
+		}
+		s.update(task, func() {
+			now := time.Now()
+			task.FinishedAt = &now
+			task.Status = model.TaskFailed
+			task.Message = fmt.Sprintf("任务执行异常: %v", r)
+		})
+		s.publish(task, model.ProgressEvent{Type: model.ProgressTaskFinished, Level: model.LogError, Message: task.Message})
+		close(stopRenew)
+		s.releaseLeases(task, slot)
+		s.notifyFinished(task)
+		s.dispatch()
+	}()
+	untrack := s.trackRunning(task)
+	defer untrack()
+
+	steps := []string{req.Step}
+	if req.Step == pipelineStep {
+		steps = pipelineSteps
+	}
+
+	if req.Benchmark != nil {
+		benchmark.Begin(task.ID)
+		defer benchmark.End(task.ID)
+	}
+	s.deployService.RetainBundles(task.ID)
+	defer s.deployService.ReleaseBundles(task.ID)
+
+	var failure string
+	var failureInfo *model.FailureInfo
+	var timings []model.StepTiming
+	for _, step := range steps {
+		// 节点已被强制接管，不再执行后续步骤
+		if s.renewNodes(task, &nodesLost); nodesLost.Load() {
+			failure = fmt.Sprintf("节点已被其他任务强制接管，任务在步骤 %s 前停止", step)
+			break
+		}
+		s.update(task, func() {
+			task.CurrentStep = step
+		})
+		s.publish(task, model.ProgressEvent{Type: model.ProgressStepStarted, Step: step, Message: fmt.Sprintf("开始执行步骤 %s", step)})
+
+		stepReq := *req
+		excludeFailed(&stepReq, task.FailedNodes)
+		stepReq.Step = step
+		// 以任务 ID 关联安装器事件、命令录制和耗时记录，提交时的请求 ID 可能被其他任务重复使用
+		stepReq.RequestID = task.ID
+		stepReq.WorkspaceID = task.ID
+		// 设置已随任务记录，之后修改的集群默认值不影响排队中的任务和重跑
+		stepReq.SettingsResolved = task.Settings != nil
+		stepStart := time.Now()
+		result := s.deployService.ExecuteStep(&stepReq)
+		stepTiming := model.StepTiming{Step: step, DurationMs: time.Since(stepStart).Milliseconds(), Success: result.Success}
+		timings = append(timings, stepTiming)
+		s.publish(task, model.ProgressEvent{Type: model.ProgressStepFinished, Step: step, Level: resultLevel(result), Message: result.Message, DurationMs: stepTiming.DurationMs})
+		// 未就绪的节点已列在步骤的错误信息中，多出的节点只告警
+		for _, r := range result.Readiness {
+			if r.Status == model.NodeUnexpected {
+				s.publish(task, stepDetail(step, r.RegisteredAs, model.LogWarn, fmt.Sprintf("集群中存在请求之外的节点 %s (%s)", r.RegisteredAs, r.IP)))
+			}
+		}
+		if result.Partial {
+			failed := failedNodes(result.Nodes)
+			for _, r := range failed {
+				s.publish(task, stepDetail(step, r.Name, model.LogError, fmt.Sprintf("%s，后续步骤不再包含节点 %s", r.Message, r.Name)))
+			}
+			s.update(task, func() {
+				task.FailedNodes = append(task.FailedNodes, failed...)
+			})
+		}
+		for _, d := range result.Digests {
+			if d.Verified {
+				s.publish(task, stepDetail(step, d.Node, model.LogInfo, fmt.Sprintf("已校验 %s %s: sha256 %s（%s）", d.Node, d.Name, d.SHA256, d.Source)))
+			} else {
+				s.publish(task, stepDetail(step, d.Node, model.LogWarn, fmt.Sprintf("未校验 %s %s: sha256 %s", d.Node, d.Name, d.SHA256)))
+			}
+		}
+		if len(result.Artifacts) > 0 {
+			s.update(task, func() {
+				task.Artifacts = append(task.Artifacts, result.Artifacts...)
+			})
+		}
+		if result.URL != "" {
+			s.update(task, func() {
+				task.URL = result.URL
+			})
+			s.publish(task, stepDetail(step, "", model.LogInfo, fmt.Sprintf("inSuite 访问地址: %s", result.URL)))
+		}
+		if !result.Success {
+			failure = result.Message
+			failureInfo = result.Failure
+			if failureInfo != nil {
+				s.publish(task, stepDetail(step, "", model.LogError, fmt.Sprintf("失败分类: %s，建议: %s", failureInfo.Category, failureInfo.Hint)))
+			}
+			break
+		}
+	}
+
+	s.update(task, func() {
+		now := time.Now()
+		task.FinishedAt = &now
+		if failure != "" {
+			task.Status = model.TaskFailed
+			task.Message = failure
+			task.Failure = failureInfo
+		} else if len(task.FailedNodes) > 0 {
+			task.Status = model.TaskSucceeded
+			task.Message = fmt.Sprintf("任务部分成功，%d 个节点失败", len(task.FailedNodes))
+		} else {
+			task.Status = model.TaskSucceeded
+			task.Message = "任务执行成功"
+		}
+	})
+	s.publish(task, model.ProgressEvent{Type: model.ProgressTaskFinished, Level: taskLevel(task), Message: task.Message})
+	if req.Benchmark != nil {
+		s.saveBenchmark(task, req, timings)
+	}
+
+	// 先写入终态再释放租约，避免其他副本重复领取
+	// 请求保留到按 retention.tasks 清理，用于重跑任务
+	close(stopRenew)
+	s.releaseLeases(task, slot)
+	released = true
+
+	s.logger.Infof("任务 %s 结束，状态 %s", task.ID, task.Status)
+	s.notifyFinished(task)
+	s.dispatch()
+}
+
+// excludeFailed 从步骤请求中去掉部分成功的步骤中失败的节点及其标签，
+// 其余节点保留按完整节点列表生成的集群名称
+func excludeFailed(req *model.DeployRequest, failed []model.NodeResult) {
+	if len(failed) == 0 {
+		return
+	}
+	names := clusterNodeNames(req.Nodes)
+	labels := maps.Clone(req.Labels)
+	kept := make([]model.NodeConfig, 0, len(req.Nodes))
+	for _, node := range req.Nodes {
+		if slices.ContainsFunc(failed, func(r model.NodeResult) bool { return r.IP == node.IP }) {
+			delete(labels, names[node.Name])
+			continue
+		}
+		node.ClusterName = names[node.Name]
+		kept = append(kept, node)
+	}
+	req.Nodes = kept
+	req.Labels = labels
+}
+
+// notifyFinished 发送任务完成或失败通知
+func (s *TaskService) notifyFinished(finished *model.Task) {
+	// 通知异步发送，使用任务的副本，避免与之后的修改并发读写
+	snapshot := *finished
+	task := &snapshot
+	event := notify.Event{
+		Type:    notify.EventTaskSucceeded,
+		Title:   fmt.Sprintf("部署任务 %s 执行成功", task.ID),
+		Message: task.Message,
+		Time:    time.Now(),
+		Data:    task,
+	}
+	if task.Status == model.TaskFailed {
+		event.Type = notify.EventTaskFailed
+		event.Title = fmt.Sprintf("部署任务 %s 失败（步骤 %s）", task.ID, task.CurrentStep)
+		if task.Failure != nil {
+			event.Message = fmt.Sprintf("%s\n\n处理建议：%s", task.Message, task.Failure.Hint)
+		}
+	}
+	// 邮件、Webhook 可能很慢，异步发送，不阻塞任务收尾和队列调度
+	go func() {
+		if err := s.notifier.Notify(event); err != nil {
+			s.logger.Warnf("发送任务通知失败: %v", err)
+		}
+	}()
+}
+
+// renewLeases 任务执行期间定期续期租约，节点锁被接管时设置 nodesLost
+func (s *TaskService) renewLeases(task *model.Task, slot int, nodesLost *atomic.Bool) chan struct{} {
+	stop := make(chan struct{})
+	owner := s.leaseOwner(task.ID)
+	names := []string{clusterLease(task.ClusterKey), slotLease(slot)}
+
+	go func() {
+		ticker := time.NewTicker(taskLeaseTTL / 3)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-stop:
+				return
+			case <-ticker.C:
+				for _, name := range names {
+					if acquired, err := s.store.AcquireLease(name, owner, taskLeaseTTL); err != nil || !acquired {
+						s.logger.Warnf("任务 %s 续期租约 %s 失败: %v", task.ID, name, err)
+					}
+				}
+				s.renewNodes(task, nodesLost)
+			}
+		}
+	}()
+	return stop
+}
+
+// update 修改本副本执行中的任务并写回共享存储
+func (s *TaskService) update(task *model.Task, fn func()) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	fn()
+	if err := s.saveTask(task); err != nil {
+		s.logger.Error(err)
+	}
+}
+
+// clusterKey 以 Master 节点地址标识集群，找不到 Master 时使用第一个节点
+func clusterKey(nodes []model.NodeConfig) string {
+	for _, node := range nodes {
+		if node.Name == "k3s-master" {
+			return node.IP
+		}
+	}
+	if len(nodes) > 0 {
+		return nodes[0].IP
+	}
+	return "unknown"
+}
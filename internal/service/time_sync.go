@@ -0,0 +1,157 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/hostos"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// defaultMaxClockOffset 同步后 Agent 与 Master 允许的默认最大时钟偏差
+const defaultMaxClockOffset = 100 * time.Millisecond
+
+// ntpServerPattern 上游 NTP 服务器只允许主机名或 IP，避免在配置文件中注入其他指令
+var ntpServerPattern = regexp.MustCompile(`^[A-Za-z0-9.:\-]+$`)
+
+// systemTimePattern 匹配 chronyc tracking 的 System time 行，如 "System time : 0.000012345 seconds fast of NTP time"
+var systemTimePattern = regexp.MustCompile(`(?m)^System time\s*:\s*([0-9.]+) seconds`)
+
+// chronyCommon 服务端与客户端共用的 chrony 配置：偏差超过 1 秒时前三次更新直接跳变，并同步写入硬件时钟
+const chronyCommon = `driftfile /var/lib/chrony/drift
+makestep 1.0 3
+rtcsync
+`
+
+// syncTime 将 Master 配置为 chrony 服务端，其余节点与 Master 同步，并校验同步后的时钟偏差。
+// 离线环境没有外部 NTP，各节点时钟漂移会导致证书校验和 etcd 选举异常。
+// Master 配置失败时返回错误，否则返回各 Agent 的结果
+func (s *K3sService) syncTime(nodes []model.NodeConfig, opts *model.TimeSyncOptions) ([]model.NodeResult, error) {
+	for _, server := range opts.Upstreams {
+		if !ntpServerPattern.MatchString(server) {
+			return nil, fmt.Errorf("无效的 NTP 服务器: %s", server)
+		}
+	}
+	maxOffset := defaultMaxClockOffset
+	if opts.MaxOffsetMs > 0 {
+		maxOffset = time.Duration(opts.MaxOffsetMs) * time.Millisecond
+	}
+
+	var master model.NodeConfig
+	var agents []model.NodeConfig
+	for _, node := range nodes {
+		if node.Name == "k3s-master" {
+			master = node
+		} else {
+			agents = append(agents, node)
+		}
+	}
+	if master.Name == "" {
+		return nil, fmt.Errorf("未找到Master节点，无法配置时间同步")
+	}
+
+	var conf strings.Builder
+	for _, server := range opts.Upstreams {
+		fmt.Fprintf(&conf, "server %s iburst\n", server)
+	}
+	// 上游不可用或未配置时以本地时钟为准继续为 Agent 提供时间
+	conf.WriteString("local stratum 10\n")
+	for _, agent := range agents {
+		fmt.Fprintf(&conf, "allow %s\n", agent.IP)
+	}
+	conf.WriteString(chronyCommon)
+	results := runOnNodes([]model.NodeConfig{master}, func(_ int, client *ssh.Client) error {
+		return s.configureChrony(client, master, conf.String(), func(client *ssh.Client, osInfo *hostos.Info) error {
+			result, err := client.ExecuteIdempotentCommand(osInfo.ServiceActiveCommand(osInfo.ChronyUnit()))
+			if err != nil || strings.TrimSpace(result.Stdout) != "active" {
+				return fmt.Errorf("chrony 服务未运行")
+			}
+			return nil
+		})
+	})
+	if err := nodesError("时间同步失败", results); err != nil {
+		return nil, err
+	}
+	s.logger.Infof("节点 %s 已配置为 chrony 服务端", master.Name)
+
+	agentConf := fmt.Sprintf("server %s iburst\n%s", master.IP, chronyCommon)
+	return runOnNodes(agents, func(i int, client *ssh.Client) error {
+		agent := agents[i]
+		return s.configureChrony(client, agent, agentConf, func(client *ssh.Client, _ *hostos.Info) error {
+			offset, err := waitClockSync(client, master.IP, maxOffset)
+			if err != nil {
+				return err
+			}
+			s.logger.Infof("节点 %s 已与 Master 同步，时钟偏差 %s", agent.Name, offset)
+			return nil
+		})
+	}), nil
+}
+
+// configureChrony 安装 chrony、写入配置（首次修改前备份原配置）、停用冲突的时间同步服务并重启 chronyd，最后执行校验
+func (s *K3sService) configureChrony(client *ssh.Client, node model.NodeConfig, conf string, verify func(*ssh.Client, *hostos.Info) error) error {
+	osInfo, err := hostos.Detect(client)
+	if err != nil {
+		return fmt.Errorf("节点 %s %v", node.Name, err)
+	}
+
+	install, err := osInfo.InstallChronyCommand()
+	if err != nil {
+		return fmt.Errorf("节点 %s %v", node.Name, err)
+	}
+	if _, err := client.ExecuteCommand(install); err != nil {
+		return fmt.Errorf("节点 %s 安装 chrony 失败（离线环境请预先安装 chrony）: %v", node.Name, err)
+	}
+
+	path := osInfo.ChronyConfigPath()
+	write := fmt.Sprintf("mkdir -p $(dirname %[1]s) /var/lib/chrony && { [ -f %[1]s.k3s-deploy.bak ] || [ ! -f %[1]s ] || cp %[1]s %[1]s.k3s-deploy.bak; } && cat > %[1]s", path)
+	if _, err := client.ExecuteCommandWithStdin([]byte(conf), write, nil); err != nil {
+		return fmt.Errorf("节点 %s 写入 chrony 配置失败: %v", node.Name, err)
+	}
+
+	unit := osInfo.ChronyUnit()
+	if _, err := client.ExecuteCommand(osInfo.DisableTimeSyncConflictsCommand()); err != nil {
+		s.logger.Warnf("节点 %s 停用其他时间同步服务失败: %v", node.Name, err)
+	}
+	if _, err := client.ExecuteCommand(osInfo.ServiceEnableStartCommand(unit) + "; " + osInfo.ServiceRestartCommand(unit)); err != nil {
+		return fmt.Errorf("节点 %s 启动 %s 失败: %v", node.Name, unit, err)
+	}
+
+	if err := verify(client, osInfo); err != nil {
+		return fmt.Errorf("节点 %s %v", node.Name, err)
+	}
+	return nil
+}
+
+// waitClockSync 等待 chronyd 与指定服务器完成同步（最长约 60 秒），返回同步后的时钟偏差
+func waitClockSync(client *ssh.Client, server string, maxOffset time.Duration) (time.Duration, error) {
+	cmd := fmt.Sprintf("chronyc waitsync 12 %g 0 5", maxOffset.Seconds())
+	if _, err := client.ExecuteCommand(cmd); err != nil {
+		return 0, fmt.Errorf("等待与 %s 时间同步超时，请检查 Master 的 123/udp 端口是否可达: %v", server, err)
+	}
+
+	result, err := client.ExecuteIdempotentCommand("chronyc -n tracking")
+	if err != nil {
+		return 0, fmt.Errorf("读取 chrony 同步状态失败: %v", err)
+	}
+	if !strings.Contains(result.Stdout, "("+server+")") {
+		return 0, fmt.Errorf("chrony 未以 %s 作为时间源", server)
+	}
+	match := systemTimePattern.FindStringSubmatch(result.Stdout)
+	if match == nil {
+		return 0, fmt.Errorf("无法解析 chrony 时钟偏差")
+	}
+	seconds, err := strconv.ParseFloat(match[1], 64)
+	if err != nil {
+		return 0, fmt.Errorf("无法解析 chrony 时钟偏差: %v", err)
+	}
+	offset := time.Duration(seconds * float64(time.Second))
+	if offset > maxOffset {
+		return offset, fmt.Errorf("与 %s 的时钟偏差 %s 超过上限 %s", server, offset, maxOffset)
+	}
+	return offset, nil
+}
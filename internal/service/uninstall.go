@@ -0,0 +1,77 @@
+package service
+
+import (
+	"fmt"
+	"strings"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/ssh"
+)
+
+// uninstallScript 执行 k3s 安装脚本生成的卸载脚本，Server 与 Agent 各有一个；都不存在时输出 not-installed
+const uninstallScript = `if [ -x /usr/local/bin/k3s-uninstall.sh ]; then /usr/local/bin/k3s-uninstall.sh
+elif [ -x /usr/local/bin/k3s-agent-uninstall.sh ]; then /usr/local/bin/k3s-agent-uninstall.sh
+else echo not-installed; fi`
+
+// UninstallNodes 并行卸载各节点的 k3s，再按变更日志逆序撤销部署对系统配置的修改。
+// rollbackOnly 时只撤销修改，节点已安装 k3s 则拒绝（撤销数据目录等修改会破坏运行中的集群）。
+// 撤销失败的修改保留在变更日志中，修复后可重复执行
+func (s *K3sService) UninstallNodes(nodes []model.NodeConfig, rollbackOnly bool) []model.UninstallResult {
+	results := make([]model.UninstallResult, len(nodes))
+	nodeResults := runOnNodes(nodes, func(i int, client *ssh.Client) error {
+		if err := s.uninstallNode(client, nodes[i], rollbackOnly, &results[i]); err != nil {
+			return err
+		}
+		if len(results[i].Remaining) > 0 {
+			return fmt.Errorf("%d 项修改撤销失败，已保留在变更日志中", len(results[i].Remaining))
+		}
+		return nil
+	})
+	for i, r := range nodeResults {
+		results[i].NodeResult = r
+	}
+	return results
+}
+
+func (s *K3sService) uninstallNode(client *ssh.Client, node model.NodeConfig, rollbackOnly bool, result *model.UninstallResult) error {
+	if rollbackOnly {
+		installed, err := client.ExecuteIdempotentCommand("[ -e /usr/local/bin/k3s ] && echo installed || true")
+		if err != nil {
+			return fmt.Errorf("检查 k3s 安装状态失败: %v", err)
+		}
+		if strings.TrimSpace(installed.Stdout) == "installed" {
+			return fmt.Errorf("节点已安装 k3s，只回滚会破坏运行中的集群，请改为卸载")
+		}
+	} else {
+		s.logger.Warnf("节点 %s 开始卸载 k3s", node.Name)
+		out, err := client.ExecuteCommand(uninstallScript)
+		if err != nil {
+			return fmt.Errorf("执行 k3s 卸载脚本失败: %v", err)
+		}
+		result.Uninstalled = strings.TrimSpace(out.Stdout) != "not-installed"
+		if result.Uninstalled {
+			s.logger.Infof("节点 %s k3s 已卸载", node.Name)
+		}
+	}
+
+	journal, err := s.journal.Load(client)
+	if err != nil {
+		return err
+	}
+	var remaining []model.NodeChange
+	for i := len(journal.Changes) - 1; i >= 0; i-- {
+		change := journal.Changes[i]
+		if _, err := client.ExecuteCommand(change.Undo); err != nil {
+			s.logger.Warnf("节点 %s 撤销「%s」失败: %v", node.Name, change.Description, err)
+			remaining = append([]model.NodeChange{change}, remaining...)
+			continue
+		}
+		s.logger.Infof("节点 %s 已撤销「%s」", node.Name, change.Description)
+		result.Restored = append(result.Restored, change)
+	}
+	result.Remaining = remaining
+	if err := s.journal.replace(client, journal, remaining); err != nil {
+		return fmt.Errorf("更新变更日志失败: %v", err)
+	}
+	return nil
+}
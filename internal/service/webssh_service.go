@@ -0,0 +1,230 @@
+package service
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/logger"
+	"k3s-deploy-backend/internal/pkg/ssh"
+	"k3s-deploy-backend/internal/pkg/store"
+	"k3s-deploy-backend/pkg/utils"
+)
+
+// webSSHTicketTTL 票据有效期，只需覆盖浏览器拿到票据到发起 WebSocket 连接的时间
+const webSSHTicketTTL = 30 * time.Second
+
+var (
+	// ErrInvalidTicket 票据不存在、已使用或已过期
+	ErrInvalidTicket = errors.New("连接票据无效、已使用或已过期")
+	// ErrTicketSubject 兑换票据的用户与申请用户不一致
+	ErrTicketSubject = errors.New("连接票据不属于当前用户")
+	// ErrInvalidTarget 未指定或同时指定了集群和凭据
+	ErrInvalidTarget = errors.New("必须指定 clusterId 或 credentialId 之一")
+)
+
+// WebSSHService 签发和兑换 WebSSH 一次性连接票据。节点凭据只在服务端兑换票据时从凭据库解析，
+// 浏览器只接触票据；票据保存在共享存储中，多副本部署时可由任意副本兑换
+type WebSSHService struct {
+	store             store.Store
+	clusterService    *ClusterService
+	credentialService *CredentialService
+	logger            *logger.Logger
+
+	// sessions 本副本上打开的终端会话，按票据 ID 索引
+	mu       sync.Mutex
+	sessions map[string]*WebSSHSession
+}
+
+// WebSSHSession 一个打开的终端会话，记录浏览器最后一次发来消息（含 pong）的时间，
+// 浏览器异常断开（休眠、断网）而 TCP 连接未关闭时由后台清理关闭
+type WebSSHSession struct {
+	ticket   *model.WebSSHTicket
+	closer   func()
+	lastSeen time.Time
+	once     sync.Once
+	service  *WebSSHService
+}
+
+func NewWebSSHService(st store.Store, clusterService *ClusterService, credentialService *CredentialService, logger *logger.Logger) *WebSSHService {
+	return &WebSSHService{
+		store:             st,
+		clusterService:    clusterService,
+		credentialService: credentialService,
+		logger:            logger,
+		sessions:          make(map[string]*WebSSHSession),
+	}
+}
+
+// IssueTicket 为 subject 签发连接票据，subject 为空表示未启用认证
+func (s *WebSSHService) IssueTicket(req *model.WebSSHTicketRequest, subject, requestID string) (*model.WebSSHTicketResponse, error) {
+	if (req.ClusterID == "") == (req.CredentialID == "") {
+		return nil, ErrInvalidTarget
+	}
+
+	ticket := &model.WebSSHTicket{
+		Subject:      subject,
+		ClusterID:    req.ClusterID,
+		CredentialID: req.CredentialID,
+		RequestID:    requestID,
+		ExpiresAt:    time.Now().Add(webSSHTicketTTL),
+	}
+	node, err := s.resolve(ticket)
+	if err != nil {
+		return nil, err
+	}
+	ticket.Host = node.IP
+
+	id, err := utils.GenerateID("wst")
+	if err != nil {
+		return nil, err
+	}
+	ticket.ID = id
+
+	s.pruneExpired()
+	data, err := json.Marshal(ticket)
+	if err != nil {
+		return nil, err
+	}
+	if err := s.store.Put(store.CollectionWebSSHTickets, ticket.ID, data); err != nil {
+		return nil, fmt.Errorf("保存连接票据失败: %w", err)
+	}
+
+	s.logger.Infof("用户 %s 申请 WebSSH 连接 %s", displaySubject(subject), ticket.Host)
+	return &model.WebSSHTicketResponse{
+		Ticket:    ticket.ID,
+		Host:      ticket.Host,
+		ExpiresAt: ticket.ExpiresAt,
+	}, nil
+}
+
+// Redeem 兑换票据并建立 SSH 连接，票据只能使用一次，subject 为兑换用户，需与申请用户一致
+func (s *WebSSHService) Redeem(id, subject string) (*ssh.Client, *model.WebSSHTicket, error) {
+	data, err := s.store.Get(store.CollectionWebSSHTickets, id)
+	if errors.Is(err, store.ErrNotFound) {
+		return nil, nil, ErrInvalidTicket
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	// 删除成功的一方获得票据，并发兑换同一票据时只有一个能成功
+	if err := s.store.Delete(store.CollectionWebSSHTickets, id); err != nil {
+		if errors.Is(err, store.ErrNotFound) {
+			return nil, nil, ErrInvalidTicket
+		}
+		return nil, nil, err
+	}
+
+	var ticket model.WebSSHTicket
+	if err := json.Unmarshal(data, &ticket); err != nil {
+		return nil, nil, err
+	}
+	if time.Now().After(ticket.ExpiresAt) {
+		return nil, nil, ErrInvalidTicket
+	}
+	// 票据已删除，被他人截获的票据兑换失败后也不能再由申请用户使用
+	if ticket.Subject != subject {
+		s.logger.Warnf("用户 %s 尝试兑换 %s 申请的 WebSSH 票据（%s）", displaySubject(subject), displaySubject(ticket.Subject), ticket.Host)
+		return nil, nil, ErrTicketSubject
+	}
+
+	node, err := s.resolve(&ticket)
+	if err != nil {
+		return nil, nil, err
+	}
+	node.RequestID = ticket.RequestID
+
+	client := newNodeClient(node)
+	if err := client.Connect(); err != nil {
+		return nil, nil, fmt.Errorf("连接节点 %s 失败: %w", node.IP, err)
+	}
+
+	s.logger.Infof("用户 %s 打开 WebSSH 会话 %s", displaySubject(ticket.Subject), node.IP)
+	return client, &ticket, nil
+}
+
+// Track 登记已建立的终端会话，closer 关闭 WebSocket、终端和 SSH 连接
+func (s *WebSSHService) Track(ticket *model.WebSSHTicket, closer func()) *WebSSHSession {
+	session := &WebSSHSession{ticket: ticket, closer: closer, lastSeen: time.Now(), service: s}
+	s.mu.Lock()
+	s.sessions[ticket.ID] = session
+	s.mu.Unlock()
+	return session
+}
+
+// Touch 浏览器发来消息，会话仍在使用
+func (session *WebSSHSession) Touch() {
+	session.service.mu.Lock()
+	session.lastSeen = time.Now()
+	session.service.mu.Unlock()
+}
+
+// Close 关闭会话并取消登记，可重复调用
+func (session *WebSSHSession) Close() {
+	session.once.Do(func() {
+		s := session.service
+		s.mu.Lock()
+		delete(s.sessions, session.ticket.ID)
+		s.mu.Unlock()
+		session.closer()
+		s.logger.Infof("用户 %s 关闭 WebSSH 会话 %s", displaySubject(session.ticket.Subject), session.ticket.Host)
+	})
+}
+
+// CloseOrphaned 关闭超过 timeout 未收到浏览器消息的会话，返回关闭的数量
+func (s *WebSSHService) CloseOrphaned(timeout time.Duration) int {
+	cutoff := time.Now().Add(-timeout)
+	var orphaned []*WebSSHSession
+	s.mu.Lock()
+	for _, session := range s.sessions {
+		if session.lastSeen.Before(cutoff) {
+			orphaned = append(orphaned, session)
+		}
+	}
+	s.mu.Unlock()
+
+	for _, session := range orphaned {
+		s.logger.Warnf("WebSSH 会话 %s（用户 %s）超过 %s 没有响应，关闭连接",
+			session.ticket.Host, displaySubject(session.ticket.Subject), timeout)
+		session.Close()
+	}
+	return len(orphaned)
+}
+
+// resolve 解析票据指向的节点，返回包含认证信息的完整配置
+func (s *WebSSHService) resolve(ticket *model.WebSSHTicket) (model.NodeConfig, error) {
+	if ticket.ClusterID != "" {
+		cluster, err := s.clusterService.Get(ticket.ClusterID)
+		if err != nil {
+			return model.NodeConfig{}, err
+		}
+		return s.clusterService.MasterNode(cluster)
+	}
+	return s.credentialService.NodeFromCredential(ticket.CredentialID)
+}
+
+// pruneExpired 清理未被使用的过期票据
+func (s *WebSSHService) pruneExpired() {
+	records, err := s.store.List(store.CollectionWebSSHTickets)
+	if err != nil {
+		return
+	}
+	now := time.Now()
+	for id, data := range records {
+		var ticket model.WebSSHTicket
+		if json.Unmarshal(data, &ticket) == nil && now.After(ticket.ExpiresAt) {
+			s.store.Delete(store.CollectionWebSSHTickets, id)
+		}
+	}
+}
+
+func displaySubject(subject string) string {
+	if subject == "" {
+		return "anonymous"
+	}
+	return subject
+}
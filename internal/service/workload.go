@@ -0,0 +1,210 @@
+package service
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k3s-deploy-backend/internal/model"
+	"k3s-deploy-backend/internal/pkg/k3s"
+)
+
+// dnsLabelPattern 实例名和命名空间（RFC 1123 标签）
+var dnsLabelPattern = regexp.MustCompile(`^[a-z0-9]([-a-z0-9]{0,61}[a-z0-9])?$`)
+
+// reservedNamespaces 系统组件使用的命名空间，不能用于部署实例
+var reservedNamespaces = map[string]bool{
+	"default":         true,
+	"kube-system":     true,
+	"kube-public":     true,
+	"kube-node-lease": true,
+	"longhorn-system": true,
+	"cnpg-system":     true,
+	"minio":           true,
+	"velero":          true,
+}
+
+// appSpec 汇总请求中的 inSuite 实例、访问方式与组件设置，并检查副本数与节点标签是否匹配
+func appSpec(req *model.DeployRequest) (k3s.AppSpec, error) {
+	spec := k3s.AppSpec{
+		Instance:   k3s.DefaultInstance,
+		Exposure:   exposure(req.Exposure),
+		Components: k3s.DefaultComponents(),
+	}
+	// 安装了 cert-manager 时 Ingress 证书由其 ClusterIssuer 签发并自动续期
+	if spec.Exposure.TLS && req.CertManager != nil {
+		spec.Exposure.Issuer = k3s.ClusterIssuerName
+	}
+
+	if inst := req.Instance; inst != nil {
+		if inst.Name != "" {
+			spec.Instance = inst.Name
+		}
+		spec.Namespace = inst.Namespace
+		if inst.Quota != nil {
+			spec.Quota = &k3s.Quota{
+				RequestsCPU:    inst.Quota.RequestsCPU,
+				RequestsMemory: inst.Quota.RequestsMemory,
+				LimitsCPU:      inst.Quota.LimitsCPU,
+				LimitsMemory:   inst.Quota.LimitsMemory,
+				Pods:           inst.Quota.Pods,
+			}
+			if err := spec.Quota.Validate(); err != nil {
+				return spec, fmt.Errorf("资源配额: %v", err)
+			}
+		}
+		if lr := inst.LimitRange; lr != nil {
+			spec.LimitRange = &k3s.LimitRange{
+				DefaultRequestCPU:    lr.DefaultRequestCPU,
+				DefaultRequestMemory: lr.DefaultRequestMemory,
+				DefaultLimitCPU:      lr.DefaultLimitCPU,
+				DefaultLimitMemory:   lr.DefaultLimitMemory,
+				MaxCPU:               lr.MaxCPU,
+				MaxMemory:            lr.MaxMemory,
+			}
+			if err := spec.LimitRange.Validate(); err != nil {
+				return spec, fmt.Errorf("LimitRange: %v", err)
+			}
+		}
+	}
+	if np := req.NetworkPolicy; np != nil {
+		spec.NetworkPolicy = &k3s.NetworkPolicy{
+			DenyEgress:  np.DefaultDeny == model.DefaultDenyAll,
+			EgressCIDRs: np.EgressCIDRs,
+		}
+		if err := spec.NetworkPolicy.Validate(); err != nil {
+			return spec, fmt.Errorf("网络策略: %v", err)
+		}
+	}
+	if db := req.Database; db != nil && db.Engine == model.DatabaseEngineCNPG {
+		if err := k3s.ValidateDatabase(db); err != nil {
+			return spec, err
+		}
+		// 实例管理器需要访问 API Server，限制出站时无法工作
+		if spec.NetworkPolicy != nil && spec.NetworkPolicy.DenyEgress {
+			return spec, fmt.Errorf("cloudnative-pg 数据库需要访问 API Server，不能与 networkPolicy.defaultDeny=all 同时使用")
+		}
+		spec.Database = k3s.NewManagedDatabase(db)
+		if spec.NetworkPolicy != nil {
+			spec.NetworkPolicy.ManagedDatabase = true
+		}
+	}
+	if spec.Namespace == "" {
+		spec.Namespace = spec.Instance
+	}
+	if !dnsLabelPattern.MatchString(spec.Instance) {
+		return spec, fmt.Errorf("无效的实例名: %s（只能包含小写字母、数字和 -）", spec.Instance)
+	}
+	if !dnsLabelPattern.MatchString(spec.Namespace) {
+		return spec, fmt.Errorf("无效的命名空间: %s", spec.Namespace)
+	}
+	if reservedNamespaces[spec.Namespace] {
+		return spec, fmt.Errorf("命名空间 %s 为系统保留，不能部署实例", spec.Namespace)
+	}
+
+	for role, opts := range req.Components {
+		component, ok := spec.Components[role]
+		if !ok {
+			return spec, fmt.Errorf("未知的组件角色: %s（可选 database、middleware、app）", role)
+		}
+		if opts == nil {
+			continue
+		}
+
+		if opts.Replicas > 0 {
+			if opts.Replicas > 1 && role != k3s.RoleApp {
+				return spec, fmt.Errorf("组件 %s 为单实例镜像，不支持多副本", role)
+			}
+			component.Replicas = opts.Replicas
+		}
+		if opts.AntiAffinity != "" {
+			component.AntiAffinity = opts.AntiAffinity
+		}
+		if component.AntiAffinity == k3s.AntiAffinityRequired {
+			if nodes := labeledNodes(req.Labels, role); component.Replicas > nodes {
+				return spec, fmt.Errorf("组件 %s 要求每个节点最多一个副本，但带 insuite.%s=true 标签的节点只有 %d 个，少于副本数 %d", role, role, nodes, component.Replicas)
+			}
+		}
+
+		if r := opts.Resources; r != nil {
+			override(&component.Resources.RequestsCPU, r.RequestsCPU)
+			override(&component.Resources.RequestsMemory, r.RequestsMemory)
+			override(&component.Resources.LimitsCPU, r.LimitsCPU)
+			override(&component.Resources.LimitsMemory, r.LimitsMemory)
+			if err := component.Resources.Validate(); err != nil {
+				return spec, fmt.Errorf("组件 %s: %v", role, err)
+			}
+		}
+
+		if p := opts.Probes; p != nil {
+			component.Probe.Disabled = p.Disabled
+			if p.Path != "" {
+				if role != k3s.RoleApp {
+					return spec, fmt.Errorf("组件 %s 不使用 HTTP 检查，不能设置 path", role)
+				}
+				if !strings.HasPrefix(p.Path, "/") {
+					return spec, fmt.Errorf("探针路径必须以 / 开头: %s", p.Path)
+				}
+				component.Probe.Path = p.Path
+			}
+			if p.ReadinessDelaySeconds > 0 {
+				component.Probe.ReadinessDelay = p.ReadinessDelaySeconds
+			}
+			if p.LivenessDelaySeconds > 0 {
+				component.Probe.LivenessDelay = p.LivenessDelaySeconds
+			}
+			if p.PeriodSeconds > 0 {
+				component.Probe.Period = p.PeriodSeconds
+			}
+			if p.FailureThreshold > 0 {
+				component.Probe.FailureThreshold = p.FailureThreshold
+			}
+		}
+
+		spec.Components[role] = component
+	}
+
+	// cloudnative-pg 数据库的每个实例按 database 组件的资源和分散方式调度
+	if spec.Database != nil {
+		database := spec.Components[k3s.RoleDatabase]
+		database.Replicas = spec.Database.Instances
+		if database.AntiAffinity == k3s.AntiAffinityRequired {
+			if nodes := labeledNodes(req.Labels, k3s.RoleDatabase); database.Replicas > nodes {
+				return spec, fmt.Errorf("数据库要求每个节点最多一个实例，但带 insuite.database=true 标签的节点只有 %d 个，少于实例数 %d", nodes, database.Replicas)
+			}
+		}
+		spec.Components[k3s.RoleDatabase] = database
+	}
+
+	// 组件资源超过 LimitRange 上限时 Pod 无法创建，部署前拒绝
+	if spec.LimitRange != nil {
+		for _, role := range []string{k3s.RoleDatabase, k3s.RoleMiddleware, k3s.RoleApp} {
+			if err := spec.LimitRange.Admits(spec.Components[role].Resources); err != nil {
+				return spec, fmt.Errorf("组件 %s: %v", role, err)
+			}
+		}
+	}
+	return spec, nil
+}
+
+// labeledNodes 统计 labels 中带某角色标签的节点数
+func labeledNodes(labels map[string][]string, role string) int {
+	label := fmt.Sprintf("insuite.%s=true", role)
+	count := 0
+	for _, nodeLabels := range labels {
+		for _, l := range nodeLabels {
+			if l == label {
+				count++
+				break
+			}
+		}
+	}
+	return count
+}
+
+// override 值非空时覆盖默认值
+func override(field *string, value string) {
+	if value != "" {
+		*field = value
+	}
+}
@@ -0,0 +1,16 @@
+package utils
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+)
+
+// GenerateID 生成带前缀的随机ID，例如 task-1f2e3d4c5b6a7988
+func GenerateID(prefix string) (string, error) {
+	buf := make([]byte, 8)
+	if _, err := rand.Read(buf); err != nil {
+		return "", fmt.Errorf("生成ID失败: %v", err)
+	}
+	return prefix + "-" + hex.EncodeToString(buf), nil
+}
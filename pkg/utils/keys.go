@@ -0,0 +1,105 @@
+package utils
+
+import (
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/ssh"
+)
+
+// SupportedKeyTypes 支持的私钥算法
+var SupportedKeyTypes = []string{"rsa", "ecdsa", "ed25519"}
+
+// SupportedKeyFormats 支持的私钥编码格式
+var SupportedKeyFormats = []string{"OpenSSH", "PKCS#1", "PKCS#8", "SEC1"}
+
+const minRSAKeyBits = 2048
+
+// PrivateKeyInfo 私钥解析结果
+type PrivateKeyInfo struct {
+	Type      string `json:"type"`
+	Format    string `json:"format"`
+	Bits      int    `json:"bits,omitempty"`
+	Encrypted bool   `json:"encrypted"`
+}
+
+// ValidatePrivateKey 实际解析私钥（含口令），返回算法与格式；失败时给出具体原因
+func ValidatePrivateKey(privateKey, passphrase string) (*PrivateKeyInfo, error) {
+	if strings.TrimSpace(privateKey) == "" {
+		return nil, fmt.Errorf("私钥不能为空")
+	}
+
+	block, _ := pem.Decode([]byte(privateKey))
+	if block == nil {
+		return nil, fmt.Errorf("私钥格式无效，必须是PEM格式（以 -----BEGIN ... PRIVATE KEY----- 开头）")
+	}
+
+	info := &PrivateKeyInfo{}
+	switch block.Type {
+	case "OPENSSH PRIVATE KEY":
+		info.Format = "OpenSSH"
+	case "RSA PRIVATE KEY":
+		info.Format = "PKCS#1"
+	case "EC PRIVATE KEY":
+		info.Format = "SEC1"
+	case "PRIVATE KEY":
+		info.Format = "PKCS#8"
+	case "ENCRYPTED PRIVATE KEY":
+		return nil, fmt.Errorf("不支持加密的PKCS#8私钥，请使用 ssh-keygen -p -f <key> 转换为 OpenSSH 格式")
+	case "DSA PRIVATE KEY":
+		return nil, fmt.Errorf("不支持DSA私钥（已被OpenSSH弃用），支持的类型: %s", strings.Join(SupportedKeyTypes, ", "))
+	default:
+		return nil, fmt.Errorf("无法识别的私钥类型: %s", block.Type)
+	}
+
+	var signer ssh.Signer
+	var err error
+	if passphrase != "" {
+		signer, err = ssh.ParsePrivateKeyWithPassphrase([]byte(privateKey), []byte(passphrase))
+		info.Encrypted = true
+	} else {
+		signer, err = ssh.ParsePrivateKey([]byte(privateKey))
+	}
+
+	if err != nil {
+		var missing *ssh.PassphraseMissingError
+		switch {
+		case errors.As(err, &missing):
+			return nil, fmt.Errorf("私钥已加密，需要提供口令")
+		case errors.Is(err, x509.IncorrectPasswordError):
+			return nil, fmt.Errorf("私钥口令错误")
+		case strings.Contains(err.Error(), "decryption password incorrect"):
+			return nil, fmt.Errorf("私钥口令错误")
+		case passphrase != "" && (strings.Contains(err.Error(), "not password protected") || strings.Contains(err.Error(), "not an encrypted key")):
+			return nil, fmt.Errorf("私钥未加密，请勿提供口令")
+		default:
+			return nil, fmt.Errorf("解析%s私钥失败: %v", info.Format, err)
+		}
+	}
+
+	switch pubType := signer.PublicKey().Type(); {
+	case pubType == ssh.KeyAlgoRSA:
+		info.Type = "rsa"
+	case pubType == ssh.KeyAlgoED25519:
+		info.Type = "ed25519"
+	case strings.HasPrefix(pubType, "ecdsa-"):
+		info.Type = "ecdsa"
+	default:
+		return nil, fmt.Errorf("不支持的私钥算法: %s，支持的类型: %s", pubType, strings.Join(SupportedKeyTypes, ", "))
+	}
+
+	if cryptoPub, ok := signer.PublicKey().(ssh.CryptoPublicKey); ok {
+		if rsaPub, ok := cryptoPub.CryptoPublicKey().(*rsa.PublicKey); ok {
+			info.Bits = rsaPub.N.BitLen()
+			if info.Bits < minRSAKeyBits {
+				return nil, fmt.Errorf("RSA私钥长度过短: %d 位（至少 %d 位）", info.Bits, minRSAKeyBits)
+			}
+		}
+	}
+
+	return info, nil
+}
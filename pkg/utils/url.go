@@ -0,0 +1,24 @@
+package utils
+
+import (
+	"net/url"
+	"regexp"
+)
+
+// urlUserinfo URL 中 scheme:// 之后、@ 之前的用户名和密码
+var urlUserinfo = regexp.MustCompile(`(://)[^/@\s]+@`)
+
+// RedactURL 将 URL 中的用户名和密码替换为 ***，用于日志和错误信息；
+// 令牌常作为用户名放在 URL 中，因此用户名也一并隐藏
+func RedactURL(raw string) string {
+	return urlUserinfo.ReplaceAllString(raw, "${1}***@")
+}
+
+// URLHasCredentials HTTP(S) URL 中是否包含用户名或密码
+func URLHasCredentials(raw string) bool {
+	u, err := url.Parse(raw)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") {
+		return false
+	}
+	return u.User != nil
+}
@@ -44,18 +44,6 @@ func ValidateNodeName(name string) error {
 	return nil
 }
 
-func ValidatePrivateKey(privateKey string) error {
-	if privateKey == "" {
-		return fmt.Errorf("私钥不能为空")
-	}
-
-	if !strings.Contains(privateKey, "BEGIN") || !strings.Contains(privateKey, "END") {
-		return fmt.Errorf("私钥格式无效，必须是PEM格式")
-	}
-
-	return nil
-}
-
 func SanitizeString(input string) string {
 	// 移除潜在的命令注入字符
 	dangerous := []string{";", "&", "|", "`", "$", "(", ")", "<", ">", "\"", "'"}
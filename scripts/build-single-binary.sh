@@ -0,0 +1,15 @@
+#!/bin/sh
+# 将前端构建产物内嵌到后端，生成单个二进制
+# 用法: scripts/build-single-binary.sh <前端 dist 目录> [输出文件，默认 bin/k3s-deploy]
+set -e
+
+dist=${1:?用法: $0 <前端 dist 目录> [输出文件]}
+out=${2:-bin/k3s-deploy}
+[ -f "$dist/index.html" ] || { echo "$dist 中没有 index.html，请先构建前端" >&2; exit 1; }
+
+cd "$(dirname "$0")/.."
+embed=internal/pkg/webui/dist
+find "$embed" -mindepth 1 ! -name .gitkeep -exec rm -rf {} +
+cp -R "$dist"/. "$embed"/
+go build -trimpath -ldflags "-s -w" -o "$out" ./cmd/server
+echo "已生成 $out，配置 server.frontend.enabled: true 后由后端提供前端页面"